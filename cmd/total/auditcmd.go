@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// runAuditCommand runs "total audit verify": it checks the hash chain of an audit log
+// export (GET /oracle/audit.json) without contacting the server or the network.
+func runAuditCommand(args []string, out io.Writer) error {
+	const usage = "usage: total audit verify --file <oracle-audit.json> [--head <hash>]"
+	if len(args) == 0 || args[0] != "verify" {
+		return fmt.Errorf(usage)
+	}
+
+	var path, head string
+	fs := flag.NewFlagSet("total audit verify", flag.ContinueOnError)
+	fs.StringVar(&path, "file", "", "JSON export of the audit log")
+	fs.StringVar(&head, "head", "", "expected hash of an entry, e.g. from an IPFS anchor")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if path == "" {
+		return fmt.Errorf(usage)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []service.AuditEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := service.VerifyAuditChain(entries); err != nil {
+		return err
+	}
+	if head != "" {
+		found := false
+		for _, e := range entries {
+			found = found || e.Hash == head
+		}
+		if !found {
+			return fmt.Errorf("%w: no entry has hash %s", service.ErrAuditChainBroken, head)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "audit log is empty")
+		return nil
+	}
+	last := entries[len(entries)-1]
+	fmt.Fprintf(out, "verified entries %d-%d, head %s\n", entries[0].Seq, last.Seq, last.Hash)
+	return nil
+}
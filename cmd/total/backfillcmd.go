@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/stellar"
+)
+
+const backfillUsage = "usage: total backfill [--from-ledger N] [--no-horizon]"
+
+// runBackfillCommand runs "total backfill": it counts the trades of the factory's markets
+// from before the stats aggregation first saw them into STATS_FILE. Stop the web server
+// first, it rewrites the same file.
+func runBackfillCommand(cfg appConfig, args []string, out io.Writer) error {
+	var fromLedger uint
+	var noHorizon bool
+	fs := flag.NewFlagSet("total backfill", flag.ContinueOnError)
+	fs.UintVar(&fromLedger, "from-ledger", 0, "first ledger to read (default: estimated from the oldest market's creation time)")
+	fs.BoolVar(&noHorizon, "no-horizon", false, "only read the RPC retention window, not older ledgers from Horizon")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return errors.New(backfillUsage)
+	}
+	if cfg.FactoryContract == "" {
+		return errors.New("MARKET_FACTORY_CONTRACT is required")
+	}
+	if cfg.StatsFile == "" {
+		return errors.New("STATS_FILE is required: a backfill adds to the persisted totals")
+	}
+
+	svc, err := newOfflineServices(cfg)
+	if err != nil {
+		return err
+	}
+	stats, err := service.NewStatsService(svc.factory, svc.events, svc.ipfs, cfg.StatsFile, slog.Default())
+	if err != nil {
+		return err
+	}
+
+	opts := service.BackfillOptions{
+		FromLedger: uint32(fromLedger),
+		Progress: func(ledger, last uint32) {
+			fmt.Fprintf(out, "horizon ledger %d of %d\n", ledger, last)
+		},
+	}
+	if !noHorizon {
+		opts.Horizon = svc.stellar.GetLedgerTransactions
+	}
+
+	// Interrupting keeps the file as it was: totals are only saved at the end.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := stats.Backfill(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("backfill failed: %w", err)
+	}
+
+	for _, id := range result.Skipped {
+		fmt.Fprintf(out, "skipped %s: counted before backfills were supported\n", id)
+	}
+	if result.Markets == 0 {
+		fmt.Fprintln(out, "no markets to backfill")
+		return nil
+	}
+	fmt.Fprintf(out, "backfilled %d markets from ledger %d (%d ledgers from Horizon, RPC from %d): %d trades\n",
+		result.Markets, result.FromLedger, result.HorizonLedgers, result.RPCLedger, result.Trades)
+	return nil
+}
+
+// offlineServices are the services the one-off commands read markets and trades with.
+type offlineServices struct {
+	stellar *stellar.HorizonClient
+	ipfs    *ipfs.Client
+	factory *service.FactoryService
+	events  *service.EventService
+}
+
+// newOfflineServices creates the market services of a one-off command, without the
+// caches and version registry of the web server.
+func newOfflineServices(cfg appConfig) (*offlineServices, error) {
+	sorobanClient, txBuilder, err := newNetworkClients(cfg)
+	if err != nil {
+		return nil, err
+	}
+	horizonHTTP, err := httpclient.New(cfg.HorizonTimeout, cfg.HTTPTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	stellarClient, err := stellar.NewHorizonClient(cfg.NetworkConfig.HorizonURL, cfg.NetworkConfig.NetworkPassphrase, horizonHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Stellar client: %w", err)
+	}
+	ipfsClient, err := newIPFSClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &offlineServices{
+		stellar: stellarClient,
+		ipfs:    ipfsClient,
+		factory: service.NewFactoryService(sorobanClient, stellarClient, txBuilder, cfg.FactoryContract, cfg.OraclePublicKey, nil, slog.Default()),
+		events:  service.NewEventService(sorobanClient, slog.Default()),
+	}, nil
+}
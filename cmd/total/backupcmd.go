@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/s3"
+	"github.com/mtlprog/total/internal/service"
+)
+
+const restoreMetadataUsage = "usage: total restore-metadata [--target s3://bucket/prefix]"
+
+// newBackupBucket returns the bucket under an s3://bucket/prefix backup target.
+func newBackupBucket(cfg appConfig, target string) (*s3.Bucket, error) {
+	t, err := s3.ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	s3HTTP, err := httpclient.New(cfg.S3Timeout, cfg.HTTPTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	client, err := s3.NewClient(cfg.S3, s3HTTP)
+	if err != nil {
+		return nil, err
+	}
+	return client.Bucket(t), nil
+}
+
+// stateFiles returns the configured JSON state files, the "database" a backup
+// snapshots. The oracle keystore and the leader lock are not state.
+func (c appConfig) stateFiles() []string {
+	var files []string
+	for _, path := range []string{
+		c.CommentsFile, c.ReferralsFile, c.AlertsFile, c.EmailSubsFile, c.ShareLinksFile,
+		c.RuleTemplatesFile, c.SeriesFile, c.RolloverFile, c.DeployBatchesFile, c.AuditLogFile,
+		c.ClaimsFile, c.StatsFile, c.PriceHistoryFile, c.ProfilesFile, c.PaperTradesFile,
+		c.WasmVersionsFile,
+	} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// runRestoreMetadataCommand runs "total restore-metadata": it pins every market
+// metadata document mirrored to the backup bucket again via Pinata.
+func runRestoreMetadataCommand(cfg appConfig, args []string, out io.Writer) error {
+	target := cfg.BackupTarget
+	fs := flag.NewFlagSet("total restore-metadata", flag.ContinueOnError)
+	fs.StringVar(&target, "target", target, "s3://bucket/prefix the metadata was backed up to (default: BACKUP_TARGET)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return errors.New(restoreMetadataUsage)
+	}
+	if target == "" {
+		return errors.New("a backup target is required: set BACKUP_TARGET or pass --target")
+	}
+	if cfg.PinataAPIKey == "" || cfg.PinataAPISecret == "" {
+		return errors.New("PINATA_API_KEY and PINATA_API_SECRET are required to pin metadata")
+	}
+
+	bucket, err := newBackupBucket(cfg, target)
+	if err != nil {
+		return fmt.Errorf("invalid backup target: %w", err)
+	}
+	ipfsClient, err := newIPFSClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := service.RestoreMetadata(ctx, bucket, ipfsClient, func(hash string) {
+		fmt.Fprintf(out, "pinned %s\n", hash)
+	})
+	for _, hash := range result.Mismatched {
+		fmt.Fprintf(out, "mismatch %s: the document pinned under a different hash\n", hash)
+	}
+	fmt.Fprintf(out, "restored %d metadata documents, %d mismatched\n", result.Pinned, len(result.Mismatched))
+	if err != nil {
+		return fmt.Errorf("restore incomplete: %w", err)
+	}
+	return nil
+}
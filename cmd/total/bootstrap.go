@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// bootstrapTimeout bounds a whole bootstrap: five transactions, each waiting for a
+// ledger.
+const bootstrapTimeout = 10 * time.Minute
+
+const bootstrapUsage = "usage: total bootstrap [--network testnet|mainnet] [--market-wasm path] [--factory-wasm path] [--collateral native|CODE:ISSUER|C...] [--fund] [--confirm-mainnet]"
+
+// runBootstrapCommand runs "total bootstrap": it sets up a new environment with the
+// oracle key and prints the settings to paste into the configuration.
+func runBootstrapCommand(cfg appConfig, args []string, out io.Writer) error {
+	var network, marketWasm, factoryWasm, collateral string
+	var fund, confirmMainnet bool
+	fs := flag.NewFlagSet("total bootstrap", flag.ContinueOnError)
+	fs.StringVar(&network, "network", cfg.Network, "network to bootstrap: testnet or mainnet")
+	fs.StringVar(&marketWasm, "market-wasm", "contracts/target/wasm32-unknown-unknown/release/lmsr_market.wasm", "path to the market contract WASM")
+	fs.StringVar(&factoryWasm, "factory-wasm", "contracts/target/wasm32-unknown-unknown/release/market_factory.wasm", "path to the factory contract WASM")
+	fs.StringVar(&collateral, "collateral", "native", `collateral token: "native", CODE:ISSUER or a token contract ID`)
+	fs.BoolVar(&fund, "fund", false, "fund the oracle account with friendbot first (testnet only)")
+	fs.BoolVar(&confirmMainnet, "confirm-mainnet", false, "required to bootstrap on mainnet")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return errors.New(bootstrapUsage)
+	}
+
+	network = strings.ToLower(network)
+	switch network {
+	case "testnet":
+	case "mainnet":
+		if !confirmMainnet {
+			return errors.New("bootstrapping mainnet spends real XLM: pass --confirm-mainnet to proceed")
+		}
+		if fund {
+			return errors.New("--fund is only available on testnet")
+		}
+	default:
+		return fmt.Errorf("unknown network %q: %s", network, bootstrapUsage)
+	}
+	if network != cfg.Network {
+		cfg.Network = network
+		cfg.NetworkConfig = config.GetNetworkConfig(network)
+		cfg.SorobanRPCURLs = nil // SOROBAN_RPC_URLS is for the configured network
+	}
+
+	if cfg.OraclePublicKey == "" {
+		return errors.New("ORACLE_PUBLIC_KEY is required")
+	}
+	signer, err := newOracleSigner(cfg)
+	if err != nil {
+		return err
+	}
+	if signer == nil {
+		return errors.New("bootstrap signs with the oracle key: set ORACLE_SECRET_KEY or ORACLE_KEYSTORE_FILE")
+	}
+
+	req := service.BootstrapRequest{}
+	if req.MarketWasm, err = os.ReadFile(marketWasm); err != nil {
+		return fmt.Errorf("failed to read market WASM: %w", err)
+	}
+	if req.FactoryWasm, err = os.ReadFile(factoryWasm); err != nil {
+		return fmt.Errorf("failed to read factory WASM: %w", err)
+	}
+	if req.Collateral, err = service.ParseCollateral(collateral, cfg.NetworkConfig.NetworkPassphrase); err != nil {
+		return err
+	}
+
+	sorobanClient, txBuilder, err := newNetworkClients(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	info, err := sorobanClient.GetNetwork(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get RPC network: %w", err)
+	}
+	if info.Passphrase != cfg.NetworkConfig.NetworkPassphrase {
+		return fmt.Errorf("RPC %s serves %q, not %s", sorobanClient.Endpoints()[0].URL, info.Passphrase, network)
+	}
+	if fund {
+		if err := fundWithFriendbot(ctx, cfg, info, signer.Address()); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "funded %s with friendbot\n", signer.Address())
+	}
+
+	bootstrapper := service.NewBootstrapper(sorobanClient, txBuilder, signer, slog.Default())
+	result, err := bootstrapper.Run(ctx, req, func(step service.BootstrapStep) {
+		if step.Skipped {
+			fmt.Fprintf(out, "%-34s already done\n", step.Name)
+			return
+		}
+		fmt.Fprintf(out, "%-34s %s\n", step.Name, step.TxHash)
+	})
+	if err != nil {
+		return fmt.Errorf("bootstrap failed (already completed steps are skipped when run again): %w", err)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "# Add to the environment:")
+	fmt.Fprintf(out, "NETWORK=%s\n", network)
+	fmt.Fprintf(out, "ORACLE_PUBLIC_KEY=%s\n", signer.Address())
+	fmt.Fprintf(out, "MARKET_FACTORY_CONTRACT=%s\n", result.FactoryContract)
+	fmt.Fprintf(out, "# Collateral token: %s\n", result.CollateralToken)
+	fmt.Fprintf(out, "# Market WASM hash: %s (register it on /admin when CONTRACT_VERSIONS_FILE is set)\n", result.MarketWasmHash)
+	fmt.Fprintf(out, "# Factory WASM hash: %s\n", result.FactoryWasmHash)
+	return nil
+}
+
+// fundWithFriendbot creates and funds a testnet account. An account that already
+// exists is not an error.
+func fundWithFriendbot(ctx context.Context, cfg appConfig, info *soroban.GetNetworkResult, address string) error {
+	if info.FriendbotURL == "" {
+		return errors.New("the RPC server has no friendbot for this network")
+	}
+	client, err := httpclient.New(cfg.HorizonTimeout, cfg.HTTPTransport)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, info.FriendbotURL+"?addr="+url.QueryEscape(address), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create friendbot request: %w", err)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("friendbot request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 && !strings.Contains(string(body), "op_already_exists") {
+		return fmt.Errorf("friendbot returned %s: %s", resp.Status, body)
+	}
+	return nil
+}
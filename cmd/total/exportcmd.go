@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/service"
+)
+
+const exportUsage = "usage: total export [--target DIR|s3://bucket/prefix]"
+
+// runExportCommand runs "total export": it writes the same CSV files as the nightly
+// analytics export job, once. Price history is read from PRICE_HISTORY_FILE, when set.
+func runExportCommand(cfg appConfig, args []string, out io.Writer) error {
+	target := cfg.ExportTarget
+	fs := flag.NewFlagSet("total export", flag.ContinueOnError)
+	fs.StringVar(&target, "target", target, "directory or s3://bucket/prefix to write to (default: EXPORT_TARGET)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return errors.New(exportUsage)
+	}
+	if target == "" {
+		return errors.New("an export target is required: set EXPORT_TARGET or pass --target")
+	}
+	if cfg.FactoryContract == "" {
+		return errors.New("MARKET_FACTORY_CONTRACT is required")
+	}
+
+	svc, err := newOfflineServices(cfg)
+	if err != nil {
+		return err
+	}
+	var priceHistory *service.PriceHistory
+	if cfg.PriceHistoryFile != "" {
+		priceHistory, err = service.NewPriceHistory(svc.factory, cfg.PriceRetention, cfg.PriceHistoryFile, slog.Default())
+		if err != nil {
+			return err
+		}
+	}
+	s3HTTP, err := httpclient.New(cfg.S3Timeout, cfg.HTTPTransport)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	sink, err := service.NewExportSink(target, cfg.S3, s3HTTP)
+	if err != nil {
+		return fmt.Errorf("invalid export target: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	exporter := service.NewExporter(svc.factory, svc.events, svc.ipfs, priceHistory, sink, slog.Default())
+	result, err := exporter.Run(ctx, time.Now())
+	if err == nil || result.Markets > 0 {
+		fmt.Fprintf(out, "exported %d markets, %d trades and %d price rows to %s/%s\n",
+			result.Markets, result.Trades, result.Prices, target, result.Dir)
+	}
+	if err != nil {
+		return fmt.Errorf("export incomplete: %w", err)
+	}
+	return nil
+}
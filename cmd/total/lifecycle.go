@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// component is a long-running part of the application. run blocks until ctx is
+// cancelled or the component fails; returning nil before that means its work is done.
+// stop, if set, asks run to return instead of cancelling its context, e.g. to let the
+// HTTP server drain in-flight requests.
+type component struct {
+	name string
+	run  func(ctx context.Context) error
+	stop func(ctx context.Context) error
+}
+
+// lifecycle starts components in the order they were added and stops them in reverse,
+// so those added last, like the HTTP server, stop taking work before the background
+// jobs they depend on are stopped.
+type lifecycle struct {
+	components   []component
+	drainTimeout time.Duration
+	logger       *slog.Logger
+}
+
+func newLifecycle(drainTimeout time.Duration, logger *slog.Logger) *lifecycle {
+	return &lifecycle{drainTimeout: drainTimeout, logger: logger}
+}
+
+// add registers a component. stop may be nil.
+func (l *lifecycle) add(name string, run, stop func(ctx context.Context) error) {
+	l.components = append(l.components, component{name: name, run: run, stop: stop})
+}
+
+// Run starts all components and blocks until ctx is cancelled or a component fails,
+// then stops them in reverse order within the drain timeout. The drain timeout is
+// shared by all components. It returns the first component failure, if any, joined
+// with errors from stopping.
+func (l *lifecycle) Run(ctx context.Context) error {
+	type running struct {
+		component
+		cancel context.CancelFunc
+		done   chan error
+	}
+
+	failed := make(chan error, len(l.components))
+	started := make([]running, 0, len(l.components))
+	for _, c := range l.components {
+		// Components get their own context, so one can be stopped while others keep running.
+		cctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r := running{component: c, cancel: cancel, done: make(chan error, 1)}
+		go func() {
+			err := c.run(cctx)
+			if err != nil && cctx.Err() == nil {
+				failed <- fmt.Errorf("%s: %w", c.name, err)
+			}
+			r.done <- err
+		}()
+		started = append(started, r)
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		l.logger.Info("shutting down", "drain_timeout", l.drainTimeout)
+	case cause = <-failed:
+		l.logger.Error("component failed, shutting down", "error", cause)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), l.drainTimeout)
+	defer cancel()
+
+	errs := []error{cause}
+	for i := len(started) - 1; i >= 0; i-- {
+		r := started[i]
+		if r.stop != nil {
+			if err := r.stop(drainCtx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", r.name, err))
+			}
+		}
+		r.cancel()
+		select {
+		case <-r.done:
+			l.logger.Debug("component stopped", "component", r.name)
+		case <-drainCtx.Done():
+			select {
+			case <-r.done:
+			default:
+				errs = append(errs, fmt.Errorf("%s did not stop within %s", r.name, l.drainTimeout))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
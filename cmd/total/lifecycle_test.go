@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycleStopsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var stopped []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = append(stopped, name)
+	}
+
+	app := newLifecycle(time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	app.add("jobs", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("jobs")
+		return nil
+	}, nil)
+	app.add("warmup", func(context.Context) error {
+		return nil // finished early, must not trigger a shutdown
+	}, nil)
+	release := make(chan struct{})
+	app.add("http", func(context.Context) error {
+		<-release
+		return nil
+	}, func(context.Context) error {
+		record("http")
+		close(release)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- app.Run(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-errc; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{"http", "jobs"}; !slices.Equal(stopped, want) {
+		t.Errorf("stop order = %v, want %v", stopped, want)
+	}
+}
+
+func TestLifecycleComponentFailure(t *testing.T) {
+	app := newLifecycle(time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	jobsStopped := false
+	app.add("jobs", func(ctx context.Context) error {
+		<-ctx.Done()
+		jobsStopped = true
+		return nil
+	}, nil)
+	app.add("http", func(context.Context) error {
+		return errors.New("address already in use")
+	}, nil)
+
+	err := app.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "http: address already in use") {
+		t.Fatalf("Run() error = %v, want the http failure", err)
+	}
+	if !jobsStopped {
+		t.Error("other components should be stopped after a failure")
+	}
+}
+
+func TestLifecycleDrainTimeout(t *testing.T) {
+	app := newLifecycle(20*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	app.add("stuck", func(context.Context) error {
+		select {} // ignores cancellation
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := app.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck did not stop") {
+		t.Fatalf("Run() error = %v, want a drain timeout", err)
+	}
+}
@@ -2,22 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // user time zones work in images without /usr/share/zoneinfo
 
 	"github.com/joho/godotenv"
 	"github.com/mtlprog/total/internal/config"
 	"github.com/mtlprog/total/internal/handler"
+	"github.com/mtlprog/total/internal/httpclient"
 	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/leader"
 	"github.com/mtlprog/total/internal/logger"
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/s3"
+	"github.com/mtlprog/total/internal/scheduler"
 	"github.com/mtlprog/total/internal/service"
 	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/static"
 	"github.com/mtlprog/total/internal/stellar"
 	"github.com/mtlprog/total/internal/template"
 )
@@ -33,8 +45,60 @@ func run() error {
 	// Load .env file if present (ignored in production)
 	_ = godotenv.Load()
 
+	// Support commands run against the network and exit
+	if len(os.Args) > 1 && os.Args[1] == "tx" {
+		cfg, err := parseConfig()
+		if err != nil {
+			return err
+		}
+		return runTxCommand(cfg, os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		return runAuditCommand(os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap" {
+		cfg, err := parseConfig()
+		if err != nil {
+			return err
+		}
+		return runBootstrapCommand(cfg, os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		cfg, err := parseConfig()
+		if err != nil {
+			return err
+		}
+		return runBackfillCommand(cfg, os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		cfg, err := parseConfig()
+		if err != nil {
+			return err
+		}
+		return runExportCommand(cfg, os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore-metadata" {
+		cfg, err := parseConfig()
+		if err != nil {
+			return err
+		}
+		return runRestoreMetadataCommand(cfg, os.Args[2:], os.Stdout)
+	}
+
+	// Parse command line flags
+	flags, err := parseFlags(os.Args[1:])
+	if err != nil {
+		return err
+	}
+
 	// Parse configuration from environment
-	cfg := parseConfig()
+	cfg, err := parseConfig()
+	if err != nil {
+		return err
+	}
+	if flags.SealKeystore != "" {
+		return sealKeystore(cfg, flags.SealKeystore)
+	}
 
 	// Validate required environment variables
 	if cfg.OraclePublicKey == "" {
@@ -45,50 +109,138 @@ func run() error {
 	}
 
 	// Setup logging
-	logger.Setup(logger.ParseLevel(cfg.LogLevel))
+	if err := logger.Setup(logger.Options{
+		Level:               logger.ParseLevel(cfg.LogLevel),
+		AccessLog:           cfg.AccessLog,
+		AccessLogMaxSize:    int64(cfg.AccessLogMaxSizeMB) * 1024 * 1024,
+		AccessLogMaxBackups: cfg.AccessLogMaxBackups,
+		DebugSampleRate:     cfg.DebugSampleRate,
+	}); err != nil {
+		return fmt.Errorf("failed to set up logging: %w", err)
+	}
+	defer logger.Close()
 
 	// Log configuration
 	slog.Info("configuration loaded",
 		"network", cfg.Network,
 		"horizon", cfg.NetworkConfig.HorizonURL,
-		"soroban_rpc", cfg.NetworkConfig.SorobanRPCURL,
 		"oracle", cfg.OraclePublicKey,
 		"factory", cfg.FactoryContract,
+		"tx_timeout", cfg.TxTimeout,
 	)
 
 	if cfg.Network == "mainnet" {
 		slog.Warn("RUNNING ON MAINNET — real funds at risk")
 	}
 
+	// Replicas sharing a volume wait here as hot standbys until they hold the leader
+	// lock, so only one process loads and writes the state files, keeps subscriptions
+	// and serves HTTP. The lock is released after everything below has stopped.
+	if cfg.LeaderLockFile != "" {
+		lock := leader.New(cfg.LeaderLockFile, slog.Default())
+		waitCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := lock.Acquire(waitCtx)
+		stop()
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		defer lock.Release()
+	}
+
+	// Initialize outbound HTTP clients (shared pool and proxy settings; custom TLS
+	// trust only for the Soroban RPC and IPFS gateways)
+	if cfg.SorobanTLS.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification disabled for Soroban RPC requests")
+	}
+	if cfg.IPFSGatewayTLS.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification disabled for IPFS gateway requests")
+	}
+	horizonHTTP, err := httpclient.New(cfg.HorizonTimeout, cfg.HTTPTransport)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	sorobanHTTP, err := httpclient.New(cfg.SorobanTimeout, cfg.transport(cfg.SorobanTLS))
+	if err != nil {
+		return fmt.Errorf("failed to create Soroban RPC HTTP client: %w", err)
+	}
+
 	// Initialize Stellar client
 	stellarClient, err := stellar.NewHorizonClient(
 		cfg.NetworkConfig.HorizonURL,
 		cfg.NetworkConfig.NetworkPassphrase,
+		horizonHTTP,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create Stellar client: %w", err)
 	}
 
-	// Initialize Soroban client
-	sorobanClient := soroban.NewClient(cfg.NetworkConfig.SorobanRPCURL)
+	// Initialize Soroban client; with several RPC URLs it fails over between them
+	sorobanClient := soroban.NewClientWithEndpoints(
+		cfg.sorobanEndpoints(),
+		sorobanHTTP,
+	)
+	for _, e := range sorobanClient.Endpoints() {
+		slog.Info("soroban RPC endpoint", "url", e.URL, "active", e.Active)
+	}
+	if cfg.SorobanDebugCapture > 0 {
+		sorobanClient.EnableCapture(cfg.SorobanDebugCapture)
+		slog.Warn("recording Soroban RPC calls for /admin/rpc-calls", "size", cfg.SorobanDebugCapture)
+	}
+
+	// Verify the RPC speaks a protocol our XDR encoding supports
+	if err := checkRPCProtocol(sorobanClient); err != nil {
+		if flags.Strict {
+			return err
+		}
+		slog.Warn("RPC protocol check failed, continuing (use --strict to fail)", "error", err)
+	}
 
-	// Initialize transaction builder
+	// Initialize transaction builder. Source accounts are cached briefly; rebuilds
+	// and transactions seen via /tx/{hash} refresh them.
 	txBuilder := stellar.NewBuilder(
-		stellarClient,
+		stellar.NewCachingClient(stellarClient),
 		cfg.NetworkConfig.NetworkPassphrase,
 		config.DefaultBaseFee,
+		cfg.TxTimeout,
 		sorobanClient,
 	)
+	if cfg.SimulationAccount != "" {
+		if err := txBuilder.SetSimulationAccount(cfg.SimulationAccount); err != nil {
+			return fmt.Errorf("SIMULATION_ACCOUNT: %w", err)
+		}
+		slog.Info("read-only simulations use a dedicated source account", "account", cfg.SimulationAccount)
+	}
+
+	// Initialize quote tokens
+	quoteSigner, err := service.NewQuoteSigner(cfg.QuoteTokenSecret)
+	if err != nil {
+		return fmt.Errorf("failed to create quote signer: %w", err)
+	}
+	if cfg.QuoteTokenSecret == "" {
+		slog.Info("QUOTE_TOKEN_SECRET not set, using a random key (quotes do not survive restarts)")
+	}
 
 	// Initialize market service
 	marketService := service.NewMarketService(
 		stellarClient,
 		sorobanClient,
 		txBuilder,
+		quoteSigner,
+		cfg.TradeLimits,
 		cfg.OraclePublicKey,
 		slog.Default(),
 	)
 
+	// Initialize the market contract version registry; markets' WASM builds are
+	// detected on first load and gate optional features
+	contractVersions, err := service.NewContractVersionRegistry(cfg.WasmVersionsFile, sorobanClient.ContractWasmHash, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to create contract version registry: %w", err)
+	}
+
 	// Initialize factory service
 	factoryService := service.NewFactoryService(
 		sorobanClient,
@@ -96,12 +248,26 @@ func run() error {
 		txBuilder,
 		cfg.FactoryContract,
 		cfg.OraclePublicKey,
+		contractVersions,
 		slog.Default(),
 	)
 	slog.Info("factory service enabled", "contract", cfg.FactoryContract)
+	if flags.StrictMarkets {
+		factoryService.EnableStrictMarkets()
+		if contractVersions.Len() == 0 {
+			slog.Warn("no contract versions registered, --strict-markets does not check WASM builds")
+		}
+		slog.Info("strict markets enabled: markets with untrusted data are hidden from public lists")
+	}
 
 	// Initialize IPFS client
-	ipfsClient := ipfs.NewClient(cfg.PinataAPIKey, cfg.PinataAPISecret)
+	ipfsClient, err := newIPFSClient(cfg)
+	if err != nil {
+		return err
+	}
+	if len(cfg.IPFSGateways) > 0 {
+		slog.Info("IPFS gateways", "gateways", cfg.IPFSGateways)
+	}
 	if cfg.PinataAPIKey != "" && cfg.PinataAPISecret != "" {
 		slog.Info("IPFS client enabled with Pinata (read+write)")
 	} else {
@@ -111,20 +277,371 @@ func run() error {
 	// Initialize event service
 	eventService := service.NewEventService(sorobanClient, slog.Default())
 
-	// Warmup IPFS cache
-	go warmupIPFSCache(factoryService, ipfsClient)
+	// Initialize market archive
+	archiveService := service.NewArchiveService(
+		factoryService,
+		eventService,
+		ipfsClient,
+		time.Duration(cfg.ArchiveAfterDays)*24*time.Hour,
+		slog.Default(),
+	)
+
+	// Initialize SEP-10 sign-in and market discussions
+	authService, err := service.NewAuthService(cfg.AuthSigningSeed, cfg.AuthHomeDomain, cfg.NetworkConfig.NetworkPassphrase)
+	if err != nil {
+		return fmt.Errorf("failed to create auth service: %w", err)
+	}
+	if cfg.AuthSigningSeed == "" {
+		slog.Info("AUTH_SIGNING_SEED not set, using a random key (sessions do not survive restarts)")
+	}
+	oracleSigner, err := newOracleSigner(cfg)
+	if err != nil {
+		return err
+	}
+	comments, err := service.NewCommentStore(cfg.CommentsFile, cfg.OraclePublicKey, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load comments: %w", err)
+	}
+	if cfg.CommentsFile == "" {
+		slog.Info("COMMENTS_FILE not set, keeping comments in memory (lost on restart)")
+	}
+
+	// Initialize referral attribution
+	referrals, err := service.NewReferralService(eventService, cfg.NetworkConfig.NetworkPassphrase, cfg.ReferralsFile, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load referrals: %w", err)
+	}
+
+	// Initialize probability alerts
+	notifyHTTP, err := httpclient.New(10*time.Second, cfg.HTTPTransport)
+	if err != nil {
+		return fmt.Errorf("failed to create notification HTTP client: %w", err)
+	}
+	transports, err := notifyTransports(cfg, notifyHTTP)
+	if err != nil {
+		return err
+	}
+	dispatcher := notify.NewDispatcher(transports...)
+	alerts, err := service.NewAlertService(
+		factoryService,
+		dispatcher,
+		cfg.PublicURL,
+		cfg.AlertsFile,
+		slog.Default(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+	slog.Info("alert notification channels", "channels", dispatcher.Channels())
+	anomalies, err := service.NewAnomalyMonitor(
+		factoryService,
+		eventService,
+		dispatcher,
+		cfg.AnomalyNotify,
+		cfg.PublicURL,
+		cfg.AnomalyThresholds,
+		slog.Default(),
+	)
+	if err != nil {
+		return err
+	}
+	if cfg.AnomalyNotify == "" {
+		slog.Info("ANOMALY_NOTIFY not set, trade anomalies are only shown on the admin dashboard")
+	}
 
-	// Initialize templates
-	tmpl, err := template.New()
+	// Email digests and resolution notices need SMTP and links back to the site.
+	var digests *service.DigestService
+	if dispatcher.Enabled(notify.ChannelEmail) && cfg.PublicURL != "" {
+		digests, err = service.NewDigestService(factoryService, ipfsClient, dispatcher, cfg.PublicURL, cfg.EmailSubsFile, slog.Default())
+		if err != nil {
+			return fmt.Errorf("failed to load email subscriptions: %w", err)
+		}
+	} else {
+		slog.Info("SMTP_HOST or PUBLIC_URL not set, email subscriptions disabled")
+	}
+	priceHistory, err := service.NewPriceHistory(factoryService, cfg.PriceRetention, cfg.PriceHistoryFile, slog.Default())
+	if err != nil {
+		return err
+	}
+	var exporter *service.Exporter
+	if cfg.ExportTarget != "" {
+		s3HTTP, err := httpclient.New(cfg.S3Timeout, cfg.HTTPTransport)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP client: %w", err)
+		}
+		sink, err := service.NewExportSink(cfg.ExportTarget, cfg.S3, s3HTTP)
+		if err != nil {
+			return fmt.Errorf("invalid EXPORT_TARGET: %w", err)
+		}
+		exporter = service.NewExporter(factoryService, eventService, ipfsClient, priceHistory, sink, slog.Default())
+	}
+	var backups *service.BackupService
+	if cfg.BackupTarget != "" {
+		bucket, err := newBackupBucket(cfg, cfg.BackupTarget)
+		if err != nil {
+			return fmt.Errorf("invalid BACKUP_TARGET: %w", err)
+		}
+		backups = service.NewBackupService(factoryService, ipfsClient, bucket, cfg.stateFiles(), cfg.BackupRetention, slog.Default())
+	}
+	shareLinks, err := service.NewShareLinkService(cfg.ShareLinksFile, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load share links: %w", err)
+	}
+	ruleTemplates, err := service.NewRuleTemplateStore(cfg.RuleTemplatesFile, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load rule templates: %w", err)
+	}
+	series, err := service.NewSeriesStore(cfg.SeriesFile, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load market series: %w", err)
+	}
+	claims, err := service.NewClaimService(factoryService, marketService, eventService, alerts, cfg.ClaimPeriod, cfg.PublicURL, cfg.ClaimsFile, slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to load claim tracking: %w", err)
+	}
+	sitemap := service.NewSitemapService(factoryService, eventService, slog.Default())
+	stats, err := service.NewStatsService(factoryService, eventService, ipfsClient, cfg.StatsFile, slog.Default())
+	if err != nil {
+		return err
+	}
+	profiles, err := service.NewProfileSettings(cfg.ProfilesFile, slog.Default())
+	if err != nil {
+		return err
+	}
+	var paper *service.PaperTrading
+	if cfg.PaperBalance > 0 {
+		paper, err = service.NewPaperTrading(marketService, cfg.PaperBalance, cfg.PaperTradesFile, slog.Default())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Initialize pending transaction tracking
+	pendingTxs := service.NewPendingTxStore()
+	pendingWatch := service.NewPendingTxWatcher(pendingTxs, eventService, factoryService, ipfsClient, slog.Default())
+
+	// Recurring series roll over to their next market when the last one resolves;
+	// the deploy transaction waits in the oracle's pending queue for signing.
+	var rollovers *service.RolloverService
+	if ipfsClient.CanPin() {
+		rollovers, err = service.NewRolloverService(factoryService, ipfsClient, series, pendingTxs, cfg.NetworkConfig.NetworkPassphrase, cfg.RolloverFile, slog.Default())
+		if err != nil {
+			return fmt.Errorf("failed to load series rollovers: %w", err)
+		}
+	} else {
+		slog.Info("IPFS pinning not configured, series rollover disabled")
+	}
+	auditLog, err := service.NewAuditLog(ipfsClient, cfg.AuditLogFile, slog.Default())
+	if err != nil {
+		return err
+	}
+	var bulkDeploys *service.BulkDeployService
+	if ipfsClient.CanPin() && factoryService.HasFactory() {
+		bulkDeploys, err = service.NewBulkDeployService(factoryService, ipfsClient, cfg.DeployBatchesFile, slog.Default())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Initialize idempotency keys for tx-building POSTs
+	idempotency := service.NewIdempotencyStore()
+
+	// Initialize rate limiting of quote and trade endpoints (each costs a simulation)
+	var txLimiter *service.RateLimiter
+	if cfg.TxRateLimit > 0 {
+		txLimiter = service.NewRateLimiter(cfg.TxRateLimit, time.Minute)
+	} else {
+		slog.Warn("TX_RATE_LIMIT is 0, quote and trade endpoints are not rate limited")
+	}
+
+	// Initialize network status
+	statusService := service.NewStatusService(sorobanClient, stellarClient, slog.Default())
+
+	// Background work and the HTTP server are started together by the lifecycle
+	// manager and stopped in reverse order on SIGINT/SIGTERM.
+	app := newLifecycle(cfg.ShutdownTimeout, slog.Default())
+	warmup := service.NewWarmupService(factoryService, ipfsClient, cfg.WarmupConcurrency, slog.Default())
+	app.add("cache-warmup", func(ctx context.Context) error {
+		if cfg.WarmupTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.WarmupTimeout)
+			defer cancel()
+		}
+		warmup.Run(ctx)
+		return nil
+	}, nil)
+
+	sched := scheduler.New(slog.Default())
+	if len(cfg.sorobanEndpoints()) > 1 {
+		sched.Every("rpc-endpoints", soroban.EndpointCheckInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			return sorobanClient.CheckEndpoints(ctx)
+		})
+	}
+	if factoryService.HasFactory() {
+		sched.Every("market-state-watch", service.StateWatchInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			return factoryService.WatchMarketStates(ctx)
+		})
+	}
+	sched.Every("network-status", service.StatusRefreshInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return statusService.Refresh(ctx)
+	})
+	if archiveService.Enabled() {
+		sched.Every("market-archive", service.ArchiveRefreshInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+			defer cancel()
+			return archiveService.Refresh(ctx)
+		})
+	}
+	sched.Every("referral-attribution", service.ReferralRefreshInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		return referrals.Refresh(ctx)
+	})
+	sched.EveryLeader("price-alerts", service.AlertEvaluateInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return alerts.Evaluate(ctx)
+	})
+	if digests != nil {
+		sched.EveryLeader("email-digest", service.DigestInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+			defer cancel()
+			return digests.SendDigests(ctx)
+		})
+		sched.EveryLeader("email-resolution-notices", service.ResolutionNoticeInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			return digests.NotifyResolutions(ctx)
+		})
+	}
+	sched.EveryLeader("trade-anomalies", service.AnomalyCheckInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		return anomalies.Refresh(ctx)
+	})
+	sched.Every("price-history", service.PriceHistoryInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return priceHistory.Refresh(ctx)
+	})
+	sched.Every("price-history-rollup", service.PriceRollupInterval, func(ctx context.Context) error {
+		return priceHistory.Rollup(time.Now())
+	})
+	if exporter != nil {
+		sched.EveryLeader("analytics-export", service.ExportInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+			defer cancel()
+			result, err := exporter.Run(ctx, time.Now())
+			slog.Info("analytics export written", "dir", result.Dir, "markets", result.Markets, "trades", result.Trades, "prices", result.Prices)
+			return err
+		})
+	}
+	if backups != nil {
+		sched.EveryLeader("backup", service.BackupInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+			defer cancel()
+			result, err := backups.Run(ctx, time.Now())
+			slog.Info("backup written", "mirrored", result.Mirrored, "snapshot", result.Snapshot, "files", result.Files, "pruned", result.Pruned)
+			return err
+		})
+	}
+	sched.Every("sitemap", service.SitemapRefreshInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		return sitemap.Refresh(ctx)
+	})
+	sched.Every("stats", service.StatsRefreshInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		return stats.Refresh(ctx)
+	})
+	sched.Every("claims", service.ClaimRefreshInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		return claims.Refresh(ctx)
+	})
+	sched.EveryLeader("claim-reminders", service.ClaimReminderInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return claims.SendReminders(ctx)
+	})
+	// Pending transactions are kept per replica, so every replica watches its own.
+	sched.Every("pending-watch", service.PendingTxWatchInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return pendingWatch.Refresh(ctx, cfg.OraclePublicKey)
+	})
+	if rollovers != nil {
+		sched.EveryLeader("series-rollover", service.RolloverInterval, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+			defer cancel()
+			return rollovers.Refresh(ctx)
+		})
+	}
+	sched.EveryLeader("audit-anchor", service.AuditAnchorInterval, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return auditLog.Anchor(ctx)
+	})
+	app.add("scheduler", func(ctx context.Context) error {
+		sched.Run(ctx)
+		return nil
+	}, nil)
+
+	// Initialize static assets and templates
+	assets, err := static.New()
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(assets)
 	if err != nil {
 		return fmt.Errorf("failed to load templates: %w", err)
 	}
 
+	// Mini App launch data is signed with the bot token, so the /tg/ alert form
+	// needs the same bot that delivers alerts.
+	var telegramApp *service.TelegramWebApp
+	if cfg.TelegramBotToken != "" {
+		telegramApp, err = service.NewTelegramWebApp(cfg.TelegramBotToken)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Initialize handler
 	marketHandler := handler.NewMarketHandler(
 		marketService,
 		factoryService,
 		eventService,
+		archiveService,
+		pendingTxs,
+		pendingWatch,
+		idempotency,
+		txLimiter,
+		authService,
+		comments,
+		referrals,
+		alerts,
+		digests,
+		telegramApp,
+		priceHistory,
+		shareLinks,
+		ruleTemplates,
+		series,
+		rollovers,
+		claims,
+		stats,
+		profiles,
+		paper,
+		bulkDeploys,
+		auditLog,
+		oracleSigner,
 		ipfsClient,
 		tmpl,
 		cfg.OraclePublicKey,
@@ -135,72 +652,482 @@ func run() error {
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	marketHandler.RegisterRoutes(mux)
+	assets.RegisterRoutes(mux)
+	handler.NewStatusHandler(statusService, slog.Default()).RegisterRoutes(mux)
+	handler.NewSitemapHandler(sitemap, cfg.PublicURL, slog.Default()).RegisterRoutes(mux)
+	handler.NewStatsHandler(stats, profiles, tmpl, cfg.Network, slog.Default()).RegisterRoutes(mux)
+	handler.NewAdminHandler(
+		cfg.AdminToken,
+		statusService,
+		factoryService,
+		eventService,
+		referrals,
+		stats,
+		ipfsClient,
+		sorobanClient,
+		service.NewTxReplayer(sorobanClient, txBuilder),
+		sched,
+		warmup,
+		anomalies,
+		tmpl,
+		cfg.Network,
+		slog.Default(),
+	).RegisterRoutes(mux)
+	if cfg.AdminToken == "" {
+		slog.Info("admin endpoints disabled (ADMIN_TOKEN not set)")
+	}
+	if cfg.DiscordPublicKey != "" {
+		discordHandler, err := handler.NewDiscordHandler(
+			cfg.DiscordPublicKey,
+			marketService,
+			factoryService,
+			ipfsClient,
+			cfg.PublicURL,
+			slog.Default(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create Discord handler: %w", err)
+		}
+		discordHandler.RegisterRoutes(mux)
+	} else {
+		slog.Info("Discord commands disabled (DISCORD_PUBLIC_KEY not set)")
+	}
+
+	// SIGUSR1 toggles debug logging without a restart
+	logToggle := make(chan os.Signal, 1)
+	signal.Notify(logToggle, syscall.SIGUSR1)
+	defer signal.Stop(logToggle)
+	go func() {
+		for range logToggle {
+			slog.Warn("log level toggled via SIGUSR1", "level", logger.ToggleDebug())
+		}
+	}()
+
+	// Stopped after the server, so no transaction is submitted once followers are cancelled.
+	app.add("tx-broadcasts", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, marketService.StopBroadcasts)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      handler.AccessLog(logger.Access(), handler.LimitBody(mux)),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Start server
-	serverErr := make(chan error, 1)
-	go func() {
+	app.add("http", func(ctx context.Context) error {
+		if flags.WarmupBlock {
+			// Readiness gate: nothing listens, so health checks fail, until the caches are warm
+			slog.Info("waiting for cache warmup before starting server", "timeout", cfg.WarmupTimeout)
+			select {
+			case <-warmup.Done():
+			case <-ctx.Done():
+				return nil
+			}
+		}
 		slog.Info("starting server", "addr", "http://localhost:"+cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			serverErr <- err
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
 		}
-	}()
+		return nil
+	}, server.Shutdown)
 
-	// Wait for shutdown signal
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	select {
-	case err := <-serverErr:
-		return fmt.Errorf("server error: %w", err)
-	case <-done:
-		slog.Info("shutting down server")
+	if err := app.Run(ctx); err != nil {
+		return err
 	}
+	slog.Info("server stopped")
+	return nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// cliFlags holds command line flags.
+type cliFlags struct {
+	Strict        bool
+	SealKeystore  string
+	WarmupBlock   bool
+	StrictMarkets bool
+}
 
-	if err := server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+// parseFlags parses command line flags. A leading "serve" command is accepted
+// for compatibility with the docker-compose entrypoint.
+func parseFlags(args []string) (cliFlags, error) {
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
 	}
 
-	slog.Info("server stopped")
+	var flags cliFlags
+	fs := flag.NewFlagSet("total", flag.ContinueOnError)
+	fs.BoolVar(&flags.Strict, "strict", false, "fail startup when the RPC protocol version is unsupported")
+	fs.BoolVar(&flags.StrictMarkets, "strict-markets", false, "hide markets with missing or invalid metadata, unknown WASM builds or undecodable state from public lists")
+	fs.BoolVar(&flags.WarmupBlock, "warmup-block", false, "start serving HTTP only after the startup cache warmup finished or timed out")
+	fs.StringVar(&flags.SealKeystore, "seal-keystore", "", "write ORACLE_SECRET_KEY encrypted with ORACLE_KEYSTORE_PASSPHRASE to this file and exit")
+	if err := fs.Parse(args); err != nil {
+		return cliFlags{}, fmt.Errorf("failed to parse flags: %w", err)
+	}
+	return flags, nil
+}
+
+// newOracleSigner creates the server-side oracle signer from ORACLE_SECRET_KEY or
+// ORACLE_KEYSTORE_FILE. It returns nil, disabling one-click oracle signing, when
+// neither is set.
+func newOracleSigner(cfg appConfig) (*service.OracleSigner, error) {
+	var signer *service.OracleSigner
+	var err error
+	switch {
+	case cfg.OracleSecretKey != "" && cfg.OracleKeystoreFile != "":
+		return nil, errors.New("set only one of ORACLE_SECRET_KEY and ORACLE_KEYSTORE_FILE")
+	case cfg.OracleSecretKey != "":
+		signer, err = service.NewOracleSigner(cfg.OracleSecretKey, cfg.OraclePublicKey, cfg.NetworkConfig.NetworkPassphrase)
+	case cfg.OracleKeystoreFile != "":
+		signer, err = service.LoadOracleSigner(cfg.OracleKeystoreFile, cfg.OracleKeystorePass, cfg.OraclePublicKey, cfg.NetworkConfig.NetworkPassphrase)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load oracle signing key: %w", err)
+	}
+	slog.Warn("server-side oracle signing enabled: the signed-in oracle can resolve, withdraw and deploy in one click", "oracle", signer.Address())
+	return signer, nil
+}
+
+// sealKeystore writes ORACLE_SECRET_KEY encrypted with ORACLE_KEYSTORE_PASSPHRASE to
+// path, for use as ORACLE_KEYSTORE_FILE.
+func sealKeystore(cfg appConfig, path string) error {
+	data, err := service.SealOracleKeystore(cfg.OracleSecretKey, cfg.OracleKeystorePass)
+	if err != nil {
+		return fmt.Errorf("failed to seal oracle keystore: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write oracle keystore: %w", err)
+	}
+	slog.Info("oracle keystore written; set ORACLE_KEYSTORE_FILE and unset ORACLE_SECRET_KEY", "path", path)
 	return nil
 }
 
+// checkRPCProtocol compares the RPC protocol version against the supported range.
+func checkRPCProtocol(sorobanClient *soroban.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := sorobanClient.GetVersionInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get RPC version info: %w", err)
+	}
+	slog.Info("soroban RPC version",
+		"version", info.Version,
+		"protocol", info.ProtocolVersion,
+		"captive_core", info.CaptiveCoreVersion,
+	)
+	return soroban.CheckProtocolVersion(info.ProtocolVersion)
+}
+
 // appConfig holds all application configuration.
 type appConfig struct {
-	Port            string
-	LogLevel        string
-	Network         string
-	NetworkConfig   config.NetworkConfig
-	OraclePublicKey string
-	FactoryContract string
-	PinataAPIKey    string
-	PinataAPISecret string
+	Port                string
+	LogLevel            string
+	AccessLog           string
+	AccessLogMaxSizeMB  int
+	AccessLogMaxBackups int
+	DebugSampleRate     int
+	Network             string
+	NetworkConfig       config.NetworkConfig
+	SorobanRPCURLs      []string // SOROBAN_RPC_URLS; empty uses NetworkConfig.SorobanRPCURL
+	OraclePublicKey     string
+	FactoryContract     string
+	SimulationAccount   string
+	PinataAPIKey        string
+	PinataAPISecret     string
+	IPFSGateways        []string // IPFS_GATEWAYS; empty uses config.DefaultIPFSGateway
+	AdminToken          string
+	QuoteTokenSecret    string
+	AuthSigningSeed     string
+	AuthHomeDomain      string
+	OracleSecretKey     string
+	OracleKeystoreFile  string
+	OracleKeystorePass  string
+	CommentsFile        string
+	ReferralsFile       string
+	AlertsFile          string
+	EmailSubsFile       string
+	ShareLinksFile      string
+	RuleTemplatesFile   string
+	SeriesFile          string
+	RolloverFile        string
+	DeployBatchesFile   string
+	AuditLogFile        string
+	ClaimsFile          string
+	StatsFile           string
+	PriceHistoryFile    string
+	PriceRetention      service.PriceRetention
+	ExportTarget        string // EXPORT_TARGET, directory or s3://bucket/prefix; empty disables the export job
+	BackupTarget        string // BACKUP_TARGET, s3://bucket/prefix; empty disables backups
+	BackupRetention     time.Duration
+	S3                  s3.Config
+	ProfilesFile        string
+	PaperTradesFile     string
+	PaperBalance        float64 // PAPER_BALANCE, virtual EURMTL per paper account; 0 disables paper trading
+	WasmVersionsFile    string
+	LeaderLockFile      string
+	TxRateLimit         int
+	TradeLimits         service.TradeLimits
+	AnomalyNotify       string // ANOMALY_NOTIFY, channel:target
+	AnomalyThresholds   service.AnomalyThresholds
+	NotifyChannels      []string
+	TelegramBotToken    string
+	DiscordPublicKey    string
+	SMTP                notify.SMTPConfig
+	PublicURL           string
+	ArchiveAfterDays    int
+	ClaimPeriod         time.Duration
+	TxTimeout           time.Duration
+	ShutdownTimeout     time.Duration
+	HorizonTimeout      time.Duration
+	SorobanTimeout      time.Duration
+	SorobanDebugCapture int
+	IPFSTimeout         time.Duration
+	S3Timeout           time.Duration
+	WarmupConcurrency   int
+	WarmupTimeout       time.Duration
+	HTTPTransport       httpclient.TransportConfig
+	SorobanTLS          backendTLS
+	IPFSGatewayTLS      backendTLS
+}
+
+// backendTLS holds the TLS trust settings of one backend, e.g. a self-hosted RPC
+// node behind a private CA. Other outbound clients always use the system roots.
+type backendTLS struct {
+	CABundle           string // PEM file trusted in addition to the system roots
+	InsecureSkipVerify bool   // Disables TLS verification; only for internal endpoints
 }
 
 // parseConfig reads configuration from environment variables.
-func parseConfig() appConfig {
+func parseConfig() (appConfig, error) {
 	network := strings.ToLower(getEnv("NETWORK", "testnet"))
 
-	return appConfig{
-		Port:            getEnv("PORT", config.DefaultPort),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
-		Network:         network,
-		NetworkConfig:   config.GetNetworkConfig(network),
-		OraclePublicKey: getEnv("ORACLE_PUBLIC_KEY", ""),
-		FactoryContract: getEnv("MARKET_FACTORY_CONTRACT", ""),
-		PinataAPIKey:    getEnv("PINATA_API_KEY", ""),
-		PinataAPISecret: getEnv("PINATA_API_SECRET", ""),
+	var errs []error
+	duration := func(key string, defaultValue time.Duration) time.Duration {
+		d, err := getEnvDuration(key, defaultValue)
+		errs = append(errs, err)
+		return d
+	}
+	integer := func(key string, defaultValue int) int {
+		n, err := getEnvInt(key, defaultValue)
+		errs = append(errs, err)
+		return n
+	}
+	number := func(key string, defaultValue float64) float64 {
+		f, err := getEnvFloat(key, defaultValue)
+		errs = append(errs, err)
+		return f
+	}
+	boolean := func(key string, defaultValue bool) bool {
+		b, err := getEnvBool(key, defaultValue)
+		errs = append(errs, err)
+		return b
+	}
+	urls := func(key string) []string {
+		u, err := getEnvURLs(key)
+		errs = append(errs, err)
+		return u
+	}
+
+	cfg := appConfig{
+		Port:                getEnv("PORT", config.DefaultPort),
+		LogLevel:            getEnv("LOG_LEVEL", "info"),
+		AccessLog:           getEnv("ACCESS_LOG", ""),
+		AccessLogMaxSizeMB:  integer("ACCESS_LOG_MAX_SIZE_MB", 100),
+		AccessLogMaxBackups: integer("ACCESS_LOG_MAX_BACKUPS", 5),
+		DebugSampleRate:     integer("LOG_DEBUG_SAMPLE_RATE", 1),
+		Network:             network,
+		NetworkConfig:       config.GetNetworkConfig(network),
+		SorobanRPCURLs:      urls("SOROBAN_RPC_URLS"),
+		OraclePublicKey:     getEnv("ORACLE_PUBLIC_KEY", ""),
+		FactoryContract:     getEnv("MARKET_FACTORY_CONTRACT", ""),
+		SimulationAccount:   getEnv("SIMULATION_ACCOUNT", ""),
+		PinataAPIKey:        getEnv("PINATA_API_KEY", ""),
+		PinataAPISecret:     getEnv("PINATA_API_SECRET", ""),
+		IPFSGateways:        urls("IPFS_GATEWAYS"),
+		AdminToken:          getEnv("ADMIN_TOKEN", ""),
+		QuoteTokenSecret:    getEnv("QUOTE_TOKEN_SECRET", ""),
+		AuthSigningSeed:     getEnv("AUTH_SIGNING_SEED", ""),
+		AuthHomeDomain:      getEnv("AUTH_HOME_DOMAIN", "localhost"),
+		OracleSecretKey:     getEnv("ORACLE_SECRET_KEY", ""),
+		OracleKeystoreFile:  getEnv("ORACLE_KEYSTORE_FILE", ""),
+		OracleKeystorePass:  getEnv("ORACLE_KEYSTORE_PASSPHRASE", ""),
+		CommentsFile:        getEnv("COMMENTS_FILE", ""),
+		ReferralsFile:       getEnv("REFERRALS_FILE", ""),
+		AlertsFile:          getEnv("ALERTS_FILE", ""),
+		EmailSubsFile:       getEnv("EMAIL_SUBSCRIPTIONS_FILE", ""),
+		ShareLinksFile:      getEnv("SHARE_LINKS_FILE", ""),
+		RuleTemplatesFile:   getEnv("RULE_TEMPLATES_FILE", ""),
+		SeriesFile:          getEnv("SERIES_FILE", ""),
+		DeployBatchesFile:   getEnv("DEPLOY_BATCHES_FILE", ""),
+		AuditLogFile:        getEnv("AUDIT_LOG_FILE", ""),
+		RolloverFile:        getEnv("ROLLOVER_FILE", ""),
+		ClaimsFile:          getEnv("CLAIMS_FILE", ""),
+		StatsFile:           getEnv("STATS_FILE", ""),
+		PriceHistoryFile:    getEnv("PRICE_HISTORY_FILE", ""),
+		PriceRetention: service.PriceRetention{
+			Raw:    duration("PRICE_RAW_RETENTION", service.DefaultPriceRetention.Raw),
+			Hourly: duration("PRICE_HOURLY_RETENTION", service.DefaultPriceRetention.Hourly),
+			Daily:  duration("PRICE_DAILY_RETENTION", service.DefaultPriceRetention.Daily),
+		},
+		ProfilesFile:     getEnv("PROFILES_FILE", ""),
+		PaperTradesFile:  getEnv("PAPER_TRADES_FILE", ""),
+		PaperBalance:     number("PAPER_BALANCE", service.DefaultPaperBalance),
+		WasmVersionsFile: getEnv("CONTRACT_VERSIONS_FILE", ""),
+		LeaderLockFile:   getEnv("LEADER_LOCK_FILE", ""),
+		TxRateLimit:      integer("TX_RATE_LIMIT", 30),
+		NotifyChannels:   strings.Split(strings.ToLower(getEnv("NOTIFY_CHANNELS", "telegram,webhook,discord,email")), ","),
+		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
+		DiscordPublicKey: getEnv("DISCORD_PUBLIC_KEY", ""),
+		SMTP: notify.SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     integer("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", ""),
+		},
+		PublicURL:           strings.TrimSuffix(getEnv("PUBLIC_URL", ""), "/"),
+		ArchiveAfterDays:    integer("MARKET_ARCHIVE_AFTER_DAYS", 30),
+		ClaimPeriod:         duration("CLAIM_PERIOD", service.DefaultClaimPeriod),
+		TxTimeout:           duration("TX_TIMEOUT", 0),
+		ShutdownTimeout:     duration("SHUTDOWN_TIMEOUT", 10*time.Second),
+		HorizonTimeout:      duration("HORIZON_TIMEOUT", httpclient.DefaultTimeout),
+		SorobanTimeout:      duration("SOROBAN_TIMEOUT", httpclient.DefaultTimeout),
+		SorobanDebugCapture: integer("SOROBAN_DEBUG_CAPTURE", 0),
+		IPFSTimeout:         duration("IPFS_TIMEOUT", httpclient.DefaultTimeout),
+		S3Timeout:           duration("S3_TIMEOUT", httpclient.DefaultTimeout),
+		WarmupConcurrency:   integer("WARMUP_CONCURRENCY", service.DefaultWarmupConcurrency),
+		WarmupTimeout:       duration("WARMUP_TIMEOUT", 5*time.Minute),
+		TradeLimits: service.TradeLimits{
+			MaxTradeTokens:    number("MAX_TRADE_TOKENS", 0),
+			MaxTradeCost:      number("MAX_TRADE_COST", 0),
+			MaxPositionTokens: number("MAX_POSITION_TOKENS", 0),
+		},
+		AnomalyNotify: getEnv("ANOMALY_NOTIFY", ""),
+		AnomalyThresholds: service.AnomalyThresholds{
+			VolumeWindow: duration("ANOMALY_VOLUME_WINDOW", service.DefaultAnomalyThresholds.VolumeWindow),
+			VolumeShare:  number("ANOMALY_VOLUME_SHARE", service.DefaultAnomalyThresholds.VolumeShare),
+			MinVolume:    number("ANOMALY_MIN_VOLUME", service.DefaultAnomalyThresholds.MinVolume),
+			SwingWindow:  duration("ANOMALY_SWING_WINDOW", service.DefaultAnomalyThresholds.SwingWindow),
+			SwingPoints:  number("ANOMALY_SWING_POINTS", service.DefaultAnomalyThresholds.SwingPoints),
+			LoopWindow:   duration("ANOMALY_LOOP_WINDOW", service.DefaultAnomalyThresholds.LoopWindow),
+			LoopTrades:   integer("ANOMALY_LOOP_TRADES", service.DefaultAnomalyThresholds.LoopTrades),
+		},
+		ExportTarget:    getEnv("EXPORT_TARGET", ""),
+		BackupTarget:    getEnv("BACKUP_TARGET", ""),
+		BackupRetention: duration("BACKUP_RETENTION", service.DefaultBackupRetention),
+		S3: s3.Config{
+			Endpoint:        getEnv("S3_ENDPOINT", ""),
+			Region:          getEnv("S3_REGION", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		},
+		HTTPTransport: httpclient.TransportConfig{
+			MaxIdleConns:        integer("HTTP_MAX_IDLE_CONNS", httpclient.DefaultMaxIdleConns),
+			MaxIdleConnsPerHost: integer("HTTP_MAX_IDLE_CONNS_PER_HOST", httpclient.DefaultMaxIdleConnsPerHost),
+			IdleConnTimeout:     duration("HTTP_IDLE_CONN_TIMEOUT", httpclient.DefaultIdleConnTimeout),
+			KeepAlive:           duration("HTTP_KEEP_ALIVE", httpclient.DefaultKeepAlive),
+		},
+		SorobanTLS: backendTLS{
+			CABundle:           getEnv("SOROBAN_CA_BUNDLE", ""),
+			InsecureSkipVerify: boolean("SOROBAN_TLS_INSECURE_SKIP_VERIFY", false),
+		},
+		IPFSGatewayTLS: backendTLS{
+			CABundle:           getEnv("IPFS_GATEWAY_CA_BUNDLE", ""),
+			InsecureSkipVerify: boolean("IPFS_GATEWAY_TLS_INSECURE_SKIP_VERIFY", false),
+		},
+	}
+	if err := errors.Join(errs...); err != nil {
+		return appConfig{}, err
 	}
+	return cfg, nil
+}
+
+// transport returns the shared connection pool settings with the TLS trust settings
+// of one backend.
+func (c appConfig) transport(tls backendTLS) httpclient.TransportConfig {
+	t := c.HTTPTransport
+	t.CABundle = tls.CABundle
+	t.InsecureSkipVerify = tls.InsecureSkipVerify
+	return t
+}
+
+// newIPFSClient creates the IPFS client: Pinata requests use the default TLS
+// settings, metadata fetches from the configured gateways those of IPFS_GATEWAY_*.
+func newIPFSClient(cfg appConfig) (*ipfs.Client, error) {
+	pinataHTTP, err := httpclient.New(cfg.IPFSTimeout, cfg.HTTPTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	gatewayHTTP, err := httpclient.New(cfg.IPFSTimeout, cfg.transport(cfg.IPFSGatewayTLS))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPFS gateway HTTP client: %w", err)
+	}
+	client := ipfs.NewClient(cfg.PinataAPIKey, cfg.PinataAPISecret, pinataHTTP)
+	client.SetGatewayClient(gatewayHTTP)
+	if len(cfg.IPFSGateways) > 0 {
+		gateways := make([]string, len(cfg.IPFSGateways))
+		for i, g := range cfg.IPFSGateways {
+			// The CID is appended to the gateway URL
+			gateways[i] = strings.TrimSuffix(g, "/") + "/"
+		}
+		client.SetGateways(gateways...)
+	}
+	return client, nil
+}
+
+// sorobanEndpoints returns the RPC URLs to use, in order of preference.
+func (c appConfig) sorobanEndpoints() []string {
+	if len(c.SorobanRPCURLs) > 0 {
+		return c.SorobanRPCURLs
+	}
+	return []string{c.NetworkConfig.SorobanRPCURL}
+}
+
+// notifyTransports creates the notification transports listed in NOTIFY_CHANNELS.
+// Telegram and email are skipped, with a log line, until their settings are present.
+func notifyTransports(cfg appConfig, httpClient *http.Client) ([]notify.Transport, error) {
+	var transports []notify.Transport
+	seen := make(map[string]bool)
+	for _, name := range cfg.NotifyChannels {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch notify.Channel(name) {
+		case "":
+		case notify.ChannelTelegram:
+			if cfg.TelegramBotToken == "" {
+				slog.Info("TELEGRAM_BOT_TOKEN not set, Telegram alerts disabled")
+				continue
+			}
+			transports = append(transports, notify.NewTelegram(cfg.TelegramBotToken, httpClient))
+		case notify.ChannelWebhook:
+			transports = append(transports, notify.NewWebhook(httpClient))
+		case notify.ChannelDiscord:
+			transports = append(transports, notify.NewDiscord(httpClient))
+		case notify.ChannelEmail:
+			if cfg.SMTP.Host == "" {
+				slog.Info("SMTP_HOST not set, email alerts disabled")
+				continue
+			}
+			email, err := notify.NewEmail(cfg.SMTP)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SMTP configuration: %w", err)
+			}
+			transports = append(transports, email)
+		default:
+			return nil, fmt.Errorf("NOTIFY_CHANNELS: unknown channel %q", name)
+		}
+	}
+	return transports, nil
 }
 
 // getEnv returns environment variable value or default.
@@ -211,36 +1138,71 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// warmupIPFSCache pre-fetches market metadata into cache.
-func warmupIPFSCache(factoryService *service.FactoryService, ipfsClient *ipfs.Client) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+// getEnvDuration parses a non-negative Go duration (e.g. "30s", "5m") from the environment.
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration (e.g. 30s, 5m), got %q", key, value)
+	}
+	return d, nil
+}
 
-	markets, err := factoryService.ListMarkets(ctx)
-	if err != nil {
-		slog.Warn("failed to list markets for cache warmup", "error", err)
-		return
+// getEnvInt parses a non-negative integer from the environment.
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
 	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+	}
+	return n, nil
+}
 
-	if len(markets) == 0 {
-		return
+// getEnvFloat parses a non-negative number from the environment.
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%s must be a non-negative number, got %q", key, value)
 	}
+	return f, nil
+}
 
-	states, err := factoryService.GetMarketStates(ctx, markets)
+// getEnvBool parses a boolean (true/false/1/0) from the environment.
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
 	if err != nil {
-		slog.Warn("failed to get market states for cache warmup", "error", err)
-		return
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
 	}
+	return b, nil
+}
 
-	var hashes []string
-	for _, s := range states {
-		if s.MetadataHash != "" {
-			hashes = append(hashes, s.MetadataHash)
+// getEnvURLs parses a comma-separated list of http(s) URLs from the environment.
+func getEnvURLs(key string) ([]string, error) {
+	var urls []string
+	for _, raw := range strings.Split(os.Getenv(key), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
 		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be a comma-separated list of http(s) URLs, got %q", key, raw)
+		}
+		urls = append(urls, raw)
 	}
-
-	if len(hashes) > 0 {
-		slog.Info("warming up IPFS cache", "count", len(hashes))
-		ipfsClient.Warmup(hashes)
-	}
+	return urls, nil
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+)
+
+// txReplayTimeout bounds the RPC calls of a replay from the command line.
+const txReplayTimeout = 30 * time.Second
+
+// runTxCommand runs "total tx <subcommand>". Only "replay" exists: it re-simulates a
+// failed transaction against current state and prints the likely cause.
+func runTxCommand(cfg appConfig, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "replay" {
+		return fmt.Errorf("usage: total tx replay --hash <hex> [--json]")
+	}
+
+	var hash string
+	var asJSON bool
+	fs := flag.NewFlagSet("total tx replay", flag.ContinueOnError)
+	fs.StringVar(&hash, "hash", "", "hash of the failed transaction")
+	fs.BoolVar(&asJSON, "json", false, "print the replay as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if hash == "" {
+		return fmt.Errorf("usage: total tx replay --hash <hex> [--json]")
+	}
+
+	replayer, err := newTxReplayer(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), txReplayTimeout)
+	defer cancel()
+	replay, err := replayer.Replay(ctx, strings.ToLower(hash))
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(replay)
+	}
+	printReplay(out, replay)
+	return nil
+}
+
+// newTxReplayer creates the RPC clients a replay needs, without the rest of the app.
+func newTxReplayer(cfg appConfig) (*service.TxReplayer, error) {
+	sorobanClient, txBuilder, err := newNetworkClients(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewTxReplayer(sorobanClient, txBuilder), nil
+}
+
+// newNetworkClients creates the RPC client and transaction builder support commands
+// run against.
+func newNetworkClients(cfg appConfig) (*soroban.Client, *stellar.Builder, error) {
+	horizonHTTP, err := httpclient.New(cfg.HorizonTimeout, cfg.HTTPTransport)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	sorobanHTTP, err := httpclient.New(cfg.SorobanTimeout, cfg.transport(cfg.SorobanTLS))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Soroban RPC HTTP client: %w", err)
+	}
+	stellarClient, err := stellar.NewHorizonClient(cfg.NetworkConfig.HorizonURL, cfg.NetworkConfig.NetworkPassphrase, horizonHTTP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Stellar client: %w", err)
+	}
+	sorobanClient := soroban.NewClientWithEndpoints(cfg.sorobanEndpoints(), sorobanHTTP)
+	txBuilder := stellar.NewBuilder(stellarClient, cfg.NetworkConfig.NetworkPassphrase, config.DefaultBaseFee, cfg.TxTimeout, sorobanClient)
+	return sorobanClient, txBuilder, nil
+}
+
+func printReplay(out io.Writer, r *service.TxReplay) {
+	fmt.Fprintf(out, "transaction  %s\n", r.Hash)
+	fmt.Fprintf(out, "call         %s.%s from %s\n", r.ContractID, r.Function, r.Source)
+	printReplayRun(out, "original", r.Original)
+	printReplayRun(out, "replay", r.Replay)
+	fmt.Fprintf(out, "reproduced   %v\n", r.Reproduced)
+	fmt.Fprintf(out, "cause        %s\n", r.Cause)
+	fmt.Fprintf(out, "             %s\n", r.Explanation)
+}
+
+func printReplayRun(out io.Writer, name string, run service.ReplayRun) {
+	status := "failed"
+	if run.Succeeded {
+		status = "succeeds"
+	}
+	fmt.Fprintf(out, "%-12s %s at ledger %d", name, status, run.Ledger)
+	if run.Error != "" {
+		fmt.Fprintf(out, ": %s", run.Error)
+	}
+	if run.ContractError != "" {
+		fmt.Fprintf(out, " [%s]", run.ContractError)
+	}
+	if run.ReturnValue != "" {
+		fmt.Fprintf(out, " → %s", run.ReturnValue)
+	}
+	fmt.Fprintln(out)
+	for _, line := range run.Trace {
+		fmt.Fprintf(out, "               %s\n", line)
+	}
+}
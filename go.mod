@@ -3,6 +3,7 @@ module github.com/mtlprog/total
 go 1.24.0
 
 require (
+	github.com/joho/godotenv v1.5.1
 	github.com/samber/hot v0.11.0
 	github.com/stellar/go-stellar-sdk v0.1.0
 )
@@ -15,7 +16,6 @@ require (
 	github.com/go-chi/chi v4.1.2+incompatible // indirect
 	github.com/go-errors/errors v1.5.1 // indirect
 	github.com/gorilla/schema v1.4.1 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/manucorporat/sse v0.0.0-20160126180136-ee05b128a739 // indirect
@@ -0,0 +1,43 @@
+package cachestats
+
+import "sync/atomic"
+
+// Counter counts cache hits and misses. The zero value is ready to use.
+type Counter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Hit records a cache hit.
+func (c *Counter) Hit() {
+	c.hits.Add(1)
+}
+
+// Miss records a cache miss.
+func (c *Counter) Miss() {
+	c.misses.Add(1)
+}
+
+// Stats returns a snapshot of the counters.
+func (c *Counter) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// Stats is a point-in-time snapshot of a Counter.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// Lookups returns the total number of cache lookups.
+func (s Stats) Lookups() uint64 {
+	return s.Hits + s.Misses
+}
+
+// HitRate returns the fraction of lookups that were hits, or 0 before any lookup.
+func (s Stats) HitRate() float64 {
+	if s.Lookups() == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Lookups())
+}
@@ -106,32 +106,6 @@ func RenderPriceChart(points []model.PricePoint, width, height int) string {
 	return sb.String()
 }
 
-// RenderSimpleBar creates a simple horizontal bar chart for YES/NO prices.
-func RenderSimpleBar(priceYes, priceNo float64, width int) string {
-	if width <= 0 {
-		width = 50
-	}
-
-	yesWidth := int(priceYes * float64(width))
-	noWidth := width - yesWidth
-
-	var sb strings.Builder
-
-	// YES bar
-	sb.WriteString(fmt.Sprintf("YES %5.1f%% ", priceYes*100))
-	sb.WriteString(strings.Repeat("█", yesWidth))
-	sb.WriteString(strings.Repeat("░", noWidth))
-	sb.WriteString("\n")
-
-	// NO bar
-	sb.WriteString(fmt.Sprintf("NO  %5.1f%% ", priceNo*100))
-	sb.WriteString(strings.Repeat("░", yesWidth))
-	sb.WriteString(strings.Repeat("█", noWidth))
-	sb.WriteString("\n")
-
-	return sb.String()
-}
-
 // samplePoints samples price points to fit a given width.
 func samplePoints(points []model.PricePoint, targetCount int) []model.PricePoint {
 	if len(points) <= targetCount {
@@ -0,0 +1,357 @@
+package chart
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	SVGWidth  = 600
+	SVGHeight = 200
+
+	svgPadLeft   = 48
+	svgPadRight  = 8
+	svgPadTop    = 8
+	svgPadBottom = 20
+	svgBarHeight = 18
+	svgBarGap    = 6
+	svgBarLabel  = 140 // Width reserved for bar labels
+)
+
+// SupplyPoint is the outstanding YES and NO token supply after a trade.
+type SupplyPoint struct {
+	Timestamp time.Time
+	YesSold   float64
+	NoSold    float64
+}
+
+// Bar is one row of a stacked YES/NO bar chart.
+type Bar struct {
+	Label string
+	Yes   float64
+	No    float64
+}
+
+// RenderSupplySVG renders YES and NO token supply over time as an inline SVG step chart.
+// Colors come from the page's --yes / --no CSS variables. Returns "" for fewer than two points.
+func RenderSupplySVG(points []SupplyPoint) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+
+	start, end := points[0].Timestamp, points[len(points)-1].Timestamp
+	span := end.Sub(start).Seconds()
+	maxSupply := 0.0
+	for _, p := range points {
+		maxSupply = max(maxSupply, p.YesSold, p.NoSold)
+	}
+	if maxSupply == 0 {
+		maxSupply = 1
+	}
+
+	plotW := float64(SVGWidth - svgPadLeft - svgPadRight)
+	plotH := float64(SVGHeight - svgPadTop - svgPadBottom)
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return svgPadLeft
+		}
+		return svgPadLeft + t.Sub(start).Seconds()/span*plotW
+	}
+	y := func(v float64) float64 {
+		return svgPadTop + plotH - v/maxSupply*plotH
+	}
+
+	// Supply changes only at trades, so draw steps rather than slopes.
+	line := func(value func(SupplyPoint) float64) string {
+		var sb strings.Builder
+		for i, p := range points {
+			if i > 0 {
+				fmt.Fprintf(&sb, "%.1f,%.1f ", x(p.Timestamp), y(value(points[i-1])))
+			}
+			fmt.Fprintf(&sb, "%.1f,%.1f ", x(p.Timestamp), y(value(p)))
+		}
+		return strings.TrimSpace(sb.String())
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="YES and NO tokens outstanding over time" style="font-family: var(--font); font-size: 10px;">`, SVGWidth, SVGHeight)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" style="stroke: var(--border-mid);"/>`, svgPadLeft, svgPadTop, svgPadLeft, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" style="stroke: var(--border-mid);"/>`, svgPadLeft, svgPadTop+plotH, SVGWidth-svgPadRight, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, svgPadLeft-4, svgPadTop+8, formatAmount(maxSupply))
+	fmt.Fprintf(&sb, `<text x="%d" y="%.1f" text-anchor="end" style="fill: var(--text-2);">0</text>`, svgPadLeft-4, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" style="fill: var(--text-2);">%s</text>`, svgPadLeft, SVGHeight-4, start.UTC().Format("01-02 15:04"))
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, SVGWidth-svgPadRight, SVGHeight-4, end.UTC().Format("01-02 15:04"))
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke-width="2" style="stroke: var(--yes);" points="%s"/>`, line(func(p SupplyPoint) float64 { return p.YesSold }))
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke-width="2" style="stroke: var(--no);" points="%s"/>`, line(func(p SupplyPoint) float64 { return p.NoSold }))
+	sb.WriteString(`</svg>`)
+
+	// Markup is built from numbers and escaped labels only.
+	return template.HTML(sb.String())
+}
+
+// RenderBarsSVG renders horizontal stacked YES/NO bars, scaled to the largest row.
+// Returns "" when there are no bars.
+func RenderBarsSVG(bars []Bar) template.HTML {
+	if len(bars) == 0 {
+		return ""
+	}
+
+	maxTotal := 0.0
+	for _, b := range bars {
+		maxTotal = max(maxTotal, b.Yes+b.No)
+	}
+	if maxTotal == 0 {
+		maxTotal = 1
+	}
+
+	height := len(bars)*(svgBarHeight+svgBarGap) - svgBarGap
+	plotW := float64(SVGWidth - svgBarLabel - svgPadRight - 56) // Leave room for the total label
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="Token holdings of the largest holders" style="font-family: var(--font); font-size: 10px;">`, SVGWidth, height)
+	for i, b := range bars {
+		top := i * (svgBarHeight + svgBarGap)
+		yesW := b.Yes / maxTotal * plotW
+		noW := b.No / maxTotal * plotW
+		fmt.Fprintf(&sb, `<text x="0" y="%d" style="fill: var(--text);">%s</text>`, top+13, html.EscapeString(b.Label))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%.1f" height="%d" style="fill: var(--yes);"/>`, svgBarLabel, top, yesW, svgBarHeight)
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%d" width="%.1f" height="%d" style="fill: var(--no);"/>`, svgBarLabel+yesW, top, noW, svgBarHeight)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d" style="fill: var(--text-2);">%s</text>`, svgBarLabel+yesW+noW+6, top+13, formatAmount(b.Yes+b.No))
+	}
+	sb.WriteString(`</svg>`)
+
+	// Markup is built from numbers and escaped labels only.
+	return template.HTML(sb.String())
+}
+
+// ProbabilityRow is one market of a probability chart.
+type ProbabilityRow struct {
+	Label string
+	Yes   float64 // YES probability, 0..1
+}
+
+// svgProbabilityLabel is the longest bar label, in characters, that fits svgBarLabel.
+const svgProbabilityLabel = 22
+
+// RenderProbabilitySVG renders each row's YES probability as a horizontal bar on a
+// common 0–100% scale, so related markets can be compared at a glance. Returns "" when
+// there are no rows.
+func RenderProbabilitySVG(rows []ProbabilityRow) template.HTML {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	height := len(rows)*(svgBarHeight+svgBarGap) - svgBarGap
+	plotW := float64(SVGWidth - svgBarLabel - svgPadRight - 40) // Leave room for the percentage
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="YES probability of each market" style="font-family: var(--font); font-size: 10px;">`, SVGWidth, height)
+	for i, row := range rows {
+		top := i * (svgBarHeight + svgBarGap)
+		p := math.Max(0, math.Min(1, row.Yes))
+		label := row.Label
+		if r := []rune(label); len(r) > svgProbabilityLabel {
+			label = string(r[:svgProbabilityLabel-1]) + "…"
+		}
+		fmt.Fprintf(&sb, `<text x="0" y="%d" style="fill: var(--text);">%s<title>%s</title></text>`, top+13, html.EscapeString(label), html.EscapeString(row.Label))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%.1f" height="%d" style="fill: var(--border-mid);"/>`, svgBarLabel, top, plotW, svgBarHeight)
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%.1f" height="%d" style="fill: var(--yes);"/>`, svgBarLabel, top, p*plotW, svgBarHeight)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d" style="fill: var(--text-2);">%.0f%%</text>`, svgBarLabel+plotW+6, top+13, p*100)
+	}
+	sb.WriteString(`</svg>`)
+
+	// Markup is built from numbers and escaped labels only.
+	return template.HTML(sb.String())
+}
+
+// VolumePoint is the collateral traded in one period.
+type VolumePoint struct {
+	Start  time.Time
+	Volume float64
+}
+
+// RenderVolumeSVG renders traded volume per period as an inline SVG column chart, one
+// column per point, oldest first. Returns "" when there are no points.
+func RenderVolumeSVG(points []VolumePoint) template.HTML {
+	if len(points) == 0 {
+		return ""
+	}
+
+	maxVolume := 0.0
+	for _, p := range points {
+		maxVolume = max(maxVolume, p.Volume)
+	}
+	if maxVolume == 0 {
+		maxVolume = 1
+	}
+
+	plotW := float64(SVGWidth - svgPadLeft - svgPadRight)
+	plotH := float64(SVGHeight - svgPadTop - svgPadBottom)
+	slot := plotW / float64(len(points))
+	barW := max(slot*0.8, 1)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="Traded volume over time" style="font-family: var(--font); font-size: 10px;">`, SVGWidth, SVGHeight)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" style="stroke: var(--border-mid);"/>`, svgPadLeft, svgPadTop+plotH, SVGWidth-svgPadRight, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, svgPadLeft-4, svgPadTop+8, formatAmount(maxVolume))
+	fmt.Fprintf(&sb, `<text x="%d" y="%.1f" text-anchor="end" style="fill: var(--text-2);">0</text>`, svgPadLeft-4, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" style="fill: var(--text-2);">%s</text>`, svgPadLeft, SVGHeight-4, points[0].Start.UTC().Format("2006-01-02"))
+	if len(points) > 1 {
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, SVGWidth-svgPadRight, SVGHeight-4, points[len(points)-1].Start.UTC().Format("2006-01-02"))
+	}
+	for i, p := range points {
+		h := p.Volume / maxVolume * plotH
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" style="fill: var(--yes);"><title>%s: %s</title></rect>`,
+			svgPadLeft+float64(i)*slot+(slot-barW)/2, svgPadTop+plotH-h, barW, h, p.Start.UTC().Format("2006-01-02"), formatAmount(p.Volume))
+	}
+	sb.WriteString(`</svg>`)
+
+	// Markup is built from numbers and dates only.
+	return template.HTML(sb.String())
+}
+
+// formatAmount formats a token amount compactly for axis labels.
+func formatAmount(v float64) string {
+	switch {
+	case v >= 1_000_000:
+		return fmt.Sprintf("%.1fM", v/1_000_000)
+	case v >= 10_000:
+		return fmt.Sprintf("%.0fk", v/1_000)
+	case v >= 100:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
+
+// CostPoint is the cost of buying Amount tokens of YES and of NO in one trade.
+type CostPoint struct {
+	Amount float64
+	Yes    float64
+	No     float64
+}
+
+// RenderCostCurveSVG renders the cost of buying increasing amounts of each outcome as a
+// standalone SVG document. Colors use the page's --yes / --no variables when inlined,
+// falling back to the dark theme when served as an image. Returns nil for fewer than
+// two points.
+func RenderCostCurveSVG(points []CostPoint) []byte {
+	if len(points) < 2 {
+		return nil
+	}
+
+	maxAmount := points[len(points)-1].Amount
+	maxCost := 0.0
+	for _, p := range points {
+		maxCost = max(maxCost, p.Yes, p.No)
+	}
+	if maxAmount <= 0 {
+		maxAmount = 1
+	}
+	if maxCost == 0 {
+		maxCost = 1
+	}
+
+	plotW := float64(SVGWidth - svgPadLeft - svgPadRight - 24) // Leave room for the line labels
+	plotH := float64(SVGHeight - svgPadTop - svgPadBottom)
+	x := func(v float64) float64 { return svgPadLeft + v/maxAmount*plotW }
+	y := func(v float64) float64 { return svgPadTop + plotH - v/maxCost*plotH }
+	line := func(value func(CostPoint) float64) string {
+		var sb strings.Builder
+		for _, p := range points {
+			fmt.Fprintf(&sb, "%.1f,%.1f ", x(p.Amount), y(value(p)))
+		}
+		return strings.TrimSpace(sb.String())
+	}
+	// Label the line ends, apart when the lines end close together.
+	last := points[len(points)-1]
+	yesLabel, noLabel := y(last.Yes)+3, y(last.No)+3
+	if math.Abs(yesLabel-noLabel) < 11 {
+		mid := (yesLabel + noLabel) / 2
+		if last.Yes >= last.No {
+			yesLabel, noLabel = mid-6, mid+6
+		} else {
+			yesLabel, noLabel = mid+6, mid-6
+		}
+	}
+	if top := min(yesLabel, noLabel); top < 10 {
+		yesLabel, noLabel = yesLabel+10-top, noLabel+10-top
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="Cost of buying YES and NO tokens by amount" style="font-family: var(--font, monospace); font-size: 10px;">`, SVGWidth, SVGHeight, SVGWidth, SVGHeight)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" style="stroke: var(--border-mid, #414868);"/>`, svgPadLeft, svgPadTop, svgPadLeft, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" style="stroke: var(--border-mid, #414868);"/>`, svgPadLeft, svgPadTop+plotH, svgPadLeft+plotW, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2, #6b7194);">%s</text>`, svgPadLeft-4, svgPadTop+8, formatAmount(maxCost))
+	fmt.Fprintf(&sb, `<text x="%d" y="%.1f" text-anchor="end" style="fill: var(--text-2, #6b7194);">0</text>`, svgPadLeft-4, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" style="fill: var(--text-2, #6b7194);">0 tokens</text>`, svgPadLeft, SVGHeight-4)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%d" text-anchor="end" style="fill: var(--text-2, #6b7194);">%s tokens</text>`, svgPadLeft+plotW, SVGHeight-4, formatAmount(maxAmount))
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke-width="2" style="stroke: var(--yes, #5de4c7);" points="%s"/>`, line(func(p CostPoint) float64 { return p.Yes }))
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke-width="2" style="stroke: var(--no, #f087bd);" points="%s"/>`, line(func(p CostPoint) float64 { return p.No }))
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" style="fill: var(--yes, #5de4c7);">YES</text>`, svgPadLeft+plotW+4, yesLabel)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" style="fill: var(--no, #f087bd);">NO</text>`, svgPadLeft+plotW+4, noLabel)
+	sb.WriteString(`</svg>`)
+	return []byte(sb.String())
+}
+
+// BandPoint is a value with its likely range at one step of a simulation.
+type BandPoint struct {
+	Step float64
+	Mid  float64 // Mean
+	Low  float64
+	High float64
+}
+
+// RenderBandSVG renders a mean line over its low–high band as an inline SVG, e.g. a
+// simulated price path across many runs. The scale includes 0; label names the chart
+// for screen readers. Returns "" for fewer than two points.
+func RenderBandSVG(points []BandPoint, label string) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+
+	lo, hi := 0.0, 0.0
+	for _, p := range points {
+		lo, hi = min(lo, p.Low, p.Mid), max(hi, p.High, p.Mid)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	first, last := points[0].Step, points[len(points)-1].Step
+	if last == first {
+		last = first + 1
+	}
+
+	plotW := float64(SVGWidth - svgPadLeft - svgPadRight)
+	plotH := float64(SVGHeight - svgPadTop - svgPadBottom)
+	x := func(v float64) float64 { return svgPadLeft + (v-first)/(last-first)*plotW }
+	y := func(v float64) float64 { return svgPadTop + plotH - (v-lo)/(hi-lo)*plotH }
+
+	var band, mid strings.Builder
+	for _, p := range points {
+		fmt.Fprintf(&band, "%.1f,%.1f ", x(p.Step), y(p.High))
+		fmt.Fprintf(&mid, "%.1f,%.1f ", x(p.Step), y(p.Mid))
+	}
+	for i := len(points) - 1; i >= 0; i-- {
+		fmt.Fprintf(&band, "%.1f,%.1f ", x(points[i].Step), y(points[i].Low))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="%s" style="font-family: var(--font); font-size: 10px;">`, SVGWidth, SVGHeight, html.EscapeString(label))
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" style="stroke: var(--border-mid);"/>`, svgPadLeft, svgPadTop, svgPadLeft, svgPadTop+plotH)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" style="stroke: var(--border-mid);"/>`, svgPadLeft, y(0), SVGWidth-svgPadRight, y(0))
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, svgPadLeft-4, svgPadTop+8, formatAmount(hi))
+	fmt.Fprintf(&sb, `<text x="%d" y="%.1f" text-anchor="end" style="fill: var(--text-2);">%s</text>`, svgPadLeft-4, svgPadTop+plotH, formatAmount(lo))
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" style="fill: var(--text-2);">%s</text>`, svgPadLeft, SVGHeight-4, formatAmount(first))
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" style="fill: var(--text-2);">%s</text>`, SVGWidth-svgPadRight, SVGHeight-4, formatAmount(last))
+	fmt.Fprintf(&sb, `<polygon points="%s" style="fill: var(--yes); fill-opacity: 0.2;"/>`, strings.TrimSpace(band.String()))
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke-width="2" style="stroke: var(--yes);" points="%s"/>`, strings.TrimSpace(mid.String()))
+	sb.WriteString(`</svg>`)
+
+	// Markup is built from numbers and an escaped label only.
+	return template.HTML(sb.String())
+}
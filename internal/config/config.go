@@ -19,6 +19,10 @@ const (
 	// IPFS configuration
 	DefaultIPFSGateway = "https://gateway.pinata.cloud/ipfs/"
 	PinataAPIURL       = "https://api.pinata.cloud/pinning/pinJSONToIPFS"
+	PinataFileAPIURL   = "https://api.pinata.cloud/pinning/pinFileToIPFS"
+
+	// Block explorer, followed by "<network>/tx/<hash>"
+	StellarExpertURL = "https://stellar.expert/explorer/"
 
 	// Market configuration
 	DefaultLiquidityParam = 100.0
@@ -0,0 +1,528 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/cachestats"
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/logger"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/scheduler"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/template"
+)
+
+// adminCookie holds the admin token for browser access to the dashboard.
+const adminCookie = "admin_token"
+
+// adminDashboardTimeout bounds the market lookups behind the pending resolutions list.
+const adminDashboardTimeout = 10 * time.Second
+
+// AdminHandler serves operator endpoints protected by a bearer token.
+// All admin routes respond 404 when no token is configured.
+type AdminHandler struct {
+	token          string
+	statusService  *service.StatusService
+	factoryService *service.FactoryService
+	eventService   *service.EventService
+	referrals      *service.ReferralService
+	stats          *service.StatsService
+	ipfsClient     *ipfs.Client
+	sorobanClient  *soroban.Client
+	replayer       *service.TxReplayer
+	scheduler      *scheduler.Scheduler
+	warmup         *service.WarmupService
+	anomalies      *service.AnomalyMonitor
+	tmpl           *template.Template
+	network        string
+	logger         *slog.Logger
+}
+
+// NewAdminHandler creates a new admin handler.
+func NewAdminHandler(
+	token string,
+	statusService *service.StatusService,
+	factoryService *service.FactoryService,
+	eventService *service.EventService,
+	referrals *service.ReferralService,
+	stats *service.StatsService,
+	ipfsClient *ipfs.Client,
+	sorobanClient *soroban.Client,
+	replayer *service.TxReplayer,
+	sched *scheduler.Scheduler,
+	warmup *service.WarmupService,
+	anomalies *service.AnomalyMonitor,
+	tmpl *template.Template,
+	network string,
+	logger *slog.Logger,
+) *AdminHandler {
+	if statusService == nil {
+		panic("NewAdminHandler: statusService must not be nil")
+	}
+	if stats == nil {
+		panic("NewAdminHandler: stats must not be nil")
+	}
+	if sched == nil {
+		panic("NewAdminHandler: scheduler must not be nil")
+	}
+	if tmpl == nil {
+		panic("NewAdminHandler: tmpl must not be nil")
+	}
+	return &AdminHandler{
+		token:          token,
+		statusService:  statusService,
+		factoryService: factoryService,
+		eventService:   eventService,
+		referrals:      referrals,
+		stats:          stats,
+		ipfsClient:     ipfsClient,
+		sorobanClient:  sorobanClient,
+		replayer:       replayer,
+		scheduler:      sched,
+		warmup:         warmup,
+		anomalies:      anomalies,
+		tmpl:           tmpl,
+		network:        network,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers admin routes.
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin", h.handleDashboard)
+	mux.HandleFunc("POST /admin/login", h.handleLogin)
+	mux.HandleFunc("POST /admin/logout", h.requireAdmin(h.handleLogout))
+	mux.HandleFunc("POST /admin/loglevel", h.requireAdmin(h.handleSetLogLevel))
+	mux.HandleFunc("GET /admin/referrals", h.requireAdmin(h.handleReferralReport))
+	mux.HandleFunc("GET /admin/rpc-calls", h.requireAdmin(h.handleRPCCalls))
+	mux.HandleFunc("GET /admin/tx-replay", h.requireAdmin(h.handleTxReplay))
+	mux.HandleFunc("GET /admin/contract-versions", h.requireAdmin(h.handleContractVersions))
+	mux.HandleFunc("POST /admin/contract-versions", h.requireAdmin(h.handleRegisterContractVersion))
+	mux.HandleFunc("GET /metrics", h.requireAdmin(h.handleMetrics))
+}
+
+// authorized reports whether the request carries the admin token,
+// either as "Authorization: Bearer <token>" or in the admin cookie.
+func (h *AdminHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		c, err := r.Cookie(adminCookie)
+		if err != nil {
+			return false
+		}
+		token = c.Value
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// requireAdmin rejects requests without a valid admin token.
+func (h *AdminHandler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !h.authorized(r) {
+			h.logger.Warn("rejected admin request", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleLogin checks the submitted token and stores it in the admin cookie.
+func (h *AdminHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := parseLimitedForm(r); err != nil {
+		status, msg := formErrorResponse(err)
+		http.Error(w, msg, status)
+		return
+	}
+
+	token := r.FormValue("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		h.logger.Warn("rejected admin login", "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		h.render(w, r, map[string]any{"LoginRequired": true, "LoginError": "Invalid token"})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Value:    token,
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// handleLogout clears the admin cookie.
+func (h *AdminHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Path:     "/admin",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// cacheView is a named cache hit-rate row on the dashboard.
+type cacheView struct {
+	Name string
+	cachestats.Stats
+}
+
+// pendingResolution is an unresolved market whose end date has passed.
+type pendingResolution struct {
+	ContractID string
+	Question   string
+	EndDate    time.Time
+	Overdue    time.Duration
+}
+
+// handleDashboard renders the operator dashboard, or a login form without a valid token.
+func (h *AdminHandler) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.authorized(r) {
+		h.render(w, r, map[string]any{"LoginRequired": true})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), adminDashboardTimeout)
+	defer cancel()
+
+	status := h.statusService.Status()
+	now := time.Now()
+
+	data := map[string]any{
+		"Status":             status,
+		"StatusHealthy":      status.Healthy(),
+		"Jobs":               h.scheduler.Jobs(),
+		"Caches":             h.cacheViews(),
+		"RecentErrors":       logger.RecentErrors(),
+		"LogLevel":           logger.Level().String(),
+		"PendingResolutions": h.pendingResolutions(ctx, now),
+		"Now":                now,
+	}
+	if versions := h.contractVersions(); versions != nil {
+		data["ContractVersions"] = versions.Usage()
+		data["Capabilities"] = service.ContractCapabilities()
+	}
+	if h.ipfsClient != nil {
+		health := h.ipfsClient.Health()
+		data["IPFS"] = health
+		data["IPFSHealthy"] = health.Healthy()
+		data["IPFSCanPin"] = h.ipfsClient.CanPin()
+		data["IPFSGateways"] = h.ipfsClient.Gateways()
+	}
+	if h.warmup != nil {
+		data["Warmup"] = h.warmup.Stats()
+	}
+	if h.factoryService != nil {
+		if q := h.factoryService.Quarantine(); q != nil {
+			data["StrictMarkets"] = true
+			data["Quarantined"] = q.Markets()
+		}
+	}
+	data["Divergences"] = h.stats.Divergences()
+	if h.anomalies != nil {
+		data["AnomalyMonitor"] = true
+		data["Anomalies"] = h.anomalies.Flagged()
+		data["AnomaliesChecked"] = h.anomalies.LastRun()
+	}
+
+	h.render(w, r, data)
+}
+
+// handleTxReplay re-simulates a failed transaction against current state and shows
+// how it differs from the original run, with the likely cause, for trade support.
+// API clients get the replay as JSON.
+func (h *AdminHandler) handleTxReplay(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		writeJSONError(w, "soroban client not configured", http.StatusServiceUnavailable)
+		return
+	}
+	hash := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("hash")))
+	data := map[string]any{"Hash": hash}
+	if hash == "" {
+		if wantsJSON(r) {
+			writeJSONError(w, "hash is required", http.StatusBadRequest)
+			return
+		}
+		h.renderPage(w, r, "admin_replay", data)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), adminDashboardTimeout)
+	defer cancel()
+	replay, err := h.replayer.Replay(ctx, hash)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, service.ErrInvalidTxHash), errors.Is(err, service.ErrReplayTxSucceeded), errors.Is(err, service.ErrInvalidTransaction):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrReplayTxNotFound):
+			status = http.StatusNotFound
+		}
+		if status == http.StatusBadGateway {
+			h.logger.Warn("transaction replay failed", "tx_hash", hash, "error", err)
+		}
+		if wantsJSON(r) {
+			writeJSONError(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		data["Error"] = err.Error()
+		h.renderPage(w, r, "admin_replay", data)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(replay)
+		return
+	}
+	data["Replay"] = replay
+	h.renderPage(w, r, "admin_replay", data)
+}
+
+// render fills in the common page fields and renders admin.html.
+func (h *AdminHandler) render(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	h.renderPage(w, r, "admin", data)
+}
+
+// renderPage fills in the common page fields and renders an admin template.
+func (h *AdminHandler) renderPage(w http.ResponseWriter, r *http.Request, name string, data map[string]any) {
+	data["ActiveNav"] = "admin"
+	data["Network"] = h.network
+	data["AccountID"] = accountIDFromCookie(r)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.tmpl.Render(w, name, data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminHandler) cacheViews() []cacheView {
+	var views []cacheView
+	if h.factoryService != nil {
+		views = append(views, cacheView{Name: "market state", Stats: h.factoryService.StateCacheStats()})
+	}
+	if h.eventService != nil {
+		views = append(views, cacheView{Name: "trade events", Stats: h.eventService.CacheStats()})
+	}
+	if h.ipfsClient != nil {
+		views = append(views, cacheView{Name: "IPFS metadata", Stats: h.ipfsClient.CacheStats()})
+	}
+	if h.sorobanClient != nil {
+		views = append(views, cacheView{Name: "simulations", Stats: h.sorobanClient.SimulationCacheStats()})
+	}
+	return views
+}
+
+// pendingResolutions lists unresolved markets past their metadata end date, most overdue first.
+// Lookup failures are logged and yield a partial list.
+func (h *AdminHandler) pendingResolutions(ctx context.Context, now time.Time) []pendingResolution {
+	if h.factoryService == nil || !h.factoryService.HasFactory() || h.ipfsClient == nil {
+		return nil
+	}
+
+	ids, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		h.logger.Warn("admin: failed to list markets", "error", err)
+		return nil
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		h.logger.Warn("admin: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		pending []pendingResolution
+	)
+	for _, s := range states {
+		if s.Resolved || s.MetadataHash == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var metadata model.MarketMetadata
+			if err := h.ipfsClient.GetJSON(ctx, s.MetadataHash, &metadata); err != nil {
+				h.logger.Warn("admin: failed to fetch metadata", "hash", s.MetadataHash, "error", err)
+				return
+			}
+			if metadata.EndDate.IsZero() || metadata.EndDate.After(now) {
+				return
+			}
+			mu.Lock()
+			pending = append(pending, pendingResolution{
+				ContractID: s.ContractID,
+				Question:   metadata.Question,
+				EndDate:    metadata.EndDate,
+				Overdue:    now.Sub(metadata.EndDate).Round(time.Minute),
+			})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(pending, func(a, b pendingResolution) int {
+		return a.EndDate.Compare(b.EndDate)
+	})
+	return pending
+}
+
+// handleSetLogLevel switches the log level at runtime, e.g. level=debug during an incident.
+func (h *AdminHandler) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	if err := parseLimitedForm(r); err != nil {
+		status, msg := formErrorResponse(err)
+		writeJSONError(w, msg, status)
+		return
+	}
+
+	level, err := logger.ParseLevelStrict(r.FormValue("level"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous := logger.Level()
+	logger.SetLevel(level)
+	h.logger.Warn("log level changed via admin endpoint", "from", previous, "to", level)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"level":    level.String(),
+		"previous": previous.String(),
+	})
+}
+
+// handleReferralReport returns per-referrer volume as JSON, e.g. for computing rewards.
+func (h *AdminHandler) handleReferralReport(w http.ResponseWriter, r *http.Request) {
+	report := []service.ReferrerStats{}
+	if h.referrals != nil {
+		report = h.referrals.Report()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(report)
+}
+
+// contractVersions returns the market contract version registry, nil when not configured.
+func (h *AdminHandler) contractVersions() *service.ContractVersionRegistry {
+	if h.factoryService == nil {
+		return nil
+	}
+	return h.factoryService.ContractVersions()
+}
+
+// handleContractVersions returns the registered market contract builds and how many
+// detected markets run each, unregistered builds included, as JSON.
+func (h *AdminHandler) handleContractVersions(w http.ResponseWriter, r *http.Request) {
+	versions := h.contractVersions()
+	if versions == nil {
+		writeJSONError(w, "contract version registry not configured", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(versions.Usage())
+}
+
+// handleRegisterContractVersion registers a market contract build: wasm_hash, version,
+// any number of capability values and an optional note. Browsers return to the
+// dashboard; API clients get the stored version as JSON.
+func (h *AdminHandler) handleRegisterContractVersion(w http.ResponseWriter, r *http.Request) {
+	versions := h.contractVersions()
+	if versions == nil {
+		writeJSONError(w, "contract version registry not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if err := parseLimitedForm(r); err != nil {
+		status, msg := formErrorResponse(err)
+		writeJSONError(w, msg, status)
+		return
+	}
+
+	v := service.ContractVersion{
+		WasmHash: r.FormValue("wasm_hash"),
+		Version:  r.FormValue("version"),
+		Note:     r.FormValue("note"),
+	}
+	for _, c := range r.Form["capability"] {
+		v.Capabilities = append(v.Capabilities, service.ContractCapability(c))
+	}
+	v, err := versions.Register(v)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidContractVersion) {
+			status = http.StatusBadRequest
+		} else {
+			h.logger.Error("failed to register contract version", "error", err)
+		}
+		writeJSONError(w, err.Error(), status)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+	http.Redirect(w, r, "/admin#contract-versions", http.StatusSeeOther)
+}
+
+// handleRPCCalls returns the recent Soroban JSON-RPC calls recorded with
+// SOROBAN_DEBUG_CAPTURE, newest first. ?method= and ?failed=1 filter the list.
+func (h *AdminHandler) handleRPCCalls(w http.ResponseWriter, r *http.Request) {
+	if h.sorobanClient == nil {
+		writeJSONError(w, "soroban client not configured", http.StatusServiceUnavailable)
+		return
+	}
+	calls := h.sorobanClient.Captured()
+	if calls == nil {
+		writeJSONError(w, "RPC capture is disabled (set SOROBAN_DEBUG_CAPTURE)", http.StatusNotFound)
+		return
+	}
+
+	method := r.URL.Query().Get("method")
+	failedOnly := r.URL.Query().Get("failed") == "1"
+	filtered := []soroban.CapturedCall{}
+	for _, c := range calls {
+		if (method == "" || c.Method == method) && (!failedOnly || c.Error != "") {
+			filtered = append(filtered, c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(filtered)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// alertsEnabled reports whether probability alerts can be created.
+func (h *MarketHandler) alertsEnabled() bool {
+	return h.alerts != nil
+}
+
+// alertChannelView is a delivery option of the alert form.
+type alertChannelView struct {
+	Channel     notify.Channel
+	Label       string
+	Placeholder string
+}
+
+// alertChannelLabels describe the built-in channels; others are listed by name.
+var alertChannelLabels = map[notify.Channel]alertChannelView{
+	notify.ChannelTelegram: {Label: "Telegram chat", Placeholder: "123456789 or @channel"},
+	notify.ChannelWebhook:  {Label: "Webhook (HTTPS POST)", Placeholder: "https://..."},
+	notify.ChannelDiscord:  {Label: "Discord webhook", Placeholder: "https://discord.com/api/webhooks/..."},
+	notify.ChannelEmail:    {Label: "Email", Placeholder: "you@example.com"},
+}
+
+// alertChannels returns the delivery options of the alert form, in the configured order.
+func (h *MarketHandler) alertChannels() []alertChannelView {
+	if !h.alertsEnabled() {
+		return nil
+	}
+	var views []alertChannelView
+	for _, ch := range h.alerts.Channels() {
+		view, ok := alertChannelLabels[ch]
+		if !ok {
+			view = alertChannelView{Label: string(ch)}
+		}
+		view.Channel = ch
+		views = append(views, view)
+	}
+	return views
+}
+
+// handleCreateAlert subscribes a chat, webhook or mailbox to a market's probability changes.
+func (h *MarketHandler) handleCreateAlert(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.alertsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Alerts are not configured")
+		return
+	}
+
+	contractID := r.PathValue("id")
+	threshold, err := strconv.ParseFloat(r.FormValue("threshold"), 64)
+	if err != nil {
+		h.writeError(w, r, service.ErrInvalidAlertThreshold, "contract_id", contractID)
+		return
+	}
+
+	rule, err := h.alerts.Create(r.Context(), service.CreateAlertRequest{
+		ContractID: contractID,
+		Kind:       service.AlertKind(r.FormValue("kind")),
+		Threshold:  threshold,
+		Channel:    notify.Channel(r.FormValue("channel")),
+		Target:     strings.TrimSpace(r.FormValue("target")),
+		ClientIP:   clientIP(r),
+	})
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	h.logger.Info("alert created", "alert_id", rule.ID, "contract_id", contractID, "kind", rule.Kind, "channel", rule.Channel)
+
+	h.renderAlert(w, r, map[string]any{"Rule": rule})
+}
+
+// handleAlertConfirmLink asks before confirming an email alert, so link scanners
+// and prefetchers following the emailed link do not confirm it.
+func (h *MarketHandler) handleAlertConfirmLink(w http.ResponseWriter, r *http.Request) {
+	h.renderAlert(w, r, map[string]any{
+		"ConfirmID":    r.PathValue("id"),
+		"ConfirmToken": r.URL.Query().Get("token"),
+	})
+}
+
+// handleConfirmAlert activates an email alert with its confirmation token.
+func (h *MarketHandler) handleConfirmAlert(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.alertsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Alerts are not configured")
+		return
+	}
+
+	id := r.PathValue("id")
+	rule, err := h.alerts.Confirm(id, r.FormValue("token"))
+	if err != nil {
+		h.writeError(w, r, err, "alert_id", id)
+		return
+	}
+	h.logger.Info("alert confirmed", "alert_id", rule.ID, "contract_id", rule.ContractID)
+	h.renderAlert(w, r, map[string]any{"Rule": rule, "Confirmed": true})
+}
+
+// handleUnsubscribeConfirm asks for confirmation before removing an alert, so link
+// previews and prefetchers following the unsubscribe URL do not remove it.
+func (h *MarketHandler) handleUnsubscribeConfirm(w http.ResponseWriter, r *http.Request) {
+	h.renderAlert(w, r, map[string]any{
+		"UnsubscribeID":    r.PathValue("id"),
+		"UnsubscribeToken": r.URL.Query().Get("token"),
+	})
+}
+
+// handleUnsubscribe removes an alert with its unsubscribe token.
+func (h *MarketHandler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.alertsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Alerts are not configured")
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.alerts.Delete(id, r.FormValue("token")); err != nil {
+		h.writeError(w, r, err, "alert_id", id)
+		return
+	}
+	h.renderAlert(w, r, map[string]any{"Unsubscribed": true})
+}
+
+func (h *MarketHandler) renderAlert(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	data["ActiveNav"] = "markets"
+	data["Network"] = h.networkName()
+	data["AccountID"] = accountIDFromCookie(r)
+	if err := h.tmpl.Render(w, "alert", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
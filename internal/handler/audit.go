@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// auditPageEntries bounds the entries shown on /oracle/audit; the exports have all.
+const auditPageEntries = 200
+
+// audit records an action of the signed-in oracle in the audit log. Builds anyone may
+// request are only recorded for the oracle's session, so visitors cannot flood the
+// log. Failures are logged only: the action itself already happened.
+func (h *MarketHandler) audit(r *http.Request, action string, details map[string]string) {
+	if h.auditLog == nil || !h.isOracleSession(r) {
+		return
+	}
+	if _, err := h.auditLog.Record(h.oraclePublicKey, action, details); err != nil {
+		h.logger.Error("failed to record audit entry", "action", action, "error", err)
+	}
+}
+
+// handleAuditLog renders the oracle audit log, newest first, with its head hash and
+// whether the chain verifies. It is public so anyone can check the oracle's history;
+// Accept: application/json returns every entry, oldest first.
+func (h *MarketHandler) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if h.auditLog == nil {
+		h.renderError(w, r, http.StatusNotFound, "The audit log is not enabled")
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	entries := h.auditLog.Entries()
+	if entries == nil {
+		entries = []service.AuditEntry{}
+	}
+	if wantsJSON(r) {
+		h.writeJSON(w, entries)
+		return
+	}
+
+	var verifyError string
+	if err := service.VerifyAuditChain(entries); err != nil {
+		verifyError = err.Error()
+	}
+	var head service.AuditEntry
+	if len(entries) > 0 {
+		head = entries[len(entries)-1]
+	}
+	recent := entries[max(0, len(entries)-auditPageEntries):]
+	slices.Reverse(recent)
+
+	data := map[string]any{
+		"Entries":     recent,
+		"Total":       len(entries),
+		"Head":        head,
+		"VerifyError": verifyError,
+		"LastAnchor":  h.auditLog.LastAnchor(),
+		"TZ":          userLocation(r),
+		"ActiveNav":   "oracle",
+		"Network":     h.networkName(),
+		"AccountID":   accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "oracle_audit", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleAuditExport downloads the whole audit log as JSON or CSV, by extension.
+func (h *MarketHandler) handleAuditExport(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.auditLog == nil {
+			h.renderError(w, r, http.StatusNotFound, "The audit log is not enabled")
+			return
+		}
+		entries := h.auditLog.Entries()
+		if entries == nil {
+			entries = []service.AuditEntry{}
+		}
+		filename := fmt.Sprintf("oracle-audit-%s.%s", time.Now().UTC().Format("20060102"), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		if format == "json" {
+			h.writeJSON(w, entries)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := service.WriteAuditCSV(w, entries); err != nil {
+			h.logger.Error("failed to write audit CSV", "error", err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// sessionCookie holds the session token issued after SEP-10 sign-in.
+const sessionCookie = "session"
+
+// sessionAccount returns the account signed in via SEP-10, or "" when there is no valid session.
+func (h *MarketHandler) sessionAccount(r *http.Request) string {
+	if h.authService == nil {
+		return ""
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || c.Value == "" {
+		return ""
+	}
+	account, err := h.authService.SessionAccount(c.Value)
+	if err != nil {
+		return ""
+	}
+	return account
+}
+
+// localReturnPath accepts only same-site paths as redirect targets.
+func localReturnPath(s string) string {
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || strings.Contains(s, `\`) {
+		return "/"
+	}
+	return s
+}
+
+// handleSignIn renders the SEP-10 sign-in page. With an account it shows a challenge
+// transaction to sign externally, like any other transaction built here.
+func (h *MarketHandler) handleSignIn(w http.ResponseWriter, r *http.Request) {
+	if h.authService == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Sign-in is not configured")
+		return
+	}
+
+	returnTo := localReturnPath(r.URL.Query().Get("return"))
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+	if account == "" {
+		account = accountIDFromCookie(r)
+	}
+
+	var challenge string
+	if account != "" {
+		var err error
+		challenge, err = h.authService.Challenge(account)
+		if err != nil {
+			h.writeError(w, r, err, "account", account)
+			return
+		}
+	}
+
+	data := map[string]any{
+		"Account":           account,
+		"Challenge":         challenge,
+		"Return":            returnTo,
+		"SessionAccount":    h.sessionAccount(r),
+		"NetworkPassphrase": h.networkPassphrase,
+		"ActiveNav":         "markets",
+		"Network":           h.networkName(),
+		"AccountID":         accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "signin", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleVerifySignIn verifies a signed challenge and starts a session.
+func (h *MarketHandler) handleVerifySignIn(w http.ResponseWriter, r *http.Request) {
+	if h.authService == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Sign-in is not configured")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	token, account, err := h.authService.Verify(strings.TrimSpace(r.FormValue("xdr")))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(service.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	setAccountIDCookie(w, account)
+	h.logger.Info("account signed in", "account", account)
+
+	http.Redirect(w, r, localReturnPath(r.FormValue("return")), http.StatusSeeOther)
+}
+
+// handleSignOut ends the session.
+func (h *MarketHandler) handleSignOut(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, localReturnPath(r.FormValue("return")), http.StatusSeeOther)
+}
+
+// requireSession returns the signed-in account, or redirects to sign-in and returns "".
+func (h *MarketHandler) requireSession(w http.ResponseWriter, r *http.Request, returnTo string) string {
+	account := h.sessionAccount(r)
+	if account == "" {
+		http.Redirect(w, r, "/auth?return="+url.QueryEscape(returnTo), http.StatusSeeOther)
+	}
+	return account
+}
+
+// isOracleSession reports whether the request is signed in as the oracle.
+func (h *MarketHandler) isOracleSession(r *http.Request) bool {
+	return h.oraclePublicKey != "" && h.sessionAccount(r) == h.oraclePublicKey
+}
+
+// requireOracle checks that the request is signed in as the oracle, redirecting to
+// sign-in or rendering an error otherwise.
+func (h *MarketHandler) requireOracle(w http.ResponseWriter, r *http.Request, returnTo string) bool {
+	account := h.requireSession(w, r, returnTo)
+	if account == "" {
+		return false
+	}
+	if account != h.oraclePublicKey {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to use this page")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+// authExpiryWarning is how close to expiry a transaction's auth entries must be for the
+// transaction page to warn that it should be refreshed before signing.
+const authExpiryWarning = 10 * time.Minute
+
+// authExpiryView describes when a transaction's auth entries expire.
+type authExpiryView struct {
+	Ledger uint32
+	At     time.Time // Estimate; zero when unknown
+	Soon   bool      // Within authExpiryWarning, or already past
+}
+
+// addAuthExpiry shows when the auth entries of the transaction expire, if it has any,
+// so slow signers (e.g. a multisig oracle) know to refresh it before they lapse.
+func (h *MarketHandler) addAuthExpiry(r *http.Request, data map[string]any, result *model.TransactionResult) {
+	if result == nil || result.AuthExpiresLedger == 0 {
+		return
+	}
+	data["AuthExpiry"] = authExpiryView{
+		Ledger: result.AuthExpiresLedger,
+		At:     result.AuthExpiresAt,
+		Soon:   result.AuthExpiresWithin(time.Now(), authExpiryWarning),
+	}
+	data["TZ"] = userLocation(r)
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// bulkMarketsExample prefills the bulk deploy form with the CSV layout.
+const bulkMarketsExample = `question,resolution_source,close_time,liquidity_param,initial_funding,category,description
+"Will it rain in Podgorica on May 1, 2027?",https://meteo.co.me,2027-05-01 12:00,100,70,Weather,`
+
+// bulkDeployEnabled renders an error and returns false unless bulk deploys are available.
+func (h *MarketHandler) bulkDeployEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.bulkDeploys == nil || !h.bulkDeploys.CanPin() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Bulk deploy needs a factory contract and IPFS pinning")
+		return false
+	}
+	return true
+}
+
+// handleBulkDeploy renders the bulk deploy upload form and the batches uploaded so far.
+func (h *MarketHandler) handleBulkDeploy(w http.ResponseWriter, r *http.Request) {
+	if !h.bulkDeployEnabled(w, r) || !h.requireOracle(w, r, "/oracle/bulk") {
+		return
+	}
+	h.renderBulkDeploy(w, r, bulkMarketsExample, nil)
+}
+
+// handlePrepareBulkDeploy validates an uploaded market list and pins the metadata of
+// every market. All row problems are shown together; nothing is pinned unless every
+// row is valid.
+func (h *MarketHandler) handlePrepareBulkDeploy(w http.ResponseWriter, r *http.Request) {
+	if !h.bulkDeployEnabled(w, r) || !h.requireOracle(w, r, "/oracle/bulk") {
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+	input := r.FormValue("markets")
+
+	markets, err := service.ParseBulkMarkets([]byte(input))
+	if err == nil {
+		var batch service.DeployBatch
+		if batch, err = h.bulkDeploys.Prepare(r.Context(), markets, h.oraclePublicKey); err == nil {
+			h.audit(r, "bulk.prepare", map[string]string{"batch": batch.ID, "markets": strconv.Itoa(len(batch.Items))})
+			http.Redirect(w, r, "/oracle/bulk/"+batch.ID, http.StatusSeeOther)
+			return
+		}
+	}
+	var rowErr *service.BulkRowError
+	if !errors.As(err, &rowErr) && !errors.Is(err, service.ErrInvalidBulkMarkets) {
+		h.writeError(w, r, err, "markets", len(markets))
+		return
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	h.renderBulkDeploy(w, r, input, bulkErrors(err))
+}
+
+// bulkErrors lists the problems of a failed upload, one per row.
+func bulkErrors(err error) []string {
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []string{err.Error()}
+	}
+	errs := joined.Unwrap()
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+	return messages
+}
+
+func (h *MarketHandler) renderBulkDeploy(w http.ResponseWriter, r *http.Request, input string, rowErrors []string) {
+	data := map[string]any{
+		"Input":      input,
+		"RowErrors":  rowErrors,
+		"Batches":    h.bulkDeploys.List(),
+		"MaxMarkets": service.MaxBulkMarkets,
+		"ActiveNav":  "oracle",
+		"Network":    h.networkName(),
+		"AccountID":  accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "oracle_bulk", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleDeployBatch renders the progress of a bulk deploy, with the next market to deploy.
+func (h *MarketHandler) handleDeployBatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.bulkDeployEnabled(w, r) || !h.requireOracle(w, r, "/oracle/bulk/"+id) {
+		return
+	}
+	batch, err := h.bulkDeploys.Batch(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err, "batch", id)
+		return
+	}
+	if wantsJSON(r) {
+		h.writeJSON(w, batch)
+		return
+	}
+
+	next := -1
+	for i, item := range batch.Items {
+		if item.ContractID == "" {
+			next = i
+			break
+		}
+	}
+	data := map[string]any{
+		"Batch":     batch,
+		"Deployed":  batch.Deployed(),
+		"Next":      next,
+		"TZ":        userLocation(r),
+		"ActiveNav": "oracle",
+		"Network":   h.networkName(),
+		"AccountID": accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "oracle_bulk_batch", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleBuildBatchDeployTx builds the deploy transaction of the next market of a batch.
+// Transactions are built one at a time, once the previous deploy landed, so each uses
+// the oracle account's current sequence number.
+func (h *MarketHandler) handleBuildBatchDeployTx(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.bulkDeployEnabled(w, r) || !h.requireOracle(w, r, "/oracle/bulk/"+id) {
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	index, req, err := h.bulkDeploys.Next(r.Context(), id, r.FormValue("force") != "")
+	if err != nil {
+		h.writeError(w, r, err, "batch", id)
+		return
+	}
+	result, err := h.factoryService.BuildDeployMarketTx(r.Context(), req)
+	if err != nil {
+		h.writeError(w, r, err, "batch", id, "metadata_hash", req.MetadataHash)
+		return
+	}
+	if err := h.bulkDeploys.Built(id, index, result.XDR, result.AuthExpiresAt); err != nil {
+		h.logger.Warn("failed to record bulk deploy transaction", "batch", id, "index", index, "error", err)
+	}
+	h.recordPendingDeploy(service.PendingTx{Kind: service.PendingTxDeploy, Description: result.Description, BuiltBy: h.sessionAccount(r), Deploy: &req}, result)
+	h.audit(r, "market.deploy_built", map[string]string{"batch": id, "metadata_hash": req.MetadataHash})
+
+	data := map[string]any{
+		"Result":            result,
+		"MarketID":          "new",
+		"ReturnURL":         "/oracle/bulk/" + id,
+		"ActiveNav":         "oracle",
+		"Network":           h.networkName(),
+		"NetworkPassphrase": h.networkPassphrase,
+		"AccountID":         accountIDFromCookie(r),
+	}
+
+	h.addServerSign(r, data, result)
+	h.addSignElsewhere(data, result)
+	h.addAuthExpiry(r, data, result)
+
+	if err := h.tmpl.Render(w, "transaction", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleDeleteDeployBatch forgets a batch; markets already deployed stay.
+func (h *MarketHandler) handleDeleteDeployBatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.bulkDeployEnabled(w, r) || !h.requireOracle(w, r, "/oracle/bulk/"+id) {
+		return
+	}
+	if err := h.bulkDeploys.Delete(strings.TrimSpace(id)); err != nil {
+		h.writeError(w, r, err, "batch", id)
+		return
+	}
+	h.audit(r, "bulk.delete", map[string]string{"batch": id})
+	http.Redirect(w, r, "/oracle/bulk", http.StatusSeeOther)
+}
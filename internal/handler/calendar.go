@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+const (
+	icsTimeFormat   = "20060102T150405Z"
+	icsLineLimit    = 75 // Octets per content line before folding (RFC 5545 §3.1)
+	icsReminderTime = "-PT1H"
+)
+
+// handleMarketCalendar serves the market close time as an iCalendar event, so traders
+// can add the deadline to their calendars.
+func (h *MarketHandler) handleMarketCalendar(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if contractID == "" {
+		h.renderError(w, r, http.StatusBadRequest, "Contract ID required")
+		return
+	}
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+
+	ctx := r.Context()
+	loaded, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
+	if err != nil {
+		h.logger.Error("failed to get market state", "contract_id", contractID, "error", err)
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	states := loaded.States
+	if len(states) == 0 {
+		h.renderError(w, r, http.StatusNotFound, "Market not found")
+		return
+	}
+	state := states[0]
+
+	var metadata model.MarketMetadata
+	if state.MetadataHash == "" || h.ipfsClient == nil {
+		h.renderError(w, r, http.StatusNotFound, "Market has no close date")
+		return
+	}
+	if err := h.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err != nil {
+		h.logger.Warn("failed to fetch metadata", "hash", state.MetadataHash, "error", err)
+		h.renderError(w, r, http.StatusBadGateway, "Failed to load market metadata")
+		return
+	}
+	if metadata.EndDate.IsZero() {
+		h.renderError(w, r, http.StatusNotFound, "Market has no close date")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "market-"+shortID(contractID)+".ics"))
+	if _, err := w.Write([]byte(marketCalendar(contractID, metadata, time.Now()))); err != nil {
+		h.logger.Debug("failed to write calendar", "error", err)
+	}
+}
+
+// marketCalendar renders a VCALENDAR with one event at the market close time and a
+// reminder an hour before. The description tells when resolution is expected.
+func marketCalendar(contractID string, metadata model.MarketMetadata, now time.Time) string {
+	question := metadata.Question
+	if question == "" {
+		question = "Market " + shortID(contractID)
+	}
+	end := metadata.EndDate.UTC()
+
+	description := "Trading closes for: " + question + "\n" +
+		"The oracle resolves the market after this time"
+	if metadata.ResolutionSource != "" {
+		description += " based on " + metadata.ResolutionSource
+	}
+	description += ".\nMarket: " + contractID
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Montelibero//MTL Predict//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:" + contractID + "-close@mtl-predict",
+		"DTSTAMP:" + now.UTC().Format(icsTimeFormat),
+		"DTSTART:" + end.Format(icsTimeFormat),
+		"DTEND:" + end.Add(30*time.Minute).Format(icsTimeFormat),
+		"SUMMARY:" + icsEscape("Market closes: "+question),
+		"DESCRIPTION:" + icsEscape(description),
+		"TRANSP:TRANSPARENT",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:" + icsEscape("Market closes in one hour: "+question),
+		"TRIGGER:" + icsReminderTime,
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(icsFold(line))
+		sb.WriteString("\r\n")
+	}
+	return sb.String()
+}
+
+// icsEscape escapes a TEXT property value.
+func icsEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// icsFold splits a content line into 75-octet chunks joined by CRLF + space,
+// without cutting multi-byte UTF-8 characters.
+func icsFold(line string) string {
+	if len(line) <= icsLineLimit {
+		return line
+	}
+	var sb strings.Builder
+	width := 0
+	for _, c := range line {
+		n := utf8.RuneLen(c)
+		if width+n > icsLineLimit {
+			sb.WriteString("\r\n ")
+			width = 1 // The leading space counts towards the limit
+		}
+		sb.WriteRune(c)
+		width += n
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestMarketCalendar(t *testing.T) {
+	const contractID = "CBQHNAXSI55GX2GN6D67GK7BHVPSLJUGZQEU7WJ5LKR5PNUCGLIMAO4K"
+	metadata := model.MarketMetadata{
+		Question:         "Will it rain; in Berlin, tomorrow?",
+		ResolutionSource: "DWD",
+		EndDate:          time.Date(2025, 6, 1, 18, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+	ics := marketCalendar(contractID, metadata, time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Fatalf("marketCalendar() is not a CRLF-terminated VCALENDAR:\n%s", ics)
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	for _, want := range []string{
+		"DTSTART:20250601T160000Z\r\n",
+		"DTEND:20250601T163000Z\r\n",
+		"DTSTAMP:20250501T120000Z\r\n",
+		"UID:" + contractID + "-close@mtl-predict\r\n",
+		`SUMMARY:Market closes: Will it rain\; in Berlin\, tomorrow?` + "\r\n",
+		`based on DWD.\nMarket: ` + contractID,
+		"TRIGGER:-PT1H\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("marketCalendar() missing %q:\n%s", want, ics)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("line of %d octets, want at most %d: %q", len(line), icsLineLimit, line)
+		}
+	}
+
+	// Without a question the market is named by its contract ID.
+	ics = marketCalendar(contractID, model.MarketMetadata{EndDate: metadata.EndDate}, time.Now())
+	if !strings.Contains(ics, "SUMMARY:Market closes: Market "+shortID(contractID)) {
+		t.Errorf("marketCalendar() without question:\n%s", ics)
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`a\b`, `a\\b`},
+		{"a;b,c", `a\;b\,c`},
+		{"one\r\ntwo\nthree\rfour", `one\ntwo\nthreefour`},
+	}
+	for _, tt := range tests {
+		if got := icsEscape(tt.in); got != tt.want {
+			t.Errorf("icsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestICSFold(t *testing.T) {
+	short := strings.Repeat("a", icsLineLimit)
+	if got := icsFold(short); got != short {
+		t.Errorf("icsFold() folded a %d-octet line", icsLineLimit)
+	}
+
+	long := "DESCRIPTION:" + strings.Repeat("x", 200)
+	lines := strings.Split(icsFold(long), "\r\n")
+	if len(lines) != 3 { // 75 + 74 + 63 octets
+		t.Fatalf("icsFold() = %d lines, want 3", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) > icsLineLimit {
+			t.Errorf("line %d has %d octets, want at most %d", i, len(line), icsLineLimit)
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space: %q", i, line)
+		}
+	}
+	if got := strings.ReplaceAll(icsFold(long), "\r\n ", ""); got != long {
+		t.Errorf("unfolding icsFold() = %q, want the original line", got)
+	}
+
+	// Multi-byte characters are never split across lines.
+	cyrillic := "SUMMARY:" + strings.Repeat("я", 100)
+	for i, line := range strings.Split(icsFold(cyrillic), "\r\n") {
+		if !utf8.ValidString(line) || len(line) > icsLineLimit {
+			t.Errorf("line %d = %q, want valid UTF-8 within %d octets", i, line, icsLineLimit)
+		}
+	}
+}
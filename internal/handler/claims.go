@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// claimView is the claim period note on a resolved market's page.
+type claimView struct {
+	Deadline time.Time
+	Closed   bool    // The claim period has ended; winnings stay claimable
+	Winning  float64 // The viewer's winning tokens, 0 when none or unknown
+}
+
+// sweepRow is a market in the oracle's withdraw report.
+type sweepRow struct {
+	service.SweepEntry
+	Question string
+}
+
+// marketClaim returns the claim period of a resolved market and the viewer's winning
+// tokens, or nil when the market is open or claims are not tracked.
+func (h *MarketHandler) marketClaim(market *model.Market, balance *service.UserBalance, now time.Time) *claimView {
+	if h.claims == nil || !market.IsResolved() {
+		return nil
+	}
+	var deadline time.Time
+	if market.ResolvedAt != nil {
+		deadline = market.ResolvedAt.Add(h.claims.ClaimPeriod())
+	} else if d, ok := h.claims.Deadline(market.ID); ok {
+		deadline = d
+	} else {
+		return nil
+	}
+
+	view := &claimView{Deadline: deadline, Closed: !now.Before(deadline)}
+	if balance != nil {
+		switch market.Resolution {
+		case model.OutcomeYes:
+			view.Winning = balance.YesBalance
+		case model.OutcomeNo:
+			view.Winning = balance.NoBalance
+		}
+	}
+	return view
+}
+
+// sweepReport returns the markets past their claim period that still hold collateral,
+// with questions from markets.
+func (h *MarketHandler) sweepReport(markets []MarketView, now time.Time) []sweepRow {
+	if h.claims == nil {
+		return nil
+	}
+	questions := make(map[string]string, len(markets))
+	for _, m := range markets {
+		questions[m.ID] = m.Question
+	}
+	var rows []sweepRow
+	for _, e := range h.claims.SweepReport(now) {
+		rows = append(rows, sweepRow{SweepEntry: e, Question: questions[e.ContractID]})
+	}
+	return rows
+}
+
+// unclaimedWinnings returns the account's known unclaimed winnings for the pending page.
+func (h *MarketHandler) unclaimedWinnings(account string) []service.UnclaimedWinning {
+	if h.claims == nil || account == "" {
+		return nil
+	}
+	return h.claims.ForAccount(account)
+}
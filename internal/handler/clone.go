@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// cloneTimeFormat is the value format of <input type="datetime-local">, read as UTC.
+const cloneTimeFormat = "2006-01-02T15:04"
+
+// cloneForm is the metadata of a new market, editable before pinning: a copy of the
+// market SourceID, a blank form, or either with a rule template merged in.
+type cloneForm struct {
+	SourceID         string // Empty for a new market
+	Question         string
+	Description      string
+	ResolutionSource string
+	Category         string
+	EndDate          string                // cloneTimeFormat, UTC
+	Rules            *service.RuleTemplate // Rule template merged into the metadata, if any
+	RuleVars         []ruleVar
+	ConditionID      string // Parent market, empty for an unconditional market
+	ConditionOutcome string
+}
+
+// addCloneForm prefills the metadata panel of the oracle page: from ?clone={id} for the
+// next round of a market, ?new=1 for a blank one, and ?rules={id} to merge a rule
+// template into either.
+func (h *MarketHandler) addCloneForm(r *http.Request, data map[string]any) {
+	q := r.URL.Query()
+	sourceID := strings.TrimSpace(q.Get("clone"))
+	rulesID := strings.TrimSpace(q.Get("rules"))
+	if sourceID == "" && rulesID == "" && q.Get("new") == "" {
+		return
+	}
+
+	form := cloneForm{EndDate: service.NextEndDate(model.MarketMetadata{}, time.Now().UTC()).Format(cloneTimeFormat)}
+	if sourceID != "" {
+		if h.ipfsClient == nil || h.factoryService == nil || !h.factoryService.HasFactory() {
+			return
+		}
+		loaded, err := h.factoryService.GetMarketStates(r.Context(), []string{sourceID})
+		states := loaded.States
+		if err != nil || len(states) == 0 || states[0].MetadataHash == "" {
+			data["CloneError"] = "Market to clone not found"
+			return
+		}
+		var metadata model.MarketMetadata
+		if err := h.ipfsClient.GetJSON(r.Context(), states[0].MetadataHash, &metadata); err != nil {
+			h.logger.Warn("clone: failed to fetch metadata", "hash", states[0].MetadataHash, "error", err)
+			data["CloneError"] = "Failed to load the market's metadata from IPFS"
+			return
+		}
+		form = cloneForm{
+			SourceID:         sourceID,
+			Question:         metadata.Question,
+			Description:      metadata.Description,
+			ResolutionSource: metadata.ResolutionSource,
+			Category:         metadata.Category,
+			EndDate:          service.NextEndDate(metadata, time.Now().UTC()).UTC().Format(cloneTimeFormat),
+		}
+		if metadata.Condition != nil {
+			form.ConditionID = metadata.Condition.MarketID
+			form.ConditionOutcome = string(metadata.Condition.Outcome)
+		}
+	}
+	if rulesID != "" {
+		if err := h.setCloneRules(&form, rulesID, q); err != nil {
+			data["CloneError"] = "Rule template not found"
+		}
+	}
+
+	data["Clone"] = form
+	h.addClonePinning(r, data)
+}
+
+// addClonePinning tells the page whether the metadata can be pinned from here: only
+// for a signed-in oracle, since pinning spends the operator's Pinata quota.
+func (h *MarketHandler) addClonePinning(r *http.Request, data map[string]any) {
+	oracleSession := h.isOracleSession(r)
+	data["ClonePinning"] = oracleSession && h.ipfsClient != nil && h.ipfsClient.CanPin()
+	data["CloneSignInRequired"] = !oracleSession && h.authService != nil
+}
+
+// handleCloneMarket builds metadata for a new market or the next round of one, merging
+// in the selected rule template. A signed-in oracle gets it pinned and the deploy form
+// prefilled with the new CID; otherwise the JSON is shown for uploading to Pinata by hand.
+func (h *MarketHandler) handleCloneMarket(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	form := cloneForm{
+		SourceID:         strings.TrimSpace(r.FormValue("source_id")),
+		Question:         strings.TrimSpace(r.FormValue("question")),
+		Description:      strings.TrimSpace(r.FormValue("description")),
+		ResolutionSource: strings.TrimSpace(r.FormValue("resolution_source")),
+		Category:         strings.TrimSpace(r.FormValue("category")),
+		EndDate:          strings.TrimSpace(r.FormValue("end_date")),
+		ConditionID:      strings.TrimSpace(r.FormValue("condition_market")),
+		ConditionOutcome: strings.TrimSpace(r.FormValue("condition_outcome")),
+	}
+	condition, err := form.condition()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	endDate, err := time.ParseInLocation(cloneTimeFormat, form.EndDate, time.UTC)
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid close time")
+		return
+	}
+	if !endDate.After(time.Now()) {
+		h.writeError(w, r, model.ErrCloseTimeInPast)
+		return
+	}
+
+	metadata := &model.MarketMetadata{
+		Question:         form.Question,
+		Description:      form.Description,
+		ResolutionSource: form.ResolutionSource,
+		Category:         form.Category,
+		EndDate:          endDate,
+		CreatedAt:        time.Now().UTC(),
+		CreatedBy:        h.oraclePublicKey,
+		Condition:        condition,
+	}
+	if rulesID := strings.TrimSpace(r.FormValue("rule_template")); rulesID != "" {
+		if err := h.setCloneRules(&form, rulesID, r.Form); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		if err := form.Rules.Apply(metadata, form.ruleValues()); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+	}
+	// The same checks as PinJSON, so hand-uploaded JSON is valid too.
+	if _, err := ipfs.NormalizeMetadata(metadata); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	data := h.oracleData(r)
+	data["Clone"] = form
+	h.addClonePinning(r, data)
+
+	if pinning, _ := data["ClonePinning"].(bool); pinning {
+		cid, err := h.ipfsClient.PinJSON(r.Context(), metadata)
+		if err != nil {
+			h.logger.Error("clone: failed to pin metadata", "source_id", form.SourceID, "error", err)
+			data["CloneError"] = "Failed to pin metadata to IPFS — upload the JSON below by hand."
+		} else {
+			h.logger.Info("pinned cloned market metadata", "source_id", form.SourceID, "cid", cid)
+			data["DeployMetadataHash"] = cid
+			data["ClonePinned"] = true
+		}
+	}
+	if pinned, _ := data["ClonePinned"].(bool); !pinned {
+		metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		data["CloneJSON"] = string(metadataJSON)
+	}
+
+	h.renderOracle(w, data)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// marketComments lists a market's discussion, or nil when comments are disabled.
+func (h *MarketHandler) marketComments(contractID string) []service.Comment {
+	if h.comments == nil {
+		return nil
+	}
+	return h.comments.List(contractID)
+}
+
+// handlePostComment adds a comment to a market's discussion.
+func (h *MarketHandler) handlePostComment(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if h.comments == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Comments are not enabled")
+		return
+	}
+	returnTo := "/market/" + contractID + "#comments"
+	account := h.requireSession(w, r, returnTo)
+	if account == "" {
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+	if h.factoryService != nil && h.factoryService.HasFactory() {
+		ids, err := h.factoryService.ListMarkets(r.Context())
+		if err != nil {
+			h.writeError(w, r, err, "contract_id", contractID)
+			return
+		}
+		if !slices.Contains(ids, contractID) {
+			h.renderError(w, r, http.StatusNotFound, "Market not found")
+			return
+		}
+	}
+
+	if _, err := h.comments.Post(service.PostCommentRequest{
+		ContractID: contractID,
+		Author:     account,
+		Body:       r.FormValue("body"),
+	}); err != nil {
+		h.writeError(w, r, err, "contract_id", contractID, "account", account)
+		return
+	}
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// handleFlagComment reports a comment for moderation.
+func (h *MarketHandler) handleFlagComment(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	contractID := r.PathValue("id")
+	if h.comments == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Comments are not enabled")
+		return
+	}
+	returnTo := "/market/" + contractID + "#comments"
+	account := h.requireSession(w, r, returnTo)
+	if account == "" {
+		return
+	}
+
+	if err := h.comments.Flag(contractID, r.PathValue("comment"), account); err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// handleHideComment hides or restores a comment. Only the oracle may moderate.
+func (h *MarketHandler) handleHideComment(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if h.comments == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Comments are not enabled")
+		return
+	}
+	returnTo := "/market/" + contractID + "#comments"
+	account := h.requireSession(w, r, returnTo)
+	if account == "" {
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	hidden := r.FormValue("hidden") != "false"
+	if err := h.comments.SetHidden(contractID, r.PathValue("comment"), account, hidden); err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	h.logger.Info("comment moderated", "contract_id", contractID, "comment", r.PathValue("comment"), "hidden", hidden)
+	h.audit(r, "comment.moderate", map[string]string{"contract_id": contractID, "comment": r.PathValue("comment"), "hidden": strconv.FormatBool(hidden)})
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
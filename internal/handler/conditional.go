@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// conditionView links a conditional market page to its parent market.
+type conditionView struct {
+	Parent      MarketView
+	Outcome     model.Outcome // Parent outcome the question assumes
+	ParentPrice float64       // Parent's price of Outcome
+	Combined    float64       // Probability of the parent resolving Outcome and this market YES
+	Met         bool          // Parent resolved to Outcome
+	Failed      bool          // Parent resolved to the other outcome
+}
+
+// linkedMarketView is a market conditional on another, with the combined probability
+// of both resolving the way it assumes and YES.
+type linkedMarketView struct {
+	Market   MarketView
+	Parent   MarketView
+	Outcome  model.Outcome
+	Combined float64
+}
+
+// outcomePrice returns the market's price of outcome, or for a resolved market 1 if
+// it won and 0 otherwise.
+func outcomePrice(m MarketView, outcome model.Outcome) float64 {
+	if m.IsResolved {
+		if m.Resolution == string(outcome) {
+			return 1
+		}
+		return 0
+	}
+	if outcome == model.OutcomeNo {
+		return m.PriceNo
+	}
+	return m.PriceYes
+}
+
+// newConditionView links a market with YES price priceYes to its parent.
+func newConditionView(parent MarketView, outcome model.Outcome, priceYes float64) *conditionView {
+	parentPrice := outcomePrice(parent, outcome)
+	return &conditionView{
+		Parent:      parent,
+		Outcome:     outcome,
+		ParentPrice: parentPrice,
+		Combined:    parentPrice * priceYes,
+		Met:         parent.IsResolved && parent.Resolution == string(outcome),
+		Failed:      parent.IsResolved && parent.Resolution != string(outcome),
+	}
+}
+
+// linkedMarkets returns the markets whose parent is in markets, in list order. With a
+// parentID only the markets conditional on that one are returned; openOnly skips
+// resolved markets.
+func linkedMarkets(markets []MarketView, parentID string, openOnly bool) []linkedMarketView {
+	byID := make(map[string]MarketView, len(markets))
+	for _, m := range markets {
+		byID[m.ID] = m
+	}
+	var linked []linkedMarketView
+	for _, m := range markets {
+		if m.Condition == nil || (parentID != "" && m.Condition.MarketID != parentID) || (openOnly && m.IsResolved) {
+			continue
+		}
+		parent, ok := byID[m.Condition.MarketID]
+		if !ok {
+			continue
+		}
+		linked = append(linked, linkedMarketView{
+			Market:   m,
+			Parent:   parent,
+			Outcome:  m.Condition.Outcome,
+			Combined: outcomePrice(parent, m.Condition.Outcome) * m.PriceYes,
+		})
+	}
+	return linked
+}
+
+// conditionAlerts returns the open markets whose parent has resolved: their condition
+// is decided, and a failed one means the market should be resolved per its rules.
+func conditionAlerts(markets []MarketView) []linkedMarketView {
+	var alerts []linkedMarketView
+	for _, l := range linkedMarkets(markets, "", true) {
+		if l.Parent.IsResolved {
+			alerts = append(alerts, l)
+		}
+	}
+	return alerts
+}
+
+// marketLinks loads the parent of a conditional market and the markets conditional on
+// it, for the market page. Failures leave the links out: they are informational.
+func (h *MarketHandler) marketLinks(ctx context.Context, market *model.Market) (*conditionView, []linkedMarketView) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		return nil, nil
+	}
+	contractIDs, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		h.logger.Warn("market links: failed to list markets", "error", err)
+		return nil, nil
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		h.logger.Warn("market links: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	markets := h.buildMarketViews(ctx, states)
+
+	var condition *conditionView
+	if c := market.Condition; c != nil {
+		for _, m := range markets {
+			if m.ID == c.MarketID {
+				condition = newConditionView(m, c.Outcome, market.PriceYes)
+				break
+			}
+		}
+	}
+	return condition, linkedMarkets(markets, market.ID, false)
+}
+
+// condition returns the parent market of the metadata form, nil when none is selected.
+func (f cloneForm) condition() (*model.MarketCondition, error) {
+	if f.ConditionID == "" {
+		return nil, nil
+	}
+	if err := soroban.ValidateContractID(f.ConditionID); err != nil {
+		return nil, model.ErrInvalidCondition
+	}
+	outcome, err := model.ParseOutcome(f.ConditionOutcome)
+	if err != nil {
+		return nil, model.ErrInvalidCondition
+	}
+	return &model.MarketCondition{MarketID: f.ConditionID, Outcome: outcome}, nil
+}
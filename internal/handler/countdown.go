@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+// countdownView drives the "countdown" partial: time left until close, or how long
+// ago the market closed or resolved.
+type countdownView struct {
+	ID         string
+	EndDate    time.Time // zero when unknown
+	Resolved   bool
+	ResolvedAt time.Time // zero when unknown
+}
+
+// resolvedAt returns when a resolved market was resolved: from the archive index
+// when it has it, otherwise from the resolution event. Zero when unknown.
+func (h *MarketHandler) resolvedAt(ctx context.Context, contractID string) time.Time {
+	if h.archiveService != nil {
+		if t, ok := h.archiveService.ResolvedAt(contractID); ok {
+			return t
+		}
+	}
+	if h.eventService != nil {
+		resolution, found, err := h.eventService.GetResolution(ctx, contractID)
+		if err != nil {
+			h.logger.Warn("failed to get resolution event", "contract_id", contractID, "error", err)
+		} else if found {
+			return resolution.Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// handleMarketCountdown renders the countdown fragment of a market page, polled by
+// the page to keep it current without a reload.
+func (h *MarketHandler) handleMarketCountdown(w http.ResponseWriter, r *http.Request) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+	ctx := r.Context()
+	contractID := r.PathValue("id")
+
+	loaded, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	states := loaded.States
+	if len(states) == 0 {
+		h.renderError(w, r, http.StatusNotFound, "Market not found")
+		return
+	}
+	state := states[0]
+
+	view := countdownView{ID: contractID, Resolved: state.Resolved}
+	if state.MetadataHash != "" && h.ipfsClient != nil {
+		var metadata model.MarketMetadata
+		if err := h.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err != nil {
+			h.logger.Warn("failed to fetch metadata", "hash", state.MetadataHash, "error", err)
+		} else {
+			view.EndDate = metadata.EndDate
+		}
+	}
+	if view.Resolved {
+		view.ResolvedAt = h.resolvedAt(ctx, contractID)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.tmpl.RenderFragment(w, "countdown", view); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mtlprog/total/internal/chart"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// handleCostCurve handles GET /market/{id}/curve.svg: the cost of buying increasing
+// amounts of YES and NO at the market's current state, so traders can see its depth
+// before a large order. ?max= sets the largest amount in tokens (default 2b).
+func (h *MarketHandler) handleCostCurve(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if err := soroban.ValidateContractID(contractID); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid market ID")
+		return
+	}
+
+	var maxAmount float64
+	if s := r.URL.Query().Get("max"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 || v > service.MaxCostCurveAmount {
+			h.renderError(w, r, http.StatusBadRequest, "max must be a positive number of tokens up to "+strconv.Itoa(service.MaxCostCurveAmount))
+			return
+		}
+		maxAmount = v
+	}
+
+	curve, err := h.marketService.CostCurve(r.Context(), contractID, maxAmount)
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	points := make([]chart.CostPoint, len(curve.Points))
+	for i, p := range curve.Points {
+		points[i] = chart.CostPoint{Amount: p.Amount, Yes: p.CostYes, No: p.CostNo}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	if _, err := w.Write(chart.RenderCostCurveSVG(points)); err != nil {
+		h.logger.Debug("failed to write cost curve", "error", err)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// digestsEnabled reports whether email subscriptions can be created.
+func (h *MarketHandler) digestsEnabled() bool {
+	return h.digests != nil
+}
+
+// handleSubscribeEmail handles POST /email/subscribe: the daily digest and/or the
+// resolution notice of one market. A confirmation link is emailed first.
+func (h *MarketHandler) handleSubscribeEmail(w http.ResponseWriter, r *http.Request) {
+	if !h.digestsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Email notifications are not configured")
+		return
+	}
+
+	req := service.SubscribeDigestRequest{
+		Email:      strings.TrimSpace(r.FormValue("email")),
+		Daily:      r.FormValue("daily") != "",
+		ContractID: r.FormValue("contract_id"),
+	}
+	if err := h.digests.Subscribe(r.Context(), req); err != nil {
+		h.writeError(w, r, err, "contract_id", req.ContractID)
+		return
+	}
+	h.logger.Info("email subscription requested", "daily", req.Daily, "contract_id", req.ContractID)
+
+	h.renderEmail(w, r, map[string]any{"Sent": true, "ContractID": req.ContractID})
+}
+
+// handleEmailLink asks for confirmation before following a confirm or unsubscribe
+// link from an email, so link scanners and prefetchers do not act on it.
+func (h *MarketHandler) handleEmailLink(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.renderEmail(w, r, map[string]any{
+			"Action": action,
+			"ID":     r.PathValue("id"),
+			"Token":  r.URL.Query().Get("token"),
+		})
+	}
+}
+
+// handleConfirmEmail applies a confirmed subscription change.
+func (h *MarketHandler) handleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.digestsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Email notifications are not configured")
+		return
+	}
+
+	id := r.PathValue("id")
+	sub, err := h.digests.Confirm(id, r.FormValue("token"))
+	if err != nil {
+		h.writeError(w, r, err, "subscription_id", id)
+		return
+	}
+	h.renderEmail(w, r, map[string]any{"Confirmed": sub})
+}
+
+// handleUnsubscribeEmail removes an email subscription with its unsubscribe token.
+func (h *MarketHandler) handleUnsubscribeEmail(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.digestsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Email notifications are not configured")
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.digests.Unsubscribe(id, r.FormValue("token")); err != nil {
+		h.writeError(w, r, err, "subscription_id", id)
+		return
+	}
+	h.renderEmail(w, r, map[string]any{"Unsubscribed": true})
+}
+
+func (h *MarketHandler) renderEmail(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	data["ActiveNav"] = "markets"
+	data["Network"] = h.networkName()
+	data["AccountID"] = accountIDFromCookie(r)
+	if err := h.tmpl.Render(w, "email", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
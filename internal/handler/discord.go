@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// Discord interaction and response types.
+const (
+	discordInteractionPing    = 1
+	discordInteractionCommand = 2
+
+	discordResponsePong    = 1
+	discordResponseMessage = 4
+
+	discordFlagEphemeral = 1 << 6 // only the invoking user sees the reply
+)
+
+const (
+	// discordMaxSkew bounds the age of a signed request, limiting replays.
+	discordMaxSkew = 5 * time.Minute
+	// discordReplyTimeout leaves headroom in Discord's 3 second response deadline.
+	discordReplyTimeout = 2500 * time.Millisecond
+	discordListLimit    = 10
+	discordMinIDPrefix  = 4
+)
+
+var errDiscordMarketAmbiguous = errors.New("market ID prefix matches several markets")
+
+// discordCommands are the slash command definitions, in the shape the Discord API
+// expects when registering application commands.
+var discordCommands = []map[string]any{
+	{"name": "markets", "description": "List open prediction markets"},
+	{"name": "price", "description": "Show a market's current probabilities", "options": []map[string]any{
+		{"type": 3, "name": "id", "description": "Market contract ID or its first characters", "required": true},
+	}},
+	{"name": "quote", "description": "Quote the cost of buying outcome tokens", "options": []map[string]any{
+		{"type": 3, "name": "id", "description": "Market contract ID or its first characters", "required": true},
+		{"type": 3, "name": "outcome", "description": "YES or NO", "required": true, "choices": []map[string]string{{"name": "YES", "value": "YES"}, {"name": "NO", "value": "NO"}}},
+		{"type": 10, "name": "amount", "description": "Number of tokens", "required": true},
+	}},
+}
+
+// discordInteraction is the part of an incoming interaction the commands use.
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// option returns the raw value of a command option, unquoted for strings.
+func (i *discordInteraction) option(name string) string {
+	for _, o := range i.Data.Options {
+		if o.Name != name {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	return ""
+}
+
+// DiscordHandler answers Discord slash commands (/markets, /price, /quote) posted to
+// its interactions endpoint, so community servers can query markets inline.
+type DiscordHandler struct {
+	publicKey      ed25519.PublicKey
+	marketService  *service.MarketService
+	factoryService *service.FactoryService
+	ipfsClient     *ipfs.Client
+	publicURL      string // Base URL for market links; empty omits links
+	logger         *slog.Logger
+}
+
+// NewDiscordHandler creates a Discord interactions handler. publicKey is the hex
+// Ed25519 public key of the Discord application, used to verify requests.
+func NewDiscordHandler(
+	publicKey string,
+	marketService *service.MarketService,
+	factoryService *service.FactoryService,
+	ipfsClient *ipfs.Client,
+	publicURL string,
+	logger *slog.Logger,
+) (*DiscordHandler, error) {
+	if marketService == nil {
+		panic("NewDiscordHandler: marketService must not be nil")
+	}
+	if factoryService == nil {
+		panic("NewDiscordHandler: factoryService must not be nil")
+	}
+	if logger == nil {
+		panic("NewDiscordHandler: logger must not be nil")
+	}
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Discord public key: expected %d hex-encoded bytes", ed25519.PublicKeySize)
+	}
+	return &DiscordHandler{
+		publicKey:      key,
+		marketService:  marketService,
+		factoryService: factoryService,
+		ipfsClient:     ipfsClient,
+		publicURL:      publicURL,
+		logger:         logger,
+	}, nil
+}
+
+// RegisterRoutes registers the interactions endpoint and the command definitions.
+func (h *DiscordHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /discord/interactions", h.handleInteraction)
+	mux.HandleFunc("GET /discord/commands", h.handleCommands)
+}
+
+// handleCommands serves the command definitions, ready to PUT to the Discord
+// applications/{id}/commands endpoint.
+func (h *DiscordHandler) handleCommands(w http.ResponseWriter, r *http.Request) {
+	h.reply(w, discordCommands)
+}
+
+func (h *DiscordHandler) handleInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		status, msg := formErrorResponse(err)
+		writeJSONError(w, msg, status)
+		return
+	}
+	if !h.verify(r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body, time.Now()) {
+		writeJSONError(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		writeJSONError(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+
+	switch interaction.Type {
+	case discordInteractionPing:
+		h.reply(w, map[string]any{"type": discordResponsePong})
+	case discordInteractionCommand:
+		ctx, cancel := context.WithTimeout(r.Context(), discordReplyTimeout)
+		defer cancel()
+		content, err := h.runCommand(ctx, &interaction)
+		data := map[string]any{"content": content, "allowed_mentions": map[string]any{"parse": []string{}}}
+		if err != nil {
+			h.logger.Warn("discord command failed", "command", interaction.Data.Name, "error", err)
+			data["content"] = discordErrorMessage(err)
+			data["flags"] = discordFlagEphemeral
+		}
+		h.reply(w, map[string]any{"type": discordResponseMessage, "data": data})
+	default:
+		writeJSONError(w, "unsupported interaction type", http.StatusBadRequest)
+	}
+}
+
+// verify checks the Ed25519 signature Discord puts on every request over the
+// timestamp and body, and that the timestamp is recent.
+func (h *DiscordHandler) verify(signature, timestamp string, body []byte, now time.Time) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(secs, 0)); skew > discordMaxSkew || skew < -discordMaxSkew {
+		return false
+	}
+	return ed25519.Verify(h.publicKey, append([]byte(timestamp), body...), sig)
+}
+
+func (h *DiscordHandler) reply(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error("failed to encode discord response", "error", err)
+	}
+}
+
+func (h *DiscordHandler) runCommand(ctx context.Context, i *discordInteraction) (string, error) {
+	switch i.Data.Name {
+	case "markets":
+		return h.commandMarkets(ctx)
+	case "price":
+		return h.commandPrice(ctx, i.option("id"))
+	case "quote":
+		return h.commandQuote(ctx, i.option("id"), i.option("outcome"), i.option("amount"))
+	default:
+		return "", fmt.Errorf("unknown command %q", i.Data.Name)
+	}
+}
+
+func (h *DiscordHandler) commandMarkets(ctx context.Context) (string, error) {
+	ids, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return "", err
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		return "", err
+	}
+	states := loaded.States
+
+	var b strings.Builder
+	listed := 0
+	for _, state := range states {
+		if state.Resolved {
+			continue
+		}
+		if listed == discordListLimit {
+			b.WriteString("…and more")
+			if link := h.link("/"); link != "" {
+				fmt.Fprintf(&b, " at <%s>", link)
+			}
+			b.WriteString("\n")
+			break
+		}
+		fmt.Fprintf(&b, "• **%s** — YES %.0f%%", h.question(ctx, state), state.PriceYes*100)
+		if link := h.link("/market/" + state.ContractID); link != "" {
+			fmt.Fprintf(&b, " <%s>", link)
+		}
+		b.WriteString("\n")
+		listed++
+	}
+	if listed == 0 {
+		return "No open markets right now.", nil
+	}
+	return b.String(), nil
+}
+
+func (h *DiscordHandler) commandPrice(ctx context.Context, id string) (string, error) {
+	state, err := h.findMarket(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", h.question(ctx, state))
+	if state.Resolved {
+		fmt.Fprintf(&b, "Resolved: **%s**", state.WinningOutcome)
+	} else {
+		fmt.Fprintf(&b, "YES %.1f%% · NO %.1f%% · pool %.2f EURMTL", state.PriceYes*100, state.PriceNo*100, float64(state.Pool)/float64(soroban.ScaleFactor))
+	}
+	if link := h.link("/market/" + state.ContractID); link != "" {
+		fmt.Fprintf(&b, "\n<%s>", link)
+	}
+	return b.String(), nil
+}
+
+func (h *DiscordHandler) commandQuote(ctx context.Context, id, outcomeStr, amountStr string) (string, error) {
+	outcome, err := model.ParseOutcome(outcomeStr)
+	if err != nil {
+		return "", err
+	}
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil || amount <= 0 || amount > service.MaxShareAmount {
+		return "", model.ErrInvalidShareAmount
+	}
+	state, err := h.findMarket(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if state.Resolved {
+		return "", service.ErrMarketResolved
+	}
+	quote, err := h.marketService.GetQuote(ctx, state.ContractID, outcome, amount)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\nBuying %g %s costs %.2f EURMTL (YES %.1f%% after the trade).",
+		h.question(ctx, state), amount, outcome, float64(quote.Cost)/float64(soroban.ScaleFactor), quote.PriceAfter*100)
+	if link := h.link(fmt.Sprintf("/market/%s?side=%s&amount=%g", state.ContractID, outcome, amount)); link != "" {
+		fmt.Fprintf(&b, "\nTrade: <%s>", link)
+	}
+	return b.String(), nil
+}
+
+// findMarket resolves a full contract ID or a unique prefix of one.
+func (h *DiscordHandler) findMarket(ctx context.Context, id string) (service.MarketState, error) {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if len(id) < discordMinIDPrefix {
+		return service.MarketState{}, service.ErrMarketNotFound
+	}
+	ids, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return service.MarketState{}, err
+	}
+	var match string
+	for _, candidate := range ids {
+		if candidate == id {
+			match = candidate
+			break
+		}
+		if strings.HasPrefix(candidate, id) {
+			if match != "" {
+				return service.MarketState{}, errDiscordMarketAmbiguous
+			}
+			match = candidate
+		}
+	}
+	if match == "" {
+		return service.MarketState{}, service.ErrMarketNotFound
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, []string{match})
+	if err != nil {
+		return service.MarketState{}, err
+	}
+	states := loaded.States
+	if len(states) == 0 {
+		return service.MarketState{}, service.ErrMarketNotFound
+	}
+	return states[0], nil
+}
+
+// question returns the market question, or the shortened contract ID when metadata
+// is unavailable.
+func (h *DiscordHandler) question(ctx context.Context, state service.MarketState) string {
+	if state.MetadataHash != "" && h.ipfsClient != nil {
+		var metadata model.MarketMetadata
+		if err := h.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err == nil && metadata.Question != "" {
+			return metadata.Question
+		}
+	}
+	return state.ContractID[:8] + "…"
+}
+
+func (h *DiscordHandler) link(path string) string {
+	if h.publicURL == "" {
+		return ""
+	}
+	return h.publicURL + path
+}
+
+// discordErrorMessage turns command errors into replies; unexpected errors are not
+// shown, since they may carry RPC details.
+func discordErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrMarketNotFound):
+		return "Market not found. Use the contract ID or at least its first 4 characters."
+	case errors.Is(err, errDiscordMarketAmbiguous):
+		return "Several markets start with that ID. Add more characters."
+	case errors.Is(err, model.ErrInvalidOutcome):
+		return "Outcome must be YES or NO."
+	case errors.Is(err, model.ErrInvalidShareAmount):
+		return fmt.Sprintf("Amount must be a positive number up to %d.", service.MaxShareAmount)
+	case errors.Is(err, service.ErrMarketResolved):
+		return "This market is already resolved."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "The network is slow right now. Please try again in a moment."
+	default:
+		return "Something went wrong. Please try again later."
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscordInteractionSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &DiscordHandler{publicKey: publicKey, logger: slog.New(slog.DiscardHandler)}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	const ping = `{"type":1}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(key ed25519.PrivateKey, timestamp, body string) string {
+		return hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body)))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      string
+		want      int
+	}{
+		{"valid", sign(privateKey, now, ping), now, ping, http.StatusOK},
+		{"tampered body", sign(privateKey, now, ping), now, `{"type":2}`, http.StatusUnauthorized},
+		{"wrong key", sign(otherKey, now, ping), now, ping, http.StatusUnauthorized},
+		{"missing signature", "", now, ping, http.StatusUnauthorized},
+		{"missing timestamp", sign(privateKey, now, ping), "", ping, http.StatusUnauthorized},
+		{"missing headers", "", "", ping, http.StatusUnauthorized},
+		{"malformed signature", "not-hex", now, ping, http.StatusUnauthorized},
+		{"stale timestamp", sign(privateKey, "1700000000", ping), "1700000000", ping, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set("X-Signature-Ed25519", tt.signature)
+			}
+			if tt.timestamp != "" {
+				req.Header.Set("X-Signature-Timestamp", tt.timestamp)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusOK && strings.TrimSpace(rec.Body.String()) != `{"type":1}` {
+				t.Errorf("body = %s, want a pong", rec.Body)
+			}
+		})
+	}
+}
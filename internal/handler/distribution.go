@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"cmp"
+	"html/template"
+	"slices"
+
+	"github.com/mtlprog/total/internal/chart"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// topHolders is the number of accounts shown in the holder concentration chart.
+const topHolders = 5
+
+// distributionView is the token distribution panel on the market page.
+type distributionView struct {
+	SupplyChart    template.HTML
+	HoldersChart   template.HTML
+	TopHolderShare float64 // Share of window net buys held by the top holders, 0..1
+	Holders        int     // Accounts with a positive net position in the window
+}
+
+// buildDistribution charts token supply over the trade window and the largest holders.
+// Trade events only cover the RPC lookback window, so the supply line is anchored to the
+// current on-chain totals and walked backwards, and holder positions are net buys within
+// the window. Returns nil without trades.
+func buildDistribution(events []service.TradeEvent, yesSold, noSold float64) *distributionView {
+	if len(events) == 0 {
+		return nil
+	}
+
+	points := make([]chart.SupplyPoint, len(events)+1)
+	yes, no := yesSold, noSold
+	for i := len(events) - 1; i >= 0; i-- {
+		evt := events[i]
+		points[i+1] = chart.SupplyPoint{Timestamp: evt.Timestamp, YesSold: yes, NoSold: no}
+		delta := evt.Amount
+		if evt.Kind == service.TradeKindSell {
+			delta = -delta
+		}
+		if evt.Outcome == "YES" {
+			yes = max(yes-delta, 0)
+		} else {
+			no = max(no-delta, 0)
+		}
+	}
+	points[0] = chart.SupplyPoint{Timestamp: events[0].Timestamp, YesSold: yes, NoSold: no}
+
+	holdings := make(map[string]*chart.Bar)
+	for _, evt := range events {
+		h, ok := holdings[evt.User]
+		if !ok {
+			h = &chart.Bar{Label: shortID(evt.User)}
+			holdings[evt.User] = h
+		}
+		delta := evt.Amount
+		if evt.Kind == service.TradeKindSell {
+			delta = -delta
+		}
+		if evt.Outcome == "YES" {
+			h.Yes = max(h.Yes+delta, 0)
+		} else {
+			h.No = max(h.No+delta, 0)
+		}
+	}
+
+	var bars []chart.Bar
+	var total float64
+	for _, h := range holdings {
+		if h.Yes+h.No > 0 {
+			bars = append(bars, *h)
+			total += h.Yes + h.No
+		}
+	}
+	slices.SortFunc(bars, func(a, b chart.Bar) int {
+		if c := cmp.Compare(b.Yes+b.No, a.Yes+a.No); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Label, b.Label)
+	})
+
+	view := &distributionView{
+		SupplyChart: chart.RenderSupplySVG(points),
+		Holders:     len(bars),
+	}
+	if len(bars) > topHolders {
+		bars = bars[:topHolders]
+	}
+	if total > 0 {
+		var top float64
+		for _, b := range bars {
+			top += b.Yes + b.No
+		}
+		view.TopHolderShare = top / total
+	}
+	view.HoldersChart = chart.RenderBarsSVG(bars)
+	return view
+}
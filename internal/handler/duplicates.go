@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+const maxSimilarMarkets = 5
+
+// similarMarket is an open market whose question resembles a new one.
+type similarMarket struct {
+	ID         string
+	Question   string
+	Similarity float64 // 0..1
+}
+
+// similarOpenMarkets returns the unresolved markets whose questions look like question,
+// most similar first.
+func similarOpenMarkets(question string, markets []MarketView) []similarMarket {
+	var similar []similarMarket
+	for _, m := range markets {
+		if m.IsResolved || m.MetadataError != "" {
+			continue
+		}
+		if score := service.QuestionSimilarity(question, m.Question); score >= service.DuplicateThreshold {
+			similar = append(similar, similarMarket{ID: m.ID, Question: m.Question, Similarity: score})
+		}
+	}
+	slices.SortFunc(similar, func(a, b similarMarket) int { return cmp.Compare(b.Similarity, a.Similarity) })
+	if len(similar) > maxSimilarMarkets {
+		similar = similar[:maxSimilarMarkets]
+	}
+	return similar
+}
+
+// deployDuplicates loads the question of the metadata about to be deployed and returns
+// open markets with a similar question. Failures skip the check: it only warns, and
+// the deploy itself does not depend on it.
+func (h *MarketHandler) deployDuplicates(r *http.Request, metadataHash string) (string, []similarMarket) {
+	if h.ipfsClient == nil {
+		return "", nil
+	}
+	ctx := r.Context()
+
+	var metadata model.MarketMetadata
+	if err := h.ipfsClient.GetJSON(ctx, metadataHash, &metadata); err != nil {
+		h.logger.Warn("duplicate check: failed to fetch metadata", "hash", metadataHash, "error", err)
+		return "", nil
+	}
+	if metadata.Question == "" {
+		return "", nil
+	}
+
+	contractIDs, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		h.logger.Warn("duplicate check: failed to list markets", "error", err)
+		return "", nil
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		h.logger.Warn("duplicate check: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	return metadata.Question, similarOpenMarkets(metadata.Question, h.buildMarketViews(ctx, states))
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// issueTrackerURL is where the error page's "report issue" link points.
+const issueTrackerURL = "https://github.com/mtlprog/total/issues/new"
+
+// renderError writes an error response: JSON for API clients, otherwise the error
+// page with navigation and a link to report the problem. Template failures while
+// rendering a page keep using http.Error, since the error page needs templates too.
+func (h *MarketHandler) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	h.renderErrorDetails(w, r, status, message, nil)
+}
+
+// renderErrorDetails is renderError with technical details, e.g. the contract call
+// trace of a failed simulation, shown in a collapsed section of the error page.
+func (h *MarketHandler) renderErrorDetails(w http.ResponseWriter, r *http.Request, status int, message string, details []string) {
+	if wantsJSON(r) {
+		if len(details) == 0 {
+			writeJSONError(w, message, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"error": message, "details": details})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	data := map[string]any{
+		"ErrorCode":    status,
+		"ErrorTitle":   http.StatusText(status),
+		"ErrorMessage": message,
+		"NotFound":     status == http.StatusNotFound,
+		"ServerError":  status >= http.StatusInternalServerError,
+		"Details":      details,
+		"ReportURL":    issueReportURL(r, status, message),
+		"ActiveNav":    "",
+		"AccountID":    accountIDFromCookie(r),
+		"Network":      h.networkName(),
+	}
+	if err := h.tmpl.Render(w, "error", data); err != nil {
+		// Headers already sent — cannot recover, just log
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+// issueReportURL prefills a new issue with the failing request. Only the path is
+// included: query strings may carry account IDs.
+func issueReportURL(r *http.Request, status int, message string) string {
+	title := fmt.Sprintf("Error %d on %s", status, r.URL.Path)
+	body := fmt.Sprintf("**Page:** `%s %s`\n**Error:** %d %s\n\n**What I was doing:**\n\n", r.Method, r.URL.Path, status, message)
+	return issueTrackerURL + "?" + url.Values{"title": {title}, "body": {body}}.Encode()
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// handleFactoryAdmin shows the factory's on-chain settings and, to the signed-in
+// oracle, forms for the admin calls that change them.
+func (h *MarketHandler) handleFactoryAdmin(w http.ResponseWriter, r *http.Request) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+
+	data := h.factoryAdminData(r)
+	settings, err := h.factoryService.FactorySettings(r.Context())
+	if err != nil {
+		h.logger.Warn("failed to read factory settings", "error", err)
+		data["Error"] = "Failed to read factory settings from the contract"
+	} else {
+		data["Settings"] = settings
+		if versions := h.factoryService.ContractVersions(); versions != nil {
+			data["Builds"] = versions.Usage()
+		}
+	}
+	h.renderFactoryAdmin(w, data)
+}
+
+// handleBuildFactoryAdminTx reviews a factory admin call, then builds its transaction
+// once the oracle confirms the summary. Only the signed-in oracle may use it.
+func (h *MarketHandler) handleBuildFactoryAdminTx(w http.ResponseWriter, r *http.Request) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to change factory settings")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	req := service.FactoryAdminRequest{
+		Op:              service.FactoryAdminOp(r.FormValue("op")),
+		WasmHash:        r.FormValue("wasm_hash"),
+		CollateralToken: r.FormValue("collateral_token"),
+		RequestedBy:     h.sessionAccount(r),
+	}
+
+	if r.FormValue("confirm") == "" {
+		summary, err := h.factoryService.ReviewFactoryAdmin(r.Context(), req)
+		if err != nil {
+			h.writeError(w, r, err, "op", req.Op)
+			return
+		}
+		data := h.factoryAdminData(r)
+		data["Settings"] = &summary.Settings
+		data["Confirm"] = summary
+		h.renderFactoryAdmin(w, data)
+		return
+	}
+
+	result, _, err := h.buildIdempotent(r, func() (*model.TransactionResult, error) {
+		return h.factoryService.BuildFactoryAdminTx(r.Context(), req)
+	})
+	if err != nil {
+		h.writeError(w, r, err, "op", req.Op)
+		return
+	}
+	h.audit(r, "factory.admin_built", map[string]string{
+		"op":               string(req.Op),
+		"wasm_hash":        req.WasmHash,
+		"collateral_token": req.CollateralToken,
+	})
+
+	data := map[string]any{
+		"Result":            result,
+		"MarketID":          "new",
+		"ActiveNav":         "oracle",
+		"Network":           h.networkName(),
+		"NetworkPassphrase": h.networkPassphrase,
+		"AccountID":         accountIDFromCookie(r),
+	}
+	h.addAuthExpiry(r, data, result)
+
+	if err := h.tmpl.Render(w, "transaction", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *MarketHandler) factoryAdminData(r *http.Request) map[string]any {
+	return map[string]any{
+		"FactoryContract": h.factoryService.FactoryContractID(),
+		"OraclePublicKey": h.oraclePublicKey,
+		"Manage":          h.isOracleSession(r),
+		"ActiveNav":       "oracle",
+		"Network":         h.networkName(),
+		"AccountID":       accountIDFromCookie(r),
+	}
+}
+
+func (h *MarketHandler) renderFactoryAdmin(w http.ResponseWriter, data map[string]any) {
+	if err := h.tmpl.Render(w, "oracle_factory", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
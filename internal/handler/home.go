@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	closingSoonWindow = 48 * time.Hour
+	newMarketWindow   = 7 * 24 * time.Hour
+	moversWindow      = 24 * time.Hour
+	minMoverChange    = 0.01 // Changes below one point are noise, not movement
+	maxSectionSize    = 4
+)
+
+// homeSection is a highlighted group of markets above the full list.
+type homeSection struct {
+	Title   string
+	Markets []MarketView
+}
+
+// homeSections picks the "Closing within 48h", "New this week" and "Biggest movers"
+// highlights from active markets. Empty sections are omitted; the archive has none.
+func homeSections(markets []MarketView, archive bool, now time.Time) []homeSection {
+	if archive {
+		return nil
+	}
+
+	var closing, fresh, movers []MarketView
+	for _, m := range markets {
+		if m.IsResolved {
+			continue
+		}
+		if !m.EndDate.IsZero() && m.EndDate.After(now) && m.EndDate.Sub(now) <= closingSoonWindow {
+			closing = append(closing, m)
+		}
+		if !m.CreatedAt.IsZero() && now.Sub(m.CreatedAt) <= newMarketWindow {
+			fresh = append(fresh, m)
+		}
+		if m.HasChange && math.Abs(m.Change) >= minMoverChange {
+			movers = append(movers, m)
+		}
+	}
+
+	slices.SortFunc(closing, func(a, b MarketView) int { return a.EndDate.Compare(b.EndDate) })
+	slices.SortFunc(fresh, func(a, b MarketView) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	slices.SortFunc(movers, func(a, b MarketView) int { return cmp.Compare(math.Abs(b.Change), math.Abs(a.Change)) })
+
+	var sections []homeSection
+	for _, s := range []homeSection{
+		{Title: "Closing within 48h", Markets: closing},
+		{Title: "New this week", Markets: fresh},
+		{Title: "Biggest movers (24h)", Markets: movers},
+	} {
+		if len(s.Markets) == 0 {
+			continue
+		}
+		if len(s.Markets) > maxSectionSize {
+			s.Markets = s.Markets[:maxSectionSize]
+		}
+		sections = append(sections, s)
+	}
+	return sections
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+// idempotencyKey reads the client key from the Idempotency-Key header or the
+// idempotency_key form field. Requires a parsed form.
+func idempotencyKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+		return key
+	}
+	return strings.TrimSpace(r.PostFormValue("idempotency_key"))
+}
+
+// requestFingerprint hashes the submitted form (minus the key itself) so a key
+// reused for a different trade is detected.
+func requestFingerprint(r *http.Request) string {
+	values := make(url.Values, len(r.PostForm))
+	for k, v := range r.PostForm {
+		if k != "idempotency_key" {
+			values[k] = v
+		}
+	}
+	sum := sha256.Sum256([]byte(r.URL.Path + "?" + values.Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildIdempotent runs build at most once per idempotency key and route.
+// Without a key (or without a store) build always runs.
+func (h *MarketHandler) buildIdempotent(r *http.Request, build func() (*model.TransactionResult, error)) (result *model.TransactionResult, replayed bool, err error) {
+	key := idempotencyKey(r)
+	if key == "" || h.idempotency == nil {
+		result, err = build()
+		return result, false, err
+	}
+
+	result, replayed, err = h.idempotency.Do(r.Method+" "+r.URL.Path+" "+key, requestFingerprint(r), build)
+	if replayed && err == nil {
+		h.logger.Info("returning previously built transaction", "path", r.URL.Path)
+	}
+	return result, replayed, err
+}
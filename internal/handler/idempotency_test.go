@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+func TestBuildIdempotent(t *testing.T) {
+	h := &MarketHandler{idempotency: service.NewIdempotencyStore(), logger: slog.New(slog.DiscardHandler)}
+	builds := 0
+	build := func() (*model.TransactionResult, error) {
+		builds++
+		return &model.TransactionResult{XDR: fmt.Sprintf("tx-%d", builds)}, nil
+	}
+	do := func(path string, form url.Values, header string) (*model.TransactionResult, bool, error) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("Idempotency-Key", header)
+		}
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		return h.buildIdempotent(req, build)
+	}
+	buy := url.Values{"user_public_key": {"GUSER"}, "outcome": {"YES"}, "amount": {"10"}, "idempotency_key": {"k1"}}
+
+	first, replayed, err := do("/market/C1/buy", buy, "")
+	if err != nil || replayed {
+		t.Fatalf("buildIdempotent() = %v, %v, %v", first, replayed, err)
+	}
+	// The same key from the header, as a double-click retried by a script would send it.
+	header := url.Values{"user_public_key": {"GUSER"}, "outcome": {"YES"}, "amount": {"10"}}
+	if again, replayed, err := do("/market/C1/buy", header, "k1"); err != nil || !replayed || again.XDR != first.XDR {
+		t.Fatalf("repeated buildIdempotent() = %v, %v, %v; want %s replayed", again, replayed, err, first.XDR)
+	}
+
+	changed := url.Values{"user_public_key": {"GUSER"}, "outcome": {"YES"}, "amount": {"20"}, "idempotency_key": {"k1"}}
+	if _, _, err := do("/market/C1/buy", changed, ""); !errors.Is(err, service.ErrIdempotencyKeyReused) {
+		t.Errorf("same key with a different body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	// Keys are scoped to the route, so the same key on another market builds anew.
+	if other, replayed, err := do("/market/C2/buy", buy, ""); err != nil || replayed || other.XDR == first.XDR {
+		t.Errorf("same key on another route = %v, %v, %v; want a new build", other, replayed, err)
+	}
+
+	delete(buy, "idempotency_key")
+	if _, replayed, err := do("/market/C1/buy", buy, ""); err != nil || replayed {
+		t.Errorf("buildIdempotent() without a key = %v, %v; want a new build", replayed, err)
+	}
+	if builds != 3 {
+		t.Errorf("builds = %d, want 3", builds)
+	}
+}
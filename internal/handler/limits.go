@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+const (
+	// maxRequestBodyBytes caps request bodies; the largest legitimate form is a
+	// Stellar URI with a transaction (maxWalletURILen), URL-encoded.
+	maxRequestBodyBytes = 3 * maxWalletURILen
+
+	// maxBulkDeployBodyBytes caps the bulk deploy upload: a market list of up to
+	// service.MaxBulkMarketsBytes, URL-encoded, and the other fields of the form.
+	maxBulkDeployBodyBytes = 3*service.MaxBulkMarketsBytes + maxRequestBodyBytes
+
+	// maxFormFields caps the number of form values per request.
+	maxFormFields = 32
+
+	// defaultFieldLimit is the byte limit of form fields not in formFieldLimits.
+	defaultFieldLimit = 256
+)
+
+// formFieldLimits are the byte limits of form fields that may be longer than
+// defaultFieldLimit.
+var formFieldLimits = map[string]int{
+	"question":          model.MaxQuestionLength,
+	"description":       model.MaxDescriptionLength,
+	"rules":             model.MaxDescriptionLength,
+	"body":              service.MaxCommentLength * 4, // runes of up to 4 bytes
+	"resolution_source": model.MaxResolutionSourceLength,
+	"target":            1024,
+	"return":            2048,
+	"market_ids":        service.MaxSeriesMarkets * 58, // contract IDs and line breaks
+	"quote_token":       1024,
+	"token":             1024,
+	"xdr":               maxWalletURILen,
+	"uri":               maxWalletURILen,
+	"markets":           service.MaxBulkMarketsBytes, // bulk deploy CSV or JSON
+}
+
+// routeBodyLimits are the body limits of routes that accept more than
+// maxRequestBodyBytes, by method and path.
+var routeBodyLimits = map[string]int64{
+	"POST /oracle/bulk": maxBulkDeployBodyBytes,
+}
+
+var errFormTooLarge = errors.New("request body too large")
+
+// formFieldError reports a form field longer than its limit.
+type formFieldError struct {
+	Field string
+	Limit int
+}
+
+func (e *formFieldError) Error() string {
+	return fmt.Sprintf("field %q exceeds %d bytes", e.Field, e.Limit)
+}
+
+// LimitBody caps the body of every request at maxRequestBodyBytes, or its
+// routeBodyLimits entry, so an oversized submission fails while being read instead of
+// being buffered in full.
+func LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			limit, ok := routeBodyLimits[r.Method+" "+r.URL.Path]
+			if !ok {
+				limit = maxRequestBodyBytes
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseLimitedForm parses the request form and checks the number and length of its fields.
+func parseLimitedForm(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return errFormTooLarge
+		}
+		return err
+	}
+
+	fields := 0
+	for name, values := range r.Form {
+		limit, ok := formFieldLimits[name]
+		if !ok {
+			limit = defaultFieldLimit
+		}
+		for _, v := range values {
+			fields++
+			if len(v) > limit {
+				return &formFieldError{Field: name, Limit: limit}
+			}
+		}
+	}
+	if fields > maxFormFields {
+		return fmt.Errorf("too many form fields (%d)", fields)
+	}
+	return nil
+}
+
+// formErrorResponse maps a parseLimitedForm error to a status and message: 413 for an
+// oversized body, 400 otherwise.
+func formErrorResponse(err error) (int, string) {
+	var fieldErr *formFieldError
+	switch {
+	case errors.Is(err, errFormTooLarge):
+		return http.StatusRequestEntityTooLarge, "Request too large"
+	case errors.As(err, &fieldErr):
+		return http.StatusBadRequest, fmt.Sprintf("Field %q is too long (max %d bytes)", fieldErr.Field, fieldErr.Limit)
+	default:
+		return http.StatusBadRequest, "Invalid form data"
+	}
+}
+
+// parseForm parses and checks the request form, rendering the error and returning
+// false when it is invalid.
+func (h *MarketHandler) parseForm(w http.ResponseWriter, r *http.Request) bool {
+	if err := parseLimitedForm(r); err != nil {
+		status, msg := formErrorResponse(err)
+		h.renderError(w, r, status, msg)
+		return false
+	}
+	return true
+}
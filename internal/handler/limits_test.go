@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+func TestBulkDeployAcceptsLargestBatch(t *testing.T) {
+	// Quotes are the worst case: JSON escapes each one and URL encoding triples that.
+	pad := func(prefix string, n int) string {
+		return prefix + strings.Repeat(`"`, n-len(prefix))
+	}
+	markets := make([]service.BulkMarket, service.MaxBulkMarkets)
+	for i := range markets {
+		markets[i] = service.BulkMarket{
+			Question:         pad(fmt.Sprintf("Market %d?", i), model.MaxQuestionLength),
+			Description:      pad("", model.MaxDescriptionLength),
+			ResolutionSource: pad("Official results", model.MaxResolutionSourceLength),
+			Category:         "Politics",
+			CloseTime:        "2027-01-01T00:00:00Z",
+			LiquidityParam:   100,
+			InitialFunding:   70,
+		}
+	}
+	list, err := json.Marshal(markets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := url.Values{"markets": {string(list)}}.Encode()
+
+	parse := func(path string) error {
+		var err error
+		handler := LimitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err = parseLimitedForm(r)
+		}))
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return err
+	}
+
+	if err := parse("/oracle/bulk"); err != nil {
+		t.Fatalf("parseLimitedForm() of a %d byte bulk deploy error = %v", len(body), err)
+	}
+	parsed, err := service.ParseBulkMarkets(list)
+	if err != nil {
+		t.Fatalf("ParseBulkMarkets() error = %v", err)
+	}
+	if _, err := service.ValidateBulkMarkets(parsed, "GORACLE", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("ValidateBulkMarkets() error = %v, want the batch valid", err)
+	}
+
+	if err := parse("/markets"); !errors.Is(err, errFormTooLarge) {
+		t.Errorf("parseLimitedForm() of the same body on another route error = %v, want errFormTooLarge", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	"github.com/mtlprog/total/internal/ipfs"
 	"github.com/mtlprog/total/internal/lmsr"
 	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/notify"
 	"github.com/mtlprog/total/internal/service"
 	"github.com/mtlprog/total/internal/soroban"
 	"github.com/mtlprog/total/internal/stellar"
@@ -31,6 +33,29 @@ type MarketHandler struct {
 	marketService     *service.MarketService
 	factoryService    *service.FactoryService
 	eventService      *service.EventService
+	archiveService    *service.ArchiveService
+	pendingTxs        *service.PendingTxStore
+	pendingWatch      *service.PendingTxWatcher
+	idempotency       *service.IdempotencyStore
+	txLimiter         *service.RateLimiter // nil disables rate limiting
+	authService       *service.AuthService
+	comments          *service.CommentStore
+	referrals         *service.ReferralService
+	alerts            *service.AlertService
+	digests           *service.DigestService  // nil when email is not configured
+	telegramApp       *service.TelegramWebApp // nil when no Telegram bot is configured
+	priceHistory      *service.PriceHistory
+	shareLinks        *service.ShareLinkService
+	ruleTemplates     *service.RuleTemplateStore
+	series            *service.SeriesStore
+	rollovers         *service.RolloverService // nil when IPFS pinning is not configured
+	claims            *service.ClaimService
+	stats             *service.StatsService
+	profiles          *service.ProfileSettings
+	paper             *service.PaperTrading      // nil when paper trading is disabled
+	bulkDeploys       *service.BulkDeployService // nil without a factory contract or IPFS pinning
+	auditLog          *service.AuditLog
+	oracleSigner      *service.OracleSigner // nil unless an oracle key is configured
 	ipfsClient        *ipfs.Client
 	tmpl              *template.Template
 	oraclePublicKey   string
@@ -43,6 +68,29 @@ func NewMarketHandler(
 	marketService *service.MarketService,
 	factoryService *service.FactoryService,
 	eventService *service.EventService,
+	archiveService *service.ArchiveService,
+	pendingTxs *service.PendingTxStore,
+	pendingWatch *service.PendingTxWatcher,
+	idempotency *service.IdempotencyStore,
+	txLimiter *service.RateLimiter,
+	authService *service.AuthService,
+	comments *service.CommentStore,
+	referrals *service.ReferralService,
+	alerts *service.AlertService,
+	digests *service.DigestService,
+	telegramApp *service.TelegramWebApp,
+	priceHistory *service.PriceHistory,
+	shareLinks *service.ShareLinkService,
+	ruleTemplates *service.RuleTemplateStore,
+	series *service.SeriesStore,
+	rollovers *service.RolloverService,
+	claims *service.ClaimService,
+	stats *service.StatsService,
+	profiles *service.ProfileSettings,
+	paper *service.PaperTrading,
+	bulkDeploys *service.BulkDeployService,
+	auditLog *service.AuditLog,
+	oracleSigner *service.OracleSigner,
 	ipfsClient *ipfs.Client,
 	tmpl *template.Template,
 	oraclePublicKey string,
@@ -53,6 +101,29 @@ func NewMarketHandler(
 		marketService:     marketService,
 		factoryService:    factoryService,
 		eventService:      eventService,
+		archiveService:    archiveService,
+		pendingTxs:        pendingTxs,
+		pendingWatch:      pendingWatch,
+		idempotency:       idempotency,
+		txLimiter:         txLimiter,
+		authService:       authService,
+		comments:          comments,
+		referrals:         referrals,
+		alerts:            alerts,
+		digests:           digests,
+		telegramApp:       telegramApp,
+		priceHistory:      priceHistory,
+		shareLinks:        shareLinks,
+		ruleTemplates:     ruleTemplates,
+		series:            series,
+		rollovers:         rollovers,
+		claims:            claims,
+		stats:             stats,
+		profiles:          profiles,
+		paper:             paper,
+		bulkDeploys:       bulkDeploys,
+		auditLog:          auditLog,
+		oracleSigner:      oracleSigner,
 		ipfsClient:        ipfsClient,
 		tmpl:              tmpl,
 		oraclePublicKey:   oraclePublicKey,
@@ -92,21 +163,86 @@ func setAccountIDCookie(w http.ResponseWriter, accountID string) {
 func (h *MarketHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /", h.handleListMarkets)
 	mux.HandleFunc("GET /markets", h.handleListMarkets)
+	mux.HandleFunc("GET /markets/archive", h.handleMarketArchive)
 	mux.HandleFunc("GET /market/{id}", h.handleMarketDetail)
-	mux.HandleFunc("POST /market/{id}/quote", h.handleGetQuote)
-	mux.HandleFunc("POST /market/{id}/buy", h.handleBuildBuyTx)
-	mux.HandleFunc("POST /market/{id}/sell", h.handleBuildSellTx)
-	mux.HandleFunc("POST /market/{id}/resolve", h.handleResolveMarket)
+	mux.HandleFunc("POST /market/{id}/quote", h.protectTx("quote", h.handleGetQuote))
+	mux.HandleFunc("POST /market/{id}/buy", h.protectTx("trade", h.handleBuildBuyTx))
+	mux.HandleFunc("POST /market/{id}/sell", h.protectTx("trade", h.handleBuildSellTx))
+	mux.HandleFunc("POST /market/{id}/resolve", h.protectTx("oracle", h.handleResolveMarket))
 	mux.HandleFunc("POST /market/{id}/claim", h.handleBuildClaimTx)
-	mux.HandleFunc("POST /market/{id}/withdraw", h.handleBuildWithdrawTx)
+	mux.HandleFunc("POST /market/{id}/withdraw", h.protectTx("oracle", h.handleBuildWithdrawTx))
 	mux.HandleFunc("GET /market/{id}/yes", h.handleOutcomePage)
 	mux.HandleFunc("GET /market/{id}/no", h.handleOutcomePage)
+	mux.HandleFunc("GET /market/{id}/close.ics", h.handleMarketCalendar)
+	mux.HandleFunc("GET /market/{id}/curve.svg", h.handleCostCurve)
+	mux.HandleFunc("POST /market/{id}/share-link", h.handleCreateShareLink)
+	mux.HandleFunc("GET /s/{code}", h.handleShareLink)
 	mux.HandleFunc("POST /account", h.handleSetAccount)
+	mux.HandleFunc("GET /pending", h.handlePendingList)
+	mux.HandleFunc("GET /pending/{id}", h.handleSignElsewhere)
+	mux.HandleFunc("GET /pending/{id}/qr.svg", h.handlePendingQR)
+	mux.HandleFunc("GET /pending/{id}/status", h.handlePendingStatus)
+	mux.HandleFunc("POST /pending/{id}/regenerate", h.protectTx("refresh", h.handleRegeneratePending))
+	mux.HandleFunc("POST /pending/{id}/dismiss", h.handleDismissPending)
+	mux.HandleFunc("POST /tx/refresh", h.protectTx("refresh", h.handleRefreshTx))
+	mux.HandleFunc("POST /tx/decode", h.handleDecodeTx)
+	mux.HandleFunc("POST /tx/submit", h.protectTx("submit", h.handleSubmitTx))
+	mux.HandleFunc("POST /tx/status", h.handleTxStatusLookup)
+	mux.HandleFunc("GET /tx/{hash}", h.handleTxStatus)
+	mux.HandleFunc("GET /auth", h.handleSignIn)
+	mux.HandleFunc("POST /auth", h.handleVerifySignIn)
+	mux.HandleFunc("POST /auth/logout", h.handleSignOut)
+	mux.HandleFunc("GET /u/{pubkey}", h.handleProfile)
+	mux.HandleFunc("POST /u/{pubkey}/visibility", h.handleProfileVisibility)
+	mux.HandleFunc("GET /paper", h.handlePaper)
+	mux.HandleFunc("POST /paper/reset", h.handlePaperReset)
+	mux.HandleFunc("POST /market/{id}/paper", h.protectTx("quote", h.handlePaperTrade))
+	mux.HandleFunc("POST /market/{id}/comments", h.handlePostComment)
+	mux.HandleFunc("POST /market/{id}/comments/{comment}/flag", h.handleFlagComment)
+	mux.HandleFunc("POST /market/{id}/comments/{comment}/hide", h.handleHideComment)
+	mux.HandleFunc("POST /market/{id}/alerts", h.protectTx("alert", h.handleCreateAlert))
+	mux.HandleFunc("GET /alerts/{id}/confirm", h.handleAlertConfirmLink)
+	mux.HandleFunc("POST /alerts/{id}/confirm", h.handleConfirmAlert)
+	mux.HandleFunc("GET /alerts/{id}/unsubscribe", h.handleUnsubscribeConfirm)
+	mux.HandleFunc("POST /alerts/{id}/unsubscribe", h.handleUnsubscribe)
+	mux.HandleFunc("POST /email/subscribe", h.protectTx("email", h.handleSubscribeEmail))
+	mux.HandleFunc("GET /email/{id}/confirm", h.handleEmailLink("confirm"))
+	mux.HandleFunc("POST /email/{id}/confirm", h.handleConfirmEmail)
+	mux.HandleFunc("GET /email/{id}/unsubscribe", h.handleEmailLink("unsubscribe"))
+	mux.HandleFunc("POST /email/{id}/unsubscribe", h.handleUnsubscribeEmail)
+	mux.HandleFunc("GET /tg/markets", h.handleTelegramMarkets)
+	mux.HandleFunc("GET /tg/market/{id}", h.handleTelegramMarket)
+	mux.HandleFunc("POST /tg/market/{id}/alert", h.handleTelegramAlert)
+	mux.HandleFunc("GET /market/{id}/countdown", h.handleMarketCountdown)
+	mux.HandleFunc("POST /timezone", h.handleSetTimezone)
 	mux.HandleFunc("GET /oracle", h.handleOracleAdmin)
+	mux.HandleFunc("GET /oracle/history", h.handleOracleHistory)
+	mux.HandleFunc("GET /oracle/simulate", h.handleSimulateMarket)
+	mux.HandleFunc("GET /oracle/audit", h.handleAuditLog)
+	mux.HandleFunc("GET /oracle/audit.json", h.handleAuditExport("json"))
+	mux.HandleFunc("GET /oracle/audit.csv", h.handleAuditExport("csv"))
+	mux.HandleFunc("GET /oracle/bulk", h.handleBulkDeploy)
+	mux.HandleFunc("POST /oracle/bulk", h.handlePrepareBulkDeploy)
+	mux.HandleFunc("GET /oracle/bulk/{id}", h.handleDeployBatch)
+	mux.HandleFunc("POST /oracle/bulk/{id}/next", h.handleBuildBatchDeployTx)
+	mux.HandleFunc("POST /oracle/bulk/{id}/delete", h.handleDeleteDeployBatch)
+	mux.HandleFunc("POST /oracle/clone", h.handleCloneMarket)
+	mux.HandleFunc("POST /oracle/sign", h.handleOracleSign)
+	mux.HandleFunc("GET /oracle/queue", h.handleOracleQueue)
+	mux.HandleFunc("GET /oracle/queue/export", h.handleExportOracleQueue)
+	mux.HandleFunc("POST /oracle/queue/rebuild", h.handleRebuildOracleQueue)
+	mux.HandleFunc("POST /oracle/rules", h.handleSaveRuleTemplate)
+	mux.HandleFunc("POST /oracle/rules/{id}/delete", h.handleDeleteRuleTemplate)
+	mux.HandleFunc("GET /series/{id}", h.handleSeries)
+	mux.HandleFunc("POST /oracle/series", h.handleSaveSeries)
+	mux.HandleFunc("POST /oracle/series/{id}/delete", h.handleDeleteSeries)
+	mux.HandleFunc("GET /oracle/factory", h.handleFactoryAdmin)
+	mux.HandleFunc("POST /oracle/factory", h.handleBuildFactoryAdminTx)
 	mux.HandleFunc("GET /deploy", h.handleRedirectToOracle)
-	mux.HandleFunc("POST /deploy", h.handleBuildDeployTx)
+	mux.HandleFunc("POST /deploy", h.protectTx("oracle", h.handleBuildDeployTx))
 	mux.HandleFunc("GET /health", h.handleHealth)
-	mux.HandleFunc("POST /api/quote/{id}", h.handleAPIQuote)
+	mux.HandleFunc("POST /api/quote/{id}", h.protectTx("quote", h.handleAPIQuote))
+	mux.HandleFunc("GET /api/v1/markets/{id}/state", h.handleAPIMarketState)
 	mux.HandleFunc("POST /api/mtl-wallet", h.handleMTLWallet)
 }
 
@@ -120,18 +256,25 @@ func (h *MarketHandler) networkName() string {
 
 // MarketView represents a market for display in templates.
 type MarketView struct {
-	ID             string
-	Question       string
-	Description    string
-	PriceYes       float64
-	PriceNo        float64
-	YesSold        float64
-	NoSold         float64
-	IsResolved     bool
-	Resolution     string
-	LiquidityParam float64
-	MetadataHash   string
-	MetadataError  string // Non-empty when IPFS metadata failed to load
+	ID               string    `json:"id"`
+	Question         string    `json:"question"`
+	Description      string    `json:"description,omitempty"`
+	ResolutionSource string    `json:"resolution_source,omitempty"` // How the question resolves, from metadata
+	PriceYes         float64   `json:"price_yes"`
+	PriceNo          float64   `json:"price_no"`
+	YesSold          float64   `json:"yes_sold"`
+	NoSold           float64   `json:"no_sold"`
+	IsResolved       bool      `json:"resolved"`
+	Resolution       string    `json:"resolution,omitempty"`
+	LiquidityParam   float64   `json:"liquidity_param"`
+	MetadataHash     string    `json:"metadata_hash,omitempty"`
+	MetadataError    string    `json:"metadata_error,omitempty"` // Non-empty when IPFS metadata failed to load
+	EndDate          time.Time `json:"end_date,omitzero"`        // From metadata; zero when unknown
+	CreatedAt        time.Time `json:"created_at,omitzero"`      // From metadata; zero when unknown
+	Change           float64   `json:"change_24h,omitempty"`     // YES price change over the last 24h, valid when HasChange
+	HasChange        bool      `json:"-"`
+
+	Condition *model.MarketCondition `json:"condition,omitempty"` // Parent market, from metadata
 }
 
 // shortID formats an ID as "first8...last8" for display.
@@ -143,17 +286,44 @@ func shortID(id string) string {
 	return id[:8] + "..." + id[len(id)-8:]
 }
 
-// handleListMarkets renders the list of all markets from factory.
+// handleListMarkets renders the list of markets from factory, without archived markets.
 func (h *MarketHandler) handleListMarkets(w http.ResponseWriter, r *http.Request) {
+	// "GET /" also matches every path without a more specific route.
+	if r.URL.Path != "/" && r.URL.Path != "/markets" {
+		h.renderError(w, r, http.StatusNotFound, "Page not found")
+		return
+	}
+	h.renderMarketList(w, r, false)
+}
+
+// handleMarketArchive renders the list of archived markets.
+func (h *MarketHandler) handleMarketArchive(w http.ResponseWriter, r *http.Request) {
+	h.renderMarketList(w, r, true)
+}
+
+// isArchived reports whether a market has been moved to the archive.
+func (h *MarketHandler) isArchived(contractID string) bool {
+	return h.archiveService != nil && h.archiveService.IsArchived(contractID)
+}
+
+// renderMarketList renders either the main market list or the archive.
+func (h *MarketHandler) renderMarketList(w http.ResponseWriter, r *http.Request, archive bool) {
 	ctx := r.Context()
+	w.Header().Add("Vary", "Accept")
+	asJSON := wantsJSON(r)
 
 	accountID := accountIDFromCookie(r)
 
 	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		if asJSON {
+			h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+			return
+		}
 		data := map[string]any{
 			"Markets":         []MarketView{},
 			"OraclePublicKey": h.oraclePublicKey,
 			"Error":           "Factory contract not configured",
+			"Archive":         archive,
 			"ActiveNav":       "markets",
 			"Network":         h.networkName(),
 			"AccountID":       accountID,
@@ -169,10 +339,15 @@ func (h *MarketHandler) handleListMarkets(w http.ResponseWriter, r *http.Request
 	contractIDs, err := h.factoryService.ListMarkets(ctx)
 	if err != nil {
 		h.logger.Error("failed to list markets", "error", err)
+		if asJSON {
+			h.renderError(w, r, http.StatusBadGateway, "Failed to fetch markets from factory")
+			return
+		}
 		data := map[string]any{
 			"Markets":         []MarketView{},
 			"OraclePublicKey": h.oraclePublicKey,
 			"Error":           "Failed to fetch markets from factory",
+			"Archive":         archive,
 			"ActiveNav":       "markets",
 			"Network":         h.networkName(),
 			"AccountID":       accountID,
@@ -184,18 +359,51 @@ func (h *MarketHandler) handleListMarkets(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get states for all markets
-	states, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+	// Split off archived markets; only one of the two lists is rendered
+	var listed []string
+	var archivedCount int
+	for _, id := range contractIDs {
+		if h.isArchived(id) {
+			archivedCount++
+			if !archive {
+				continue
+			}
+		} else if archive {
+			continue
+		}
+		listed = append(listed, id)
+	}
+
+	// Get states for listed markets. Markets whose state failed to load are counted
+	// rather than dropped silently, so they do not look delisted.
+	loaded, err := h.factoryService.GetMarketStates(ctx, listed)
 	if err != nil {
-		h.logger.Warn("failed to get some market states", "error", err)
+		h.logger.Warn("failed to get market states", "error", err)
+	}
+	unavailable := make([]string, len(loaded.Failed))
+	for i, f := range loaded.Failed {
+		unavailable[i] = f.ContractID
 	}
 
 	// Convert states to views with metadata from IPFS
-	markets := h.buildMarketViews(ctx, states)
+	markets := h.listedMarkets(h.buildMarketViews(ctx, loaded.States))
+	if asJSON {
+		h.writeJSON(w, marketListResponse{
+			Markets:          markets,
+			ArchivedCount:    archivedCount,
+			Unavailable:      unavailable,
+			UnavailableCount: len(unavailable),
+		})
+		return
+	}
 
 	data := map[string]any{
 		"Markets":         markets,
+		"Sections":        homeSections(markets, archive, time.Now()),
 		"OraclePublicKey": h.oraclePublicKey,
+		"Archive":         archive,
+		"ArchivedCount":   archivedCount,
+		"Unavailable":     len(unavailable),
 		"ActiveNav":       "markets",
 		"Network":         h.networkName(),
 		"AccountID":       accountID,
@@ -225,6 +433,7 @@ func (h *MarketHandler) buildMarketViews(ctx context.Context, states []service.M
 				YesSold:      float64(s.YesSold) / float64(soroban.ScaleFactor),
 				NoSold:       float64(s.NoSold) / float64(soroban.ScaleFactor),
 				IsResolved:   s.Resolved,
+				Resolution:   s.WinningOutcome,
 				MetadataHash: s.MetadataHash,
 			}
 
@@ -235,12 +444,21 @@ func (h *MarketHandler) buildMarketViews(ctx context.Context, states []service.M
 					h.logger.Warn("failed to fetch metadata", "hash", s.MetadataHash, "error", err)
 					view.Question = "Market " + shortID(s.ContractID)
 					view.MetadataError = "Failed to load market details from IPFS"
+					h.checkMetadata(s.ContractID, err)
 				} else {
+					h.checkMetadata(s.ContractID, metadata.Validate())
 					view.Question = metadata.Question
 					view.Description = metadata.Description
+					view.ResolutionSource = metadata.ResolutionSource
+					view.EndDate = metadata.EndDate
+					view.CreatedAt = metadata.CreatedAt
+					view.Condition = metadata.Condition
 				}
 			} else {
 				view.Question = "Market " + shortID(s.ContractID)
+				if s.MetadataHash == "" {
+					h.flagQuarantine(s.ContractID, service.QuarantineMissingMetadata, "the contract has no metadata hash")
+				}
 			}
 
 			views[idx] = view
@@ -248,44 +466,91 @@ func (h *MarketHandler) buildMarketViews(ctx context.Context, states []service.M
 	}
 
 	wg.Wait()
+
+	if h.priceHistory != nil {
+		now := time.Now()
+		for i := range views {
+			views[i].Change, views[i].HasChange = h.priceHistory.Change(views[i].ID, moversWindow, now)
+		}
+	}
 	return views
 }
 
+// checkMetadata quarantines a market whose metadata failed to load or validate, and
+// releases it once the metadata is valid. It does nothing unless --strict-markets is set.
+func (h *MarketHandler) checkMetadata(contractID string, err error) {
+	if err != nil {
+		h.flagQuarantine(contractID, service.QuarantineInvalidMetadata, err.Error())
+		return
+	}
+	if q := h.quarantine(); q != nil {
+		q.Clear(contractID, service.QuarantineMissingMetadata)
+		q.Clear(contractID, service.QuarantineInvalidMetadata)
+	}
+}
+
+func (h *MarketHandler) flagQuarantine(contractID string, reason service.QuarantineReason, detail string) {
+	if q := h.quarantine(); q != nil {
+		q.Flag(contractID, reason, detail)
+	}
+}
+
+func (h *MarketHandler) quarantine() *service.Quarantine {
+	if h.factoryService == nil {
+		return nil
+	}
+	return h.factoryService.Quarantine()
+}
+
+// listedMarkets drops quarantined markets from a public list.
+func (h *MarketHandler) listedMarkets(views []MarketView) []MarketView {
+	q := h.quarantine()
+	if q == nil {
+		return views
+	}
+	return slices.DeleteFunc(views, func(v MarketView) bool { return q.Contains(v.ID) })
+}
+
 // handleMarketDetail renders a single market's detail page.
 func (h *MarketHandler) handleMarketDetail(w http.ResponseWriter, r *http.Request) {
 	contractID := r.PathValue("id")
 	if contractID == "" {
-		http.Error(w, "Contract ID required", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Contract ID required")
 		return
 	}
+	h.captureReferral(w, r)
+	w.Header().Add("Vary", "Accept")
 
 	if h.factoryService == nil || !h.factoryService.HasFactory() {
-		http.Error(w, "Factory contract not configured", http.StatusServiceUnavailable)
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
 		return
 	}
 
 	ctx := r.Context()
 
 	// Get market state
-	states, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
+	loaded, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
 	if err != nil {
 		h.logger.Error("failed to get market state", "contract_id", contractID, "error", err)
 		h.writeError(w, r, err, "contract_id", contractID)
 		return
 	}
+	states := loaded.States
 	if len(states) == 0 {
-		http.Error(w, "Market not found", http.StatusNotFound)
+		h.renderError(w, r, http.StatusNotFound, "Market not found")
 		return
 	}
 
 	state := states[0]
 
 	market := model.Market{
-		ID:       state.ContractID,
-		YesSold:  float64(state.YesSold) / float64(soroban.ScaleFactor),
-		NoSold:   float64(state.NoSold) / float64(soroban.ScaleFactor),
-		PriceYes: state.PriceYes,
-		PriceNo:  state.PriceNo,
+		ID:               state.ContractID,
+		YesSold:          float64(state.YesSold) / float64(soroban.ScaleFactor),
+		NoSold:           float64(state.NoSold) / float64(soroban.ScaleFactor),
+		PriceYes:         state.PriceYes,
+		PriceNo:          state.PriceNo,
+		ContractVersion:  state.ContractVersion,
+		ContractWasmHash: state.WasmHash,
 	}
 
 	if state.Resolved && state.WinningOutcome != "" {
@@ -305,12 +570,22 @@ func (h *MarketHandler) handleMarketDetail(w http.ResponseWriter, r *http.Reques
 			market.Category = metadata.Category
 			market.EndDate = metadata.EndDate
 			market.CreatedAt = metadata.CreatedAt
+			market.Scalar = metadata.Scalar
+			market.Condition = metadata.Condition
 		}
 		market.MetadataHash = state.MetadataHash
 	} else {
 		market.Question = "Market " + shortID(contractID)
 	}
 
+	var resolvedAt time.Time
+	if market.IsResolved() {
+		resolvedAt = h.resolvedAt(ctx, contractID)
+		if !resolvedAt.IsZero() {
+			market.ResolvedAt = &resolvedAt
+		}
+	}
+
 	// Resolve account: cookie first, then query param override
 	accountID := accountIDFromCookie(r)
 	accountKey := strings.TrimSpace(r.URL.Query().Get("account"))
@@ -338,6 +613,7 @@ func (h *MarketHandler) handleMarketDetail(w http.ResponseWriter, r *http.Reques
 	// Fetch trade events and build price chart
 	var tradeEvents []service.TradeEvent
 	var priceChart string
+	var distribution *distributionView
 	var eventsError string
 	if h.eventService != nil {
 		events, err := h.eventService.GetTradeEvents(ctx, contractID)
@@ -347,24 +623,73 @@ func (h *MarketHandler) handleMarketDetail(w http.ResponseWriter, r *http.Reques
 		} else {
 			tradeEvents = events
 			if len(events) > 0 {
-				points := eventsToChartPoints(events)
-				priceChart = chart.RenderPriceChart(points, chart.DefaultWidth, chart.DefaultHeight)
+				distribution = buildDistribution(events, market.YesSold, market.NoSold)
 			}
 		}
 	}
+	// Sampled prices reach further back than the ~24h of trade events once the
+	// history has been kept for a while.
+	points := eventsToChartPoints(tradeEvents)
+	if h.priceHistory != nil {
+		if history := h.priceHistory.Series(contractID); len(history) > 1 && (len(points) == 0 || history[0].Timestamp.Before(points[0].Timestamp)) {
+			points = history
+		}
+	}
+	if len(points) > 0 {
+		priceChart = chart.RenderPriceChart(points, chart.DefaultWidth, chart.DefaultHeight)
+	}
+
+	if wantsJSON(r) {
+		resp := marketDetailResponse{
+			Market:   &market,
+			Archived: h.isArchived(contractID),
+			Trades:   tradesJSON(tradeEvents),
+		}
+		if userBalance != nil {
+			resp.Balance = &balanceJSON{Account: accountID, Yes: userBalance.YesBalance, No: userBalance.NoBalance}
+		}
+		h.writeJSON(w, resp)
+		return
+	}
+
+	sessionAccount := h.sessionAccount(r)
+
+	var pendingTxs []service.PendingTx
+	if sessionAccount != "" && h.pendingTxs != nil {
+		pendingTxs = h.pendingTxs.ListForContract(sessionAccount, contractID)
+	}
+
+	condition, linked := h.marketLinks(ctx, &market)
 
 	data := map[string]any{
 		"Market":          &market,
 		"OraclePublicKey": h.oraclePublicKey,
+		"PendingTxs":      pendingTxs,
+		"Now":             time.Now(),
+		"TZ":              userLocation(r),
+		"Countdown":       countdownView{ID: contractID, EndDate: market.EndDate, Resolved: market.IsResolved(), ResolvedAt: resolvedAt},
 		"PriceChart":      priceChart,
+		"Distribution":    distribution,
 		"TradeEvents":     tradeEvents,
 		"EventsError":     eventsError,
+		"Archived":        h.isArchived(contractID),
+		"Comments":        h.marketComments(contractID),
+		"SessionAccount":  sessionAccount,
+		"IsOracleSession": sessionAccount != "" && sessionAccount == h.oraclePublicKey,
+		"AlertChannels":   h.alertChannels(),
+		"EmailUpdates":    h.digestsEnabled(),
 		"ActiveNav":       "markets",
 		"Network":         h.networkName(),
 		"UserBalance":     userBalance,
 		"AccountID":       accountID,
 		"BalanceError":    balanceError,
+		"Condition":       condition,
+		"LinkedMarkets":   linked,
+		"SeriesLinks":     h.marketSeries(contractID),
+		"Claim":           h.marketClaim(&market, userBalance, time.Now()),
+		"PaperTrading":    h.paper != nil,
 	}
+	h.addTradePrefill(r, contractID, data)
 
 	if err := h.tmpl.Render(w, "market", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
@@ -374,8 +699,7 @@ func (h *MarketHandler) handleMarketDetail(w http.ResponseWriter, r *http.Reques
 
 // handleGetQuote returns a price quote for buying tokens.
 func (h *MarketHandler) handleGetQuote(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -385,29 +709,94 @@ func (h *MarketHandler) handleGetQuote(w http.ResponseWriter, r *http.Request) {
 
 	outcome, err := model.ParseOutcome(outcomeStr)
 	if err != nil {
-		http.Error(w, "Invalid outcome: must be YES or NO", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
 		return
 	}
 
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil || amount <= 0 {
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid amount")
 		return
 	}
 
-	quote, err := h.marketService.GetQuote(r.Context(), contractID, outcome, amount)
-	if err != nil {
-		h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "amount", amount)
+	collateral, ok := h.spendsCollateral(w, r)
+	if !ok {
 		return
 	}
+	compare, ok := comparesQuote(r)
+	if !ok {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid quote source: must be contract")
+		return
+	}
+
+	var quote *service.Quote
+	if collateral {
+		bq, err := h.marketService.QuoteForBudget(r.Context(), contractID, outcome, amount)
+		if err != nil {
+			h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "budget", amount)
+			return
+		}
+		amount, quote = bq.Shares, bq.Quote
+	} else {
+		quote, err = h.marketService.GetQuote(r.Context(), contractID, outcome, amount)
+		if err != nil {
+			h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "amount", amount)
+			return
+		}
+	}
+
+	var estimate *service.QuoteEstimate
+	if compare {
+		estimate = h.quoteEstimate(r, contractID, outcome, amount, quote)
+	}
+	h.renderQuote(w, r, contractID, outcome, amount, "", h.defaultSlippage(r, contractID, outcome), quote, estimate)
+}
+
+// renderQuote renders the quote page. With a user public key it doubles as the buy
+// confirmation step: the form carries the quote token the buy endpoint requires.
+// estimate is the Go LMSR estimate shown next to the quote, or nil.
+func (h *MarketHandler) renderQuote(w http.ResponseWriter, r *http.Request, contractID string, outcome model.Outcome, amount float64, userPubKey string, slippage float64, quote *service.Quote, estimate *service.QuoteEstimate) {
+	cost := float64(quote.Cost) / float64(soroban.ScaleFactor)
+	suggested, _ := h.suggestedSlippage(contractID, outcome)
+	w.Header().Add("Vary", "Accept")
+	if wantsJSON(r) {
+		h.writeJSON(w, quoteResponse{
+			ContractID:        contractID,
+			Outcome:           outcome,
+			Amount:            amount,
+			Cost:              cost,
+			PricePerShare:     cost / amount,
+			PriceAfter:        quote.PriceAfter,
+			QuoteToken:        quote.Token,
+			ExpiresAt:         quote.ExpiresAt,
+			Slippage:          slippage,
+			SuggestedSlippage: suggested,
+			Estimate:          quoteEstimateResponse(estimate),
+		})
+		return
+	}
+	var warning string
+	if estimate != nil && estimate.Mismatch {
+		warning = quoteMismatchWarning(estimate)
+	}
 
-	// Return quote page
 	data := map[string]any{
-		"Quote":      quote,
-		"ContractID": contractID,
-		"ActiveNav":  "markets",
-		"Network":    h.networkName(),
-		"AccountID":  accountIDFromCookie(r),
+		"Quote":         quote,
+		"ContractID":    contractID,
+		"Outcome":       outcome,
+		"Amount":        amount,
+		"Cost":          cost,
+		"PricePerShare": cost / amount,
+		"UserPublicKey": userPubKey,
+		"Slippage":      slippage,
+		"SlippageAuto":  usesAutoSlippage(r),
+		"Suggested":     suggested,
+		"Estimate":      estimate,
+		"QuoteWarning":  warning,
+		"TZ":            userLocation(r),
+		"ActiveNav":     "markets",
+		"Network":       h.networkName(),
+		"AccountID":     accountIDFromCookie(r),
 	}
 
 	if err := h.tmpl.Render(w, "quote", data); err != nil {
@@ -418,8 +807,7 @@ func (h *MarketHandler) handleGetQuote(w http.ResponseWriter, r *http.Request) {
 
 // handleBuildBuyTx builds a transaction for buying tokens.
 func (h *MarketHandler) handleBuildBuyTx(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -427,39 +815,43 @@ func (h *MarketHandler) handleBuildBuyTx(w http.ResponseWriter, r *http.Request)
 	userPubKey := strings.TrimSpace(r.FormValue("user_public_key"))
 	outcomeStr := r.FormValue("outcome")
 	amountStr := r.FormValue("amount")
-	slippageStr := r.FormValue("slippage")
 
 	// Validate public key using Stellar SDK
 	if _, err := keypair.ParseAddress(userPubKey); err != nil {
-		http.Error(w, "Invalid Stellar public key", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid Stellar public key")
 		return
 	}
 
 	outcome, err := model.ParseOutcome(outcomeStr)
 	if err != nil {
-		http.Error(w, "Invalid outcome: must be YES or NO", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
 		return
 	}
 
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil || amount <= 0 {
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid amount")
 		return
 	}
 
-	// Parse slippage (default 1%, max 10%)
-	slippage := model.DefaultSlippage
-	if slippageStr != "" {
-		s, err := strconv.ParseFloat(slippageStr, 64)
+	slippage, ok := h.tradeSlippage(w, r, contractID, outcome)
+	if !ok {
+		return
+	}
+
+	collateral, ok := h.spendsCollateral(w, r)
+	if !ok {
+		return
+	}
+	if collateral {
+		// The token amount is only known once quoted: confirm it on the quote page.
+		bq, err := h.marketService.QuoteForBudget(r.Context(), contractID, outcome, amount)
 		if err != nil {
-			http.Error(w, "Invalid slippage: must be a number", http.StatusBadRequest)
-			return
-		}
-		if s <= 0 || s > model.MaxSlippage {
-			http.Error(w, fmt.Sprintf("Invalid slippage: must be between 0 and %.0f%% (e.g., 0.01 for 1%%)", model.MaxSlippage*100), http.StatusBadRequest)
+			h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "budget", amount)
 			return
 		}
-		slippage = s
+		h.renderQuote(w, r, contractID, outcome, bq.Shares, userPubKey, slippage, bq.Quote, nil)
+		return
 	}
 
 	req := service.BuyRequest{
@@ -469,14 +861,33 @@ func (h *MarketHandler) handleBuildBuyTx(w http.ResponseWriter, r *http.Request)
 			Outcome:       outcome,
 			ShareAmount:   amount,
 			Slippage:      slippage,
+			Referral:      referralFromCookie(r),
 		},
+		QuoteToken: r.FormValue("quote_token"),
 	}
 
-	result, err := h.marketService.BuildBuyTx(r.Context(), req)
+	result, replayed, err := h.buildIdempotent(r, func() (*model.TransactionResult, error) {
+		return h.marketService.BuildBuyTx(r.Context(), req)
+	})
+	if errors.Is(err, service.ErrQuoteTokenRequired) || errors.Is(err, service.ErrQuoteTokenExpired) {
+		// No confirmed price (JS disabled, or the user waited too long): show a fresh
+		// quote and let the user confirm it instead of failing the buy.
+		quote, qErr := h.marketService.GetQuote(r.Context(), contractID, outcome, amount)
+		if qErr != nil {
+			h.writeError(w, r, qErr, "contract_id", contractID, "outcome", outcome, "amount", amount)
+			return
+		}
+		h.renderQuote(w, r, contractID, outcome, amount, userPubKey, slippage, quote, nil)
+		return
+	}
 	if err != nil {
 		h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "amount", amount)
 		return
 	}
+	if !replayed {
+		h.recordPendingTx(service.PendingTxBuy, contractID, h.sessionAccount(r), result, &req.TradeRequest)
+		h.trackReferral(result)
+	}
 
 	// Render XDR result page
 	data := map[string]any{
@@ -488,6 +899,8 @@ func (h *MarketHandler) handleBuildBuyTx(w http.ResponseWriter, r *http.Request)
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -496,8 +909,7 @@ func (h *MarketHandler) handleBuildBuyTx(w http.ResponseWriter, r *http.Request)
 
 // handleBuildSellTx builds a transaction for selling tokens.
 func (h *MarketHandler) handleBuildSellTx(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -505,39 +917,37 @@ func (h *MarketHandler) handleBuildSellTx(w http.ResponseWriter, r *http.Request
 	userPubKey := strings.TrimSpace(r.FormValue("user_public_key"))
 	outcomeStr := r.FormValue("outcome")
 	amountStr := r.FormValue("amount")
-	slippageStr := r.FormValue("slippage")
 
 	// Validate public key using Stellar SDK
 	if _, err := keypair.ParseAddress(userPubKey); err != nil {
-		http.Error(w, "Invalid Stellar public key", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid Stellar public key")
 		return
 	}
 
 	outcome, err := model.ParseOutcome(outcomeStr)
 	if err != nil {
-		http.Error(w, "Invalid outcome: must be YES or NO", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
 		return
 	}
 
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil || amount <= 0 {
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid amount")
 		return
 	}
 
-	// Parse slippage (default 1%, max 10%)
-	slippage := model.DefaultSlippage
-	if slippageStr != "" {
-		s, err := strconv.ParseFloat(slippageStr, 64)
-		if err != nil {
-			http.Error(w, "Invalid slippage: must be a number", http.StatusBadRequest)
-			return
-		}
-		if s <= 0 || s > model.MaxSlippage {
-			http.Error(w, fmt.Sprintf("Invalid slippage: must be between 0 and %.0f%% (e.g., 0.01 for 1%%)", model.MaxSlippage*100), http.StatusBadRequest)
-			return
-		}
-		slippage = s
+	collateral, ok := h.spendsCollateral(w, r)
+	if !ok {
+		return
+	}
+	if collateral {
+		h.renderError(w, r, http.StatusBadRequest, "Sell amounts are in tokens: switch the form to tokens to sell")
+		return
+	}
+
+	slippage, ok := h.tradeSlippage(w, r, contractID, outcome)
+	if !ok {
+		return
 	}
 
 	req := service.SellRequest{
@@ -547,14 +957,21 @@ func (h *MarketHandler) handleBuildSellTx(w http.ResponseWriter, r *http.Request
 			Outcome:       outcome,
 			ShareAmount:   amount,
 			Slippage:      slippage,
+			Referral:      referralFromCookie(r),
 		},
 	}
 
-	result, err := h.marketService.BuildSellTx(r.Context(), req)
+	result, replayed, err := h.buildIdempotent(r, func() (*model.TransactionResult, error) {
+		return h.marketService.BuildSellTx(r.Context(), req)
+	})
 	if err != nil {
 		h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome, "amount", amount)
 		return
 	}
+	if !replayed {
+		h.recordPendingTx(service.PendingTxSell, contractID, h.sessionAccount(r), result, &req.TradeRequest)
+		h.trackReferral(result)
+	}
 
 	// Render XDR result page
 	data := map[string]any{
@@ -566,25 +983,36 @@ func (h *MarketHandler) handleBuildSellTx(w http.ResponseWriter, r *http.Request
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-// handleResolveMarket resolves a market.
+// handleResolveMarket resolves a market. Only the signed-in oracle may use it, since
+// building the transaction reserves the oracle's next sequence number.
 func (h *MarketHandler) handleResolveMarket(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	contractID := r.PathValue("id")
+	if !h.requireOracle(w, r, "/oracle") {
+		return
+	}
+	if !h.parseForm(w, r) {
 		return
 	}
 
-	contractID := r.PathValue("id")
 	outcomeStr := r.FormValue("outcome")
 
 	outcome, err := model.ParseOutcome(outcomeStr)
 	if err != nil {
-		http.Error(w, "Invalid outcome: must be YES or NO", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
+		return
+	}
+
+	// The oracle confirms the outcome against the market's resolution source first.
+	if r.FormValue("confirm") == "" {
+		h.renderResolveConfirmation(w, r, contractID, outcome)
 		return
 	}
 
@@ -594,11 +1022,17 @@ func (h *MarketHandler) handleResolveMarket(w http.ResponseWriter, r *http.Reque
 		WinningOutcome:  outcome,
 	}
 
-	result, err := h.marketService.BuildResolveTx(r.Context(), req)
+	result, replayed, err := h.buildIdempotent(r, func() (*model.TransactionResult, error) {
+		return h.marketService.BuildResolveTx(r.Context(), req)
+	})
 	if err != nil {
 		h.writeError(w, r, err, "contract_id", contractID, "outcome", outcome)
 		return
 	}
+	if !replayed {
+		h.recordPendingResolve(req, h.sessionAccount(r), result)
+		h.audit(r, "market.resolve_built", map[string]string{"contract_id": contractID, "outcome": outcome.String()})
+	}
 
 	data := map[string]any{
 		"Result":            result,
@@ -609,6 +1043,10 @@ func (h *MarketHandler) handleResolveMarket(w http.ResponseWriter, r *http.Reque
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addServerSign(r, data, result)
+	h.addSignElsewhere(data, result)
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -617,8 +1055,7 @@ func (h *MarketHandler) handleResolveMarket(w http.ResponseWriter, r *http.Reque
 
 // handleBuildClaimTx builds a transaction to claim winnings.
 func (h *MarketHandler) handleBuildClaimTx(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -627,7 +1064,7 @@ func (h *MarketHandler) handleBuildClaimTx(w http.ResponseWriter, r *http.Reques
 
 	// Validate public key using Stellar SDK
 	if _, err := keypair.ParseAddress(userPubKey); err != nil {
-		http.Error(w, "Invalid Stellar public key", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid Stellar public key")
 		return
 	}
 
@@ -641,6 +1078,7 @@ func (h *MarketHandler) handleBuildClaimTx(w http.ResponseWriter, r *http.Reques
 		h.writeError(w, r, err, "contract_id", contractID, "user_public_key", userPubKey)
 		return
 	}
+	h.recordPendingTx(service.PendingTxClaim, contractID, h.sessionAccount(r), result, nil)
 
 	data := map[string]any{
 		"Result":            result,
@@ -651,6 +1089,8 @@ func (h *MarketHandler) handleBuildClaimTx(w http.ResponseWriter, r *http.Reques
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -658,31 +1098,28 @@ func (h *MarketHandler) handleBuildClaimTx(w http.ResponseWriter, r *http.Reques
 }
 
 // handleBuildWithdrawTx builds a transaction for oracle to withdraw remaining pool.
+// Only the signed-in oracle may use it.
 func (h *MarketHandler) handleBuildWithdrawTx(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.requireOracle(w, r, "/oracle") {
 		return
 	}
-
-	contractID := r.PathValue("id")
-	oraclePubKey := strings.TrimSpace(r.FormValue("oracle_public_key"))
-
-	// Validate public key using Stellar SDK
-	if _, err := keypair.ParseAddress(oraclePubKey); err != nil {
-		http.Error(w, "Invalid Stellar public key", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
+	contractID := r.PathValue("id")
 	req := service.WithdrawRequest{
-		OraclePublicKey: oraclePubKey,
+		OraclePublicKey: h.oraclePublicKey,
 		ContractID:      contractID,
 	}
 
 	result, err := h.marketService.BuildWithdrawTx(r.Context(), req)
 	if err != nil {
-		h.writeError(w, r, err, "contract_id", contractID, "oracle_public_key", oraclePubKey)
+		h.writeError(w, r, err, "contract_id", contractID)
 		return
 	}
+	h.recordPendingTx(service.PendingTxWithdraw, contractID, h.sessionAccount(r), result, nil)
+	h.audit(r, "market.withdraw_built", map[string]string{"contract_id": contractID})
 
 	data := map[string]any{
 		"Result":            result,
@@ -693,6 +1130,52 @@ func (h *MarketHandler) handleBuildWithdrawTx(w http.ResponseWriter, r *http.Req
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addServerSign(r, data, result)
+	h.addAuthExpiry(r, data, result)
+
+	if err := h.tmpl.Render(w, "transaction", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleRefreshTx rebuilds a previously built transaction with a fresh sequence number
+// and time bounds, e.g. after multisig signing took longer than the timeout.
+func (h *MarketHandler) handleRefreshTx(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	marketID := r.FormValue("market_id")
+	txXDR := strings.TrimSpace(r.FormValue("xdr"))
+	result, err := h.marketService.BuildRefreshTx(r.Context(), service.RefreshRequest{
+		XDR:    txXDR,
+		Oracle: h.isOracleSession(r),
+	})
+	if err != nil {
+		h.writeError(w, r, err, "market_id", marketID)
+		return
+	}
+	// The rebuilt transaction takes the place of the one it refreshes, so it can be
+	// submitted here and signed with the server key like the original.
+	if pending, ok := h.pendingByXDR(result.SignWith, txXDR); ok {
+		h.replacePending(pending, result)
+	} else {
+		h.trackReferral(result)
+	}
+
+	data := map[string]any{
+		"Result":            result,
+		"MarketID":          marketID,
+		"ActiveNav":         "markets",
+		"Network":           h.networkName(),
+		"NetworkPassphrase": h.networkPassphrase,
+		"AccountID":         accountIDFromCookie(r),
+	}
+
+	h.addServerSign(r, data, result)
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -701,8 +1184,7 @@ func (h *MarketHandler) handleBuildWithdrawTx(w http.ResponseWriter, r *http.Req
 
 // handleSetAccount handles POST /account to save account_id cookie.
 func (h *MarketHandler) handleSetAccount(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -717,7 +1199,7 @@ func (h *MarketHandler) handleSetAccount(w http.ResponseWriter, r *http.Request)
 		})
 	} else {
 		if _, err := keypair.ParseAddress(accountID); err != nil {
-			http.Error(w, "Invalid Stellar public key", http.StatusBadRequest)
+			h.renderError(w, r, http.StatusBadRequest, "Invalid Stellar public key")
 			return
 		}
 		setAccountIDCookie(w, accountID)
@@ -737,9 +1219,10 @@ func (h *MarketHandler) handleSetAccount(w http.ResponseWriter, r *http.Request)
 func (h *MarketHandler) handleOutcomePage(w http.ResponseWriter, r *http.Request) {
 	contractID := r.PathValue("id")
 	if contractID == "" {
-		http.Error(w, "Contract ID required", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Contract ID required")
 		return
 	}
+	h.captureReferral(w, r)
 
 	// Determine outcome from URL path
 	path := r.URL.Path
@@ -749,34 +1232,37 @@ func (h *MarketHandler) handleOutcomePage(w http.ResponseWriter, r *http.Request
 	} else if strings.HasSuffix(path, "/no") {
 		outcome = "NO"
 	} else {
-		http.Error(w, "Invalid outcome", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome")
 		return
 	}
 
 	if h.factoryService == nil || !h.factoryService.HasFactory() {
-		http.Error(w, "Factory contract not configured", http.StatusServiceUnavailable)
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
 		return
 	}
 
 	ctx := r.Context()
 
-	states, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
+	loaded, err := h.factoryService.GetMarketStates(ctx, []string{contractID})
 	if err != nil {
 		h.writeError(w, r, err, "contract_id", contractID)
 		return
 	}
+	states := loaded.States
 	if len(states) == 0 {
-		http.Error(w, "Market not found", http.StatusNotFound)
+		h.renderError(w, r, http.StatusNotFound, "Market not found")
 		return
 	}
 
 	state := states[0]
 	market := model.Market{
-		ID:       state.ContractID,
-		YesSold:  float64(state.YesSold) / float64(soroban.ScaleFactor),
-		NoSold:   float64(state.NoSold) / float64(soroban.ScaleFactor),
-		PriceYes: state.PriceYes,
-		PriceNo:  state.PriceNo,
+		ID:               state.ContractID,
+		YesSold:          float64(state.YesSold) / float64(soroban.ScaleFactor),
+		NoSold:           float64(state.NoSold) / float64(soroban.ScaleFactor),
+		PriceYes:         state.PriceYes,
+		PriceNo:          state.PriceNo,
+		ContractVersion:  state.ContractVersion,
+		ContractWasmHash: state.WasmHash,
 	}
 
 	if state.Resolved && state.WinningOutcome != "" {
@@ -834,6 +1320,7 @@ func (h *MarketHandler) handleOutcomePage(w http.ResponseWriter, r *http.Request
 		"Network":           h.networkName(),
 		"NetworkPassphrase": h.networkPassphrase,
 	}
+	h.addTradePrefill(r, contractID, data)
 
 	if err := h.tmpl.Render(w, "outcome", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
@@ -901,6 +1388,21 @@ func (h *MarketHandler) handleRedirectToOracle(w http.ResponseWriter, r *http.Re
 
 // handleOracleAdmin renders the oracle admin page with deploy/resolve/withdraw forms.
 func (h *MarketHandler) handleOracleAdmin(w http.ResponseWriter, r *http.Request) {
+	data := h.oracleData(r)
+
+	// Checking a question before uploading its metadata avoids pinning a duplicate.
+	if question := strings.TrimSpace(r.URL.Query().Get("question")); question != "" {
+		markets, _ := data["Markets"].([]MarketView)
+		data["CheckedQuestion"] = question
+		data["SimilarMarkets"] = similarOpenMarkets(question, markets)
+	}
+	h.addCloneForm(r, data)
+
+	h.renderOracle(w, data)
+}
+
+// oracleData loads the markets and settings shown on the oracle page.
+func (h *MarketHandler) oracleData(r *http.Request) map[string]any {
 	ctx := r.Context()
 
 	var markets []MarketView
@@ -916,12 +1418,12 @@ func (h *MarketHandler) handleOracleAdmin(w http.ResponseWriter, r *http.Request
 			h.logger.Warn("failed to list markets for oracle admin", "error", err)
 			marketsError = "Failed to load markets from factory"
 		} else {
-			states, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+			loaded, err := h.factoryService.GetMarketStates(ctx, contractIDs)
 			if err != nil {
 				h.logger.Warn("failed to get market states for oracle admin", "error", err)
 				marketsError = "Failed to load market states"
 			} else {
-				markets = h.buildMarketViews(ctx, states)
+				markets = h.buildMarketViews(ctx, loaded.States)
 			}
 		}
 	}
@@ -929,29 +1431,40 @@ func (h *MarketHandler) handleOracleAdmin(w http.ResponseWriter, r *http.Request
 	data := map[string]any{
 		"OraclePublicKey":       h.oraclePublicKey,
 		"DefaultLiquidityParam": 100.0,
+		"DefaultInitialFunding": 72.0,
 		"FactoryContract":       factoryContract,
 		"Markets":               markets,
 		"MarketsError":          marketsError,
+		"ConditionAlerts":       conditionAlerts(markets),
+		"ClaimSweep":            h.sweepReport(markets, time.Now()),
 		"ActiveNav":             "oracle",
 		"Network":               h.networkName(),
 		"AccountID":             accountIDFromCookie(r),
 	}
+	h.addRuleTemplates(r, data)
+	h.addSeries(r, data)
+	return data
+}
 
+func (h *MarketHandler) renderOracle(w http.ResponseWriter, data map[string]any) {
 	if err := h.tmpl.Render(w, "oracle", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-// handleBuildDeployTx builds a transaction to deploy a new market.
+// handleBuildDeployTx builds a transaction to deploy a new market. Only the signed-in
+// oracle may use it.
 func (h *MarketHandler) handleBuildDeployTx(w http.ResponseWriter, r *http.Request) {
 	if h.factoryService == nil || !h.factoryService.HasFactory() {
-		http.Error(w, "Factory contract not configured", http.StatusServiceUnavailable)
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+	if !h.requireOracle(w, r, "/oracle") {
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	if !h.parseForm(w, r) {
 		return
 	}
 
@@ -960,39 +1473,68 @@ func (h *MarketHandler) handleBuildDeployTx(w http.ResponseWriter, r *http.Reque
 	initialFundingStr := r.FormValue("initial_funding")
 
 	if metadataHash == "" {
-		http.Error(w, "Metadata hash is required (upload metadata to IPFS first)", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Metadata hash is required (upload metadata to IPFS first)")
 		return
 	}
 
 	// Validate IPFS CID format to prevent SSRF
 	if err := ipfs.ValidateCID(metadataHash); err != nil {
-		http.Error(w, "Invalid IPFS hash format (must be CIDv0 Qm... or CIDv1 b...)", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid IPFS hash format (must be CIDv0 Qm... or CIDv1 b...)")
 		return
 	}
 
 	liquidityParam, err := strconv.ParseFloat(liquidityParamStr, 64)
 	if err != nil || liquidityParam <= 0 {
-		http.Error(w, "Invalid liquidity parameter", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid liquidity parameter")
 		return
 	}
 
 	initialFunding, err := strconv.ParseFloat(initialFundingStr, 64)
 	if err != nil || initialFunding <= 0 {
-		http.Error(w, "Invalid initial funding", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid initial funding")
 		return
 	}
 
+	if err := h.checkDeployMetadata(r, metadataHash); err != nil {
+		h.writeError(w, r, err, "metadata_hash", metadataHash)
+		return
+	}
+
+	// Warn about likely duplicates once; the oracle can confirm and deploy anyway.
+	if r.FormValue("confirm_duplicate") == "" {
+		if question, similar := h.deployDuplicates(r, metadataHash); len(similar) > 0 {
+			data := h.oracleData(r)
+			data["DuplicateQuestion"] = question
+			data["SimilarMarkets"] = similar
+			data["DeployMetadataHash"] = metadataHash
+			data["DefaultLiquidityParam"] = liquidityParam
+			data["DefaultInitialFunding"] = initialFunding
+			h.renderOracle(w, data)
+			return
+		}
+	}
+
 	req := service.DeployMarketRequest{
 		LiquidityParam: liquidityParam,
 		MetadataHash:   metadataHash,
 		InitialFunding: initialFunding,
 	}
 
-	result, err := h.factoryService.BuildDeployMarketTx(r.Context(), req)
+	result, replayed, err := h.buildIdempotent(r, func() (*model.TransactionResult, error) {
+		return h.factoryService.BuildDeployMarketTx(r.Context(), req)
+	})
 	if err != nil {
 		h.writeError(w, r, err, "liquidity_param", liquidityParam, "metadata_hash", metadataHash)
 		return
 	}
+	if !replayed {
+		h.recordPendingDeploy(service.PendingTx{Kind: service.PendingTxDeploy, Description: result.Description, BuiltBy: h.sessionAccount(r), Deploy: &req}, result)
+		h.audit(r, "market.deploy_built", map[string]string{
+			"metadata_hash":   metadataHash,
+			"liquidity_param": strconv.FormatFloat(liquidityParam, 'f', -1, 64),
+			"initial_funding": strconv.FormatFloat(initialFunding, 'f', -1, 64),
+		})
+	}
 
 	data := map[string]any{
 		"Result":            result,
@@ -1003,14 +1545,42 @@ func (h *MarketHandler) handleBuildDeployTx(w http.ResponseWriter, r *http.Reque
 		"AccountID":         accountIDFromCookie(r),
 	}
 
+	h.addServerSign(r, data, result)
+	h.addSignElsewhere(data, result)
+	h.addAuthExpiry(r, data, result)
+
 	if err := h.tmpl.Render(w, "transaction", data); err != nil {
 		h.logger.Error("failed to render template", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-// handleHealth returns health status.
+// healthResponse is the JSON body of GET /health.
+type healthResponse struct {
+	Status         string                   `json:"status"`          // "degraded" when no RPC endpoint is healthy
+	BuilderVersion int                      `json:"builder_version"` // Transaction encoding, see stellar.BuilderVersion
+	RPCEndpoints   []soroban.EndpointStatus `json:"rpc_endpoints"`
+}
+
+// handleHealth returns health status, the transaction builder version and the Soroban
+// RPC endpoints. The version and the active endpoint are also sent as
+// X-Builder-Version and X-RPC-Endpoint so plain-text probes keep getting "OK".
 func (h *MarketHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	endpoints := h.marketService.RPCEndpoints()
+	status := "degraded"
+	for _, e := range endpoints {
+		if e.Active {
+			w.Header().Set("X-RPC-Endpoint", e.URL)
+		}
+		if e.Healthy {
+			status = "ok"
+		}
+	}
+	w.Header().Set("X-Builder-Version", strconv.Itoa(stellar.BuilderVersion))
+	if wantsJSON(r) {
+		h.writeJSON(w, healthResponse{Status: status, BuilderVersion: stellar.BuilderVersion, RPCEndpoints: endpoints})
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, "OK")
 }
@@ -1028,6 +1598,31 @@ func mapError(err error) errorResponse {
 	// Not found errors -> 404
 	case errors.Is(err, service.ErrMarketNotFound):
 		return errorResponse{"Market not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrPendingTxNotFound):
+		return errorResponse{"Pending transaction not found. It may have been dismissed or expired from history.", http.StatusNotFound}
+
+	case errors.Is(err, service.ErrQuoteTokenInvalid):
+		return errorResponse{"Quote does not match this trade. Please request a new quote.", http.StatusBadRequest}
+	case errors.Is(err, service.ErrQuoteTokenRequired), errors.Is(err, service.ErrQuoteTokenExpired):
+		return errorResponse{"Quote expired. Please review the current price and try again.", http.StatusConflict}
+	case errors.Is(err, service.ErrInvalidIdempotencyKey):
+		return errorResponse{fmt.Sprintf("Invalid idempotency key (max %d characters)", service.MaxIdempotencyKeyLength), http.StatusBadRequest}
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return errorResponse{"This form was already submitted with different values. Reload the page and try again.", http.StatusUnprocessableEntity}
+	case errors.Is(err, service.ErrInvalidTransaction):
+		return errorResponse{"Invalid transaction: expected a single contract invocation built by this app", http.StatusBadRequest}
+	case errors.Is(err, service.ErrInvalidTxHash):
+		return errorResponse{"Invalid transaction hash: expected 64 hex characters", http.StatusBadRequest}
+	case errors.Is(err, service.ErrTxNotBuilt):
+		return errorResponse{"Transaction does not match any pending transaction built here. It may have been changed after it was built, or already submitted.", http.StatusUnprocessableEntity}
+	case errors.Is(err, service.ErrTxNotSigned):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrOracleTxNotAllowed):
+		return errorResponse{"Only resolve, withdraw and deploy transactions of the oracle can be signed by the server", http.StatusForbidden}
+	case errors.Is(err, service.ErrOracleRefresh):
+		return errorResponse{"Sign in as the oracle to rebuild oracle transactions", http.StatusForbidden}
+	case errors.Is(err, service.ErrTxExpired):
+		return errorResponse{"Transaction has expired. Rebuild it from Pending transactions and sign it again.", http.StatusConflict}
 
 	// Business logic errors -> 409 Conflict
 	case errors.Is(err, service.ErrMarketResolved):
@@ -1038,6 +1633,16 @@ func mapError(err error) errorResponse {
 		return errorResponse{"Factory contract not configured", http.StatusServiceUnavailable}
 	case errors.Is(err, service.ErrInvalidMetadataHash):
 		return errorResponse{"Invalid metadata hash", http.StatusBadRequest}
+	case errors.Is(err, service.ErrMetadataUnavailable):
+		return errorResponse{"Metadata could not be loaded from IPFS, so it cannot be checked before deploying. A fresh upload may take a minute to reach the gateway; try again shortly.", http.StatusServiceUnavailable}
+	case errors.Is(err, service.ErrScalarMarketUnsupported):
+		return errorResponse{"Scalar markets cannot be deployed yet: the market contract only pays out YES or NO in full. The metadata is valid and can be deployed once a scalar contract is available.", http.StatusUnprocessableEntity}
+	case errors.Is(err, service.ErrInvalidFactoryAdminOp), errors.Is(err, service.ErrInvalidWasmHash), errors.Is(err, service.ErrInvalidCollateralToken):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrWasmNotInstalled):
+		return errorResponse{"No WASM code with this hash is on the network. Upload it first (stellar contract upload), then set the hash.", http.StatusUnprocessableEntity}
+	case errors.Is(err, service.ErrFactoryValueUnchanged):
+		return errorResponse{"The factory already uses this value", http.StatusConflict}
 
 	// Validation errors -> 400 Bad Request
 	case errors.Is(err, service.ErrInvalidOutcome):
@@ -1048,6 +1653,30 @@ func mapError(err error) errorResponse {
 		return errorResponse{fmt.Sprintf("Question exceeds maximum length (%d characters)", model.MaxQuestionLength), http.StatusBadRequest}
 	case errors.Is(err, model.ErrDescriptionTooLong):
 		return errorResponse{fmt.Sprintf("Description exceeds maximum length (%d characters)", model.MaxDescriptionLength), http.StatusBadRequest}
+	case errors.Is(err, model.ErrEmptyResolutionSource):
+		return errorResponse{"Resolution source is required: a URL or a description of how the question resolves", http.StatusBadRequest}
+	case errors.Is(err, model.ErrResolutionSourceTooLong):
+		return errorResponse{fmt.Sprintf("Resolution source exceeds maximum length (%d characters)", model.MaxResolutionSourceLength), http.StatusBadRequest}
+	case errors.Is(err, service.ErrRuleTemplateNotFound):
+		return errorResponse{"Rule template not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrInvalidRuleTemplate), errors.Is(err, service.ErrMissingRuleVariable):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrTooManyRuleTemplates):
+		return errorResponse{"Too many rule templates. Delete unused ones first.", http.StatusConflict}
+	case errors.Is(err, service.ErrSeriesNotFound):
+		return errorResponse{"Series not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrInvalidSeries):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrTooManySeries):
+		return errorResponse{"Too many series. Delete unused ones first.", http.StatusConflict}
+	case errors.Is(err, model.ErrInvalidScalarRange):
+		return errorResponse{"Scalar range needs a minimum below its maximum", http.StatusBadRequest}
+	case errors.Is(err, model.ErrScalarUnitTooLong):
+		return errorResponse{fmt.Sprintf("Scalar unit exceeds maximum length (%d characters)", model.MaxScalarUnitLength), http.StatusBadRequest}
+	case errors.Is(err, model.ErrInvalidCondition):
+		return errorResponse{"Conditional market must name a market contract ID and YES or NO", http.StatusBadRequest}
+	case errors.Is(err, model.ErrInvalidResolutionURL):
+		return errorResponse{"Resolution source URL must start with http:// or https://", http.StatusBadRequest}
 	case errors.Is(err, model.ErrInvalidLiquidityParam):
 		return errorResponse{"Liquidity parameter must be a positive number", http.StatusBadRequest}
 	case errors.Is(err, model.ErrInvalidShareAmount):
@@ -1059,17 +1688,98 @@ func mapError(err error) errorResponse {
 	case errors.Is(err, model.ErrInvalidSlippage):
 		return errorResponse{fmt.Sprintf("Slippage must be between 0 and %.0f%%", model.MaxSlippage*100), http.StatusBadRequest}
 
+	// Metadata pinning errors (model validation errors wrapped by ErrInvalidMetadata match above)
+	case errors.Is(err, ipfs.ErrMetadataTooLarge):
+		return errorResponse{fmt.Sprintf("Metadata is too large (max %d bytes)", ipfs.MaxMetadataSize), http.StatusBadRequest}
+	case errors.Is(err, ipfs.ErrInvalidMetadata):
+		return errorResponse{"Invalid market metadata", http.StatusBadRequest}
+	case errors.Is(err, ipfs.ErrPinningNotConfigured):
+		return errorResponse{"Pinning to IPFS is not configured on this server", http.StatusServiceUnavailable}
+
+	// Share link errors
+	case errors.Is(err, service.ErrInvalidShareLinkAmount):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrShareLinkNotFound):
+		return errorResponse{"Share link not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrTooManyShareLinks):
+		return errorResponse{"Share links are temporarily unavailable", http.StatusServiceUnavailable}
+
+	// Discussion errors
+	case errors.Is(err, service.ErrCommentEmpty), errors.Is(err, service.ErrCommentTooLong):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrCommentTooFast):
+		return errorResponse{"Please wait a few seconds before posting another comment.", http.StatusTooManyRequests}
+	case errors.Is(err, service.ErrCommentsFull):
+		return errorResponse{"This discussion has reached its comment limit.", http.StatusConflict}
+	case errors.Is(err, service.ErrCommentNotFound):
+		return errorResponse{"Comment not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrNotOracle):
+		return errorResponse{"Only the oracle can moderate comments", http.StatusForbidden}
+	case errors.Is(err, service.ErrInvalidChallenge):
+		return errorResponse{"Sign-in failed: the challenge is invalid, expired, or not signed by the account.", http.StatusBadRequest}
+	case errors.Is(err, service.ErrSessionInvalid), errors.Is(err, service.ErrSessionExpired):
+		return errorResponse{"Please sign in again.", http.StatusUnauthorized}
+
+	// Paper trading errors
+	case errors.Is(err, service.ErrPaperInsufficientBalance), errors.Is(err, service.ErrPaperInsufficientTokens):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrPaperAccountsFull):
+		return errorResponse{"Paper trading is not accepting new accounts right now", http.StatusServiceUnavailable}
+
+	// Bulk deploy errors
+	case errors.Is(err, service.ErrInvalidBulkMarkets):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, service.ErrDeployBatchNotFound):
+		return errorResponse{"Deploy batch not found", http.StatusNotFound}
+	case errors.Is(err, service.ErrDeployBatchDone):
+		return errorResponse{"Every market of this batch is deployed", http.StatusConflict}
+	case errors.Is(err, service.ErrDeployInFlight):
+		return errorResponse{"The previous deploy transaction of this batch may still land. Wait for it to expire, or build again anyway if it was never submitted.", http.StatusConflict}
+
+	// Alert errors
+	case errors.Is(err, service.ErrInvalidAlertKind), errors.Is(err, service.ErrInvalidAlertThreshold),
+		errors.Is(err, notify.ErrInvalidTarget):
+		return errorResponse{err.Error(), http.StatusBadRequest}
+	case errors.Is(err, notify.ErrChannelDisabled):
+		return errorResponse{"This notification channel is not available on this server", http.StatusBadRequest}
+	case errors.Is(err, service.ErrTooManyAlerts):
+		return errorResponse{"Too many alerts for this destination or from your network. Remove some before adding more.", http.StatusTooManyRequests}
+	case errors.Is(err, service.ErrAlertConfirmSent):
+		return errorResponse{"A confirmation email for this address was sent a few minutes ago. Confirm it or try again later.", http.StatusTooManyRequests}
+	case errors.Is(err, service.ErrAlertNotFound):
+		return errorResponse{"Alert not found. It may have been removed already.", http.StatusNotFound}
+	case errors.Is(err, service.ErrInitDataInvalid), errors.Is(err, service.ErrInitDataExpired):
+		return errorResponse{err.Error(), http.StatusUnauthorized}
+
+	// Email subscription errors
+	case errors.Is(err, service.ErrDigestEmpty):
+		return errorResponse{"Choose the daily digest or a market to watch", http.StatusBadRequest}
+	case errors.Is(err, service.ErrDigestConfirmSent):
+		return errorResponse{"A confirmation email was sent a few minutes ago. Check your inbox or try again later.", http.StatusTooManyRequests}
+	case errors.Is(err, service.ErrTooManyDigests), errors.Is(err, service.ErrTooManyWatched):
+		return errorResponse{"Too many email subscriptions. Remove some before adding more.", http.StatusTooManyRequests}
+	case errors.Is(err, service.ErrDigestNotFound):
+		return errorResponse{"Email subscription not found. The link may be outdated.", http.StatusNotFound}
+
 	// LMSR errors -> 400 Bad Request
 	case errors.Is(err, lmsr.ErrInvalidOutcome):
 		return errorResponse{"Invalid outcome: must be YES or NO", http.StatusBadRequest}
 	case errors.Is(err, lmsr.ErrNegativeAmount):
 		return errorResponse{"Amount must be positive", http.StatusBadRequest}
+	case errors.Is(err, service.ErrBudgetTooSmall):
+		return errorResponse{"Amount is too small to buy any tokens", http.StatusBadRequest}
+	case errors.Is(err, service.ErrTradeTooLarge), errors.Is(err, service.ErrPositionTooLarge):
+		return errorResponse{err.Error(), http.StatusBadRequest}
 	case errors.Is(err, lmsr.ErrInsufficientTokens):
 		return errorResponse{"Insufficient tokens available", http.StatusBadRequest}
 	case errors.Is(err, lmsr.ErrNegativeQuantities):
 		return errorResponse{"Invalid market state: negative quantities", http.StatusBadRequest}
 	case errors.Is(err, lmsr.ErrInvalidLiquidity):
 		return errorResponse{"Invalid liquidity parameter", http.StatusBadRequest}
+	case errors.Is(err, lmsr.ErrNotFinite):
+		return errorResponse{"Amount must be a finite number", http.StatusBadRequest}
+	case errors.Is(err, lmsr.ErrOverflow):
+		return errorResponse{"Amount is too large for this market", http.StatusBadRequest}
 
 	// Stellar account errors -> 400 Bad Request
 	case errors.Is(err, stellar.ErrAccountNotFound):
@@ -1182,25 +1892,13 @@ func extractLastErrorCode(errStr string) int {
 func (h *MarketHandler) writeError(w http.ResponseWriter, r *http.Request, err error, logContext ...any) {
 	resp := mapError(err)
 	logArgs := append([]any{"error", err, "status", resp.Status}, logContext...)
-	h.logger.Error("request failed", logArgs...)
-
-	var accountID string
-	if r != nil {
-		accountID = accountIDFromCookie(r)
+	trace := soroban.DiagnosticTrace(err)
+	if len(trace) > 0 {
+		logArgs = append(logArgs, "diagnostics", trace)
 	}
+	h.logger.Error("request failed", logArgs...)
 
-	w.WriteHeader(resp.Status)
-	data := map[string]any{
-		"ErrorCode":    resp.Status,
-		"ErrorMessage": resp.Message,
-		"ActiveNav":    "",
-		"AccountID":    accountID,
-		"Network":      h.networkName(),
-	}
-	if tmplErr := h.tmpl.Render(w, "error", data); tmplErr != nil {
-		// Headers already sent — cannot recover, just log
-		h.logger.Error("failed to render error template", "error", tmplErr)
-	}
+	h.renderErrorDetails(w, r, resp.Status, resp.Message, trace)
 }
 
 // handleAPIQuote returns a JSON price quote for the trade form.
@@ -1226,7 +1924,34 @@ func (h *MarketHandler) handleAPIQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quote, err := h.marketService.GetQuote(r.Context(), contractID, outcome, amount)
+	compare, ok := comparesQuote(r)
+	if !ok {
+		writeJSONError(w, "invalid source", http.StatusBadRequest)
+		return
+	}
+
+	var quote *service.Quote
+	switch r.FormValue("amount_mode") {
+	case "", "tokens":
+		quote, err = h.marketService.GetQuote(r.Context(), contractID, outcome, amount)
+	case amountModeCollateral:
+		var bq *service.BudgetQuote
+		bq, err = h.marketService.QuoteForBudget(r.Context(), contractID, outcome, amount)
+		if err == nil {
+			amount, quote = bq.Shares, bq.Quote
+		}
+	default:
+		writeJSONError(w, "invalid amount mode", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrBudgetTooSmall) {
+		writeJSONError(w, "budget too small", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrTradeTooLarge) {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		h.logger.Error("quote API error", "error", err, "contract_id", contractID, "outcome", outcomeStr, "amount", amountStr)
 		writeJSONError(w, "quote unavailable", http.StatusBadGateway)
@@ -1234,11 +1959,23 @@ func (h *MarketHandler) handleAPIQuote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	costFloat := float64(quote.Cost) / float64(soroban.ScaleFactor)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{
+	resp := map[string]any{
+		"amount":      amount,
 		"cost":        costFloat,
 		"price_after": quote.PriceAfter,
-	}); err != nil {
+		"quote_token": quote.Token,
+		"expires_at":  quote.ExpiresAt,
+	}
+	if suggested, ok := h.suggestedSlippage(contractID, outcome); ok {
+		resp["suggested_slippage"] = suggested
+	}
+	if compare {
+		if estimate := quoteEstimateResponse(h.quoteEstimate(r, contractID, outcome, amount, quote)); estimate != nil {
+			resp["estimate"] = estimate
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.logger.Error("failed to encode quote response", "error", err)
 	}
 }
@@ -1272,6 +2009,11 @@ func (h *MarketHandler) handleMTLWallet(w http.ResponseWriter, r *http.Request)
 		writeJSONError(w, "MTL Wallet is only available on mainnet", http.StatusBadRequest)
 		return
 	}
+	if err := parseLimitedForm(r); err != nil {
+		status, msg := formErrorResponse(err)
+		writeJSONError(w, msg, status)
+		return
+	}
 
 	uri := r.FormValue("uri")
 	if uri == "" || !strings.HasPrefix(uri, "web+stellar:tx?") {
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// stateETagEpoch makes ETags unique per process: cache generations restart at zero,
+// so an ETag from before a restart or from another replica never matches.
+var stateETagEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// marketStateResponse is the compact numeric state of a market. Amounts are in
+// tokens and collateral, not scaled contract units.
+type marketStateResponse struct {
+	ContractID     string    `json:"contract_id"`
+	YesSold        float64   `json:"yes_sold"`
+	NoSold         float64   `json:"no_sold"`
+	Pool           float64   `json:"pool"`
+	PriceYes       float64   `json:"price_yes"`
+	PriceNo        float64   `json:"price_no"`
+	Resolved       bool      `json:"resolved"`
+	WinningOutcome string    `json:"winning_outcome,omitempty"`
+	Generation     uint64    `json:"generation"`
+	UpdatedAt      time.Time `json:"updated_at"` // when the state last changed
+}
+
+// handleAPIMarketState handles GET /api/v1/markets/{id}/state. The state comes from
+// the market state cache, and ETag / Last-Modified follow its changes, so polling
+// clients sending If-None-Match or If-Modified-Since get 304 until a trade or
+// resolution changes the market.
+func (h *MarketHandler) handleAPIMarketState(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if err := soroban.ValidateContractID(contractID); err != nil {
+		writeJSONError(w, "invalid contract ID", http.StatusBadRequest)
+		return
+	}
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		writeJSONError(w, "factory contract not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	loaded, err := h.factoryService.GetMarketStates(r.Context(), []string{contractID})
+	if err != nil {
+		h.logger.Error("market state API error", "error", err, "contract_id", contractID)
+		writeJSONError(w, "market state unavailable", http.StatusBadGateway)
+		return
+	}
+	states := loaded.States
+	if len(states) == 0 {
+		writeJSONError(w, "market not found", http.StatusNotFound)
+		return
+	}
+	state := states[0]
+	version := h.factoryService.MarketStateVersion(state)
+
+	body, err := json.Marshal(marketStateResponse{
+		ContractID:     state.ContractID,
+		YesSold:        float64(state.YesSold) / float64(soroban.ScaleFactor),
+		NoSold:         float64(state.NoSold) / float64(soroban.ScaleFactor),
+		Pool:           float64(state.Pool) / float64(soroban.ScaleFactor),
+		PriceYes:       state.PriceYes,
+		PriceNo:        state.PriceNo,
+		Resolved:       state.Resolved,
+		WinningOutcome: state.WinningOutcome,
+		Generation:     version.Generation,
+		UpdatedAt:      version.Modified.UTC(),
+	})
+	if err != nil {
+		h.logger.Error("failed to encode market state", "error", err)
+		writeJSONError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, stateETagEpoch, version.Generation))
+	// ServeContent answers conditional requests from the ETag and modification time.
+	http.ServeContent(w, r, "", version.Modified, bytes.NewReader(body))
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mtlprog/total/internal/metrics"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// handleMetrics serves business and background job metrics in the Prometheus text
+// format. Everything is read from state the scheduler keeps current, so scrapes
+// never call the backends.
+func (h *AdminHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	m := metrics.NewWriter(w)
+
+	stats := h.stats.Stats()
+	m.Family("total_markets", metrics.Gauge, "Markets listed by the factory, by state.")
+	m.Sample("total_markets", float64(stats.OpenMarkets), metrics.Label{Name: "state", Value: "open"})
+	m.Sample("total_markets", float64(stats.ResolvedMarkets), metrics.Label{Name: "state", Value: "resolved"})
+	m.Metric("total_pool_collateral", metrics.Gauge, "Collateral held by all market pools, in EURMTL.", stats.PoolLocked)
+	m.Metric("total_trades_last_hour", metrics.Gauge, "Trades in the hour before the last aggregation.", float64(stats.TradesLastHour))
+	m.Metric("total_trades_total", metrics.Counter, "Trades counted since statistics began.", float64(stats.Trades))
+	m.Metric("total_volume_total", metrics.Counter, "Collateral traded since statistics began, in EURMTL.", stats.Volume)
+	if !stats.UpdatedAt.IsZero() {
+		m.Metric("total_index_lag_seconds", metrics.Gauge, "Time since trade events were last aggregated.", now.Sub(stats.UpdatedAt).Seconds())
+	}
+
+	if status := h.statusService.Status(); !status.LedgerClosedAt.IsZero() {
+		m.Metric("total_rpc_ledger_age_seconds", metrics.Gauge, "Age of the latest ledger reported by the Soroban RPC.", now.Sub(status.LedgerClosedAt).Seconds())
+	}
+	if h.sorobanClient != nil {
+		endpoints := h.sorobanClient.Endpoints()
+		m.Family("total_rpc_endpoint_up", metrics.Gauge, "Whether a Soroban RPC endpoint passed its last health check and calls.")
+		for _, e := range endpoints {
+			m.Sample("total_rpc_endpoint_up", boolGauge(e.Healthy), metrics.Label{Name: "endpoint", Value: e.URL})
+		}
+		m.Family("total_rpc_endpoint_active", metrics.Gauge, "Whether calls go to a Soroban RPC endpoint.")
+		for _, e := range endpoints {
+			m.Sample("total_rpc_endpoint_active", boolGauge(e.Active), metrics.Label{Name: "endpoint", Value: e.URL})
+		}
+		m.Family("total_rpc_endpoint_latency_seconds", metrics.Gauge, "Duration of the last health check of a Soroban RPC endpoint.")
+		for _, e := range endpoints {
+			if !e.LastChecked.IsZero() {
+				m.Sample("total_rpc_endpoint_latency_seconds", e.Latency.Seconds(), metrics.Label{Name: "endpoint", Value: e.URL})
+			}
+		}
+		m.Metric("total_rpc_failovers_total", metrics.Counter, "Switches to another Soroban RPC endpoint.", float64(h.sorobanClient.Failovers()))
+	}
+	if h.ipfsClient != nil {
+		m.Metric("total_ipfs_fetch_failures_total", metrics.Counter, "IPFS gateway fetches that failed after retries.", float64(h.ipfsClient.FetchFailures()))
+		gateways := h.ipfsClient.Gateways()
+		m.Family("total_ipfs_gateway_requests_total", metrics.Counter, "Requests to an IPFS gateway.")
+		for _, g := range gateways {
+			m.Sample("total_ipfs_gateway_requests_total", float64(g.Requests), metrics.Label{Name: "gateway", Value: g.URL})
+		}
+		m.Family("total_ipfs_gateway_errors_total", metrics.Counter, "Failed requests to an IPFS gateway, not counting missing content.")
+		for _, g := range gateways {
+			m.Sample("total_ipfs_gateway_errors_total", float64(g.Errors), metrics.Label{Name: "gateway", Value: g.URL})
+		}
+		m.Family("total_ipfs_gateway_latency_seconds", metrics.Gauge, "Moving average duration of successful requests to an IPFS gateway.")
+		for _, g := range gateways {
+			if g.Latency > 0 {
+				m.Sample("total_ipfs_gateway_latency_seconds", g.Latency.Seconds(), metrics.Label{Name: "gateway", Value: g.URL})
+			}
+		}
+		m.Family("total_ipfs_gateway_up", metrics.Gauge, "Whether an IPFS gateway is ranked as healthy.")
+		for _, g := range gateways {
+			m.Sample("total_ipfs_gateway_up", boolGauge(g.Healthy), metrics.Label{Name: "gateway", Value: g.URL})
+		}
+	}
+	if h.warmup != nil {
+		warmup := h.warmup.Stats()
+		m.Metric("total_warmup_complete", metrics.Gauge, "Whether the startup cache warmup has finished.", boolGauge(warmup.Done()))
+		m.Metric("total_warmup_duration_seconds", metrics.Gauge, "How long the startup cache warmup ran, or has been running.", warmup.Duration().Seconds())
+		m.Metric("total_warmup_fetched", metrics.Gauge, "Market metadata documents the startup cache warmup loaded.", float64(warmup.Fetched))
+		m.Metric("total_warmup_failures", metrics.Gauge, "Market metadata fetches that failed during the startup cache warmup.", float64(warmup.Failed))
+	}
+
+	if h.factoryService != nil {
+		watch := h.factoryService.StateWatchStats()
+		m.Metric("total_state_watch_changed_total", metrics.Counter, "Market states fetched again because their ledger entry changed.", float64(watch.Changed))
+		m.Metric("total_state_watch_unchanged_total", metrics.Counter, "Cached market states kept because their ledger entry did not change.", float64(watch.Unchanged))
+		if watch.LatestLedger > 0 {
+			m.Metric("total_state_watch_ledger", metrics.Gauge, "Latest ledger seen by the market ledger entry watch.", float64(watch.LatestLedger))
+		}
+		if q := h.factoryService.Quarantine(); q != nil {
+			m.Metric("total_quarantined_markets", metrics.Gauge, "Markets hidden from public lists by --strict-markets.", float64(q.Len()))
+		}
+	}
+	m.Metric("total_index_divergences", metrics.Gauge, "Divergences of counted trades from market state flagged in the last week.", float64(len(h.stats.Divergences())))
+	if h.anomalies != nil {
+		counts := h.anomalies.Counts()
+		m.Family("total_trade_anomalies", metrics.Gauge, "Suspicious trading patterns flagged in the last week.")
+		for _, kind := range service.AnomalyKinds {
+			m.Sample("total_trade_anomalies", float64(counts[kind]), metrics.Label{Name: "kind", Value: string(kind)})
+		}
+	}
+
+	caches := h.cacheViews()
+	m.Family("total_cache_hits_total", metrics.Counter, "Cache lookups served from the cache.")
+	for _, c := range caches {
+		m.Sample("total_cache_hits_total", float64(c.Hits), metrics.Label{Name: "cache", Value: c.Name})
+	}
+	m.Family("total_cache_misses_total", metrics.Counter, "Cache lookups that went to the backend.")
+	for _, c := range caches {
+		m.Sample("total_cache_misses_total", float64(c.Misses), metrics.Label{Name: "cache", Value: c.Name})
+	}
+
+	jobs := h.scheduler.Jobs()
+	m.Family("total_job_runs_total", metrics.Counter, "Completed runs of a background job.")
+	for _, j := range jobs {
+		m.Sample("total_job_runs_total", float64(j.Runs), metrics.Label{Name: "job", Value: j.Name})
+	}
+	m.Family("total_job_failures_total", metrics.Counter, "Failed runs of a background job.")
+	for _, j := range jobs {
+		m.Sample("total_job_failures_total", float64(j.Failures), metrics.Label{Name: "job", Value: j.Name})
+	}
+	m.Family("total_job_last_run_timestamp_seconds", metrics.Gauge, "Start of the last completed run of a background job.")
+	for _, j := range jobs {
+		if !j.LastRun.IsZero() {
+			m.Sample("total_job_last_run_timestamp_seconds", float64(j.LastRun.Unix()), metrics.Label{Name: "job", Value: j.Name})
+		}
+	}
+
+	if err := m.Err(); err != nil {
+		h.logger.Warn("failed to write metrics", "error", err)
+	}
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// wantsJSON reports whether the client asked for JSON rather than HTML: the Accept
+// header lists application/json, and before text/html if both are present.
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	jsonAt := strings.Index(accept, "application/json")
+	if jsonAt < 0 {
+		return false
+	}
+	htmlAt := strings.Index(accept, "text/html")
+	return htmlAt < 0 || jsonAt < htmlAt
+}
+
+// writeJSON writes v as a JSON response.
+func (h *MarketHandler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// marketListResponse is the JSON body of GET /markets and GET /markets/archive.
+type marketListResponse struct {
+	Markets          []MarketView `json:"markets"`
+	ArchivedCount    int          `json:"archived_count"`
+	Unavailable      []string     `json:"unavailable"` // Markets whose state could not be loaded right now
+	UnavailableCount int          `json:"unavailable_count"`
+}
+
+// marketDetailResponse is the JSON body of GET /market/{id}.
+type marketDetailResponse struct {
+	Market   *model.Market `json:"market"`
+	Archived bool          `json:"archived"`
+	Trades   []tradeJSON   `json:"trades"`            // Last ~24h, oldest first
+	Balance  *balanceJSON  `json:"balance,omitempty"` // Set for ?account=G...
+}
+
+type tradeJSON struct {
+	Kind      service.TradeKind `json:"kind"`
+	User      string            `json:"user"`
+	Outcome   string            `json:"outcome"`
+	Amount    float64           `json:"amount"`
+	Cost      float64           `json:"cost"`
+	Timestamp time.Time         `json:"timestamp"`
+	TxHash    string            `json:"tx_hash,omitempty"`
+}
+
+type balanceJSON struct {
+	Account string  `json:"account"`
+	Yes     float64 `json:"yes"`
+	No      float64 `json:"no"`
+}
+
+func tradesJSON(events []service.TradeEvent) []tradeJSON {
+	trades := make([]tradeJSON, len(events))
+	for i, e := range events {
+		trades[i] = tradeJSON{
+			Kind:      e.Kind,
+			User:      e.User,
+			Outcome:   e.Outcome,
+			Amount:    e.Amount,
+			Cost:      e.Cost,
+			Timestamp: e.Timestamp,
+			TxHash:    e.TxHash,
+		}
+	}
+	return trades
+}
+
+// quoteResponse is the JSON body of POST /market/{id}/quote. The quote fields match
+// POST /api/quote/{id}.
+type quoteResponse struct {
+	ContractID    string        `json:"contract_id"`
+	Outcome       model.Outcome `json:"outcome"`
+	Amount        float64       `json:"amount"`
+	Cost          float64       `json:"cost"`
+	PricePerShare float64       `json:"price_per_share"`
+	PriceAfter    float64       `json:"price_after"`
+	QuoteToken    string        `json:"quote_token"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	Slippage      float64       `json:"slippage"` // applied when the quote is confirmed
+	// SuggestedSlippage is the slippage suggested by the market's recent volatility,
+	// omitted while it has too little price history.
+	SuggestedSlippage float64 `json:"suggested_slippage,omitempty"`
+	// Estimate is the Go LMSR price of the quote, only with source=contract.
+	Estimate *quoteEstimateJSON `json:"estimate,omitempty"`
+}
+
+// quoteEstimateJSON is the Go LMSR estimate of a quote requested with source=contract.
+type quoteEstimateJSON struct {
+	Cost       float64 `json:"cost"`
+	PriceAfter float64 `json:"price_after"`
+	Difference float64 `json:"difference"` // contract cost minus estimate
+	Mismatch   bool    `json:"mismatch"`
+	Warning    string  `json:"warning,omitempty"`
+}
+
+func quoteEstimateResponse(e *service.QuoteEstimate) *quoteEstimateJSON {
+	if e == nil {
+		return nil
+	}
+	resp := &quoteEstimateJSON{
+		Cost:       e.Cost,
+		PriceAfter: e.PriceAfter,
+		Difference: e.Difference,
+		Mismatch:   e.Mismatch,
+	}
+	if e.Mismatch {
+		resp.Warning = quoteMismatchWarning(e)
+	}
+	return resp
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// OracleResolutionView is one resolved market on the oracle history page.
+type OracleResolutionView struct {
+	ID                  string
+	Question            string
+	Outcome             string
+	EndDate             time.Time // zero when metadata is unavailable
+	ResolvedAt          time.Time // zero when resolved before the RPC event retention window
+	Delay               string    // end date to resolution, empty when either is unknown
+	ResolutionSource    string
+	ResolutionSourceURL string // set when ResolutionSource is an http(s) URL
+	TxHash              string
+	MetadataHash        string
+}
+
+// handleOracleHistory renders every market the oracle has resolved, newest first.
+func (h *MarketHandler) handleOracleHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var resolutions []OracleResolutionView
+	var historyError string
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		historyError = "Factory contract not configured"
+	} else if contractIDs, err := h.factoryService.ListMarkets(ctx); err != nil {
+		h.logger.Error("failed to list markets for oracle history", "error", err)
+		historyError = "Failed to fetch markets from factory"
+	} else {
+		loaded, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+		if err != nil {
+			h.logger.Warn("failed to get some market states", "error", err)
+		}
+		states := loaded.States
+		resolutions = h.buildResolutionViews(ctx, states)
+	}
+
+	var yes, no int
+	var delays []time.Duration
+	for _, v := range resolutions {
+		switch v.Outcome {
+		case string(model.OutcomeYes):
+			yes++
+		case string(model.OutcomeNo):
+			no++
+		}
+		if !v.EndDate.IsZero() && !v.ResolvedAt.IsZero() {
+			delays = append(delays, v.ResolvedAt.Sub(v.EndDate))
+		}
+	}
+	var medianDelay string
+	if len(delays) > 0 {
+		slices.Sort(delays)
+		medianDelay = formatDelay(delays[len(delays)/2])
+	}
+
+	data := map[string]any{
+		"Resolutions":     resolutions,
+		"Error":           historyError,
+		"YesCount":        yes,
+		"NoCount":         no,
+		"MedianDelay":     medianDelay,
+		"OraclePublicKey": h.oraclePublicKey,
+		"TZ":              userLocation(r),
+		"ActiveNav":       "oracle",
+		"Network":         h.networkName(),
+		"AccountID":       accountIDFromCookie(r),
+	}
+
+	if err := h.tmpl.Render(w, "oracle_history", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// buildResolutionViews builds views for resolved markets, fetching resolve events
+// and metadata in parallel. Lookup failures leave the corresponding fields empty.
+func (h *MarketHandler) buildResolutionViews(ctx context.Context, states []service.MarketState) []OracleResolutionView {
+	var views []OracleResolutionView
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, s := range states {
+		if !s.Resolved {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			view := OracleResolutionView{
+				ID:           s.ContractID,
+				Question:     "Market " + shortID(s.ContractID),
+				Outcome:      s.WinningOutcome,
+				MetadataHash: s.MetadataHash,
+			}
+
+			if h.eventService != nil {
+				resolution, found, err := h.eventService.GetResolution(ctx, s.ContractID)
+				if err != nil {
+					h.logger.Warn("failed to get resolve event", "contract_id", s.ContractID, "error", err)
+				} else if found {
+					view.ResolvedAt = resolution.Timestamp
+					view.TxHash = resolution.TxHash
+				}
+			}
+
+			if s.MetadataHash != "" && h.ipfsClient != nil {
+				var metadata model.MarketMetadata
+				if err := h.ipfsClient.GetJSON(ctx, s.MetadataHash, &metadata); err != nil {
+					h.logger.Warn("failed to fetch metadata", "hash", s.MetadataHash, "error", err)
+				} else {
+					view.Question = metadata.Question
+					view.EndDate = metadata.EndDate
+					view.ResolutionSource = metadata.ResolutionSource
+					view.ResolutionSourceURL = model.ResolutionSourceURL(metadata.ResolutionSource)
+				}
+			}
+
+			if !view.EndDate.IsZero() && !view.ResolvedAt.IsZero() {
+				view.Delay = formatDelay(view.ResolvedAt.Sub(view.EndDate))
+			}
+
+			mu.Lock()
+			views = append(views, view)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Newest resolution first; markets resolved before the retention window last
+	slices.SortFunc(views, func(a, b OracleResolutionView) int {
+		if c := b.ResolvedAt.Compare(a.ResolvedAt); c != 0 {
+			return c
+		}
+		return b.EndDate.Compare(a.EndDate)
+	})
+	return views
+}
+
+// formatDelay formats a duration in days, hours and minutes, e.g. "2d 3h".
+// Negative delays (resolved before the end date) are prefixed with "-".
+func formatDelay(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%s%dd %dh", sign, days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%s%dh %dm", sign, hours, minutes)
+	default:
+		return fmt.Sprintf("%s%dm", sign, minutes)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// oracleQueue returns the oracle's pending deploys and resolves in submission order,
+// after dropping the ones that landed. Only transactions the oracle built itself are
+// queued; one built for the oracle's account by anyone else is never signed or exported.
+func (h *MarketHandler) oracleQueue(r *http.Request) []service.PendingTx {
+	if h.pendingWatch != nil {
+		if err := h.pendingWatch.Refresh(r.Context(), h.oraclePublicKey); err != nil {
+			h.logger.Warn("failed to check oracle transactions", "error", err)
+		}
+	}
+	return slices.DeleteFunc(h.pendingTxs.Queue(h.oraclePublicKey), func(tx service.PendingTx) bool {
+		return (tx.Kind != service.PendingTxDeploy && tx.Kind != service.PendingTxResolve) || tx.BuiltBy != h.oraclePublicKey
+	})
+}
+
+// handleOracleQueue renders the oracle's signing queue: every deploy and resolve built
+// for the oracle and not yet on-chain, in the order their sequence numbers require.
+// Oracle transactions built back-to-back get consecutive sequence numbers, so the
+// whole queue can be signed at once and submitted in order, or one by one.
+func (h *MarketHandler) handleOracleQueue(w http.ResponseWriter, r *http.Request) {
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return
+	}
+	if !h.requireOracle(w, r, "/oracle/queue") {
+		return
+	}
+
+	now := time.Now()
+	queue := h.oracleQueue(r)
+	var expired int
+	for _, tx := range queue {
+		if tx.IsExpired(now) {
+			expired++
+		}
+	}
+	if wantsJSON(r) {
+		h.writeJSON(w, queue)
+		return
+	}
+
+	data := map[string]any{
+		"Queue":             queue,
+		"Expired":           expired,
+		"ServerSign":        h.oracleSigner != nil,
+		"NetworkPassphrase": h.networkPassphrase,
+		"Submitted":         r.URL.Query().Get("submitted"),
+		"Now":               now,
+		"TZ":                userLocation(r),
+		"ActiveNav":         "oracle",
+		"Network":           h.networkName(),
+		"AccountID":         accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "oracle_queue", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleExportOracleQueue downloads the queue as one transaction envelope per line, in
+// submission order, for signing them all at once offline or with the stellar CLI.
+// Expired transactions are left out.
+func (h *MarketHandler) handleExportOracleQueue(w http.ResponseWriter, r *http.Request) {
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return
+	}
+	if !h.requireOracle(w, r, "/oracle/queue") {
+		return
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	for _, tx := range h.oracleQueue(r) {
+		if !tx.IsExpired(now) {
+			b.WriteString(tx.XDR)
+			b.WriteByte('\n')
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="oracle-queue-%s.txt"`, now.UTC().Format("20060102-150405")))
+	fmt.Fprint(w, b.String())
+}
+
+// handleRebuildOracleQueue rebuilds every transaction of the queue in order, from the
+// oracle account's sequence number on the network. It repairs a queue with a gap,
+// e.g. after a transaction expired or was dismissed, which leaves every later one
+// with a sequence number the network rejects.
+func (h *MarketHandler) handleRebuildOracleQueue(w http.ResponseWriter, r *http.Request) {
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return
+	}
+	if !h.requireOracle(w, r, "/oracle/queue") {
+		return
+	}
+
+	queue := h.oracleQueue(r)
+	h.marketService.ReleaseSequences(h.oraclePublicKey)
+	for _, pending := range queue {
+		result, err := h.rebuildPending(r.Context(), pending)
+		if err != nil {
+			// Later transactions keep their old sequence numbers; rebuilding again
+			// after fixing this one restarts from the network.
+			h.writeError(w, r, err, "pending_id", pending.ID, "kind", pending.Kind, "contract_id", pending.ContractID)
+			return
+		}
+		h.replacePending(pending, result)
+	}
+	h.logger.Info("rebuilt oracle signing queue", "transactions", len(queue))
+	h.audit(r, "queue.rebuild", map[string]string{"transactions": strconv.Itoa(len(queue))})
+	http.Redirect(w, r, "/oracle/queue", http.StatusSeeOther)
+}
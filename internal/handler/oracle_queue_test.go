@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+// signIn completes SEP-10 sign-in for kp and returns its session cookie.
+func signIn(t *testing.T, auth *service.AuthService, kp *keypair.Full) *http.Cookie {
+	t.Helper()
+	challenge, err := auth.Challenge(kp.Address())
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	parsed, err := txnbuild.TransactionFromXDR(challenge)
+	if err != nil {
+		t.Fatalf("TransactionFromXDR() error = %v", err)
+	}
+	tx, _ := parsed.Transaction()
+	if tx, err = tx.Sign(config.TestnetNetworkPassphrase, kp); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	signed, err := tx.Base64()
+	if err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	token, _, err := auth.Verify(signed)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	return &http.Cookie{Name: sessionCookie, Value: token}
+}
+
+func TestOracleQueueOnlyHoldsOracleBuilds(t *testing.T) {
+	const contractID = "CBQHNAXSI55GX2GN6D67GK7BHVPSLJUGZQEU7WJ5LKR5PNUCGLIMAO4K"
+	auth, err := service.NewAuthService("", "predict.example", config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewAuthService() error = %v", err)
+	}
+	oracle := keypair.MustRandom()
+	store := service.NewPendingTxStore()
+	h := &MarketHandler{
+		pendingTxs:        store,
+		authService:       auth,
+		oraclePublicKey:   oracle.Address(),
+		networkPassphrase: config.TestnetNetworkPassphrase,
+		logger:            slog.New(slog.DiscardHandler),
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	// An anonymous resolve is sent to sign-in before anything is built or queued.
+	form := url.Values{"outcome": {"NO"}, "confirm": {"1"}}
+	req := httptest.NewRequest(http.MethodPost, "/market/"+contractID+"/resolve", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || !strings.HasPrefix(rec.Header().Get("Location"), "/auth") {
+		t.Fatalf("anonymous resolve = %d %q, want a redirect to sign-in", rec.Code, rec.Header().Get("Location"))
+	}
+	if queued := store.List(oracle.Address()); len(queued) != 0 {
+		t.Fatalf("anonymous resolve queued %d transactions, want none", len(queued))
+	}
+
+	// Entries for the oracle's account that someone else built stay out of the queue.
+	expires := time.Now().Add(time.Hour)
+	for i, builtBy := range []string{"", keypair.MustRandom().Address(), oracle.Address()} {
+		if _, err := store.Add(service.PendingTx{
+			Kind:      service.PendingTxResolve,
+			Account:   oracle.Address(),
+			XDR:       "resolve-built-by-" + builtBy,
+			Sequence:  int64(i + 1),
+			ExpiresAt: expires,
+			BuiltBy:   builtBy,
+			Resolve:   &service.ResolveRequest{OraclePublicKey: oracle.Address(), ContractID: contractID, WinningOutcome: model.OutcomeYes},
+		}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/oracle/queue/export", nil)
+	req.AddCookie(signIn(t, auth, oracle))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got, want := rec.Body.String(), "resolve-built-by-"+oracle.Address()+"\n"; got != want {
+		t.Errorf("export = %q, want only the oracle's own build %q", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// addServerSign offers one-click signing on the transaction page when the server holds
+// the oracle key, the oracle is signed in and the transaction is the oracle's to sign.
+func (h *MarketHandler) addServerSign(r *http.Request, data map[string]any, result *model.TransactionResult) {
+	if h.oracleSigner == nil || result == nil || result.SignWith != h.oraclePublicKey {
+		return
+	}
+	data["ServerSign"] = h.isOracleSession(r)
+}
+
+// handleOracleSign handles POST /oracle/sign: signs an oracle transaction built here
+// (resolve, withdraw or deploy) with the configured oracle key and submits it, then
+// follows it on /tx/{hash}, or returns to the local path in "return" with the hash
+// as ?submitted=. Only the signed-in oracle may use it, only when
+// ORACLE_SECRET_KEY or ORACLE_KEYSTORE_FILE is set, and only for a transaction
+// this server built for the oracle: the pending copy is what gets signed.
+func (h *MarketHandler) handleOracleSign(w http.ResponseWriter, r *http.Request) {
+	if h.oracleSigner == nil {
+		h.renderError(w, r, http.StatusNotFound, "Server-side oracle signing is not enabled")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to sign with the server key")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Transaction submission not available")
+		return
+	}
+
+	builtHash, err := soroban.TransactionHash(strings.TrimSpace(r.FormValue("xdr")), h.networkPassphrase)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("%w: %v", service.ErrInvalidTransaction, err))
+		return
+	}
+	pending, err := h.pendingTxs.FindByHash(h.oracleSigner.Address(), builtHash)
+	if errors.Is(err, service.ErrPendingTxNotFound) {
+		err = service.ErrTxNotBuilt
+	}
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", builtHash)
+		return
+	}
+
+	var contracts service.OracleContracts
+	if h.factoryService != nil {
+		contracts = h.factoryService
+	}
+	signedXDR, hash, err := h.oracleSigner.Sign(r.Context(), pending.XDR, contracts)
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", builtHash)
+		return
+	}
+	broadcast, err := h.marketService.SubmitTx(r.Context(), signedXDR, hash, h.oracleSigner.Address())
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", hash)
+		return
+	}
+	h.logger.Info("oracle transaction signed by server", "tx_hash", hash, "state", broadcast.State)
+	h.audit(r, "tx.server_signed", map[string]string{"tx_hash": hash, "state": string(broadcast.State)})
+	h.pendingTxs.Remove(pending.Account, pending.ID)
+
+	if wantsJSON(r) {
+		h.writeJSON(w, txSubmitResponse{Hash: hash, Status: broadcast.RPCStatus, State: string(broadcast.State)})
+		return
+	}
+	if returnTo := r.FormValue("return"); returnTo != "" {
+		http.Redirect(w, r, localReturnPath(returnTo)+"?submitted="+hash, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/tx/"+hash, http.StatusSeeOther)
+}
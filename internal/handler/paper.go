@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// paperPosition is a paper position with its market's question and current value.
+type paperPosition struct {
+	service.PaperPosition
+	Question string
+	Value    float64 // Tokens at the current prices
+	Priced   bool
+}
+
+// handlePaper renders the signed-in account's paper trading balance. Positions in
+// markets that resolved since the last visit are settled first.
+func (h *MarketHandler) handlePaper(w http.ResponseWriter, r *http.Request) {
+	if h.paper == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Paper trading is not enabled")
+		return
+	}
+	account := h.requireSession(w, r, "/paper")
+	if account == "" {
+		return
+	}
+
+	_, positions := h.paper.Account(account)
+	views := make(map[string]MarketView, len(positions))
+	if len(positions) > 0 && h.factoryService != nil && h.factoryService.HasFactory() {
+		ids := make([]string, len(positions))
+		for i, p := range positions {
+			ids[i] = p.ContractID
+		}
+		loaded, err := h.factoryService.GetMarketStates(r.Context(), ids)
+		if err != nil {
+			h.logger.Warn("failed to load paper markets", "account", account, "error", err)
+		}
+		for _, state := range loaded.States {
+			if state.WinningOutcome == "" {
+				continue
+			}
+			if _, err := h.paper.Settle(state.ContractID, state.WinningOutcome); err != nil {
+				h.logger.Warn("failed to settle paper positions", "contract_id", state.ContractID, "error", err)
+			}
+		}
+		for _, view := range h.buildMarketViews(r.Context(), loaded.States) {
+			views[view.ID] = view
+		}
+	}
+
+	paper, positions := h.paper.Account(account)
+	rows := make([]paperPosition, len(positions))
+	value := paper.Balance
+	for i, p := range positions {
+		rows[i] = paperPosition{PaperPosition: p, Question: "Market " + shortID(p.ContractID)}
+		if view, ok := views[p.ContractID]; ok {
+			rows[i].Question = view.Question
+			rows[i].Value = p.Yes*view.PriceYes + p.No*view.PriceNo
+			rows[i].Priced = true
+			value += rows[i].Value
+		}
+	}
+	trades := paper.Trades
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+
+	if wantsJSON(r) {
+		h.writeJSON(w, map[string]any{
+			"account":       account,
+			"balance":       paper.Balance,
+			"start_balance": h.paper.StartBalance(),
+			"positions":     positions,
+			"trades":        trades,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Paper":        paper,
+		"Positions":    rows,
+		"Trades":       trades,
+		"Value":        value,
+		"StartBalance": h.paper.StartBalance(),
+		"ActiveNav":    "markets",
+		"Network":      h.networkName(),
+		"AccountID":    accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "paper", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handlePaperTrade buys or sells paper tokens at the market's current quote.
+func (h *MarketHandler) handlePaperTrade(w http.ResponseWriter, r *http.Request) {
+	contractID := r.PathValue("id")
+	if h.paper == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Paper trading is not enabled")
+		return
+	}
+	account := h.requireSession(w, r, "/market/"+contractID+"#paper")
+	if account == "" {
+		return
+	}
+
+	outcome, err := model.ParseOutcome(r.FormValue("outcome"))
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount <= 0 {
+		h.renderError(w, r, http.StatusBadRequest, "Amount must be positive")
+		return
+	}
+
+	var trade service.PaperTrade
+	switch r.FormValue("side") {
+	case "", "buy":
+		trade, err = h.paper.Buy(r.Context(), account, contractID, outcome, amount)
+	case "sell":
+		trade, err = h.paper.Sell(r.Context(), account, contractID, outcome, amount)
+	default:
+		h.renderError(w, r, http.StatusBadRequest, "Side must be buy or sell")
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID, "account", account)
+		return
+	}
+	h.logger.Info("paper trade", "account", account, "contract_id", contractID, "kind", trade.Kind, "outcome", trade.Outcome, "amount", trade.Amount, "cost", trade.Cost)
+
+	if wantsJSON(r) {
+		h.writeJSON(w, trade)
+		return
+	}
+	http.Redirect(w, r, "/paper", http.StatusSeeOther)
+}
+
+// handlePaperReset restores the starting paper balance of the signed-in account.
+func (h *MarketHandler) handlePaperReset(w http.ResponseWriter, r *http.Request) {
+	if h.paper == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Paper trading is not enabled")
+		return
+	}
+	account := h.requireSession(w, r, "/paper")
+	if account == "" {
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+	if err := h.paper.Reset(account); err != nil {
+		h.writeError(w, r, err, "account", account)
+		return
+	}
+	h.logger.Info("paper account reset", "account", account)
+	http.Redirect(w, r, "/paper", http.StatusSeeOther)
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// recordPendingTx remembers a built transaction so the user can find or rebuild it later.
+// builtBy is the signed-in account that built it, if any.
+// Failures are logged only: tracking is a convenience and must not block the trade flow.
+func (h *MarketHandler) recordPendingTx(kind service.PendingTxKind, contractID, builtBy string, result *model.TransactionResult, trade *service.TradeRequest) {
+	if h.pendingTxs == nil || result == nil {
+		return
+	}
+	hash, err := soroban.TransactionHash(result.XDR, h.networkPassphrase)
+	if err != nil {
+		h.logger.Warn("failed to hash pending transaction", "kind", kind, "contract_id", contractID, "error", err)
+	}
+	_, err = h.pendingTxs.Add(service.PendingTx{
+		Kind:          kind,
+		Account:       result.SignWith,
+		ContractID:    contractID,
+		Description:   result.Description,
+		XDR:           result.XDR,
+		Hash:          hash,
+		AuthExpiresAt: result.AuthExpiresAt,
+		BuiltBy:       builtBy,
+		Trade:         trade,
+	})
+	if err != nil {
+		h.logger.Warn("failed to record pending transaction", "kind", kind, "contract_id", contractID, "error", err)
+	}
+}
+
+// recordPendingDeploy records a built deploy transaction, keeping the description,
+// deploy request and builder of pending for the next rebuild.
+func (h *MarketHandler) recordPendingDeploy(pending service.PendingTx, result *model.TransactionResult) {
+	hash, err := soroban.TransactionHash(result.XDR, h.networkPassphrase)
+	if err != nil {
+		h.logger.Warn("failed to hash pending transaction", "kind", pending.Kind, "error", err)
+	}
+	_, err = h.pendingTxs.Add(service.PendingTx{
+		Kind:          pending.Kind,
+		Account:       result.SignWith,
+		Description:   pending.Description,
+		XDR:           result.XDR,
+		Hash:          hash,
+		AuthExpiresAt: result.AuthExpiresAt,
+		BuiltBy:       pending.BuiltBy,
+		Deploy:        pending.Deploy,
+	})
+	if err != nil {
+		h.logger.Warn("failed to record pending transaction", "kind", pending.Kind, "error", err)
+	}
+}
+
+// pendingSession returns the signed-in account whose pending transactions the request
+// addresses, or redirects to sign-in and returns "". Pending entries are only shown
+// and changed for their own account, never for an account named in the form or the
+// account cookie.
+func (h *MarketHandler) pendingSession(w http.ResponseWriter, r *http.Request) string {
+	return h.requireSession(w, r, "/pending")
+}
+
+// handlePendingList renders all pending transactions for the signed-in account.
+func (h *MarketHandler) handlePendingList(w http.ResponseWriter, r *http.Request) {
+	accountID := accountIDFromCookie(r)
+	sessionAccount := h.sessionAccount(r)
+
+	var pendingTxs []service.PendingTx
+	if sessionAccount != "" && h.pendingTxs != nil {
+		pendingTxs = h.pendingTxs.List(sessionAccount)
+	}
+
+	data := map[string]any{
+		"PendingTxs":     pendingTxs,
+		"SessionAccount": sessionAccount,
+		"Unclaimed":      h.unclaimedWinnings(accountID),
+		"Now":            time.Now(),
+		"TZ":             userLocation(r),
+		"ActiveNav":      "pending",
+		"Network":        h.networkName(),
+		"AccountID":      accountID,
+	}
+
+	if err := h.tmpl.Render(w, "pending", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleRegeneratePending rebuilds a pending transaction with a fresh quote,
+// sequence number and time bounds, replacing the old entry.
+func (h *MarketHandler) handleRegeneratePending(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return
+	}
+
+	id := r.PathValue("id")
+	account := h.pendingSession(w, r)
+	if account == "" {
+		return
+	}
+
+	pending, err := h.pendingTxs.Get(account, id)
+	if err != nil {
+		h.writeError(w, r, err, "pending_id", id)
+		return
+	}
+
+	result, err := h.rebuildPending(r.Context(), pending)
+	if err != nil {
+		h.writeError(w, r, err, "pending_id", id, "kind", pending.Kind, "contract_id", pending.ContractID)
+		return
+	}
+	h.replacePending(pending, result)
+
+	data := map[string]any{
+		"Result":            result,
+		"MarketID":          pending.ContractID,
+		"ActiveNav":         "markets",
+		"Network":           h.networkName(),
+		"NetworkPassphrase": h.networkPassphrase,
+		"AccountID":         accountIDFromCookie(r),
+	}
+
+	h.addServerSign(r, data, result)
+	h.addSignElsewhere(data, result)
+	h.addAuthExpiry(r, data, result)
+
+	if err := h.tmpl.Render(w, "transaction", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// rebuildPending builds a pending transaction again from its original request, with a
+// fresh quote, sequence number and time bounds.
+func (h *MarketHandler) rebuildPending(ctx context.Context, pending service.PendingTx) (*model.TransactionResult, error) {
+	var result *model.TransactionResult
+	var err error
+	switch pending.Kind {
+	case service.PendingTxBuy:
+		if pending.Trade == nil {
+			err = fmt.Errorf("pending buy %s has no trade request", pending.ID)
+			break
+		}
+		// Regenerating is an explicit request for a fresh quote, so issue one here.
+		var quote *service.Quote
+		quote, err = h.marketService.GetQuote(ctx, pending.ContractID, pending.Trade.Outcome, pending.Trade.ShareAmount)
+		if err != nil {
+			break
+		}
+		result, err = h.marketService.BuildBuyTx(ctx, service.BuyRequest{
+			TradeRequest: *pending.Trade,
+			QuoteToken:   quote.Token,
+		})
+	case service.PendingTxSell:
+		if pending.Trade == nil {
+			err = fmt.Errorf("pending sell %s has no trade request", pending.ID)
+			break
+		}
+		result, err = h.marketService.BuildSellTx(ctx, service.SellRequest{TradeRequest: *pending.Trade})
+	case service.PendingTxClaim:
+		result, err = h.marketService.BuildClaimTx(ctx, service.ClaimRequest{
+			UserPublicKey: pending.Account,
+			ContractID:    pending.ContractID,
+		})
+	case service.PendingTxWithdraw:
+		result, err = h.marketService.BuildWithdrawTx(ctx, service.WithdrawRequest{
+			OraclePublicKey: pending.Account,
+			ContractID:      pending.ContractID,
+		})
+	case service.PendingTxDeploy:
+		if pending.Deploy == nil {
+			err = fmt.Errorf("pending deploy %s has no deploy request", pending.ID)
+			break
+		}
+		result, err = h.factoryService.BuildDeployMarketTx(ctx, *pending.Deploy)
+	case service.PendingTxResolve:
+		if pending.Resolve == nil {
+			err = fmt.Errorf("pending resolve %s has no resolve request", pending.ID)
+			break
+		}
+		result, err = h.marketService.BuildResolveTx(ctx, *pending.Resolve)
+	default:
+		err = fmt.Errorf("unknown pending transaction kind %q", pending.Kind)
+	}
+	return result, err
+}
+
+// replacePending swaps a pending transaction for its rebuilt version.
+func (h *MarketHandler) replacePending(pending service.PendingTx, result *model.TransactionResult) {
+	h.pendingTxs.Remove(pending.Account, pending.ID)
+	switch pending.Kind {
+	case service.PendingTxDeploy:
+		h.recordPendingDeploy(pending, result)
+	case service.PendingTxResolve:
+		h.recordPendingResolve(*pending.Resolve, pending.BuiltBy, result)
+	default:
+		h.recordPendingTx(pending.Kind, pending.ContractID, pending.BuiltBy, result, pending.Trade)
+		h.trackReferral(result)
+	}
+}
+
+// pendingByXDR returns the account's pending entry of a built transaction, signed or not.
+func (h *MarketHandler) pendingByXDR(account, txXDR string) (service.PendingTx, bool) {
+	if h.pendingTxs == nil {
+		return service.PendingTx{}, false
+	}
+	hash, err := soroban.TransactionHash(txXDR, h.networkPassphrase)
+	if err != nil {
+		return service.PendingTx{}, false
+	}
+	pending, err := h.pendingTxs.FindByHash(account, hash)
+	return pending, err == nil
+}
+
+// handleDismissPending removes a pending transaction (e.g. after the user submitted it).
+func (h *MarketHandler) handleDismissPending(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	account := h.pendingSession(w, r)
+	if account == "" {
+		return
+	}
+	if h.pendingTxs != nil {
+		h.pendingTxs.Remove(account, r.PathValue("id"))
+	}
+
+	redirect := "/pending"
+	if contractID := r.FormValue("contract_id"); soroban.ValidateContractID(contractID) == nil {
+		redirect = "/market/" + contractID
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mtlprog/total/internal/service"
+	"github.com/stellar/go-stellar-sdk/keypair"
+)
+
+// maxProfilePositions bounds the positions listed on a profile, most recent first.
+const maxProfilePositions = 100
+
+// profilePosition is a position on a profile page with its market's question.
+type profilePosition struct {
+	service.AccountPosition
+	Question string
+}
+
+// handleProfile renders a trader's public profile: positions by market, resolution
+// wins and achievements, from the /stats aggregation. Accounts that hid their profile are
+// shown as private to everyone but themselves.
+func (h *MarketHandler) handleProfile(w http.ResponseWriter, r *http.Request) {
+	account := r.PathValue("pubkey")
+	if _, err := keypair.ParseAddress(account); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	if h.stats == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Profiles are not enabled")
+		return
+	}
+	own := h.sessionAccount(r) == account
+	hidden := h.profiles != nil && h.profiles.Hidden(account)
+	if hidden && !own {
+		h.renderError(w, r, http.StatusNotFound, "This profile is private")
+		return
+	}
+
+	stats := h.stats.AccountStats(account)
+	positions := h.stats.AccountPositions(account)
+	if len(positions) > maxProfilePositions {
+		positions = positions[:maxProfilePositions]
+	}
+
+	questions := make(map[string]string, len(positions))
+	if len(positions) > 0 && h.factoryService != nil && h.factoryService.HasFactory() {
+		ids := make([]string, len(positions))
+		for i, p := range positions {
+			ids[i] = p.ContractID
+		}
+		loaded, err := h.factoryService.GetMarketStates(r.Context(), ids)
+		if err != nil {
+			h.logger.Warn("failed to load profile markets", "account", account, "error", err)
+		}
+		for _, view := range h.buildMarketViews(r.Context(), loaded.States) {
+			questions[view.ID] = view.Question
+		}
+	}
+	rows := make([]profilePosition, len(positions))
+	for i, p := range positions {
+		rows[i] = profilePosition{AccountPosition: p, Question: questions[p.ContractID]}
+		if rows[i].Question == "" {
+			rows[i].Question = "Market " + shortID(p.ContractID)
+		}
+	}
+
+	data := map[string]any{
+		"Account":      account,
+		"Stats":        stats,
+		"Positions":    rows,
+		"Achievements": h.stats.Achievements(account),
+		"Own":          own,
+		"Hidden":       hidden,
+		"CanOptOut":    h.profiles != nil && h.authService != nil,
+		"ActiveNav":    "markets",
+		"Network":      h.networkName(),
+		"AccountID":    accountIDFromCookie(r),
+		"ProfilePath":  "/u/" + account,
+	}
+	if err := h.tmpl.Render(w, "profile", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleProfileVisibility hides or shows a profile. Only the account itself, signed in
+// via SEP-10, may change it.
+func (h *MarketHandler) handleProfileVisibility(w http.ResponseWriter, r *http.Request) {
+	account := r.PathValue("pubkey")
+	if h.profiles == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Profiles are not enabled")
+		return
+	}
+	returnTo := "/u/" + account
+	session := h.requireSession(w, r, returnTo)
+	if session == "" {
+		return
+	}
+	if session != account {
+		h.renderError(w, r, http.StatusForbidden, "Only the account owner can change its profile visibility")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	hidden := r.FormValue("hidden") == "true"
+	if err := h.profiles.SetHidden(account, hidden); err != nil {
+		h.writeError(w, r, err, "account", account)
+		return
+	}
+	h.logger.Info("profile visibility changed", "account", account, "hidden", hidden)
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// honeypotField is a form field hidden from people. Browsers leave it empty; bots that
+// fill in every input do not.
+const honeypotField = "website"
+
+// protectTx guards an unauthenticated endpoint that costs a Soroban simulation: it
+// rejects forms with the honeypot filled in and limits requests per client IP and,
+// when given, per account. kind separates budgets, so live quotes while typing do not
+// use up the budget for building the trade itself.
+func (h *MarketHandler) protectTx(kind string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.parseForm(w, r) {
+			return
+		}
+		if r.PostFormValue(honeypotField) != "" {
+			h.logger.Warn("honeypot field filled, rejecting request", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			h.renderError(w, r, http.StatusBadRequest, "Invalid form data")
+			return
+		}
+
+		if h.txLimiter != nil {
+			keys := []string{kind + ":ip:" + clientIP(r)}
+			if account := strings.TrimSpace(r.PostFormValue("user_public_key")); account != "" {
+				keys = append(keys, kind+":account:"+account)
+			}
+			now := time.Now()
+			for _, key := range keys {
+				if ok, retryAfter := h.txLimiter.Allow(key, now); !ok {
+					h.logger.Warn("rate limited", "key", key, "path", r.URL.Path)
+					w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+					h.renderError(w, r, http.StatusTooManyRequests, "Too many requests. Please wait a moment and try again.")
+					return
+				}
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// clientIP returns the IP of the client. X-Forwarded-For is only trusted from a
+// reverse proxy on a loopback or private address, and only its last entry — the one
+// that proxy appended — since clients can send any value.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+		return host
+	}
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		return host
+	}
+	entries := strings.Split(forwarded[len(forwarded)-1], ",")
+	if last := strings.TrimSpace(entries[len(entries)-1]); net.ParseIP(last) != nil {
+		return last
+	}
+	return host
+}
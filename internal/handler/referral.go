@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+const (
+	// referralCookie holds the referral code of the last ?ref= link the visitor followed.
+	referralCookie = "ref"
+	// referralCookieMaxAge is the attribution window of a referral link.
+	referralCookieMaxAge = 30 * 24 * 3600
+)
+
+// captureReferral stores a valid ?ref= code in the referral cookie. The last link wins.
+func (h *MarketHandler) captureReferral(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("ref")
+	if code == "" || service.ValidateReferralCode(code) != nil {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     referralCookie,
+		Value:    code,
+		Path:     "/",
+		MaxAge:   referralCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// referralFromCookie returns the visitor's referral code, or "" when unset or malformed.
+func referralFromCookie(r *http.Request) string {
+	c, err := r.Cookie(referralCookie)
+	if err != nil || service.ValidateReferralCode(c.Value) != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// trackReferral remembers a built trade transaction that carries a referral memo.
+// Failures are logged only: attribution must not block the trade flow.
+func (h *MarketHandler) trackReferral(result *model.TransactionResult) {
+	if h.referrals == nil || result == nil {
+		return
+	}
+	if err := h.referrals.Track(result.XDR); err != nil {
+		h.logger.Warn("failed to track referral transaction", "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// resolveConfirmation is the confirm step of resolving a market: the winning outcome
+// next to the question and the source it must be checked against.
+type resolveConfirmation struct {
+	Market     MarketView
+	Outcome    model.Outcome
+	SourceURL  string             // set when the resolution source is an http(s) URL
+	Dependents []linkedMarketView // Open markets conditional on this one
+}
+
+// renderResolveConfirmation shows the oracle page with the resolution to confirm. The
+// confirm form posts the outcome again with confirm=1 to build the transaction.
+func (h *MarketHandler) renderResolveConfirmation(w http.ResponseWriter, r *http.Request, contractID string, outcome model.Outcome) {
+	data := h.oracleData(r)
+	markets, _ := data["Markets"].([]MarketView)
+	for _, m := range markets {
+		if m.ID != contractID {
+			continue
+		}
+		if m.IsResolved {
+			h.writeError(w, r, service.ErrMarketResolved)
+			return
+		}
+		data["TZ"] = userLocation(r)
+		data["ResolveConfirm"] = resolveConfirmation{
+			Market:     m,
+			Outcome:    outcome,
+			SourceURL:  model.ResolutionSourceURL(m.ResolutionSource),
+			Dependents: linkedMarkets(markets, m.ID, true),
+		}
+		h.renderOracle(w, data)
+		return
+	}
+	h.renderError(w, r, http.StatusNotFound, "Market not found")
+}
+
+// checkDeployMetadata validates the metadata about to be deployed, so every new market
+// names its resolution source and none is scalar. Metadata that cannot be fetched
+// cannot be checked, so its deploy is refused until the gateway serves it.
+func (h *MarketHandler) checkDeployMetadata(r *http.Request, metadataHash string) error {
+	if h.ipfsClient == nil {
+		return service.ErrMetadataUnavailable
+	}
+	var metadata model.MarketMetadata
+	if err := h.ipfsClient.GetJSON(r.Context(), metadataHash, &metadata); err != nil {
+		h.logger.Warn("deploy: failed to fetch metadata for validation", "hash", metadataHash, "error", err)
+		return fmt.Errorf("%w: %v", service.ErrMetadataUnavailable, err)
+	}
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+	if metadata.Scalar != nil {
+		return service.ErrScalarMarketUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// ruleVar is one placeholder of a rule template in the metadata form.
+type ruleVar struct {
+	Name  string
+	Value string
+}
+
+// addRuleTemplates adds the rule templates panel of the oracle page. ?edit_rules={id}
+// prefills the template form for editing.
+func (h *MarketHandler) addRuleTemplates(r *http.Request, data map[string]any) {
+	if h.ruleTemplates == nil {
+		return
+	}
+	data["RuleTemplates"] = h.ruleTemplates.List()
+	data["RuleTemplatesManage"] = h.isOracleSession(r)
+	if id := strings.TrimSpace(r.URL.Query().Get("edit_rules")); id != "" {
+		if t, err := h.ruleTemplates.Get(id); err == nil {
+			data["EditRuleTemplate"] = t
+		}
+	}
+}
+
+// setCloneRules selects rule template id for the metadata form, with the variable
+// values from values (var_{name} fields).
+func (h *MarketHandler) setCloneRules(form *cloneForm, id string, values url.Values) error {
+	if h.ruleTemplates == nil {
+		return service.ErrRuleTemplateNotFound
+	}
+	t, err := h.ruleTemplates.Get(id)
+	if err != nil {
+		return err
+	}
+	form.Rules = &t
+	form.RuleVars = nil
+	for _, name := range t.Variables() {
+		form.RuleVars = append(form.RuleVars, ruleVar{Name: name, Value: strings.TrimSpace(values.Get("var_" + name))})
+	}
+	return nil
+}
+
+// ruleValues returns the variable values of the metadata form by name.
+func (f cloneForm) ruleValues() map[string]string {
+	values := make(map[string]string, len(f.RuleVars))
+	for _, v := range f.RuleVars {
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+// handleSaveRuleTemplate creates a rule template, or updates the one in the id field.
+// Only the signed-in oracle may change templates.
+func (h *MarketHandler) handleSaveRuleTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.ruleTemplates == nil {
+		h.renderError(w, r, http.StatusNotFound, "Rule templates are not enabled")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to manage rule templates")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	t := service.RuleTemplate{
+		ID:               strings.TrimSpace(r.FormValue("id")),
+		Name:             r.FormValue("name"),
+		Rules:            r.FormValue("rules"),
+		ResolutionSource: r.FormValue("resolution_source"),
+		Category:         r.FormValue("category"),
+	}
+	saved, err := h.ruleTemplates.Save(t)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.audit(r, "rules.save", map[string]string{"id": saved.ID, "name": saved.Name})
+	http.Redirect(w, r, "/oracle#rules", http.StatusSeeOther)
+}
+
+// handleDeleteRuleTemplate deletes a rule template. Only the signed-in oracle may
+// change templates.
+func (h *MarketHandler) handleDeleteRuleTemplate(w http.ResponseWriter, r *http.Request) {
+	if h.ruleTemplates == nil {
+		h.renderError(w, r, http.StatusNotFound, "Rule templates are not enabled")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to manage rule templates")
+		return
+	}
+
+	if err := h.ruleTemplates.Delete(r.PathValue("id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.audit(r, "rules.delete", map[string]string{"id": r.PathValue("id")})
+	http.Redirect(w, r, "/oracle#rules", http.StatusSeeOther)
+}
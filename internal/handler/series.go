@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mtlprog/total/internal/chart"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// seriesView is a series page: the member markets in series order and their totals.
+type seriesView struct {
+	Series     service.Series
+	Markets    []MarketView
+	Missing    int // Members whose state could not be loaded
+	Open       int // Unresolved members
+	Resolved   int
+	YesSold    float64 // Tokens
+	NoSold     float64 // Tokens
+	PoolLocked float64 // Collateral held by the member pools
+	SumYes     float64 // Sum of the open members' YES prices; about 1 for mutually exclusive markets
+}
+
+// seriesResponse is the JSON body of GET /series/{id}.
+type seriesResponse struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Markets     []MarketView `json:"markets"`
+	YesSold     float64      `json:"yes_sold"`
+	NoSold      float64      `json:"no_sold"`
+	PoolLocked  float64      `json:"pool_locked"`
+}
+
+// handleSeries renders a market series: its members in order, their total volume and
+// a chart comparing their YES probabilities.
+func (h *MarketHandler) handleSeries(w http.ResponseWriter, r *http.Request) {
+	if h.series == nil {
+		h.renderError(w, r, http.StatusNotFound, "Series not found")
+		return
+	}
+	ser, err := h.series.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.writeError(w, r, service.ErrFactoryNotConfigured)
+		return
+	}
+	ctx := r.Context()
+
+	loaded, err := h.factoryService.GetMarketStates(ctx, ser.MarketIDs)
+	if err != nil {
+		h.logger.Warn("failed to get some series market states", "series", ser.ID, "error", err)
+	}
+	states := loaded.States
+	view := seriesView{Series: ser, Missing: len(ser.MarketIDs) - len(states)}
+	for _, s := range states {
+		view.PoolLocked += float64(s.Pool) / float64(soroban.ScaleFactor)
+	}
+	view.Markets = h.buildMarketViews(ctx, states)
+	rows := make([]chart.ProbabilityRow, len(view.Markets))
+	for i, m := range view.Markets {
+		view.YesSold += m.YesSold
+		view.NoSold += m.NoSold
+		if m.IsResolved {
+			view.Resolved++
+		} else {
+			view.Open++
+			view.SumYes += m.PriceYes
+		}
+		rows[i] = chart.ProbabilityRow{Label: m.Question, Yes: outcomePrice(m, "YES")}
+	}
+
+	w.Header().Add("Vary", "Accept")
+	if wantsJSON(r) {
+		h.writeJSON(w, seriesResponse{
+			ID:          ser.ID,
+			Name:        ser.Name,
+			Description: ser.Description,
+			Markets:     view.Markets,
+			YesSold:     view.YesSold,
+			NoSold:      view.NoSold,
+			PoolLocked:  view.PoolLocked,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Series":           view,
+		"ProbabilityChart": chart.RenderProbabilitySVG(rows),
+		"IsOracleSession":  h.isOracleSession(r),
+		"TZ":               userLocation(r),
+		"ActiveNav":        "markets",
+		"Network":          h.networkName(),
+		"AccountID":        accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "series", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// addSeries adds the series panel of the oracle page, with the rollovers waiting to be
+// deployed. ?edit_series={id} prefills the series form for editing.
+func (h *MarketHandler) addSeries(r *http.Request, data map[string]any) {
+	if h.series == nil {
+		return
+	}
+	data["SeriesList"] = h.series.List()
+	data["SeriesManage"] = h.isOracleSession(r)
+	if h.rollovers != nil {
+		data["Rollovers"] = h.rollovers.List()
+	}
+	if id := strings.TrimSpace(r.URL.Query().Get("edit_series")); id != "" {
+		if ser, err := h.series.Get(id); err == nil {
+			data["EditSeries"] = ser
+		}
+	}
+}
+
+// handleSaveSeries creates a series, or updates the one in the id field, with the
+// contract IDs of the market_ids field in order. Only the signed-in oracle may change
+// series.
+func (h *MarketHandler) handleSaveSeries(w http.ResponseWriter, r *http.Request) {
+	if h.series == nil {
+		h.renderError(w, r, http.StatusNotFound, "Series are not enabled")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to manage series")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	ser := service.Series{
+		ID:          strings.TrimSpace(r.FormValue("id")),
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		MarketIDs:   strings.Fields(r.FormValue("market_ids")),
+		Recurring:   r.FormValue("recurring") != "",
+	}
+	if ser.Recurring {
+		var err error
+		if ser.LiquidityParam, err = strconv.ParseFloat(r.FormValue("liquidity_param"), 64); err != nil {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid liquidity parameter")
+			return
+		}
+		if ser.InitialFunding, err = strconv.ParseFloat(r.FormValue("initial_funding"), 64); err != nil {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid initial funding")
+			return
+		}
+	}
+	ser, err := h.series.Save(ser)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.audit(r, "series.save", map[string]string{"id": ser.ID, "name": ser.Name})
+	http.Redirect(w, r, "/series/"+ser.ID, http.StatusSeeOther)
+}
+
+// handleDeleteSeries deletes a series. Only the signed-in oracle may change series.
+func (h *MarketHandler) handleDeleteSeries(w http.ResponseWriter, r *http.Request) {
+	if h.series == nil {
+		h.renderError(w, r, http.StatusNotFound, "Series are not enabled")
+		return
+	}
+	if !h.isOracleSession(r) {
+		h.renderError(w, r, http.StatusForbidden, "Sign in as the oracle to manage series")
+		return
+	}
+
+	if err := h.series.Delete(r.PathValue("id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.audit(r, "series.delete", map[string]string{"id": r.PathValue("id")})
+	http.Redirect(w, r, "/oracle#series", http.StatusSeeOther)
+}
+
+// marketSeries returns the series a market belongs to, for links on its page.
+func (h *MarketHandler) marketSeries(contractID string) []service.Series {
+	if h.series == nil {
+		return nil
+	}
+	return h.series.ForMarket(contractID)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// shareLinkResponse is the JSON body of POST /market/{id}/share-link.
+type shareLinkResponse struct {
+	Code      string `json:"code"`
+	ShortURL  string `json:"short_url"`
+	TargetURL string `json:"target_url"`
+}
+
+// addTradePrefill adds trade form prefill from ?side=YES&amount=25, the slippage
+// preference and the share link fields to a market or outcome page. A side already set by the page (outcome pages)
+// is kept. Invalid values are ignored: the form falls back to its defaults.
+func (h *MarketHandler) addTradePrefill(r *http.Request, contractID string, data map[string]any) {
+	q := r.URL.Query()
+	if _, ok := data["Outcome"]; !ok {
+		if side, err := model.ParseOutcome(q.Get("side")); err == nil {
+			data["Outcome"] = string(side)
+		}
+	}
+	if amount, err := strconv.ParseFloat(strings.TrimSpace(q.Get("amount")), 64); err == nil && amount > 0 && amount <= service.MaxShareAmount {
+		data["Amount"] = amount
+	}
+
+	outcome := model.OutcomeYes
+	if side, ok := data["Outcome"].(string); ok {
+		outcome = model.Outcome(side)
+	}
+	data["Slippage"] = h.slippageChoiceFor(r, contractID, outcome)
+
+	data["ShareLinksEnabled"] = h.shareLinks != nil
+	if code := q.Get("shared"); code != "" && h.shareLinks != nil {
+		if _, err := h.shareLinks.Get(code); err == nil {
+			data["SharedURL"] = requestBaseURL(r) + "/s/" + code
+		}
+	}
+}
+
+// handleCreateShareLink shortens a prefilled trade link. Browsers are redirected back
+// to the market page, which shows the short link; JSON clients get it in the body.
+func (h *MarketHandler) handleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if h.shareLinks == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Share links are not enabled")
+		return
+	}
+
+	contractID := r.PathValue("id")
+	side, err := model.ParseOutcome(r.FormValue("side"))
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid outcome: must be YES or NO")
+		return
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("amount")), 64)
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+
+	// Only shorten links to markets the factory knows about.
+	if h.factoryService != nil && h.factoryService.HasFactory() {
+		loaded, err := h.factoryService.GetMarketStates(r.Context(), []string{contractID})
+		if err != nil {
+			h.writeError(w, r, err, "contract_id", contractID)
+			return
+		}
+		states := loaded.States
+		if len(states) == 0 {
+			h.renderError(w, r, http.StatusNotFound, "Market not found")
+			return
+		}
+	}
+
+	link, err := h.shareLinks.Create(service.CreateShareLinkRequest{
+		ContractID: contractID,
+		Side:       side,
+		Amount:     amount,
+	})
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+
+	if wantsJSON(r) {
+		base := requestBaseURL(r)
+		h.writeJSON(w, shareLinkResponse{
+			Code:      link.Code,
+			ShortURL:  base + "/s/" + link.Code,
+			TargetURL: base + link.Path(),
+		})
+		return
+	}
+	http.Redirect(w, r, link.Path()+"&shared="+link.Code, http.StatusSeeOther)
+}
+
+// handleShareLink redirects a short link to the market page with the prefilled trade form.
+func (h *MarketHandler) handleShareLink(w http.ResponseWriter, r *http.Request) {
+	if h.shareLinks == nil {
+		h.renderError(w, r, http.StatusNotFound, "Share link not found")
+		return
+	}
+	link, err := h.shareLinks.Get(r.PathValue("code"))
+	if err != nil {
+		h.renderError(w, r, http.StatusNotFound, "Share link not found")
+		return
+	}
+	http.Redirect(w, r, link.Path(), http.StatusFound)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/qr"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// qrModuleSize is the pixel size of one QR module in /pending/{id}/qr.svg.
+const qrModuleSize = 4
+
+// recordPendingResolve queues a resolve transaction built by builtBy for the oracle, so
+// it can be signed elsewhere and closed once it lands on-chain.
+func (h *MarketHandler) recordPendingResolve(req service.ResolveRequest, builtBy string, result *model.TransactionResult) {
+	if h.pendingTxs == nil || result == nil {
+		return
+	}
+	hash, err := soroban.TransactionHash(result.XDR, h.networkPassphrase)
+	if err != nil {
+		h.logger.Warn("failed to hash pending transaction", "kind", service.PendingTxResolve, "contract_id", req.ContractID, "error", err)
+	}
+	_, err = h.pendingTxs.Add(service.PendingTx{
+		Kind:          service.PendingTxResolve,
+		Account:       result.SignWith,
+		ContractID:    req.ContractID,
+		Description:   result.Description,
+		XDR:           result.XDR,
+		Hash:          hash,
+		AuthExpiresAt: result.AuthExpiresAt,
+		BuiltBy:       builtBy,
+		Resolve:       &req,
+	})
+	if err != nil {
+		h.logger.Warn("failed to record pending transaction", "kind", service.PendingTxResolve, "contract_id", req.ContractID, "error", err)
+	}
+}
+
+// addSignElsewhere links the transaction page to the pending entry of its transaction,
+// where it can be signed from a QR code on another device.
+func (h *MarketHandler) addSignElsewhere(data map[string]any, result *model.TransactionResult) {
+	if h.pendingTxs == nil || result == nil {
+		return
+	}
+	hash, err := soroban.TransactionHash(result.XDR, h.networkPassphrase)
+	if err != nil {
+		return
+	}
+	if pending, err := h.pendingTxs.FindByHash(result.SignWith, hash); err == nil {
+		data["SignElsewhere"] = pending
+	}
+}
+
+// sep7URI returns the SEP-7 URI that asks a wallet to sign the transaction. Networks
+// other than the public one must be named in it. Spaces are escaped as %20, since
+// SEP-7 values are percent-encoded rather than form-encoded.
+func (h *MarketHandler) sep7URI(txXDR string) string {
+	values := url.Values{"xdr": {txXDR}}
+	if h.networkName() != "public" {
+		values.Set("network_passphrase", h.networkPassphrase)
+	}
+	return "web+stellar:tx?" + strings.ReplaceAll(values.Encode(), "+", "%20")
+}
+
+// pendingEntry returns the signed-in account's pending transaction addressed by the
+// request's path ID, writing the error response when there is none.
+func (h *MarketHandler) pendingEntry(w http.ResponseWriter, r *http.Request) (service.PendingTx, bool) {
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return service.PendingTx{}, false
+	}
+	account := h.pendingSession(w, r)
+	if account == "" {
+		return service.PendingTx{}, false
+	}
+	pending, err := h.pendingTxs.Get(account, r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err, "pending_id", r.PathValue("id"))
+		return service.PendingTx{}, false
+	}
+	return pending, true
+}
+
+// handleSignElsewhere handles GET /pending/{id}: the pending transaction as a QR code
+// for a hardware wallet or air-gapped signer. The page polls /pending/{id}/status and
+// moves on to /tx/{hash} once the transaction is seen on-chain, wherever it was
+// submitted from.
+func (h *MarketHandler) handleSignElsewhere(w http.ResponseWriter, r *http.Request) {
+	pending, ok := h.pendingEntry(w, r)
+	if !ok {
+		return
+	}
+
+	uri := h.sep7URI(pending.XDR)
+	_, qrErr := qr.Encode([]byte(uri), qr.LevelL)
+	data := map[string]any{
+		"Pending":   pending,
+		"URI":       uri,
+		"QRTooLong": errors.Is(qrErr, qr.ErrTooLong),
+		"Now":       time.Now(),
+		"TZ":        userLocation(r),
+		"ActiveNav": "pending",
+		"Network":   h.networkName(),
+		"AccountID": accountIDFromCookie(r),
+	}
+
+	if err := h.tmpl.Render(w, "pending_sign", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handlePendingQR handles GET /pending/{id}/qr.svg: the SEP-7 URI of a pending
+// transaction as a QR code.
+func (h *MarketHandler) handlePendingQR(w http.ResponseWriter, r *http.Request) {
+	pending, ok := h.pendingEntry(w, r)
+	if !ok {
+		return
+	}
+	code, err := qr.Encode([]byte(h.sep7URI(pending.XDR)), qr.LevelL)
+	if err != nil {
+		h.renderError(w, r, http.StatusUnprocessableEntity, "Transaction is too large for a QR code. Copy the XDR instead.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if _, err := w.Write(code.SVG(qrModuleSize)); err != nil {
+		h.logger.Debug("failed to write QR code", "error", err)
+	}
+}
+
+// handlePendingStatus handles GET /pending/{id}/status: whether the pending
+// transaction has landed on-chain, as JSON. An included transaction is removed from
+// the pending list, so later polls return 404; follow it on /tx/{hash}.
+func (h *MarketHandler) handlePendingStatus(w http.ResponseWriter, r *http.Request) {
+	if h.pendingWatch == nil {
+		h.renderError(w, r, http.StatusNotFound, "Pending transactions are not tracked")
+		return
+	}
+	account := h.sessionAccount(r)
+	if account == "" {
+		h.renderError(w, r, http.StatusUnauthorized, "Sign in to follow pending transactions")
+		return
+	}
+
+	check, err := h.pendingWatch.Check(r.Context(), account, r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, err, "pending_id", r.PathValue("id"))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSON(w, check)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mtlprog/total/internal/chart"
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// defaultSimulation prefills the simulation form.
+var defaultSimulation = service.SimulationParams{
+	LiquidityParam:  config.DefaultLiquidityParam,
+	Trades:          200,
+	TradeSize:       10,
+	TrueProbability: 0.7,
+	Informed:        0.3,
+	Runs:            100,
+	Seed:            1,
+}
+
+// handleSimulateMarket renders the market simulation sandbox: it plays a market's life
+// with a chosen liquidity parameter and trade flow so oracles can size b before
+// funding a market. The form submits via GET, so results can be linked.
+func (h *MarketHandler) handleSimulateMarket(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params := defaultSimulation
+	var formError string
+	number := func(key string, v *float64) {
+		if s := q.Get(key); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				formError = "Invalid number for " + key
+				return
+			}
+			*v = f
+		}
+	}
+	integer := func(key string, v *int) {
+		if s := q.Get(key); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				formError = "Invalid number for " + key
+				return
+			}
+			*v = n
+		}
+	}
+	number("liquidity_param", &params.LiquidityParam)
+	integer("trades", &params.Trades)
+	number("trade_size", &params.TradeSize)
+	var trueProbability, informed float64 = params.TrueProbability * 100, params.Informed * 100
+	number("true_probability", &trueProbability)
+	number("informed", &informed)
+	params.TrueProbability, params.Informed = trueProbability/100, informed/100
+	integer("runs", &params.Runs)
+	if s := q.Get("seed"); s != "" {
+		seed, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			formError = "Invalid number for seed"
+		}
+		params.Seed = seed
+	}
+
+	var sim *service.MarketSimulation
+	if formError == "" && len(q) > 0 {
+		var err error
+		if sim, err = service.SimulateMarket(params); err != nil {
+			formError = err.Error()
+		}
+	}
+
+	data := map[string]any{
+		"Params":          params,
+		"TrueProbability": trueProbability,
+		"Informed":        informed,
+		"Simulation":      sim,
+		"Error":           formError,
+		"MaxTrades":       service.MaxSimulationTrades,
+		"MaxRuns":         service.MaxSimulationRuns,
+		"ActiveNav":       "oracle",
+		"Network":         h.networkName(),
+		"AccountID":       accountIDFromCookie(r),
+	}
+	if sim != nil {
+		prices := make([]chart.BandPoint, len(sim.Steps))
+		reserves := make([]chart.BandPoint, len(sim.Steps))
+		for i, s := range sim.Steps {
+			prices[i] = chart.BandPoint{Step: float64(s.Trade), Mid: s.PriceYes, Low: s.PriceLow, High: s.PriceHigh}
+			reserves[i] = chart.BandPoint{Step: float64(s.Trade), Mid: s.Reserve, Low: s.ReserveLow, High: s.ReserveHigh}
+		}
+		data["PriceChart"] = chart.RenderBandSVG(prices, "Simulated YES price by trade")
+		data["ReserveChart"] = chart.RenderBandSVG(reserves, "Simulated pool reserve by trade")
+		data["Final"] = sim.Steps[len(sim.Steps)-1]
+	}
+	if err := h.tmpl.Render(w, "oracle_simulate", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// noindexPaths are operator and transaction routes that crawlers should skip.
+// Everything else — the market list, archive, market and outcome pages — is indexable.
+var noindexPaths = []string{
+	"/admin",
+	"/oracle",
+	"/deploy",
+	"/pending",
+	"/auth",
+	"/account",
+	"/alerts/",
+	"/email/",
+	"/discord/",
+	"/tg/",
+	"/tx/",
+	"/api/",
+	"/status",
+	"/health",
+	"/metrics",
+}
+
+// SitemapHandler serves /sitemap.xml and /robots.txt.
+type SitemapHandler struct {
+	sitemap   *service.SitemapService
+	publicURL string // Empty derives the base URL from the request
+	logger    *slog.Logger
+}
+
+// NewSitemapHandler creates a new sitemap handler.
+func NewSitemapHandler(sitemap *service.SitemapService, publicURL string, logger *slog.Logger) *SitemapHandler {
+	if sitemap == nil {
+		panic("NewSitemapHandler: sitemap must not be nil")
+	}
+	return &SitemapHandler{
+		sitemap:   sitemap,
+		publicURL: publicURL,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers sitemap routes.
+func (h *SitemapHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /sitemap.xml", h.handleSitemap)
+	mux.HandleFunc("GET /robots.txt", h.handleRobots)
+}
+
+// sitemapURLSet is the <urlset> document of the sitemap protocol.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+// handleSitemap lists the market list pages and every market from the cached sitemap.
+func (h *SitemapHandler) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	base := h.baseURL(r)
+	set := sitemapURLSet{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs: []sitemapURL{
+			{Loc: base + "/", ChangeFreq: "hourly"},
+			{Loc: base + "/markets/archive", ChangeFreq: "daily"},
+			{Loc: base + "/oracle/history", ChangeFreq: "daily"},
+			{Loc: base + "/stats", ChangeFreq: "daily"},
+		},
+	}
+	for _, e := range h.sitemap.Entries() {
+		u := sitemapURL{Loc: base + "/market/" + e.ContractID, ChangeFreq: "hourly"}
+		if e.Resolved {
+			u.ChangeFreq = "never"
+		}
+		if !e.LastMod.IsZero() {
+			u.LastMod = e.LastMod.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(set); err != nil {
+		h.logger.Error("failed to encode sitemap", "error", err)
+	}
+}
+
+// handleRobots allows market pages, keeps crawlers off operator routes and points to the sitemap.
+func (h *SitemapHandler) handleRobots(w http.ResponseWriter, r *http.Request) {
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	sb.WriteString("Allow: /oracle/history\n")
+	for _, p := range noindexPaths {
+		fmt.Fprintf(&sb, "Disallow: %s\n", p)
+	}
+	fmt.Fprintf(&sb, "\nSitemap: %s/sitemap.xml\n", h.baseURL(r))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(sb.String())); err != nil {
+		h.logger.Debug("failed to write robots.txt", "error", err)
+	}
+}
+
+// baseURL returns PUBLIC_URL, or the scheme and host the request arrived on.
+func (h *SitemapHandler) baseURL(r *http.Request) string {
+	if h.publicURL != "" {
+		return h.publicURL
+	}
+	return requestBaseURL(r)
+}
+
+// requestBaseURL returns the scheme and host the request arrived on.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+// slippageCookie holds the slippage tolerance last chosen in the trade form, as a
+// fraction (e.g. "0.005"). Trades without an explicit slippage use it; without the
+// cookie they use the market's suggested slippage.
+const slippageCookie = "slippage"
+
+// slippagePresets are the tolerances offered as buttons in the trade form.
+var slippagePresets = []float64{0.005, 0.01, 0.02}
+
+// slippageCustom is the form value of the custom option; the tolerance is then read
+// from slippage_custom, in percent.
+const slippageCustom = "custom"
+
+// slippageAuto is the form value of the suggested slippage. Choosing it clears the
+// preference, so every market gets its own suggestion.
+const slippageAuto = "auto"
+
+// slippagePreset is one option of the trade form.
+type slippagePreset struct {
+	Value    string // form value, a fraction
+	Label    string // e.g. "0.5%"
+	Selected bool
+}
+
+// slippageChoice is the slippage part of the trade form.
+type slippageChoice struct {
+	Presets []slippagePreset
+	Custom  bool   // the preference is not one of the presets
+	Percent string // the preference in percent, e.g. "1.5"
+	Auto    bool   // no preference: the suggested slippage applies
+	// Suggested is the market's suggested slippage in percent, empty while it has
+	// too little price history.
+	Suggested string
+}
+
+// slippagePreference returns the user's slippage preference. ok is false when the
+// user has none.
+func slippagePreference(r *http.Request) (slippage float64, ok bool) {
+	c, err := r.Cookie(slippageCookie)
+	if err != nil {
+		return 0, false
+	}
+	s, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil || s <= 0 || s > model.MaxSlippage {
+		return 0, false
+	}
+	return s, true
+}
+
+// suggestedSlippage returns the slippage suggested by the market's recent volatility.
+func (h *MarketHandler) suggestedSlippage(contractID string, outcome model.Outcome) (float64, bool) {
+	if h.priceHistory == nil {
+		return 0, false
+	}
+	return h.priceHistory.SuggestSlippage(contractID, outcome, time.Now())
+}
+
+// autoSlippage returns the suggested slippage, falling back to model.DefaultSlippage.
+func (h *MarketHandler) autoSlippage(contractID string, outcome model.Outcome) float64 {
+	if s, ok := h.suggestedSlippage(contractID, outcome); ok {
+		return s
+	}
+	return model.DefaultSlippage
+}
+
+// defaultSlippage returns the slippage of a trade without one: the user's preference,
+// else the suggested slippage.
+func (h *MarketHandler) defaultSlippage(r *http.Request, contractID string, outcome model.Outcome) float64 {
+	if s, ok := slippagePreference(r); ok {
+		return s
+	}
+	return h.autoSlippage(contractID, outcome)
+}
+
+// usesAutoSlippage reports whether a trade request gets the suggested slippage: it
+// chose it, or it sent none and the user has no preference.
+func usesAutoSlippage(r *http.Request) bool {
+	switch strings.TrimSpace(r.FormValue("slippage")) {
+	case slippageAuto:
+		return true
+	case "":
+		_, ok := slippagePreference(r)
+		return !ok
+	}
+	return false
+}
+
+// setSlippageCookie remembers the slippage chosen for a trade.
+func setSlippageCookie(w http.ResponseWriter, slippage float64) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     slippageCookie,
+		Value:    strconv.FormatFloat(slippage, 'f', -1, 64),
+		Path:     "/",
+		MaxAge:   cookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSlippageCookie forgets the slippage preference.
+func clearSlippageCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     slippageCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// parseSlippage reads the slippage of a trade form: a fraction in slippage, or
+// "custom" with a percentage in slippage_custom. ok is false when the form has none.
+func parseSlippage(r *http.Request) (slippage float64, ok bool, err error) {
+	value := strings.TrimSpace(r.FormValue("slippage"))
+	switch value {
+	case "":
+		return 0, false, nil
+	case slippageCustom:
+		pct, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("slippage_custom")), 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("%w: custom slippage is not a number", model.ErrInvalidSlippage)
+		}
+		slippage = pct / 100
+	default:
+		slippage, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("%w: slippage is not a number", model.ErrInvalidSlippage)
+		}
+	}
+	if slippage <= 0 || slippage > model.MaxSlippage {
+		return 0, false, model.ErrInvalidSlippage
+	}
+	return slippage, true, nil
+}
+
+// tradeSlippage returns the slippage for a buy or sell: the one sent with the form,
+// which becomes the user's preference, or defaultSlippage. Choosing the suggested
+// slippage clears the preference. On invalid input it writes the error response and
+// returns false.
+func (h *MarketHandler) tradeSlippage(w http.ResponseWriter, r *http.Request, contractID string, outcome model.Outcome) (float64, bool) {
+	if strings.TrimSpace(r.FormValue("slippage")) == slippageAuto {
+		clearSlippageCookie(w)
+		return h.autoSlippage(contractID, outcome), true
+	}
+	slippage, explicit, err := parseSlippage(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return 0, false
+	}
+	if !explicit {
+		return h.defaultSlippage(r, contractID, outcome), true
+	}
+	setSlippageCookie(w, slippage)
+	return slippage, true
+}
+
+// slippageChoiceFor builds the trade form options for the user's preference and the
+// market's suggested slippage, which is selected when the user has no preference.
+func (h *MarketHandler) slippageChoiceFor(r *http.Request, contractID string, outcome model.Outcome) slippageChoice {
+	var choice slippageChoice
+	suggested, hasSuggestion := h.suggestedSlippage(contractID, outcome)
+	if hasSuggestion {
+		choice.Suggested = formatPercent(suggested)
+	}
+	current, ok := slippagePreference(r)
+	switch {
+	case ok:
+	case hasSuggestion:
+		current, choice.Auto = suggested, true
+	default:
+		current = model.DefaultSlippage
+	}
+	choice.Custom = !choice.Auto
+	choice.Percent = formatPercent(current)
+	for _, p := range slippagePresets {
+		selected := !choice.Auto && p == current
+		if selected {
+			choice.Custom = false
+		}
+		choice.Presets = append(choice.Presets, slippagePreset{
+			Value:    strconv.FormatFloat(p, 'f', -1, 64),
+			Label:    formatPercent(p) + "%",
+			Selected: selected,
+		})
+	}
+	return choice
+}
+
+// formatPercent formats a fraction in percent with at most two decimals, e.g. 0.005 as "0.5".
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10000)/100, 'f', -1, 64)
+}
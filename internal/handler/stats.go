@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mtlprog/total/internal/chart"
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/template"
+	"github.com/stellar/go-stellar-sdk/keypair"
+)
+
+// StatsHandler serves the public platform statistics page.
+type StatsHandler struct {
+	stats    *service.StatsService
+	profiles *service.ProfileSettings // nil: every account is public
+	tmpl     *template.Template
+	network  string
+	logger   *slog.Logger
+}
+
+// NewStatsHandler creates a new stats handler.
+func NewStatsHandler(stats *service.StatsService, profiles *service.ProfileSettings, tmpl *template.Template, network string, logger *slog.Logger) *StatsHandler {
+	if stats == nil {
+		panic("NewStatsHandler: stats must not be nil")
+	}
+	if tmpl == nil {
+		panic("NewStatsHandler: tmpl must not be nil")
+	}
+	return &StatsHandler{
+		stats:    stats,
+		profiles: profiles,
+		tmpl:     tmpl,
+		network:  network,
+		logger:   logger,
+	}
+}
+
+// RegisterRoutes registers stats routes.
+func (h *StatsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /stats", h.handleStats)
+	mux.HandleFunc("GET /api/v1/accounts/{pubkey}/stats", h.handleAPIAccountStats)
+	mux.HandleFunc("GET /leaderboard", h.handleLeaderboard)
+}
+
+// leaderboardSize is how many traders the leaderboard lists.
+const leaderboardSize = 50
+
+// statsResponse is the JSON body of GET /stats.
+type statsResponse struct {
+	service.PlatformStats
+	AvgResolutionHours float64 `json:"avg_resolution_hours,omitempty"`
+}
+
+// handleStats renders the statistics aggregated by the scheduler; it never calls the
+// backends itself.
+func (h *StatsHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.stats.Stats()
+
+	w.Header().Add("Vary", "Accept")
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statsResponse{
+			PlatformStats:      stats,
+			AvgResolutionHours: stats.AvgResolution.Hours(),
+		}); err != nil {
+			h.logger.Error("failed to encode stats response", "error", err)
+		}
+		return
+	}
+
+	points := make([]chart.VolumePoint, len(stats.DailyVolume))
+	for i, d := range stats.DailyVolume {
+		points[i] = chart.VolumePoint{Start: d.Day, Volume: d.Volume}
+	}
+	data := map[string]any{
+		"Stats":       stats,
+		"VolumeChart": chart.RenderVolumeSVG(points),
+		"AvgDays":     stats.AvgResolution.Hours() / 24,
+		"TZ":          userLocation(r),
+		"Network":     h.network,
+		"AccountID":   accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "stats", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleAPIAccountStats handles GET /api/v1/accounts/{pubkey}/stats with an account's
+// trade count, volume, markets, win rate and realized profit, for profile pages and
+// reputation tools. Like /stats it reads the aggregated totals only. Accounts that hid
+// their profile are not found.
+func (h *StatsHandler) handleAPIAccountStats(w http.ResponseWriter, r *http.Request) {
+	pubkey := r.PathValue("pubkey")
+	if _, err := keypair.ParseAddress(pubkey); err != nil {
+		writeJSONError(w, "invalid account ID", http.StatusBadRequest)
+		return
+	}
+	if h.profiles != nil && h.profiles.Hidden(pubkey) {
+		writeJSONError(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	if err := json.NewEncoder(w).Encode(h.stats.AccountStats(pubkey)); err != nil {
+		h.logger.Error("failed to encode account stats", "error", err)
+	}
+}
+
+// handleLeaderboard ranks traders by realized profit with their achievements. Accounts
+// that hid their profile are left out.
+func (h *StatsHandler) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	var hidden func(string) bool
+	if h.profiles != nil {
+		hidden = h.profiles.Hidden
+	}
+	entries := h.stats.Leaderboard(leaderboardSize, hidden)
+
+	w.Header().Add("Vary", "Accept")
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			h.logger.Error("failed to encode leaderboard", "error", err)
+		}
+		return
+	}
+
+	data := map[string]any{
+		"Entries":   entries,
+		"Updated":   h.stats.Stats().UpdatedAt,
+		"Network":   h.network,
+		"AccountID": accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "leaderboard", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
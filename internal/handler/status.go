@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mtlprog/total/internal/service"
+)
+
+// StatusHandler serves the network status widget data.
+type StatusHandler struct {
+	statusService *service.StatusService
+	logger        *slog.Logger
+}
+
+// NewStatusHandler creates a new status handler.
+func NewStatusHandler(statusService *service.StatusService, logger *slog.Logger) *StatusHandler {
+	return &StatusHandler{
+		statusService: statusService,
+		logger:        logger,
+	}
+}
+
+// RegisterRoutes registers status routes.
+func (h *StatusHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /status", h.handleStatus)
+}
+
+// statusResponse is the JSON body of GET /status.
+type statusResponse struct {
+	service.NetworkStatus
+	Healthy bool `json:"healthy"`
+}
+
+// handleStatus returns the last network status snapshot as JSON.
+// It never calls the backends itself: the snapshot is refreshed by the scheduler.
+func (h *StatusHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.statusService.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(statusResponse{
+		NetworkStatus: status,
+		Healthy:       status.Healthy(),
+	}); err != nil {
+		h.logger.Error("failed to encode status response", "error", err)
+	}
+}
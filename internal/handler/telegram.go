@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// Routes under /tg/ are a compact, mobile-first version of the site for use as a
+// Telegram Mini App. Pages are public like the rest of the site; only actions tied
+// to the Telegram user, such as alerts to their own chat, verify the launch data.
+
+// telegramAlertsEnabled reports whether the Mini App can subscribe its user to alerts.
+func (h *MarketHandler) telegramAlertsEnabled() bool {
+	return h.telegramApp != nil && h.alertsEnabled() && slices.Contains(h.alerts.Channels(), notify.ChannelTelegram)
+}
+
+// handleTelegramMarkets handles GET /tg/markets: open markets, most active first.
+func (h *MarketHandler) handleTelegramMarkets(w http.ResponseWriter, r *http.Request) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+	ctx := r.Context()
+
+	contractIDs, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		h.logger.Error("failed to list markets", "error", err)
+		h.renderError(w, r, http.StatusBadGateway, "Failed to fetch markets from factory")
+		return
+	}
+	contractIDs = slices.DeleteFunc(contractIDs, h.isArchived)
+	loaded, err := h.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		h.logger.Warn("failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	states = slices.DeleteFunc(states, func(s service.MarketState) bool { return s.Resolved })
+
+	markets := h.listedMarkets(h.buildMarketViews(ctx, states))
+	slices.SortStableFunc(markets, func(a, b MarketView) int {
+		return cmp.Compare(b.YesSold+b.NoSold, a.YesSold+a.NoSold)
+	})
+
+	h.renderTelegram(w, r, "tg_markets", map[string]any{"Markets": markets})
+}
+
+// handleTelegramMarket handles GET /tg/market/{id}: prices and the trade form, which
+// posts into the regular quote and signing flow.
+func (h *MarketHandler) handleTelegramMarket(w http.ResponseWriter, r *http.Request) {
+	if h.factoryService == nil || !h.factoryService.HasFactory() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Factory contract not configured")
+		return
+	}
+	contractID := r.PathValue("id")
+
+	loaded, err := h.factoryService.GetMarketStates(r.Context(), []string{contractID})
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	states := loaded.States
+	if len(states) == 0 {
+		h.renderError(w, r, http.StatusNotFound, "Market not found")
+		return
+	}
+	market := h.buildMarketViews(r.Context(), states)[0]
+
+	data := map[string]any{
+		"Market":        market,
+		"TelegramAlert": h.telegramAlertsEnabled() && !market.IsResolved,
+	}
+	h.addTradePrefill(r, contractID, data)
+	h.renderTelegram(w, r, "tg_market", data)
+}
+
+// handleTelegramAlert handles POST /tg/market/{id}/alert: a price alert delivered by
+// the bot to the private chat of the user who opened the Mini App.
+func (h *MarketHandler) handleTelegramAlert(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if !h.telegramAlertsEnabled() {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Telegram alerts are not configured")
+		return
+	}
+
+	contractID := r.PathValue("id")
+	user, err := h.telegramApp.Verify(r.FormValue("init_data"))
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	threshold, err := strconv.ParseFloat(r.FormValue("threshold"), 64)
+	if err != nil {
+		h.writeError(w, r, service.ErrInvalidAlertThreshold, "contract_id", contractID)
+		return
+	}
+
+	rule, err := h.alerts.Create(r.Context(), service.CreateAlertRequest{
+		ContractID: contractID,
+		Kind:       service.AlertKind(r.FormValue("kind")),
+		Threshold:  threshold,
+		Channel:    notify.ChannelTelegram,
+		Target:     strconv.FormatInt(user.ID, 10),
+		ClientIP:   clientIP(r),
+	})
+	if err != nil {
+		h.writeError(w, r, err, "contract_id", contractID)
+		return
+	}
+	h.logger.Info("alert created", "alert_id", rule.ID, "contract_id", contractID, "kind", rule.Kind, "channel", rule.Channel, "source", "telegram_app")
+
+	h.renderAlert(w, r, map[string]any{"Rule": rule})
+}
+
+func (h *MarketHandler) renderTelegram(w http.ResponseWriter, r *http.Request, name string, data map[string]any) {
+	data["Network"] = h.networkName()
+	data["AccountID"] = accountIDFromCookie(r)
+	if err := h.tmpl.Render(w, name, data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timezoneCookie holds the IANA time zone (e.g. "Europe/Belgrade") times are shown in.
+// It is set by the footer picker, or detected from the browser on first visit.
+const timezoneCookie = "tz"
+
+// locations caches loaded time zones by name, so pages do not read the zone database
+// on every request. Only names that load are stored, which bounds it by the IANA list.
+var locations sync.Map // string -> *time.Location
+
+// loadLocation is time.LoadLocation with a cache.
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// userLocation returns the time zone chosen by the user, falling back to UTC.
+func userLocation(r *http.Request) *time.Location {
+	c, err := r.Cookie(timezoneCookie)
+	if err != nil || c.Value == "" {
+		return time.UTC
+	}
+	loc, err := loadLocation(c.Value)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// handleSetTimezone stores the user's time zone and sends them back to the page they
+// came from. An empty zone resets to UTC.
+func (h *MarketHandler) handleSetTimezone(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	tz := strings.TrimSpace(r.FormValue("tz"))
+	if tz == "" {
+		tz = "UTC"
+	}
+	// LoadLocation also accepts "Local", the server's zone; only IANA names are wanted.
+	if _, err := loadLocation(tz); err != nil || tz == "Local" {
+		h.renderError(w, r, http.StatusBadRequest, "Unknown time zone")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     timezoneCookie,
+		Value:    tz,
+		Path:     "/",
+		MaxAge:   cookieMaxAge,
+		SameSite: http.SameSiteLaxMode,
+		// Not HttpOnly: the footer script sets it from the browser's zone on first visit.
+	})
+	http.Redirect(w, r, localReturnPath(r.FormValue("return")), http.StatusSeeOther)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/service"
+)
+
+// amountModeCollateral is the amount_mode form value of trades whose amount is the
+// collateral to spend ("spend 50 EURMTL") rather than the tokens to buy. Only buys
+// and quotes accept it; the default, "tokens", is the token amount.
+const amountModeCollateral = "collateral"
+
+// spendsCollateral reports whether a trade form amount is collateral to spend. On an
+// unknown mode it writes the error response and returns ok false.
+func (h *MarketHandler) spendsCollateral(w http.ResponseWriter, r *http.Request) (collateral, ok bool) {
+	switch strings.TrimSpace(r.FormValue("amount_mode")) {
+	case "", "tokens":
+		return false, true
+	case amountModeCollateral:
+		return true, true
+	default:
+		h.renderError(w, r, http.StatusBadRequest, "Invalid amount mode: must be tokens or collateral")
+		return false, false
+	}
+}
+
+// quoteSourceContract is the source form value of quotes shown with the Go LMSR
+// estimate next to the contract's own cost and a warning when the two disagree.
+// Quotes always come from the contract's get_quote; the default, "", skips the
+// estimate and the simulations it needs.
+const quoteSourceContract = "contract"
+
+// comparesQuote reports whether a quote request asked for source=contract. ok is
+// false for an unknown source.
+func comparesQuote(r *http.Request) (compare, ok bool) {
+	switch strings.TrimSpace(r.FormValue("source")) {
+	case "":
+		return false, true
+	case quoteSourceContract:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// quoteEstimate returns the Go LMSR estimate of a contract quote. It is nil when the
+// estimate fails: the contract's quote stands on its own.
+func (h *MarketHandler) quoteEstimate(r *http.Request, contractID string, outcome model.Outcome, amount float64, quote *service.Quote) *service.QuoteEstimate {
+	estimate, err := h.marketService.EstimateQuote(r.Context(), contractID, outcome, amount, quote)
+	if err != nil {
+		h.logger.Warn("failed to estimate quote", "error", err, "contract_id", contractID)
+		return nil
+	}
+	return estimate
+}
+
+// quoteMismatchWarning explains a contract quote that differs from its estimate.
+func quoteMismatchWarning(e *service.QuoteEstimate) string {
+	return fmt.Sprintf("The contract's cost differs from the LMSR estimate (%.7f) by %+.7f. The contract's cost is what the buy is charged; the market may have traded in between.",
+		e.Cost, e.Difference)
+}
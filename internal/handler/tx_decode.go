@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// txDecodeResponse is the JSON body of POST /tx/decode. Fees are in stroops.
+type txDecodeResponse struct {
+	Hash           string                `json:"hash"`
+	Source         string                `json:"source"`
+	FeeSource      string                `json:"fee_source,omitempty"`
+	MaxFee         int64                 `json:"max_fee"`
+	ResourceFee    int64                 `json:"resource_fee,omitempty"`
+	SequenceNumber int64                 `json:"sequence_number,string"`
+	MinTime        *time.Time            `json:"min_time,omitempty"`
+	MaxTime        *time.Time            `json:"max_time,omitempty"`
+	Memo           string                `json:"memo,omitempty"`
+	Signatures     int                   `json:"signatures"`
+	Operations     []txOperationResponse `json:"operations"`
+}
+
+type txOperationResponse struct {
+	Type        string   `json:"type"`
+	Source      string   `json:"source,omitempty"`
+	ContractID  string   `json:"contract_id,omitempty"`
+	Function    string   `json:"function,omitempty"`
+	Args        []string `json:"args,omitempty"`
+	AuthEntries int      `json:"auth_entries,omitempty"`
+}
+
+// handleDecodeTx handles POST /tx/decode: a readable summary of a transaction XDR,
+// signed or not, so users can check what they are signing without leaving the site.
+// The HTML page also takes a pasted XDR; an empty form shows just the input.
+func (h *MarketHandler) handleDecodeTx(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	txXDR := strings.TrimSpace(r.FormValue("xdr"))
+	var summary *soroban.TxSummary
+	if txXDR != "" || wantsJSON(r) {
+		var err error
+		summary, err = soroban.DescribeTx(txXDR, h.networkPassphrase)
+		if err != nil {
+			h.logger.Warn("failed to decode transaction", "error", err)
+			h.renderError(w, r, http.StatusBadRequest, "Invalid transaction XDR")
+			return
+		}
+	}
+
+	if wantsJSON(r) {
+		resp := txDecodeResponse{
+			Hash:           summary.Hash,
+			Source:         summary.Source,
+			FeeSource:      summary.FeeSource,
+			MaxFee:         summary.MaxFee,
+			ResourceFee:    summary.ResourceFee,
+			SequenceNumber: summary.SequenceNumber,
+			MinTime:        summary.MinTime,
+			MaxTime:        summary.MaxTime,
+			Memo:           summary.Memo,
+			Signatures:     summary.Signatures,
+			Operations:     make([]txOperationResponse, len(summary.Operations)),
+		}
+		for i, op := range summary.Operations {
+			resp.Operations[i] = txOperationResponse(op)
+		}
+		h.writeJSON(w, resp)
+		return
+	}
+
+	data := map[string]any{
+		"XDR":       txXDR,
+		"Summary":   summary,
+		"Expired":   summary != nil && summary.MaxTime != nil && time.Now().After(*summary.MaxTime),
+		"TZ":        userLocation(r),
+		"ActiveNav": "markets",
+		"Network":   h.networkName(),
+		"AccountID": accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "tx_decode", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
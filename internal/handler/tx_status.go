@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// txStatusResponse is the JSON body of GET /tx/{hash}.
+type txStatusResponse struct {
+	Hash           string              `json:"hash"`
+	Status         string              `json:"status"` // SUCCESS, FAILED or NOT_FOUND
+	Ledger         uint32              `json:"ledger,omitempty"`
+	CreatedAt      *time.Time          `json:"created_at,omitempty"`
+	FeeCharged     int64               `json:"fee_charged,omitempty"` // stroops
+	ResultCode     string              `json:"result_code,omitempty"`
+	OperationCode  string              `json:"operation_code,omitempty"`
+	ReturnValue    string              `json:"return_value,omitempty"`
+	Trades         []tradeJSON         `json:"trades,omitempty"`
+	BalanceChanges []balanceChangeView `json:"balance_changes,omitempty"`
+
+	// Submit pipeline state while NOT_FOUND, for transactions submitted here.
+	Broadcast *broadcastJSON `json:"broadcast,omitempty"`
+}
+
+// broadcastJSON is the submit pipeline state of a transaction submitted on /tx/submit.
+type broadcastJSON struct {
+	State     string `json:"state"` // retrying, pending, done, rejected or timed_out
+	RPCStatus string `json:"rpc_status"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error,omitempty"`
+}
+
+// balanceChangeView is a token balance change in whole units.
+type balanceChangeView struct {
+	Address string  `json:"address"`
+	Asset   string  `json:"asset,omitempty"` // asset code, e.g. "EURMTL"
+	Token   string  `json:"token"`
+	Amount  float64 `json:"amount"` // positive is received
+}
+
+func balanceChangeViews(changes []soroban.BalanceChange) []balanceChangeView {
+	views := make([]balanceChangeView, len(changes))
+	for i, c := range changes {
+		code, _, _ := strings.Cut(c.Asset, ":")
+		views[i] = balanceChangeView{
+			Address: c.Address,
+			Asset:   code,
+			Token:   c.Token,
+			Amount:  float64(c.Amount) / float64(soroban.ScaleFactor),
+		}
+	}
+	return views
+}
+
+// handleTxStatus handles GET /tx/{hash}: what a submitted transaction did — the
+// tokens bought or sold and the collateral moved — or that it is not known yet, with
+// where it is in the submit pipeline if it was submitted here.
+func (h *MarketHandler) handleTxStatus(w http.ResponseWriter, r *http.Request) {
+	if h.eventService == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Transaction lookup not available")
+		return
+	}
+	hash := strings.ToLower(r.PathValue("hash"))
+
+	status, err := h.eventService.TransactionStatus(r.Context(), hash)
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", hash)
+		return
+	}
+	outcome := status.Outcome
+	if status.Found() && outcome.Source != "" {
+		h.marketService.InvalidateAccount(outcome.Source)
+	}
+	returnValue := ""
+	if outcome.ReturnValue != nil {
+		returnValue = soroban.FormatSCVal(*outcome.ReturnValue)
+	}
+
+	var broadcast *service.Broadcast
+	w.Header().Add("Vary", "Accept")
+	if !status.Found() {
+		// Pending transactions show up within a ledger or two; do not cache the miss.
+		w.Header().Set("Cache-Control", "no-store")
+		if b, ok := h.marketService.Broadcast(hash); ok {
+			broadcast = &b
+		}
+	}
+	if wantsJSON(r) {
+		resp := txStatusResponse{
+			Hash:           status.Hash,
+			Status:         outcome.Status,
+			Ledger:         outcome.Ledger,
+			FeeCharged:     outcome.FeeCharged,
+			ResultCode:     outcome.ResultCode,
+			OperationCode:  outcome.OperationCode,
+			ReturnValue:    returnValue,
+			Trades:         tradesJSON(status.Trades),
+			BalanceChanges: balanceChangeViews(status.BalanceChanges),
+		}
+		if !outcome.CreatedAt.IsZero() {
+			resp.CreatedAt = &outcome.CreatedAt
+		}
+		if broadcast != nil {
+			resp.Broadcast = &broadcastJSON{
+				State:     string(broadcast.State),
+				RPCStatus: broadcast.RPCStatus,
+				Attempts:  broadcast.Attempts,
+				Error:     broadcast.Error,
+			}
+		}
+		h.writeJSON(w, resp)
+		return
+	}
+
+	data := map[string]any{
+		"Status":         status,
+		"Broadcast":      broadcast,
+		"ReturnValue":    returnValue,
+		"BalanceChanges": balanceChangeViews(status.BalanceChanges),
+		"TZ":             userLocation(r),
+		"ActiveNav":      "markets",
+		"Network":        h.networkName(),
+		"AccountID":      accountIDFromCookie(r),
+	}
+	if err := h.tmpl.Render(w, "tx_status", data); err != nil {
+		h.logger.Error("failed to render template", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleTxStatusLookup handles POST /tx/status from the transaction page: the hash of
+// a transaction does not change when it is signed, so the unsigned XDR identifies it.
+func (h *MarketHandler) handleTxStatusLookup(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	hash, err := soroban.TransactionHash(r.FormValue("xdr"), h.networkPassphrase)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("%w: %v", service.ErrInvalidTransaction, err))
+		return
+	}
+	http.Redirect(w, r, "/tx/"+hash, http.StatusSeeOther)
+}
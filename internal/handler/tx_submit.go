@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// txSubmitResponse is the JSON body of POST /tx/submit.
+type txSubmitResponse struct {
+	Hash   string `json:"hash"`
+	Status string `json:"status"` // RPC status, e.g. PENDING, DUPLICATE or TRY_AGAIN_LATER
+	State  string `json:"state"`  // Submit pipeline state: retrying or pending; follow it on /tx/{hash}
+}
+
+// handleSubmitTx handles POST /tx/submit: checks a signed transaction against the
+// pending transaction it was built as, then submits it. Mismatches get a clear error
+// here instead of an opaque failure from the network. A TRY_AGAIN_LATER answer is not
+// an error: the transaction is resubmitted in the background, and both that and the
+// result are followed on /tx/{hash}.
+func (h *MarketHandler) handleSubmitTx(w http.ResponseWriter, r *http.Request) {
+	if h.pendingTxs == nil {
+		h.renderError(w, r, http.StatusServiceUnavailable, "Transaction submission not available")
+		return
+	}
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	signedXDR := strings.TrimSpace(r.FormValue("xdr"))
+	invoke, err := soroban.DecodeInvokeTx(signedXDR)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("%w: %v", service.ErrInvalidTransaction, err))
+		return
+	}
+	hash, err := soroban.TransactionHash(signedXDR, h.networkPassphrase)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("%w: %v", service.ErrInvalidTransaction, err))
+		return
+	}
+	pending, err := h.pendingTxs.FindByHash(invoke.SourceAccount, hash)
+	if errors.Is(err, service.ErrPendingTxNotFound) {
+		err = service.ErrTxNotBuilt
+	}
+	if err == nil {
+		err = service.CheckSignedTx(signedXDR, h.networkPassphrase, pending, time.Now())
+	}
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", hash)
+		return
+	}
+
+	broadcast, err := h.marketService.SubmitTx(r.Context(), signedXDR, hash, invoke.SourceAccount)
+	if err != nil {
+		h.writeError(w, r, err, "tx_hash", hash)
+		return
+	}
+	h.pendingTxs.Remove(pending.Account, pending.ID)
+
+	if wantsJSON(r) {
+		h.writeJSON(w, txSubmitResponse{Hash: hash, Status: broadcast.RPCStatus, State: string(broadcast.State)})
+		return
+	}
+	http.Redirect(w, r, "/tx/"+hash, http.StatusSeeOther)
+}
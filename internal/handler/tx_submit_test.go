@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/service"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/static"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/mtlprog/total/internal/template"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+// fakeSubmitRPC answers simulateTransaction like a protocol 23 RPC, accepts every
+// sendTransaction and never finds the transaction on-chain.
+func fakeSubmitRPC(t *testing.T) *soroban.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch req.Method {
+		case "simulateTransaction":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"transactionData":"AAAAAAAAAAAAAAAAABLWhwAACAAAAAIAAAAAAAABgc0=","minResourceFee":"98765","events":[],"results":[{"auth":[],"xdr":"AAAAAQ=="}],"latestLedger":51234}}`))
+		case "sendTransaction":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"status":"PENDING","latestLedger":51234}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"status":"NOT_FOUND","latestLedger":51234}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return soroban.NewClient(srv.URL, srv.Client())
+}
+
+func TestRefreshedTxCanBeSubmitted(t *testing.T) {
+	horizonSrv := stellartest.NewServer(t)
+	user := keypair.MustRandom()
+	horizonSrv.AddFundedAccount(user.Address(), 7, "100.0000000")
+
+	sorobanClient := fakeSubmitRPC(t)
+	stellarClient := stellar.NewCachingClient(horizonSrv.Client(t))
+	txBuilder := stellar.NewBuilder(stellarClient, stellartest.Passphrase, 100, 0, sorobanClient)
+	quoteSigner, err := service.NewQuoteSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	markets := service.NewMarketService(stellarClient, sorobanClient, txBuilder, quoteSigner, service.TradeLimits{}, keypair.MustRandom().Address(), slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { markets.StopBroadcasts(t.Context()) })
+
+	assets, err := static.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := template.New(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := service.NewPendingTxStore()
+	h := &MarketHandler{
+		marketService:     markets,
+		pendingTxs:        store,
+		tmpl:              tmpl,
+		networkPassphrase: stellartest.Passphrase,
+		logger:            slog.New(slog.DiscardHandler),
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := post("/market/"+contractID+"/claim", url.Values{"user_public_key": {user.Address()}}); rec.Code != http.StatusOK {
+		t.Fatalf("build claim = %d: %s", rec.Code, rec.Body)
+	}
+	built := store.List(user.Address())
+	if len(built) != 1 {
+		t.Fatalf("pending after build = %d entries, want 1", len(built))
+	}
+
+	// Another transaction of the account landed, so the refresh gets a new sequence number.
+	horizonSrv.AddFundedAccount(user.Address(), 8, "100.0000000")
+	if rec := post("/tx/refresh", url.Values{"xdr": {built[0].XDR}, "market_id": {contractID}}); rec.Code != http.StatusOK {
+		t.Fatalf("refresh = %d: %s", rec.Code, rec.Body)
+	}
+	refreshed := store.List(user.Address())
+	if len(refreshed) != 1 || refreshed[0].Hash == built[0].Hash || refreshed[0].Kind != service.PendingTxClaim {
+		t.Fatalf("pending after refresh = %+v, want the rebuilt claim in place of the original", refreshed)
+	}
+
+	parsed, err := txnbuild.TransactionFromXDR(refreshed[0].XDR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := parsed.Transaction()
+	if tx, err = tx.Sign(stellartest.Passphrase, user); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := tx.Base64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := post("/tx/submit", url.Values{"xdr": {signed}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/tx/"+refreshed[0].Hash {
+		t.Fatalf("submit of the refreshed transaction = %d %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	if left := store.List(user.Address()); len(left) != 0 {
+		t.Errorf("pending after submit = %d entries, want none", len(left))
+	}
+}
@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCABundle is returned when the CA bundle file contains no PEM certificates.
+var ErrInvalidCABundle = errors.New("CA bundle contains no valid certificates")
+
+// Default settings, matching the previous hardcoded clients and net/http defaults.
+const (
+	DefaultTimeout             = 30 * time.Second
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+)
+
+// TransportConfig holds connection pool and TLS settings of outbound clients. Pool
+// settings are usually shared; custom TLS trust is set only for backends that need
+// it. Proxies are always taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	CABundle            string // PEM file trusted in addition to the system roots
+	InsecureSkipVerify  bool   // Disables TLS verification; only for internal endpoints
+}
+
+// DefaultTransportConfig returns the default connection pool settings.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        DefaultMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+		KeepAlive:           DefaultKeepAlive,
+	}
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = map[TransportConfig]*http.Transport{}
+)
+
+// Transport returns a tuned transport for cfg. Clients with equal settings share one
+// transport, and therefore one connection pool.
+func Transport(cfg TransportConfig) (*http.Transport, error) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	if t, ok := transports[cfg]; ok {
+		return t, nil
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	t.TLSClientConfig = tlsConfig
+	t.MaxIdleConns = cfg.MaxIdleConns
+	t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	t.IdleConnTimeout = cfg.IdleConnTimeout
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: cfg.KeepAlive,
+	}).DialContext
+
+	transports[cfg] = t
+	return t, nil
+}
+
+// newTLSConfig builds the client TLS configuration for cfg.
+func newTLSConfig(cfg TransportConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CABundle == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCABundle, cfg.CABundle)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// New creates an HTTP client with the given overall request timeout on the shared transport for cfg.
+func New(timeout time.Duration, cfg TransportConfig) (*http.Client, error) {
+	t, err := Transport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: t,
+	}, nil
+}
+
+// Default returns a client with default timeout and transport settings.
+// Used by constructors when no client is injected.
+func Default() *http.Client {
+	// The default config has no CA bundle, so building its transport cannot fail.
+	client, _ := New(DefaultTimeout, DefaultTransportConfig())
+	return client
+}
@@ -0,0 +1,65 @@
+package httpclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransportSharedForEqualConfig(t *testing.T) {
+	cfg := DefaultTransportConfig()
+
+	a, err := Transport(cfg)
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+	b, err := Transport(cfg)
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+	if a != b {
+		t.Error("Transport() returned different transports for equal config")
+	}
+
+	cfg.MaxIdleConnsPerHost++
+	c, err := Transport(cfg)
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+	if a == c {
+		t.Error("Transport() returned the same transport for different config")
+	}
+	if c.Proxy == nil {
+		t.Error("Transport() must honor proxy environment variables")
+	}
+}
+
+func TestTransportCABundle(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		caBundle string
+		wantErr  error
+	}{
+		{"missing file", filepath.Join(dir, "missing.pem"), os.ErrNotExist},
+		{"no certificates", invalid, ErrInvalidCABundle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultTransportConfig()
+			cfg.CABundle = tt.caBundle
+
+			_, err := Transport(cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Transport() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -8,21 +8,40 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/mtlprog/total/internal/cachestats"
 	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/mtlprog/total/internal/model"
 	"github.com/samber/hot"
 )
 
-// ErrInvalidCID is returned when an IPFS CID has invalid format.
-var ErrInvalidCID = errors.New("invalid IPFS CID format")
+var (
+	// ErrInvalidCID is returned when an IPFS CID has invalid format.
+	ErrInvalidCID = errors.New("invalid IPFS CID format")
+	// ErrPinningNotConfigured is returned by PinJSON without Pinata credentials.
+	ErrPinningNotConfigured = errors.New("pinata credentials not configured")
+	// ErrInvalidMetadata is returned by PinJSON for data that is not valid market
+	// metadata. It wraps the decoding or model validation error.
+	ErrInvalidMetadata = errors.New("invalid market metadata")
+	// ErrMetadataTooLarge is returned by PinJSON when the serialized metadata exceeds
+	// MaxMetadataSize.
+	ErrMetadataTooLarge = fmt.Errorf("market metadata exceeds %d bytes", MaxMetadataSize)
+)
 
 // ipfsCIDPattern matches IPFS CIDv0 (Qm...) and CIDv1 (b...) formats.
 var ipfsCIDPattern = regexp.MustCompile(`^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[A-Za-z2-7]{58,})$`)
 
 const (
+	// MaxMetadataSize is the largest serialized metadata PinJSON uploads.
+	MaxMetadataSize = 16 * 1024
+
 	// cacheTTL is the time-to-live for cached IPFS responses.
 	cacheTTL = 5 * time.Minute
 	// cacheSize is the maximum number of entries in the cache.
@@ -49,22 +68,44 @@ func ValidateCID(cid string) error {
 
 // Client provides IPFS operations via Pinata.
 type Client struct {
-	apiKey     string
-	apiSecret  string
-	gatewayURL string
-	httpClient *http.Client
-	cache      *hot.HotCache[string, []byte]
+	apiKey        string
+	apiSecret     string
+	gateways      *gatewaySet
+	httpClient    *http.Client // Pinata API
+	gatewayClient *http.Client // Metadata fetches from gateways
+	cache         *hot.HotCache[string, []byte]
+	cacheStats    cachestats.Counter
+	failures      atomic.Uint64 // fetches that failed after retries
+
+	healthMu sync.Mutex
+	health   GatewayHealth
+}
+
+// GatewayHealth is the outcome of recent IPFS gateway requests, across gateways.
+// It is updated by regular fetches; no extra probes are sent.
+type GatewayHealth struct {
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// Healthy reports whether the most recent gateway request succeeded.
+func (h GatewayHealth) Healthy() bool {
+	return !h.LastSuccess.IsZero() && !h.LastSuccess.Before(h.LastErrorAt)
 }
 
 // NewClient creates a new IPFS client with caching.
-func NewClient(apiKey, apiSecret string) *Client {
+// A nil httpClient uses httpclient.Default().
+func NewClient(apiKey, apiSecret string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
 	c := &Client{
-		apiKey:     apiKey,
-		apiSecret:  apiSecret,
-		gatewayURL: config.DefaultIPFSGateway,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:        apiKey,
+		apiSecret:     apiSecret,
+		gateways:      newGatewaySet([]string{config.DefaultIPFSGateway}),
+		httpClient:    httpClient,
+		gatewayClient: httpClient,
 	}
 
 	// Create cache with TTL and background revalidation.
@@ -79,6 +120,26 @@ func NewClient(apiKey, apiSecret string) *Client {
 	return c
 }
 
+// SetGateways replaces the gateways metadata is fetched from. Each URL is a prefix the
+// CID is appended to, e.g. "https://ipfs.io/ipfs/". Fetches go to the healthy gateway
+// with the lowest latency and fall back to the others. Call it before the client is
+// used.
+func (c *Client) SetGateways(urls ...string) {
+	if len(urls) == 0 {
+		return
+	}
+	c.gateways = newGatewaySet(urls)
+}
+
+// SetGatewayClient sets the HTTP client of gateway fetches, e.g. one trusting the
+// private CA of a self-hosted gateway; Pinata requests keep the client passed to
+// NewClient. Call it before the client is used.
+func (c *Client) SetGatewayClient(httpClient *http.Client) {
+	if httpClient != nil {
+		c.gatewayClient = httpClient
+	}
+}
+
 // loadFromGateway is the cache loader that fetches data from IPFS gateway.
 // Logs warnings for failed fetches but continues processing remaining hashes.
 // Adds delay between requests to avoid rate limiting.
@@ -112,7 +173,12 @@ func (c *Client) loadFromGateway(hashes []string) (map[string][]byte, error) {
 // fetchFromGateway fetches raw JSON bytes from IPFS gateway.
 // Validates CID format to prevent SSRF attacks.
 // Retries with exponential backoff on 429 rate limit errors.
-func (c *Client) fetchFromGateway(ctx context.Context, hash string) ([]byte, error) {
+func (c *Client) fetchFromGateway(ctx context.Context, hash string) (_ []byte, err error) {
+	defer func() {
+		if err != nil {
+			c.failures.Add(1)
+		}
+	}()
 	if err := ValidateCID(hash); err != nil {
 		return nil, fmt.Errorf("invalid IPFS hash %q: %w", hash, err)
 	}
@@ -148,17 +214,48 @@ func (c *Client) fetchFromGateway(ctx context.Context, hash string) ([]byte, err
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
-// doFetch performs a single HTTP request to the IPFS gateway.
+// doFetch tries the gateways in ranked order until one returns the data, recording
+// each request in the gateway stats and the outcome in Health. It returns the last
+// gateway's error, so a fetch is retried as rate limited only when the last fallback
+// was rate limited too.
 func (c *Client) doFetch(ctx context.Context, hash string) ([]byte, error) {
+	var data []byte
+	var err error
+	for _, gateway := range c.gateways.ranked(time.Now()) {
+		start := time.Now()
+		data, err = c.doFetchOnce(ctx, gateway, hash)
+		if ctx.Err() != nil {
+			break // The caller gave up; that says nothing about the gateway
+		}
+		c.gateways.record(gateway, time.Since(start), gatewayFault(err), time.Now())
+		if err == nil {
+			break
+		}
+		slog.Debug("IPFS gateway fetch failed", "gateway", gateway, "hash", hash, "error", err)
+	}
+
+	c.healthMu.Lock()
+	if err != nil {
+		c.health.LastError = err.Error()
+		c.health.LastErrorAt = time.Now()
+	} else {
+		c.health.LastSuccess = time.Now()
+	}
+	c.healthMu.Unlock()
+
+	return data, err
+}
+
+func (c *Client) doFetchOnce(ctx context.Context, gateway, hash string) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", c.gatewayURL+hash, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", gateway+hash, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.gatewayClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch from IPFS: %w", err)
 	}
@@ -186,6 +283,16 @@ func (e *gatewayError) Error() string {
 	return fmt.Sprintf("IPFS error: %s", e.msg)
 }
 
+// gatewayFault returns err unless it is a 404: content missing from a gateway is an
+// answer, not a sign the gateway is unhealthy.
+func gatewayFault(err error) error {
+	var ge *gatewayError
+	if errors.As(err, &ge) && ge.status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
 func isRateLimitError(err error) bool {
 	var ge *gatewayError
 	return errors.As(err, &ge) && ge.status == http.StatusTooManyRequests
@@ -199,15 +306,20 @@ type PinataResponse struct {
 	IsDuplicate bool      `json:"isDuplicate"`
 }
 
-// PinJSON pins JSON data to IPFS via Pinata and returns the hash.
-// Requires Pinata API credentials to be configured.
+// PinJSON pins market metadata to IPFS via Pinata and returns the hash.
+// Requires Pinata API credentials to be configured. data is checked with
+// NormalizeMetadata first, so only metadata the app can render is pinned.
 func (c *Client) PinJSON(ctx context.Context, data any) (string, error) {
+	metadata, err := NormalizeMetadata(data)
+	if err != nil {
+		return "", err
+	}
 	if c.apiKey == "" || c.apiSecret == "" {
-		return "", fmt.Errorf("pinata credentials not configured")
+		return "", ErrPinningNotConfigured
 	}
 
 	jsonData, err := json.Marshal(map[string]any{
-		"pinataContent": data,
+		"pinataContent": metadata,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
@@ -241,35 +353,151 @@ func (c *Client) PinJSON(ctx context.Context, data any) (string, error) {
 	return pinataResp.IpfsHash, nil
 }
 
+// NormalizeMetadata converts data (a model.MarketMetadata or any value with the same
+// JSON shape) into market metadata: unknown fields are dropped, required fields are
+// validated and the serialized size is bounded by MaxMetadataSize.
+func NormalizeMetadata(data any) (model.MarketMetadata, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return model.MarketMetadata{}, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+	var metadata model.MarketMetadata
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return model.MarketMetadata{}, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+	if err := metadata.Validate(); err != nil {
+		return model.MarketMetadata{}, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+
+	normalized, err := json.Marshal(metadata)
+	if err != nil {
+		return model.MarketMetadata{}, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+	if len(normalized) > MaxMetadataSize {
+		return model.MarketMetadata{}, ErrMetadataTooLarge
+	}
+	return metadata, nil
+}
+
 // GetJSON retrieves JSON data from IPFS by hash with caching.
 // On cache miss, fetches from gateway and stores result for future requests.
 func (c *Client) GetJSON(ctx context.Context, hash string, v any) error {
+	data, err := c.GetRaw(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return nil
+}
+
+// GetRaw retrieves the bytes of an IPFS document by hash, exactly as pinned, with
+// the same caching as GetJSON.
+func (c *Client) GetRaw(ctx context.Context, hash string) ([]byte, error) {
 	// Try to get from cache (will trigger loader on miss)
 	data, found, err := c.cache.Get(hash)
 	if err != nil {
-		return fmt.Errorf("cache error: %w", err)
+		return nil, fmt.Errorf("cache error: %w", err)
 	}
 
-	if !found {
+	if found {
+		c.cacheStats.Hit()
+	} else {
+		c.cacheStats.Miss()
 		// Cache miss and loader didn't find it, fetch directly
 		data, err = c.fetchFromGateway(ctx, hash)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// Store in cache for future requests
 		c.cache.Set(hash, data)
 	}
+	return data, nil
+}
 
-	if err := json.Unmarshal(data, v); err != nil {
-		return fmt.Errorf("failed to decode JSON: %w", err)
+// PinFile pins a document to IPFS via Pinata as-is and returns its hash. Pinning
+// the exact bytes of a document fetched with GetRaw, with the CIDv0 that PinJSON
+// produces, restores it under its original hash. data must be JSON no larger than
+// MaxMetadataSize.
+func (c *Client) PinFile(ctx context.Context, name string, data []byte) (string, error) {
+	if !json.Valid(data) {
+		return "", fmt.Errorf("%w: not JSON", ErrInvalidMetadata)
+	}
+	if len(data) > MaxMetadataSize {
+		return "", ErrMetadataTooLarge
+	}
+	if c.apiKey == "" || c.apiSecret == "" {
+		return "", ErrPinningNotConfigured
 	}
 
-	return nil
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create form: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("failed to create form: %w", err)
+	}
+	if err := w.WriteField("pinataOptions", `{"cidVersion":0}`); err != nil {
+		return "", fmt.Errorf("failed to create form: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to create form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.PinataFileAPIURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("pinata_api_key", c.apiKey)
+	req.Header.Set("pinata_secret_api_key", c.apiSecret)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to pin file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("pinata error: %s - %s", resp.Status, string(respBody))
+	}
+
+	var pinataResp PinataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pinataResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return pinataResp.IpfsHash, nil
 }
 
-// GatewayURL returns the IPFS gateway URL.
+// GatewayURL returns the gateway fetches currently go to first.
 func (c *Client) GatewayURL() string {
-	return c.gatewayURL
+	return c.gateways.ranked(time.Now())[0]
+}
+
+// Gateways returns latency and error counts of each gateway, in configured order.
+func (c *Client) Gateways() []GatewayStats {
+	return c.gateways.stats(time.Now())
+}
+
+// Health returns the outcome of recent gateway requests.
+func (c *Client) Health() GatewayHealth {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	return c.health
+}
+
+// CacheStats returns hit and miss counts of the metadata cache.
+func (c *Client) CacheStats() cachestats.Stats {
+	return c.cacheStats.Stats()
+}
+
+// FetchFailures returns the number of gateway fetches that failed since start.
+func (c *Client) FetchFailures() uint64 {
+	return c.failures.Load()
 }
 
 // CanPin returns true if Pinata credentials are configured for writing.
@@ -277,32 +505,15 @@ func (c *Client) CanPin() bool {
 	return c.apiKey != "" && c.apiSecret != ""
 }
 
-// Warmup pre-fetches IPFS data for the given hashes to populate the cache.
-// Runs in background goroutine and returns immediately. Empty hashes are skipped.
-// Adds delay between requests to avoid rate limiting.
-func (c *Client) Warmup(hashes []string) {
-	go func() {
-		ctx := context.Background()
-		var succeeded, failed int
-
-		for i, hash := range hashes {
-			if hash == "" {
-				continue
-			}
-			// Delay between requests to avoid rate limiting
-			if i > 0 {
-				time.Sleep(200 * time.Millisecond)
-			}
-			data, err := c.fetchFromGateway(ctx, hash)
-			if err != nil {
-				slog.Warn("cache warmup fetch failed", "hash", hash, "error", err)
-				failed++
-				continue
-			}
-			c.cache.Set(hash, data)
-			succeeded++
-		}
-
-		slog.Info("cache warmup completed", "succeeded", succeeded, "failed", failed)
-	}()
+// Prefetch fetches the data for hash into the cache unless it is already cached.
+func (c *Client) Prefetch(ctx context.Context, hash string) error {
+	if c.cache.Has(hash) {
+		return nil
+	}
+	data, err := c.fetchFromGateway(ctx, hash)
+	if err != nil {
+		return err
+	}
+	c.cache.Set(hash, data)
+	return nil
 }
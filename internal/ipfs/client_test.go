@@ -0,0 +1,182 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestNormalizeMetadata(t *testing.T) {
+	t.Run("drops unknown fields", func(t *testing.T) {
+		metadata, err := NormalizeMetadata(map[string]any{
+			"question":          "Will it rain?",
+			"resolution_source": "https://www.metoffice.gov.uk",
+			"category":          "weather",
+			"script":            "<script>",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if metadata.Question != "Will it rain?" || metadata.Category != "weather" {
+			t.Errorf("unexpected metadata: %+v", metadata)
+		}
+	})
+
+	t.Run("requires a question", func(t *testing.T) {
+		_, err := NormalizeMetadata(map[string]any{"description": "no question"})
+		if !errors.Is(err, ErrInvalidMetadata) || !errors.Is(err, model.ErrEmptyQuestion) {
+			t.Errorf("err = %v, want ErrInvalidMetadata wrapping ErrEmptyQuestion", err)
+		}
+	})
+
+	t.Run("requires a resolution source", func(t *testing.T) {
+		_, err := NormalizeMetadata(map[string]any{"question": "Will it rain?"})
+		if !errors.Is(err, ErrInvalidMetadata) || !errors.Is(err, model.ErrEmptyResolutionSource) {
+			t.Errorf("err = %v, want ErrInvalidMetadata wrapping ErrEmptyResolutionSource", err)
+		}
+	})
+
+	t.Run("rejects wrong field types", func(t *testing.T) {
+		_, err := NormalizeMetadata(map[string]any{"question": "Q?", "end_date": 42})
+		if !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("err = %v, want ErrInvalidMetadata", err)
+		}
+	})
+
+	t.Run("bounds the serialized size", func(t *testing.T) {
+		// Valid lengths, but escaping "<" as \u003c inflates the JSON sixfold.
+		_, err := NormalizeMetadata(model.MarketMetadata{
+			Question:         "Q?",
+			Description:      strings.Repeat("<", model.MaxDescriptionLength),
+			ResolutionSource: "coinbase.com",
+			Category:         strings.Repeat("<", model.MaxDescriptionLength),
+		})
+		if !errors.Is(err, ErrMetadataTooLarge) {
+			t.Errorf("err = %v, want ErrMetadataTooLarge", err)
+		}
+	})
+}
+
+func TestPinJSONValidatesBeforeUpload(t *testing.T) {
+	c := NewClient("", "", nil)
+	if _, err := c.PinJSON(context.Background(), map[string]any{}); !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("err = %v, want ErrInvalidMetadata", err)
+	}
+	if _, err := c.PinJSON(context.Background(), model.MarketMetadata{Question: "Q?", ResolutionSource: "coinbase.com"}); !errors.Is(err, ErrPinningNotConfigured) {
+		t.Errorf("err = %v, want ErrPinningNotConfigured", err)
+	}
+}
+
+const testCID = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+
+func TestGetJSONFallsBackAndPrefersFasterGateway(t *testing.T) {
+	var brokenCalls atomic.Int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenCalls.Add(1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte(`{"question":"slow"}`))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"question":"fast"}`))
+	}))
+	defer fast.Close()
+
+	c := NewClient("", "", nil)
+	c.SetGateways(broken.URL+"/ipfs/", slow.URL+"/ipfs/", fast.URL+"/ipfs/")
+	ctx := context.Background()
+
+	// Each gateway gets measured: broken fails, slow answers, fast is tried next time.
+	for range 3 {
+		if _, err := c.fetchFromGateway(ctx, testCID); err != nil {
+			t.Fatalf("fetchFromGateway() error = %v", err)
+		}
+	}
+	if got := brokenCalls.Load(); got != gatewayFailureThreshold {
+		t.Errorf("requests to the broken gateway = %d, want %d before it is passed over", got, gatewayFailureThreshold)
+	}
+	if got := c.GatewayURL(); got != fast.URL+"/ipfs/" {
+		t.Errorf("GatewayURL() = %s, want the fast gateway", got)
+	}
+
+	var v struct{ Question string }
+	if err := c.GetJSON(ctx, testCID, &v); err != nil || v.Question != "fast" {
+		t.Errorf("GetJSON() = %+v, %v; want the fast gateway's answer", v, err)
+	}
+
+	stats := c.Gateways()
+	if stats[0].Healthy || stats[0].ErrorRate() != 1 || stats[0].LastError == "" {
+		t.Errorf("broken gateway stats = %+v, want unhealthy with every request failed", stats[0])
+	}
+	if !stats[2].Preferred || stats[2].Latency == 0 || stats[2].Latency >= stats[1].Latency {
+		t.Errorf("gateway stats = %+v, want the fast gateway preferred with the lowest latency", stats)
+	}
+	if c.FetchFailures() != 0 {
+		t.Errorf("FetchFailures() = %d, want 0: every fetch succeeded on a fallback", c.FetchFailures())
+	}
+}
+
+func TestGatewayClientOnlyUsedForGateways(t *testing.T) {
+	gateway := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"question":"private"}`))
+	}))
+	defer gateway.Close()
+
+	// The default client does not trust the test server's certificate; the gateway client does.
+	c := NewClient("", "", nil)
+	c.SetGateways(gateway.URL + "/ipfs/")
+	if _, err := c.fetchFromGateway(context.Background(), testCID); err == nil {
+		t.Fatal("fetchFromGateway() with the default client trusted a self-signed gateway")
+	}
+	c.SetGatewayClient(gateway.Client())
+	if _, err := c.fetchFromGateway(context.Background(), testCID); err != nil {
+		t.Fatalf("fetchFromGateway() with the gateway client error = %v", err)
+	}
+	if c.httpClient == c.gatewayClient {
+		t.Error("SetGatewayClient() replaced the Pinata client")
+	}
+}
+
+func TestMissingContentIsNotAGatewayFault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	c := NewClient("", "", nil)
+	c.SetGateways(srv.URL + "/ipfs/")
+
+	for range gatewayFailureThreshold + 1 {
+		if _, err := c.fetchFromGateway(context.Background(), testCID); err == nil {
+			t.Fatal("fetchFromGateway() of missing content should fail")
+		}
+	}
+	if g := c.Gateways()[0]; !g.Healthy || g.Errors != 0 || g.Requests != gatewayFailureThreshold+1 {
+		t.Errorf("gateway stats = %+v, want healthy with no errors", g)
+	}
+}
+
+func TestGatewaySetRetriesUnhealthyGateway(t *testing.T) {
+	s := newGatewaySet([]string{"https://a/ipfs/", "https://b/ipfs/"})
+	now := time.Now()
+	s.record("https://b/ipfs/", 200*time.Millisecond, nil, now)
+	for range gatewayFailureThreshold {
+		s.record("https://a/ipfs/", 0, errors.New("timeout"), now)
+	}
+	if got := s.ranked(now)[0]; got != "https://b/ipfs/" {
+		t.Errorf("ranked()[0] = %s, want b while a is unhealthy", got)
+	}
+	if got := s.ranked(now.Add(gatewayRetryAfter))[0]; got != "https://a/ipfs/" {
+		t.Errorf("ranked()[0] = %s, want a retried after gatewayRetryAfter", got)
+	}
+}
@@ -0,0 +1,121 @@
+package ipfs
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// gatewayFailureThreshold is how many consecutive failed fetches mark a gateway
+	// unhealthy. Unhealthy gateways are tried only after the healthy ones.
+	gatewayFailureThreshold = 3
+	// gatewayRetryAfter is how long an unhealthy gateway is passed over before fetches
+	// try it first again, if it is the fastest.
+	gatewayRetryAfter = time.Minute
+	// latencyWeight is the weight of the newest fetch in a gateway's average latency.
+	latencyWeight = 0.3
+)
+
+// GatewayStats is the fetch history of one IPFS gateway.
+type GatewayStats struct {
+	URL                 string
+	Preferred           bool          // Fetches go here first
+	Healthy             bool          // Fewer than gatewayFailureThreshold failures in a row, or retrying
+	Latency             time.Duration // Moving average of successful fetches
+	Requests            uint64
+	Errors              uint64
+	ConsecutiveFailures int
+	LastSuccess         time.Time
+	LastError           string
+	LastErrorAt         time.Time
+}
+
+// ErrorRate returns the share of failed requests.
+func (s GatewayStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// gatewaySet keeps per-gateway latency and errors and ranks gateways for fetches: the
+// healthy ones by latency, gateways without measurements first so they get measured,
+// then the unhealthy ones.
+type gatewaySet struct {
+	mu       sync.Mutex
+	gateways []*GatewayStats
+}
+
+func newGatewaySet(urls []string) *gatewaySet {
+	s := &gatewaySet{}
+	for _, u := range urls {
+		s.gateways = append(s.gateways, &GatewayStats{URL: u})
+	}
+	return s
+}
+
+func (s *gatewaySet) healthy(g *GatewayStats, now time.Time) bool {
+	return g.ConsecutiveFailures < gatewayFailureThreshold || now.Sub(g.LastErrorAt) >= gatewayRetryAfter
+}
+
+// ranked returns gateway URLs in the order fetches should try them.
+func (s *gatewaySet) ranked(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := slices.Clone(s.gateways)
+	slices.SortStableFunc(order, func(a, b *GatewayStats) int {
+		if ha, hb := s.healthy(a, now), s.healthy(b, now); ha != hb {
+			if ha {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Latency, b.Latency)
+	})
+	urls := make([]string, len(order))
+	for i, g := range order {
+		urls[i] = g.URL
+	}
+	return urls
+}
+
+// record adds the outcome of one fetch from a gateway.
+func (s *gatewaySet) record(url string, latency time.Duration, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.gateways, func(g *GatewayStats) bool { return g.URL == url })
+	if i < 0 {
+		return
+	}
+	g := s.gateways[i]
+	g.Requests++
+	if err != nil {
+		g.Errors++
+		g.ConsecutiveFailures++
+		g.LastError = err.Error()
+		g.LastErrorAt = now
+		return
+	}
+	g.ConsecutiveFailures = 0
+	g.LastSuccess = now
+	if g.Latency == 0 {
+		g.Latency = latency
+	} else {
+		g.Latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(g.Latency))
+	}
+}
+
+func (s *gatewaySet) stats(now time.Time) []GatewayStats {
+	preferred := s.ranked(now)[0]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]GatewayStats, len(s.gateways))
+	for i, g := range s.gateways {
+		stats[i] = *g
+		stats[i].Healthy = s.healthy(g, now)
+		stats[i].Preferred = g.URL == preferred
+	}
+	return stats
+}
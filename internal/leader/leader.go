@@ -0,0 +1,106 @@
+// Package leader elects one replica to serve the site when several instances share a
+// volume. The others wait as hot standbys, so every state file has a single writer.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RetryInterval is how often a standby tries to take over leadership.
+const RetryInterval = 15 * time.Second
+
+// Lock elects a leader among replicas sharing a file system, by holding an exclusive
+// flock on a lock file. The kernel releases the lock when the process exits, so a
+// crashed leader is replaced by the next standby to retry.
+type Lock struct {
+	path   string
+	logger *slog.Logger
+	retry  time.Duration
+	file   *os.File
+	leader atomic.Bool
+}
+
+// New creates a leadership lock on the file at path, which is created if missing.
+func New(path string, logger *slog.Logger) *Lock {
+	if path == "" {
+		panic("leader.New: path must not be empty")
+	}
+	if logger == nil {
+		panic("leader.New: logger must not be nil")
+	}
+	return &Lock{path: path, logger: logger, retry: RetryInterval}
+}
+
+// IsLeader reports whether this process currently holds the lock.
+func (l *Lock) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Acquire tries to take the lock every RetryInterval until it succeeds or ctx is
+// cancelled. The lock is then held until Release or until the process exits. It
+// returns ctx's error if cancelled first, or an error if the lock file cannot be opened.
+func (l *Lock) Acquire(ctx context.Context) error {
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open leader lock file: %w", err)
+	}
+
+	ticker := time.NewTicker(l.retry)
+	defer ticker.Stop()
+
+	logged := false
+	for {
+		acquired, err := tryLock(f)
+		if err != nil {
+			l.logger.Warn("failed to acquire leader lock", "path", l.path, "error", err)
+		}
+		if acquired {
+			l.file = f
+			l.leader.Store(true)
+			l.logger.Info("acquired leader lock", "path", l.path)
+			return nil
+		}
+		if !logged {
+			l.logger.Info("another replica holds the leader lock, waiting as a standby", "path", l.path, "retry", l.retry)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Release gives up the lock, letting a standby take over. It does nothing if the
+// lock is not held.
+func (l *Lock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+	l.leader.Store(false)
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to release leader lock: %w", err)
+	}
+	return nil
+}
+
+// tryLock takes an exclusive lock on f without blocking.
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockElectsOneLeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	first, second := New(path, logger), New(path, logger)
+	second.retry = 5 * time.Millisecond
+
+	if err := first.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if !first.IsLeader() {
+		t.Fatal("first lock should be leader after Acquire")
+	}
+
+	// flock locks belong to the open file, so a second open in the same process
+	// contends like another replica would.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second Acquire() error = %v, want to wait while the first holds the file", err)
+	}
+	if second.IsLeader() {
+		t.Fatal("second lock should not be leader while the first holds the file")
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if first.IsLeader() {
+		t.Error("leadership should be given up on Release")
+	}
+	if err := second.Acquire(context.Background()); err != nil {
+		t.Fatalf("second Acquire() after Release error = %v", err)
+	}
+	if !second.IsLeader() {
+		t.Error("second lock should take over once the first is released")
+	}
+	second.Release()
+}
+
+func TestStandbyDoesNotWriteState(t *testing.T) {
+	dir := t.TempDir()
+	lockPath, statePath := filepath.Join(dir, "leader.lock"), filepath.Join(dir, "state.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Each instance starts like the server: wait for the lock, then load the state
+	// file and write it back on changes until it stops.
+	type instance struct {
+		lock *Lock
+		stop chan struct{}
+		done chan error
+	}
+	start := func(name string) *instance {
+		in := &instance{lock: New(lockPath, logger), stop: make(chan struct{}), done: make(chan error, 1)}
+		in.lock.retry = 5 * time.Millisecond
+		go func() {
+			if err := in.lock.Acquire(context.Background()); err != nil {
+				in.done <- err
+				return
+			}
+			defer in.lock.Release()
+			loaded, err := os.ReadFile(statePath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				in.done <- err
+				return
+			}
+			in.done <- os.WriteFile(statePath, append(loaded, name...), 0o644)
+			<-in.stop
+		}()
+		return in
+	}
+
+	first := start("a")
+	if err := <-first.done; err != nil {
+		t.Fatalf("first instance error = %v", err)
+	}
+	second := start("b")
+	select {
+	case err := <-second.done:
+		t.Fatalf("second instance ran (error = %v) while the first holds the lock", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(first.stop)
+	if err := <-second.done; err != nil {
+		t.Fatalf("second instance error = %v", err)
+	}
+	close(second.stop)
+	got, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ab" {
+		t.Errorf("state = %q, want the standby to load the leader's writes before its own", got)
+	}
+}
@@ -11,6 +11,8 @@ var (
 	ErrInvalidLiquidity   = errors.New("liquidity parameter must be positive")
 	ErrNegativeQuantities = errors.New("quantities must be non-negative")
 	ErrInsufficientTokens = errors.New("cannot sell more than available")
+	ErrNotFinite          = errors.New("amounts and quantities must be finite numbers")
+	ErrOverflow           = errors.New("result is too large to represent")
 )
 
 // Calculator implements LMSR (Logarithmic Market Scoring Rule) pricing.
@@ -22,35 +24,98 @@ type Calculator struct {
 // New creates a new LMSR calculator with the given liquidity parameter.
 // The b parameter controls market depth: larger b = more liquidity, smaller price impact.
 func New(liquidityParam float64) (*Calculator, error) {
-	if liquidityParam <= 0 {
+	if !(liquidityParam > 0) || math.IsInf(liquidityParam, 1) {
 		return nil, ErrInvalidLiquidity
 	}
 	return &Calculator{b: liquidityParam}, nil
 }
 
-// cost calculates the cost function C(q) = b * ln(exp(qYes/b) + exp(qNo/b))
-func (c *Calculator) cost(qYes, qNo float64) float64 {
-	// Use log-sum-exp trick for numerical stability
-	maxQ := math.Max(qYes, qNo)
-	return c.b * (maxQ/c.b + math.Log(math.Exp((qYes-maxQ)/c.b)+math.Exp((qNo-maxQ)/c.b)))
+// checkQuantities validates the sold quantities of a market.
+func checkQuantities(qYes, qNo float64) error {
+	if !isFinite(qYes) || !isFinite(qNo) {
+		return ErrNotFinite
+	}
+	if qYes < 0 || qNo < 0 {
+		return ErrNegativeQuantities
+	}
+	return nil
+}
+
+// checkAmount validates a token or collateral amount.
+func checkAmount(amount float64) error {
+	if !isFinite(amount) {
+		return ErrNotFinite
+	}
+	if amount <= 0 {
+		return ErrNegativeAmount
+	}
+	return nil
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// softplus returns ln(1 + e^x) without overflow.
+func softplus(x float64) float64 {
+	if x > 0 {
+		return x + math.Log1p(math.Exp(-x))
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+// logSumExp returns ln(e^x + e^y) without overflow.
+func logSumExp(x, y float64) float64 {
+	return math.Max(x, y) + math.Log1p(math.Exp(-math.Abs(x-y)))
+}
+
+// logPrices returns ln P(YES) and ln P(NO). Working in log space keeps the price of
+// the unlikely outcome exact even when it underflows to 0 as a float.
+func (c *Calculator) logPrices(qYes, qNo float64) (lnYes, lnNo float64) {
+	d := (qYes - qNo) / c.b
+	return -softplus(-d), -softplus(d)
+}
+
+// sidePrices returns ln P of outcome and of the other outcome.
+func (c *Calculator) sidePrices(qYes, qNo float64, outcome string) (lnOutcome, lnOther float64, err error) {
+	lnYes, lnNo := c.logPrices(qYes, qNo)
+	switch outcome {
+	case "YES":
+		return lnYes, lnNo, nil
+	case "NO":
+		return lnNo, lnYes, nil
+	default:
+		return 0, 0, ErrInvalidOutcome
+	}
+}
+
+// costDelta returns C(q + amount·outcome) - C(q), with amount negative for a sale.
+// Since C(q) = b·ln(e^(qYes/b) + e^(qNo/b)), the difference is
+// b·ln(p·e^(amount/b) + (1-p)) for the outcome's price p, which needs neither
+// quantities nor costs of the whole market: subtracting two large costs would lose all
+// precision once quantities are many times b.
+func (c *Calculator) costDelta(lnP, lnOther, amount float64) float64 {
+	x := amount / c.b
+	// p·(e^x - 1) is the change of the sum; log1p keeps small trades exact.
+	if arg := math.Exp(lnP) * math.Expm1(x); arg > -0.5 && arg < 1 {
+		return c.b * math.Log1p(arg)
+	}
+	return c.b * logSumExp(lnP+x, lnOther)
 }
 
 // Price calculates current prices for YES and NO outcomes.
 // Returns (priceYes, priceNo) where both sum to 1.
 // Price represents the probability of each outcome.
 func (c *Calculator) Price(qYes, qNo float64) (priceYes, priceNo float64, err error) {
-	if qYes < 0 || qNo < 0 {
-		return 0, 0, ErrNegativeQuantities
+	if err := checkQuantities(qYes, qNo); err != nil {
+		return 0, 0, err
 	}
 
-	// P(yes) = exp(qYes/b) / (exp(qYes/b) + exp(qNo/b))
-	// Direct computation is numerically stable here since we're computing a ratio
-	expYes := math.Exp(qYes / c.b)
-	expNo := math.Exp(qNo / c.b)
-	sum := expYes + expNo
-
-	priceYes = expYes / sum
-	priceNo = expNo / sum
+	// P(yes) = exp(qYes/b) / (exp(qYes/b) + exp(qNo/b)) = 1 / (1 + exp((qNo-qYes)/b)),
+	// which stays finite however far apart the quantities are.
+	d := (qYes - qNo) / c.b
+	priceYes = 1 / (1 + math.Exp(-d))
+	priceNo = 1 / (1 + math.Exp(d))
 
 	return priceYes, priceNo, nil
 }
@@ -58,57 +123,101 @@ func (c *Calculator) Price(qYes, qNo float64) (priceYes, priceNo float64, err er
 // CalculateCost calculates the cost to buy a given amount of outcome tokens.
 // Returns the cost in collateral tokens.
 func (c *Calculator) CalculateCost(qYes, qNo, amount float64, outcome string) (float64, error) {
-	if amount <= 0 {
-		return 0, ErrNegativeAmount
+	if err := checkAmount(amount); err != nil {
+		return 0, err
 	}
-	if qYes < 0 || qNo < 0 {
-		return 0, ErrNegativeQuantities
+	if err := checkQuantities(qYes, qNo); err != nil {
+		return 0, err
 	}
 
-	costBefore := c.cost(qYes, qNo)
-	var costAfter float64
-
-	switch outcome {
-	case "YES":
-		costAfter = c.cost(qYes+amount, qNo)
-	case "NO":
-		costAfter = c.cost(qYes, qNo+amount)
-	default:
-		return 0, ErrInvalidOutcome
+	lnP, lnOther, err := c.sidePrices(qYes, qNo, outcome)
+	if err != nil {
+		return 0, err
 	}
-
-	return costAfter - costBefore, nil
+	cost := c.costDelta(lnP, lnOther, amount)
+	if !isFinite(cost) {
+		return 0, ErrOverflow
+	}
+	// Each token costs at most 1; rounding must not say otherwise.
+	return math.Min(math.Max(cost, 0), amount), nil
 }
 
 // CalculateSellReturn calculates the return from selling outcome tokens.
 // Returns the amount of collateral received.
 func (c *Calculator) CalculateSellReturn(qYes, qNo, amount float64, outcome string) (float64, error) {
-	if amount <= 0 {
-		return 0, ErrNegativeAmount
+	if err := checkAmount(amount); err != nil {
+		return 0, err
 	}
-	if qYes < 0 || qNo < 0 {
-		return 0, ErrNegativeQuantities
+	if err := checkQuantities(qYes, qNo); err != nil {
+		return 0, err
 	}
 
-	costBefore := c.cost(qYes, qNo)
-	var costAfter float64
+	lnP, lnOther, err := c.sidePrices(qYes, qNo, outcome)
+	if err != nil {
+		return 0, err
+	}
+	held := qYes
+	if outcome == "NO" {
+		held = qNo
+	}
+	if held < amount {
+		return 0, ErrInsufficientTokens
+	}
 
-	switch outcome {
-	case "YES":
-		if qYes < amount {
-			return 0, ErrInsufficientTokens
+	ret := -c.costDelta(lnP, lnOther, -amount)
+	if !isFinite(ret) {
+		return 0, ErrOverflow
+	}
+	// Each token returns at most 1; rounding must not say otherwise.
+	return math.Min(math.Max(ret, 0), amount), nil
+}
+
+// sharesForCostIterations bounds the bisection in SharesForCost. Each step halves the
+// search interval, so 100 steps are far beyond float64 precision for any market.
+const sharesForCostIterations = 100
+
+// SharesForCost calculates how many outcome tokens a given amount of collateral buys:
+// the inverse of CalculateCost, found by bisection since the cost is increasing in the
+// amount. The result is rounded towards fewer tokens, so their cost never exceeds budget.
+func (c *Calculator) SharesForCost(qYes, qNo, budget float64, outcome string) (float64, error) {
+	if err := checkAmount(budget); err != nil {
+		return 0, err
+	}
+	if err := checkQuantities(qYes, qNo); err != nil {
+		return 0, err
+	}
+
+	lnP, _, err := c.sidePrices(qYes, qNo, outcome)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every token costs between the current price and 1, so budget tokens are always
+	// affordable. Solving b·ln(1 + p·(e^(x/b) - 1)) = budget gives the exact amount
+	// b·ln(1 + (e^(budget/b) - 1)/p); twice that brackets the answer from above even
+	// when the price underflows.
+	z := budget / c.b
+	lnExpm1 := z + math.Log(-math.Expm1(-z)) // ln(e^z - 1)
+	lo, hi := budget, 2*c.b*softplus(lnExpm1-lnP)
+	if !isFinite(hi) {
+		return 0, ErrOverflow
+	}
+	for range sharesForCostIterations {
+		mid := lo + (hi-lo)/2
+		if mid <= lo || mid >= hi {
+			break
 		}
-		costAfter = c.cost(qYes-amount, qNo)
-	case "NO":
-		if qNo < amount {
-			return 0, ErrInsufficientTokens
+		cost, err := c.CalculateCost(qYes, qNo, mid, outcome)
+		if err != nil {
+			return 0, err
+		}
+		if cost > budget {
+			hi = mid
+		} else {
+			lo = mid
 		}
-		costAfter = c.cost(qYes, qNo-amount)
-	default:
-		return 0, ErrInvalidOutcome
 	}
-
-	return costBefore - costAfter, nil
+	return lo, nil
 }
 
 // InitialLiquidity calculates the initial funding required for a binary market.
@@ -134,6 +243,9 @@ func (c *Calculator) Quote(qYes, qNo, amount float64, outcome string) (cost, pri
 	case "NO":
 		newQYes, newQNo = qYes, qNo+amount
 	}
+	if !isFinite(newQYes) || !isFinite(newQNo) {
+		return 0, 0, 0, ErrOverflow
+	}
 
 	newPriceYes, _, err := c.Price(newQYes, newQNo)
 	if err != nil {
@@ -1,8 +1,12 @@
 package lmsr
 
 import (
+	"errors"
 	"math"
+	"math/rand"
+	"reflect"
 	"testing"
+	"testing/quick"
 )
 
 func TestNew(t *testing.T) {
@@ -509,3 +513,367 @@ func TestAsymmetricMarketState(t *testing.T) {
 		})
 	}
 }
+
+func TestSharesForCost(t *testing.T) {
+	calc, _ := New(100)
+
+	tests := []struct {
+		name    string
+		qYes    float64
+		qNo     float64
+		budget  float64
+		outcome string
+	}{
+		{"small budget at equilibrium", 0, 0, 1, "YES"},
+		{"large budget at equilibrium", 0, 0, 500, "NO"},
+		{"cheap side of skewed market", 200, 0, 10, "NO"},
+		{"expensive side of skewed market", 200, 0, 10, "YES"},
+		{"tiny budget", 50, 50, 0.0001, "YES"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := calc.SharesForCost(tt.qYes, tt.qNo, tt.budget, tt.outcome)
+			if err != nil {
+				t.Fatalf("SharesForCost() error = %v", err)
+			}
+			cost, err := calc.CalculateCost(tt.qYes, tt.qNo, shares, tt.outcome)
+			if err != nil {
+				t.Fatalf("CalculateCost() error = %v", err)
+			}
+			if cost > tt.budget {
+				t.Errorf("cost of %v tokens = %v, exceeds budget %v", shares, cost, tt.budget)
+			}
+			if math.Abs(cost-tt.budget) > tt.budget*1e-9 {
+				t.Errorf("cost of %v tokens = %v, want %v", shares, cost, tt.budget)
+			}
+		})
+	}
+}
+
+func TestSharesForCostErrors(t *testing.T) {
+	calc, _ := New(100)
+
+	if _, err := calc.SharesForCost(0, 0, 0, "YES"); err != ErrNegativeAmount {
+		t.Errorf("zero budget: error = %v, want %v", err, ErrNegativeAmount)
+	}
+	if _, err := calc.SharesForCost(-1, 0, 10, "YES"); err != ErrNegativeQuantities {
+		t.Errorf("negative quantities: error = %v, want %v", err, ErrNegativeQuantities)
+	}
+	if _, err := calc.SharesForCost(0, 0, 10, "MAYBE"); err != ErrInvalidOutcome {
+		t.Errorf("invalid outcome: error = %v, want %v", err, ErrInvalidOutcome)
+	}
+}
+
+func TestHardeningRejectsNonFinite(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	for _, b := range []float64{nan, inf, -inf} {
+		if _, err := New(b); err != ErrInvalidLiquidity {
+			t.Errorf("New(%v) error = %v, want %v", b, err, ErrInvalidLiquidity)
+		}
+	}
+
+	calc, _ := New(100)
+	tests := []struct {
+		name             string
+		qYes, qNo, value float64
+	}{
+		{"NaN amount", 0, 0, nan},
+		{"Inf amount", 0, 0, inf},
+		{"NaN quantity", nan, 0, 10},
+		{"Inf quantity", 0, inf, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := calc.CalculateCost(tt.qYes, tt.qNo, tt.value, "YES"); err != ErrNotFinite {
+				t.Errorf("CalculateCost() error = %v, want %v", err, ErrNotFinite)
+			}
+			if _, err := calc.CalculateSellReturn(tt.qYes+20, tt.qNo, tt.value, "YES"); err != ErrNotFinite {
+				t.Errorf("CalculateSellReturn() error = %v, want %v", err, ErrNotFinite)
+			}
+			if _, err := calc.SharesForCost(tt.qYes, tt.qNo, tt.value, "YES"); err != ErrNotFinite {
+				t.Errorf("SharesForCost() error = %v, want %v", err, ErrNotFinite)
+			}
+		})
+	}
+	if _, _, err := calc.Price(nan, 0); err != ErrNotFinite {
+		t.Errorf("Price(NaN) error = %v, want %v", err, ErrNotFinite)
+	}
+
+	tiny, _ := New(math.SmallestNonzeroFloat64)
+	if _, err := tiny.CalculateCost(0, 0, 1, "YES"); err != ErrOverflow {
+		t.Errorf("CalculateCost() with subnormal b error = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestExtremeMarketStates(t *testing.T) {
+	tests := []struct {
+		name      string
+		b         float64
+		qYes, qNo float64
+		amount    float64
+		outcome   string
+		wantCost  float64
+	}{
+		// Quantities 10^4·b apart: the cheap side costs e^-10000 per token, which
+		// underflows, and the expensive side costs 1 per token.
+		{"cheap side far out", 1, 10000, 0, 5, "NO", 0},
+		{"expensive side far out", 1, 10000, 0, 5, "YES", 5},
+		// Quantities 10^12 with a modest trade: costs of the whole market are ~10^12, so
+		// their difference kept at most 4 significant digits.
+		{"huge quantities", 100, 1e12, 1e12, 1, "YES", 100 * math.Log((1+math.Exp(0.01))/2)},
+		{"trade far beyond b", 0.01, 0, 0, 1e6, "YES", 1e6 - 0.01*math.Log(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc, _ := New(tt.b)
+			cost, err := calc.CalculateCost(tt.qYes, tt.qNo, tt.amount, tt.outcome)
+			if err != nil {
+				t.Fatalf("CalculateCost() error = %v", err)
+			}
+			if math.Abs(cost-tt.wantCost) > 1e-9*math.Max(1, tt.wantCost) {
+				t.Errorf("CalculateCost() = %v, want %v", cost, tt.wantCost)
+			}
+			priceYes, priceNo, err := calc.Price(tt.qYes, tt.qNo)
+			if err != nil || math.IsNaN(priceYes) || math.IsNaN(priceNo) {
+				t.Errorf("Price() = %v, %v, %v; want finite prices", priceYes, priceNo, err)
+			}
+			shares, err := calc.SharesForCost(tt.qYes, tt.qNo, 1, tt.outcome)
+			if err != nil || !isFinite(shares) || shares < 1 {
+				t.Errorf("SharesForCost() = %v, %v; want at least 1 token for 1 unit", shares, err)
+			}
+		})
+	}
+}
+
+// market is a random market state and trade for property tests. Magnitudes are
+// log-uniform so tiny and huge liquidity parameters and quantities are both covered.
+type market struct {
+	B, QYes, QNo, Amount float64
+	Outcome              string
+}
+
+func logUniform(r *rand.Rand, lo, hi float64) float64 {
+	return math.Exp(math.Log(lo) + r.Float64()*(math.Log(hi)-math.Log(lo)))
+}
+
+func (market) Generate(r *rand.Rand, _ int) reflect.Value {
+	b := logUniform(r, 1e-3, 1e7)
+	m := market{
+		B:       b,
+		QYes:    b * logUniform(r, 1e-6, 1e3),
+		QNo:     b * logUniform(r, 1e-6, 1e3),
+		Amount:  b * logUniform(r, 1e-6, 1e2),
+		Outcome: "YES",
+	}
+	if r.Intn(4) == 0 {
+		m.QNo = 0
+	}
+	if r.Intn(2) == 0 {
+		m.Outcome = "NO"
+	}
+	return reflect.ValueOf(m)
+}
+
+func (m market) held() float64 {
+	if m.Outcome == "YES" {
+		return m.QYes
+	}
+	return m.QNo
+}
+
+// after returns the quantities after buying amount tokens of the outcome.
+func (m market) after(amount float64) (qYes, qNo float64) {
+	if m.Outcome == "YES" {
+		return m.QYes + amount, m.QNo
+	}
+	return m.QYes, m.QNo + amount
+}
+
+// near reports whether got is within a relative tolerance of want, or within an
+// absolute one of b for values near 0.
+func near(got, want, b float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Abs(want)+1e-12*b
+}
+
+var propertyConfig = &quick.Config{MaxCount: 5000, Rand: rand.New(rand.NewSource(1))}
+
+func TestPropertyPricesSumToOne(t *testing.T) {
+	property := func(m market) bool {
+		calc, _ := New(m.B)
+		priceYes, priceNo, err := calc.Price(m.QYes, m.QNo)
+		if err != nil {
+			return false
+		}
+		return priceYes >= 0 && priceYes <= 1 && priceNo >= 0 && priceNo <= 1 &&
+			math.Abs(priceYes+priceNo-1) <= 1e-12
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyCostMonotonic(t *testing.T) {
+	property := func(m market) bool {
+		calc, _ := New(m.B)
+		priceYes, priceNo, _ := calc.Price(m.QYes, m.QNo)
+		price := priceYes
+		if m.Outcome == "NO" {
+			price = priceNo
+		}
+		cost, err := calc.CalculateCost(m.QYes, m.QNo, m.Amount, m.Outcome)
+		if err != nil {
+			return false
+		}
+		more, err := calc.CalculateCost(m.QYes, m.QNo, 2*m.Amount, m.Outcome)
+		if err != nil {
+			return false
+		}
+		// Buying more costs more; each token costs between the current price and 1.
+		return more >= cost && cost <= m.Amount && cost >= price*m.Amount*(1-1e-9)
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyCostPathIndependent(t *testing.T) {
+	property := func(m market) bool {
+		calc, _ := New(m.B)
+		whole, err := calc.CalculateCost(m.QYes, m.QNo, m.Amount, m.Outcome)
+		if err != nil {
+			return false
+		}
+		half := m.Amount / 2
+		first, err := calc.CalculateCost(m.QYes, m.QNo, half, m.Outcome)
+		if err != nil {
+			return false
+		}
+		qYes, qNo := m.after(half)
+		second, err := calc.CalculateCost(qYes, qNo, m.Amount-half, m.Outcome)
+		if err != nil {
+			return false
+		}
+		return near(first+second, whole, m.B)
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyBuySellInverse(t *testing.T) {
+	property := func(m market) bool {
+		calc, _ := New(m.B)
+		cost, err := calc.CalculateCost(m.QYes, m.QNo, m.Amount, m.Outcome)
+		if err != nil {
+			return false
+		}
+		qYes, qNo := m.after(m.Amount)
+		ret, err := calc.CalculateSellReturn(qYes, qNo, m.Amount, m.Outcome)
+		if err != nil {
+			return false
+		}
+		// Selling right back returns the cost, never more beyond rounding.
+		if !near(ret, cost, m.B) {
+			return false
+		}
+		// Selling what is held first returns at most what buying it back costs.
+		sellable := math.Min(m.Amount, m.held())
+		if sellable <= 0 {
+			return true
+		}
+		sold, err := calc.CalculateSellReturn(m.QYes, m.QNo, sellable, m.Outcome)
+		if err != nil {
+			return false
+		}
+		return sold >= 0 && sold <= sellable
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertySharesForCostWithinBudget(t *testing.T) {
+	property := func(m market) bool {
+		calc, _ := New(m.B)
+		budget := m.Amount
+		shares, err := calc.SharesForCost(m.QYes, m.QNo, budget, m.Outcome)
+		if err != nil || shares < budget {
+			return false
+		}
+		cost, err := calc.CalculateCost(m.QYes, m.QNo, shares, m.Outcome)
+		return err == nil && cost <= budget && near(cost, budget, m.B)
+	}
+	if err := quick.Check(property, propertyConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+// FuzzCalculator checks that any input yields either a typed error or finite, bounded
+// results: never NaN or Inf reaching a quote or a transaction amount.
+func FuzzCalculator(f *testing.F) {
+	f.Add(100.0, 0.0, 0.0, 10.0, true)
+	f.Add(0.001, 1e6, 0.0, 1.0, false)
+	f.Add(1e9, 1e15, 1e15, 1e-7, true)
+	f.Add(1e-300, 1.0, 0.0, 1e300, false)
+	f.Add(math.MaxFloat64, math.MaxFloat64, 0.0, math.MaxFloat64, true)
+
+	known := []error{ErrInvalidLiquidity, ErrNegativeAmount, ErrNegativeQuantities, ErrInsufficientTokens, ErrNotFinite, ErrOverflow}
+	typed := func(err error) bool {
+		for _, e := range known {
+			if errors.Is(err, e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	f.Fuzz(func(t *testing.T, b, qYes, qNo, amount float64, yes bool) {
+		calc, err := New(b)
+		if err != nil {
+			if !typed(err) {
+				t.Fatalf("New(%v) untyped error %v", b, err)
+			}
+			return
+		}
+		outcome := "NO"
+		if yes {
+			outcome = "YES"
+		}
+
+		if priceYes, priceNo, err := calc.Price(qYes, qNo); err != nil {
+			if !typed(err) {
+				t.Fatalf("Price() untyped error %v", err)
+			}
+		} else if !(priceYes >= 0 && priceYes <= 1 && priceNo >= 0 && priceNo <= 1) {
+			t.Fatalf("Price(%v, %v) = %v, %v with b=%v", qYes, qNo, priceYes, priceNo, b)
+		}
+
+		check := func(name string, v float64, err error, max float64) {
+			t.Helper()
+			if err != nil {
+				if !typed(err) {
+					t.Fatalf("%s untyped error %v", name, err)
+				}
+				return
+			}
+			if !isFinite(v) || v < 0 || v > max {
+				t.Fatalf("%s(b=%v, q=%v/%v, amount=%v) = %v, want a finite value in [0, %v]", name, b, qYes, qNo, amount, v, max)
+			}
+		}
+		cost, err := calc.CalculateCost(qYes, qNo, amount, outcome)
+		check("CalculateCost", cost, err, amount)
+		ret, err := calc.CalculateSellReturn(qYes, qNo, amount, outcome)
+		check("CalculateSellReturn", ret, err, amount)
+		shares, err := calc.SharesForCost(qYes, qNo, amount, outcome)
+		check("SharesForCost", shares, err, math.MaxFloat64)
+
+		if c, perShare, prob, err := calc.Quote(qYes, qNo, amount, outcome); err != nil {
+			if !typed(err) {
+				t.Fatalf("Quote() untyped error %v", err)
+			}
+		} else if !isFinite(c) || !isFinite(perShare) || !(prob >= 0 && prob <= 1) {
+			t.Fatalf("Quote() = %v, %v, %v", c, perShare, prob)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package lmsr
+
+import (
+	"errors"
+	"math"
+)
+
+// Scalar markets trade LONG and SHORT tokens over a numeric range [min, max], priced
+// by the binary LMSR with LONG as YES and SHORT as NO. When the market resolves to a
+// value, a LONG token pays the value's position in the range and a SHORT token the rest,
+// so the LONG price is the market's expected position of the value.
+
+// ErrInvalidRange is returned for a scalar range that is empty or not finite.
+var ErrInvalidRange = errors.New("scalar range must have a finite min below max")
+
+func checkRange(min, max float64) error {
+	if math.IsNaN(min) || math.IsNaN(max) || math.IsInf(min, 0) || math.IsInf(max, 0) || min >= max {
+		return ErrInvalidRange
+	}
+	return nil
+}
+
+// ScalarPayout returns what one LONG and one SHORT token pay when the market resolves
+// to value. Values outside the range pay as the nearest bound.
+func ScalarPayout(value, min, max float64) (long, short float64, err error) {
+	if err := checkRange(min, max); err != nil {
+		return 0, 0, err
+	}
+	long = math.Max(0, math.Min(1, (value-min)/(max-min)))
+	return long, 1 - long, nil
+}
+
+// ImpliedValue returns the value the market expects, from the LONG (YES) price.
+func ImpliedValue(priceLong, min, max float64) (float64, error) {
+	if err := checkRange(min, max); err != nil {
+		return 0, err
+	}
+	return min + math.Max(0, math.Min(1, priceLong))*(max-min), nil
+}
+
+// PositionPayout returns what a position of long and short tokens pays when the market
+// resolves to value.
+func PositionPayout(long, short, value, min, max float64) (float64, error) {
+	if long < 0 || short < 0 {
+		return 0, ErrNegativeQuantities
+	}
+	longPays, shortPays, err := ScalarPayout(value, min, max)
+	if err != nil {
+		return 0, err
+	}
+	return long*longPays + short*shortPays, nil
+}
@@ -0,0 +1,70 @@
+package lmsr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestScalarPayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     float64
+		wantLong  float64
+		wantShort float64
+	}{
+		{"at min", 0.1, 0, 1},
+		{"at max", 0.5, 1, 0},
+		{"quarter", 0.2, 0.25, 0.75},
+		{"below range", -3, 0, 1},
+		{"above range", 9, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			long, short, err := ScalarPayout(tt.value, 0.1, 0.5)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(long-tt.wantLong) > 1e-9 || math.Abs(short-tt.wantShort) > 1e-9 {
+				t.Errorf("ScalarPayout(%v) = %v, %v, want %v, %v", tt.value, long, short, tt.wantLong, tt.wantShort)
+			}
+		})
+	}
+
+	for _, r := range [][2]float64{{1, 1}, {2, 1}, {math.NaN(), 1}, {0, math.Inf(1)}} {
+		if _, _, err := ScalarPayout(0, r[0], r[1]); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("ScalarPayout(range %v) error = %v, want ErrInvalidRange", r, err)
+		}
+	}
+}
+
+func TestImpliedValue(t *testing.T) {
+	v, err := ImpliedValue(0.25, 100, 200)
+	if err != nil || v != 125 {
+		t.Errorf("ImpliedValue(0.25) = %v, %v, want 125", v, err)
+	}
+
+	// The implied value of an even market is the middle of the range, and buying LONG
+	// moves it up.
+	calc, _ := New(100)
+	before, _, _ := calc.Price(0, 0)
+	after, _, _ := calc.Price(50, 0)
+	mid, _ := ImpliedValue(before, 100, 200)
+	up, _ := ImpliedValue(after, 100, 200)
+	if mid != 150 || up <= mid {
+		t.Errorf("implied values = %v then %v, want 150 then higher", mid, up)
+	}
+}
+
+func TestPositionPayout(t *testing.T) {
+	got, err := PositionPayout(10, 4, 175, 100, 200)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 10*0.75 + 4*0.25; math.Abs(got-want) > 1e-9 {
+		t.Errorf("PositionPayout() = %v, want %v", got, want)
+	}
+	if _, err := PositionPayout(-1, 0, 150, 100, 200); !errors.Is(err, ErrNegativeQuantities) {
+		t.Errorf("PositionPayout(negative) error = %v, want ErrNegativeQuantities", err)
+	}
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentErrorsSize is how many error records RecentErrors keeps.
+const recentErrorsSize = 50
+
+// ErrorRecord is an error-level log record kept for the admin dashboard.
+type ErrorRecord struct {
+	Time    time.Time
+	Message string
+	Attrs   string // "key=value" pairs, space separated
+}
+
+// errorRing is a fixed-size ring buffer of the most recent error records.
+type errorRing struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+	next    int
+}
+
+var recentErrors errorRing
+
+func (r *errorRing) add(rec ErrorRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < recentErrorsSize {
+		r.records = append(r.records, rec)
+		return
+	}
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % recentErrorsSize
+}
+
+// snapshot returns the records newest first.
+func (r *errorRing) snapshot() []ErrorRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]ErrorRecord, 0, len(r.records))
+	for i := range len(r.records) {
+		idx := (r.next - 1 - i + 2*len(r.records)) % len(r.records)
+		out = append(out, r.records[idx])
+	}
+	return out
+}
+
+// RecentErrors returns the most recent error-level log records, newest first.
+func RecentErrors() []ErrorRecord {
+	return recentErrors.snapshot()
+}
+
+// errorRecorder copies error-level records into recentErrors before passing them on.
+type errorRecorder struct {
+	next  slog.Handler
+	attrs []slog.Attr
+}
+
+func newErrorRecorder(next slog.Handler) slog.Handler {
+	return &errorRecorder{next: next}
+}
+
+func (h *errorRecorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *errorRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		var b strings.Builder
+		write := func(a slog.Attr) bool {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(a.Key + "=" + a.Value.String())
+			return true
+		}
+		for _, a := range h.attrs {
+			write(a)
+		}
+		r.Attrs(write)
+		recentErrors.add(ErrorRecord{Time: r.Time, Message: r.Message, Attrs: b.String()})
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *errorRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorRecorder{
+		next:  h.next.WithAttrs(attrs),
+		attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+func (h *errorRecorder) WithGroup(name string) slog.Handler {
+	return &errorRecorder{next: h.next.WithGroup(name), attrs: h.attrs}
+}
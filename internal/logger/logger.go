@@ -1,30 +1,160 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 )
 
-func Setup(level slog.Level) {
-	opts := &slog.HandlerOptions{
-		Level: level,
-	}
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+var (
+	// level is shared by all handlers created by Setup so it can be changed at runtime.
+	level slog.LevelVar
+
+	mu        sync.Mutex
+	baseLevel slog.Level // level configured at startup, restored by ToggleDebug
+	access    = slog.New(slog.DiscardHandler)
+	closers   []io.Closer
+)
+
+// Access log destinations besides a file path.
+const (
+	AccessLogDefault = ""    // Application log (stdout)
+	AccessLogOff     = "off" // Disabled
+	AccessLogStdout  = "stdout"
+	AccessLogStderr  = "stderr"
+)
+
+// Options configures application and access logging.
+type Options struct {
+	Level slog.Level
+
+	// AccessLog is AccessLogDefault, AccessLogOff, AccessLogStdout, AccessLogStderr or a file path.
+	AccessLog           string
+	AccessLogMaxSize    int64 // Bytes before a file is rotated; <= 0 disables rotation
+	AccessLogMaxBackups int   // Rotated files to keep
+
+	// DebugSampleRate keeps 1 of every N debug records per message; <= 1 keeps all.
+	DebugSampleRate int
+}
+
+// Setup configures the default (application) logger and the access logger.
+func Setup(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	baseLevel = opts.Level
+	level.Set(opts.Level)
+
+	handler := newErrorRecorder(newSamplingHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: &level,
+	}), opts.DebugSampleRate))
 	slog.SetDefault(slog.New(handler))
+
+	accessLogger, closer, err := newAccessLogger(opts)
+	if err != nil {
+		return err
+	}
+	access = accessLogger
+	if closer != nil {
+		closers = append(closers, closer)
+	}
+	return nil
+}
+
+// newAccessLogger creates the access logger for opts.AccessLog.
+// Access records are always logged at info level, independent of the application level.
+func newAccessLogger(opts Options) (*slog.Logger, io.Closer, error) {
+	var w io.Writer
+	var closer io.Closer
+	switch strings.ToLower(opts.AccessLog) {
+	case AccessLogDefault:
+		return slog.Default().With("log", "access"), nil, nil
+	case AccessLogOff:
+		return slog.New(slog.DiscardHandler), nil, nil
+	case AccessLogStdout:
+		w = os.Stdout
+	case AccessLogStderr:
+		w = os.Stderr
+	default:
+		f, err := NewRotatingFile(opts.AccessLog, opts.AccessLogMaxSize, opts.AccessLogMaxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		w, closer = f, f
+	}
+	return slog.New(slog.NewJSONHandler(w, nil)), closer, nil
+}
+
+// Access returns the HTTP access logger configured by Setup.
+func Access() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return access
+}
+
+// Close closes log files opened by Setup.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var errs []error
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	closers = nil
+	access = slog.New(slog.DiscardHandler)
+	return errors.Join(errs...)
+}
+
+// Level returns the current log level.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the log level of the default logger at runtime.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// ToggleDebug switches between debug and the startup level and returns the new level.
+func ToggleDebug() slog.Level {
+	mu.Lock()
+	defer mu.Unlock()
+
+	next := slog.LevelDebug
+	if level.Level() == slog.LevelDebug && baseLevel != slog.LevelDebug {
+		next = baseLevel
+	}
+	level.Set(next)
+	return next
 }
 
 func ParseLevel(s string) slog.Level {
-	switch strings.ToLower(s) {
+	l, err := ParseLevelStrict(s)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// ParseLevelStrict is like ParseLevel but rejects unknown level names.
+func ParseLevelStrict(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
-		return slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		return slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn", "warning":
-		return slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		return slog.LevelError
+		return slog.LevelError, nil
 	default:
-		return slog.LevelInfo
+		return 0, fmt.Errorf("unknown log level %q: must be debug, info, warn or error", s)
 	}
 }
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevelStrict(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{" warning ", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseLevelStrict(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevelStrict(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLevelStrict(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := newSamplingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), 3)
+	log := slog.New(h).With("component", "test")
+
+	for range 9 {
+		log.Debug("noisy")
+		log.Info("important")
+	}
+
+	if got := strings.Count(buf.String(), "msg=noisy"); got != 3 {
+		t.Errorf("debug records kept = %d, want 3", got)
+	}
+	if got := strings.Count(buf.String(), "msg=important"); got != 9 {
+		t.Errorf("info records kept = %d, want 9", got)
+	}
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("sampling handler must not change Enabled")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), got, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", filepath.Base(path))
+	}
+}
+
+func TestErrorRecorder(t *testing.T) {
+	recentErrors = errorRing{}
+	t.Cleanup(func() { recentErrors = errorRing{} })
+
+	log := slog.New(newErrorRecorder(slog.NewTextHandler(io.Discard, nil))).With("component", "test")
+	log.Warn("not recorded")
+	for i := range recentErrorsSize + 2 {
+		log.Error("failed", "n", i)
+	}
+
+	got := RecentErrors()
+	if len(got) != recentErrorsSize {
+		t.Fatalf("len(RecentErrors()) = %d, want %d", len(got), recentErrorsSize)
+	}
+	if want := fmt.Sprintf("component=test n=%d", recentErrorsSize+1); got[0].Attrs != want {
+		t.Errorf("newest record attrs = %q, want %q", got[0].Attrs, want)
+	}
+	if want := "component=test n=2"; got[len(got)-1].Attrs != want {
+		t.Errorf("oldest record attrs = %q, want %q", got[len(got)-1].Attrs, want)
+	}
+}
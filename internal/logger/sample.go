@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// samplingHandler passes only every rate-th debug record per message.
+// Records at info level and above are never sampled.
+type samplingHandler struct {
+	next     slog.Handler
+	rate     uint64
+	counters *sync.Map // message -> *atomic.Uint64, shared across WithAttrs/WithGroup
+}
+
+func newSamplingHandler(next slog.Handler, rate int) slog.Handler {
+	if rate <= 1 {
+		return next
+	}
+	return &samplingHandler{
+		next:     next,
+		rate:     uint64(rate),
+		counters: &sync.Map{},
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level <= slog.LevelDebug {
+		v, _ := h.counters.LoadOrStore(r.Message, new(atomic.Uint64))
+		if (v.(*atomic.Uint64).Add(1)-1)%h.rate != 0 {
+			return nil
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), rate: h.rate, counters: h.counters}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), rate: h.rate, counters: h.counters}
+}
@@ -0,0 +1,101 @@
+// Package metrics writes metrics in the Prometheus text exposition format.
+// Values are collected when a scrape happens, so there are no registries to keep
+// in sync: a scrape handler reads the current state and writes it out.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Type is a Prometheus metric type.
+type Type string
+
+const (
+	Gauge   Type = "gauge"
+	Counter Type = "counter"
+)
+
+// Label is a name/value pair of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Writer writes metric families. The first error is kept and returned by Err;
+// later writes are skipped.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter creates a writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Family writes the HELP and TYPE lines of a metric. Samples of the metric follow.
+func (w *Writer) Family(name string, typ Type, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+// Sample writes one value of the metric last passed to Family.
+func (w *Writer) Sample(name string, value float64, labels ...Label) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(l.Name)
+			sb.WriteString(`="`)
+			sb.WriteString(escapeLabel(l.Value))
+			sb.WriteByte('"')
+		}
+		sb.WriteByte('}')
+	}
+	w.printf("%s %s\n", sb.String(), formatValue(value))
+}
+
+// Metric writes a metric family with a single unlabeled sample.
+func (w *Writer) Metric(name string, typ Type, help string, value float64) {
+	w.Family(name, typ, help)
+	w.Sample(name, value)
+}
+
+// Err returns the first write error.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
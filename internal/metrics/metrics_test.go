@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var sb strings.Builder
+	w := NewWriter(&sb)
+	w.Metric("total_open_markets", Gauge, "Markets not yet resolved.", 3)
+	w.Family("total_job_failures_total", Counter, "Failed runs.\nPer job.")
+	w.Sample("total_job_failures_total", 2, Label{"job", `stats "daily"`})
+	w.Sample("total_job_failures_total", 0.5, Label{"job", "a"}, Label{"kind", `b\c`})
+	w.Metric("total_inf", Gauge, "x", math.Inf(1))
+	if err := w.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	want := `# HELP total_open_markets Markets not yet resolved.
+# TYPE total_open_markets gauge
+total_open_markets 3
+# HELP total_job_failures_total Failed runs.\nPer job.
+# TYPE total_job_failures_total counter
+total_job_failures_total{job="stats \"daily\""} 2
+total_job_failures_total{job="a",kind="b\\c"} 0.5
+# HELP total_inf x
+# TYPE total_inf gauge
+total_inf +Inf
+`
+	if got := sb.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("closed") }
+
+func TestWriterKeepsFirstError(t *testing.T) {
+	w := NewWriter(failingWriter{})
+	w.Metric("a", Gauge, "a", 1)
+	w.Metric("b", Gauge, "b", 2)
+	if err := w.Err(); err == nil || err.Error() != "closed" {
+		t.Errorf("Err() = %v, want closed", err)
+	}
+}
@@ -8,22 +8,30 @@ import (
 
 // Validation errors.
 var (
-	ErrInvalidOutcome        = errors.New("invalid outcome: must be YES or NO")
-	ErrInvalidPublicKey      = errors.New("invalid Stellar public key format")
-	ErrEmptyQuestion         = errors.New("question is required")
-	ErrQuestionTooLong       = errors.New("question exceeds maximum length (500 characters)")
-	ErrDescriptionTooLong    = errors.New("description exceeds maximum length (2000 characters)")
-	ErrInvalidLiquidityParam = errors.New("liquidity parameter must be positive")
-	ErrInvalidShareAmount    = errors.New("share amount must be positive")
-	ErrCloseTimeInPast       = errors.New("close time must be in the future")
-	ErrInvalidSlippage       = errors.New("slippage must be between 0 and 10%")
+	ErrInvalidOutcome          = errors.New("invalid outcome: must be YES or NO")
+	ErrInvalidPublicKey        = errors.New("invalid Stellar public key format")
+	ErrEmptyQuestion           = errors.New("question is required")
+	ErrQuestionTooLong         = errors.New("question exceeds maximum length (500 characters)")
+	ErrDescriptionTooLong      = errors.New("description exceeds maximum length (2000 characters)")
+	ErrEmptyResolutionSource   = errors.New("resolution source is required")
+	ErrResolutionSourceTooLong = errors.New("resolution source exceeds maximum length (1024 characters)")
+	ErrInvalidResolutionURL    = errors.New("resolution source URL must be an http(s) URL with a host")
+	ErrInvalidLiquidityParam   = errors.New("liquidity parameter must be positive")
+	ErrInvalidShareAmount      = errors.New("share amount must be positive")
+	ErrCloseTimeInPast         = errors.New("close time must be in the future")
+	ErrInvalidSlippage         = errors.New("slippage must be between 0 and 10%")
+	ErrInvalidScalarRange      = errors.New("scalar range must have a finite min below max")
+	ErrScalarUnitTooLong       = errors.New("scalar unit exceeds maximum length (16 characters)")
+	ErrInvalidCondition        = errors.New("condition must name a market contract ID and YES or NO")
 )
 
 const (
-	MaxQuestionLength    = 500
-	MaxDescriptionLength = 2000
-	DefaultSlippage      = 0.01 // 1%
-	MaxSlippage          = 0.10 // 10%
+	MaxQuestionLength         = 500
+	MaxDescriptionLength      = 2000
+	MaxResolutionSourceLength = 1024
+	MaxScalarUnitLength       = 16
+	DefaultSlippage           = 0.01 // 1%
+	MaxSlippage               = 0.10 // 10%
 )
 
 // Outcome represents a market outcome (YES or NO).
@@ -104,6 +112,15 @@ type Market struct {
 	Resolution       Outcome    `json:"resolution"`        // OutcomeYes, OutcomeNo, or ""
 	CreatedAt        time.Time  `json:"created_at"`        // Creation timestamp
 	MetadataHash     string     `json:"metadata_hash"`     // IPFS hash
+
+	// Scalar is the numeric range of a scalar market (from IPFS), nil for YES/NO markets.
+	Scalar *ScalarRange `json:"scalar,omitempty"`
+	// Condition is the parent market this one is conditional on (from IPFS), if any.
+	Condition *MarketCondition `json:"condition,omitempty"`
+	// ContractVersion is the registered version of the contract build the market runs,
+	// empty when the build is unregistered; ContractWasmHash identifies the build.
+	ContractVersion  string `json:"contract_version,omitempty"`
+	ContractWasmHash string `json:"contract_wasm_hash,omitempty"`
 }
 
 // IsResolved returns true if the market has been resolved.
@@ -111,6 +128,18 @@ func (m *Market) IsResolved() bool {
 	return m.Resolution != ""
 }
 
+// IsScalar returns true if the market trades LONG/SHORT over a numeric range rather
+// than YES/NO on a question.
+func (m *Market) IsScalar() bool {
+	return m.Scalar != nil
+}
+
+// ResolutionSourceURL returns the resolution source as a link target when it is an
+// http(s) URL, otherwise "".
+func (m *Market) ResolutionSourceURL() string {
+	return ResolutionSourceURL(m.ResolutionSource)
+}
+
 // Validate checks all market invariants.
 // Returns an error if any invariant is violated.
 func (m *Market) Validate() error {
@@ -169,4 +198,15 @@ type TransactionResult struct {
 	Description string `json:"description"` // Human-readable description
 	SignWith    string `json:"sign_with"`   // Public key that must sign
 	SubmitURL   string `json:"submit_url"`  // Horizon submit URL
+
+	// Set when the transaction carries address auth entries: the ledger after which
+	// their signatures expire and an estimate of when that is. Past it the transaction
+	// must be rebuilt, which re-simulates it and refreshes the entries.
+	AuthExpiresLedger uint32    `json:"auth_expires_ledger,omitempty"`
+	AuthExpiresAt     time.Time `json:"auth_expires_at,omitzero"`
+}
+
+// AuthExpiresWithin reports whether the auth entries expire within d of now.
+func (r TransactionResult) AuthExpiresWithin(now time.Time, d time.Duration) bool {
+	return !r.AuthExpiresAt.IsZero() && r.AuthExpiresAt.Before(now.Add(d))
 }
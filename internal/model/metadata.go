@@ -1,13 +1,19 @@
 package model
 
-import "time"
+import (
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
 
 // NewMarketMetadata creates a new MarketMetadata with required fields validated.
-// Question is required and must not exceed MaxQuestionLength.
-func NewMarketMetadata(question string) (*MarketMetadata, error) {
+// Question and resolution source are required; see Validate for the limits.
+func NewMarketMetadata(question, resolutionSource string) (*MarketMetadata, error) {
 	m := &MarketMetadata{
-		Question:  question,
-		CreatedAt: time.Now().UTC(),
+		Question:         question,
+		ResolutionSource: resolutionSource,
+		CreatedAt:        time.Now().UTC(),
 	}
 	if err := m.Validate(); err != nil {
 		return nil, err
@@ -25,9 +31,55 @@ type MarketMetadata struct {
 	EndDate          time.Time `json:"end_date,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	CreatedBy        string    `json:"created_by,omitempty"`
+
+	// Scalar makes the market a scalar one: YES is LONG and NO is SHORT on a value in
+	// the range, and the question asks for that value.
+	Scalar *ScalarRange `json:"scalar,omitempty"`
+
+	// Condition makes the market conditional on another: its question assumes the
+	// parent resolves to Condition.Outcome.
+	Condition *MarketCondition `json:"condition,omitempty"`
+}
+
+// MarketCondition links a market to the parent market it is conditional on. It is
+// informational: the contracts know nothing of it, so a market whose condition fails
+// still has to be resolved by the oracle per its rules.
+type MarketCondition struct {
+	MarketID string  `json:"market_id"` // Parent market contract ID
+	Outcome  Outcome `json:"outcome"`   // Parent outcome the question assumes
+}
+
+// Validate checks that the condition names a contract ID and an outcome.
+// For full contract ID validation, use soroban.ValidateContractID.
+func (c *MarketCondition) Validate() error {
+	if len(c.MarketID) != 56 || !strings.HasPrefix(c.MarketID, "C") || !c.Outcome.IsValid() {
+		return ErrInvalidCondition
+	}
+	return nil
+}
+
+// ScalarRange is the numeric range of a scalar market. At resolution a LONG token
+// pays the value's position in [Min, Max] and a SHORT token the rest.
+type ScalarRange struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Unit string  `json:"unit,omitempty"` // e.g. "EURMTL", shown after values
+}
+
+// Validate checks that the range is finite and not empty.
+func (s *ScalarRange) Validate() error {
+	if math.IsNaN(s.Min) || math.IsNaN(s.Max) || math.IsInf(s.Min, 0) || math.IsInf(s.Max, 0) || s.Min >= s.Max {
+		return ErrInvalidScalarRange
+	}
+	if len(s.Unit) > MaxScalarUnitLength {
+		return ErrScalarUnitTooLong
+	}
+	return nil
 }
 
 // Validate checks that required metadata fields are present.
+// The resolution source, a URL or a description of how the question resolves, is
+// required so disputes can point at it; a URL must be http(s).
 func (m *MarketMetadata) Validate() error {
 	if m.Question == "" {
 		return ErrEmptyQuestion
@@ -38,5 +90,41 @@ func (m *MarketMetadata) Validate() error {
 	if len(m.Description) > MaxDescriptionLength {
 		return ErrDescriptionTooLong
 	}
+	if m.Scalar != nil {
+		if err := m.Scalar.Validate(); err != nil {
+			return err
+		}
+	}
+	if m.Condition != nil {
+		if err := m.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+	return ValidateResolutionSource(m.ResolutionSource)
+}
+
+// ValidateResolutionSource checks a market's resolution source: required, bounded
+// by MaxResolutionSourceLength, and a well-formed http(s) URL when it has a scheme.
+func ValidateResolutionSource(source string) error {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return ErrEmptyResolutionSource
+	}
+	if len(source) > MaxResolutionSourceLength {
+		return ErrResolutionSourceTooLong
+	}
+	if strings.Contains(source, "://") && !strings.ContainsAny(source, " \t\n") && ResolutionSourceURL(source) == "" {
+		return ErrInvalidResolutionURL
+	}
 	return nil
 }
+
+// ResolutionSourceURL returns source as a link target when it is an http(s) URL,
+// otherwise "".
+func ResolutionSourceURL(source string) string {
+	u, err := url.Parse(strings.TrimSpace(source))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
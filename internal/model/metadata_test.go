@@ -37,7 +37,7 @@ func TestNewMarketMetadata(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			meta, err := NewMarketMetadata(tt.question)
+			meta, err := NewMarketMetadata(tt.question, "https://www.coinbase.com/price/bitcoin")
 			if tt.wantErr != nil {
 				if !errors.Is(err, tt.wantErr) {
 					t.Errorf("NewMarketMetadata() error = %v, wantErr %v", err, tt.wantErr)
@@ -73,9 +73,10 @@ func TestMarketMetadata_Validate(t *testing.T) {
 		{
 			name: "valid metadata",
 			meta: MarketMetadata{
-				Question:    "Will X happen?",
-				Description: "Some description",
-				CreatedAt:   time.Now(),
+				Question:         "Will X happen?",
+				Description:      "Some description",
+				ResolutionSource: "coinbase.com",
+				CreatedAt:        time.Now(),
 			},
 			wantErr: nil,
 		},
@@ -104,31 +105,132 @@ func TestMarketMetadata_Validate(t *testing.T) {
 			},
 			wantErr: ErrDescriptionTooLong,
 		},
+		{
+			name: "valid scalar range",
+			meta: MarketMetadata{
+				Question:         "What will MTL trade at on 2026-12-31?",
+				ResolutionSource: "stellar.expert",
+				Scalar:           &ScalarRange{Min: 0.5, Max: 5, Unit: "EURMTL"},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "empty scalar range",
+			meta: MarketMetadata{
+				Question:         "What will MTL trade at?",
+				ResolutionSource: "stellar.expert",
+				Scalar:           &ScalarRange{Min: 5, Max: 5},
+			},
+			wantErr: ErrInvalidScalarRange,
+		},
+		{
+			name: "scalar unit too long",
+			meta: MarketMetadata{
+				Question:         "What will MTL trade at?",
+				ResolutionSource: "stellar.expert",
+				Scalar:           &ScalarRange{Min: 0, Max: 5, Unit: strings.Repeat("a", MaxScalarUnitLength+1)},
+			},
+			wantErr: ErrScalarUnitTooLong,
+		},
+		{
+			name: "valid condition",
+			meta: MarketMetadata{
+				Question:         "If MTL lists on the DEX, will it trade above 1 EURMTL?",
+				ResolutionSource: "stellar.expert",
+				Condition:        &MarketCondition{MarketID: "C" + strings.Repeat("A", 55), Outcome: OutcomeYes},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "condition without outcome",
+			meta: MarketMetadata{
+				Question:         "If MTL lists on the DEX, will it trade above 1 EURMTL?",
+				ResolutionSource: "stellar.expert",
+				Condition:        &MarketCondition{MarketID: "C" + strings.Repeat("A", 55)},
+			},
+			wantErr: ErrInvalidCondition,
+		},
+		{
+			name: "condition with account ID",
+			meta: MarketMetadata{
+				Question:         "If MTL lists on the DEX, will it trade above 1 EURMTL?",
+				ResolutionSource: "stellar.expert",
+				Condition:        &MarketCondition{MarketID: "G" + strings.Repeat("A", 55), Outcome: OutcomeNo},
+			},
+			wantErr: ErrInvalidCondition,
+		},
 		{
 			name: "question at boundary",
 			meta: MarketMetadata{
-				Question:  strings.Repeat("a", MaxQuestionLength),
-				CreatedAt: time.Now(),
+				Question:         strings.Repeat("a", MaxQuestionLength),
+				ResolutionSource: "coinbase.com",
+				CreatedAt:        time.Now(),
 			},
 			wantErr: nil,
 		},
 		{
 			name: "description at boundary",
 			meta: MarketMetadata{
-				Question:    "Valid question?",
-				Description: strings.Repeat("a", MaxDescriptionLength),
-				CreatedAt:   time.Now(),
+				Question:         "Valid question?",
+				Description:      strings.Repeat("a", MaxDescriptionLength),
+				ResolutionSource: "coinbase.com",
+				CreatedAt:        time.Now(),
 			},
 			wantErr: nil,
 		},
 		{
 			name: "whitespace only question",
 			meta: MarketMetadata{
-				Question:  "   ",
-				CreatedAt: time.Now(),
+				Question:         "   ",
+				ResolutionSource: "coinbase.com",
+				CreatedAt:        time.Now(),
 			},
 			wantErr: nil, // Current implementation doesn't trim whitespace
 		},
+		{
+			name: "missing resolution source",
+			meta: MarketMetadata{
+				Question:  "Will X happen?",
+				CreatedAt: time.Now(),
+			},
+			wantErr: ErrEmptyResolutionSource,
+		},
+		{
+			name: "whitespace only resolution source",
+			meta: MarketMetadata{
+				Question:         "Will X happen?",
+				ResolutionSource: "  ",
+				CreatedAt:        time.Now(),
+			},
+			wantErr: ErrEmptyResolutionSource,
+		},
+		{
+			name: "resolution source too long",
+			meta: MarketMetadata{
+				Question:         "Will X happen?",
+				ResolutionSource: strings.Repeat("a", MaxResolutionSourceLength+1),
+				CreatedAt:        time.Now(),
+			},
+			wantErr: ErrResolutionSourceTooLong,
+		},
+		{
+			name: "resolution source with non-http URL",
+			meta: MarketMetadata{
+				Question:         "Will X happen?",
+				ResolutionSource: "javascript://alert(1)",
+				CreatedAt:        time.Now(),
+			},
+			wantErr: ErrInvalidResolutionURL,
+		},
+		{
+			name: "resolution source description mentioning a URL",
+			meta: MarketMetadata{
+				Question:         "Will X happen?",
+				ResolutionSource: "Closing price on https://www.coinbase.com at 00:00 UTC",
+				CreatedAt:        time.Now(),
+			},
+			wantErr: nil,
+		},
 		{
 			name: "valid with all optional fields",
 			meta: MarketMetadata{
@@ -157,3 +259,22 @@ func TestMarketMetadata_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestResolutionSourceURL(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"https://www.coinbase.com/price/bitcoin", "https://www.coinbase.com/price/bitcoin"},
+		{" http://example.com ", "http://example.com"},
+		{"coinbase.com", ""},
+		{"https://", ""},
+		{"ftp://example.com", ""},
+		{"Official election results", ""},
+	}
+	for _, tt := range tests {
+		if got := ResolutionSourceURL(tt.source); got != tt.want {
+			t.Errorf("ResolutionSourceURL(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mtlprog/total/internal/httpclient"
+)
+
+// discordHosts are the hosts Discord issues webhook URLs on.
+var discordHosts = []string{"discord.com", "discordapp.com", "canary.discord.com", "ptb.discord.com"}
+
+// Discord limits embed titles to 256 and descriptions to 4096 characters.
+const (
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+)
+
+// Discord posts messages as embeds to Discord channel webhooks.
+type Discord struct {
+	httpClient *http.Client
+}
+
+// NewDiscord creates a Discord webhook transport.
+// A nil httpClient uses httpclient.Default().
+func NewDiscord(httpClient *http.Client) *Discord {
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
+	return &Discord{httpClient: httpClient}
+}
+
+func (d *Discord) Channel() Channel { return ChannelDiscord }
+
+func (d *Discord) ValidateTarget(target string) error { return validateDiscordWebhook(target) }
+
+func (d *Discord) Send(ctx context.Context, target string, msg Message) error {
+	embed := map[string]string{
+		"title":       truncateRunes(msg.Title, discordMaxTitle),
+		"description": truncateRunes(msg.Text, discordMaxDescription),
+	}
+	if msg.URL != "" {
+		embed["url"] = msg.URL
+	}
+	return postJSON(ctx, d.httpClient, target, map[string]any{
+		"embeds": []map[string]string{embed},
+		// Alert texts are ours, but never let them ping @everyone or roles.
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+}
+
+func validateDiscordWebhook(target string) error {
+	if err := validateWebhookURL(target); err != nil {
+		return err
+	}
+	u, _ := url.Parse(target)
+	if !slices.Contains(discordHosts, strings.ToLower(u.Hostname())) || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+		return fmt.Errorf("%w: expected a Discord webhook URL (https://discord.com/api/webhooks/...)", ErrInvalidTarget)
+	}
+	return nil
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
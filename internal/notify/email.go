@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEmailLength is the longest address SMTP can deliver to (RFC 5321 path limit).
+const maxEmailLength = 254
+
+// SMTPConfig configures email delivery. Port 465 uses implicit TLS; other ports
+// upgrade with STARTTLS when the server offers it, which authentication requires.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // empty sends without authentication
+	Password string
+	From     string // envelope and header sender address
+}
+
+// Email sends messages as plain-text email over SMTP.
+type Email struct {
+	cfg SMTPConfig
+}
+
+// NewEmail creates an email transport.
+func NewEmail(cfg SMTPConfig) (*Email, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("invalid SMTP port %d", cfg.Port)
+	}
+	if err := validateEmailAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender: %w", err)
+	}
+	return &Email{cfg: cfg}, nil
+}
+
+func (e *Email) Channel() Channel { return ChannelEmail }
+
+func (e *Email) ValidateTarget(target string) error { return validateEmailAddress(target) }
+
+func (e *Email) Send(ctx context.Context, target string, msg Message) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if e.cfg.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: e.cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.cfg.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if e.cfg.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection.
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := c.Rcpt(target); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(buildEmail(e.cfg.From, target, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected email: %w", err)
+	}
+	return c.Quit()
+}
+
+// buildEmail renders msg as a plain-text RFC 5322 message.
+func buildEmail(from, to string, msg Message, now time.Time) []byte {
+	// Header values must not contain line breaks, or they could inject headers.
+	subject := strings.Join(strings.Fields(msg.Title), " ")
+	body := msg.Text
+	if msg.URL != "" {
+		body += "\n\n" + msg.URL
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func validateEmailAddress(target string) error {
+	if len(target) > maxEmailLength {
+		return fmt.Errorf("%w: email address is too long", ErrInvalidTarget)
+	}
+	// Only a bare address: a display name or comments could smuggle in header text.
+	addr, err := mail.ParseAddress(target)
+	if err != nil || addr.Address != target || addr.Name != "" {
+		return fmt.Errorf("%w: expected an email address", ErrInvalidTarget)
+	}
+	return nil
+}
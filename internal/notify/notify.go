@@ -0,0 +1,146 @@
+// Package notify delivers short user notifications over pluggable transports:
+// Telegram, Discord, generic webhooks and email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Channel identifies a notification transport.
+type Channel string
+
+const (
+	ChannelTelegram Channel = "telegram"
+	ChannelWebhook  Channel = "webhook"
+	ChannelDiscord  Channel = "discord"
+	ChannelEmail    Channel = "email"
+)
+
+var (
+	ErrChannelDisabled = errors.New("notification channel is not configured")
+	ErrInvalidTarget   = errors.New("invalid notification target")
+)
+
+// Message is a notification. Text is plain text; URL, when set, links to the related page.
+type Message struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Transport delivers messages over one channel. Targets are channel specific: a chat
+// ID, a webhook URL, an email address.
+type Transport interface {
+	Channel() Channel
+	ValidateTarget(target string) error
+	Send(ctx context.Context, target string, msg Message) error
+}
+
+// targetValidators check targets of the built-in channels without a configured
+// transport, so requests can be validated before they reach a dispatcher.
+var targetValidators = map[Channel]func(string) error{
+	ChannelTelegram: validateTelegramChat,
+	ChannelWebhook:  validateWebhookURL,
+	ChannelDiscord:  validateDiscordWebhook,
+	ChannelEmail:    validateEmailAddress,
+}
+
+// ValidateTarget checks a target of a built-in channel.
+func ValidateTarget(ch Channel, target string) error {
+	validate, ok := targetValidators[ch]
+	if !ok {
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalidTarget, ch)
+	}
+	return validate(target)
+}
+
+// Dispatcher routes messages to the transport of their channel.
+type Dispatcher struct {
+	transports map[Channel]Transport
+	channels   []Channel // registration order
+}
+
+// NewDispatcher creates a dispatcher over transports; channels without a transport
+// are disabled. Registering two transports for one channel panics.
+func NewDispatcher(transports ...Transport) *Dispatcher {
+	d := &Dispatcher{transports: make(map[Channel]Transport, len(transports))}
+	for _, t := range transports {
+		if t == nil {
+			panic("NewDispatcher: transport must not be nil")
+		}
+		ch := t.Channel()
+		if _, ok := d.transports[ch]; ok {
+			panic(fmt.Sprintf("NewDispatcher: duplicate transport for channel %q", ch))
+		}
+		d.transports[ch] = t
+		d.channels = append(d.channels, ch)
+	}
+	return d
+}
+
+// Enabled reports whether messages can be sent over ch.
+func (d *Dispatcher) Enabled(ch Channel) bool {
+	_, ok := d.transports[ch]
+	return ok
+}
+
+// Channels returns the enabled channels in registration order.
+func (d *Dispatcher) Channels() []Channel {
+	return append([]Channel(nil), d.channels...)
+}
+
+// ValidateTarget checks target with the transport of ch.
+func (d *Dispatcher) ValidateTarget(ch Channel, target string) error {
+	t, ok := d.transports[ch]
+	if !ok {
+		return ErrChannelDisabled
+	}
+	return t.ValidateTarget(target)
+}
+
+// Send delivers msg to target over ch.
+func (d *Dispatcher) Send(ctx context.Context, ch Channel, target string, msg Message) error {
+	t, ok := d.transports[ch]
+	if !ok {
+		return ErrChannelDisabled
+	}
+	if err := t.ValidateTarget(target); err != nil {
+		return err
+	}
+	return t.Send(ctx, target, msg)
+}
+
+// postJSON sends body as JSON and treats any non-2xx response as a failure.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may embed a bot token or webhook secret; report the host only.
+		if errors.Is(err, ErrInvalidTarget) {
+			return fmt.Errorf("notification request to %s refused: %w", req.URL.Host, ErrInvalidTarget)
+		}
+		return fmt.Errorf("notification request to %s failed", req.URL.Host)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification to %s failed: status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		target  string
+		wantErr bool
+	}{
+		{"telegram chat ID", ChannelTelegram, "123456789", false},
+		{"telegram group ID", ChannelTelegram, "-1001234567890", false},
+		{"telegram channel", ChannelTelegram, "@mtl_alerts", false},
+		{"telegram short username", ChannelTelegram, "@abc", true},
+		{"telegram URL", ChannelTelegram, "https://t.me/x", true},
+		{"webhook https", ChannelWebhook, "https://example.com/hook", false},
+		{"webhook http", ChannelWebhook, "http://example.com/hook", true},
+		{"webhook credentials", ChannelWebhook, "https://user:pw@example.com/hook", true},
+		{"webhook relative", ChannelWebhook, "/hook", true},
+		{"webhook public IP", ChannelWebhook, "https://203.0.113.7/hook", false},
+		{"webhook localhost", ChannelWebhook, "https://localhost:8080/hook", true},
+		{"webhook loopback", ChannelWebhook, "https://127.0.0.1/hook", true},
+		{"webhook IPv6 loopback", ChannelWebhook, "https://[::1]/hook", true},
+		{"webhook private", ChannelWebhook, "https://10.0.0.5/hook", true},
+		{"webhook link-local", ChannelWebhook, "https://169.254.169.254/latest", true},
+		{"webhook mapped private", ChannelWebhook, "https://[::ffff:192.168.1.1]/hook", true},
+		{"webhook unspecified", ChannelWebhook, "https://0.0.0.0/hook", true},
+		{"webhook internal name", ChannelWebhook, "https://metadata.google.internal/hook", true},
+		{"discord webhook", ChannelDiscord, "https://discord.com/api/webhooks/123/abc", false},
+		{"discord other host", ChannelDiscord, "https://example.com/api/webhooks/123/abc", true},
+		{"discord other path", ChannelDiscord, "https://discord.com/channels/123", true},
+		{"email", ChannelEmail, "alice@example.com", false},
+		{"email with name", ChannelEmail, "Alice <alice@example.com>", true},
+		{"email header injection", ChannelEmail, "alice@example.com\r\nBcc: eve@example.com", true},
+		{"unknown channel", Channel("sms"), "123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTarget(tt.channel, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTarget) {
+				t.Errorf("ValidateTarget() error = %v, want ErrInvalidTarget", err)
+			}
+		})
+	}
+}
+
+func TestWebhookRefusesInternalHost(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook delivered to a loopback address")
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.Client()).Send(context.Background(), server.URL+"/hook", Message{Title: "x"})
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("Send() error = %v, want ErrInvalidTarget", err)
+	}
+}
+
+func TestWebhookRefusesRedirect(t *testing.T) {
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook followed a redirect to a private address")
+	}))
+	defer internal.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/admin", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// The first hop is allowed here, so only the redirect check stands in the way.
+	webhook := NewWebhook(server.Client())
+	webhook.allowInternal = true
+	err := webhook.Send(context.Background(), server.URL+"/hook", Message{Title: "x"})
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("Send() error = %v, want ErrInvalidTarget", err)
+	}
+}
+
+func TestWebhookDialRefusesPrivateAddress(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook connected to a loopback address")
+	}))
+	defer server.Close()
+
+	// A host name that resolved to a public address when checked, then to a private
+	// one when dialed, reaches the dialer like this.
+	webhook := NewWebhook(server.Client())
+	err := postJSON(context.Background(), webhook.httpClient, server.URL+"/hook", Message{Title: "x"})
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("postJSON() error = %v, want ErrInvalidTarget", err)
+	}
+
+	for _, tt := range []struct {
+		address string
+		wantErr bool
+	}{
+		{"203.0.113.7:443", false},
+		{"[2001:db8::1]:443", false},
+		{"127.0.0.1:443", true},
+		{"10.0.0.5:443", true},
+		{"169.254.169.254:80", true},
+		{"[::ffff:192.168.1.1]:443", true},
+		{"[fe80::1]:443", true},
+	} {
+		if err := checkDialAddr(tt.address); (err != nil) != tt.wantErr {
+			t.Errorf("checkDialAddr(%s) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDispatcherSend(t *testing.T) {
+	var got Message
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.Client())
+	webhook.allowInternal = true
+	d := NewDispatcher(webhook)
+	msg := Message{Title: "Market alert", Text: "YES above 60%", URL: "https://example.com/market/C"}
+
+	if err := d.Send(context.Background(), ChannelWebhook, server.URL+"/hook", msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got != msg {
+		t.Errorf("webhook received %+v, want %+v", got, msg)
+	}
+	if err := d.Send(context.Background(), ChannelWebhook, server.URL+"/fail", msg); err == nil {
+		t.Error("Send() to failing webhook: expected error")
+	}
+	if err := d.Send(context.Background(), ChannelTelegram, "123", msg); !errors.Is(err, ErrChannelDisabled) {
+		t.Errorf("Send() telegram without token error = %v, want ErrChannelDisabled", err)
+	}
+}
+
+func TestDiscordSend(t *testing.T) {
+	var got struct {
+		Embeds []map[string]string `json:"embeds"`
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode Discord body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	msg := Message{Title: "Market alert", Text: "YES above 60%", URL: "https://example.com/market/C"}
+	if err := NewDiscord(server.Client()).Send(context.Background(), server.URL+"/api/webhooks/1/x", msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(got.Embeds) != 1 || got.Embeds[0]["title"] != msg.Title || got.Embeds[0]["description"] != msg.Text || got.Embeds[0]["url"] != msg.URL {
+		t.Errorf("Discord received %+v, want one embed of %+v", got, msg)
+	}
+}
+
+func TestDispatcherChannels(t *testing.T) {
+	d := NewDispatcher(NewWebhook(nil), NewDiscord(nil))
+	if got := d.Channels(); len(got) != 2 || got[0] != ChannelWebhook || got[1] != ChannelDiscord {
+		t.Errorf("Channels() = %v, want [webhook discord]", got)
+	}
+	if d.Enabled(ChannelEmail) {
+		t.Error("email should be disabled without a transport")
+	}
+	if err := d.ValidateTarget(ChannelDiscord, "https://example.com/hook"); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("ValidateTarget() error = %v, want ErrInvalidTarget", err)
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	msg := Message{Title: "Market alert\r\nBcc: eve@example.com", Text: "YES above 60%\nnow 61%", URL: "https://example.com/market/C"}
+	raw := string(buildEmail("alerts@example.com", "alice@example.com", msg, time.Unix(1_700_000_000, 0)))
+
+	header, body, ok := strings.Cut(raw, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header/body separator in %q", raw)
+	}
+	if strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("title injected a header: %q", header)
+	}
+	if !strings.Contains(header, "Subject: Market alert Bcc: eve@example.com\r\n") {
+		t.Errorf("subject not folded onto one line: %q", header)
+	}
+	if body != "YES above 60%\r\nnow 61%\r\n\r\nhttps://example.com/market/C\r\n" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/mtlprog/total/internal/httpclient"
+)
+
+// telegramAPI is the Telegram Bot API base URL.
+const telegramAPI = "https://api.telegram.org"
+
+// telegramChatPattern matches a numeric chat ID or a public @channel/@group username.
+var telegramChatPattern = regexp.MustCompile(`^(-?[0-9]{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// Telegram sends messages through a Telegram bot to chat IDs and public channels.
+type Telegram struct {
+	token      string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewTelegram creates a Telegram transport for the bot token.
+// A nil httpClient uses httpclient.Default().
+func NewTelegram(token string, httpClient *http.Client) *Telegram {
+	if token == "" {
+		panic("NewTelegram: token must not be empty")
+	}
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
+	return &Telegram{token: token, apiURL: telegramAPI, httpClient: httpClient}
+}
+
+func (t *Telegram) Channel() Channel { return ChannelTelegram }
+
+func (t *Telegram) ValidateTarget(target string) error { return validateTelegramChat(target) }
+
+func (t *Telegram) Send(ctx context.Context, target string, msg Message) error {
+	text := msg.Title + "\n" + msg.Text
+	if msg.URL != "" {
+		text += "\n" + msg.URL
+	}
+	return postJSON(ctx, t.httpClient, t.apiURL+"/bot"+t.token+"/sendMessage", map[string]any{
+		"chat_id":                  target,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+}
+
+func validateTelegramChat(target string) error {
+	if !telegramChatPattern.MatchString(target) {
+		return fmt.Errorf("%w: expected a numeric chat ID or @channel", ErrInvalidTarget)
+	}
+	return nil
+}
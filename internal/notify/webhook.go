@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mtlprog/total/internal/httpclient"
+)
+
+// maxWebhookURLLength bounds stored webhook URLs.
+const maxWebhookURLLength = 512
+
+// Webhook posts messages as JSON to https URLs:
+// {"title": ..., "text": ..., "url": ..., "sent_at": ...}.
+type Webhook struct {
+	httpClient    *http.Client
+	allowInternal bool // Tests only: deliver to servers on the local machine
+}
+
+// NewWebhook creates a generic webhook transport.
+// A nil httpClient uses httpclient.Default(). Deliveries use a copy of its transport
+// that only connects to public addresses and do not follow redirects.
+func NewWebhook(httpClient *http.Client) *Webhook {
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
+	w := &Webhook{}
+	w.httpClient = w.publicClient(httpClient)
+	return w
+}
+
+// publicClient returns a copy of client whose connections are checked after the host
+// name is resolved, in the dialer: checkPublicHost alone resolves the name once and
+// the request resolves it again, so a name could be repointed in between (DNS
+// rebinding). Redirects are refused, since they could lead to any address. There is
+// no proxy, because the dialer would check the proxy's address instead of the target.
+func (w *Webhook) publicClient(client *http.Client) *http.Client {
+	base, ok := client.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: httpclient.DefaultKeepAlive,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if w.allowInternal {
+				return nil
+			}
+			return checkDialAddr(address)
+		},
+	}).DialContext
+
+	return &http.Client{
+		Timeout:   client.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return fmt.Errorf("%w: webhook redirects are not followed", ErrInvalidTarget)
+		},
+	}
+}
+
+// checkDialAddr refuses a connection to a non-public address.
+func checkDialAddr(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: webhook dials unexpected address %s", ErrInvalidTarget, address)
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: webhook host resolves to a non-public address", ErrInvalidTarget)
+	}
+	return nil
+}
+
+func (w *Webhook) Channel() Channel { return ChannelWebhook }
+
+func (w *Webhook) ValidateTarget(target string) error {
+	if w.allowInternal {
+		_, err := parseWebhookURL(target)
+		return err
+	}
+	return validateWebhookURL(target)
+}
+
+func (w *Webhook) Send(ctx context.Context, target string, msg Message) error {
+	// Rules are validated on creation, but a host name can be repointed since.
+	if !w.allowInternal {
+		if err := checkPublicHost(ctx, target); err != nil {
+			return err
+		}
+	}
+	return postJSON(ctx, w.httpClient, target, struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()})
+}
+
+func validateWebhookURL(target string) error {
+	u, err := parseWebhookURL(target)
+	if err != nil {
+		return err
+	}
+	if internalHost(u.Hostname()) {
+		return fmt.Errorf("%w: webhook must point to a public host", ErrInvalidTarget)
+	}
+	return nil
+}
+
+// parseWebhookURL checks that target is an https URL without credentials.
+func parseWebhookURL(target string) (*url.URL, error) {
+	if len(target) > maxWebhookURLLength {
+		return nil, fmt.Errorf("%w: webhook URL is too long", ErrInvalidTarget)
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: webhook must be an https URL", ErrInvalidTarget)
+	}
+	return u, nil
+}
+
+// internalHost reports whether host names this machine or a private network:
+// localhost, an internal-only suffix or a non-public IP literal.
+func internalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && !publicAddr(addr)
+}
+
+// publicAddr reports whether addr is routable on the internet: not loopback,
+// private, link-local, multicast or unspecified.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// checkPublicHost resolves the host of target and refuses delivery if any of its
+// addresses is not public, so a webhook cannot reach services on the server's network.
+// It reports a bad target before any request; the dialer checks the address again.
+func checkPublicHost(ctx context.Context, target string) error {
+	u, err := parseWebhookURL(target)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if internalHost(host) {
+		return fmt.Errorf("%w: webhook must point to a public host", ErrInvalidTarget)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host %s", host)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%w: webhook host %s resolves to a non-public address", ErrInvalidTarget, host)
+		}
+	}
+	return nil
+}
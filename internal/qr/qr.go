@@ -0,0 +1,490 @@
+// Package qr encodes short texts, like SEP-7 transaction URIs, as QR codes and
+// renders them as SVG. Only byte mode is supported, which is all a URI needs.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Level is the error correction level of a code.
+type Level int
+
+const (
+	// LevelL recovers about 7% of damaged codewords and fits the most data.
+	LevelL Level = iota
+	// LevelM recovers about 15% of damaged codewords.
+	LevelM
+)
+
+// quietZone is the light border around a code, in modules, required by scanners.
+const quietZone = 4
+
+// ErrTooLong is returned for data that does not fit in the largest code (version 40).
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// Per version (index 1..40): error correction codewords per block and number of
+// blocks, for LevelL and LevelM.
+var (
+	eccPerBlock = [2][41]int{
+		{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	}
+	eccBlocks = [2][41]int{
+		{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	}
+	// formatLevelBits are the error correction bits of the format information.
+	formatLevelBits = [2]int{1, 0}
+)
+
+// Code is an encoded QR code.
+type Code struct {
+	Version int
+	Level   Level
+	Size    int      // Modules per side, without the quiet zone
+	dark    [][]bool // [y][x]
+	fixed   [][]bool // Function patterns, not covered by data or masks
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.dark[y][x]
+}
+
+// Encode encodes data at the given level in the smallest version it fits.
+func Encode(data []byte, level Level) (*Code, error) {
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if len(data) <= dataCapacity(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(data))
+	}
+
+	c := &Code{Version: version, Level: level, Size: version*4 + 17}
+	c.dark = newGrid(c.Size)
+	c.fixed = newGrid(c.Size)
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.addECC(encodeBytes(data, version, level)))
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+// SVG renders the code with its quiet zone as a standalone SVG image, scale pixels
+// per module.
+func (c *Code) SVG(scale int) []byte {
+	size := c.Size + 2*quietZone
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size*scale, size*scale, size, size)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.dark[y][x] {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+// rawCodewords returns the number of 8-bit codewords (data and error correction) a
+// version holds.
+func rawCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		modules -= (25*align-10)*align - 55
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+	return modules / 8
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawCodewords(version) - eccPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// dataCapacity returns how many bytes fit in byte mode: the data codewords minus the
+// mode indicator and character count.
+func dataCapacity(version int, level Level) int {
+	return (dataCodewords(version, level)*8 - 4 - countBits(version)) / 8
+}
+
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// encodeBytes builds the data codewords: byte mode indicator, length, data,
+// terminator and padding.
+func encodeBytes(data []byte, version int, level Level) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := dataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+// addECC splits data into blocks, appends each block's Reed-Solomon codewords and
+// interleaves the result.
+func (c *Code) addECC(data []byte) []byte {
+	numBlocks := eccBlocks[c.Level][c.Version]
+	eccLen := eccPerBlock[c.Level][c.Version]
+	raw := rawCodewords(c.Version)
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range blocks {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < numShort {
+			block = append(block, 0) // placeholder so all blocks have the same length
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	out := make([]byte, 0, raw)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			// Skip the placeholder of short blocks
+			if i != shortLen-eccLen || j >= numShort {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given degree, highest
+// coefficient first and the leading 1 omitted.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		if (y>>i)&1 != 0 {
+			z ^= int(x)
+		}
+	}
+	return byte(z)
+}
+
+func (c *Code) setFixed(x, y int, dark bool) {
+	c.dark[y][x] = dark
+	c.fixed[y][x] = true
+}
+
+// drawFunctionPatterns draws timing, finder and alignment patterns and reserves the
+// format and version areas.
+func (c *Code) drawFunctionPatterns() {
+	for i := range c.Size {
+		c.setFixed(6, i, i%2 == 0)
+		c.setFixed(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	n := len(positions)
+	for i, y := range positions {
+		for j, x := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue // overlaps a finder
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFixed(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	c.drawFormat(0) // reserved now, redrawn with the chosen mask
+	c.drawVersion()
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFixed(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// alignmentPositions returns the row/column centers of the alignment patterns.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	positions := make([]int, n)
+	positions[0] = 6
+	for i, pos := n-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (c *Code) drawFormat(mask int) {
+	data := formatLevelBits[c.Level]<<3 | mask
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFixed(8, i, bit(i))
+	}
+	c.setFixed(8, 7, bit(6))
+	c.setFixed(8, 8, bit(7))
+	c.setFixed(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFixed(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFixed(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFixed(8, c.Size-15+i, bit(i))
+	}
+	c.setFixed(8, c.Size-8, true) // always dark
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+	for i := range 18 {
+		dark := (bits>>i)&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFixed(a, b, dark)
+		c.setFixed(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the two-module-wide zigzag from the bottom
+// right corner, skipping function patterns.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.fixed[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.dark[y][x] = (data[i/8]>>(7-i%8))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.fixed[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.dark[y][x] = !c.dark[y][x]
+			}
+		}
+	}
+}
+
+// finderLike are the 1:1:3:1:1 patterns with four light modules on one side that the
+// mask penalty avoids, since scanners could take them for finders.
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores how hard the masked code is to scan; the mask with the lowest score
+// is used.
+func (c *Code) penalty() int {
+	total := 0
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= c.Size; i++ {
+			if i < c.Size && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				total += run - 2
+			}
+			run = 1
+		}
+		for i := 0; i+11 <= c.Size; i++ {
+			for _, pattern := range finderLike {
+				match := true
+				for k, dark := range pattern {
+					if get(i+k) != dark {
+						match = false
+						break
+					}
+				}
+				if match {
+					total += 40
+				}
+			}
+		}
+	}
+	darkCount := 0
+	for y := range c.Size {
+		line(func(i int) bool { return c.dark[y][i] })
+		line(func(i int) bool { return c.dark[i][y] })
+		for x := range c.Size {
+			if c.dark[y][x] {
+				darkCount++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				d := c.dark[y][x]
+				if c.dark[y][x+1] == d && c.dark[y+1][x] == d && c.dark[y+1][x+1] == d {
+					total += 3
+				}
+			}
+		}
+	}
+	percent := darkCount * 100 / (c.Size * c.Size)
+	total += abs(percent-50) / 5 * 10
+	return total
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,90 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" at 1-M, from the worked example of the standard.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestDataCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, LevelL, 17},
+		{1, LevelM, 14},
+		{10, LevelM, 213},
+		{40, LevelM, 2331},
+		{40, LevelL, 2953},
+	}
+	for _, tt := range tests {
+		if got := dataCapacity(tt.version, tt.level); got != tt.want {
+			t.Errorf("dataCapacity(%d, %d) = %d, want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	tests := map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	}
+	for version, want := range tests {
+		got := alignmentPositions(version)
+		if len(got) != len(want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", version, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("alignmentPositions(%d) = %v, want %v", version, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	c, err := Encode([]byte("web+stellar:tx?xdr=AAAA"), LevelM)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if c.Version != 2 || c.Size != 25 {
+		t.Errorf("Encode() version %d size %d, want 2 and 25", c.Version, c.Size)
+	}
+	// Finder pattern corners and the always-dark module
+	for _, p := range [][2]int{{0, 0}, {6, 6}, {c.Size - 1, 0}, {0, c.Size - 1}, {8, c.Size - 8}} {
+		if !c.Dark(p[0], p[1]) {
+			t.Errorf("module %v is light, want dark", p)
+		}
+	}
+	if c.Dark(7, 7) {
+		t.Error("separator module (7, 7) is dark, want light")
+	}
+
+	svg := string(c.SVG(4))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, `viewBox="0 0 33 33"`) {
+		t.Errorf("SVG() = %.80s..., want a 33x33 module image", svg)
+	}
+
+	if _, err := Encode(bytes.Repeat([]byte("x"), 2954), LevelL); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode() of 2954 bytes error = %v, want %v", err, ErrTooLong)
+	}
+	if c, err := Encode(bytes.Repeat([]byte("x"), 2953), LevelL); err != nil || c.Version != 40 {
+		t.Errorf("Encode() of 2953 bytes = %v, %v, want version 40", c, err)
+	}
+}
@@ -0,0 +1,330 @@
+// Package s3 is a minimal client for S3-compatible object storage (AWS S3, MinIO,
+// Cloudflare R2, Backblaze B2, ...). Requests are signed with AWS Signature Version 4
+// and use path-style URLs, which every compatible service accepts.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/httpclient"
+)
+
+var (
+	// ErrNotConfigured is returned by NewClient without an endpoint or credentials.
+	ErrNotConfigured = errors.New("S3 endpoint and credentials are required")
+	// ErrInvalidTarget is returned by ParseTarget for anything but s3://bucket[/prefix].
+	ErrInvalidTarget = errors.New("S3 target must look like s3://bucket/prefix")
+	// ErrNotFound is returned by Get for a missing object.
+	ErrNotFound = errors.New("S3 object not found")
+)
+
+// Config holds the endpoint and credentials of an S3-compatible service.
+type Config struct {
+	Endpoint        string // e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Region          string // signing region; "us-east-1" when empty
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Client reads and writes objects of an S3-compatible service.
+type Client struct {
+	endpoint   *url.URL
+	region     string
+	accessKey  string
+	secretKey  string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient creates a client. A nil httpClient uses httpclient.Default().
+func NewClient(cfg Config, httpClient *http.Client) (*Client, error) {
+	if cfg.Endpoint == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, ErrNotConfigured
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
+	return &Client{
+		endpoint:   endpoint,
+		region:     cfg.Region,
+		accessKey:  cfg.AccessKeyID,
+		secretKey:  cfg.SecretAccessKey,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+// Target is a bucket and a key prefix objects are written under.
+type Target struct {
+	Bucket string
+	Prefix string // empty or ending in "/"
+}
+
+// ParseTarget parses s3://bucket or s3://bucket/prefix.
+func ParseTarget(s string) (Target, error) {
+	rest, ok := strings.CutPrefix(s, "s3://")
+	if !ok {
+		return Target{}, ErrInvalidTarget
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return Target{}, ErrInvalidTarget
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return Target{Bucket: bucket, Prefix: prefix}, nil
+}
+
+// Put uploads an object, replacing any object with the same key.
+func (c *Client) Put(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, nil, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.sign(req, body)
+	_, err = c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads an object.
+func (c *Client) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, nil)
+	body, err := c.do(req)
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to get %s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return body, nil
+}
+
+// Delete removes an object; deleting a missing object succeeds.
+func (c *Client) Delete(ctx context.Context, bucket, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	c.sign(req, nil)
+	if _, err := c.do(req); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// listResult is the part of a ListObjectsV2 response List reads.
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the keys of all objects starting with prefix, in key order.
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.newRequest(ctx, http.MethodGet, bucket, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.sign(req, nil)
+		body, err := c.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		var result listResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Bucket returns the objects under a target, with keys relative to its prefix.
+func (c *Client) Bucket(t Target) *Bucket {
+	return &Bucket{client: c, target: t}
+}
+
+// Bucket reads and writes the objects under one bucket and key prefix.
+type Bucket struct {
+	client *Client
+	target Target
+}
+
+// Put uploads an object.
+func (b *Bucket) Put(ctx context.Context, key string, data []byte) error {
+	return b.client.Put(ctx, b.target.Bucket, b.target.Prefix+key, data, "")
+}
+
+// Get downloads an object; a missing object returns ErrNotFound.
+func (b *Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	return b.client.Get(ctx, b.target.Bucket, b.target.Prefix+key)
+}
+
+// Delete removes an object.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	return b.client.Delete(ctx, b.target.Bucket, b.target.Prefix+key)
+}
+
+// List returns the keys starting with prefix, relative to the target's prefix.
+func (b *Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := b.client.List(ctx, b.target.Bucket, b.target.Prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, b.target.Prefix)
+	}
+	return keys, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, query url.Values, body []byte) (*http.Request, error) {
+	u := *c.endpoint
+	u.Path = c.endpoint.Path + "/" + bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return req, nil
+}
+
+// do sends a signed request and returns the response body of a 2xx response.
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &ResponseError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(body))}
+	}
+	return body, nil
+}
+
+// ResponseError is a non-2xx response; Body holds the service's XML error document.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("S3 request failed: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("S3 request failed: %d: %s", e.StatusCode, e.Body)
+}
+
+// sign adds AWS Signature Version 4 headers to a request.
+func (c *Client) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), day)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key, with spaces as %20.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a unit of periodic background work.
+type Job func(ctx context.Context) error
+
+type job struct {
+	name       string
+	interval   time.Duration
+	fn         Job
+	leaderOnly bool
+}
+
+// JobStatus describes the runs of a registered job so far.
+type JobStatus struct {
+	Name         string
+	Interval     time.Duration
+	LastRun      time.Time // Start of the last completed run; zero before the first
+	LastDuration time.Duration
+	LastError    string // Empty when the last run succeeded
+	Runs         int
+	Failures     int
+	LeaderOnly   bool // Skipped while this replica is not the leader
+}
+
+// Scheduler runs registered jobs at fixed intervals until its context is cancelled.
+// Job errors are logged and the job is retried on the next tick.
+type Scheduler struct {
+	logger   *slog.Logger
+	jobs     []job
+	isLeader func() bool // nil: single instance, always the leader
+
+	mu     sync.Mutex
+	status map[string]*JobStatus
+}
+
+// New creates a new scheduler.
+func New(logger *slog.Logger) *Scheduler {
+	if logger == nil {
+		panic("scheduler.New: logger must not be nil")
+	}
+	return &Scheduler{logger: logger, status: make(map[string]*JobStatus)}
+}
+
+// SetLeader sets the leadership check for jobs registered with EveryLeader.
+// Must be called before Run.
+func (s *Scheduler) SetLeader(isLeader func() bool) {
+	s.isLeader = isLeader
+}
+
+// Every registers a job that runs immediately on Run and then every interval.
+// Must be called before Run.
+func (s *Scheduler) Every(name string, interval time.Duration, fn Job) {
+	s.add(job{name: name, interval: interval, fn: fn})
+}
+
+// EveryLeader registers a job like Every, but its runs are skipped while this replica
+// is not the leader. Use it for jobs with side effects outside the process, such as
+// notifications, which must happen once across replicas.
+func (s *Scheduler) EveryLeader(name string, interval time.Duration, fn Job) {
+	s.add(job{name: name, interval: interval, fn: fn, leaderOnly: true})
+}
+
+func (s *Scheduler) add(j job) {
+	s.jobs = append(s.jobs, j)
+
+	s.mu.Lock()
+	s.status[j.name] = &JobStatus{Name: j.name, Interval: j.interval, LeaderOnly: j.leaderOnly}
+	s.mu.Unlock()
+}
+
+// Jobs returns the status of all registered jobs in registration order.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, *s.status[j.name])
+	}
+	return jobs
+}
+
+// record stores the outcome of a run. Runs cut short by shutdown are not recorded.
+func (s *Scheduler) record(name string, start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.status[name]
+	st.LastRun = start
+	st.LastDuration = time.Since(start)
+	st.Runs++
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+		st.Failures++
+	}
+}
+
+// Run starts all jobs and blocks until ctx is cancelled and running jobs have returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+// loop runs a single job on its interval. Ticks are not queued while a run is in progress.
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, j)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	if j.leaderOnly && s.isLeader != nil && !s.isLeader() {
+		return
+	}
+	start := time.Now()
+	err := j.fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	s.record(j.name, start, err)
+	if err != nil {
+		s.logger.Warn("scheduled job failed", "job", j.name, "error", err, "duration", time.Since(start))
+		return
+	}
+	s.logger.Debug("scheduled job completed", "job", j.name, "duration", time.Since(start))
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// positionEpsilon is the net token amount below which a position counts as closed.
+const positionEpsilon = 1e-7
+
+// accountTotals are the counted trades of one account.
+type accountTotals struct {
+	Trades  int                       `json:"trades"`
+	Volume  float64                   `json:"volume"`
+	Markets map[string]*accountMarket `json:"markets"` // contract ID -> trades in that market
+}
+
+// accountMarket sums an account's counted trades in one market.
+type accountMarket struct {
+	Bought float64   `json:"bought"`            // Collateral paid
+	Sold   float64   `json:"sold"`              // Collateral received
+	Yes    float64   `json:"yes"`               // Net YES tokens bought
+	No     float64   `json:"no"`                // Net NO tokens bought
+	First  time.Time `json:"first,omitzero"`    // First counted trade
+	LowYes float64   `json:"low_yes,omitempty"` // Lowest price per token paid for YES, 0 before a buy
+	LowNo  float64   `json:"low_no,omitempty"`  // Lowest price per token paid for NO, 0 before a buy
+}
+
+// account returns the totals of an account, creating them on first use.
+func (f *statsFile) account(address string) *accountTotals {
+	a := f.Accounts[address]
+	if a == nil {
+		a = &accountTotals{Markets: make(map[string]*accountMarket)}
+		f.Accounts[address] = a
+	}
+	return a
+}
+
+// add counts one trade of the account in a market.
+func (a *accountTotals) add(contractID string, evt TradeEvent) {
+	a.Trades++
+	a.Volume += evt.Cost
+	m := a.Markets[contractID]
+	if m == nil {
+		m = &accountMarket{First: evt.Timestamp}
+		a.Markets[contractID] = m
+	}
+	if evt.Timestamp.Before(m.First) {
+		m.First = evt.Timestamp
+	}
+	amount := evt.Amount
+	if evt.Kind == TradeKindSell {
+		m.Sold += evt.Cost
+		amount = -amount
+	} else {
+		m.Bought += evt.Cost
+		if evt.Amount > 0 {
+			low := &m.LowNo
+			if evt.Outcome == "YES" {
+				low = &m.LowYes
+			}
+			if price := evt.Cost / evt.Amount; *low == 0 || price < *low {
+				*low = price
+			}
+		}
+	}
+	if evt.Outcome == "YES" {
+		m.Yes += amount
+	} else {
+		m.No += amount
+	}
+}
+
+// settle returns the realized profit of a position: a resolved market pays its
+// winning tokens less the claim fee, as if claimed, and a position sold off entirely
+// realizes what it was sold for. ok is false while the position is still open.
+func (m *accountMarket) settle(outcome string) (pnl float64, ok bool) {
+	switch outcome {
+	case "YES":
+		return m.Sold + max(m.Yes, 0)*(1-ClaimFeeRate) - m.Bought, true
+	case "NO":
+		return m.Sold + max(m.No, 0)*(1-ClaimFeeRate) - m.Bought, true
+	}
+	if math.Abs(m.Yes) < positionEpsilon && math.Abs(m.No) < positionEpsilon {
+		return m.Sold - m.Bought, true
+	}
+	return 0, false
+}
+
+// recordOutcome remembers the winning outcome of a resolved market, which settles
+// every position in it.
+func (s *StatsService) recordOutcome(state MarketState) {
+	if state.WinningOutcome == "" {
+		return
+	}
+	s.mu.Lock()
+	s.file.Outcomes[state.ContractID] = state.WinningOutcome
+	s.mu.Unlock()
+}
+
+// AccountStats are one account's trading statistics, counted from the trades observed
+// since Since like the platform totals. Positions opened before then are missing, so
+// profit and win rate of long-time traders are approximate.
+type AccountStats struct {
+	Account        string    `json:"account"`
+	Trades         int       `json:"trades"`
+	Volume         float64   `json:"volume"` // collateral, buys and sells
+	Markets        int       `json:"markets"`
+	Settled        int       `json:"settled_positions"` // Positions in resolved markets or sold off entirely
+	Wins           int       `json:"wins"`              // Settled positions with a profit
+	ResolutionWins int       `json:"resolution_wins"`   // Wins in resolved markets
+	WinRate        float64   `json:"win_rate"`          // Wins per settled position, 0 without any
+	RealizedPnL    float64   `json:"realized_pnl"`
+	Since          time.Time `json:"since,omitzero"`
+	UpdatedAt      time.Time `json:"updated_at,omitzero"`
+}
+
+// AccountStats returns the statistics of an account; an account without counted
+// trades has zero totals.
+func (s *StatsService) AccountStats(address string) AccountStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accountStatsLocked(address)
+}
+
+func (s *StatsService) accountStatsLocked(address string) AccountStats {
+	stats := AccountStats{
+		Account:   address,
+		Since:     s.file.Since,
+		UpdatedAt: s.updated,
+	}
+	a := s.file.Accounts[address]
+	if a == nil {
+		return stats
+	}
+	stats.Trades = a.Trades
+	stats.Volume = a.Volume
+	stats.Markets = len(a.Markets)
+	for contractID, m := range a.Markets {
+		outcome := s.file.Outcomes[contractID]
+		pnl, ok := m.settle(outcome)
+		if !ok {
+			continue
+		}
+		stats.Settled++
+		stats.RealizedPnL += pnl
+		if pnl > 0 {
+			stats.Wins++
+			if outcome != "" {
+				stats.ResolutionWins++
+			}
+		}
+	}
+	if stats.Settled > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.Settled)
+	}
+	return stats
+}
+
+// AccountPosition is an account's counted trades in one market.
+type AccountPosition struct {
+	ContractID string
+	Bought     float64 // Collateral paid
+	Sold       float64 // Collateral received
+	Yes        float64 // Net YES tokens bought
+	No         float64 // Net NO tokens bought
+	FirstTrade time.Time
+	Outcome    string  // Winning outcome once the market resolved
+	Settled    bool    // Resolved, or sold off entirely
+	PnL        float64 // Realized profit of a settled position
+	LowYes     float64 // Lowest price per token paid for YES, 0 without a buy
+	LowNo      float64 // Lowest price per token paid for NO, 0 without a buy
+}
+
+// Won reports whether a settled position made a profit.
+func (p AccountPosition) Won() bool {
+	return p.Settled && p.PnL > 0
+}
+
+// AccountPositions returns an account's positions, the most recently entered first.
+func (s *StatsService) AccountPositions(address string) []AccountPosition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accountPositionsLocked(address)
+}
+
+func (s *StatsService) accountPositionsLocked(address string) []AccountPosition {
+	a := s.file.Accounts[address]
+	if a == nil {
+		return nil
+	}
+	positions := make([]AccountPosition, 0, len(a.Markets))
+	for contractID, m := range a.Markets {
+		outcome := s.file.Outcomes[contractID]
+		pnl, settled := m.settle(outcome)
+		positions = append(positions, AccountPosition{
+			ContractID: contractID,
+			Bought:     m.Bought,
+			Sold:       m.Sold,
+			Yes:        m.Yes,
+			No:         m.No,
+			FirstTrade: m.First,
+			Outcome:    outcome,
+			Settled:    settled,
+			PnL:        pnl,
+			LowYes:     m.LowYes,
+			LowNo:      m.LowNo,
+		})
+	}
+	slices.SortFunc(positions, func(a, b AccountPosition) int {
+		return cmp.Or(b.FirstTrade.Compare(a.FirstTrade), cmp.Compare(a.ContractID, b.ContractID))
+	})
+	return positions
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+const (
+	// earlyBettorWindow is how soon after a market's creation a first trade earns the
+	// early bettor achievement.
+	earlyBettorWindow = 24 * time.Hour
+	// upsetPrice is the most a winning outcome may have cost for an upset call.
+	upsetPrice = 0.10
+)
+
+// AchievementInput is what achievement rules see of an account.
+type AchievementInput struct {
+	Stats     AccountStats
+	Positions []AccountPosition
+	CreatedAt map[string]time.Time // Market creation times from metadata, where known
+}
+
+// AchievementRule awards an achievement to accounts whose activity satisfies Earned.
+// Achievements are kept once earned, even if the activity later no longer satisfies
+// the rule, so IDs must stay stable.
+type AchievementRule struct {
+	ID          string
+	Name        string
+	Description string
+	Earned      func(AchievementInput) bool
+}
+
+// AchievementRuleSet is the set of achievements accounts can earn, in display order.
+type AchievementRuleSet []AchievementRule
+
+// DefaultAchievements are the achievements of the platform.
+var DefaultAchievements = AchievementRuleSet{
+	{
+		ID:          "first-trade",
+		Name:        "First trade",
+		Description: "Made a first trade",
+		Earned:      func(in AchievementInput) bool { return in.Stats.Trades > 0 },
+	},
+	{
+		ID:          "early-bettor",
+		Name:        "Early bettor",
+		Description: "Traded within a day of a market's creation",
+		Earned:      earnedEarlyBettor,
+	},
+	{
+		ID:          "ten-markets",
+		Name:        "10 markets",
+		Description: "Traded in 10 markets",
+		Earned:      func(in AchievementInput) bool { return in.Stats.Markets >= 10 },
+	},
+	{
+		ID:          "upset",
+		Name:        "Called the upset",
+		Description: "Bought the winning outcome at 10% or less",
+		Earned:      earnedUpset,
+	},
+	{
+		ID:          "sharpshooter",
+		Name:        "Sharpshooter",
+		Description: "Profitable in at least 5 settled positions, at a 60% win rate or better",
+		Earned: func(in AchievementInput) bool {
+			return in.Stats.Wins >= 5 && in.Stats.WinRate >= 0.6
+		},
+	},
+}
+
+func earnedEarlyBettor(in AchievementInput) bool {
+	for _, p := range in.Positions {
+		created := in.CreatedAt[p.ContractID]
+		if !created.IsZero() && !p.FirstTrade.IsZero() && p.FirstTrade.Sub(created) < earlyBettorWindow {
+			return true
+		}
+	}
+	return false
+}
+
+func earnedUpset(in AchievementInput) bool {
+	for _, p := range in.Positions {
+		var low, held float64
+		switch p.Outcome {
+		case "YES":
+			low, held = p.LowYes, p.Yes
+		case "NO":
+			low, held = p.LowNo, p.No
+		}
+		if held > 0 && low > 0 && low <= upsetPrice {
+			return true
+		}
+	}
+	return false
+}
+
+// EarnedAchievement is an achievement an account earned.
+type EarnedAchievement struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+// SetAchievements replaces the achievement rule set. Call it before the first Refresh.
+func (s *StatsService) SetAchievements(rules AchievementRuleSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.achievements = rules
+}
+
+// awardLocked evaluates the achievement rules for every account with counted trades
+// and records the newly earned ones. It returns how many were awarded.
+func (s *StatsService) awardLocked(now time.Time) int {
+	var awarded int
+	for account := range s.file.Accounts {
+		earned := s.file.Achievements[account]
+		in := AchievementInput{
+			Stats:     s.accountStatsLocked(account),
+			Positions: s.accountPositionsLocked(account),
+			CreatedAt: s.file.Created,
+		}
+		for _, rule := range s.achievements {
+			if _, ok := earned[rule.ID]; ok || !rule.Earned(in) {
+				continue
+			}
+			if earned == nil {
+				earned = make(map[string]time.Time)
+				s.file.Achievements[account] = earned
+			}
+			earned[rule.ID] = now
+			awarded++
+		}
+	}
+	return awarded
+}
+
+// Achievements returns the achievements an account earned, in the order of the rule
+// set. Achievements whose rule was removed are left out.
+func (s *StatsService) Achievements(address string) []EarnedAchievement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	earned := s.file.Achievements[address]
+	var list []EarnedAchievement
+	for _, rule := range s.achievements {
+		if at, ok := earned[rule.ID]; ok {
+			list = append(list, EarnedAchievement{ID: rule.ID, Name: rule.Name, Description: rule.Description, EarnedAt: at})
+		}
+	}
+	return list
+}
+
+// LeaderboardEntry is an account's standing on the leaderboard.
+type LeaderboardEntry struct {
+	Rank         int                 `json:"rank"`
+	Stats        AccountStats        `json:"stats"`
+	Achievements []EarnedAchievement `json:"achievements"`
+}
+
+// Leaderboard ranks accounts by realized profit, then volume, and returns the first
+// limit. Accounts for which skip returns true are left out.
+func (s *StatsService) Leaderboard(limit int, skip func(account string) bool) []LeaderboardEntry {
+	s.mu.RLock()
+	stats := make([]AccountStats, 0, len(s.file.Accounts))
+	for account := range s.file.Accounts {
+		if skip != nil && skip(account) {
+			continue
+		}
+		stats = append(stats, s.accountStatsLocked(account))
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(stats, func(a, b AccountStats) int {
+		return cmp.Or(cmp.Compare(b.RealizedPnL, a.RealizedPnL), cmp.Compare(b.Volume, a.Volume), cmp.Compare(a.Account, b.Account))
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	entries := make([]LeaderboardEntry, len(stats))
+	for i, st := range stats {
+		entries[i] = LeaderboardEntry{Rank: i + 1, Stats: st, Achievements: s.Achievements(st.Account)}
+	}
+	return entries
+}
@@ -0,0 +1,543 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// AlertEvaluateInterval is how often alert rules are checked against market state.
+	AlertEvaluateInterval = time.Minute
+
+	alertMoveWindow      = time.Hour
+	maxAlertsPerTarget   = 20
+	maxAlertsPerClient   = 50 // Rules created from one client IP
+	maxAlertRules        = 5000
+	alertDeliveryTimeout = 10 * time.Second
+	alertDisableFailures = 5                  // Consecutive delivery failures before a rule is paused
+	alertConfirmCooldown = 10 * time.Minute   // between confirmation emails to one address
+	alertUnconfirmedTTL  = 7 * 24 * time.Hour // unconfirmed email alerts are dropped after this
+)
+
+var (
+	ErrInvalidAlertKind      = errors.New("alert type must be above, below or move")
+	ErrInvalidAlertThreshold = errors.New("alert threshold must be between 1 and 99")
+	ErrAlertNotFound         = errors.New("alert not found")
+	ErrTooManyAlerts         = errors.New("too many alerts for this destination or client")
+	ErrAlertConfirmSent      = errors.New("an alert confirmation email was sent recently")
+)
+
+// AlertKind is the condition an alert rule watches.
+type AlertKind string
+
+const (
+	AlertAbove AlertKind = "above" // YES probability rises to or above Threshold
+	AlertBelow AlertKind = "below" // YES probability falls to or below Threshold
+	AlertMove  AlertKind = "move"  // YES probability moves by at least Threshold within an hour
+)
+
+// AlertRule is a user subscription to probability changes of one market.
+// Thresholds are in percentage points (1..99). Email rules wait for the owner to
+// follow a confirmation link before they fire, like email digest subscriptions.
+type AlertRule struct {
+	ID         string         `json:"id"`
+	Token      string         `json:"token"` // Secret that authorizes unsubscribing
+	ContractID string         `json:"contract_id"`
+	Kind       AlertKind      `json:"kind"`
+	Threshold  float64        `json:"threshold"`
+	Channel    notify.Channel `json:"channel"`
+	Target     string         `json:"target"`
+	Client     string         `json:"client,omitempty"` // Hash of the creator's IP, for the per-client cap
+	CreatedAt  time.Time      `json:"created_at"`
+
+	ConfirmToken string `json:"confirm_token,omitempty"` // Set until an email rule is confirmed
+
+	LastPrice float64   `json:"last_price,omitempty"` // YES price at the previous evaluation, 0..1
+	LastFired time.Time `json:"last_fired,omitzero"`
+	Failures  int       `json:"failures,omitempty"` // Consecutive delivery failures
+}
+
+// Confirmed reports whether the owner of the destination confirmed the rule.
+func (r AlertRule) Confirmed() bool {
+	return r.ConfirmToken == ""
+}
+
+// Paused reports whether delivery kept failing and the rule no longer fires.
+func (r AlertRule) Paused() bool {
+	return r.Failures >= alertDisableFailures
+}
+
+// CreateAlertRequest contains parameters for subscribing to a market alert.
+type CreateAlertRequest struct {
+	ContractID string
+	Kind       AlertKind
+	Threshold  float64
+	Channel    notify.Channel
+	Target     string
+	ClientIP   string // Creator's IP; rules per IP are capped. Empty skips the cap
+}
+
+// Validate validates the create alert request.
+func (r *CreateAlertRequest) Validate() error {
+	if err := soroban.ValidateContractID(r.ContractID); err != nil {
+		return err
+	}
+	switch r.Kind {
+	case AlertAbove, AlertBelow, AlertMove:
+	default:
+		return ErrInvalidAlertKind
+	}
+	if r.Threshold < 1 || r.Threshold > 99 || math.IsNaN(r.Threshold) {
+		return ErrInvalidAlertThreshold
+	}
+	return notify.ValidateTarget(r.Channel, r.Target)
+}
+
+// priceSample is an observed YES price, kept for move detection.
+type priceSample struct {
+	at    time.Time
+	price float64
+}
+
+// AlertService stores alert rules and evaluates them against the market state cache.
+// Rules are kept in memory, optionally persisted to a JSON file; recent price samples
+// for move alerts are memory-only and rebuilt within an hour after a restart.
+type AlertService struct {
+	factoryService *FactoryService
+	dispatcher     *notify.Dispatcher
+	publicURL      string // Base URL for market links in messages; empty omits links
+	path           string // Empty keeps rules in memory only
+	logger         *slog.Logger
+
+	mu      sync.Mutex
+	rules   map[string]*AlertRule
+	samples map[string][]priceSample // contract ID -> samples within alertMoveWindow
+}
+
+// NewAlertService creates an alert service and loads existing rules from path, if set.
+func NewAlertService(factoryService *FactoryService, dispatcher *notify.Dispatcher, publicURL, path string, logger *slog.Logger) (*AlertService, error) {
+	if factoryService == nil {
+		panic("NewAlertService: factoryService must not be nil")
+	}
+	if dispatcher == nil {
+		panic("NewAlertService: dispatcher must not be nil")
+	}
+	if logger == nil {
+		panic("NewAlertService: logger must not be nil")
+	}
+
+	s := &AlertService{
+		factoryService: factoryService,
+		dispatcher:     dispatcher,
+		publicURL:      publicURL,
+		path:           path,
+		logger:         logger,
+		rules:          make(map[string]*AlertRule),
+		samples:        make(map[string][]priceSample),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var rules []*AlertRule
+	if _, err := readJSONFile(path, &rules); err != nil {
+		return nil, fmt.Errorf("failed to load alerts file: %w", err)
+	}
+	for _, r := range rules {
+		s.rules[r.ID] = r
+	}
+	return s, nil
+}
+
+// ChannelEnabled reports whether alerts can be delivered over ch. Email alerts
+// need PUBLIC_URL for their confirmation links.
+func (s *AlertService) ChannelEnabled(ch notify.Channel) bool {
+	if ch == notify.ChannelEmail && s.publicURL == "" {
+		return false
+	}
+	return s.dispatcher.Enabled(ch)
+}
+
+// Channels returns the channels alerts can be delivered over.
+func (s *AlertService) Channels() []notify.Channel {
+	return slices.DeleteFunc(s.dispatcher.Channels(), func(ch notify.Channel) bool {
+		return !s.ChannelEnabled(ch)
+	})
+}
+
+// Create adds an alert rule and returns it, including the unsubscribe token.
+// Email rules are emailed a confirmation link and do not fire until it is followed.
+func (s *AlertService) Create(ctx context.Context, req CreateAlertRequest) (AlertRule, error) {
+	if err := req.Validate(); err != nil {
+		return AlertRule{}, err
+	}
+	if !s.ChannelEnabled(req.Channel) {
+		return AlertRule{}, notify.ErrChannelDisabled
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return AlertRule{}, err
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return AlertRule{}, err
+	}
+	var confirmToken string
+	if req.Channel == notify.ChannelEmail {
+		if confirmToken, err = randomHex(16); err != nil {
+			return AlertRule{}, err
+		}
+	}
+
+	s.mu.Lock()
+	if len(s.rules) >= maxAlertRules {
+		s.mu.Unlock()
+		return AlertRule{}, ErrTooManyAlerts
+	}
+	now := time.Now().UTC()
+	client := clientKey(req.ClientIP)
+	perTarget, perClient := 0, 0
+	for _, r := range s.rules {
+		if client != "" && r.Client == client {
+			perClient++
+		}
+		if r.Channel != req.Channel || r.Target != req.Target {
+			continue
+		}
+		perTarget++
+		if confirmToken != "" && !r.Confirmed() && now.Sub(r.CreatedAt) < alertConfirmCooldown {
+			s.mu.Unlock()
+			return AlertRule{}, ErrAlertConfirmSent
+		}
+	}
+	if perTarget >= maxAlertsPerTarget || perClient >= maxAlertsPerClient {
+		s.mu.Unlock()
+		return AlertRule{}, ErrTooManyAlerts
+	}
+
+	rule := &AlertRule{
+		ID:           id,
+		Token:        token,
+		ContractID:   req.ContractID,
+		Kind:         req.Kind,
+		Threshold:    req.Threshold,
+		Channel:      req.Channel,
+		Target:       req.Target,
+		Client:       client,
+		CreatedAt:    now,
+		ConfirmToken: confirmToken,
+	}
+	s.rules[id] = rule
+	if err := s.saveLocked(); err != nil {
+		delete(s.rules, id)
+		s.mu.Unlock()
+		return AlertRule{}, err
+	}
+	created := *rule
+	s.mu.Unlock()
+
+	if confirmToken == "" {
+		return created, nil
+	}
+	msg := notify.Message{
+		Title: "Confirm your MTL Predict alert",
+		Text: "Confirm the price alert on market " + req.ContractID[:8] + " to start receiving it." +
+			"\n\nIf you did not ask for this, ignore this email and nothing will be sent.",
+		URL: s.publicURL + "/alerts/" + id + "/confirm?token=" + confirmToken,
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, alertDeliveryTimeout)
+	defer cancel()
+	if err := s.dispatcher.Send(sendCtx, notify.ChannelEmail, req.Target, msg); err != nil {
+		// Drop the rule so the sign-up can be retried without waiting for the cooldown.
+		s.mu.Lock()
+		delete(s.rules, id)
+		if saveErr := s.saveLocked(); saveErr != nil {
+			s.logger.Warn("failed to remove unconfirmable alert", "alert_id", id, "error", saveErr)
+		}
+		s.mu.Unlock()
+		return AlertRule{}, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+	return created, nil
+}
+
+// Confirm activates an email alert rule with the token from its confirmation email.
+func (s *AlertService) Confirm(id, token string) (AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[id]
+	if !ok || rule.Confirmed() || subtle.ConstantTimeCompare([]byte(rule.ConfirmToken), []byte(token)) != 1 {
+		return AlertRule{}, ErrAlertNotFound
+	}
+	rule.ConfirmToken = ""
+	if err := s.saveLocked(); err != nil {
+		return AlertRule{}, err
+	}
+	return *rule, nil
+}
+
+// Delete removes an alert rule. The token must match the one issued on creation.
+func (s *AlertService) Delete(id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(rule.Token), []byte(token)) != 1 {
+		return ErrAlertNotFound
+	}
+	delete(s.rules, id)
+	return s.saveLocked()
+}
+
+// Count returns the number of alert rules.
+func (s *AlertService) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rules)
+}
+
+// alertNotification is a message due for delivery, produced while holding mu.
+type alertNotification struct {
+	ruleID  string
+	channel notify.Channel
+	target  string
+	msg     notify.Message
+}
+
+// Evaluate checks all rules against current market prices and dispatches triggered alerts.
+func (s *AlertService) Evaluate(ctx context.Context) error {
+	s.mu.Lock()
+	s.dropUnconfirmedLocked(time.Now())
+	var ids []string
+	for _, r := range s.rules {
+		if r.Confirmed() && !slices.Contains(ids, r.ContractID) {
+			ids = append(ids, r.ContractID)
+		}
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	loaded, stateErr := s.factoryService.GetMarketStates(ctx, ids)
+	if stateErr != nil {
+		return fmt.Errorf("failed to get market states: %w", stateErr)
+	}
+	states := loaded.States
+
+	now := time.Now()
+	due := s.collect(states, now)
+
+	var errs []error
+	for _, n := range due {
+		sendCtx, cancel := context.WithTimeout(ctx, alertDeliveryTimeout)
+		err := s.dispatcher.Send(sendCtx, n.channel, n.target, n.msg)
+		cancel()
+		s.recordDelivery(n.ruleID, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", n.ruleID, err))
+		}
+	}
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+
+	if stateErr != nil {
+		s.logger.Warn("alerts: failed to get some market states", "error", stateErr)
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyMarket sends msg to every destination subscribed to alerts on the market,
+// once per destination, with the unsubscribe link of one of its rules. Paused and
+// unconfirmed rules are skipped.
+func (s *AlertService) NotifyMarket(ctx context.Context, contractID string, msg notify.Message) error {
+	type destination struct {
+		channel notify.Channel
+		target  string
+	}
+	s.mu.Lock()
+	rules := make(map[destination]AlertRule)
+	for _, r := range s.rules {
+		d := destination{r.Channel, r.Target}
+		if _, seen := rules[d]; r.ContractID == contractID && r.Confirmed() && !r.Paused() && !seen {
+			rules[d] = *r
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for d, r := range rules {
+		m := msg
+		if s.publicURL != "" {
+			m.Text += "\nUnsubscribe: " + s.publicURL + "/alerts/" + r.ID + "/unsubscribe?token=" + r.Token
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, alertDeliveryTimeout)
+		err := s.dispatcher.Send(sendCtx, d.channel, d.target, m)
+		cancel()
+		s.recordDelivery(r.ID, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", r.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collect records new price samples and returns the notifications of triggered rules.
+func (s *AlertService) collect(states []MarketState, now time.Time) []alertNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prices := make(map[string]float64, len(states))
+	for _, st := range states {
+		if st.Resolved {
+			continue
+		}
+		prices[st.ContractID] = st.PriceYes
+		samples := append(s.samples[st.ContractID], priceSample{at: now, price: st.PriceYes})
+		cutoff := now.Add(-alertMoveWindow)
+		samples = slices.DeleteFunc(samples, func(p priceSample) bool { return p.at.Before(cutoff) })
+		s.samples[st.ContractID] = samples
+	}
+
+	var due []alertNotification
+	for _, r := range s.rules {
+		price, ok := prices[r.ContractID]
+		if !ok || !r.Confirmed() || r.Paused() {
+			continue
+		}
+		prev := r.LastPrice
+		r.LastPrice = price
+		threshold := r.Threshold / 100
+
+		var text string
+		switch r.Kind {
+		case AlertAbove:
+			if prev > 0 && prev < threshold && price >= threshold {
+				text = fmt.Sprintf("YES probability rose above %.0f%%: now %.1f%% (was %.1f%%).", r.Threshold, price*100, prev*100)
+			}
+		case AlertBelow:
+			if prev > 0 && prev > threshold && price <= threshold {
+				text = fmt.Sprintf("YES probability fell below %.0f%%: now %.1f%% (was %.1f%%).", r.Threshold, price*100, prev*100)
+			}
+		case AlertMove:
+			// One alert per window, so a sustained move is not reported every minute.
+			if now.Sub(r.LastFired) < alertMoveWindow {
+				continue
+			}
+			samples := s.samples[r.ContractID]
+			if len(samples) == 0 {
+				continue
+			}
+			oldest := samples[0]
+			if delta := price - oldest.price; math.Abs(delta) >= threshold {
+				text = fmt.Sprintf("YES probability moved %+.1f points in %s: now %.1f%%.", delta*100, now.Sub(oldest.at).Round(time.Minute), price*100)
+			}
+		}
+		if text == "" {
+			continue
+		}
+
+		if s.publicURL != "" {
+			text += "\nUnsubscribe: " + s.publicURL + "/alerts/" + r.ID + "/unsubscribe?token=" + r.Token
+		}
+		r.LastFired = now.UTC()
+		due = append(due, alertNotification{
+			ruleID:  r.ID,
+			channel: r.Channel,
+			target:  r.Target,
+			msg: notify.Message{
+				Title: "Market alert " + r.ContractID[:8],
+				Text:  text,
+				URL:   s.marketURL(r.ContractID),
+			},
+		})
+	}
+	return due
+}
+
+// dropUnconfirmedLocked removes email rules whose confirmation link was never
+// followed. Must be called with mu held.
+func (s *AlertService) dropUnconfirmedLocked(now time.Time) {
+	for id, r := range s.rules {
+		if !r.Confirmed() && now.Sub(r.CreatedAt) > alertUnconfirmedTTL {
+			delete(s.rules, id)
+		}
+	}
+}
+
+// recordDelivery tracks consecutive delivery failures; failing rules are paused.
+func (s *AlertService) recordDelivery(ruleID string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[ruleID]
+	if !ok {
+		return
+	}
+	if err == nil {
+		rule.Failures = 0
+		return
+	}
+	rule.Failures++
+	if rule.Paused() {
+		s.logger.Warn("alert paused after repeated delivery failures", "alert_id", ruleID, "channel", rule.Channel, "error", err)
+	}
+}
+
+func (s *AlertService) marketURL(contractID string) string {
+	if s.publicURL == "" {
+		return ""
+	}
+	return s.publicURL + "/market/" + contractID
+}
+
+// saveLocked writes all rules to the file atomically. Must be called with mu held.
+func (s *AlertService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	rules := make([]*AlertRule, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, r)
+	}
+	slices.SortFunc(rules, func(a, b *AlertRule) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	if err := writeJSONFile(s.path, rules); err != nil {
+		return fmt.Errorf("failed to save alerts: %w", err)
+	}
+	return nil
+}
+
+// clientKey hashes a client IP, so the alerts file does not store addresses.
+func clientKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("alert-client:" + ip))
+	return hex.EncodeToString(sum[:8])
+}
+
+// randomHex returns n random bytes, hex-encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/notify"
+)
+
+func TestAlertServiceCollect(t *testing.T) {
+	s := &AlertService{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		samples: make(map[string][]priceSample),
+		rules: map[string]*AlertRule{
+			"above": {ID: "above", ContractID: testContractID, Kind: AlertAbove, Threshold: 60, Channel: notify.ChannelWebhook},
+			"below": {ID: "below", ContractID: testContractID, Kind: AlertBelow, Threshold: 40, Channel: notify.ChannelWebhook},
+			"move":  {ID: "move", ContractID: testContractID, Kind: AlertMove, Threshold: 10, Channel: notify.ChannelWebhook},
+		},
+	}
+	start := time.Unix(1_700_000_000, 0)
+	state := func(price float64) []MarketState {
+		return []MarketState{{ContractID: testContractID, PriceYes: price}}
+	}
+
+	steps := []struct {
+		at    time.Duration
+		price float64
+		want  []string
+	}{
+		{0, 0.50, nil},           // First observation only records the price
+		{time.Minute, 0.58, nil}, // No crossing, moved 8 points
+		{2 * time.Minute, 0.61, []string{"above", "move"}},
+		{3 * time.Minute, 0.65, nil}, // Still above: no repeat; move already fired this hour
+		{4 * time.Minute, 0.55, nil}, // Dropping back below 60 is not a rise
+		{5 * time.Minute, 0.62, []string{"above"}},
+		{6 * time.Minute, 0.39, []string{"below"}},
+	}
+
+	for _, step := range steps {
+		due := s.collect(state(step.price), start.Add(step.at))
+		var got []string
+		for _, n := range due {
+			got = append(got, n.ruleID)
+		}
+		if len(got) != len(step.want) {
+			t.Fatalf("at %v price %.2f: fired %v, want %v", step.at, step.price, got, step.want)
+		}
+		for _, id := range step.want {
+			found := false
+			for _, g := range got {
+				found = found || g == id
+			}
+			if !found {
+				t.Errorf("at %v price %.2f: fired %v, want %v", step.at, step.price, got, step.want)
+			}
+		}
+	}
+}
+
+func TestCreateAlertRequestValidate(t *testing.T) {
+	valid := CreateAlertRequest{ContractID: testContractID, Kind: AlertAbove, Threshold: 60, Channel: notify.ChannelWebhook, Target: "https://example.com/hook"}
+
+	tests := []struct {
+		name    string
+		modify  func(*CreateAlertRequest)
+		wantErr bool
+	}{
+		{"valid", func(*CreateAlertRequest) {}, false},
+		{"unknown kind", func(r *CreateAlertRequest) { r.Kind = "sideways" }, true},
+		{"threshold too low", func(r *CreateAlertRequest) { r.Threshold = 0 }, true},
+		{"threshold too high", func(r *CreateAlertRequest) { r.Threshold = 100 }, true},
+		{"bad target", func(r *CreateAlertRequest) { r.Target = "ftp://example.com" }, true},
+		{"bad contract", func(r *CreateAlertRequest) { r.ContractID = "C123" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlertEmailConfirm(t *testing.T) {
+	transport := &recordingTransport{}
+	s := &AlertService{
+		dispatcher: notify.NewDispatcher(transport),
+		publicURL:  "https://predict.example.org",
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rules:      make(map[string]*AlertRule),
+		samples:    make(map[string][]priceSample),
+	}
+	ctx := context.Background()
+	req := CreateAlertRequest{ContractID: testContractID, Kind: AlertAbove, Threshold: 60, Channel: notify.ChannelEmail, Target: "alice@example.com"}
+
+	rule, err := s.Create(ctx, req)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if rule.Confirmed() || len(transport.sent) != 1 {
+		t.Fatalf("Create() confirmed = %v, sent %d emails; want unconfirmed with 1 confirmation", rule.Confirmed(), len(transport.sent))
+	}
+	if _, err := s.Create(ctx, req); !errors.Is(err, ErrAlertConfirmSent) {
+		t.Errorf("second Create() error = %v, want ErrAlertConfirmSent", err)
+	}
+
+	state := func(price float64) []MarketState {
+		return []MarketState{{ContractID: testContractID, PriceYes: price}}
+	}
+	start := time.Unix(1_700_000_000, 0)
+	s.collect(state(0.5), start)
+	if due := s.collect(state(0.7), start.Add(time.Minute)); len(due) != 0 {
+		t.Errorf("unconfirmed rule fired %d alerts", len(due))
+	}
+
+	link := transport.sent[0].URL
+	if !strings.HasPrefix(link, "https://predict.example.org/alerts/"+rule.ID+"/confirm?token=") {
+		t.Fatalf("confirmation link = %q", link)
+	}
+	token := link[strings.Index(link, "token=")+len("token="):]
+	if _, err := s.Confirm(rule.ID, rule.Token); !errors.Is(err, ErrAlertNotFound) {
+		t.Error("Confirm() accepted the unsubscribe token")
+	}
+	if _, err := s.Confirm(rule.ID, token); err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if _, err := s.Confirm(rule.ID, token); !errors.Is(err, ErrAlertNotFound) {
+		t.Error("confirmation token should be single-use")
+	}
+
+	s.collect(state(0.5), start.Add(2*time.Minute))
+	if due := s.collect(state(0.7), start.Add(3*time.Minute)); len(due) != 1 {
+		t.Errorf("confirmed rule fired %d alerts, want 1", len(due))
+	}
+}
+
+func TestAlertEmailNeedsPublicURL(t *testing.T) {
+	s := &AlertService{
+		dispatcher: notify.NewDispatcher(&recordingTransport{}),
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rules:      make(map[string]*AlertRule),
+	}
+	if len(s.Channels()) != 0 {
+		t.Errorf("Channels() = %v, want email hidden without a public URL", s.Channels())
+	}
+	req := CreateAlertRequest{ContractID: testContractID, Kind: AlertAbove, Threshold: 60, Channel: notify.ChannelEmail, Target: "alice@example.com"}
+	if _, err := s.Create(context.Background(), req); !errors.Is(err, notify.ErrChannelDisabled) {
+		t.Errorf("Create() error = %v, want ErrChannelDisabled", err)
+	}
+}
+
+func TestAlertClientCap(t *testing.T) {
+	s := &AlertService{
+		dispatcher: notify.NewDispatcher(notify.NewWebhook(nil)),
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rules:      make(map[string]*AlertRule),
+	}
+	ctx := context.Background()
+	req := CreateAlertRequest{ContractID: testContractID, Kind: AlertAbove, Threshold: 60, Channel: notify.ChannelWebhook, ClientIP: "198.51.100.7"}
+
+	// Spread over destinations, so only the per-client cap applies.
+	for i := range maxAlertsPerClient {
+		req.Target = fmt.Sprintf("https://example.com/hook/%d", i)
+		if _, err := s.Create(ctx, req); err != nil {
+			t.Fatalf("Create() #%d error = %v", i, err)
+		}
+	}
+	req.Target = "https://example.com/hook/last"
+	if _, err := s.Create(ctx, req); !errors.Is(err, ErrTooManyAlerts) {
+		t.Errorf("Create() over the client cap error = %v, want ErrTooManyAlerts", err)
+	}
+	req.ClientIP = "198.51.100.8"
+	if _, err := s.Create(ctx, req); err != nil {
+		t.Errorf("Create() from another client error = %v", err)
+	}
+	for _, r := range s.rules {
+		if strings.Contains(r.Client, "198.51") {
+			t.Fatalf("rule stores the client IP %q", r.Client)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/notify"
+)
+
+// AnomalyCheckInterval is how often the background scheduler scans trades for
+// suspicious patterns.
+const AnomalyCheckInterval = 5 * time.Minute
+
+// anomalyRetention is how long a flagged anomaly stays on the dashboard.
+const anomalyRetention = 7 * 24 * time.Hour
+
+// anomalyDeliveryTimeout bounds one anomaly notification.
+const anomalyDeliveryTimeout = 15 * time.Second
+
+// AnomalyKind is the kind of suspicious trading pattern.
+type AnomalyKind string
+
+const (
+	AnomalyVolumeSpike AnomalyKind = "volume spike"
+	AnomalyPriceSwing  AnomalyKind = "price swing"
+	AnomalyWashTrading AnomalyKind = "wash trading"
+)
+
+// AnomalyKinds lists every kind of anomaly the monitor looks for.
+var AnomalyKinds = []AnomalyKind{AnomalyVolumeSpike, AnomalyPriceSwing, AnomalyWashTrading}
+
+// AnomalyThresholds configures what counts as suspicious. Zero fields disable the
+// check they belong to.
+type AnomalyThresholds struct {
+	VolumeWindow time.Duration // Window one address's volume is summed over
+	VolumeShare  float64       // Share of the market's recent volume one address may trade in a window
+	MinVolume    float64       // Collateral an address must trade in a window to count as a spike
+	SwingWindow  time.Duration // Window a probability swing must happen within
+	SwingPoints  float64       // YES probability change, 0-1, that counts as a swing
+	LoopWindow   time.Duration // Window buy/sell round trips are counted over
+	LoopTrades   int           // Round trips of one address on one outcome that look like wash trading
+}
+
+// DefaultAnomalyThresholds are the thresholds used when none are configured.
+var DefaultAnomalyThresholds = AnomalyThresholds{
+	VolumeWindow: time.Hour,
+	VolumeShare:  0.5,
+	MinVolume:    100,
+	SwingWindow:  10 * time.Minute,
+	SwingPoints:  0.2,
+	LoopWindow:   time.Hour,
+	LoopTrades:   3,
+}
+
+// Anomaly is a suspicious trading pattern found in a market.
+type Anomaly struct {
+	ContractID string
+	Kind       AnomalyKind
+	Address    string    // Trader responsible; empty for price swings
+	Detail     string    // What was seen, for the oracle
+	At         time.Time // Start of the window the pattern was seen in
+	Detected   time.Time
+}
+
+// key identifies an anomaly, so one pattern is reported once however often it is seen.
+func (a Anomaly) key() string {
+	return a.ContractID + "|" + string(a.Kind) + "|" + a.Address + "|" + a.At.UTC().Format(time.RFC3339)
+}
+
+// FlaggedMarket is a market with recent anomalies.
+type FlaggedMarket struct {
+	ContractID string
+	Anomalies  []Anomaly // Newest first
+}
+
+// AnomalyMonitor scans the trade events of open markets for single-address volume
+// spikes, fast probability swings and wash-like buy/sell loops. New anomalies are sent
+// to the oracle's notification target and kept, in memory, for the admin dashboard.
+type AnomalyMonitor struct {
+	factoryService *FactoryService
+	eventService   *EventService
+	dispatcher     *notify.Dispatcher
+	channel        notify.Channel
+	target         string
+	publicURL      string
+	thresholds     AnomalyThresholds
+	logger         *slog.Logger
+
+	mu        sync.Mutex
+	anomalies map[string]Anomaly
+	lastRun   time.Time
+}
+
+// NewAnomalyMonitor creates a monitor. notifyTarget is "channel:target", e.g.
+// "telegram:123456789"; empty only flags anomalies on the dashboard.
+func NewAnomalyMonitor(
+	factoryService *FactoryService,
+	eventService *EventService,
+	dispatcher *notify.Dispatcher,
+	notifyTarget string,
+	publicURL string,
+	thresholds AnomalyThresholds,
+	logger *slog.Logger,
+) (*AnomalyMonitor, error) {
+	if factoryService == nil {
+		panic("NewAnomalyMonitor: factoryService must not be nil")
+	}
+	if eventService == nil {
+		panic("NewAnomalyMonitor: eventService must not be nil")
+	}
+	if dispatcher == nil {
+		panic("NewAnomalyMonitor: dispatcher must not be nil")
+	}
+	if logger == nil {
+		panic("NewAnomalyMonitor: logger must not be nil")
+	}
+	m := &AnomalyMonitor{
+		factoryService: factoryService,
+		eventService:   eventService,
+		dispatcher:     dispatcher,
+		publicURL:      publicURL,
+		thresholds:     thresholds,
+		logger:         logger,
+		anomalies:      make(map[string]Anomaly),
+	}
+	if notifyTarget != "" {
+		ch, target, ok := strings.Cut(notifyTarget, ":")
+		if !ok {
+			return nil, fmt.Errorf("anomaly notification target %q must be channel:target", notifyTarget)
+		}
+		m.channel = notify.Channel(strings.ToLower(ch))
+		m.target = target
+		if err := dispatcher.ValidateTarget(m.channel, m.target); err != nil {
+			return nil, fmt.Errorf("invalid anomaly notification target: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Refresh scans the trades of every open market, records new anomalies and notifies
+// the oracle of them. Markets whose trades cannot be loaded are skipped and reported.
+func (m *AnomalyMonitor) Refresh(ctx context.Context) error {
+	if !m.factoryService.HasFactory() {
+		return nil
+	}
+	ids, err := m.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := m.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get market states: %w", err)
+	}
+
+	var errs []error
+	var found []Anomaly
+	now := time.Now()
+	for _, state := range loaded.States {
+		if state.Resolved {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		events, err := m.eventService.GetTradeEvents(ctx, state.ContractID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			continue
+		}
+		found = append(found, detectAnomalies(state.ContractID, events, m.thresholds)...)
+	}
+
+	fresh := m.record(found, now)
+	for _, a := range fresh {
+		m.logger.Warn("trade anomaly detected",
+			"contract_id", a.ContractID,
+			"kind", a.Kind,
+			"address", a.Address,
+			"detail", a.Detail,
+		)
+		if err := m.notify(ctx, a); err != nil {
+			errs = append(errs, fmt.Errorf("anomaly notification for %s: %w", a.ContractID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record stores anomalies not seen before, drops those past anomalyRetention and
+// returns the new ones.
+func (m *AnomalyMonitor) record(found []Anomaly, now time.Time) []Anomaly {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var fresh []Anomaly
+	for _, a := range found {
+		key := a.key()
+		if _, ok := m.anomalies[key]; ok {
+			continue
+		}
+		a.Detected = now
+		m.anomalies[key] = a
+		fresh = append(fresh, a)
+	}
+	for key, a := range m.anomalies {
+		if now.Sub(a.Detected) > anomalyRetention {
+			delete(m.anomalies, key)
+		}
+	}
+	m.lastRun = now
+	return fresh
+}
+
+func (m *AnomalyMonitor) notify(ctx context.Context, a Anomaly) error {
+	if m.target == "" || !m.dispatcher.Enabled(m.channel) {
+		return nil
+	}
+	msg := notify.Message{
+		Title: "Suspicious trading: " + string(a.Kind),
+		Text:  fmt.Sprintf("Market %s\n%s", a.ContractID, a.Detail),
+	}
+	if m.publicURL != "" {
+		msg.URL = m.publicURL + "/market/" + a.ContractID
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, anomalyDeliveryTimeout)
+	defer cancel()
+	return m.dispatcher.Send(sendCtx, m.channel, m.target, msg)
+}
+
+// Flagged returns the markets with anomalies, most recently detected first.
+func (m *AnomalyMonitor) Flagged() []FlaggedMarket {
+	m.mu.Lock()
+	byMarket := make(map[string][]Anomaly)
+	for _, a := range m.anomalies {
+		byMarket[a.ContractID] = append(byMarket[a.ContractID], a)
+	}
+	m.mu.Unlock()
+
+	markets := make([]FlaggedMarket, 0, len(byMarket))
+	for id, anomalies := range byMarket {
+		slices.SortFunc(anomalies, func(a, b Anomaly) int {
+			return cmp.Or(b.Detected.Compare(a.Detected), b.At.Compare(a.At), cmp.Compare(a.Kind, b.Kind))
+		})
+		markets = append(markets, FlaggedMarket{ContractID: id, Anomalies: anomalies})
+	}
+	slices.SortFunc(markets, func(a, b FlaggedMarket) int {
+		return cmp.Or(b.Anomalies[0].Detected.Compare(a.Anomalies[0].Detected), cmp.Compare(a.ContractID, b.ContractID))
+	})
+	return markets
+}
+
+// Counts returns the number of kept anomalies by kind.
+func (m *AnomalyMonitor) Counts() map[AnomalyKind]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[AnomalyKind]int)
+	for _, a := range m.anomalies {
+		counts[a.Kind]++
+	}
+	return counts
+}
+
+// LastRun returns when Refresh last completed its scan; zero before the first.
+func (m *AnomalyMonitor) LastRun() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastRun
+}
+
+// NotifyTarget returns the channel and target anomalies are sent to, if any.
+func (m *AnomalyMonitor) NotifyTarget() (notify.Channel, string) {
+	return m.channel, m.target
+}
+
+// detectAnomalies runs every enabled check over a market's trade events.
+func detectAnomalies(contractID string, events []TradeEvent, t AnomalyThresholds) []Anomaly {
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b TradeEvent) int { return cmp.Compare(a.Ledger, b.Ledger) })
+	var found []Anomaly
+	found = append(found, detectVolumeSpikes(contractID, events, t)...)
+	found = append(found, detectPriceSwings(contractID, events, t)...)
+	found = append(found, detectWashTrading(contractID, events, t)...)
+	return found
+}
+
+// detectVolumeSpikes flags addresses that traded at least t.MinVolume within one
+// t.VolumeWindow and at least t.VolumeShare of all the market's volume in events.
+func detectVolumeSpikes(contractID string, events []TradeEvent, t AnomalyThresholds) []Anomaly {
+	if t.VolumeWindow <= 0 || t.VolumeShare <= 0 {
+		return nil
+	}
+	type bucket struct {
+		address string
+		start   time.Time
+	}
+	var total float64
+	volumes := make(map[bucket]float64)
+	for _, evt := range events {
+		total += evt.Cost
+		volumes[bucket{evt.User, evt.Timestamp.Truncate(t.VolumeWindow)}] += evt.Cost
+	}
+	if total <= 0 {
+		return nil
+	}
+	var found []Anomaly
+	for b, volume := range volumes {
+		share := volume / total
+		if volume < t.MinVolume || share < t.VolumeShare {
+			continue
+		}
+		found = append(found, Anomaly{
+			ContractID: contractID,
+			Kind:       AnomalyVolumeSpike,
+			Address:    b.address,
+			Detail: fmt.Sprintf("%s traded %.2f of %.2f recent volume (%.0f%%) within %s",
+				b.address, volume, total, share*100, t.VolumeWindow),
+			At: b.start,
+		})
+	}
+	return found
+}
+
+// impliedYesPrice returns the average YES price a trade executed at: its cost or
+// return per token, complemented for NO trades. Unlike quantities sold, it needs no
+// liquidity parameter to reflect the market's probability.
+func impliedYesPrice(evt TradeEvent) (float64, bool) {
+	if evt.Amount <= 0 || evt.Cost <= 0 {
+		return 0, false
+	}
+	price := min(evt.Cost/evt.Amount, 1)
+	if evt.Outcome == "NO" {
+		price = 1 - price
+	}
+	return price, true
+}
+
+// detectPriceSwings flags the implied YES probability moving by at least
+// t.SwingPoints between trades within t.SwingWindow. A swing is reported once per
+// window.
+func detectPriceSwings(contractID string, events []TradeEvent, t AnomalyThresholds) []Anomaly {
+	if t.SwingWindow <= 0 || t.SwingPoints <= 0 {
+		return nil
+	}
+	type point struct {
+		at    time.Time
+		price float64
+	}
+	points := make([]point, 0, len(events))
+	for _, evt := range events {
+		if price, ok := impliedYesPrice(evt); ok {
+			points = append(points, point{evt.Timestamp, price})
+		}
+	}
+
+	reported := make(map[time.Time]bool)
+	var found []Anomaly
+	for i, from := range points {
+		start := from.at.Truncate(t.SwingWindow)
+		if reported[start] {
+			continue
+		}
+		for j := i + 1; j < len(points) && points[j].at.Sub(from.at) <= t.SwingWindow; j++ {
+			to := points[j]
+			if math.Abs(to.price-from.price) < t.SwingPoints {
+				continue
+			}
+			reported[start] = true
+			found = append(found, Anomaly{
+				ContractID: contractID,
+				Kind:       AnomalyPriceSwing,
+				Detail: fmt.Sprintf("YES probability moved from %.0f%% to %.0f%% in %s over %d trades",
+					from.price*100, to.price*100, to.at.Sub(from.at).Round(time.Second), j-i+1),
+				At: start,
+			})
+			break
+		}
+	}
+	return found
+}
+
+// detectWashTrading flags addresses that bought an outcome and sold it back at least
+// t.LoopTrades times within one t.LoopWindow.
+func detectWashTrading(contractID string, events []TradeEvent, t AnomalyThresholds) []Anomaly {
+	if t.LoopWindow <= 0 || t.LoopTrades <= 0 {
+		return nil
+	}
+	type bucket struct {
+		address string
+		start   time.Time
+	}
+	type loops struct {
+		open   map[string]int // Buys by outcome not yet followed by a sell
+		trips  int
+		volume float64
+	}
+	counts := make(map[bucket]*loops)
+	for _, evt := range events {
+		b := bucket{evt.User, evt.Timestamp.Truncate(t.LoopWindow)}
+		l := counts[b]
+		if l == nil {
+			l = &loops{open: make(map[string]int)}
+			counts[b] = l
+		}
+		l.volume += evt.Cost
+		switch evt.Kind {
+		case TradeKindBuy:
+			l.open[evt.Outcome]++
+		case TradeKindSell:
+			if l.open[evt.Outcome] > 0 {
+				l.open[evt.Outcome]--
+				l.trips++
+			}
+		}
+	}
+	var found []Anomaly
+	for b, l := range counts {
+		if l.trips < t.LoopTrades {
+			continue
+		}
+		found = append(found, Anomaly{
+			ContractID: contractID,
+			Kind:       AnomalyWashTrading,
+			Address:    b.address,
+			Detail: fmt.Sprintf("%s bought and sold back %d times within %s, %.2f volume",
+				b.address, l.trips, t.LoopWindow, l.volume),
+			At: b.start,
+		})
+	}
+	return found
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+var anomalyBase = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func trade(kind TradeKind, user, outcome string, amount, cost float64, minutes int) TradeEvent {
+	return TradeEvent{
+		Kind:      kind,
+		User:      user,
+		Outcome:   outcome,
+		Amount:    amount,
+		Cost:      cost,
+		Timestamp: anomalyBase.Add(time.Duration(minutes) * time.Minute),
+		Ledger:    uint32(1000 + minutes*12),
+	}
+}
+
+func TestDetectVolumeSpikes(t *testing.T) {
+	events := []TradeEvent{
+		trade(TradeKindBuy, "GA", "YES", 10, 5, 0),
+		trade(TradeKindBuy, "GB", "NO", 10, 5, 1),
+		trade(TradeKindBuy, "GWHALE", "YES", 400, 200, 5),
+		trade(TradeKindBuy, "GWHALE", "YES", 200, 100, 20),
+		trade(TradeKindBuy, "GC", "NO", 20, 10, 90),
+	}
+	found := detectVolumeSpikes("CA", events, DefaultAnomalyThresholds)
+	if len(found) != 1 || found[0].Address != "GWHALE" || !found[0].At.Equal(anomalyBase) {
+		t.Fatalf("detectVolumeSpikes() = %+v, want one spike by GWHALE in the first hour", found)
+	}
+
+	thresholds := DefaultAnomalyThresholds
+	thresholds.MinVolume = 1000
+	if found := detectVolumeSpikes("CA", events, thresholds); len(found) != 0 {
+		t.Errorf("detectVolumeSpikes() below MinVolume = %+v, want none", found)
+	}
+}
+
+func TestDetectPriceSwings(t *testing.T) {
+	events := []TradeEvent{
+		trade(TradeKindBuy, "GA", "YES", 20, 10, 0),  // 50%
+		trade(TradeKindBuy, "GB", "NO", 10, 4, 2),    // 60%
+		trade(TradeKindBuy, "GB", "YES", 50, 40, 4),  // 80%
+		trade(TradeKindSell, "GB", "YES", 50, 39, 5), // 78%, same window
+	}
+	found := detectPriceSwings("CA", events, DefaultAnomalyThresholds)
+	if len(found) != 1 || found[0].Kind != AnomalyPriceSwing || !found[0].At.Equal(anomalyBase) {
+		t.Fatalf("detectPriceSwings() = %+v, want one swing in the first window", found)
+	}
+
+	slow := []TradeEvent{
+		trade(TradeKindBuy, "GA", "YES", 20, 10, 0),
+		trade(TradeKindBuy, "GB", "NO", 10, 4, 30),
+		trade(TradeKindBuy, "GB", "YES", 50, 40, 60),
+	}
+	if found := detectPriceSwings("CA", slow, DefaultAnomalyThresholds); len(found) != 0 {
+		t.Errorf("detectPriceSwings() for a slow move = %+v, want none", found)
+	}
+}
+
+func TestDetectWashTrading(t *testing.T) {
+	var events []TradeEvent
+	for i := range 3 {
+		events = append(events,
+			trade(TradeKindBuy, "GLOOP", "YES", 10, 6, i*10),
+			trade(TradeKindSell, "GLOOP", "YES", 10, 5, i*10+1),
+		)
+	}
+	// Selling without a prior buy in the window is not a round trip
+	events = append(events,
+		trade(TradeKindSell, "GHOLDER", "NO", 10, 5, 3),
+		trade(TradeKindSell, "GHOLDER", "NO", 10, 5, 4),
+		trade(TradeKindSell, "GHOLDER", "NO", 10, 5, 5),
+	)
+	found := detectWashTrading("CA", events, DefaultAnomalyThresholds)
+	if len(found) != 1 || found[0].Address != "GLOOP" {
+		t.Fatalf("detectWashTrading() = %+v, want only GLOOP", found)
+	}
+}
+
+func TestAnomalyMonitorRecordReportsOnce(t *testing.T) {
+	m := &AnomalyMonitor{anomalies: make(map[string]Anomaly)}
+	a := Anomaly{ContractID: "CA", Kind: AnomalyWashTrading, Address: "GLOOP", At: anomalyBase}
+	now := anomalyBase.Add(time.Hour)
+
+	if fresh := m.record([]Anomaly{a}, now); len(fresh) != 1 {
+		t.Fatalf("first record() = %d new, want 1", len(fresh))
+	}
+	a.Detail = "seen again with more trades"
+	if fresh := m.record([]Anomaly{a}, now.Add(AnomalyCheckInterval)); len(fresh) != 0 {
+		t.Errorf("record() of a known anomaly = %+v, want none new", fresh)
+	}
+	if flagged := m.Flagged(); len(flagged) != 1 || flagged[0].ContractID != "CA" {
+		t.Errorf("Flagged() = %+v, want CA", flagged)
+	}
+
+	m.record(nil, now.Add(anomalyRetention+time.Minute))
+	if flagged := m.Flagged(); len(flagged) != 0 {
+		t.Errorf("Flagged() after retention = %+v, want none", flagged)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+)
+
+// ArchiveRefreshInterval is how often the background scheduler re-evaluates archived markets.
+const ArchiveRefreshInterval = 10 * time.Minute
+
+// ArchiveService archives resolved markets a fixed time after resolution.
+// Archived markets leave the main list and their state is frozen in FactoryService,
+// so the state refresher stops calling RPC for them. Their pages stay viewable.
+//
+// The resolution time comes from the market's resolve event. Markets resolved before
+// the RPC retention window fall back to their metadata end date as an estimate.
+type ArchiveService struct {
+	factoryService *FactoryService
+	eventService   *EventService
+	ipfsClient     *ipfs.Client
+	archiveAfter   time.Duration
+	logger         *slog.Logger
+
+	mu         sync.RWMutex
+	resolvedAt map[string]time.Time
+	archived   map[string]bool
+}
+
+// NewArchiveService creates a new archive service.
+// An archiveAfter of zero or less disables archiving.
+func NewArchiveService(
+	factoryService *FactoryService,
+	eventService *EventService,
+	ipfsClient *ipfs.Client,
+	archiveAfter time.Duration,
+	logger *slog.Logger,
+) *ArchiveService {
+	if factoryService == nil {
+		panic("NewArchiveService: factoryService must not be nil")
+	}
+	if eventService == nil {
+		panic("NewArchiveService: eventService must not be nil")
+	}
+	if logger == nil {
+		panic("NewArchiveService: logger must not be nil")
+	}
+	return &ArchiveService{
+		factoryService: factoryService,
+		eventService:   eventService,
+		ipfsClient:     ipfsClient,
+		archiveAfter:   archiveAfter,
+		logger:         logger,
+		resolvedAt:     make(map[string]time.Time),
+		archived:       make(map[string]bool),
+	}
+}
+
+// Enabled reports whether archiving is configured.
+func (s *ArchiveService) Enabled() bool {
+	return s.archiveAfter > 0
+}
+
+// IsArchived reports whether a market has been archived.
+func (s *ArchiveService) IsArchived(contractID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.archived[contractID]
+}
+
+// ResolvedAt returns the known or estimated resolution time of a market.
+func (s *ArchiveService) ResolvedAt(contractID string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.resolvedAt[contractID]
+	return t, ok
+}
+
+// Refresh archives resolved markets whose resolution is older than archiveAfter.
+// Failures for single markets are logged and retried on the next refresh.
+func (s *ArchiveService) Refresh(ctx context.Context) error {
+	if !s.Enabled() || !s.factoryService.HasFactory() {
+		return nil
+	}
+
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+
+	var candidates []string
+	for _, id := range ids {
+		if !s.IsArchived(id) {
+			candidates = append(candidates, id)
+		}
+	}
+
+	loaded, err := s.factoryService.GetMarketStates(ctx, candidates)
+	if err != nil {
+		return fmt.Errorf("failed to get market states: %w", err)
+	}
+	states := loaded.States
+
+	now := time.Now()
+	var archivedCount int
+	for _, state := range states {
+		if !state.Resolved {
+			continue
+		}
+		resolvedAt, ok := s.resolutionTime(ctx, state)
+		if !ok || now.Sub(resolvedAt) < s.archiveAfter {
+			continue
+		}
+
+		s.factoryService.FreezeMarketState(state)
+		s.mu.Lock()
+		s.archived[state.ContractID] = true
+		s.mu.Unlock()
+		archivedCount++
+	}
+
+	if archivedCount > 0 {
+		s.logger.Info("archived markets", "count", archivedCount, "archive_after", s.archiveAfter)
+	}
+	return nil
+}
+
+// resolutionTime returns the cached resolution time, looking it up on first use.
+func (s *ArchiveService) resolutionTime(ctx context.Context, state MarketState) (time.Time, bool) {
+	if t, ok := s.ResolvedAt(state.ContractID); ok {
+		return t, true
+	}
+
+	resolution, found, err := s.eventService.GetResolution(ctx, state.ContractID)
+	if err != nil {
+		s.logger.Warn("failed to get resolve event", "contract_id", state.ContractID, "error", err)
+		return time.Time{}, false
+	}
+
+	t := resolution.Timestamp
+	if !found {
+		// Resolved before the RPC retention window: estimate with the end date.
+		t, found = s.endDate(ctx, state)
+		if !found {
+			return time.Time{}, false
+		}
+	}
+
+	s.mu.Lock()
+	s.resolvedAt[state.ContractID] = t
+	s.mu.Unlock()
+	return t, true
+}
+
+func (s *ArchiveService) endDate(ctx context.Context, state MarketState) (time.Time, bool) {
+	if s.ipfsClient == nil || state.MetadataHash == "" {
+		return time.Time{}, false
+	}
+	var metadata model.MarketMetadata
+	if err := s.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err != nil {
+		s.logger.Warn("failed to fetch metadata", "hash", state.MetadataHash, "error", err)
+		return time.Time{}, false
+	}
+	return metadata.EndDate, !metadata.EndDate.IsZero()
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+)
+
+// AuditAnchorInterval is how often the head of the audit log is pinned to IPFS.
+const AuditAnchorInterval = 24 * time.Hour
+
+// AuditActionAnchor is the action of the entry recording an IPFS anchor of the log.
+const AuditActionAnchor = "audit.anchor"
+
+// ErrAuditChainBroken is returned when audit entries do not form an unbroken hash chain.
+var ErrAuditChainBroken = errors.New("audit log hash chain is broken")
+
+// AuditEntry is one oracle action in the audit log. Hash covers every other field,
+// including the hash of the previous entry, so rewriting any entry changes the hash
+// of every entry after it.
+type AuditEntry struct {
+	Seq      int64             `json:"seq"` // 1 for the first entry
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor"`
+	Action   string            `json:"action"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prev_hash"` // Empty for the first entry
+	Hash     string            `json:"hash"`
+}
+
+// auditPayload is the part of an entry its hash covers. encoding/json writes map
+// keys sorted, so the encoding is canonical.
+type auditPayload struct {
+	Seq      int64             `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor"`
+	Action   string            `json:"action"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prev_hash"`
+}
+
+// AuditHash returns the hex SHA-256 of the entry's JSON encoding without its hash:
+// {"seq":…,"time":…,"actor":…,"action":…,"details":{…},"prev_hash":…}.
+func AuditHash(e AuditEntry) string {
+	data, _ := json.Marshal(auditPayload{
+		Seq:      e.Seq,
+		Time:     e.Time,
+		Actor:    e.Actor,
+		Action:   e.Action,
+		Details:  e.Details,
+		PrevHash: e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that entries are consecutive, each hash matches its entry
+// and each entry names the hash of the one before it. An export may start after the
+// first entry; its first PrevHash is then taken on trust, or checked against an anchor.
+func VerifyAuditChain(entries []AuditEntry) error {
+	for i, e := range entries {
+		if i > 0 {
+			prev := entries[i-1]
+			if e.Seq != prev.Seq+1 {
+				return fmt.Errorf("%w: entry %d follows entry %d", ErrAuditChainBroken, e.Seq, prev.Seq)
+			}
+			if e.PrevHash != prev.Hash {
+				return fmt.Errorf("%w: entry %d does not link to entry %d", ErrAuditChainBroken, e.Seq, prev.Seq)
+			}
+		} else if e.Seq == 1 && e.PrevHash != "" {
+			return fmt.Errorf("%w: entry 1 has a previous hash", ErrAuditChainBroken)
+		}
+		if AuditHash(e) != e.Hash {
+			return fmt.Errorf("%w: entry %d does not match its hash", ErrAuditChainBroken, e.Seq)
+		}
+	}
+	return nil
+}
+
+// AuditAnchor is the document pinned to IPFS for the head of the audit log.
+type AuditAnchor struct {
+	Seq      int64     `json:"seq"`
+	HeadHash string    `json:"head_hash"`
+	Time     time.Time `json:"time"`
+	Previous string    `json:"previous,omitempty"` // CID of the previous anchor
+}
+
+// AuditLog is an append-only, hash-chained record of the oracle's actions on this
+// server: builds by the signed-in oracle, server-key signatures, moderation and
+// configuration changes. Entries are kept in memory, optionally persisted to a JSON
+// file; once a day the head hash is pinned to IPFS so a rewritten history shows.
+type AuditLog struct {
+	ipfsClient *ipfs.Client
+	path       string // Empty keeps the log in memory only
+	logger     *slog.Logger
+
+	mu         sync.Mutex
+	entries    []AuditEntry
+	lastAnchor string // CID of the newest anchor
+}
+
+// auditFile is the on-disk form of the log.
+type auditFile struct {
+	Entries    []AuditEntry `json:"entries"`
+	LastAnchor string       `json:"last_anchor,omitempty"`
+}
+
+// NewAuditLog creates an audit log and loads existing entries from path, if set.
+// A file whose chain does not verify is refused.
+func NewAuditLog(ipfsClient *ipfs.Client, path string, logger *slog.Logger) (*AuditLog, error) {
+	if ipfsClient == nil {
+		panic("NewAuditLog: ipfsClient must not be nil")
+	}
+	if logger == nil {
+		panic("NewAuditLog: logger must not be nil")
+	}
+
+	l := &AuditLog{ipfsClient: ipfsClient, path: path, logger: logger}
+	if path == "" {
+		return l, nil
+	}
+	var file auditFile
+	if _, err := readJSONFile(path, &file); err != nil {
+		return nil, fmt.Errorf("failed to load audit log: %w", err)
+	}
+	if err := VerifyAuditChain(file.Entries); err != nil {
+		return nil, fmt.Errorf("failed to load audit log %s: %w", path, err)
+	}
+	l.entries, l.lastAnchor = file.Entries, file.LastAnchor
+	return l, nil
+}
+
+// Record appends an action to the log. details may be nil.
+func (l *AuditLog) Record(actor, action string, details map[string]string) (AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recordLocked(actor, action, details)
+}
+
+func (l *AuditLog) recordLocked(actor, action string, details map[string]string) (AuditEntry, error) {
+	e := AuditEntry{
+		Seq:     1,
+		Time:    time.Now().UTC(),
+		Actor:   actor,
+		Action:  action,
+		Details: maps.Clone(details),
+	}
+	if n := len(l.entries); n > 0 {
+		e.Seq, e.PrevHash = l.entries[n-1].Seq+1, l.entries[n-1].Hash
+	}
+	e.Hash = AuditHash(e)
+
+	l.entries = append(l.entries, e)
+	if err := l.saveLocked(); err != nil {
+		l.entries = l.entries[:len(l.entries)-1]
+		return AuditEntry{}, err
+	}
+	return e, nil
+}
+
+// Entries returns the log, oldest first.
+func (l *AuditLog) Entries() []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.entries)
+}
+
+// LastAnchor returns the CID of the newest IPFS anchor, empty before the first.
+func (l *AuditLog) LastAnchor() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastAnchor
+}
+
+// Anchor pins the current head of the log to IPFS, linked to the previous anchor, and
+// records the CID as a new entry. It does nothing when pinning is not configured or
+// nothing was recorded since the last anchor.
+func (l *AuditLog) Anchor(ctx context.Context) error {
+	if !l.ipfsClient.CanPin() {
+		return nil
+	}
+	l.mu.Lock()
+	if len(l.entries) == 0 || l.entries[len(l.entries)-1].Action == AuditActionAnchor {
+		l.mu.Unlock()
+		return nil
+	}
+	head := l.entries[len(l.entries)-1]
+	anchor := AuditAnchor{Seq: head.Seq, HeadHash: head.Hash, Time: time.Now().UTC(), Previous: l.lastAnchor}
+	l.mu.Unlock()
+
+	cid, err := l.ipfsClient.PinJSON(ctx, anchor)
+	if err != nil {
+		return fmt.Errorf("failed to pin audit anchor: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastAnchor = cid
+	if _, err := l.recordLocked("", AuditActionAnchor, map[string]string{
+		"cid":       cid,
+		"seq":       strconv.FormatInt(anchor.Seq, 10),
+		"head_hash": anchor.HeadHash,
+	}); err != nil {
+		return err
+	}
+	l.logger.Info("anchored audit log", "seq", anchor.Seq, "cid", cid)
+	return nil
+}
+
+// saveLocked writes the log to the file atomically. Must be called with mu held.
+func (l *AuditLog) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(l.path, auditFile{Entries: l.entries, LastAnchor: l.lastAnchor}); err != nil {
+		return fmt.Errorf("failed to save audit log: %w", err)
+	}
+	return nil
+}
+
+// WriteAuditCSV writes entries as CSV with a header row. Details are written as
+// key=value pairs separated by "; ", sorted by key; verify the chain from the JSON
+// export, which keeps them exact.
+func WriteAuditCSV(w io.Writer, entries []AuditEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"seq", "time", "actor", "action", "details", "prev_hash", "hash"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var details []string
+		for _, k := range slices.Sorted(maps.Keys(e.Details)) {
+			details = append(details, k+"="+e.Details[k])
+		}
+		if err := cw.Write([]string{
+			strconv.FormatInt(e.Seq, 10),
+			e.Time.Format(time.RFC3339Nano),
+			e.Actor,
+			e.Action,
+			strings.Join(details, "; "),
+			e.PrevHash,
+			e.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
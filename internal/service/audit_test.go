@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/ipfs"
+)
+
+func TestAuditLogChainsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	log, err := NewAuditLog(ipfs.NewClient("", "", nil), path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"market.resolve", "comment.hide", "series.save"} {
+		if _, err := log.Record("GORACLE", action, map[string]string{"id": action}); err != nil {
+			t.Fatalf("Record(%s) error = %v", action, err)
+		}
+	}
+
+	entries := log.Entries()
+	if len(entries) != 3 || entries[0].PrevHash != "" || entries[2].PrevHash != entries[1].Hash || entries[2].Seq != 3 {
+		t.Fatalf("Entries() = %+v", entries)
+	}
+	if err := VerifyAuditChain(entries); err != nil {
+		t.Fatalf("VerifyAuditChain() error = %v", err)
+	}
+	if err := VerifyAuditChain(entries[1:]); err != nil {
+		t.Errorf("VerifyAuditChain(tail) error = %v", err)
+	}
+
+	tampered := log.Entries()
+	tampered[1].Details = map[string]string{"id": "rewritten"}
+	if err := VerifyAuditChain(tampered); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("VerifyAuditChain(rewritten details) error = %v, want ErrAuditChainBroken", err)
+	}
+	tampered = log.Entries()
+	tampered[1].Hash = AuditHash(AuditEntry{Seq: 2, Time: tampered[1].Time, Action: "comment.hide", PrevHash: tampered[1].PrevHash})
+	if err := VerifyAuditChain(tampered); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("VerifyAuditChain(rehashed entry) error = %v, want ErrAuditChainBroken", err)
+	}
+
+	reloaded, err := NewAuditLog(ipfs.NewClient("", "", nil), path, logger)
+	if err != nil {
+		t.Fatalf("NewAuditLog(reload) error = %v", err)
+	}
+	if got := reloaded.Entries(); len(got) != 3 || got[2].Hash != entries[2].Hash {
+		t.Errorf("reloaded Entries() = %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAuditCSV(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "seq,time,actor,action,details,prev_hash,hash" || !strings.Contains(lines[2], "comment.hide,id=comment.hide,") {
+		t.Errorf("WriteAuditCSV() =\n%s", buf.String())
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+const (
+	// ChallengeTTL is how long a SEP-10 challenge can be signed and submitted.
+	ChallengeTTL = 5 * time.Minute
+	// SessionTTL is how long a session issued after SEP-10 verification lasts.
+	SessionTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidChallenge = errors.New("invalid or unsigned challenge")
+	ErrSessionInvalid   = errors.New("invalid session")
+	ErrSessionExpired   = errors.New("session expired")
+)
+
+// sessionClaims is the signed content of a session token.
+type sessionClaims struct {
+	Account   string `json:"a"`
+	ExpiresAt int64  `json:"e"` // Unix seconds
+}
+
+// AuthService authenticates Stellar accounts with SEP-10 challenge transactions
+// and issues HMAC-signed session tokens for them.
+//
+// Users sign the challenge externally, like any other transaction built here.
+// Only the account's master key is accepted as signer.
+type AuthService struct {
+	signer            *keypair.Full
+	networkPassphrase string
+	homeDomain        string
+	sessionKey        []byte
+}
+
+// NewAuthService creates an auth service. An empty signingSeed generates a random
+// server key, so sessions do not survive a restart and are not shared between replicas.
+func NewAuthService(signingSeed, homeDomain, networkPassphrase string) (*AuthService, error) {
+	var signer *keypair.Full
+	var err error
+	if signingSeed == "" {
+		signer, err = keypair.Random()
+	} else {
+		signer, err = keypair.ParseFull(signingSeed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth signing key: %w", err)
+	}
+	if homeDomain == "" {
+		return nil, errors.New("auth home domain must not be empty")
+	}
+
+	// Derive the session key so a single secret needs to be configured
+	sessionKey := sha256.Sum256([]byte("total-session:" + signer.Seed()))
+
+	return &AuthService{
+		signer:            signer,
+		networkPassphrase: networkPassphrase,
+		homeDomain:        homeDomain,
+		sessionKey:        sessionKey[:],
+	}, nil
+}
+
+// Challenge builds a SEP-10 challenge transaction for the account.
+func (s *AuthService) Challenge(account string) (string, error) {
+	if err := model.ValidateStellarPublicKey(account); err != nil {
+		return "", err
+	}
+	tx, err := txnbuild.BuildChallengeTx(s.signer.Seed(), account, s.homeDomain, s.homeDomain, s.networkPassphrase, ChallengeTTL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build challenge: %w", err)
+	}
+	xdr, err := tx.Base64()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode challenge: %w", err)
+	}
+	return xdr, nil
+}
+
+// Verify checks a challenge signed by the client and returns a session token for its account.
+func (s *AuthService) Verify(signedXDR string) (token, account string, err error) {
+	_, account, _, _, err = txnbuild.ReadChallengeTx(signedXDR, s.signer.Address(), s.networkPassphrase, s.homeDomain, []string{s.homeDomain})
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidChallenge, err)
+	}
+	if _, err := txnbuild.VerifyChallengeTxSigners(signedXDR, s.signer.Address(), s.networkPassphrase, s.homeDomain, []string{s.homeDomain}, account); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidChallenge, err)
+	}
+
+	token, err = s.signSession(sessionClaims{Account: account}, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	return token, account, nil
+}
+
+// SessionAccount returns the account of a valid session token.
+func (s *AuthService) SessionAccount(token string) (string, error) {
+	claims, err := s.verifySession(token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return claims.Account, nil
+}
+
+func (s *AuthService) signSession(claims sessionClaims, now time.Time) (string, error) {
+	claims.ExpiresAt = now.Add(SessionTTL).Unix()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.mac(encoded), nil
+}
+
+func (s *AuthService) verifySession(token string, now time.Time) (sessionClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(encoded))) {
+		return sessionClaims{}, ErrSessionInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return sessionClaims{}, ErrSessionInvalid
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return sessionClaims{}, ErrSessionInvalid
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return sessionClaims{}, ErrSessionExpired
+	}
+	return claims, nil
+}
+
+func (s *AuthService) mac(encoded string) string {
+	m := hmac.New(sha256.New, s.sessionKey)
+	m.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+func signChallenge(t *testing.T, challenge string, kp *keypair.Full) string {
+	t.Helper()
+	parsed, err := txnbuild.TransactionFromXDR(challenge)
+	if err != nil {
+		t.Fatalf("TransactionFromXDR() error = %v", err)
+	}
+	tx, _ := parsed.Transaction()
+	tx, err = tx.Sign(config.TestnetNetworkPassphrase, kp)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	signed, err := tx.Base64()
+	if err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	return signed
+}
+
+func TestAuthService_SignIn(t *testing.T) {
+	auth, err := NewAuthService("", "predict.example", config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewAuthService() error = %v", err)
+	}
+	user := keypair.MustRandom()
+	other := keypair.MustRandom()
+
+	tests := []struct {
+		name    string
+		signer  *keypair.Full
+		wantErr error
+	}{
+		{"signed by account", user, nil},
+		{"signed by another key", other, ErrInvalidChallenge},
+		{"unsigned", nil, ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := auth.Challenge(user.Address())
+			if err != nil {
+				t.Fatalf("Challenge() error = %v", err)
+			}
+			if tt.signer != nil {
+				challenge = signChallenge(t, challenge, tt.signer)
+			}
+
+			token, account, err := auth.Verify(challenge)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if account != user.Address() {
+				t.Errorf("Verify() account = %s, want %s", account, user.Address())
+			}
+			got, err := auth.SessionAccount(token)
+			if err != nil || got != user.Address() {
+				t.Errorf("SessionAccount() = %q, %v, want %q", got, err, user.Address())
+			}
+		})
+	}
+}
+
+func TestAuthService_Session(t *testing.T) {
+	auth, err := NewAuthService("", "predict.example", config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewAuthService() error = %v", err)
+	}
+	now := time.Now()
+	token, err := auth.signSession(sessionClaims{Account: "GABC"}, now)
+	if err != nil {
+		t.Fatalf("signSession() error = %v", err)
+	}
+
+	if _, err := auth.verifySession(token, now.Add(SessionTTL+time.Second)); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expired session error = %v, want %v", err, ErrSessionExpired)
+	}
+	if _, err := auth.verifySession(token+"x", now); !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("tampered session error = %v, want %v", err, ErrSessionInvalid)
+	}
+	other, _ := NewAuthService("", "predict.example", config.TestnetNetworkPassphrase)
+	if _, err := other.verifySession(token, now); !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("session from another key error = %v, want %v", err, ErrSessionInvalid)
+	}
+}
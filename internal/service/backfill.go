@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+)
+
+const (
+	// backfillMaxPages bounds the getEvents pages of one market's backfill, far above
+	// what the RPC retention window holds.
+	backfillMaxPages = 5000
+	// backfillRPCMargin keeps the first RPC ledger of a backfill clear of the retention
+	// edge, which moves on while the backfill runs.
+	backfillRPCMargin = 60
+	// backfillProgressEvery is how many Horizon ledgers pass between progress reports.
+	backfillProgressEvery = 1000
+)
+
+// ErrHorizonNoMeta is returned when Horizon serves transactions without result meta,
+// so contract events older than the RPC retention window cannot be read from it.
+var ErrHorizonNoMeta = errors.New("horizon does not serve transaction meta")
+
+// LedgerTransactionsFunc returns the transactions of one ledger with their result meta,
+// e.g. stellar.HorizonClient.GetLedgerTransactions.
+type LedgerTransactionsFunc func(ctx context.Context, ledger uint32) ([]horizon.Transaction, error)
+
+// BackfillOptions configure a backfill of the trade statistics.
+type BackfillOptions struct {
+	// FromLedger is the first ledger to read. Zero estimates it from the creation time
+	// of the oldest market, or starts at the RPC retention window without one.
+	FromLedger uint32
+	// Horizon reads ledgers older than the RPC retention window. Nil limits the
+	// backfill to the window.
+	Horizon LedgerTransactionsFunc
+	// Progress is called every thousand ledgers walked on Horizon; optional.
+	Progress func(ledger, last uint32)
+}
+
+// BackfillResult summarizes a backfill.
+type BackfillResult struct {
+	Markets        int      // markets with trades left to backfill
+	Skipped        []string // markets counted before coverage was tracked
+	FromLedger     uint32
+	RPCLedger      uint32 // first ledger read from the RPC; earlier ones came from Horizon
+	HorizonLedgers int    // ledgers walked on Horizon
+	Trades         int    // trades counted by the backfill
+}
+
+// Backfill counts the trades the running aggregation never saw, because they happened
+// before the RPC's ~24h event window when it first counted a market. Ledgers still in
+// the RPC retention window are read with getEvents, older ones from Horizon's
+// transaction meta when opts.Horizon is set. Markets whose earliest counted ledger was
+// not recorded are skipped rather than counted twice.
+//
+// A backfill walks every ledger outside the RPC window one request at a time and is
+// meant to run once, from the command line, while the web server is stopped.
+func (s *StatsService) Backfill(ctx context.Context, opts BackfillOptions) (BackfillResult, error) {
+	var result BackfillResult
+	if !s.factoryService.HasFactory() {
+		return result, ErrFactoryNotConfigured
+	}
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		return result, fmt.Errorf("failed to get market states: %w", err)
+	}
+
+	// bounds maps the markets to backfill to the ledger their counted trades start at;
+	// 0 when none were counted yet.
+	bounds := make(map[string]uint32)
+	var oldest time.Time
+	for _, state := range loaded.States {
+		s.mu.RLock()
+		from, counted := s.file.CountedFrom[state.ContractID]
+		s.mu.RUnlock()
+		if counted && from == 0 {
+			result.Skipped = append(result.Skipped, state.ContractID)
+			continue
+		}
+		bounds[state.ContractID] = from
+		created, err := s.marketCreated(ctx, state)
+		if err != nil {
+			s.logger.Warn("backfill: failed to get market creation time", "contract_id", state.ContractID, "error", err)
+		}
+		if !created.IsZero() && (oldest.IsZero() || created.Before(oldest)) {
+			oldest = created
+		}
+	}
+	result.Markets = len(bounds)
+	if len(bounds) == 0 {
+		return result, nil
+	}
+
+	history, err := s.eventService.tradeHistory(ctx, slices.Sorted(maps.Keys(bounds)), opts, oldest, &result)
+	if err != nil {
+		return result, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.Trades = s.backfillLocked(history, bounds, time.Now())
+	if err := s.saveLocked(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// backfillLocked counts the trades of each market before its counted range, or all of
+// them for a market without counted trades, and returns how many it counted.
+func (s *StatsService) backfillLocked(history map[string][]TradeEvent, bounds map[string]uint32, now time.Time) int {
+	var n int
+	for contractID, events := range history {
+		bound := bounds[contractID]
+		for _, evt := range events {
+			if bound > 0 && evt.Ledger >= bound {
+				continue
+			}
+			if from, ok := s.file.CountedFrom[contractID]; !ok || evt.Ledger < from {
+				s.file.CountedFrom[contractID] = evt.Ledger
+			}
+			if s.file.Since.IsZero() || evt.Timestamp.Before(s.file.Since) {
+				s.file.Since = evt.Timestamp
+			}
+			s.addLocked(contractID, evt)
+			n++
+			// The baseline held these trades until now.
+			if baseline, ok := s.file.Baselines[contractID]; ok {
+				var backfilled marketNet
+				backfilled.add(evt)
+				s.file.Baselines[contractID] = marketNet{Yes: baseline.Yes - backfilled.Yes, No: baseline.No - backfilled.No}
+			}
+		}
+	}
+	s.pruneDailyLocked(now)
+	return n
+}
+
+// tradeHistory returns the trade events of the given markets from the first ledger of
+// a backfill on, oldest first. oldestMarket estimates the first ledger when
+// opts.FromLedger is zero.
+func (s *EventService) tradeHistory(ctx context.Context, contractIDs []string, opts BackfillOptions, oldestMarket time.Time, result *BackfillResult) (map[string][]TradeEvent, error) {
+	health, err := s.sorobanClient.GetHealth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RPC retention window: %w", err)
+	}
+	rpcStart := health.OldestLedger + backfillRPCMargin
+
+	from := opts.FromLedger
+	if from == 0 && !oldestMarket.IsZero() {
+		// Ledgers close in 5s or more, so this errs towards earlier ledgers.
+		elapsed := uint32(time.Since(oldestMarket) / soroban.LedgerInterval)
+		from = 1
+		if elapsed < health.LatestLedger {
+			from = health.LatestLedger - elapsed
+		}
+	}
+	if from == 0 || opts.Horizon == nil {
+		from = max(from, rpcStart)
+	}
+	result.FromLedger, result.RPCLedger = from, max(from, rpcStart)
+
+	history := make(map[string][]TradeEvent, len(contractIDs))
+	if from < rpcStart {
+		wanted := make(map[string]bool, len(contractIDs))
+		for _, id := range contractIDs {
+			wanted[id] = true
+		}
+		if err := s.horizonTradeEvents(ctx, opts, from, rpcStart, wanted, history); err != nil {
+			return nil, err
+		}
+		result.HorizonLedgers = int(rpcStart - from)
+	}
+
+	for _, id := range contractIDs {
+		events, err := s.fetchEventsFrom(ctx, id, result.RPCLedger, backfillMaxPages)
+		if err != nil {
+			return nil, fmt.Errorf("market %s: %w", id, err)
+		}
+		history[id] = append(history[id], events...)
+	}
+	return history, nil
+}
+
+// horizonTradeEvents walks ledgers [from, to) on Horizon and adds the trade events of
+// the wanted markets to history.
+func (s *EventService) horizonTradeEvents(ctx context.Context, opts BackfillOptions, from, to uint32, wanted map[string]bool, history map[string][]TradeEvent) error {
+	buyTopicXDR, err := encodeSymbolBase64("buy")
+	if err != nil {
+		return fmt.Errorf("failed to encode buy topic: %w", err)
+	}
+	sellTopicXDR, err := encodeSymbolBase64("sell")
+	if err != nil {
+		return fmt.Errorf("failed to encode sell topic: %w", err)
+	}
+
+	for ledger := from; ledger < to; ledger++ {
+		if opts.Progress != nil && (ledger-from)%backfillProgressEvery == 0 {
+			opts.Progress(ledger, to-1)
+		}
+		txs, err := opts.Horizon(ctx, ledger)
+		if err != nil {
+			return err
+		}
+
+		raw := make(map[string][]soroban.ContractEvent)
+		var successful, withMeta int
+		for _, tx := range txs {
+			if !tx.Successful {
+				continue
+			}
+			successful++
+			if tx.ResultMetaXdr == "" {
+				continue
+			}
+			withMeta++
+			outcome, err := (&soroban.GetTransactionResult{
+				Status:        soroban.TxResultSuccess,
+				Ledger:        uint32(tx.Ledger),
+				CreatedAt:     strconv.FormatInt(tx.LedgerCloseTime.Unix(), 10),
+				ResultMetaXdr: tx.ResultMetaXdr,
+			}).Outcome(tx.Hash)
+			if err != nil {
+				return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+			}
+			toid, err := strconv.ParseInt(tx.PT, 10, 64)
+			if err != nil {
+				return fmt.Errorf("transaction %s: invalid paging token %q", tx.Hash, tx.PT)
+			}
+			for i, evt := range outcome.Events {
+				// getEvents IDs are the transaction's TOID and the event index.
+				evt.ID = fmt.Sprintf("%019d-%010d", toid, i)
+				if wanted[evt.ContractID] && len(evt.Topic) > 0 && (evt.Topic[0] == buyTopicXDR || evt.Topic[0] == sellTopicXDR) {
+					raw[evt.ContractID] = append(raw[evt.ContractID], evt)
+				}
+			}
+		}
+		if successful > 0 && withMeta == 0 {
+			return fmt.Errorf("%w (ledger %d): use a Horizon that keeps it or a later --from-ledger", ErrHorizonNoMeta, ledger)
+		}
+
+		for id, events := range raw {
+			parsed, err := s.parseTradeEvents(events)
+			if err != nil {
+				return fmt.Errorf("market %s: %w", id, err)
+			}
+			history[id] = append(history[id], parsed...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+)
+
+const (
+	// BackupInterval is how often metadata is mirrored and state files are snapshotted.
+	BackupInterval = 6 * time.Hour
+	// DefaultBackupRetention is how long state file snapshots are kept.
+	DefaultBackupRetention = 30 * 24 * time.Hour
+
+	backupMetadataPrefix = "metadata/"
+	backupSnapshotPrefix = "snapshots/"
+	// backupSnapshotLayout names a snapshot's directory after its UTC time.
+	backupSnapshotLayout = "20060102T150405Z"
+)
+
+// BackupBucket stores backup objects; s3.Bucket implements it. Keys are
+// slash-separated.
+type BackupBucket interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// BackupResult summarizes a backup run.
+type BackupResult struct {
+	Mirrored int    // metadata documents newly copied to the bucket
+	Snapshot string // snapshot directory, empty without state files
+	Files    int    // state files in the snapshot
+	Pruned   int    // snapshot objects deleted past the retention
+}
+
+// BackupService protects against losing the Pinata pins and the state files. It
+// mirrors the metadata document of every market to metadata/<hash>.json, byte for
+// byte so it can be pinned again under the same hash, and copies the configured
+// state files to snapshots/<time>/. Metadata is immutable and kept forever; snapshots
+// older than the retention are deleted, except the newest one.
+type BackupService struct {
+	factoryService *FactoryService
+	ipfsClient     *ipfs.Client
+	bucket         BackupBucket
+	files          []string
+	retention      time.Duration // 0 keeps every snapshot
+	logger         *slog.Logger
+
+	mu       sync.Mutex
+	mirrored map[string]bool // metadata hashes known to be in the bucket; nil until listed
+}
+
+// NewBackupService creates a backup service. files are the state files to snapshot;
+// missing ones are skipped.
+func NewBackupService(factoryService *FactoryService, ipfsClient *ipfs.Client, bucket BackupBucket, files []string, retention time.Duration, logger *slog.Logger) *BackupService {
+	return &BackupService{
+		factoryService: factoryService,
+		ipfsClient:     ipfsClient,
+		bucket:         bucket,
+		files:          files,
+		retention:      retention,
+		logger:         logger,
+	}
+}
+
+// Run mirrors new metadata, takes a snapshot and prunes old snapshots. Metadata that
+// cannot be fetched is retried on the next run.
+func (s *BackupService) Run(ctx context.Context, now time.Time) (BackupResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result BackupResult
+	var errs []error
+	if s.factoryService.HasFactory() {
+		mirrored, err := s.mirrorMetadata(ctx)
+		result.Mirrored = mirrored
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	snapshot, files, err := s.snapshot(ctx, now)
+	result.Snapshot, result.Files = snapshot, files
+	if err != nil {
+		errs = append(errs, err)
+	}
+	pruned, err := s.prune(ctx, now)
+	result.Pruned = pruned
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return result, errors.Join(errs...)
+}
+
+func (s *BackupService) mirrorMetadata(ctx context.Context) (int, error) {
+	if s.mirrored == nil {
+		keys, err := s.bucket.List(ctx, backupMetadataPrefix)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list mirrored metadata: %w", err)
+		}
+		s.mirrored = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			if hash, ok := backupMetadataHash(key); ok {
+				s.mirrored[hash] = true
+			}
+		}
+	}
+
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		s.logger.Warn("backup: failed to get some market states", "error", err)
+	}
+
+	var errs []error
+	mirrored := 0
+	for _, state := range loaded.States {
+		hash := state.MetadataHash
+		if hash == "" || s.mirrored[hash] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return mirrored, err
+		}
+		data, err := s.ipfsClient.GetRaw(ctx, hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: failed to fetch metadata: %w", state.ContractID, err))
+			continue
+		}
+		if err := s.bucket.Put(ctx, backupMetadataPrefix+hash+".json", data); err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			continue
+		}
+		s.mirrored[hash] = true
+		mirrored++
+	}
+	return mirrored, errors.Join(errs...)
+}
+
+// snapshot copies the state files to a new snapshot directory. Files are replaced
+// atomically by their services, so each copy is consistent on its own.
+func (s *BackupService) snapshot(ctx context.Context, now time.Time) (string, int, error) {
+	dir := backupSnapshotPrefix + now.UTC().Format(backupSnapshotLayout) + "/"
+	copied := 0
+	for _, path := range s.files {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return dir, copied, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		if err := s.bucket.Put(ctx, dir+filepath.Base(path), data); err != nil {
+			return dir, copied, err
+		}
+		copied++
+	}
+	if copied == 0 {
+		return "", 0, nil
+	}
+	return dir, copied, nil
+}
+
+// prune deletes snapshots older than the retention, always keeping the newest.
+func (s *BackupService) prune(ctx context.Context, now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	keys, err := s.bucket.List(ctx, backupSnapshotPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var newest time.Time
+	taken := make(map[string]time.Time, len(keys))
+	for _, key := range keys {
+		dir, _, _ := strings.Cut(strings.TrimPrefix(key, backupSnapshotPrefix), "/")
+		at, err := time.Parse(backupSnapshotLayout, dir)
+		if err != nil {
+			continue // not a snapshot of ours
+		}
+		taken[key] = at
+		if at.After(newest) {
+			newest = at
+		}
+	}
+
+	pruned := 0
+	for _, key := range slices.Sorted(maps.Keys(taken)) {
+		at := taken[key]
+		if at.Equal(newest) || now.Sub(at) <= s.retention {
+			continue
+		}
+		if err := s.bucket.Delete(ctx, key); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// backupMetadataHash returns the hash of a mirrored metadata key.
+func backupMetadataHash(key string) (string, bool) {
+	name, ok := strings.CutPrefix(key, backupMetadataPrefix)
+	if !ok {
+		return "", false
+	}
+	hash, ok := strings.CutSuffix(name, ".json")
+	return hash, ok && ipfs.ValidateCID(hash) == nil
+}
+
+// RestoreResult summarizes a metadata restore.
+type RestoreResult struct {
+	Pinned     int      // documents pinned under their original hash
+	Mismatched []string // hashes whose document pinned under a different hash
+}
+
+// RestoreMetadata pins every metadata document mirrored by BackupService again, so
+// markets keep their metadata after the original pins are lost. Pinata deduplicates
+// documents that are still pinned. A document pinned under a different hash than its
+// original is reported in Mismatched: the market still points at the old hash.
+func RestoreMetadata(ctx context.Context, bucket BackupBucket, ipfsClient *ipfs.Client, progress func(hash string)) (RestoreResult, error) {
+	var result RestoreResult
+	keys, err := bucket.List(ctx, backupMetadataPrefix)
+	if err != nil {
+		return result, fmt.Errorf("failed to list mirrored metadata: %w", err)
+	}
+	var errs []error
+	for _, key := range keys {
+		hash, ok := backupMetadataHash(key)
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		data, err := bucket.Get(ctx, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pinned, err := ipfsClient.PinFile(ctx, hash+".json", data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+			continue
+		}
+		if pinned != hash {
+			result.Mismatched = append(result.Mismatched, hash)
+			continue
+		}
+		result.Pinned++
+		if progress != nil {
+			progress(hash)
+		}
+	}
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/s3"
+)
+
+// memoryBucket is an in-memory BackupBucket.
+type memoryBucket map[string][]byte
+
+func (b memoryBucket) Put(_ context.Context, key string, data []byte) error {
+	b[key] = data
+	return nil
+}
+
+func (b memoryBucket) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := b[key]
+	if !ok {
+		return nil, s3.ErrNotFound
+	}
+	return data, nil
+}
+
+func (b memoryBucket) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range b {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (b memoryBucket) Delete(_ context.Context, key string) error {
+	delete(b, key)
+	return nil
+}
+
+func TestBackupSnapshotsAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	stats := filepath.Join(dir, "stats.json")
+	if err := os.WriteFile(stats, []byte(`{"volume":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bucket := memoryBucket{}
+	files := []string{stats, filepath.Join(dir, "missing.json")}
+	s := NewBackupService(&FactoryService{}, nil, bucket, files, 48*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	result, err := s.Run(context.Background(), start)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Snapshot != "snapshots/20250301T000000Z/" || result.Files != 1 {
+		t.Fatalf("Run() = %+v, want one file in the 2025-03-01 snapshot", result)
+	}
+	if got := string(bucket["snapshots/20250301T000000Z/stats.json"]); got != `{"volume":1}` {
+		t.Errorf("snapshot = %q, want the stats file", got)
+	}
+
+	// The first snapshot is kept while within the retention...
+	if result, err := s.Run(context.Background(), start.Add(24*time.Hour)); err != nil || result.Pruned != 0 {
+		t.Fatalf("Run() = %+v, %v, want nothing pruned", result, err)
+	}
+	// ...and deleted past it, but the newest snapshot always stays.
+	os.Remove(stats)
+	if result, err := s.Run(context.Background(), start.Add(10*24*time.Hour)); err != nil || result.Pruned != 1 || result.Snapshot != "" {
+		t.Fatalf("Run() = %+v, %v, want the first snapshot pruned and no new one", result, err)
+	}
+	keys, _ := bucket.List(context.Background(), "")
+	if want := []string{"snapshots/20250302T000000Z/stats.json"}; !slices.Equal(keys, want) {
+		t.Errorf("bucket = %v, want %v", keys, want)
+	}
+}
+
+func TestBackupMetadataHash(t *testing.T) {
+	const hash = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	if got, ok := backupMetadataHash("metadata/" + hash + ".json"); !ok || got != hash {
+		t.Errorf("backupMetadataHash() = %q, %v, want %q", got, ok, hash)
+	}
+	for _, key := range []string{"metadata/notes.txt", "metadata/x.json", "snapshots/" + hash + ".json"} {
+		if _, ok := backupMetadataHash(key); ok {
+			t.Errorf("backupMetadataHash(%q) ok, want false", key)
+		}
+	}
+}
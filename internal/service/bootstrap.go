@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// bootstrapPollInterval is how often a bootstrap step checks whether its transaction
+// is in a ledger.
+const bootstrapPollInterval = time.Second
+
+var (
+	ErrInvalidCollateral = errors.New(`invalid collateral: expected "native", CODE:ISSUER or a token contract ID (C...)`)
+	ErrBootstrapFailed   = errors.New("bootstrap transaction failed")
+)
+
+// Collateral is the collateral token of a new environment: a token contract, or a
+// classic asset whose Stellar Asset Contract is deployed when missing.
+type Collateral struct {
+	Asset      *xdr.Asset // nil for a token contract given by ID
+	ContractID string
+}
+
+// ParseCollateral parses "native" (XLM), CODE:ISSUER or a contract ID and derives the
+// asset's SAC contract ID on the network.
+func ParseCollateral(s, networkPassphrase string) (Collateral, error) {
+	s = strings.TrimSpace(s)
+	if strkey.IsValidContractAddress(s) {
+		return Collateral{ContractID: s}, nil
+	}
+
+	var asset txnbuild.Asset
+	if strings.EqualFold(s, "native") || strings.EqualFold(s, "XLM") {
+		asset = txnbuild.NativeAsset{}
+	} else {
+		code, issuer, ok := strings.Cut(s, ":")
+		if !ok || code == "" || len(code) > 12 || !strkey.IsValidEd25519PublicKey(issuer) {
+			return Collateral{}, fmt.Errorf("%w: %q", ErrInvalidCollateral, s)
+		}
+		asset = txnbuild.CreditAsset{Code: code, Issuer: issuer}
+	}
+	xdrAsset, err := asset.ToXDR()
+	if err != nil {
+		return Collateral{}, fmt.Errorf("%w: %v", ErrInvalidCollateral, err)
+	}
+	id, err := soroban.AssetContractID(xdrAsset, networkPassphrase)
+	if err != nil {
+		return Collateral{}, err
+	}
+	return Collateral{Asset: &xdrAsset, ContractID: id}, nil
+}
+
+// BootstrapRequest is what a new environment is set up from.
+type BootstrapRequest struct {
+	MarketWasm  []byte
+	FactoryWasm []byte
+	Collateral  Collateral
+}
+
+// BootstrapStep is one transaction of a bootstrap, or a step skipped because its
+// result already exists on the network.
+type BootstrapStep struct {
+	Name    string
+	TxHash  string // Empty when skipped
+	Skipped bool
+}
+
+// BootstrapResult holds the contract IDs and hashes to put into the configuration.
+type BootstrapResult struct {
+	MarketWasmHash  string
+	FactoryWasmHash string
+	FactoryContract string
+	CollateralToken string
+	Steps           []BootstrapStep
+}
+
+// Bootstrapper sets up a new environment with the oracle key: it uploads the market
+// and factory WASM, deploys the collateral's SAC if needed, then deploys the factory
+// and initializes it with the oracle as admin. Steps whose result already exists are
+// skipped, so a failed bootstrap can be run again.
+type Bootstrapper struct {
+	sorobanClient *soroban.Client
+	txBuilder     *stellar.Builder
+	signer        *OracleSigner
+	broadcaster   *txBroadcaster
+	logger        *slog.Logger
+}
+
+// NewBootstrapper creates a bootstrapper signing with the oracle key.
+func NewBootstrapper(sorobanClient *soroban.Client, txBuilder *stellar.Builder, signer *OracleSigner, logger *slog.Logger) *Bootstrapper {
+	if sorobanClient == nil || txBuilder == nil || signer == nil || logger == nil {
+		panic("bootstrapper requires the soroban client, transaction builder, oracle signer and logger")
+	}
+	return &Bootstrapper{
+		sorobanClient: sorobanClient,
+		txBuilder:     txBuilder,
+		signer:        signer,
+		broadcaster:   newTxBroadcaster(sorobanClient, logger),
+		logger:        logger,
+	}
+}
+
+// Run performs the bootstrap. progress, if set, is called after each step.
+func (b *Bootstrapper) Run(ctx context.Context, req BootstrapRequest, progress func(BootstrapStep)) (*BootstrapResult, error) {
+	oracle := b.signer.Address()
+	marketHash := soroban.WasmHash(req.MarketWasm)
+	factoryHash := soroban.WasmHash(req.FactoryWasm)
+	result := &BootstrapResult{
+		MarketWasmHash:  hex.EncodeToString(marketHash[:]),
+		FactoryWasmHash: hex.EncodeToString(factoryHash[:]),
+		CollateralToken: req.Collateral.ContractID,
+	}
+	record := func(step BootstrapStep) {
+		result.Steps = append(result.Steps, step)
+		b.logger.Info("bootstrap step done", "step", step.Name, "tx_hash", step.TxHash, "skipped", step.Skipped)
+		if progress != nil {
+			progress(step)
+		}
+	}
+
+	for _, upload := range []struct {
+		name string
+		hash string
+		wasm []byte
+	}{
+		{"upload market WASM", result.MarketWasmHash, req.MarketWasm},
+		{"upload factory WASM", result.FactoryWasmHash, req.FactoryWasm},
+	} {
+		installed, err := b.sorobanClient.WasmInstalled(ctx, upload.hash)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", upload.name, err)
+		}
+		if installed {
+			record(BootstrapStep{Name: upload.name, Skipped: true})
+			continue
+		}
+		hash, _, err := b.execute(ctx, upload.name, func() (string, error) {
+			return b.txBuilder.BuildUploadWasmTx(ctx, oracle, upload.wasm)
+		})
+		if err != nil {
+			return result, err
+		}
+		record(BootstrapStep{Name: upload.name, TxHash: hash})
+	}
+
+	if asset := req.Collateral.Asset; asset != nil {
+		const name = "deploy collateral asset contract"
+		_, err := b.sorobanClient.ContractInstance(ctx, req.Collateral.ContractID)
+		switch {
+		case err == nil:
+			record(BootstrapStep{Name: name, Skipped: true})
+		case errors.Is(err, soroban.ErrContractNotFound):
+			hash, _, err := b.execute(ctx, name, func() (string, error) {
+				return b.txBuilder.BuildDeployAssetContractTx(ctx, oracle, *asset)
+			})
+			if err != nil {
+				return result, err
+			}
+			record(BootstrapStep{Name: name, TxHash: hash})
+		default:
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	var salt [32]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return result, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	hash, outcome, err := b.execute(ctx, "deploy factory", func() (string, error) {
+		return b.txBuilder.BuildCreateContractTx(ctx, oracle, factoryHash, salt)
+	})
+	if err != nil {
+		return result, err
+	}
+	if outcome.ReturnValue == nil {
+		return result, fmt.Errorf("deploy factory: transaction %s returned no contract address", hash)
+	}
+	if result.FactoryContract, err = soroban.DecodeAddress(*outcome.ReturnValue); err != nil {
+		return result, fmt.Errorf("deploy factory: %w", err)
+	}
+	record(BootstrapStep{Name: "deploy factory", TxHash: hash})
+
+	hash, _, err = b.execute(ctx, "initialize factory", func() (string, error) {
+		return b.txBuilder.BuildInitializeFactoryTx(ctx, stellar.InitializeFactoryTxParams{
+			AdminPublicKey:  oracle,
+			FactoryContract: result.FactoryContract,
+			MarketWasmHash:  marketHash,
+			CollateralToken: req.Collateral.ContractID,
+		})
+	})
+	if err != nil {
+		return result, err
+	}
+	record(BootstrapStep{Name: "initialize factory", TxHash: hash})
+	return result, nil
+}
+
+// execute builds, simulates, signs and submits one transaction and waits until it is
+// in a ledger. A transaction that fails on-chain is an error.
+func (b *Bootstrapper) execute(ctx context.Context, step string, build func() (string, error)) (string, *soroban.TransactionOutcome, error) {
+	txXDR, err := build()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to build transaction: %w", step, err)
+	}
+	prepared, err := b.txBuilder.SimulateAndPrepareTx(ctx, txXDR)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to simulate transaction: %w", step, err)
+	}
+	signed, hash, err := b.signer.SignBootstrap(prepared)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", step, err)
+	}
+
+	outcome, err := b.await(ctx, signed, hash)
+	if err != nil {
+		return hash, nil, fmt.Errorf("%s: %w", step, err)
+	}
+	if !outcome.Successful {
+		return hash, outcome, fmt.Errorf("%w: %s: transaction %s: %s", ErrBootstrapFailed, step, hash, joinCodes(outcome.ResultCode, outcome.OperationCode))
+	}
+	return hash, outcome, nil
+}
+
+// await submits a signed transaction, waits until it is in a ledger and returns its
+// outcome, successful or not.
+func (b *Bootstrapper) await(ctx context.Context, signedXDR, hash string) (*soroban.TransactionOutcome, error) {
+	broadcast, err := b.broadcaster.submit(ctx, signedXDR, hash)
+	if err != nil {
+		return nil, err
+	}
+	for !broadcast.Final() {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s: %w", hash, ctx.Err())
+		case <-time.After(bootstrapPollInterval):
+		}
+		broadcast, _ = b.broadcaster.get(hash)
+	}
+	if broadcast.State != BroadcastDone {
+		return nil, fmt.Errorf("%w: transaction %s %s: %s", ErrBootstrapFailed, hash, broadcast.State, broadcast.Error)
+	}
+
+	tx, err := b.sorobanClient.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", hash, err)
+	}
+	return tx.Outcome(hash)
+}
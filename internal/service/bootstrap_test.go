@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/stellar/go-stellar-sdk/keypair"
+)
+
+func TestParseCollateral(t *testing.T) {
+	native, err := ParseCollateral("native", config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("ParseCollateral(native) error = %v", err)
+	}
+	if native.Asset == nil || native.ContractID != "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC" {
+		t.Errorf("ParseCollateral(native) = %+v, want the testnet XLM asset contract", native)
+	}
+	if xlm, err := ParseCollateral(" XLM ", config.TestnetNetworkPassphrase); err != nil || xlm.ContractID != native.ContractID {
+		t.Errorf("ParseCollateral(XLM) = %+v, %v; want the same contract as native", xlm, err)
+	}
+
+	issuer := keypair.MustRandom().Address()
+	credit, err := ParseCollateral("EURMTL:"+issuer, config.TestnetNetworkPassphrase)
+	if err != nil || credit.Asset == nil || credit.ContractID == native.ContractID {
+		t.Errorf("ParseCollateral(EURMTL) = %+v, %v; want its own asset contract", credit, err)
+	}
+
+	token, err := ParseCollateral(native.ContractID, config.TestnetNetworkPassphrase)
+	if err != nil || token.Asset != nil || token.ContractID != native.ContractID {
+		t.Errorf("ParseCollateral(contract) = %+v, %v; want the contract without an asset", token, err)
+	}
+
+	for _, bad := range []string{"", "EURMTL", "EURMTL:not-an-issuer", "TOOLONGASSETCODE:" + issuer} {
+		if _, err := ParseCollateral(bad, config.TestnetNetworkPassphrase); !errors.Is(err, ErrInvalidCollateral) {
+			t.Errorf("ParseCollateral(%q) error = %v, want %v", bad, err, ErrInvalidCollateral)
+		}
+	}
+}
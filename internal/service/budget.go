@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mtlprog/total/internal/lmsr"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ErrBudgetTooSmall is returned when a collateral budget does not buy any tokens.
+var ErrBudgetTooSmall = errors.New("budget is too small to buy any tokens")
+
+// budgetQuoteAttempts bounds how often QuoteForBudget scales the token amount down
+// when the contract quotes more than the budget.
+const budgetQuoteAttempts = 3
+
+// BudgetQuote is a buy quote for the most tokens a collateral budget buys.
+type BudgetQuote struct {
+	Shares float64 // Tokens to buy, the amount the quote token is issued for
+	*Quote
+}
+
+// QuoteForBudget quotes buying as many outcome tokens as budget (in collateral) pays for.
+// The token amount comes from inverting the LMSR cost with the market's liquidity
+// parameter and sold quantities; the quote itself is the contract's, so the cost is
+// exact. When contract rounding or a trade in between puts the cost over the budget,
+// the amount is scaled down and quoted again.
+func (s *MarketService) QuoteForBudget(ctx context.Context, contractID string, outcome model.Outcome, budget float64) (*BudgetQuote, error) {
+	if budget <= 0 {
+		return nil, lmsr.ErrNegativeAmount
+	}
+	if err := s.limits.checkCost(budget); err != nil {
+		return nil, err
+	}
+	budgetScaled, err := safeFloatToInt64(budget * float64(soroban.ScaleFactor))
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget: %w", err)
+	}
+
+	liquidity, err := s.liquidityParam(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	yesSold, noSold, err := s.soldQuantities(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	calc, err := lmsr.New(liquidity)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := calc.SharesForCost(yesSold, noSold, budget, string(outcome))
+	if err != nil {
+		return nil, err
+	}
+
+	for range budgetQuoteAttempts {
+		shares = math.Floor(shares*float64(soroban.ScaleFactor)) / float64(soroban.ScaleFactor)
+		if shares <= 0 {
+			return nil, ErrBudgetTooSmall
+		}
+		quote, err := s.GetQuote(ctx, contractID, outcome, shares)
+		if err != nil {
+			return nil, err
+		}
+		if quote.Cost <= budgetScaled {
+			return &BudgetQuote{Shares: shares, Quote: quote}, nil
+		}
+		shares *= float64(budgetScaled) / float64(quote.Cost)
+	}
+	return nil, fmt.Errorf("no quote within budget %.7f after %d attempts", budget, budgetQuoteAttempts)
+}
+
+// liquidityParam returns the LMSR liquidity parameter b of a market, in tokens.
+func (s *MarketService) liquidityParam(ctx context.Context, contractID string) (float64, error) {
+	txXDR, err := s.txBuilder.BuildGetLiquidityParamTx(ctx, stellar.GetLiquidityParamTxParams{
+		UserPublicKey: s.oraclePublicKey,
+		ContractID:    contractID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to build get_liquidity_param tx: %w", err)
+	}
+	val, err := simulateReturnValue(ctx, s.sorobanClient, txXDR)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get liquidity parameter: %w", err)
+	}
+	b, err := soroban.DecodeI128(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode liquidity parameter: %w", err)
+	}
+	return float64(b) / float64(soroban.ScaleFactor), nil
+}
+
+// soldQuantities returns the YES and NO tokens sold by a market, in tokens.
+func (s *MarketService) soldQuantities(ctx context.Context, contractID string) (yesSold, noSold float64, err error) {
+	txXDR, err := s.txBuilder.BuildGetStateTx(ctx, stellar.GetStateTxParams{
+		UserPublicKey: s.oraclePublicKey,
+		ContractID:    contractID,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to build get_state tx: %w", err)
+	}
+	val, err := simulateReturnValue(ctx, s.sorobanClient, txXDR)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get market state: %w", err)
+	}
+
+	// get_state returns (yes_sold, no_sold, pool, resolved)
+	tuple, err := soroban.DecodeVec(val)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode state tuple: %w", err)
+	}
+	if len(tuple) < 2 {
+		return 0, 0, fmt.Errorf("expected at least 2 elements in state tuple, got %d", len(tuple))
+	}
+	yes, err := soroban.DecodeI128(tuple[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode yes_sold: %w", err)
+	}
+	no, err := soroban.DecodeI128(tuple[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode no_sold: %w", err)
+	}
+	return float64(yes) / float64(soroban.ScaleFactor), float64(no) / float64(soroban.ScaleFactor), nil
+}
+
+// simulateReturnValue simulates a read-only contract call and returns its result.
+func simulateReturnValue(ctx context.Context, client *soroban.Client, txXDR string) (xdr.ScVal, error) {
+	simResult, err := client.SimulateTransaction(ctx, txXDR)
+	if err != nil {
+		return xdr.ScVal{}, err
+	}
+	if simResult.Error != "" {
+		return xdr.ScVal{}, fmt.Errorf("simulation error: %s", simResult.Error)
+	}
+	if len(simResult.Results) == 0 || simResult.Results[0].XDR == "" {
+		return xdr.ScVal{}, fmt.Errorf("no result from simulation")
+	}
+	return soroban.ParseReturnValue(simResult.Results[0].XDR)
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+)
+
+const (
+	// MaxBulkMarkets bounds the markets of one bulk deploy.
+	MaxBulkMarkets = 100
+	// MaxBulkMarketsBytes bounds the text of a market list: MaxBulkMarkets rows with
+	// every text field at its limit and quoted (CSV or JSON escaping doubles a quote).
+	MaxBulkMarketsBytes = MaxBulkMarkets * (2*(model.MaxQuestionLength+model.MaxDescriptionLength+model.MaxResolutionSourceLength) + bulkRowOverhead)
+
+	bulkRowOverhead = 512 // Category, close time, numbers and JSON keys of one row
+
+	maxDeployBatches = 50 // Batches kept, oldest dropped first
+)
+
+var (
+	// ErrInvalidBulkMarkets is returned when an uploaded market list cannot be read.
+	ErrInvalidBulkMarkets = errors.New("invalid market list")
+	// ErrDeployBatchNotFound is returned for an unknown bulk deploy batch.
+	ErrDeployBatchNotFound = errors.New("deploy batch not found")
+	// ErrDeployBatchDone is returned when every market of a batch is deployed.
+	ErrDeployBatchDone = errors.New("every market of this batch is deployed")
+	// ErrDeployInFlight is returned while the last deploy transaction built for a batch
+	// may still land. Deploys use a random salt, so building it again risks a duplicate.
+	ErrDeployInFlight = errors.New("the previous deploy transaction of this batch may still land")
+)
+
+// bulkTimeFormats are the accepted close time formats besides RFC 3339, read as UTC.
+var bulkTimeFormats = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// BulkMarket is one row of a bulk deploy upload.
+type BulkMarket struct {
+	Question         string  `json:"question"`
+	Description      string  `json:"description"`
+	ResolutionSource string  `json:"resolution_source"`
+	Category         string  `json:"category"`
+	CloseTime        string  `json:"close_time"`
+	LiquidityParam   float64 `json:"liquidity_param"`
+	InitialFunding   float64 `json:"initial_funding"`
+}
+
+// BulkRowError is a problem with one row of a bulk deploy upload.
+type BulkRowError struct {
+	Row int // 1-based, not counting a CSV header
+	Err error
+}
+
+func (e *BulkRowError) Error() string { return fmt.Sprintf("row %d: %v", e.Row, e.Err) }
+
+func (e *BulkRowError) Unwrap() error { return e.Err }
+
+// ParseBulkMarkets reads a market list: a JSON array of BulkMarket objects, or CSV
+// with a header row naming the columns question, resolution_source, close_time,
+// liquidity_param (or b), initial_funding (or funding), and optionally description
+// and category.
+func ParseBulkMarkets(data []byte) ([]BulkMarket, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: no markets", ErrInvalidBulkMarkets)
+	}
+	var markets []BulkMarket
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &markets); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBulkMarkets, err)
+		}
+	} else {
+		var err error
+		if markets, err = parseBulkCSV(data); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case len(markets) == 0:
+		return nil, fmt.Errorf("%w: no markets", ErrInvalidBulkMarkets)
+	case len(markets) > MaxBulkMarkets:
+		return nil, fmt.Errorf("%w: %d markets, at most %d per upload", ErrInvalidBulkMarkets, len(markets), MaxBulkMarkets)
+	}
+	return markets, nil
+}
+
+func parseBulkCSV(data []byte) ([]BulkMarket, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulkMarkets, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "b":
+			name = "liquidity_param"
+		case "funding":
+			name = "initial_funding"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"question", "resolution_source", "close_time", "liquidity_param", "initial_funding"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidBulkMarkets, required)
+		}
+	}
+
+	var markets []BulkMarket
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBulkMarkets, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			f, err := strconv.ParseFloat(field(name), 64)
+			if err != nil {
+				return 0, &BulkRowError{Row: row, Err: fmt.Errorf("invalid %s %q", name, field(name))}
+			}
+			return f, nil
+		}
+		m := BulkMarket{
+			Question:         field("question"),
+			Description:      field("description"),
+			ResolutionSource: field("resolution_source"),
+			Category:         field("category"),
+			CloseTime:        field("close_time"),
+		}
+		if m.LiquidityParam, err = number("liquidity_param"); err != nil {
+			return nil, err
+		}
+		if m.InitialFunding, err = number("initial_funding"); err != nil {
+			return nil, err
+		}
+		markets = append(markets, m)
+	}
+	return markets, nil
+}
+
+// parseCloseTime reads a close time in RFC 3339 or one of bulkTimeFormats.
+func parseCloseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range bulkTimeFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid close time %q, use RFC 3339 or YYYY-MM-DD HH:MM (UTC)", s)
+}
+
+// ValidateBulkMarkets checks every row as a deploy would and returns the metadata to
+// pin for each. All problems are reported together, one BulkRowError per row.
+func ValidateBulkMarkets(markets []BulkMarket, createdBy string, now time.Time) ([]model.MarketMetadata, error) {
+	metadata := make([]model.MarketMetadata, len(markets))
+	var errs []error
+	questions := make(map[string]int, len(markets))
+	for i, m := range markets {
+		row := i + 1
+		if err := checkDeployFunding(m.LiquidityParam, m.InitialFunding); err != nil {
+			errs = append(errs, &BulkRowError{Row: row, Err: err})
+			continue
+		}
+		closeTime, err := parseCloseTime(m.CloseTime)
+		if err != nil {
+			errs = append(errs, &BulkRowError{Row: row, Err: err})
+			continue
+		}
+		if !closeTime.After(now) {
+			errs = append(errs, &BulkRowError{Row: row, Err: model.ErrCloseTimeInPast})
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(m.Question))
+		if first, ok := questions[key]; ok {
+			errs = append(errs, &BulkRowError{Row: row, Err: fmt.Errorf("same question as row %d", first)})
+			continue
+		}
+		questions[key] = row
+		normalized, err := ipfs.NormalizeMetadata(&model.MarketMetadata{
+			Question:         m.Question,
+			Description:      m.Description,
+			ResolutionSource: m.ResolutionSource,
+			Category:         m.Category,
+			EndDate:          closeTime,
+			CreatedAt:        now.UTC(),
+			CreatedBy:        createdBy,
+		})
+		if err != nil {
+			errs = append(errs, &BulkRowError{Row: row, Err: err})
+			continue
+		}
+		metadata[i] = normalized
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return metadata, nil
+}
+
+// DeployBatchItem is one market of a bulk deploy.
+type DeployBatchItem struct {
+	Question       string    `json:"question"`
+	EndDate        time.Time `json:"end_date"`
+	MetadataHash   string    `json:"metadata_hash"`
+	LiquidityParam float64   `json:"liquidity_param"`
+	InitialFunding float64   `json:"initial_funding"`
+	ContractID     string    `json:"contract_id,omitempty"` // Set once deployed
+	BuiltUntil     time.Time `json:"built_until,omitzero"`  // Expiry of the last deploy transaction built
+}
+
+// DeployBatch is an uploaded list of markets with pinned metadata, deployed one
+// transaction at a time.
+type DeployBatch struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Items     []DeployBatchItem `json:"items"`
+}
+
+// Deployed returns how many markets of the batch are deployed.
+func (b DeployBatch) Deployed() int {
+	var n int
+	for _, item := range b.Items {
+		if item.ContractID != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// BulkDeployService pins the metadata of uploaded market lists and builds their
+// deploy transactions one at a time: each is built only after the previous one landed,
+// so it carries the oracle account's next sequence number. Batches are kept in memory,
+// optionally persisted to a JSON file.
+type BulkDeployService struct {
+	factoryService *FactoryService
+	ipfsClient     *ipfs.Client
+	path           string // Empty keeps batches in memory only
+	logger         *slog.Logger
+
+	mu      sync.Mutex
+	batches []DeployBatch // Oldest first
+}
+
+// NewBulkDeployService creates a bulk deploy service and loads existing batches from path, if set.
+func NewBulkDeployService(factoryService *FactoryService, ipfsClient *ipfs.Client, path string, logger *slog.Logger) (*BulkDeployService, error) {
+	if factoryService == nil {
+		panic("NewBulkDeployService: factoryService must not be nil")
+	}
+	if ipfsClient == nil {
+		panic("NewBulkDeployService: ipfsClient must not be nil")
+	}
+	if logger == nil {
+		panic("NewBulkDeployService: logger must not be nil")
+	}
+
+	s := &BulkDeployService{
+		factoryService: factoryService,
+		ipfsClient:     ipfsClient,
+		path:           path,
+		logger:         logger,
+	}
+	if path == "" {
+		return s, nil
+	}
+	if _, err := readJSONFile(path, &s.batches); err != nil {
+		return nil, fmt.Errorf("failed to load deploy batches: %w", err)
+	}
+	return s, nil
+}
+
+// CanPin reports whether metadata can be pinned, which a bulk deploy needs.
+func (s *BulkDeployService) CanPin() bool {
+	return s.ipfsClient.CanPin()
+}
+
+// Prepare validates every market, pins the metadata of each and stores them as a new
+// batch. Nothing is pinned unless all rows are valid.
+func (s *BulkDeployService) Prepare(ctx context.Context, markets []BulkMarket, createdBy string) (DeployBatch, error) {
+	metadata, err := ValidateBulkMarkets(markets, createdBy, time.Now())
+	if err != nil {
+		return DeployBatch{}, err
+	}
+	id, err := newDeployBatchID()
+	if err != nil {
+		return DeployBatch{}, err
+	}
+
+	batch := DeployBatch{ID: id, CreatedAt: time.Now().UTC(), Items: make([]DeployBatchItem, len(markets))}
+	for i, m := range metadata {
+		cid, err := s.ipfsClient.PinJSON(ctx, m)
+		if err != nil {
+			return DeployBatch{}, &BulkRowError{Row: i + 1, Err: fmt.Errorf("failed to pin metadata: %w", err)}
+		}
+		batch.Items[i] = DeployBatchItem{
+			Question:       m.Question,
+			EndDate:        m.EndDate,
+			MetadataHash:   cid,
+			LiquidityParam: markets[i].LiquidityParam,
+			InitialFunding: markets[i].InitialFunding,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	if len(s.batches) > maxDeployBatches {
+		s.batches = slices.Delete(s.batches, 0, len(s.batches)-maxDeployBatches)
+	}
+	if err := s.saveLocked(); err != nil {
+		return DeployBatch{}, err
+	}
+	s.logger.Info("prepared bulk deploy", "batch", id, "markets", len(batch.Items))
+	return batch, nil
+}
+
+// List returns the batches, newest first.
+func (s *BulkDeployService) List() []DeployBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches := slices.Clone(s.batches)
+	slices.Reverse(batches)
+	return batches
+}
+
+// Batch returns a batch with the markets deployed so far, matched to the factory's
+// markets by metadata hash.
+func (s *BulkDeployService) Batch(ctx context.Context, id string) (DeployBatch, error) {
+	s.mu.Lock()
+	i := slices.IndexFunc(s.batches, func(b DeployBatch) bool { return b.ID == id })
+	if i < 0 {
+		s.mu.Unlock()
+		return DeployBatch{}, ErrDeployBatchNotFound
+	}
+	batch := s.batches[i]
+	batch.Items = slices.Clone(batch.Items)
+	s.mu.Unlock()
+	if batch.Deployed() == len(batch.Items) {
+		return batch, nil
+	}
+
+	contractIDs, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return DeployBatch{}, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		s.logger.Warn("bulk deploy: failed to get some market states", "error", err)
+	}
+	byHash := make(map[string]string, len(loaded.States))
+	for _, st := range loaded.States {
+		if st.MetadataHash != "" {
+			byHash[st.MetadataHash] = st.ContractID
+		}
+	}
+	changed := false
+	for j, item := range batch.Items {
+		if contractID := byHash[item.MetadataHash]; item.ContractID == "" && contractID != "" {
+			batch.Items[j].ContractID = contractID
+			changed = true
+		}
+	}
+	if !changed {
+		return batch, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.batches, func(b DeployBatch) bool { return b.ID == id }); i >= 0 {
+		s.batches[i] = batch
+	}
+	if err := s.saveLocked(); err != nil {
+		s.logger.Warn("bulk deploy: failed to save batch progress", "batch", id, "error", err)
+	}
+	return batch, nil
+}
+
+// Next returns the position and deploy request of the first market of a batch that
+// is not deployed yet. While a transaction built for it is still valid, it returns
+// ErrDeployInFlight unless force is set.
+func (s *BulkDeployService) Next(ctx context.Context, id string, force bool) (int, DeployMarketRequest, error) {
+	batch, err := s.Batch(ctx, id)
+	if err != nil {
+		return 0, DeployMarketRequest{}, err
+	}
+	i := slices.IndexFunc(batch.Items, func(item DeployBatchItem) bool { return item.ContractID == "" })
+	if i < 0 {
+		return 0, DeployMarketRequest{}, ErrDeployBatchDone
+	}
+	item := batch.Items[i]
+	if !force && time.Now().Before(item.BuiltUntil) {
+		return i, DeployMarketRequest{}, ErrDeployInFlight
+	}
+	return i, DeployMarketRequest{
+		LiquidityParam: item.LiquidityParam,
+		MetadataHash:   item.MetadataHash,
+		InitialFunding: item.InitialFunding,
+	}, nil
+}
+
+// Built records the deploy transaction built for a market of a batch, so it is not
+// built again while it may still land.
+func (s *BulkDeployService) Built(id string, index int, txXDR string, authExpiresAt time.Time) error {
+	until, err := transactionExpiry(txXDR)
+	if err != nil {
+		return err
+	}
+	if until.IsZero() || !authExpiresAt.IsZero() && authExpiresAt.Before(until) {
+		until = authExpiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.batches, func(b DeployBatch) bool { return b.ID == id })
+	if i < 0 || index < 0 || index >= len(s.batches[i].Items) {
+		return ErrDeployBatchNotFound
+	}
+	s.batches[i].Items[index].BuiltUntil = until
+	return s.saveLocked()
+}
+
+// Delete forgets a batch. Markets already deployed are not affected.
+func (s *BulkDeployService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.batches, func(b DeployBatch) bool { return b.ID == id })
+	if i < 0 {
+		return ErrDeployBatchNotFound
+	}
+	s.batches = slices.Delete(s.batches, i, i+1)
+	return s.saveLocked()
+}
+
+// saveLocked writes all batches to the file atomically. Must be called with mu held.
+func (s *BulkDeployService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(s.path, s.batches); err != nil {
+		return fmt.Errorf("failed to save deploy batches: %w", err)
+	}
+	return nil
+}
+
+func newDeployBatchID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBulkMarkets(t *testing.T) {
+	csv := `question,resolution_source,close_time,b,funding,category
+"Will it rain in Podgorica on May 1, 2027?",Official weather station,2027-05-01 12:00,100,70,Weather
+Will BTC close above 100k?,https://www.coingecko.com,2027-06-30T00:00:00Z,200,150,`
+	markets, err := ParseBulkMarkets([]byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markets) != 2 || markets[0].Question != "Will it rain in Podgorica on May 1, 2027?" || markets[1].LiquidityParam != 200 || markets[0].Category != "Weather" {
+		t.Fatalf("ParseBulkMarkets(csv) = %+v", markets)
+	}
+
+	json := `[{"question": "Q?", "resolution_source": "News", "close_time": "2027-01-01", "liquidity_param": 100, "initial_funding": 70}]`
+	if markets, err := ParseBulkMarkets([]byte(json)); err != nil || len(markets) != 1 || markets[0].InitialFunding != 70 {
+		t.Fatalf("ParseBulkMarkets(json) = %+v, %v", markets, err)
+	}
+
+	if _, err := ParseBulkMarkets([]byte("question,close_time\nQ?,2027-01-01")); !errors.Is(err, ErrInvalidBulkMarkets) {
+		t.Errorf("ParseBulkMarkets() without funding columns error = %v, want ErrInvalidBulkMarkets", err)
+	}
+}
+
+func TestValidateBulkMarketsReportsEveryRow(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	markets := []BulkMarket{
+		{Question: "Valid?", ResolutionSource: "News", CloseTime: "2027-01-01", LiquidityParam: 100, InitialFunding: 70},
+		{Question: "Underfunded?", ResolutionSource: "News", CloseTime: "2027-01-01", LiquidityParam: 100, InitialFunding: 10},
+		{Question: "Closed?", ResolutionSource: "News", CloseTime: "2026-01-01", LiquidityParam: 100, InitialFunding: 70},
+		{Question: "valid?", ResolutionSource: "News", CloseTime: "2027-01-01", LiquidityParam: 100, InitialFunding: 70},
+		{Question: "", ResolutionSource: "News", CloseTime: "2027-01-01", LiquidityParam: 100, InitialFunding: 70},
+	}
+	_, err := ValidateBulkMarkets(markets, "GORACLE", now)
+	if err == nil {
+		t.Fatal("ValidateBulkMarkets() = nil, want row errors")
+	}
+	for _, row := range []string{"row 2:", "row 3:", "row 4:", "row 5:"} {
+		if !strings.Contains(err.Error(), row) {
+			t.Errorf("ValidateBulkMarkets() error %q does not report %s", err, row)
+		}
+	}
+	if strings.Contains(err.Error(), "row 1:") {
+		t.Errorf("ValidateBulkMarkets() error %q reports the valid row", err)
+	}
+
+	metadata, err := ValidateBulkMarkets(markets[:1], "GORACLE", now)
+	if err != nil || len(metadata) != 1 || metadata[0].CreatedBy != "GORACLE" || !metadata[0].EndDate.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ValidateBulkMarkets(valid) = %+v, %v", metadata, err)
+	}
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/mtlprog/total/internal/cachestats"
 	"github.com/samber/hot"
 )
 
@@ -12,9 +14,28 @@ const (
 	marketStateCacheSize = 500
 )
 
+// StateVersion identifies a market state as seen by the cache. Generation increases
+// each time any cached market state changes; Modified is when this market's state
+// last changed.
+type StateVersion struct {
+	Generation uint64
+	Modified   time.Time
+}
+
+type versionedState struct {
+	state   MarketState
+	version StateVersion
+}
+
 // StateCache provides in-memory caching for market states with stale-while-revalidate.
+// It also tracks when each market's state last changed, so clients can poll cheaply.
 type StateCache struct {
 	cache *hot.HotCache[string, MarketState]
+	stats cachestats.Counter
+
+	mu         sync.Mutex
+	generation uint64
+	versions   map[string]versionedState
 }
 
 // NewStateCache creates a new market state cache.
@@ -23,12 +44,19 @@ func NewStateCache(loader func(ids []string) (map[string]MarketState, error)) *S
 	if loader == nil {
 		panic("NewStateCache: loader must not be nil")
 	}
-	c := hot.NewHotCache[string, MarketState](hot.LRU, marketStateCacheSize).
+	sc := &StateCache{versions: make(map[string]versionedState)}
+	sc.cache = hot.NewHotCache[string, MarketState](hot.LRU, marketStateCacheSize).
 		WithTTL(marketStateCacheTTL).
-		WithRevalidation(marketStateCacheTTL, loader).
+		WithRevalidation(marketStateCacheTTL, func(ids []string) (map[string]MarketState, error) {
+			states, err := loader(ids)
+			for id, state := range states {
+				sc.Version(id, state)
+			}
+			return states, err
+		}).
 		WithRevalidationErrorPolicy(hot.KeepOnError).
 		Build()
-	return &StateCache{cache: c}
+	return sc
 }
 
 // Get returns a cached market state if available.
@@ -36,15 +64,44 @@ func (sc *StateCache) Get(id string) (MarketState, bool) {
 	val, found, err := sc.cache.Get(id)
 	if err != nil {
 		slog.Warn("state cache error, treating as miss", "id", id, "error", err)
+		sc.stats.Miss()
 		return MarketState{}, false
 	}
 	if !found {
+		sc.stats.Miss()
 		return MarketState{}, false
 	}
+	sc.stats.Hit()
 	return val, true
 }
 
+// Peek returns a cached market state without counting a lookup or triggering a load.
+func (sc *StateCache) Peek(id string) (MarketState, bool) {
+	return sc.cache.Peek(id)
+}
+
+// Stats returns hit and miss counts.
+func (sc *StateCache) Stats() cachestats.Stats {
+	return sc.stats.Stats()
+}
+
 // Set stores a market state in the cache.
 func (sc *StateCache) Set(id string, state MarketState) {
+	sc.Version(id, state)
 	sc.cache.Set(id, state)
 }
+
+// Version returns the version of a market state, starting a new generation when it
+// differs from the state last seen for the market.
+func (sc *StateCache) Version(id string, state MarketState) StateVersion {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if v, ok := sc.versions[id]; ok && v.state == state {
+		return v.version
+	}
+	sc.generation++
+	v := versionedState{state: state, version: StateVersion{Generation: sc.generation, Modified: time.Now()}}
+	sc.versions[id] = v
+	return v.version
+}
@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestStateCacheVersion(t *testing.T) {
+	sc := NewStateCache(func([]string) (map[string]MarketState, error) { return nil, nil })
+
+	state := MarketState{ContractID: "A", YesSold: 10, PriceYes: 0.6, PriceNo: 0.4}
+	v1 := sc.Version("A", state)
+	if v1.Generation == 0 || v1.Modified.IsZero() {
+		t.Fatalf("first version = %+v, want a generation and modification time", v1)
+	}
+	if v := sc.Version("A", state); v != v1 {
+		t.Errorf("unchanged state version = %+v, want %+v", v, v1)
+	}
+
+	sc.Set("B", MarketState{ContractID: "B"})
+	if v := sc.Version("A", state); v != v1 {
+		t.Error("changes of other markets must not change the version")
+	}
+
+	state.YesSold = 20
+	v2 := sc.Version("A", state)
+	if v2.Generation <= v1.Generation {
+		t.Errorf("changed state generation = %d, want > %d", v2.Generation, v1.Generation)
+	}
+}
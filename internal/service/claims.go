@@ -0,0 +1,458 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// ClaimRefreshInterval is how often holders and unclaimed winnings are tracked.
+	ClaimRefreshInterval = 15 * time.Minute
+	// ClaimReminderInterval is how often winners are reminded of closing claim periods.
+	ClaimReminderInterval = time.Hour
+	// DefaultClaimPeriod is how long after resolution winners are expected to claim.
+	DefaultClaimPeriod = 30 * 24 * time.Hour
+	// ClaimFeeRate is the share of a payout kept in the pool by claim (CLAIM_FEE_BPS
+	// in the market contract).
+	ClaimFeeRate = 0.02
+
+	claimReminderBefore = 7 * 24 * time.Hour // Reminders go out this long before the deadline
+	maxClaimHolders     = 1000               // Tracked accounts per market
+	claimChecksPerRun   = 50                 // Balance lookups per refresh; each costs two simulations
+)
+
+// claimMarket is what is known about the holders of one market. Holders are the
+// accounts seen buying while the market was open; the trade event window is short,
+// so markets that traded before tracking started have incomplete holder lists.
+type claimMarket struct {
+	ContractID string             `json:"contract_id"`
+	Holders    []string           `json:"holders,omitempty"`
+	ResolvedAt time.Time          `json:"resolved_at,omitzero"` // Resolve event time, or when first seen resolved
+	Outcome    string             `json:"outcome,omitempty"`
+	Pool       float64            `json:"pool,omitempty"`      // Collateral in the contract at the last refresh
+	Unclaimed  map[string]float64 `json:"unclaimed,omitempty"` // Holder -> winning tokens at the last check; 0 once claimed
+	Reminded   bool               `json:"reminded,omitempty"`
+}
+
+// unclaimedTotal returns the known unclaimed winning tokens and how many holders hold them.
+func (m *claimMarket) unclaimedTotal() (tokens float64, holders int) {
+	for _, amount := range m.Unclaimed {
+		if amount > 0 {
+			tokens += amount
+			holders++
+		}
+	}
+	return tokens, holders
+}
+
+// UnclaimedWinning is an account's winning tokens in a resolved market that are not
+// claimed yet.
+type UnclaimedWinning struct {
+	ContractID string
+	Outcome    string
+	Amount     float64 // Winning tokens; each pays 1 collateral unit minus the claim fee
+	ResolvedAt time.Time
+	Deadline   time.Time
+}
+
+// SweepEntry is a resolved market whose claim period has ended, for the oracle's
+// withdraw_remaining report.
+type SweepEntry struct {
+	ContractID string
+	Outcome    string
+	ResolvedAt time.Time
+	Deadline   time.Time
+	Pool       float64 // Collateral left in the contract
+	Unclaimed  float64 // Known unclaimed winning tokens of tracked holders
+	Holders    int     // Tracked holders that have not claimed
+	// MaxWithdrawable bounds what withdraw_remaining pays out: the contract keeps
+	// collateral for every unclaimed winning token, including untracked holders'.
+	MaxWithdrawable float64
+}
+
+// ClaimService tracks unclaimed winnings after resolution. Holders are collected from
+// trade events while markets are open; once a market resolves, their winning balances
+// are checked until claimed. Winners are reminded over their market alert channels
+// before the claim period ends, and the oracle gets a report of markets past it.
+// The claim period is a policy of this site: the contract itself has no deadline and
+// keeps collateral for unclaimed winners even after withdraw_remaining.
+type ClaimService struct {
+	factoryService *FactoryService
+	marketService  *MarketService
+	eventService   *EventService
+	alerts         *AlertService // nil disables reminders
+	claimPeriod    time.Duration
+	publicURL      string // Base URL for market links in reminders; empty omits links
+	path           string // Empty keeps claim tracking in memory only
+	logger         *slog.Logger
+
+	mu      sync.Mutex
+	markets map[string]*claimMarket
+}
+
+// NewClaimService creates a claim tracker and loads its state from path, if set.
+// A claimPeriod of zero or less means DefaultClaimPeriod.
+func NewClaimService(
+	factoryService *FactoryService,
+	marketService *MarketService,
+	eventService *EventService,
+	alerts *AlertService,
+	claimPeriod time.Duration,
+	publicURL string,
+	path string,
+	logger *slog.Logger,
+) (*ClaimService, error) {
+	if factoryService == nil {
+		panic("NewClaimService: factoryService must not be nil")
+	}
+	if marketService == nil {
+		panic("NewClaimService: marketService must not be nil")
+	}
+	if eventService == nil {
+		panic("NewClaimService: eventService must not be nil")
+	}
+	if logger == nil {
+		panic("NewClaimService: logger must not be nil")
+	}
+	if claimPeriod <= 0 {
+		claimPeriod = DefaultClaimPeriod
+	}
+
+	s := &ClaimService{
+		factoryService: factoryService,
+		marketService:  marketService,
+		eventService:   eventService,
+		alerts:         alerts,
+		claimPeriod:    claimPeriod,
+		publicURL:      publicURL,
+		path:           path,
+		logger:         logger,
+		markets:        make(map[string]*claimMarket),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var markets []*claimMarket
+	if _, err := readJSONFile(path, &markets); err != nil {
+		return nil, fmt.Errorf("failed to load claims file: %w", err)
+	}
+	for _, m := range markets {
+		s.markets[m.ContractID] = m
+	}
+	return s, nil
+}
+
+// ClaimPeriod returns how long after resolution winners are expected to claim.
+func (s *ClaimService) ClaimPeriod() time.Duration {
+	return s.claimPeriod
+}
+
+// Deadline returns the end of the claim period of a resolved market.
+// Returns false for markets not seen resolved yet.
+func (s *ClaimService) Deadline(contractID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.markets[contractID]
+	if !ok || m.ResolvedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return m.ResolvedAt.Add(s.claimPeriod), true
+}
+
+// Refresh records the buyers of open markets, notes newly resolved markets and checks
+// the winning balances of their holders, a batch per run.
+func (s *ClaimService) Refresh(ctx context.Context) error {
+	if !s.factoryService.HasFactory() {
+		return nil
+	}
+	contractIDs, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, stateErr := s.factoryService.GetMarketStates(ctx, contractIDs)
+	if stateErr != nil {
+		return fmt.Errorf("failed to get market states: %w", stateErr)
+	}
+	states := loaded.States
+
+	var errs []error
+	for _, st := range states {
+		if st.Resolved {
+			s.trackResolution(ctx, st)
+			continue
+		}
+		events, err := s.eventService.GetTradeEvents(ctx, st.ContractID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", st.ContractID, err))
+			continue
+		}
+		s.trackHolders(st.ContractID, events)
+	}
+	errs = append(errs, s.checkBalances(ctx))
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+
+	if stateErr != nil {
+		s.logger.Warn("claims: failed to get some market states", "error", stateErr)
+	}
+	return errors.Join(errs...)
+}
+
+// trackHolders adds the buyers among events to the market's holders.
+func (s *ClaimService) trackHolders(contractID string, events []TradeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.markets[contractID]
+	for _, evt := range events {
+		if evt.Kind != TradeKindBuy || evt.User == "" {
+			continue
+		}
+		if m == nil {
+			m = &claimMarket{ContractID: contractID}
+			s.markets[contractID] = m
+		}
+		i, found := slices.BinarySearch(m.Holders, evt.User)
+		if found || len(m.Holders) >= maxClaimHolders {
+			continue
+		}
+		m.Holders = slices.Insert(m.Holders, i, evt.User)
+	}
+}
+
+// trackResolution records when a market resolved and the collateral left in it.
+func (s *ClaimService) trackResolution(ctx context.Context, st MarketState) {
+	s.mu.Lock()
+	m := s.markets[st.ContractID]
+	if m == nil {
+		m = &claimMarket{ContractID: st.ContractID}
+		s.markets[st.ContractID] = m
+	}
+	m.Outcome = st.WinningOutcome
+	m.Pool = float64(st.Pool) / float64(soroban.ScaleFactor)
+	known := !m.ResolvedAt.IsZero()
+	s.mu.Unlock()
+	if known {
+		return
+	}
+
+	resolvedAt := time.Now().UTC()
+	resolution, found, err := s.eventService.GetResolution(ctx, st.ContractID)
+	if err != nil {
+		s.logger.Warn("claims: failed to look up resolve event", "contract_id", st.ContractID, "error", err)
+	} else if found {
+		resolvedAt = resolution.Timestamp.UTC()
+	}
+
+	s.mu.Lock()
+	m.ResolvedAt = resolvedAt
+	s.mu.Unlock()
+}
+
+// checkBalances looks up the winning balances of holders of resolved markets: first
+// the ones never checked, then the ones that have not claimed yet.
+func (s *ClaimService) checkBalances(ctx context.Context) error {
+	type check struct {
+		contractID, account, outcome string
+		unchecked                    bool
+	}
+	s.mu.Lock()
+	var checks []check
+	for _, m := range s.markets {
+		if m.ResolvedAt.IsZero() {
+			continue
+		}
+		for _, account := range m.Holders {
+			amount, checked := m.Unclaimed[account]
+			if !checked || amount > 0 {
+				checks = append(checks, check{m.ContractID, account, m.Outcome, !checked})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortStableFunc(checks, func(a, b check) int {
+		if a.unchecked != b.unchecked {
+			if a.unchecked {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+	if len(checks) > claimChecksPerRun {
+		checks = checks[:claimChecksPerRun]
+	}
+
+	var errs []error
+	for _, c := range checks {
+		balance, err := s.marketService.GetBalance(ctx, c.contractID, c.account)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("balance of %s in %s: %w", c.account, c.contractID, err))
+			continue
+		}
+		amount := balance.NoBalance
+		if c.outcome == "YES" {
+			amount = balance.YesBalance
+		}
+
+		s.mu.Lock()
+		if m := s.markets[c.contractID]; m != nil {
+			if m.Unclaimed == nil {
+				m.Unclaimed = make(map[string]float64)
+			}
+			m.Unclaimed[c.account] = amount
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// ForAccount returns the account's unclaimed winnings, nearest deadline first.
+func (s *ClaimService) ForAccount(account string) []UnclaimedWinning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var winnings []UnclaimedWinning
+	for _, m := range s.markets {
+		if amount := m.Unclaimed[account]; amount > 0 && !m.ResolvedAt.IsZero() {
+			winnings = append(winnings, UnclaimedWinning{
+				ContractID: m.ContractID,
+				Outcome:    m.Outcome,
+				Amount:     amount,
+				ResolvedAt: m.ResolvedAt,
+				Deadline:   m.ResolvedAt.Add(s.claimPeriod),
+			})
+		}
+	}
+	slices.SortFunc(winnings, func(a, b UnclaimedWinning) int {
+		return cmp.Or(a.Deadline.Compare(b.Deadline), cmp.Compare(a.ContractID, b.ContractID))
+	})
+	return winnings
+}
+
+// SweepReport returns the resolved markets whose claim period ended before now and
+// that still hold collateral, oldest deadline first.
+func (s *ClaimService) SweepReport(now time.Time) []SweepEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []SweepEntry
+	for _, m := range s.markets {
+		if m.ResolvedAt.IsZero() || m.Pool <= 0 {
+			continue
+		}
+		deadline := m.ResolvedAt.Add(s.claimPeriod)
+		if now.Before(deadline) {
+			continue
+		}
+		unclaimed, holders := m.unclaimedTotal()
+		entries = append(entries, SweepEntry{
+			ContractID:      m.ContractID,
+			Outcome:         m.Outcome,
+			ResolvedAt:      m.ResolvedAt,
+			Deadline:        deadline,
+			Pool:            m.Pool,
+			Unclaimed:       unclaimed,
+			Holders:         holders,
+			MaxWithdrawable: max(m.Pool-unclaimed*(1-ClaimFeeRate), 0),
+		})
+	}
+	slices.SortFunc(entries, func(a, b SweepEntry) int {
+		return cmp.Or(a.Deadline.Compare(b.Deadline), cmp.Compare(a.ContractID, b.ContractID))
+	})
+	return entries
+}
+
+// dueReminders returns the markets whose claim period ends within claimReminderBefore
+// of now, with known unclaimed winnings and no reminder sent yet.
+func (s *ClaimService) dueReminders(now time.Time) []claimMarket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []claimMarket
+	for _, m := range s.markets {
+		if m.ResolvedAt.IsZero() || m.Reminded {
+			continue
+		}
+		deadline := m.ResolvedAt.Add(s.claimPeriod)
+		if now.Before(deadline.Add(-claimReminderBefore)) || !now.Before(deadline) {
+			continue
+		}
+		if tokens, _ := m.unclaimedTotal(); tokens > 0 {
+			due = append(due, *m)
+		}
+	}
+	slices.SortFunc(due, func(a, b claimMarket) int { return cmp.Compare(a.ContractID, b.ContractID) })
+	return due
+}
+
+// SendReminders notifies the alert subscribers of markets whose claim period is about
+// to end while winnings are unclaimed. Each market is reminded once.
+func (s *ClaimService) SendReminders(ctx context.Context) error {
+	if s.alerts == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, m := range s.dueReminders(time.Now()) {
+		deadline := m.ResolvedAt.Add(s.claimPeriod)
+		msg := notify.Message{
+			Title: "Claim your winnings: market resolved " + m.Outcome,
+			Text: fmt.Sprintf("Market %s resolved %s and winnings are still unclaimed. Holders of %s tokens: the claim period ends %s.",
+				m.ContractID, m.Outcome, m.Outcome, deadline.UTC().Format("2006-01-02 15:04 UTC")),
+		}
+		if s.publicURL != "" {
+			msg.URL = s.publicURL + "/market/" + m.ContractID
+		}
+		if err := s.alerts.NotifyMarket(ctx, m.ContractID, msg); err != nil {
+			errs = append(errs, fmt.Errorf("claim reminder %s: %w", m.ContractID, err))
+			continue
+		}
+
+		s.mu.Lock()
+		if tracked := s.markets[m.ContractID]; tracked != nil {
+			tracked.Reminded = true
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// saveLocked writes the claim tracking state to the file atomically. Must be called
+// with mu held.
+func (s *ClaimService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	markets := make([]*claimMarket, 0, len(s.markets))
+	for _, m := range s.markets {
+		markets = append(markets, m)
+	}
+	slices.SortFunc(markets, func(a, b *claimMarket) int { return cmp.Compare(a.ContractID, b.ContractID) })
+	if err := writeJSONFile(s.path, markets); err != nil {
+		return fmt.Errorf("failed to save claims: %w", err)
+	}
+	return nil
+}
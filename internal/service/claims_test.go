@@ -0,0 +1,80 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestClaimServiceReports(t *testing.T) {
+	const period = 30 * 24 * time.Hour
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	alice, bob := "GALICE", "GBOB"
+	s := &ClaimService{
+		claimPeriod: period,
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		markets: map[string]*claimMarket{
+			// Claim period ended: Bob still holds 10 winning tokens.
+			"expired": {ContractID: "expired", Outcome: "YES", Pool: 50, ResolvedAt: now.Add(-period - time.Hour),
+				Holders: []string{alice, bob}, Unclaimed: map[string]float64{alice: 0, bob: 10}},
+			// Ends in three days: Alice has not claimed.
+			"closing": {ContractID: "closing", Outcome: "NO", Pool: 20, ResolvedAt: now.Add(-period + 3*24*time.Hour),
+				Holders: []string{alice}, Unclaimed: map[string]float64{alice: 5}},
+			// Just resolved, nothing known about balances yet.
+			"fresh": {ContractID: "fresh", Outcome: "YES", Pool: 80, ResolvedAt: now.Add(-time.Hour), Holders: []string{bob}},
+			// Still open.
+			"open": {ContractID: "open", Holders: []string{alice}},
+		},
+	}
+
+	winnings := s.ForAccount(alice)
+	if len(winnings) != 1 || winnings[0].ContractID != "closing" || winnings[0].Amount != 5 {
+		t.Fatalf("ForAccount(alice) = %+v, want 5 tokens in closing", winnings)
+	}
+	if want := now.Add(3 * 24 * time.Hour); !winnings[0].Deadline.Equal(want) {
+		t.Errorf("Deadline = %v, want %v", winnings[0].Deadline, want)
+	}
+	if winnings := s.ForAccount(bob); len(winnings) != 1 || winnings[0].ContractID != "expired" {
+		t.Errorf("ForAccount(bob) = %+v, want expired only", winnings)
+	}
+	if _, ok := s.Deadline("open"); ok {
+		t.Error("Deadline(open) ok = true, want false")
+	}
+
+	report := s.SweepReport(now)
+	if len(report) != 1 || report[0].ContractID != "expired" {
+		t.Fatalf("SweepReport() = %+v, want expired only", report)
+	}
+	if e := report[0]; e.Unclaimed != 10 || e.Holders != 1 || e.MaxWithdrawable != 50-10*(1-ClaimFeeRate) {
+		t.Errorf("SweepReport()[0] = %+v, want 10 unclaimed by 1 holder, 40.2 withdrawable", e)
+	}
+
+	due := s.dueReminders(now)
+	if len(due) != 1 || due[0].ContractID != "closing" {
+		t.Fatalf("dueReminders() = %+v, want closing only", due)
+	}
+	s.markets["closing"].Reminded = true
+	if due := s.dueReminders(now); len(due) != 0 {
+		t.Errorf("dueReminders() after reminding = %+v, want none", due)
+	}
+}
+
+func TestClaimServiceTrackHolders(t *testing.T) {
+	s := &ClaimService{markets: make(map[string]*claimMarket)}
+	s.trackHolders("m", []TradeEvent{
+		{Kind: TradeKindBuy, User: "GB"},
+		{Kind: TradeKindSell, User: "GC"},
+		{Kind: TradeKindBuy, User: "GA"},
+		{Kind: TradeKindBuy, User: "GB"},
+	})
+	s.trackHolders("quiet", []TradeEvent{{Kind: TradeKindSell, User: "GA"}})
+
+	m, ok := s.markets["m"]
+	if !ok || len(m.Holders) != 2 || m.Holders[0] != "GA" || m.Holders[1] != "GB" {
+		t.Errorf("holders = %+v, want sorted buyers GA, GB", m)
+	}
+	if _, ok := s.markets["quiet"]; ok {
+		t.Error("market without buys is tracked")
+	}
+}
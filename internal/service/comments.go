@@ -0,0 +1,239 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// MaxCommentLength is the maximum comment length in characters.
+	MaxCommentLength = 2000
+	// CommentFlagThreshold is the number of flags after which a comment is collapsed for review.
+	CommentFlagThreshold = 3
+
+	commentCooldown      = 15 * time.Second
+	maxCommentsPerMarket = 1000
+)
+
+var (
+	ErrCommentEmpty    = errors.New("comment must not be empty")
+	ErrCommentTooLong  = fmt.Errorf("comment must be at most %d characters", MaxCommentLength)
+	ErrCommentTooFast  = errors.New("please wait before posting another comment")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrCommentsFull    = errors.New("comment limit reached for this market")
+	ErrNotOracle       = errors.New("only the oracle can moderate comments")
+)
+
+// Comment is a post in a market's discussion.
+type Comment struct {
+	ID         string    `json:"id"`
+	ContractID string    `json:"contract_id"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	Official   bool      `json:"official"` // Posted by the oracle account
+	Hidden     bool      `json:"hidden"`   // Hidden by the oracle
+	FlaggedBy  []string  `json:"flagged_by,omitempty"`
+}
+
+// Flagged reports whether enough users flagged the comment to collapse it.
+func (c Comment) Flagged() bool {
+	return len(c.FlaggedBy) >= CommentFlagThreshold
+}
+
+// PostCommentRequest contains parameters for posting a comment.
+type PostCommentRequest struct {
+	ContractID string
+	Author     string // Authenticated account (SEP-10)
+	Body       string
+}
+
+// Validate validates the post comment request.
+func (r *PostCommentRequest) Validate() error {
+	if err := model.ValidateStellarPublicKey(r.Author); err != nil {
+		return err
+	}
+	if err := soroban.ValidateContractID(r.ContractID); err != nil {
+		return err
+	}
+	body := strings.TrimSpace(r.Body)
+	if body == "" {
+		return ErrCommentEmpty
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return ErrCommentTooLong
+	}
+	return nil
+}
+
+// CommentStore keeps market discussions in memory, optionally persisted to a JSON file.
+// The file is rewritten on every change; discussions are small and writes are rare.
+type CommentStore struct {
+	path            string // Empty keeps comments in memory only
+	oraclePublicKey string
+	logger          *slog.Logger
+
+	mu       sync.Mutex
+	comments map[string][]Comment // contract ID -> comments, oldest first
+	lastPost map[string]time.Time // account -> last post time
+}
+
+// NewCommentStore creates a comment store and loads existing comments from path, if set.
+func NewCommentStore(path, oraclePublicKey string, logger *slog.Logger) (*CommentStore, error) {
+	if logger == nil {
+		panic("NewCommentStore: logger must not be nil")
+	}
+	s := &CommentStore{
+		path:            path,
+		oraclePublicKey: oraclePublicKey,
+		logger:          logger,
+		comments:        make(map[string][]Comment),
+		lastPost:        make(map[string]time.Time),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var all []Comment
+	if _, err := readJSONFile(path, &all); err != nil {
+		return nil, fmt.Errorf("failed to load comments file: %w", err)
+	}
+	for _, c := range all {
+		s.comments[c.ContractID] = append(s.comments[c.ContractID], c)
+	}
+	return s, nil
+}
+
+// List returns the comments of a market, oldest first.
+func (s *CommentStore) List(contractID string) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := slices.Clone(s.comments[contractID])
+	for i := range comments {
+		comments[i].FlaggedBy = slices.Clone(comments[i].FlaggedBy)
+	}
+	return comments
+}
+
+// Post adds a comment. Comments by the oracle account are marked official.
+func (s *CommentStore) Post(req PostCommentRequest) (Comment, error) {
+	if err := req.Validate(); err != nil {
+		return Comment{}, err
+	}
+	id, err := newCommentID()
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if last, ok := s.lastPost[req.Author]; ok && now.Sub(last) < commentCooldown {
+		return Comment{}, ErrCommentTooFast
+	}
+	if len(s.comments[req.ContractID]) >= maxCommentsPerMarket {
+		return Comment{}, ErrCommentsFull
+	}
+
+	c := Comment{
+		ID:         id,
+		ContractID: req.ContractID,
+		Author:     req.Author,
+		Body:       strings.TrimSpace(req.Body),
+		CreatedAt:  now,
+		Official:   req.Author == s.oraclePublicKey,
+	}
+	s.comments[req.ContractID] = append(s.comments[req.ContractID], c)
+	s.lastPost[req.Author] = now
+
+	if err := s.saveLocked(); err != nil {
+		s.comments[req.ContractID] = s.comments[req.ContractID][:len(s.comments[req.ContractID])-1]
+		return Comment{}, err
+	}
+	return c, nil
+}
+
+// Flag records that account reported a comment. Flagging twice has no effect.
+func (s *CommentStore) Flag(contractID, commentID, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.findLocked(contractID, commentID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(c.FlaggedBy, account) {
+		return nil
+	}
+	c.FlaggedBy = append(c.FlaggedBy, account)
+	return s.saveLocked()
+}
+
+// SetHidden hides or restores a comment. Only the oracle account may moderate.
+func (s *CommentStore) SetHidden(contractID, commentID, account string, hidden bool) error {
+	if account == "" || account != s.oraclePublicKey {
+		return ErrNotOracle
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.findLocked(contractID, commentID)
+	if err != nil {
+		return err
+	}
+	c.Hidden = hidden
+	if !hidden {
+		c.FlaggedBy = nil // Restoring a comment clears the review
+	}
+	return s.saveLocked()
+}
+
+func (s *CommentStore) findLocked(contractID, commentID string) (*Comment, error) {
+	comments := s.comments[contractID]
+	for i := range comments {
+		if comments[i].ID == commentID {
+			return &comments[i], nil
+		}
+	}
+	return nil, ErrCommentNotFound
+}
+
+// saveLocked writes all comments to the file atomically. Must be called with mu held.
+func (s *CommentStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	var all []Comment
+	for _, comments := range s.comments {
+		all = append(all, comments...)
+	}
+	slices.SortFunc(all, func(a, b Comment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	if err := writeJSONFile(s.path, all); err != nil {
+		return fmt.Errorf("failed to save comments: %w", err)
+	}
+	return nil
+}
+
+func newCommentID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate comment ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
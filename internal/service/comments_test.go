@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/keypair"
+)
+
+const testContractID = "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+
+func TestCommentStore(t *testing.T) {
+	oracle := keypair.MustRandom().Address()
+	alice := keypair.MustRandom().Address()
+	path := filepath.Join(t.TempDir(), "comments.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	store, err := NewCommentStore(path, oracle, logger)
+	if err != nil {
+		t.Fatalf("NewCommentStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		req     PostCommentRequest
+		wantErr error
+	}{
+		{"empty", PostCommentRequest{ContractID: testContractID, Author: alice, Body: "  "}, ErrCommentEmpty},
+		{"too long", PostCommentRequest{ContractID: testContractID, Author: alice, Body: strings.Repeat("x", MaxCommentLength+1)}, ErrCommentTooLong},
+		{"valid", PostCommentRequest{ContractID: testContractID, Author: alice, Body: "Does a tie count as NO?"}, nil},
+		{"too fast", PostCommentRequest{ContractID: testContractID, Author: alice, Body: "again"}, ErrCommentTooFast},
+		{"oracle", PostCommentRequest{ContractID: testContractID, Author: oracle, Body: "A tie resolves NO."}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Post(tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Post() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	comments := store.List(testContractID)
+	if len(comments) != 2 {
+		t.Fatalf("List() returned %d comments, want 2", len(comments))
+	}
+	if comments[0].Official || !comments[1].Official {
+		t.Errorf("Official = %v, %v, want false, true", comments[0].Official, comments[1].Official)
+	}
+
+	if err := store.SetHidden(testContractID, comments[0].ID, alice, true); !errors.Is(err, ErrNotOracle) {
+		t.Errorf("SetHidden() by non-oracle error = %v, want %v", err, ErrNotOracle)
+	}
+	if err := store.SetHidden(testContractID, comments[0].ID, oracle, true); err != nil {
+		t.Fatalf("SetHidden() error = %v", err)
+	}
+	for range 2 {
+		if err := store.Flag(testContractID, comments[1].ID, alice); err != nil {
+			t.Fatalf("Flag() error = %v", err)
+		}
+	}
+
+	// Reload from disk
+	reloaded, err := NewCommentStore(path, oracle, logger)
+	if err != nil {
+		t.Fatalf("NewCommentStore() reload error = %v", err)
+	}
+	got := reloaded.List(testContractID)
+	if len(got) != 2 || !got[0].Hidden || len(got[1].FlaggedBy) != 1 {
+		t.Errorf("reloaded comments = %+v, want first hidden and second flagged once", got)
+	}
+}
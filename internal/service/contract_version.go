@@ -0,0 +1,280 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contractVersionRecheck is how long a market's detected WASM hash is trusted before it
+// is read again. Markets only change code through an upgrade, which is rare.
+const contractVersionRecheck = time.Hour
+
+var (
+	ErrCapabilityUnsupported  = errors.New("market contract version does not support this feature")
+	ErrInvalidContractVersion = errors.New("invalid contract version")
+)
+
+// ContractCapability is an optional part of the market contract ABI. Features that
+// depend on one are enabled per market, so markets deployed from different WASM
+// builds can run side by side during an upgrade.
+type ContractCapability string
+
+const (
+	CapabilityPause          ContractCapability = "pause"           // oracle can halt and resume trading
+	CapabilityFees           ContractCapability = "fees"            // fee rates are read from the contract instead of ClaimFeeRate
+	CapabilityPaginatedState ContractCapability = "paginated_state" // holders and state can be read in pages
+)
+
+// knownCapabilities are the capabilities the platform gates features on.
+var knownCapabilities = map[ContractCapability]bool{
+	CapabilityPause:          true,
+	CapabilityFees:           true,
+	CapabilityPaginatedState: true,
+}
+
+// ContractCapabilities returns the capabilities features can be gated on, sorted.
+func ContractCapabilities() []ContractCapability {
+	caps := make([]ContractCapability, 0, len(knownCapabilities))
+	for c := range knownCapabilities {
+		caps = append(caps, c)
+	}
+	slices.Sort(caps)
+	return caps
+}
+
+// ContractVersion describes one build of the market contract, identified by the hash
+// of its WASM code.
+type ContractVersion struct {
+	WasmHash     string               `json:"wasm_hash"` // Hex SHA-256 of the WASM code
+	Version      string               `json:"version"`   // e.g. "v1.2.0"
+	Capabilities []ContractCapability `json:"capabilities,omitempty"`
+	Note         string               `json:"note,omitempty"`
+}
+
+// Has reports whether the version supports a capability.
+func (v ContractVersion) Has(c ContractCapability) bool {
+	return slices.Contains(v.Capabilities, c)
+}
+
+// Validate checks the WASM hash and that all capabilities are known.
+func (v *ContractVersion) Validate() error {
+	if b, err := hex.DecodeString(v.WasmHash); err != nil || len(b) != 32 {
+		return fmt.Errorf("%w: wasm hash must be 64 hex characters", ErrInvalidContractVersion)
+	}
+	if v.Version == "" {
+		return fmt.Errorf("%w: version is required", ErrInvalidContractVersion)
+	}
+	for _, c := range v.Capabilities {
+		if !knownCapabilities[c] {
+			return fmt.Errorf("%w: unknown capability %q", ErrInvalidContractVersion, c)
+		}
+	}
+	return nil
+}
+
+// detectedCode is the WASM hash read from a market's instance entry.
+type detectedCode struct {
+	wasmHash  string
+	checkedAt time.Time
+}
+
+// ContractVersionRegistry maps WASM hashes of market contract builds to their versions
+// and capabilities, optionally persisted to a JSON file, and detects which build each
+// market runs. Markets running an unregistered build get no optional capabilities:
+// the original lmsr_market ABI is all they are assumed to have.
+type ContractVersionRegistry struct {
+	path     string // Empty keeps versions in memory only
+	wasmHash func(ctx context.Context, contractID string) (string, error)
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	versions map[string]ContractVersion // wasm hash -> version
+	markets  map[string]detectedCode    // contract ID -> detected code
+	unknown  map[string]bool            // unregistered hashes already logged
+}
+
+// NewContractVersionRegistry creates a registry and loads versions from path, if set.
+// wasmHash reads the WASM hash a contract runs, e.g. soroban.Client.ContractWasmHash.
+func NewContractVersionRegistry(path string, wasmHash func(ctx context.Context, contractID string) (string, error), logger *slog.Logger) (*ContractVersionRegistry, error) {
+	if wasmHash == nil || logger == nil {
+		panic("NewContractVersionRegistry: wasmHash and logger must not be nil")
+	}
+
+	r := &ContractVersionRegistry{
+		path:     path,
+		wasmHash: wasmHash,
+		logger:   logger,
+		versions: make(map[string]ContractVersion),
+		markets:  make(map[string]detectedCode),
+		unknown:  make(map[string]bool),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	var versions []ContractVersion
+	if _, err := readJSONFile(path, &versions); err != nil {
+		return nil, fmt.Errorf("failed to load contract versions file: %w", err)
+	}
+	for _, v := range versions {
+		v.WasmHash = strings.ToLower(v.WasmHash)
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("contract versions file: %w", err)
+		}
+		r.versions[v.WasmHash] = v
+	}
+	return r, nil
+}
+
+// Len returns the number of registered versions.
+func (r *ContractVersionRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.versions)
+}
+
+// List returns the registered versions sorted by version name.
+func (r *ContractVersionRegistry) List() []ContractVersion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := make([]ContractVersion, 0, len(r.versions))
+	for _, v := range r.versions {
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, func(a, b ContractVersion) int {
+		return cmp.Or(cmp.Compare(a.Version, b.Version), cmp.Compare(a.WasmHash, b.WasmHash))
+	})
+	return versions
+}
+
+// Register adds a version, or replaces the one with the same WASM hash.
+func (r *ContractVersionRegistry) Register(v ContractVersion) (ContractVersion, error) {
+	v.WasmHash = strings.ToLower(strings.TrimSpace(v.WasmHash))
+	v.Version = strings.TrimSpace(v.Version)
+	v.Note = strings.TrimSpace(v.Note)
+	slices.Sort(v.Capabilities)
+	v.Capabilities = slices.Compact(v.Capabilities)
+	if err := v.Validate(); err != nil {
+		return ContractVersion{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, exists := r.versions[v.WasmHash]
+	r.versions[v.WasmHash] = v
+	if err := r.saveLocked(); err != nil {
+		if exists {
+			r.versions[v.WasmHash] = previous
+		} else {
+			delete(r.versions, v.WasmHash)
+		}
+		return ContractVersion{}, err
+	}
+	delete(r.unknown, v.WasmHash)
+	r.logger.Info("registered market contract version", "version", v.Version, "wasm_hash", v.WasmHash, "capabilities", v.Capabilities)
+	return v, nil
+}
+
+// Detect returns the version a market runs. A market whose build is not registered
+// gets a version with only its WASM hash set; one whose code cannot be read yet gets
+// the zero version and the error.
+func (r *ContractVersionRegistry) Detect(ctx context.Context, contractID string) (ContractVersion, error) {
+	now := time.Now()
+	r.mu.Lock()
+	code, ok := r.markets[contractID]
+	r.mu.Unlock()
+
+	if !ok || now.Sub(code.checkedAt) > contractVersionRecheck {
+		hash, err := r.wasmHash(ctx, contractID)
+		if err != nil {
+			if !ok {
+				return ContractVersion{}, fmt.Errorf("failed to detect contract version of %s: %w", contractID, err)
+			}
+			// Keep the last detection; the code rarely changes.
+			r.logger.Debug("contract version recheck failed", "contract_id", contractID, "error", err)
+		} else {
+			if ok && code.wasmHash != hash {
+				r.logger.Info("market contract code changed", "contract_id", contractID, "from", code.wasmHash, "to", hash)
+			}
+			code = detectedCode{wasmHash: hash, checkedAt: now}
+			r.mu.Lock()
+			r.markets[contractID] = code
+			r.mu.Unlock()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.versions[code.wasmHash]; ok {
+		return v, nil
+	}
+	if !r.unknown[code.wasmHash] {
+		r.unknown[code.wasmHash] = true
+		r.logger.Warn("market runs an unregistered contract build; optional features are disabled for it", "contract_id", contractID, "wasm_hash", code.wasmHash)
+	}
+	return ContractVersion{WasmHash: code.wasmHash}, nil
+}
+
+// ContractVersionUsage is how many detected markets run one build.
+type ContractVersionUsage struct {
+	ContractVersion
+	Markets int
+}
+
+// Usage counts the detected markets per build, registered builds first. It shows how
+// far an upgrade has progressed.
+func (r *ContractVersionRegistry) Usage() []ContractVersionUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, code := range r.markets {
+		counts[code.wasmHash]++
+	}
+	usage := make([]ContractVersionUsage, 0, len(r.versions)+len(counts))
+	for hash, v := range r.versions {
+		usage = append(usage, ContractVersionUsage{ContractVersion: v, Markets: counts[hash]})
+	}
+	for hash, n := range counts {
+		if _, ok := r.versions[hash]; !ok {
+			usage = append(usage, ContractVersionUsage{ContractVersion: ContractVersion{WasmHash: hash}, Markets: n})
+		}
+	}
+	slices.SortFunc(usage, func(a, b ContractVersionUsage) int {
+		// Unregistered builds (empty version) sort last.
+		if (a.Version == "") != (b.Version == "") {
+			if a.Version == "" {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(cmp.Compare(a.Version, b.Version), cmp.Compare(a.WasmHash, b.WasmHash))
+	})
+	return usage
+}
+
+// saveLocked writes all versions to the file atomically. Must be called with mu held.
+func (r *ContractVersionRegistry) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+	versions := make([]ContractVersion, 0, len(r.versions))
+	for _, v := range r.versions {
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, func(a, b ContractVersion) int { return cmp.Compare(a.WasmHash, b.WasmHash) })
+	if err := writeJSONFile(r.path, versions); err != nil {
+		return fmt.Errorf("failed to save contract versions: %w", err)
+	}
+	return nil
+}
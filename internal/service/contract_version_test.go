@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testWasmV1 = strings.Repeat("a1", 32)
+	testWasmV2 = strings.Repeat("b2", 32)
+)
+
+// fakeWasmHashes serves WASM hashes per contract ID and counts lookups.
+type fakeWasmHashes struct {
+	hashes map[string]string
+	calls  int
+}
+
+func (f *fakeWasmHashes) lookup(_ context.Context, contractID string) (string, error) {
+	f.calls++
+	hash, ok := f.hashes[contractID]
+	if !ok {
+		return "", errors.New("contract not found")
+	}
+	return hash, nil
+}
+
+func newTestVersionRegistry(t *testing.T, path string, hashes *fakeWasmHashes) *ContractVersionRegistry {
+	t.Helper()
+	r, err := NewContractVersionRegistry(path, hashes.lookup, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewContractVersionRegistry() error = %v", err)
+	}
+	return r
+}
+
+func TestContractVersionRegister(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.json")
+	r := newTestVersionRegistry(t, path, &fakeWasmHashes{})
+
+	v, err := r.Register(ContractVersion{
+		WasmHash:     " " + strings.ToUpper(testWasmV2) + " ",
+		Version:      "v1.1.0",
+		Capabilities: []ContractCapability{CapabilityPause, CapabilityFees, CapabilityPause},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if v.WasmHash != testWasmV2 {
+		t.Errorf("WasmHash = %q, want lowercase %q", v.WasmHash, testWasmV2)
+	}
+	if len(v.Capabilities) != 2 || !v.Has(CapabilityFees) || !v.Has(CapabilityPause) || v.Has(CapabilityPaginatedState) {
+		t.Errorf("Capabilities = %v, want fees and pause", v.Capabilities)
+	}
+
+	for name, bad := range map[string]ContractVersion{
+		"short hash":         {WasmHash: "abcd", Version: "v1"},
+		"missing version":    {WasmHash: testWasmV1},
+		"unknown capability": {WasmHash: testWasmV1, Version: "v1", Capabilities: []ContractCapability{"teleport"}},
+	} {
+		if _, err := r.Register(bad); !errors.Is(err, ErrInvalidContractVersion) {
+			t.Errorf("Register(%s) error = %v, want ErrInvalidContractVersion", name, err)
+		}
+	}
+
+	reloaded := newTestVersionRegistry(t, path, &fakeWasmHashes{})
+	if got := reloaded.List(); len(got) != 1 || got[0].Version != "v1.1.0" || !got[0].Has(CapabilityPause) {
+		t.Errorf("reloaded List() = %+v, want the registered v1.1.0", got)
+	}
+}
+
+func TestContractVersionDetect(t *testing.T) {
+	hashes := &fakeWasmHashes{hashes: map[string]string{
+		"CNEW": testWasmV2,
+		"COLD": testWasmV1,
+	}}
+	r := newTestVersionRegistry(t, "", hashes)
+	if _, err := r.Register(ContractVersion{WasmHash: testWasmV2, Version: "v1.1.0", Capabilities: []ContractCapability{CapabilityPause}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	ctx := context.Background()
+
+	v, err := r.Detect(ctx, "CNEW")
+	if err != nil || v.Version != "v1.1.0" || !v.Has(CapabilityPause) {
+		t.Errorf("Detect(CNEW) = %+v, %v; want v1.1.0 with pause", v, err)
+	}
+	v, err = r.Detect(ctx, "COLD")
+	if err != nil || v.Version != "" || v.WasmHash != testWasmV1 || len(v.Capabilities) != 0 {
+		t.Errorf("Detect(COLD) = %+v, %v; want the unregistered build with no capabilities", v, err)
+	}
+	if _, err := r.Detect(ctx, "CMISSING"); err == nil {
+		t.Error("Detect(CMISSING) error = nil, want the lookup error")
+	}
+
+	t.Run("caches detections", func(t *testing.T) {
+		calls := hashes.calls
+		if _, err := r.Detect(ctx, "CNEW"); err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if hashes.calls != calls {
+			t.Errorf("Detect() looked up the hash again within the recheck interval")
+		}
+	})
+
+	t.Run("rechecks after an upgrade", func(t *testing.T) {
+		hashes.hashes["COLD"] = testWasmV2
+		r.mu.Lock()
+		code := r.markets["COLD"]
+		code.checkedAt = time.Now().Add(-contractVersionRecheck - time.Minute)
+		r.markets["COLD"] = code
+		r.mu.Unlock()
+
+		if v, err := r.Detect(ctx, "COLD"); err != nil || v.Version != "v1.1.0" {
+			t.Errorf("Detect(COLD) after upgrade = %+v, %v; want v1.1.0", v, err)
+		}
+	})
+
+	t.Run("keeps the last detection when a recheck fails", func(t *testing.T) {
+		delete(hashes.hashes, "CNEW")
+		r.mu.Lock()
+		code := r.markets["CNEW"]
+		code.checkedAt = time.Now().Add(-contractVersionRecheck - time.Minute)
+		r.markets["CNEW"] = code
+		r.mu.Unlock()
+
+		if v, err := r.Detect(ctx, "CNEW"); err != nil || v.Version != "v1.1.0" {
+			t.Errorf("Detect(CNEW) = %+v, %v; want the cached v1.1.0", v, err)
+		}
+	})
+}
+
+func TestContractVersionUsage(t *testing.T) {
+	hashes := &fakeWasmHashes{hashes: map[string]string{
+		"C1": testWasmV1,
+		"C2": testWasmV2,
+		"C3": testWasmV2,
+	}}
+	r := newTestVersionRegistry(t, "", hashes)
+	if _, err := r.Register(ContractVersion{WasmHash: testWasmV2, Version: "v1.1.0"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, err := r.Register(ContractVersion{WasmHash: strings.Repeat("c3", 32), Version: "v1.2.0"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	for id := range hashes.hashes {
+		if _, err := r.Detect(context.Background(), id); err != nil {
+			t.Fatalf("Detect(%s) error = %v", id, err)
+		}
+	}
+
+	usage := r.Usage()
+	want := []struct {
+		version string
+		markets int
+	}{{"v1.1.0", 2}, {"v1.2.0", 0}, {"", 1}}
+	if len(usage) != len(want) {
+		t.Fatalf("Usage() = %+v, want %d entries", usage, len(want))
+	}
+	for i, w := range want {
+		if usage[i].Version != w.version || usage[i].Markets != w.markets {
+			t.Errorf("Usage()[%d] = %s with %d markets, want %q with %d", i, usage[i].Version, usage[i].Markets, w.version, w.markets)
+		}
+	}
+	if usage[2].WasmHash != testWasmV1 {
+		t.Errorf("unregistered build hash = %q, want %q", usage[2].WasmHash, testWasmV1)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mtlprog/total/internal/lmsr"
+)
+
+const (
+	// CostCurveSteps is the number of amounts CostCurve prices per outcome.
+	CostCurveSteps = 40
+	// MaxCostCurveAmount bounds the largest amount a cost curve may be requested for.
+	MaxCostCurveAmount = 1_000_000
+)
+
+// CostCurvePoint is the cost of buying Amount tokens of each outcome in one trade.
+type CostCurvePoint struct {
+	Amount  float64
+	CostYes float64
+	CostNo  float64
+}
+
+// CostCurve is the cost of buying increasing amounts of YES and NO at a market's
+// current state, showing how deep the market is before a large order.
+type CostCurve struct {
+	LiquidityParam float64
+	YesSold        float64
+	NoSold         float64
+	Points         []CostCurvePoint
+}
+
+// CostCurve prices buying up to maxAmount tokens of each outcome at the market's
+// current state, with its real liquidity parameter. A maxAmount of 0 or less means
+// twice the liquidity parameter, which takes an even market to about 88%.
+func (s *MarketService) CostCurve(ctx context.Context, contractID string, maxAmount float64) (*CostCurve, error) {
+	liquidity, err := s.liquidityParam(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	yesSold, noSold, err := s.soldQuantities(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	calc, err := lmsr.New(liquidity)
+	if err != nil {
+		return nil, err
+	}
+	if maxAmount <= 0 {
+		maxAmount = 2 * liquidity
+	}
+
+	curve := &CostCurve{
+		LiquidityParam: liquidity,
+		YesSold:        yesSold,
+		NoSold:         noSold,
+		Points:         make([]CostCurvePoint, 0, CostCurveSteps+1),
+	}
+	curve.Points = append(curve.Points, CostCurvePoint{})
+	for i := 1; i <= CostCurveSteps; i++ {
+		amount := maxAmount * float64(i) / CostCurveSteps
+		costYes, err := calc.CalculateCost(yesSold, noSold, amount, "YES")
+		if err != nil {
+			return nil, err
+		}
+		costNo, err := calc.CalculateCost(yesSold, noSold, amount, "NO")
+		if err != nil {
+			return nil, err
+		}
+		curve.Points = append(curve.Points, CostCurvePoint{Amount: amount, CostYes: costYes, CostNo: costNo})
+	}
+	return curve, nil
+}
@@ -0,0 +1,519 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/notify"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// DigestInterval is how often due daily digests are sent; each subscriber gets
+	// at most one per digestPeriod.
+	DigestInterval = time.Hour
+	// ResolutionNoticeInterval is how often watched markets are checked for resolution.
+	ResolutionNoticeInterval = time.Minute
+
+	digestPeriod          = 24 * time.Hour
+	digestClosingWindow   = 48 * time.Hour
+	digestMaxMarkets      = 20 // per digest section
+	maxDigestSubscribers  = 10000
+	maxWatchedMarkets     = 50
+	digestConfirmCooldown = 10 * time.Minute // between confirmation emails to one address
+	digestDeliveryTimeout = 30 * time.Second
+	digestUnconfirmedTTL  = 7 * 24 * time.Hour // unconfirmed sign-ups are dropped after this
+)
+
+var (
+	ErrDigestNotFound     = errors.New("email subscription not found")
+	ErrDigestEmpty        = errors.New("choose the daily digest or a market to watch")
+	ErrDigestConfirmSent  = errors.New("a confirmation email was sent recently")
+	ErrTooManyDigests     = errors.New("too many email subscriptions")
+	ErrTooManyWatched     = errors.New("too many watched markets")
+	ErrDigestNotAvailable = errors.New("email notifications are not configured")
+)
+
+// DigestSubscription is an email address subscribed to the daily digest and/or
+// resolution notices of watched markets. Changes wait in Pending* until the owner
+// follows the confirmation link, so nobody can sign up an address they do not read.
+type DigestSubscription struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"` // Secret that authorizes unsubscribing
+	Email     string    `json:"email"`
+	Daily     bool      `json:"daily"`
+	Watched   []string  `json:"watched,omitempty"` // contract IDs
+	CreatedAt time.Time `json:"created_at"`
+
+	ConfirmToken   string    `json:"confirm_token,omitempty"`
+	ConfirmSentAt  time.Time `json:"confirm_sent_at,omitzero"`
+	PendingDaily   bool      `json:"pending_daily,omitempty"`
+	PendingWatched []string  `json:"pending_watched,omitempty"`
+
+	LastDigest time.Time `json:"last_digest,omitzero"`
+	Notified   []string  `json:"notified,omitempty"` // watched markets whose resolution was sent
+}
+
+// Active reports whether the subscription has confirmed content to deliver.
+func (s DigestSubscription) Active() bool {
+	return s.Daily || len(s.Watched) > 0
+}
+
+// SubscribeDigestRequest contains parameters for an email subscription.
+type SubscribeDigestRequest struct {
+	Email      string
+	Daily      bool
+	ContractID string // optional market to watch for resolution
+}
+
+// Validate validates the subscribe request.
+func (r *SubscribeDigestRequest) Validate() error {
+	if err := notify.ValidateTarget(notify.ChannelEmail, r.Email); err != nil {
+		return err
+	}
+	if r.ContractID != "" {
+		if err := soroban.ValidateContractID(r.ContractID); err != nil {
+			return err
+		}
+	}
+	if !r.Daily && r.ContractID == "" {
+		return ErrDigestEmpty
+	}
+	return nil
+}
+
+// DigestService manages email subscriptions and sends daily digests of new and
+// closing markets and resolution notices for watched markets. Subscriptions are kept
+// in memory, optionally persisted to a JSON file.
+type DigestService struct {
+	factoryService *FactoryService
+	ipfsClient     *ipfs.Client
+	dispatcher     *notify.Dispatcher
+	publicURL      string // Base URL for links in emails
+	path           string // Empty keeps subscriptions in memory only
+	logger         *slog.Logger
+
+	mu   sync.Mutex
+	subs map[string]*DigestSubscription // by ID
+}
+
+// NewDigestService creates a digest service and loads existing subscriptions from
+// path, if set. The dispatcher must have the email channel enabled.
+func NewDigestService(
+	factoryService *FactoryService,
+	ipfsClient *ipfs.Client,
+	dispatcher *notify.Dispatcher,
+	publicURL, path string,
+	logger *slog.Logger,
+) (*DigestService, error) {
+	if factoryService == nil {
+		panic("NewDigestService: factoryService must not be nil")
+	}
+	if ipfsClient == nil {
+		panic("NewDigestService: ipfsClient must not be nil")
+	}
+	if dispatcher == nil {
+		panic("NewDigestService: dispatcher must not be nil")
+	}
+	if logger == nil {
+		panic("NewDigestService: logger must not be nil")
+	}
+	if !dispatcher.Enabled(notify.ChannelEmail) {
+		return nil, ErrDigestNotAvailable
+	}
+	if publicURL == "" {
+		return nil, errors.New("email subscriptions need a public URL for confirmation links")
+	}
+
+	s := &DigestService{
+		factoryService: factoryService,
+		ipfsClient:     ipfsClient,
+		dispatcher:     dispatcher,
+		publicURL:      publicURL,
+		path:           path,
+		logger:         logger,
+		subs:           make(map[string]*DigestSubscription),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var subs []*DigestSubscription
+	if _, err := readJSONFile(path, &subs); err != nil {
+		return nil, fmt.Errorf("failed to load email subscriptions file: %w", err)
+	}
+	for _, sub := range subs {
+		s.subs[sub.ID] = sub
+	}
+	return s, nil
+}
+
+// Subscribe records a subscription change for an address and emails a confirmation
+// link. Nothing is delivered for the change until it is confirmed.
+func (s *DigestService) Subscribe(ctx context.Context, req SubscribeDigestRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	confirmToken, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sub := s.findLocked(req.Email)
+	now := time.Now().UTC()
+	if sub == nil {
+		if len(s.subs) >= maxDigestSubscribers {
+			s.mu.Unlock()
+			return ErrTooManyDigests
+		}
+		id, err := randomHex(8)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		token, err := randomHex(16)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		sub = &DigestSubscription{ID: id, Token: token, Email: req.Email, CreatedAt: now}
+		s.subs[id] = sub
+	} else if now.Sub(sub.ConfirmSentAt) < digestConfirmCooldown {
+		s.mu.Unlock()
+		return ErrDigestConfirmSent
+	}
+
+	sub.PendingDaily = sub.PendingDaily || req.Daily
+	if req.ContractID != "" && !slices.Contains(sub.Watched, req.ContractID) && !slices.Contains(sub.PendingWatched, req.ContractID) {
+		if len(sub.Watched)+len(sub.PendingWatched) >= maxWatchedMarkets {
+			s.mu.Unlock()
+			return ErrTooManyWatched
+		}
+		sub.PendingWatched = append(sub.PendingWatched, req.ContractID)
+	}
+	sub.ConfirmToken = confirmToken
+	sub.ConfirmSentAt = now
+	id, email := sub.ID, sub.Email
+	err = s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	text.WriteString("Confirm your MTL Predict email notifications:\n")
+	if req.Daily {
+		text.WriteString("- a daily digest of new and closing markets\n")
+	}
+	if req.ContractID != "" {
+		text.WriteString("- a notice when the watched market resolves\n")
+	}
+	text.WriteString("\nIf you did not ask for this, ignore this email and nothing will be sent.")
+	msg := notify.Message{
+		Title: "Confirm your MTL Predict notifications",
+		Text:  text.String(),
+		URL:   s.publicURL + "/email/" + id + "/confirm?token=" + confirmToken,
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, digestDeliveryTimeout)
+	defer cancel()
+	if err := s.dispatcher.Send(sendCtx, notify.ChannelEmail, email, msg); err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+	return nil
+}
+
+// Confirm applies the pending changes of a subscription.
+func (s *DigestService) Confirm(id, token string) (DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subs[id]
+	if !ok || sub.ConfirmToken == "" || subtle.ConstantTimeCompare([]byte(sub.ConfirmToken), []byte(token)) != 1 {
+		return DigestSubscription{}, ErrDigestNotFound
+	}
+	sub.Daily = sub.Daily || sub.PendingDaily
+	for _, contractID := range sub.PendingWatched {
+		if !slices.Contains(sub.Watched, contractID) {
+			sub.Watched = append(sub.Watched, contractID)
+		}
+	}
+	sub.PendingDaily, sub.PendingWatched, sub.ConfirmToken = false, nil, ""
+	if err := s.saveLocked(); err != nil {
+		return DigestSubscription{}, err
+	}
+	return *sub, nil
+}
+
+// Unsubscribe removes a subscription. The token must match the one in its emails.
+func (s *DigestService) Unsubscribe(id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subs[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(sub.Token), []byte(token)) != 1 {
+		return ErrDigestNotFound
+	}
+	delete(s.subs, id)
+	return s.saveLocked()
+}
+
+// Count returns the number of confirmed subscriptions.
+func (s *DigestService) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sub := range s.subs {
+		if sub.Active() {
+			n++
+		}
+	}
+	return n
+}
+
+// digestMarket is a market listed in a digest or notice.
+type digestMarket struct {
+	ID       string
+	Question string
+	EndDate  time.Time
+	PriceYes float64
+}
+
+// SendDigests emails the daily digest to subscribers who did not get one within
+// digestPeriod. Days without new or closing markets send nothing.
+func (s *DigestService) SendDigests(ctx context.Context) error {
+	now := time.Now()
+	s.mu.Lock()
+	var due []*DigestSubscription
+	for id, sub := range s.subs {
+		if !sub.Active() && now.Sub(sub.CreatedAt) > digestUnconfirmedTTL {
+			delete(s.subs, id)
+			continue
+		}
+		if sub.Daily && now.Sub(sub.LastDigest) >= digestPeriod {
+			due = append(due, sub)
+		}
+	}
+	s.mu.Unlock()
+	if len(due) == 0 || !s.factoryService.HasFactory() {
+		return nil
+	}
+
+	newMarkets, closing, err := s.digestMarkets(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range due {
+		if len(newMarkets) > 0 || len(closing) > 0 {
+			msg := notify.Message{
+				Title: "MTL Predict daily digest",
+				Text:  s.digestText(newMarkets, closing, sub),
+				URL:   s.publicURL,
+			}
+			sendCtx, cancel := context.WithTimeout(ctx, digestDeliveryTimeout)
+			err := s.dispatcher.Send(sendCtx, notify.ChannelEmail, sub.Email, msg)
+			cancel()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("digest %s: %w", sub.ID, err))
+				continue
+			}
+		}
+		s.mu.Lock()
+		sub.LastDigest = now.UTC()
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// digestMarkets returns open markets created within digestPeriod and open markets
+// closing within digestClosingWindow, soonest first.
+func (s *DigestService) digestMarkets(ctx context.Context, now time.Time) (newMarkets, closing []digestMarket, err error) {
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get market states: %w", err)
+	}
+	states := loaded.States
+
+	for _, state := range states {
+		if state.Resolved || state.MetadataHash == "" {
+			continue
+		}
+		var metadata model.MarketMetadata
+		if err := s.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err != nil {
+			s.logger.Warn("digest: failed to fetch metadata", "contract_id", state.ContractID, "error", err)
+			continue
+		}
+		m := digestMarket{ID: state.ContractID, Question: metadata.Question, EndDate: metadata.EndDate, PriceYes: state.PriceYes}
+		if !metadata.CreatedAt.IsZero() && now.Sub(metadata.CreatedAt) < digestPeriod {
+			newMarkets = append(newMarkets, m)
+		}
+		if !m.EndDate.IsZero() && m.EndDate.After(now) && m.EndDate.Sub(now) <= digestClosingWindow {
+			closing = append(closing, m)
+		}
+	}
+	slices.SortFunc(closing, func(a, b digestMarket) int { return a.EndDate.Compare(b.EndDate) })
+	if len(newMarkets) > digestMaxMarkets {
+		newMarkets = newMarkets[:digestMaxMarkets]
+	}
+	if len(closing) > digestMaxMarkets {
+		closing = closing[:digestMaxMarkets]
+	}
+	return newMarkets, closing, nil
+}
+
+func (s *DigestService) digestText(newMarkets, closing []digestMarket, sub *DigestSubscription) string {
+	var b strings.Builder
+	section := func(title string, markets []digestMarket, withEnd bool) {
+		if len(markets) == 0 {
+			return
+		}
+		b.WriteString(title + "\n\n")
+		for _, m := range markets {
+			fmt.Fprintf(&b, "%s\nYES %.0f%%", m.Question, m.PriceYes*100)
+			if withEnd {
+				fmt.Fprintf(&b, " · closes %s UTC", m.EndDate.UTC().Format("Jan 2 15:04"))
+			}
+			fmt.Fprintf(&b, "\n%s/market/%s\n\n", s.publicURL, m.ID)
+		}
+	}
+	section("New markets", newMarkets, false)
+	section("Closing soon", closing, true)
+	b.WriteString(s.unsubscribeLine(sub))
+	return b.String()
+}
+
+// NotifyResolutions emails a notice for each newly resolved watched market.
+// Failed deliveries are retried on the next run.
+func (s *DigestService) NotifyResolutions(ctx context.Context) error {
+	s.mu.Lock()
+	var ids []string
+	for _, sub := range s.subs {
+		for _, id := range sub.Watched {
+			if !slices.Contains(sub.Notified, id) && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	loaded, stateErr := s.factoryService.GetMarketStates(ctx, ids)
+	if stateErr != nil {
+		return fmt.Errorf("failed to get market states: %w", stateErr)
+	}
+	states := loaded.States
+
+	resolved := make(map[string]MarketState)
+	for _, state := range states {
+		if state.Resolved {
+			resolved[state.ContractID] = state
+		}
+	}
+	if len(resolved) == 0 {
+		return nil
+	}
+
+	type notice struct {
+		sub   *DigestSubscription
+		state MarketState
+	}
+	var notices []notice
+	s.mu.Lock()
+	for _, sub := range s.subs {
+		for _, id := range sub.Watched {
+			if state, ok := resolved[id]; ok && !slices.Contains(sub.Notified, id) {
+				notices = append(notices, notice{sub, state})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, n := range notices {
+		question := n.state.ContractID
+		if n.state.MetadataHash != "" {
+			var metadata model.MarketMetadata
+			if err := s.ipfsClient.GetJSON(ctx, n.state.MetadataHash, &metadata); err == nil {
+				question = metadata.Question
+			}
+		}
+		msg := notify.Message{
+			Title: "Market resolved: " + n.state.WinningOutcome,
+			Text:  fmt.Sprintf("%s\n\nThe market resolved %s. Holders of %s tokens can claim their payout now.\n\n%s", question, n.state.WinningOutcome, n.state.WinningOutcome, s.unsubscribeLine(n.sub)),
+			URL:   s.publicURL + "/market/" + n.state.ContractID,
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, digestDeliveryTimeout)
+		err := s.dispatcher.Send(sendCtx, notify.ChannelEmail, n.sub.Email, msg)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolution notice %s: %w", n.sub.ID, err))
+			continue
+		}
+		s.mu.Lock()
+		n.sub.Notified = append(n.sub.Notified, n.state.ContractID)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+	if stateErr != nil {
+		s.logger.Warn("email notices: failed to get some market states", "error", stateErr)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *DigestService) unsubscribeLine(sub *DigestSubscription) string {
+	return "Unsubscribe: " + s.publicURL + "/email/" + sub.ID + "/unsubscribe?token=" + sub.Token
+}
+
+// findLocked returns the subscription of an address. Must be called with mu held.
+func (s *DigestService) findLocked(email string) *DigestSubscription {
+	for _, sub := range s.subs {
+		if strings.EqualFold(sub.Email, email) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// saveLocked writes all subscriptions to the file atomically. Must be called with mu held.
+func (s *DigestService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	subs := make([]*DigestSubscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	slices.SortFunc(subs, func(a, b *DigestSubscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	if err := writeJSONFile(s.path, subs); err != nil {
+		return fmt.Errorf("failed to save email subscriptions: %w", err)
+	}
+	return nil
+}
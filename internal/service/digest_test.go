@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/notify"
+)
+
+// recordingTransport is an email transport that keeps sent messages.
+type recordingTransport struct {
+	sent []notify.Message
+}
+
+func (t *recordingTransport) Channel() notify.Channel { return notify.ChannelEmail }
+
+func (t *recordingTransport) ValidateTarget(target string) error {
+	return notify.ValidateTarget(notify.ChannelEmail, target)
+}
+
+func (t *recordingTransport) Send(_ context.Context, _ string, msg notify.Message) error {
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+func TestSubscribeDigestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     SubscribeDigestRequest
+		wantErr error
+	}{
+		{"daily", SubscribeDigestRequest{Email: "alice@example.com", Daily: true}, nil},
+		{"watch", SubscribeDigestRequest{Email: "alice@example.com", ContractID: testContractID}, nil},
+		{"nothing chosen", SubscribeDigestRequest{Email: "alice@example.com"}, ErrDigestEmpty},
+		{"bad email", SubscribeDigestRequest{Email: "alice", Daily: true}, notify.ErrInvalidTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (tt.wantErr == nil) != (err == nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := (&SubscribeDigestRequest{Email: "alice@example.com", ContractID: "C123"}).Validate(); err == nil {
+		t.Error("Validate() accepted an invalid contract ID")
+	}
+}
+
+func TestDigestSubscribeConfirm(t *testing.T) {
+	transport := &recordingTransport{}
+	s := &DigestService{
+		dispatcher: notify.NewDispatcher(transport),
+		publicURL:  "https://predict.example.org",
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		subs:       make(map[string]*DigestSubscription),
+	}
+	ctx := context.Background()
+
+	if err := s.Subscribe(ctx, SubscribeDigestRequest{Email: "alice@example.com", ContractID: testContractID}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if len(transport.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1 confirmation", len(transport.sent))
+	}
+	if s.Count() != 0 {
+		t.Error("unconfirmed subscription counted as active")
+	}
+	if err := s.Subscribe(ctx, SubscribeDigestRequest{Email: "ALICE@example.com", Daily: true}); !errors.Is(err, ErrDigestConfirmSent) {
+		t.Errorf("second Subscribe() error = %v, want ErrDigestConfirmSent", err)
+	}
+
+	var sub *DigestSubscription
+	for _, v := range s.subs {
+		sub = v
+	}
+	link := transport.sent[0].URL
+	if !strings.HasPrefix(link, "https://predict.example.org/email/"+sub.ID+"/confirm?token=") {
+		t.Fatalf("confirmation link = %q", link)
+	}
+	token := link[strings.Index(link, "token=")+len("token="):]
+
+	if _, err := s.Confirm(sub.ID, "wrong"); !errors.Is(err, ErrDigestNotFound) {
+		t.Errorf("Confirm() with wrong token error = %v, want ErrDigestNotFound", err)
+	}
+	confirmed, err := s.Confirm(sub.ID, token)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if confirmed.Daily || len(confirmed.Watched) != 1 || confirmed.Watched[0] != testContractID {
+		t.Errorf("confirmed = %+v, want watching the market only", confirmed)
+	}
+	if _, err := s.Confirm(sub.ID, token); !errors.Is(err, ErrDigestNotFound) {
+		t.Error("confirmation token should be single-use")
+	}
+	if s.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", s.Count())
+	}
+
+	if err := s.Unsubscribe(sub.ID, token); !errors.Is(err, ErrDigestNotFound) {
+		t.Error("Unsubscribe() accepted the confirmation token")
+	}
+	if err := s.Unsubscribe(sub.ID, sub.Token); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if len(s.subs) != 0 {
+		t.Error("subscription not removed")
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"sync"
 	"time"
 
+	"github.com/mtlprog/total/internal/cachestats"
 	"github.com/mtlprog/total/internal/soroban"
 	"github.com/samber/hot"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -18,6 +20,7 @@ const lookbackLedgers = 17280
 const (
 	eventCacheTTL  = 5 * time.Minute
 	eventCacheSize = 500
+	maxEventPages  = 10 // Bounds a single fetch; the next fetch continues from the saved cursor
 )
 
 // TradeKind represents the type of trade event.
@@ -37,6 +40,8 @@ type TradeEvent struct {
 	Cost      float64   // collateral paid (buy) or received (sell)
 	Timestamp time.Time // ledger close time
 	Ledger    uint32
+	TxHash    string // empty when the RPC does not report transaction hashes
+	ID        string // getEvents event ID; IDs sort in ledger and event order
 }
 
 // EventService fetches and caches contract trade events.
@@ -44,6 +49,21 @@ type EventService struct {
 	sorobanClient *soroban.Client
 	logger        *slog.Logger
 	cache         *hot.HotCache[string, []TradeEvent]
+	cacheStats    cachestats.Counter
+
+	tailsMu sync.Mutex
+	tails   map[string]eventTail // trade events fetched so far per contract
+
+	resolutionsMu sync.RWMutex
+	resolutions   map[string]ResolutionEvent // resolve events never change once found
+	notResolved   map[string]time.Time       // last lookup without a resolve event
+}
+
+// eventTail holds the trade events of a contract within the lookback window and the
+// cursor after the last one, so later fetches only page through newer events.
+type eventTail struct {
+	events []TradeEvent
+	cursor string
 }
 
 // NewEventService creates a new event service.
@@ -58,6 +78,9 @@ func NewEventService(sorobanClient *soroban.Client, logger *slog.Logger) *EventS
 	s := &EventService{
 		sorobanClient: sorobanClient,
 		logger:        logger,
+		tails:         make(map[string]eventTail),
+		resolutions:   make(map[string]ResolutionEvent),
+		notResolved:   make(map[string]time.Time),
 	}
 
 	s.cache = hot.NewHotCache[string, []TradeEvent](hot.LRU, eventCacheSize).
@@ -74,69 +97,144 @@ func (s *EventService) GetTradeEvents(ctx context.Context, contractID string) ([
 		s.logger.Warn("event cache error, treating as miss", "contract_id", contractID, "error", err)
 	}
 	if found && err == nil {
+		s.cacheStats.Hit()
 		return slices.Clone(cached), nil
 	}
+	s.cacheStats.Miss()
 
-	events, err := s.fetchEvents(ctx, contractID)
+	events, caughtUp, err := s.fetchEvents(ctx, contractID)
 	if err != nil {
 		return nil, err
 	}
 
-	s.cache.Set(contractID, events)
+	// Until paging has caught up with the ledger, skip the cache so the next call
+	// continues from the saved cursor.
+	if caughtUp {
+		s.cache.Set(contractID, events)
+	} else {
+		s.logger.Info("trade events not caught up, continuing on next fetch", "contract_id", contractID, "events", len(events))
+	}
 	return slices.Clone(events), nil
 }
 
-func (s *EventService) fetchEvents(ctx context.Context, contractID string) ([]TradeEvent, error) {
+// CacheStats returns hit and miss counts of the event cache.
+func (s *EventService) CacheStats() cachestats.Stats {
+	return s.cacheStats.Stats()
+}
+
+// fetchEvents returns the trade events of a contract within the lookback window. It
+// continues from the cursor of the previous fetch, so each call pages through newer
+// events only; caughtUp is false when maxEventPages ran out before the latest ledger.
+func (s *EventService) fetchEvents(ctx context.Context, contractID string) (events []TradeEvent, caughtUp bool, err error) {
 	latestLedger, err := s.sorobanClient.GetLatestLedger(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get latest ledger: %w", err)
+		return nil, false, fmt.Errorf("failed to get latest ledger: %w", err)
 	}
 
-	startLedger := uint32(0)
+	startLedger := uint32(1)
 	if latestLedger.Sequence > lookbackLedgers {
 		startLedger = latestLedger.Sequence - lookbackLedgers
 	}
 
+	s.tailsMu.Lock()
+	tail := s.tails[contractID]
+	s.tailsMu.Unlock()
+
+	newEvents, cursor, caughtUp, err := s.pageTradeEvents(ctx, contractID, startLedger, tail.cursor, maxEventPages)
+	if err != nil && tail.cursor != "" {
+		// The saved cursor may have left the RPC retention window; start over.
+		s.logger.Warn("failed to continue trade events from cursor, refetching", "contract_id", contractID, "error", err)
+		tail = eventTail{}
+		newEvents, cursor, caughtUp, err = s.pageTradeEvents(ctx, contractID, startLedger, "", maxEventPages)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.tailsMu.Lock()
+	defer s.tailsMu.Unlock()
+	// A concurrent fetch may have stored newer events meanwhile. Event IDs and cursors
+	// sort in ledger order, so keep only what follows the stored tail.
+	if tail.cursor != "" {
+		tail = s.tails[contractID]
+	}
+	events = make([]TradeEvent, 0, len(tail.events)+len(newEvents))
+	for _, evt := range tail.events {
+		if evt.Ledger >= startLedger {
+			events = append(events, evt)
+		}
+	}
+	for _, evt := range newEvents {
+		if len(events) == 0 || evt.ID > events[len(events)-1].ID {
+			events = append(events, evt)
+		}
+	}
+	if cursor == "" || (tail.cursor != "" && cursor < tail.cursor) {
+		cursor = tail.cursor
+	}
+	s.tails[contractID] = eventTail{events: events, cursor: cursor}
+	return slices.Clone(events), caughtUp, nil
+}
+
+// fetchEventsFrom pages through the trade events of a contract from startLedger, at
+// most maxPages pages.
+func (s *EventService) fetchEventsFrom(ctx context.Context, contractID string, startLedger uint32, maxPages int) ([]TradeEvent, error) {
+	events, _, _, err := s.pageTradeEvents(ctx, contractID, startLedger, "", maxPages)
+	return events, err
+}
+
+// pageTradeEvents pages through the trade events of a contract from cursor or, without
+// one, from startLedger, at most maxPages pages. It returns the cursor after the last
+// page and whether paging caught up with the latest ledger.
+func (s *EventService) pageTradeEvents(ctx context.Context, contractID string, startLedger uint32, cursor string, maxPages int) ([]TradeEvent, string, bool, error) {
 	buyTopicXDR, err := encodeSymbolBase64("buy")
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode buy topic: %w", err)
+		return nil, "", false, fmt.Errorf("failed to encode buy topic: %w", err)
 	}
 	sellTopicXDR, err := encodeSymbolBase64("sell")
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode sell topic: %w", err)
+		return nil, "", false, fmt.Errorf("failed to encode sell topic: %w", err)
 	}
 
-	params := soroban.GetEventsParams{
-		StartLedger: startLedger,
-		Filters: []soroban.EventFilter{
-			{
-				Type:        "contract",
-				ContractIDs: []string{contractID},
-				Topics: [][]string{
-					{buyTopicXDR, "*", "*"},
-				},
-			},
-			{
-				Type:        "contract",
-				ContractIDs: []string{contractID},
-				Topics: [][]string{
-					{sellTopicXDR, "*", "*"},
-				},
-			},
-		},
-		Pagination: &soroban.EventPagination{Limit: 200},
+	filters := []soroban.EventFilter{
+		soroban.NewContractEventFilter([]string{contractID}, []string{buyTopicXDR, soroban.TopicWildcard, soroban.TopicWildcard}),
+		soroban.NewContractEventFilter([]string{contractID}, []string{sellTopicXDR, soroban.TopicWildcard, soroban.TopicWildcard}),
 	}
 
-	result, err := s.sorobanClient.GetEvents(ctx, params)
+	var rawEvents []soroban.ContractEvent
+	caughtUp := false
+	for page := 0; page < maxPages; page++ {
+		result, err := s.sorobanClient.GetEvents(ctx, startLedger, filters, cursor)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("failed to get events: %w", err)
+		}
+		rawEvents = append(rawEvents, result.Events...)
+
+		// A short page or a stalled cursor means we have caught up with the ledger.
+		if len(result.Events) < soroban.EventsPageLimit || result.Cursor == "" || result.Cursor == cursor {
+			if result.Cursor != "" {
+				cursor = result.Cursor
+			}
+			caughtUp = true
+			break
+		}
+		cursor = result.Cursor
+	}
+
+	events, err := s.parseTradeEvents(rawEvents)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get events: %w", err)
+		return nil, "", false, err
 	}
+	return events, cursor, caughtUp, nil
+}
 
+// parseTradeEvents parses the trade events of successful contract calls.
+func (s *EventService) parseTradeEvents(rawEvents []soroban.ContractEvent) ([]TradeEvent, error) {
 	var events []TradeEvent
 	var parseErrors int
 	var lastParseErr error
 	successfulEvents := 0
-	for _, evt := range result.Events {
+	for _, evt := range rawEvents {
 		if !evt.InSuccessfulContractCall {
 			continue
 		}
@@ -233,6 +331,8 @@ func (s *EventService) parseTradeEvent(evt soroban.ContractEvent) (TradeEvent, e
 		Cost:      float64(cost) / float64(soroban.ScaleFactor),
 		Timestamp: ts,
 		Ledger:    evt.Ledger,
+		TxHash:    evt.TxHash,
+		ID:        evt.ID,
 	}, nil
 }
 
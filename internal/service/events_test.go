@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// eventRPC serves getLatestLedger and getEvents over a list of trade events whose
+// IDs sort in ledger order.
+type eventRPC struct {
+	mu      sync.Mutex
+	latest  uint32
+	events  []soroban.ContractEvent
+	cursors []string // cursor of each getEvents call
+}
+
+func (f *eventRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string                  `json:"method"`
+		Params soroban.GetEventsParams `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result any
+	switch req.Method {
+	case "getLatestLedger":
+		result = soroban.GetLatestLedgerResult{Sequence: f.latest, ProtocolVersion: 23}
+	case "getEvents":
+		cursor := req.Params.Pagination.Cursor
+		f.cursors = append(f.cursors, cursor)
+		page := soroban.GetEventsResult{Cursor: cursor, LatestLedger: f.latest}
+		for _, evt := range f.events {
+			if len(page.Events) == req.Params.Pagination.Limit {
+				break
+			}
+			if (cursor == "" && evt.Ledger >= req.Params.StartLedger) || (cursor != "" && evt.ID > cursor) {
+				page.Events = append(page.Events, evt)
+				page.Cursor = evt.ID
+			}
+		}
+		result = page
+	}
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+}
+
+func (f *eventRPC) add(t *testing.T, ledger uint32, n int) {
+	t.Helper()
+	topic := func(v xdr.ScVal) string {
+		b, err := xdr.MarshalBase64(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	user, err := soroban.EncodeAddress(keypair.MustRandom().Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	amounts := &xdr.ScVec{soroban.EncodeI128(soroban.ScaleFactor), soroban.EncodeI128(soroban.ScaleFactor / 2)}
+	value := xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &amounts}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for range n {
+		f.events = append(f.events, soroban.ContractEvent{
+			Type:                     soroban.EventTypeContract,
+			Ledger:                   ledger,
+			LedgerClosedAt:           "2026-10-15T12:00:00Z",
+			ID:                       fmt.Sprintf("%019d-%010d", uint64(ledger)<<32, len(f.events)),
+			Topic:                    []string{topic(soroban.EncodeSymbol("buy")), topic(user), topic(soroban.EncodeU32(0))},
+			Value:                    topic(value),
+			InSuccessfulContractCall: true,
+		})
+	}
+}
+
+func (f *eventRPC) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.cursors...)
+}
+
+func TestTradeEventsContinueFromCursor(t *testing.T) {
+	rpc := &eventRPC{latest: 100000}
+	srv := httptest.NewServer(rpc)
+	t.Cleanup(srv.Close)
+	s := NewEventService(soroban.NewClient(srv.URL, srv.Client()), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	// More events than one fetch pages through, plus one outside the lookback window.
+	total := maxEventPages*soroban.EventsPageLimit + 50
+	rpc.add(t, rpc.latest-lookbackLedgers-1, 1)
+	rpc.add(t, rpc.latest-100, total)
+
+	events, err := s.GetTradeEvents(ctx, "contract")
+	if err != nil {
+		t.Fatalf("GetTradeEvents() error = %v", err)
+	}
+	if len(events) != maxEventPages*soroban.EventsPageLimit {
+		t.Fatalf("first fetch = %d events, want the %d of one fetch", len(events), maxEventPages*soroban.EventsPageLimit)
+	}
+
+	events, err = s.GetTradeEvents(ctx, "contract")
+	if err != nil {
+		t.Fatalf("GetTradeEvents() error = %v", err)
+	}
+	if len(events) != total || events[len(events)-1].ID != rpc.events[len(rpc.events)-1].ID {
+		t.Fatalf("second fetch = %d events, want all %d up to the newest", len(events), total)
+	}
+	if got := len(rpc.calls()); got != maxEventPages+1 {
+		t.Errorf("getEvents calls = %d, want %d: the second fetch continues from the cursor", got, maxEventPages+1)
+	}
+
+	// The caught-up result is cached; a later fetch only pages through new events.
+	if _, err := s.GetTradeEvents(ctx, "contract"); err != nil {
+		t.Fatalf("GetTradeEvents() error = %v", err)
+	}
+	if got := len(rpc.calls()); got != maxEventPages+1 {
+		t.Errorf("getEvents calls = %d, want the cached result", got)
+	}
+	rpc.add(t, rpc.latest, 3)
+	events, _, err = s.fetchEvents(ctx, "contract")
+	if err != nil {
+		t.Fatalf("fetchEvents() error = %v", err)
+	}
+	calls := rpc.calls()
+	if len(events) != total+3 || len(calls) != maxEventPages+2 || calls[len(calls)-1] == "" {
+		t.Errorf("fetchEvents() = %d events after %d calls, want %d from one call with a cursor", len(events), len(calls), total+3)
+	}
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/s3"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// ExportInterval is how often the analytics export runs. Trades come from the event
+// lookback of about a day, so a daily export covers every trade.
+const ExportInterval = 24 * time.Hour
+
+// ExportSink stores the files of an export.
+type ExportSink interface {
+	// Put stores a file; name is a slash-separated relative path.
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+// NewExportSink returns a sink for an export target: s3://bucket/prefix for an
+// S3-compatible bucket, anything else is a local directory.
+func NewExportSink(target string, s3Config s3.Config, httpClient *http.Client) (ExportSink, error) {
+	if !strings.HasPrefix(target, "s3://") {
+		return dirSink(target), nil
+	}
+	t, err := s3.ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s3.NewClient(s3Config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Sink{client: client, target: t}, nil
+}
+
+// dirSink writes export files under a local directory.
+type dirSink string
+
+func (d dirSink) Put(_ context.Context, name string, data []byte) error {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// s3Sink uploads export files to a bucket.
+type s3Sink struct {
+	client *s3.Client
+	target s3.Target
+}
+
+func (s *s3Sink) Put(ctx context.Context, name string, data []byte) error {
+	return s.client.Put(ctx, s.target.Bucket, s.target.Prefix+name, data, "text/csv")
+}
+
+// ExportResult summarizes an export.
+type ExportResult struct {
+	Dir     string // YYYY-MM-DD directory the files were written to
+	Markets int
+	Trades  int
+	Prices  int
+}
+
+// Exporter dumps trades, markets and price history to CSV files for offline analysis.
+// Each run writes trades.csv, markets.csv and prices.csv under a directory named after
+// the UTC date; a later run on the same day replaces them.
+type Exporter struct {
+	factoryService *FactoryService
+	eventService   *EventService
+	ipfsClient     *ipfs.Client
+	priceHistory   *PriceHistory // nil skips prices.csv
+	sink           ExportSink
+	logger         *slog.Logger
+}
+
+// NewExporter creates an exporter writing to sink.
+func NewExporter(factoryService *FactoryService, eventService *EventService, ipfsClient *ipfs.Client, priceHistory *PriceHistory, sink ExportSink, logger *slog.Logger) *Exporter {
+	return &Exporter{
+		factoryService: factoryService,
+		eventService:   eventService,
+		ipfsClient:     ipfsClient,
+		priceHistory:   priceHistory,
+		sink:           sink,
+		logger:         logger,
+	}
+}
+
+// exportMarket is a market row of markets.csv.
+type exportMarket struct {
+	state    MarketState
+	metadata model.MarketMetadata
+}
+
+type exportFile struct {
+	name string
+	data []byte
+}
+
+// Run exports all markets of the factory. Markets whose metadata or trades cannot be
+// fetched are exported without them and reported in the returned error; the files
+// are written either way.
+func (e *Exporter) Run(ctx context.Context, now time.Time) (ExportResult, error) {
+	result := ExportResult{Dir: now.UTC().Format(time.DateOnly)}
+	if !e.factoryService.HasFactory() {
+		return result, nil
+	}
+	ids, err := e.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := e.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		e.logger.Warn("export: failed to get some market states", "error", err)
+	}
+
+	var errs []error
+	markets := make([]exportMarket, 0, len(loaded.States))
+	trades := make(map[string][]TradeEvent, len(loaded.States))
+	for _, state := range loaded.States {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		m := exportMarket{state: state}
+		if state.MetadataHash != "" {
+			if err := e.ipfsClient.GetJSON(ctx, state.MetadataHash, &m.metadata); err != nil {
+				errs = append(errs, fmt.Errorf("market %s: failed to fetch metadata: %w", state.ContractID, err))
+			}
+		}
+		markets = append(markets, m)
+		events, err := e.eventService.GetTradeEvents(ctx, state.ContractID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			continue
+		}
+		trades[state.ContractID] = events
+	}
+
+	files := []exportFile{
+		{"markets.csv", marketsCSV(markets)},
+		{"trades.csv", tradesCSV(trades)},
+	}
+	result.Markets = len(markets)
+	for _, events := range trades {
+		result.Trades += len(events)
+	}
+	if e.priceHistory != nil {
+		rows := e.priceHistory.Rows()
+		files = append(files, exportFile{"prices.csv", pricesCSV(rows)})
+		result.Prices = len(rows)
+	}
+	for _, f := range files {
+		if err := e.sink.Put(ctx, result.Dir+"/"+f.name, f.data); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// marketsCSV encodes one row per market, sorted by contract ID.
+func marketsCSV(markets []exportMarket) []byte {
+	slices.SortFunc(markets, func(a, b exportMarket) int { return strings.Compare(a.state.ContractID, b.state.ContractID) })
+	rows := [][]string{{
+		"contract_id", "question", "category", "created_at", "end_date", "resolved", "winning_outcome",
+		"yes_sold", "no_sold", "pool", "price_yes", "price_no", "metadata_hash",
+	}}
+	for _, m := range markets {
+		rows = append(rows, []string{
+			m.state.ContractID,
+			m.metadata.Question,
+			m.metadata.Category,
+			csvTime(m.metadata.CreatedAt),
+			csvTime(m.metadata.EndDate),
+			strconv.FormatBool(m.state.Resolved),
+			m.state.WinningOutcome,
+			csvScaled(m.state.YesSold),
+			csvScaled(m.state.NoSold),
+			csvScaled(m.state.Pool),
+			csvFloat(m.state.PriceYes),
+			csvFloat(m.state.PriceNo),
+			m.state.MetadataHash,
+		})
+	}
+	return encodeCSV(rows)
+}
+
+// tradesCSV encodes one row per trade, sorted by market and event ID. Consecutive
+// exports overlap by the part of the event lookback they share: dedupe by event_id.
+func tradesCSV(trades map[string][]TradeEvent) []byte {
+	rows := [][]string{{
+		"contract_id", "event_id", "ledger", "timestamp", "tx_hash", "user", "kind", "outcome", "amount", "cost",
+	}}
+	for _, id := range slices.Sorted(maps.Keys(trades)) {
+		events := slices.Clone(trades[id])
+		slices.SortStableFunc(events, func(a, b TradeEvent) int { return strings.Compare(a.ID, b.ID) })
+		for _, evt := range events {
+			rows = append(rows, []string{
+				id,
+				evt.ID,
+				strconv.FormatUint(uint64(evt.Ledger), 10),
+				csvTime(evt.Timestamp),
+				evt.TxHash,
+				evt.User,
+				string(evt.Kind),
+				evt.Outcome,
+				csvFloat(evt.Amount),
+				csvFloat(evt.Cost),
+			})
+		}
+	}
+	return encodeCSV(rows)
+}
+
+// pricesCSV encodes the price history; raw samples have granularity "sample".
+func pricesCSV(history []PriceRow) []byte {
+	rows := [][]string{{"contract_id", "granularity", "start", "open", "high", "low", "close", "samples"}}
+	for _, r := range history {
+		rows = append(rows, []string{
+			r.ContractID,
+			r.Granularity,
+			csvTime(r.Start),
+			csvFloat(r.Open),
+			csvFloat(r.High),
+			csvFloat(r.Low),
+			csvFloat(r.Close),
+			strconv.Itoa(r.Samples),
+		})
+	}
+	return encodeCSV(rows)
+}
+
+func encodeCSV(rows [][]string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	// Writing to a bytes.Buffer cannot fail.
+	_ = w.WriteAll(rows)
+	return buf.Bytes()
+}
+
+// csvTime formats a time as RFC 3339 in UTC, or empty when zero.
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func csvFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// csvScaled formats a contract amount scaled by soroban.ScaleFactor as tokens.
+func csvScaled(v int64) string {
+	return csvFloat(float64(v) / float64(soroban.ScaleFactor))
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/s3"
+)
+
+func TestExportCSV(t *testing.T) {
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	markets := marketsCSV([]exportMarket{{
+		state: MarketState{ContractID: testContractID, YesSold: 25_000_000, Pool: 15_000_000, PriceYes: 0.6, PriceNo: 0.4, Resolved: true, WinningOutcome: "YES"},
+	}})
+	wantMarkets := "contract_id,question,category,created_at,end_date,resolved,winning_outcome,yes_sold,no_sold,pool,price_yes,price_no,metadata_hash\n" +
+		testContractID + ",,,,,true,YES,2.5,0,1.5,0.6,0.4,\n"
+	if string(markets) != wantMarkets {
+		t.Errorf("marketsCSV() =\n%s\nwant\n%s", markets, wantMarkets)
+	}
+
+	trades := tradesCSV(map[string][]TradeEvent{testContractID: {
+		{ID: "0002", Kind: TradeKindSell, User: "GB", Outcome: "NO", Amount: 1, Cost: 0.45, Ledger: 11, Timestamp: at.Add(time.Minute)},
+		{ID: "0001", Kind: TradeKindBuy, User: "GA", Outcome: "YES", Amount: 2, Cost: 1.1, Ledger: 10, Timestamp: at, TxHash: "abc"},
+	}})
+	wantTrades := "contract_id,event_id,ledger,timestamp,tx_hash,user,kind,outcome,amount,cost\n" +
+		testContractID + ",0001,10,2025-03-01T10:00:00Z,abc,GA,buy,YES,2,1.1\n" +
+		testContractID + ",0002,11,2025-03-01T10:01:00Z,,GB,sell,NO,1,0.45\n"
+	if string(trades) != wantTrades {
+		t.Errorf("tradesCSV() =\n%s\nwant\n%s", trades, wantTrades)
+	}
+
+	h, err := NewPriceHistory(&FactoryService{}, DefaultPriceRetention, "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewPriceHistory() error = %v", err)
+	}
+	h.record([]MarketState{{ContractID: testContractID, PriceYes: 0.55}}, at)
+	prices := string(pricesCSV(h.Rows()))
+	if want := testContractID + ",sample,2025-03-01T10:00:00Z,0.55,0.55,0.55,0.55,1\n"; !strings.HasSuffix(prices, want) {
+		t.Errorf("pricesCSV() =\n%s\nwant a row %s", prices, want)
+	}
+}
+
+func TestExportDirSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewExportSink(dir, s3.Config{}, nil)
+	if err != nil {
+		t.Fatalf("NewExportSink() error = %v", err)
+	}
+	if err := sink.Put(context.Background(), "2025-03-01/trades.csv", []byte("a\n")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "2025-03-01", "trades.csv"))
+	if err != nil || string(data) != "a\n" {
+		t.Errorf("file = %q, %v, want %q", data, err, "a\n")
+	}
+
+	if _, err := NewExportSink("s3://bucket/exports", s3.Config{}, nil); err == nil {
+		t.Error("NewExportSink() for a bucket without credentials error = nil, want an error")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mtlprog/total/internal/cachestats"
 	"github.com/mtlprog/total/internal/model"
 	"github.com/mtlprog/total/internal/soroban"
 	"github.com/mtlprog/total/internal/stellar"
@@ -18,6 +19,15 @@ import (
 var (
 	ErrFactoryNotConfigured = errors.New("factory contract not configured")
 	ErrInvalidMetadataHash  = errors.New("invalid metadata hash")
+	// ErrUndecodableState is returned when the RPC answers get_state with a value that
+	// is not a market state.
+	ErrUndecodableState = errors.New("undecodable market state")
+	// ErrScalarMarketUnsupported is returned for deploying a market with scalar metadata:
+	// the market contract pays 1 per winning token and cannot split payouts by value.
+	ErrScalarMarketUnsupported = errors.New("scalar markets need a market contract with fractional payouts")
+	// ErrMetadataUnavailable is returned for deploying metadata that cannot be fetched
+	// from IPFS, and so cannot be validated.
+	ErrMetadataUnavailable = errors.New("market metadata could not be fetched")
 )
 
 // FactoryService handles market factory operations.
@@ -30,6 +40,14 @@ type FactoryService struct {
 	logger          *slog.Logger
 	stateCache      *StateCache
 	marketListCache *hot.HotCache[string, []string]
+	versions        *ContractVersionRegistry // nil: every market gets the baseline ABI
+
+	frozenMu sync.RWMutex
+	frozen   map[string]MarketState // archived markets, served without RPC calls
+
+	quarantine *Quarantine // nil unless strict markets are enabled
+
+	watch stateWatch
 }
 
 // NewFactoryService creates a new factory service.
@@ -39,6 +57,7 @@ func NewFactoryService(
 	txBuilder *stellar.Builder,
 	factoryContract string,
 	oraclePublicKey string,
+	versions *ContractVersionRegistry,
 	logger *slog.Logger,
 ) *FactoryService {
 	fs := &FactoryService{
@@ -47,13 +66,18 @@ func NewFactoryService(
 		txBuilder:       txBuilder,
 		factoryContract: factoryContract,
 		oraclePublicKey: oraclePublicKey,
+		versions:        versions,
 		logger:          logger,
+		frozen:          make(map[string]MarketState),
 	}
 
 	// Initialize state cache with a loader that fetches from Soroban RPC
 	fs.stateCache = NewStateCache(func(ids []string) (map[string]MarketState, error) {
 		result := make(map[string]MarketState, len(ids))
 		for _, id := range ids {
+			if _, ok := fs.frozenState(id); ok {
+				continue
+			}
 			ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
 			state, err := fs.fetchMarketState(ctx, id)
 			cancel()
@@ -121,6 +145,20 @@ func (s *FactoryService) ListMarkets(ctx context.Context) ([]string, error) {
 	return ids, nil
 }
 
+// RefreshMarketList fetches the market list bypassing the cache and stores it, so a
+// market deployed a moment ago is listed without waiting for revalidation.
+func (s *FactoryService) RefreshMarketList(ctx context.Context) ([]string, error) {
+	if s.factoryContract == "" {
+		return nil, ErrFactoryNotConfigured
+	}
+	ids, err := s.fetchMarketList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.marketListCache.Set("all", ids)
+	return ids, nil
+}
+
 // fetchMarketList fetches market IDs from the factory contract via RPC.
 func (s *FactoryService) fetchMarketList(ctx context.Context) ([]string, error) {
 	txXDR, err := s.txBuilder.BuildListMarketsTx(ctx, stellar.ListMarketsTxParams{
@@ -179,63 +217,145 @@ type MarketState struct {
 	MetadataHash   string
 	PriceYes       float64
 	PriceNo        float64
+	// ContractVersion is the registered version of the market's WASM build; empty
+	// when the build is unregistered or could not be detected.
+	ContractVersion string
+	WasmHash        string
+}
+
+// StateCacheStats returns hit and miss counts of the market state cache.
+func (s *FactoryService) StateCacheStats() cachestats.Stats {
+	return s.stateCache.Stats()
 }
 
-// GetMarketStates fetches state for multiple markets in parallel.
-func (s *FactoryService) GetMarketStates(ctx context.Context, contractIDs []string) ([]MarketState, error) {
+// MarketStateVersion returns the cache version of a state returned by GetMarketStates,
+// for conditional requests by clients polling a market.
+func (s *FactoryService) MarketStateVersion(state MarketState) StateVersion {
+	return s.stateCache.Version(state.ContractID, state)
+}
+
+// FreezeMarketState stores the final state of an archived market.
+// Frozen states are served by GetMarketStates without RPC calls or cache revalidation.
+func (s *FactoryService) FreezeMarketState(state MarketState) {
+	s.frozenMu.Lock()
+	s.frozen[state.ContractID] = state
+	s.frozenMu.Unlock()
+}
+
+func (s *FactoryService) frozenState(contractID string) (MarketState, bool) {
+	s.frozenMu.RLock()
+	defer s.frozenMu.RUnlock()
+	state, ok := s.frozen[contractID]
+	return state, ok
+}
+
+// EnableStrictMarkets makes GetMarketStates quarantine markets with undecodable state
+// or, once versions are registered, a WASM build the contract version registry does
+// not know. Call it before the service is used.
+func (s *FactoryService) EnableStrictMarkets() {
+	s.quarantine = NewQuarantine()
+}
+
+// Quarantine returns the markets strict mode hides from public lists, or nil when
+// strict markets are disabled.
+func (s *FactoryService) Quarantine() *Quarantine {
+	return s.quarantine
+}
+
+// inspect updates the quarantine with the outcome of loading a market's state.
+func (s *FactoryService) inspect(contractID string, state MarketState, err error) {
+	q := s.quarantine
+	if q == nil {
+		return
+	}
+	if err != nil {
+		if errors.Is(err, ErrUndecodableState) {
+			q.Flag(contractID, QuarantineUndecodableState, err.Error())
+		}
+		return
+	}
+	q.Clear(contractID, QuarantineUndecodableState)
+	// Without registered versions every build would be unknown
+	if s.versions != nil && s.versions.Len() > 0 && state.WasmHash != "" && state.ContractVersion == "" {
+		q.Flag(contractID, QuarantineUnknownWasm, state.WasmHash)
+	} else {
+		q.Clear(contractID, QuarantineUnknownWasm)
+	}
+}
+
+// MarketStateFailure is a market whose state could not be loaded.
+type MarketStateFailure struct {
+	ContractID string
+	Reason     string
+}
+
+// MarketStates is the result of GetMarketStates. Markets whose state could not be
+// loaded are listed in Failed rather than left out, so callers can tell a market that
+// is temporarily unavailable from one that is not listed.
+type MarketStates struct {
+	States []MarketState        // In the order requested
+	Failed []MarketStateFailure // In the order requested
+}
+
+// GetMarketStates fetches state for multiple markets in parallel. It returns an error
+// only when every fetch failed; otherwise failed markets are reported in Failed.
+func (s *FactoryService) GetMarketStates(ctx context.Context, contractIDs []string) (MarketStates, error) {
 	states := make([]MarketState, len(contractIDs))
+	errs := make([]error, len(contractIDs))
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var firstErr error
 
 	for i, id := range contractIDs {
 		wg.Add(1)
 		go func(idx int, contractID string) {
 			defer wg.Done()
 
+			if frozen, ok := s.frozenState(contractID); ok {
+				states[idx] = frozen
+				return
+			}
+
 			// Check cache first
 			if cached, ok := s.stateCache.Get(contractID); ok {
-				mu.Lock()
 				states[idx] = cached
-				mu.Unlock()
 				return
 			}
 
 			state, err := s.fetchMarketState(ctx, contractID)
 			if err != nil {
-				mu.Lock()
-				if firstErr == nil {
-					firstErr = fmt.Errorf("failed to get state for %s: %w", contractID, err)
-				}
-				mu.Unlock()
+				errs[idx] = err
 				s.logger.Warn("failed to get market state", "contract_id", contractID, "error", err)
 				return
 			}
 
 			s.stateCache.Set(contractID, *state)
-
-			mu.Lock()
 			states[idx] = *state
-			mu.Unlock()
 		}(i, id)
 	}
 
 	wg.Wait()
 
-	// Filter out empty states (failed fetches)
-	validStates := make([]MarketState, 0, len(states))
-	for _, state := range states {
-		if state.ContractID != "" {
-			validStates = append(validStates, state)
+	result := MarketStates{States: make([]MarketState, 0, len(states))}
+	var firstErr error
+	for i, state := range states {
+		if _, frozen := s.frozenState(contractIDs[i]); !frozen {
+			s.inspect(contractIDs[i], state, errs[i])
 		}
+		if errs[i] != nil {
+			result.Failed = append(result.Failed, MarketStateFailure{ContractID: contractIDs[i], Reason: errs[i].Error()})
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to get state for %s: %w", contractIDs[i], errs[i])
+			}
+			continue
+		}
+		result.States = append(result.States, state)
 	}
 
 	// Return error when all fetches failed
-	if len(validStates) == 0 && len(contractIDs) > 0 && firstErr != nil {
-		return nil, fmt.Errorf("failed to fetch any market states: %w", firstErr)
+	if len(result.States) == 0 && firstErr != nil {
+		return result, fmt.Errorf("failed to fetch any market states: %w", firstErr)
 	}
 
-	return validStates, nil
+	return result, nil
 }
 
 // fetchMarketState fetches state for a single market from Soroban RPC (bypasses cache).
@@ -264,37 +384,37 @@ func (s *FactoryService) fetchMarketState(ctx context.Context, contractID string
 
 	returnVal, err := soroban.ParseReturnValue(simResult.Results[0].XDR)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse return value: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse return value: %w", ErrUndecodableState, err)
 	}
 
 	// get_state returns (yes_sold, no_sold, pool, resolved)
 	tuple, err := soroban.DecodeVec(returnVal)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode state tuple: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode state tuple: %w", ErrUndecodableState, err)
 	}
 
 	if len(tuple) < 4 {
-		return nil, fmt.Errorf("expected 4 elements in state tuple, got %d", len(tuple))
+		return nil, fmt.Errorf("%w: expected 4 elements in state tuple, got %d", ErrUndecodableState, len(tuple))
 	}
 
 	yesSold, err := soroban.DecodeI128(tuple[0])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode yes_sold: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode yes_sold: %w", ErrUndecodableState, err)
 	}
 
 	noSold, err := soroban.DecodeI128(tuple[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode no_sold: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode no_sold: %w", ErrUndecodableState, err)
 	}
 
 	pool, err := soroban.DecodeI128(tuple[2])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode pool: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode pool: %w", ErrUndecodableState, err)
 	}
 
 	resolved, err := soroban.DecodeBool(tuple[3])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode resolved: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode resolved: %w", ErrUndecodableState, err)
 	}
 
 	// Get winning outcome if resolved
@@ -316,19 +436,58 @@ func (s *FactoryService) fetchMarketState(ctx context.Context, contractID string
 	// Calculate prices using LMSR formula
 	priceYes, priceNo := calculatePrices(yesSold, noSold)
 
+	version := s.MarketContractVersion(ctx, contractID)
+
+	s.logger.Debug("fetched market state", "contract_id", contractID, "yes_sold", yesSold, "no_sold", noSold, "resolved", resolved)
+
 	return &MarketState{
-		ContractID:     contractID,
-		YesSold:        yesSold,
-		NoSold:         noSold,
-		Pool:           pool,
-		Resolved:       resolved,
-		WinningOutcome: winningOutcome,
-		MetadataHash:   metadataHash,
-		PriceYes:       priceYes,
-		PriceNo:        priceNo,
+		ContractID:      contractID,
+		YesSold:         yesSold,
+		NoSold:          noSold,
+		Pool:            pool,
+		Resolved:        resolved,
+		WinningOutcome:  winningOutcome,
+		MetadataHash:    metadataHash,
+		PriceYes:        priceYes,
+		PriceNo:         priceNo,
+		ContractVersion: version.Version,
+		WasmHash:        version.WasmHash,
 	}, nil
 }
 
+// MarketContractVersion returns the contract version a market runs. It is the zero
+// version, with no capabilities, without a registry or when detection fails.
+func (s *FactoryService) MarketContractVersion(ctx context.Context, contractID string) ContractVersion {
+	if s.versions == nil {
+		return ContractVersion{}
+	}
+	v, err := s.versions.Detect(ctx, contractID)
+	if err != nil {
+		s.logger.Warn("failed to detect market contract version", "contract_id", contractID, "error", err)
+	}
+	return v
+}
+
+// RequireCapability returns ErrCapabilityUnsupported unless the market's contract
+// version supports c. Features beyond the baseline ABI check it before building
+// transactions, so markets on older builds keep working without them.
+func (s *FactoryService) RequireCapability(ctx context.Context, contractID string, c ContractCapability) error {
+	v := s.MarketContractVersion(ctx, contractID)
+	if !v.Has(c) {
+		name := v.Version
+		if name == "" {
+			name = "unregistered build"
+		}
+		return fmt.Errorf("%w: %s (%s)", ErrCapabilityUnsupported, c, name)
+	}
+	return nil
+}
+
+// ContractVersions returns the contract version registry, nil when not configured.
+func (s *FactoryService) ContractVersions() *ContractVersionRegistry {
+	return s.versions
+}
+
 // getMetadataHash fetches metadata hash from contract.
 func (s *FactoryService) getMetadataHash(ctx context.Context, contractID string) (string, error) {
 	txXDR, err := s.txBuilder.BuildGetMetadataHashTx(ctx, stellar.GetMetadataHashTxParams{
@@ -456,15 +615,20 @@ type DeployMarketRequest struct {
 
 // Validate validates the deploy request.
 func (r *DeployMarketRequest) Validate() error {
-	if r.LiquidityParam <= 0 {
-		return model.ErrInvalidLiquidityParam
-	}
 	if r.MetadataHash == "" {
 		return ErrInvalidMetadataHash
 	}
+	return checkDeployFunding(r.LiquidityParam, r.InitialFunding)
+}
+
+// checkDeployFunding checks the liquidity parameter and initial funding of a new market.
+func checkDeployFunding(liquidityParam, initialFunding float64) error {
+	if liquidityParam <= 0 {
+		return model.ErrInvalidLiquidityParam
+	}
 	// Initial funding must be at least 70% of liquidity param (b * ln(2) ≈ 0.693)
-	minFunding := r.LiquidityParam * 0.7
-	if r.InitialFunding < minFunding {
+	minFunding := liquidityParam * 0.7
+	if initialFunding < minFunding {
 		return fmt.Errorf("initial funding must be at least %.2f (70%% of liquidity parameter)", minFunding)
 	}
 	return nil
@@ -514,10 +678,10 @@ func (s *FactoryService) BuildDeployMarketTx(ctx context.Context, req DeployMark
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: fmt.Sprintf("Deploy new market (b=%.2f, funding=%.2f)", req.LiquidityParam, req.InitialFunding),
 		SignWith:    s.oraclePublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
 }
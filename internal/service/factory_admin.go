@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+var (
+	ErrInvalidFactoryAdminOp  = errors.New("unknown factory admin operation")
+	ErrInvalidWasmHash        = errors.New("invalid WASM hash: expected 64 hex characters")
+	ErrWasmNotInstalled       = errors.New("WASM code with this hash has not been uploaded to the network")
+	ErrInvalidCollateralToken = errors.New("invalid collateral token: expected a token contract ID (C...)")
+	ErrFactoryValueUnchanged  = errors.New("factory already uses this value")
+)
+
+// FactoryAdminOp is an admin-only call of the market factory contract.
+type FactoryAdminOp string
+
+const (
+	FactoryOpSetMarketWasm FactoryAdminOp = "set_market_wasm_hash"
+	FactoryOpSetCollateral FactoryAdminOp = "set_default_collateral_token"
+)
+
+// FactoryAdminRequest changes a factory setting. Only the field of Op is used.
+type FactoryAdminRequest struct {
+	Op              FactoryAdminOp
+	WasmHash        string // FactoryOpSetMarketWasm: hex hash of an uploaded market build
+	CollateralToken string // FactoryOpSetCollateral: token contract ID
+	RequestedBy     string // Signed-in account, recorded in the audit log
+}
+
+// Validate normalizes and checks the request.
+func (r *FactoryAdminRequest) Validate() error {
+	switch r.Op {
+	case FactoryOpSetMarketWasm:
+		r.WasmHash = strings.ToLower(strings.TrimSpace(r.WasmHash))
+		if b, err := hex.DecodeString(r.WasmHash); err != nil || len(b) != 32 {
+			return ErrInvalidWasmHash
+		}
+	case FactoryOpSetCollateral:
+		r.CollateralToken = strings.TrimSpace(r.CollateralToken)
+		if !strkey.IsValidContractAddress(r.CollateralToken) {
+			return ErrInvalidCollateralToken
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidFactoryAdminOp, r.Op)
+	}
+	return nil
+}
+
+// FactorySettings are the factory values readable on-chain. The contract has no
+// getter for the default collateral token.
+type FactorySettings struct {
+	Admin          string
+	MarketWasmHash string
+}
+
+// FactoryAdminSummary is what the oracle confirms before a factory admin transaction
+// is built: the current and proposed value and what the change affects.
+type FactoryAdminSummary struct {
+	Request  FactoryAdminRequest
+	Settings FactorySettings
+	Current  string // Empty when the contract cannot report it
+	Proposed string
+	// CurrentVersion and ProposedVersion describe WASM builds from the contract
+	// version registry; Version is empty for unregistered builds.
+	CurrentVersion  ContractVersion
+	ProposedVersion ContractVersion
+	Warnings        []string
+}
+
+// FactorySettings reads the admin and market WASM hash from the factory contract.
+func (s *FactoryService) FactorySettings(ctx context.Context) (*FactorySettings, error) {
+	if s.factoryContract == "" {
+		return nil, ErrFactoryNotConfigured
+	}
+	params := stellar.FactoryQueryTxParams{UserPublicKey: s.oraclePublicKey, FactoryContract: s.factoryContract}
+
+	txXDR, err := s.txBuilder.BuildGetFactoryAdminTx(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get_admin tx: %w", err)
+	}
+	val, err := simulateReturnValue(ctx, s.sorobanClient, txXDR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read factory admin: %w", err)
+	}
+	admin, err := soroban.DecodeAddress(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode factory admin: %w", err)
+	}
+
+	txXDR, err = s.txBuilder.BuildGetMarketWasmHashTx(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get_market_wasm_hash tx: %w", err)
+	}
+	val, err = simulateReturnValue(ctx, s.sorobanClient, txXDR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market wasm hash: %w", err)
+	}
+	if val.Type != xdr.ScValTypeScvBytes || val.Bytes == nil || len(*val.Bytes) != 32 {
+		return nil, fmt.Errorf("unexpected market wasm hash value %s", soroban.FormatSCVal(val))
+	}
+
+	return &FactorySettings{Admin: admin, MarketWasmHash: hex.EncodeToString(*val.Bytes)}, nil
+}
+
+// ReviewFactoryAdmin validates a factory admin request against the current settings
+// and summarizes it for confirmation.
+func (s *FactoryService) ReviewFactoryAdmin(ctx context.Context, req FactoryAdminRequest) (*FactoryAdminSummary, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	settings, err := s.FactorySettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &FactoryAdminSummary{Request: req, Settings: *settings}
+	switch req.Op {
+	case FactoryOpSetMarketWasm:
+		summary.Current, summary.Proposed = settings.MarketWasmHash, req.WasmHash
+		if summary.Current == summary.Proposed {
+			return nil, ErrFactoryValueUnchanged
+		}
+		installed, err := s.sorobanClient.WasmInstalled(ctx, req.WasmHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up wasm code: %w", err)
+		}
+		if !installed {
+			return nil, ErrWasmNotInstalled
+		}
+		summary.CurrentVersion = s.registeredVersion(summary.Current)
+		summary.ProposedVersion = s.registeredVersion(summary.Proposed)
+		if summary.ProposedVersion.Version == "" {
+			summary.Warnings = append(summary.Warnings, "The new build is not in the contract version registry, so its markets get no optional features. Register it on /admin first.")
+		}
+		summary.Warnings = append(summary.Warnings, "Only markets deployed after this change run the new build; existing markets keep their code.")
+	case FactoryOpSetCollateral:
+		summary.Proposed = req.CollateralToken
+		summary.Warnings = append(summary.Warnings, "Only markets deployed after this change use the new token; existing markets keep their collateral. Check COLLATERAL_ASSET matches before deploying.")
+	}
+	if settings.Admin != s.oraclePublicKey {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("The factory admin is %s, not the oracle account; that account has to sign.", settings.Admin))
+	}
+	return summary, nil
+}
+
+// BuildFactoryAdminTx builds a factory admin transaction signed by the factory admin.
+// Every build is written to the audit log with the requesting account.
+func (s *FactoryService) BuildFactoryAdminTx(ctx context.Context, req FactoryAdminRequest) (*model.TransactionResult, error) {
+	summary, err := s.ReviewFactoryAdmin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	req, admin := summary.Request, summary.Settings.Admin
+
+	var txXDR, description string
+	switch req.Op {
+	case FactoryOpSetMarketWasm:
+		var hash [32]byte
+		b, _ := hex.DecodeString(req.WasmHash)
+		copy(hash[:], b)
+		txXDR, err = s.txBuilder.BuildSetMarketWasmHashTx(ctx, stellar.SetMarketWasmHashTxParams{
+			AdminPublicKey:  admin,
+			FactoryContract: s.factoryContract,
+			WasmHash:        hash,
+		})
+		description = "Set factory market WASM to " + describeBuild(summary.ProposedVersion)
+	case FactoryOpSetCollateral:
+		txXDR, err = s.txBuilder.BuildSetCollateralTokenTx(ctx, stellar.SetCollateralTokenTxParams{
+			AdminPublicKey:  admin,
+			FactoryContract: s.factoryContract,
+			TokenContract:   req.CollateralToken,
+		})
+		description = "Set factory collateral token to " + req.CollateralToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s transaction: %w", req.Op, err)
+	}
+
+	preparedXDR, err := s.txBuilder.SimulateAndPrepareTx(ctx, txXDR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+
+	s.logger.Warn("audit: factory admin transaction built",
+		"function", req.Op,
+		"factory", s.factoryContract,
+		"from", summary.Current,
+		"to", summary.Proposed,
+		"admin", admin,
+		"requested_by", req.RequestedBy,
+	)
+
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
+		XDR:         preparedXDR,
+		Description: description,
+		SignWith:    admin,
+		SubmitURL:   s.sorobanClient.RPCURL(),
+	}), nil
+}
+
+// registeredVersion returns the registered version of a WASM build, or one with only
+// the hash set.
+func (s *FactoryService) registeredVersion(wasmHash string) ContractVersion {
+	if s.versions != nil {
+		for _, v := range s.versions.List() {
+			if v.WasmHash == wasmHash {
+				return v
+			}
+		}
+	}
+	return ContractVersion{WasmHash: wasmHash}
+}
+
+// describeBuild names a WASM build by version and short hash.
+func describeBuild(v ContractVersion) string {
+	short := v.WasmHash
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	if v.Version == "" {
+		return short
+	}
+	return v.Version + " (" + short + ")"
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFactoryAdminRequestValidate(t *testing.T) {
+	token := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	tests := []struct {
+		name    string
+		req     FactoryAdminRequest
+		wantErr error
+	}{
+		{"wasm hash", FactoryAdminRequest{Op: FactoryOpSetMarketWasm, WasmHash: " " + strings.Repeat("AB", 32) + " "}, nil},
+		{"short wasm hash", FactoryAdminRequest{Op: FactoryOpSetMarketWasm, WasmHash: "abcd"}, ErrInvalidWasmHash},
+		{"collateral token", FactoryAdminRequest{Op: FactoryOpSetCollateral, CollateralToken: token}, nil},
+		{"account as token", FactoryAdminRequest{Op: FactoryOpSetCollateral, CollateralToken: "GAAZI4TCR3TY5OJHCTJC2A4QSY6CJWJH5IAJTGKIN2ER7LBNVKOCCWN7"}, ErrInvalidCollateralToken},
+		{"unknown op", FactoryAdminRequest{Op: "transfer_admin"}, ErrInvalidFactoryAdminOp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tt.req.Op == FactoryOpSetMarketWasm && tt.req.WasmHash != strings.Repeat("ab", 32) {
+				t.Errorf("WasmHash = %q, want trimmed lowercase hex", tt.req.WasmHash)
+			}
+		})
+	}
+}
+
+func TestDescribeBuild(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	if got, want := describeBuild(ContractVersion{WasmHash: hash, Version: "v1.1.0"}), "v1.1.0 (abababababab)"; got != want {
+		t.Errorf("describeBuild() = %q, want %q", got, want)
+	}
+	if got, want := describeBuild(ContractVersion{WasmHash: hash}), "abababababab"; got != want {
+		t.Errorf("describeBuild() unregistered = %q, want %q", got, want)
+	}
+}
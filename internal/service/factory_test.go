@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+func TestGetMarketStatesReportsFailedMarkets(t *testing.T) {
+	rpc := &ledgerEntryRPC{seqs: make(map[string]uint32)}
+	srv := httptest.NewServer(rpc)
+	t.Cleanup(srv.Close)
+
+	sorobanClient := soroban.NewClient(srv.URL, srv.Client())
+	stellarClient := stellar.NewCachingClient(stellartest.NewServer(t).Client(t))
+	txBuilder := stellar.NewBuilder(stellarClient, stellartest.Passphrase, 100, 0, sorobanClient)
+	fs := NewFactoryService(sorobanClient, stellarClient, txBuilder, "factory", keypair.MustRandom().Address(), nil, slog.New(slog.DiscardHandler))
+
+	var ids []string
+	for i := range 3 {
+		id, err := strkey.Encode(strkey.VersionByteContract, append(make([]byte, 31), byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	fs.stateCache.Set(ids[0], MarketState{ContractID: ids[0]})
+	fs.FreezeMarketState(MarketState{ContractID: ids[2], Resolved: true})
+	ctx := context.Background()
+
+	loaded, err := fs.GetMarketStates(ctx, ids)
+	if err != nil {
+		t.Fatalf("GetMarketStates() error = %v, want nil while some states loaded", err)
+	}
+	if len(loaded.States) != 2 || loaded.States[0].ContractID != ids[0] || loaded.States[1].ContractID != ids[2] {
+		t.Errorf("States = %+v, want the cached and the frozen market in order", loaded.States)
+	}
+	if len(loaded.Failed) != 1 || loaded.Failed[0].ContractID != ids[1] || !strings.Contains(loaded.Failed[0].Reason, "simulation unavailable") {
+		t.Errorf("Failed = %+v, want the uncached market with the RPC error", loaded.Failed)
+	}
+
+	loaded, err = fs.GetMarketStates(ctx, ids[1:2])
+	if err == nil {
+		t.Error("GetMarketStates() should fail when no state loaded")
+	}
+	if len(loaded.Failed) != 1 {
+		t.Errorf("Failed = %+v, want the market reported along with the error", loaded.Failed)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/samber/hot"
+)
+
+const (
+	idempotencyTTL       = 10 * time.Minute
+	idempotencyCacheSize = 10000
+
+	// MaxIdempotencyKeyLength bounds client-supplied idempotency keys.
+	MaxIdempotencyKeyLength = 128
+)
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with different parameters")
+)
+
+// idempotentEntry is a built transaction remembered under an idempotency key.
+type idempotentEntry struct {
+	fingerprint string
+	result      *model.TransactionResult
+}
+
+// idempotentCall is a build in progress; concurrent requests with the same key wait for it.
+type idempotentCall struct {
+	done        chan struct{}
+	fingerprint string
+	result      *model.TransactionResult
+	err         error
+}
+
+// IdempotencyStore remembers built transactions by idempotency key, so a repeated
+// submission (e.g. a double-click) returns the same XDR instead of building a second
+// transaction with a different sequence number or salt. Failed builds are not remembered.
+type IdempotencyStore struct {
+	mu       sync.Mutex
+	cache    *hot.HotCache[string, idempotentEntry]
+	inflight map[string]*idempotentCall
+}
+
+// NewIdempotencyStore creates a new in-memory idempotency store.
+func NewIdempotencyStore() *IdempotencyStore {
+	return newIdempotencyStore(idempotencyTTL)
+}
+
+// newIdempotencyStore creates a store whose keys expire ttl after the build.
+func newIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		cache: hot.NewHotCache[string, idempotentEntry](hot.LRU, idempotencyCacheSize).
+			WithTTL(ttl).
+			Build(),
+		inflight: make(map[string]*idempotentCall),
+	}
+}
+
+// Do returns the result stored under key, or runs build and stores its result.
+// fingerprint identifies the request parameters: reusing a key for different
+// parameters returns ErrIdempotencyKeyReused. replayed is true when the result
+// was not built by this call.
+func (s *IdempotencyStore) Do(key, fingerprint string, build func() (*model.TransactionResult, error)) (result *model.TransactionResult, replayed bool, err error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, false, ErrInvalidIdempotencyKey
+	}
+
+	s.mu.Lock()
+	if entry, found, _ := s.cache.Get(key); found {
+		s.mu.Unlock()
+		if entry.fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		return entry.result, true, nil
+	}
+	if call, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		<-call.done
+		if call.fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		return call.result, true, call.err
+	}
+	call := &idempotentCall{done: make(chan struct{}), fingerprint: fingerprint}
+	s.inflight[key] = call
+	s.mu.Unlock()
+
+	call.result, call.err = build()
+
+	s.mu.Lock()
+	if call.err == nil {
+		s.cache.Set(key, idempotentEntry{fingerprint: fingerprint, result: call.result})
+	}
+	delete(s.inflight, key)
+	s.mu.Unlock()
+	close(call.done)
+
+	return call.result, false, call.err
+}
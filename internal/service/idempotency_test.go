@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	builds := 0
+	build := func() (*model.TransactionResult, error) {
+		builds++
+		return &model.TransactionResult{XDR: fmt.Sprintf("tx-%d", builds)}, nil
+	}
+	s := newIdempotencyStore(50 * time.Millisecond)
+
+	first, replayed, err := s.Do("buy k1", "fp-a", build)
+	if err != nil || replayed || first.XDR != "tx-1" {
+		t.Fatalf("Do() = %v, %v, %v; want tx-1 built", first, replayed, err)
+	}
+	again, replayed, err := s.Do("buy k1", "fp-a", build)
+	if err != nil || !replayed || again.XDR != first.XDR {
+		t.Fatalf("repeated Do() = %v, %v, %v; want the same XDR replayed", again, replayed, err)
+	}
+	if _, _, err := s.Do("buy k1", "fp-b", build); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Do() with other parameters error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if builds != 1 {
+		t.Fatalf("builds = %d, want 1", builds)
+	}
+
+	// Failed builds are not remembered, so the client can retry with the same key.
+	if _, _, err := s.Do("buy k2", "fp-a", func() (*model.TransactionResult, error) { return nil, errors.New("rpc down") }); err == nil {
+		t.Fatal("Do() with a failing build: expected error")
+	}
+	if result, replayed, err := s.Do("buy k2", "fp-a", build); err != nil || replayed || result.XDR != "tx-2" {
+		t.Fatalf("retry after failure = %v, %v, %v; want a fresh build", result, replayed, err)
+	}
+
+	for _, key := range []string{"", strings.Repeat("k", MaxIdempotencyKeyLength+1)} {
+		if _, _, err := s.Do(key, "fp-a", build); !errors.Is(err, ErrInvalidIdempotencyKey) {
+			t.Errorf("Do(%d byte key) error = %v, want ErrInvalidIdempotencyKey", len(key), err)
+		}
+	}
+
+	// After the TTL the key builds a new transaction, even for other parameters.
+	time.Sleep(100 * time.Millisecond)
+	expired, replayed, err := s.Do("buy k1", "fp-b", build)
+	if err != nil || replayed || expired.XDR == first.XDR {
+		t.Errorf("Do() after expiry = %v, %v, %v; want a new build", expired, replayed, err)
+	}
+}
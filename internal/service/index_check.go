@@ -0,0 +1,107 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// divergenceQuietPeriod is how long a market must go without trades before its
+	// counted trades are compared with its state: the state and event caches then agree.
+	divergenceQuietPeriod = 10 * time.Minute
+	// divergenceRetention is how long a flagged divergence stays on the dashboard.
+	divergenceRetention = 7 * 24 * time.Hour
+)
+
+// marketNet is the net amount of tokens bought in a market, scaled by
+// soroban.ScaleFactor like the contract's yes_sold and no_sold.
+type marketNet struct {
+	Yes int64 `json:"yes"`
+	No  int64 `json:"no"`
+}
+
+func (n *marketNet) add(evt TradeEvent) {
+	amount := int64(math.Round(evt.Amount * float64(soroban.ScaleFactor)))
+	if evt.Kind == TradeKindSell {
+		amount = -amount
+	}
+	if evt.Outcome == "YES" {
+		n.Yes += amount
+	} else {
+		n.No += amount
+	}
+}
+
+// IndexDivergence is a market whose counted trades do not add up to its state: the
+// contract's yes_sold and no_sold are the net tokens bought through buy and sell.
+type IndexDivergence struct {
+	ContractID string    `json:"contract_id"`
+	YesCounted float64   `json:"yes_counted"` // tokens, including trades from before counting began
+	YesState   float64   `json:"yes_state"`
+	NoCounted  float64   `json:"no_counted"`
+	NoState    float64   `json:"no_state"`
+	At         time.Time `json:"at"`
+}
+
+// checkDivergence compares the counted net tokens of a market without recent
+// trades against its state. Trades from before counting began are not known, so the
+// first check records the difference as a baseline; a later mismatch means trades
+// were dropped or counted twice. It is flagged once and the baseline moves to the
+// state, so the next check only looks for new drift.
+func (s *StatsService) checkDivergence(state MarketState, lastTrade, now time.Time) {
+	if now.Sub(lastTrade) < divergenceQuietPeriod {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	net := s.file.Net[state.ContractID]
+	if net == nil {
+		net = &marketNet{}
+	}
+	live := marketNet{Yes: state.YesSold, No: state.NoSold}
+	baseline, ok := s.file.Baselines[state.ContractID]
+	if !ok {
+		s.file.Baselines[state.ContractID] = marketNet{Yes: live.Yes - net.Yes, No: live.No - net.No}
+		return
+	}
+	counted := marketNet{Yes: baseline.Yes + net.Yes, No: baseline.No + net.No}
+	if counted == live {
+		return
+	}
+
+	scale := float64(soroban.ScaleFactor)
+	d := IndexDivergence{
+		ContractID: state.ContractID,
+		YesCounted: float64(counted.Yes) / scale,
+		YesState:   float64(live.Yes) / scale,
+		NoCounted:  float64(counted.No) / scale,
+		NoState:    float64(live.No) / scale,
+		At:         now,
+	}
+	s.logger.Warn("counted trades diverge from market state",
+		"contract_id", d.ContractID,
+		"yes_counted", d.YesCounted, "yes_state", d.YesState,
+		"no_counted", d.NoCounted, "no_state", d.NoState)
+	s.divergences = append(s.divergences, d)
+	s.file.Baselines[state.ContractID] = marketNet{Yes: live.Yes - net.Yes, No: live.No - net.No}
+}
+
+// Divergences returns the markets whose counted trades diverged from their state in
+// the last week, newest first.
+func (s *StatsService) Divergences() []IndexDivergence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	divergences := slices.Clone(s.divergences)
+	slices.Reverse(divergences)
+	return divergences
+}
+
+// pruneDivergencesLocked drops divergences past divergenceRetention.
+func (s *StatsService) pruneDivergencesLocked(now time.Time) {
+	s.divergences = slices.DeleteFunc(s.divergences, func(d IndexDivergence) bool {
+		return now.Sub(d.At) > divergenceRetention
+	})
+}
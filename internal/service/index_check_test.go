@@ -0,0 +1,56 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestStatsServiceCheckDivergence(t *testing.T) {
+	s := &StatsService{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	// 5 YES were bought before counting began; counting sees 3 more and a sale of 1.
+	s.count(testContractID, []TradeEvent{
+		{Kind: TradeKindBuy, User: "GA", Outcome: "YES", Amount: 3, Ledger: 100, Timestamp: now.Add(-time.Hour)},
+		{Kind: TradeKindSell, User: "GA", Outcome: "YES", Amount: 1, Ledger: 101, Timestamp: now.Add(-time.Hour)},
+	}, now)
+	state := MarketState{ContractID: testContractID, YesSold: 7 * 10_000_000, NoSold: 2 * 10_000_000}
+
+	// A market still trading is not checked.
+	s.checkDivergence(state, now.Add(-time.Minute), now)
+	if _, ok := s.file.Baselines[testContractID]; ok {
+		t.Fatal("checkDivergence() recorded a baseline for a market with recent trades")
+	}
+
+	s.checkDivergence(state, now.Add(-time.Hour), now)
+	if got := s.file.Baselines[testContractID]; got != (marketNet{Yes: 5 * 10_000_000, No: 2 * 10_000_000}) {
+		t.Errorf("baseline = %+v, want 5 YES and 2 NO", got)
+	}
+
+	// A new trade counted and in the state agrees.
+	s.count(testContractID, []TradeEvent{{Kind: TradeKindBuy, User: "GB", Outcome: "NO", Amount: 4, Ledger: 102, Timestamp: now}}, now)
+	state.NoSold += 4 * 10_000_000
+	s.checkDivergence(state, now, now.Add(time.Hour))
+	if d := s.Divergences(); len(d) != 0 {
+		t.Errorf("Divergences() = %+v, want none", d)
+	}
+
+	// A trade the counting missed is flagged once.
+	state.YesSold += 10_000_000
+	s.checkDivergence(state, now, now.Add(time.Hour))
+	s.checkDivergence(state, now, now.Add(2*time.Hour))
+	d := s.Divergences()
+	if len(d) != 1 || d[0].YesCounted != 7 || d[0].YesState != 8 || d[0].NoCounted != d[0].NoState {
+		t.Fatalf("Divergences() = %+v, want one with 7 YES counted and 8 in state", d)
+	}
+
+	s.mu.Lock()
+	s.pruneDivergencesLocked(now.Add(divergenceRetention + 2*time.Hour))
+	s.mu.Unlock()
+	if d := s.Divergences(); len(d) != 0 {
+		t.Errorf("Divergences() after a week = %+v, want none", d)
+	}
+}
@@ -0,0 +1,294 @@
+//go:build integration
+
+// Integration tests against a local Stellar quickstart, e.g.
+//
+//	docker run --rm -p 8000:8000 stellar/quickstart --local --enable rpc
+//	make test-integration
+//
+// INTEGRATION_RPC_URL selects the RPC (make defaults it to the quickstart above);
+// INTEGRATION_HORIZON_URL, INTEGRATION_NETWORK_PASSPHRASE, INTEGRATION_MARKET_WASM and
+// INTEGRATION_FACTORY_WASM override the quickstart defaults.
+//
+// They deploy the contracts from contracts/target and drive markets through the
+// services exactly as the handlers do, catching XDR and contract ABI mismatches.
+
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+const (
+	integrationPassphrase = "Standalone Network ; February 2017"
+	integrationTimeout    = 5 * time.Minute
+)
+
+// integrationEnv is a freshly bootstrapped factory with a funded oracle.
+type integrationEnv struct {
+	network      config.NetworkConfig
+	oracle       *keypair.Full
+	signer       *OracleSigner
+	bootstrapper *Bootstrapper
+	factory      *FactoryService
+	markets      *MarketService
+	friendbotURL string
+}
+
+func getIntegrationEnv(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// newIntegrationEnv bootstraps the contracts with a new oracle account. The test is
+// skipped unless INTEGRATION_RPC_URL is set.
+func newIntegrationEnv(t *testing.T, ctx context.Context) *integrationEnv {
+	t.Helper()
+	rpcURL := os.Getenv("INTEGRATION_RPC_URL")
+	if rpcURL == "" {
+		t.Skip("INTEGRATION_RPC_URL not set")
+	}
+	network := config.NetworkConfig{
+		SorobanRPCURL:     rpcURL,
+		HorizonURL:        getIntegrationEnv("INTEGRATION_HORIZON_URL", strings.TrimSuffix(strings.TrimSuffix(rpcURL, "/"), "/rpc")),
+		NetworkPassphrase: getIntegrationEnv("INTEGRATION_NETWORK_PASSPHRASE", integrationPassphrase),
+	}
+	wasmDir := "../../contracts/target/wasm32-unknown-unknown/release/"
+	marketWasm, err := os.ReadFile(getIntegrationEnv("INTEGRATION_MARKET_WASM", wasmDir+"lmsr_market.wasm"))
+	if err != nil {
+		t.Fatalf("failed to read market WASM (build the contracts first): %v", err)
+	}
+	factoryWasm, err := os.ReadFile(getIntegrationEnv("INTEGRATION_FACTORY_WASM", wasmDir+"market_factory.wasm"))
+	if err != nil {
+		t.Fatalf("failed to read factory WASM (build the contracts first): %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sorobanClient := soroban.NewClient(network.SorobanRPCURL, &http.Client{Timeout: 30 * time.Second})
+	info, err := sorobanClient.GetNetwork(ctx)
+	if err != nil {
+		t.Fatalf("GetNetwork() error = %v", err)
+	}
+	if info.Passphrase != network.NetworkPassphrase {
+		t.Fatalf("RPC serves %q, want %q", info.Passphrase, network.NetworkPassphrase)
+	}
+	stellarClient, err := stellar.NewHorizonClient(network.HorizonURL, network.NetworkPassphrase, &http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("NewHorizonClient() error = %v", err)
+	}
+	txBuilder := stellar.NewBuilder(stellarClient, network.NetworkPassphrase, config.DefaultBaseFee, time.Minute, sorobanClient)
+
+	env := &integrationEnv{network: network, oracle: keypair.MustRandom(), friendbotURL: info.FriendbotURL}
+	env.fund(t, ctx, env.oracle.Address())
+	env.signer, err = NewOracleSigner(env.oracle.Seed(), env.oracle.Address(), network.NetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewOracleSigner() error = %v", err)
+	}
+	collateral, err := ParseCollateral("native", network.NetworkPassphrase)
+	if err != nil {
+		t.Fatalf("ParseCollateral() error = %v", err)
+	}
+
+	env.bootstrapper = NewBootstrapper(sorobanClient, txBuilder, env.signer, logger)
+	result, err := env.bootstrapper.Run(ctx, BootstrapRequest{MarketWasm: marketWasm, FactoryWasm: factoryWasm, Collateral: collateral}, nil)
+	if err != nil {
+		t.Fatalf("bootstrap error = %v", err)
+	}
+
+	quoteSigner, err := NewQuoteSigner("")
+	if err != nil {
+		t.Fatalf("NewQuoteSigner() error = %v", err)
+	}
+	env.factory = NewFactoryService(sorobanClient, stellarClient, txBuilder, result.FactoryContract, env.oracle.Address(), nil, logger)
+	env.markets = NewMarketService(stellarClient, sorobanClient, txBuilder, quoteSigner, TradeLimits{}, env.oracle.Address(), logger)
+	return env
+}
+
+// fund creates an account with friendbot.
+func (e *integrationEnv) fund(t *testing.T, ctx context.Context, address string) {
+	t.Helper()
+	if e.friendbotURL == "" {
+		t.Fatal("the RPC server has no friendbot")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.friendbotURL+"?addr="+url.QueryEscape(address), nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("friendbot error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("friendbot returned %s: %s", resp.Status, body)
+	}
+}
+
+// submit waits for a signed transaction to land and fails the test unless it succeeded.
+func (e *integrationEnv) submit(t *testing.T, ctx context.Context, step, signedXDR, hash string) *soroban.TransactionOutcome {
+	t.Helper()
+	outcome, err := e.bootstrapper.await(ctx, signedXDR, hash)
+	if err != nil {
+		t.Fatalf("%s: %v", step, err)
+	}
+	if !outcome.Successful {
+		t.Fatalf("%s: transaction %s failed: %s %v", step, hash, joinCodes(outcome.ResultCode, outcome.OperationCode), outcome.Diagnostics)
+	}
+	return outcome
+}
+
+// signAs signs a built transaction like a wallet would.
+func (e *integrationEnv) signAs(t *testing.T, kp *keypair.Full, txXDR string) (signedXDR, hash string) {
+	t.Helper()
+	generic, err := txnbuild.TransactionFromXDR(txXDR)
+	if err != nil {
+		t.Fatalf("TransactionFromXDR() error = %v", err)
+	}
+	tx, ok := generic.Transaction()
+	if !ok {
+		t.Fatal("built transaction is a fee bump")
+	}
+	if tx, err = tx.Sign(e.network.NetworkPassphrase, kp); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if signedXDR, err = tx.Base64(); err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	h, err := tx.Hash(e.network.NetworkPassphrase)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	return signedXDR, hex.EncodeToString(h[:])
+}
+
+func TestIntegrationMarketLifecycle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), integrationTimeout)
+	defer cancel()
+	env := newIntegrationEnv(t, ctx)
+	user := keypair.MustRandom()
+	env.fund(t, ctx, user.Address())
+
+	// Deploy
+	deployTx, err := env.factory.BuildDeployMarketTx(ctx, DeployMarketRequest{
+		LiquidityParam: 100,
+		MetadataHash:   "QmIntegrationTestMetadata",
+		InitialFunding: 100,
+	})
+	if err != nil {
+		t.Fatalf("BuildDeployMarketTx() error = %v", err)
+	}
+	signed, hash, err := env.signer.Sign(ctx, deployTx.XDR, env.factory)
+	if err != nil {
+		t.Fatalf("oracle Sign(deploy_market) error = %v", err)
+	}
+	outcome := env.submit(t, ctx, "deploy market", signed, hash)
+	if outcome.ReturnValue == nil {
+		t.Fatal("deploy_market returned no value")
+	}
+	marketID, err := soroban.DecodeAddress(*outcome.ReturnValue)
+	if err != nil {
+		t.Fatalf("DecodeAddress(deploy_market result) error = %v", err)
+	}
+	ids, err := env.factory.fetchMarketList(ctx)
+	if err != nil || len(ids) != 1 || ids[0] != marketID {
+		t.Fatalf("factory markets = %v, %v; want [%s]", ids, err, marketID)
+	}
+
+	// Buy
+	quote, err := env.markets.GetQuote(ctx, marketID, model.OutcomeYes, 10)
+	if err != nil {
+		t.Fatalf("GetQuote() error = %v", err)
+	}
+	if quote.Cost <= 0 || quote.PriceAfter <= 0.5 || quote.PriceAfter >= 1 {
+		t.Errorf("GetQuote() = %+v, want a positive cost and a YES price above 0.5", quote)
+	}
+	buyTx, err := env.markets.BuildBuyTx(ctx, BuyRequest{
+		TradeRequest: TradeRequest{
+			UserPublicKey: user.Address(),
+			ContractID:    marketID,
+			Outcome:       model.OutcomeYes,
+			ShareAmount:   10,
+			Slippage:      0.05,
+		},
+		QuoteToken: quote.Token,
+	})
+	if err != nil {
+		t.Fatalf("BuildBuyTx() error = %v", err)
+	}
+	signed, hash = env.signAs(t, user, buyTx.XDR)
+	env.submit(t, ctx, "buy", signed, hash)
+
+	balance, err := env.markets.GetBalance(ctx, marketID, user.Address())
+	if err != nil {
+		t.Fatalf("GetBalance() error = %v", err)
+	}
+	if balance.YesBalance != 10 || balance.NoBalance != 0 {
+		t.Errorf("GetBalance() = %+v, want 10 YES", balance)
+	}
+	state, err := env.factory.fetchMarketState(ctx, marketID)
+	if err != nil {
+		t.Fatalf("fetchMarketState() error = %v", err)
+	}
+	if state.YesSold != 10*soroban.ScaleFactor || state.Resolved {
+		t.Errorf("market state = %+v, want 10 YES sold and unresolved", state)
+	}
+
+	// Resolve
+	resolveTx, err := env.markets.BuildResolveTx(ctx, ResolveRequest{
+		OraclePublicKey: env.oracle.Address(),
+		ContractID:      marketID,
+		WinningOutcome:  model.OutcomeYes,
+	})
+	if err != nil {
+		t.Fatalf("BuildResolveTx() error = %v", err)
+	}
+	signed, hash, err = env.signer.Sign(ctx, resolveTx.XDR, env.factory)
+	if err != nil {
+		t.Fatalf("oracle Sign(resolve) error = %v", err)
+	}
+	env.submit(t, ctx, "resolve", signed, hash)
+
+	state, err = env.factory.fetchMarketState(ctx, marketID)
+	if err != nil {
+		t.Fatalf("fetchMarketState() error = %v", err)
+	}
+	if !state.Resolved || state.WinningOutcome != string(model.OutcomeYes) {
+		t.Errorf("market state = %+v, want resolved with YES", state)
+	}
+
+	// Claim
+	claimTx, err := env.markets.BuildClaimTx(ctx, ClaimRequest{UserPublicKey: user.Address(), ContractID: marketID})
+	if err != nil {
+		t.Fatalf("BuildClaimTx() error = %v", err)
+	}
+	signed, hash = env.signAs(t, user, claimTx.XDR)
+	outcome = env.submit(t, ctx, "claim", signed, hash)
+	if outcome.ReturnValue == nil {
+		t.Fatal("claim returned no value")
+	}
+	payout, err := soroban.DecodeI128(*outcome.ReturnValue)
+	if err != nil {
+		t.Fatalf("DecodeI128(claim result) error = %v", err)
+	}
+	if payout != 10*soroban.ScaleFactor {
+		t.Errorf("claim payout = %d, want %d (1 per winning token)", payout, 10*soroban.ScaleFactor)
+	}
+}
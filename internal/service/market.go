@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"strings"
+	"time"
 
 	"github.com/mtlprog/total/internal/model"
 	"github.com/mtlprog/total/internal/soroban"
@@ -18,10 +20,12 @@ var (
 )
 
 var (
-	ErrMarketNotFound   = errors.New("market not found")
-	ErrMarketResolved   = errors.New("market already resolved")
-	ErrInvalidOutcome   = errors.New("invalid outcome")
-	ErrInsufficientCost = errors.New("insufficient cost provided")
+	ErrMarketNotFound     = errors.New("market not found")
+	ErrMarketResolved     = errors.New("market already resolved")
+	ErrInvalidOutcome     = errors.New("invalid outcome")
+	ErrInsufficientCost   = errors.New("insufficient cost provided")
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	ErrOracleRefresh      = errors.New("sign in as the oracle to rebuild oracle transactions")
 )
 
 // safeFloatToInt64 converts a float64 to int64 with overflow checking.
@@ -41,7 +45,10 @@ type MarketService struct {
 	stellarClient   stellar.Client
 	sorobanClient   *soroban.Client
 	txBuilder       *stellar.Builder
+	quoteSigner     *QuoteSigner
+	limits          TradeLimits
 	oraclePublicKey string
+	broadcaster     *txBroadcaster
 	logger          *slog.Logger
 }
 
@@ -50,18 +57,43 @@ func NewMarketService(
 	stellarClient stellar.Client,
 	sorobanClient *soroban.Client,
 	txBuilder *stellar.Builder,
+	quoteSigner *QuoteSigner,
+	limits TradeLimits,
 	oraclePublicKey string,
 	logger *slog.Logger,
 ) *MarketService {
+	if quoteSigner == nil {
+		panic("NewMarketService: quoteSigner must not be nil")
+	}
 	return &MarketService{
 		stellarClient:   stellarClient,
 		sorobanClient:   sorobanClient,
 		txBuilder:       txBuilder,
+		quoteSigner:     quoteSigner,
+		limits:          limits,
 		oraclePublicKey: oraclePublicKey,
+		broadcaster:     newTxBroadcaster(sorobanClient, logger),
 		logger:          logger,
 	}
 }
 
+// InvalidateAccount forgets the cached sequence number of an account once one of its
+// transactions is seen on the network.
+func (s *MarketService) InvalidateAccount(publicKey string) {
+	s.txBuilder.InvalidateAccount(publicKey)
+}
+
+// ReleaseSequences drops the sequence numbers reserved for an account's built but not
+// submitted transactions, before they are rebuilt in order.
+func (s *MarketService) ReleaseSequences(publicKey string) {
+	s.txBuilder.ReleaseSequences(publicKey)
+}
+
+// RPCEndpoints returns the Soroban RPC endpoints and which one calls go to.
+func (s *MarketService) RPCEndpoints() []soroban.EndpointStatus {
+	return s.sorobanClient.Endpoints()
+}
+
 // TradeRequest contains common fields for buy/sell operations.
 type TradeRequest struct {
 	UserPublicKey string
@@ -69,6 +101,7 @@ type TradeRequest struct {
 	Outcome       model.Outcome
 	ShareAmount   float64
 	Slippage      float64
+	Referral      string // Optional referral code, recorded in the transaction memo
 }
 
 // Validate validates the trade request fields.
@@ -88,12 +121,30 @@ func (r *TradeRequest) Validate() error {
 	if r.Slippage <= 0 || r.Slippage > model.MaxSlippage {
 		return model.ErrInvalidSlippage
 	}
+	if r.Referral != "" {
+		if err := ValidateReferralCode(r.Referral); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // BuyRequest contains data for buying outcome tokens.
+// QuoteToken comes from GetQuote and pins the cost the user confirmed.
 type BuyRequest struct {
 	TradeRequest
+	QuoteToken string
+}
+
+// Validate validates the buy request.
+func (r *BuyRequest) Validate() error {
+	if err := r.TradeRequest.Validate(); err != nil {
+		return err
+	}
+	if r.QuoteToken == "" {
+		return ErrQuoteTokenRequired
+	}
+	return nil
 }
 
 // SellRequest contains data for selling tokens.
@@ -113,14 +164,25 @@ func (s *MarketService) BuildBuyTx(ctx context.Context, req BuyRequest) (*model.
 		return nil, fmt.Errorf("invalid share amount: %w", err)
 	}
 
-	quote, err := s.GetQuote(ctx, req.ContractID, req.Outcome, req.ShareAmount)
+	// Use the cost the user was quoted, not a fresh one: if the price moved since,
+	// the contract rejects the trade via max_cost instead of silently charging more.
+	quote, err := s.quoteSigner.verify(req.QuoteToken, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get quote: %w", err)
+		return nil, err
+	}
+	if quote.ContractID != req.ContractID || quote.Outcome != string(req.Outcome) || quote.Amount != amount {
+		return nil, fmt.Errorf("%w: quote was issued for a different trade", ErrQuoteTokenInvalid)
 	}
 
 	if quote.Cost <= 0 {
 		return nil, fmt.Errorf("invalid quote cost: %d (expected positive value)", quote.Cost)
 	}
+	if err := s.limits.checkTokens(req.ShareAmount); err != nil {
+		return nil, err
+	}
+	if err := s.limits.checkCost(float64(quote.Cost) / float64(soroban.ScaleFactor)); err != nil {
+		return nil, err
+	}
 
 	// Apply slippage with overflow check
 	maxCostFloat := float64(quote.Cost) * (1 + req.Slippage)
@@ -129,17 +191,39 @@ func (s *MarketService) BuildBuyTx(ctx context.Context, req BuyRequest) (*model.
 		return nil, fmt.Errorf("max cost calculation overflow: %w", err)
 	}
 
+	// If the price has fallen since the quote, bound the buy by the fresh price so the
+	// user never signs a max cost far above the market. A failed re-quote keeps the
+	// quoted bound, which the user confirmed.
+	if fresh, err := s.GetQuote(ctx, req.ContractID, req.Outcome, req.ShareAmount); err != nil {
+		s.logger.Warn("failed to re-quote buy, using the quoted max cost", "contract_id", req.ContractID, "error", err)
+	} else if improved, ok := improvedMaxCost(quote.Cost, fresh.Cost, req.Slippage); ok && improved < maxCost {
+		s.logger.Info("price improved since quote, tightening max cost",
+			"contract_id", req.ContractID, "quoted_cost", quote.Cost, "fresh_cost", fresh.Cost, "max_cost", improved)
+		maxCost = improved
+	}
+
 	outcomeU32, err := soroban.OutcomeToU32(string(req.Outcome))
 	if err != nil {
 		return nil, fmt.Errorf("invalid outcome: %w", err)
 	}
 
+	if s.limits.MaxPositionTokens > 0 {
+		held, err := s.getOutcomeBalance(ctx, req.ContractID, req.UserPublicKey, outcomeU32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check position: %w", err)
+		}
+		if err := s.limits.checkPosition(float64(held)/float64(soroban.ScaleFactor), req.ShareAmount); err != nil {
+			return nil, err
+		}
+	}
+
 	txXDR, err := s.txBuilder.BuildBuyTx(ctx, stellar.BuyTxParams{
 		UserPublicKey: req.UserPublicKey,
 		ContractID:    req.ContractID,
 		Outcome:       outcomeU32,
 		Amount:        amount,
 		MaxCost:       maxCost,
+		Memo:          referralMemo(req.Referral),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to build transaction: %w", err)
@@ -150,12 +234,30 @@ func (s *MarketService) BuildBuyTx(ctx context.Context, req BuyRequest) (*model.
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: fmt.Sprintf("Buy %.2f %s tokens", req.ShareAmount, req.Outcome),
 		SignWith:    req.UserPublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
+}
+
+// PriceImprovementThreshold is how much lower than the quoted cost a fresh quote must
+// be for a buy to be bound by the fresh price instead.
+const PriceImprovementThreshold = 0.005
+
+// improvedMaxCost returns the max cost of a buy quoted at quotedCost whose fresh cost
+// is freshCost: the fresh cost plus slippage. ok is false unless the fresh cost is
+// more than PriceImprovementThreshold below the quoted one.
+func improvedMaxCost(quotedCost, freshCost int64, slippage float64) (maxCost int64, ok bool) {
+	if freshCost <= 0 || float64(freshCost) >= float64(quotedCost)*(1-PriceImprovementThreshold) {
+		return 0, false
+	}
+	maxCost, err := safeFloatToInt64(float64(freshCost) * (1 + slippage))
+	if err != nil {
+		return 0, false
+	}
+	return maxCost, true
 }
 
 // BuildSellTx builds a transaction for selling tokens.
@@ -163,6 +265,9 @@ func (s *MarketService) BuildSellTx(ctx context.Context, req SellRequest) (*mode
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("sell request validation failed: %w", err)
 	}
+	if err := s.limits.checkTokens(req.ShareAmount); err != nil {
+		return nil, err
+	}
 
 	// Convert amount to scaled int64 with overflow check
 	amount, err := safeFloatToInt64(req.ShareAmount * float64(soroban.ScaleFactor))
@@ -197,6 +302,7 @@ func (s *MarketService) BuildSellTx(ctx context.Context, req SellRequest) (*mode
 		Outcome:       outcomeU32,
 		Amount:        amount,
 		MinReturn:     minReturn,
+		Memo:          referralMemo(req.Referral),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to build transaction: %w", err)
@@ -207,12 +313,12 @@ func (s *MarketService) BuildSellTx(ctx context.Context, req SellRequest) (*mode
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: fmt.Sprintf("Sell %.2f %s tokens", req.ShareAmount, req.Outcome),
 		SignWith:    req.UserPublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
 }
 
 // ResolveRequest contains data for resolving a market.
@@ -261,12 +367,12 @@ func (s *MarketService) BuildResolveTx(ctx context.Context, req ResolveRequest)
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: fmt.Sprintf("Resolve market: %s wins", req.WinningOutcome),
 		SignWith:    req.OraclePublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
 }
 
 // ClaimRequest contains data for claiming winnings.
@@ -305,12 +411,12 @@ func (s *MarketService) BuildClaimTx(ctx context.Context, req ClaimRequest) (*mo
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: "Claim winnings",
 		SignWith:    req.UserPublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
 }
 
 // WithdrawRequest contains data for oracle withdrawing remaining pool.
@@ -349,12 +455,73 @@ func (s *MarketService) BuildWithdrawTx(ctx context.Context, req WithdrawRequest
 		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
 	}
 
-	return &model.TransactionResult{
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
 		XDR:         preparedXDR,
 		Description: "Withdraw remaining pool",
 		SignWith:    req.OraclePublicKey,
 		SubmitURL:   s.sorobanClient.RPCURL(),
-	}, nil
+	}), nil
+}
+
+// RefreshRequest contains a previously built transaction to rebuild.
+type RefreshRequest struct {
+	XDR    string
+	Oracle bool // Caller is signed in as the oracle
+}
+
+// Validate validates the refresh request.
+func (r *RefreshRequest) Validate() error {
+	if strings.TrimSpace(r.XDR) == "" {
+		return ErrInvalidTransaction
+	}
+	return nil
+}
+
+// BuildRefreshTx rebuilds the contract call carried by a previously built transaction
+// with a fresh sequence number and time bounds. Arguments are kept as-is, so a trade
+// keeps its original max cost / min return as slippage protection. Transactions of
+// the oracle account restart its sequence reservations, so only an oracle session
+// may rebuild them.
+func (s *MarketService) BuildRefreshTx(ctx context.Context, req RefreshRequest) (*model.TransactionResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("refresh request validation failed: %w", err)
+	}
+
+	invoke, err := soroban.DecodeInvokeTx(strings.TrimSpace(req.XDR))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+
+	oracle := s.oraclePublicKey != "" && invoke.SourceAccount == s.oraclePublicKey
+	if oracle && !req.Oracle {
+		return nil, ErrOracleRefresh
+	}
+
+	txXDR, err := s.txBuilder.RebuildInvokeTx(ctx, invoke, oracle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+
+	preparedXDR, err := s.txBuilder.SimulateAndPrepareTx(ctx, txXDR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+
+	return withAuthExpiry(s.txBuilder, &model.TransactionResult{
+		XDR:         preparedXDR,
+		Description: fmt.Sprintf("Refreshed %s transaction", invoke.FunctionName),
+		SignWith:    invoke.SourceAccount,
+		SubmitURL:   s.sorobanClient.RPCURL(),
+	}), nil
+}
+
+// withAuthExpiry fills in when the auth entries of a built transaction expire.
+func withAuthExpiry(builder *stellar.Builder, result *model.TransactionResult) *model.TransactionResult {
+	ledger, at, err := builder.AuthExpiry(result.XDR)
+	if err == nil {
+		result.AuthExpiresLedger, result.AuthExpiresAt = ledger, at
+	}
+	return result
 }
 
 // UserBalance represents a user's YES and NO token balances in a market.
@@ -445,8 +612,10 @@ func (s *MarketService) getOutcomeBalance(ctx context.Context, contractID string
 
 // Quote represents a price quote for buying from the contract.
 type Quote struct {
-	Cost       int64   // Scaled by 10^7
-	PriceAfter float64 // 0-1
+	Cost       int64     // Scaled by 10^7
+	PriceAfter float64   // 0-1
+	Token      string    // Signed quote token, required by BuildBuyTx
+	ExpiresAt  time.Time // Token expiry
 }
 
 // SellQuote represents a price quote for selling from the contract.
@@ -457,6 +626,9 @@ type SellQuote struct {
 
 // GetQuote gets a price quote from a market contract.
 func (s *MarketService) GetQuote(ctx context.Context, contractID string, outcome model.Outcome, amount float64) (*Quote, error) {
+	if err := s.limits.checkTokens(amount); err != nil {
+		return nil, err
+	}
 	amountScaled, err := safeFloatToInt64(amount * float64(soroban.ScaleFactor))
 	if err != nil {
 		return nil, fmt.Errorf("invalid quote amount: %w", err)
@@ -518,9 +690,25 @@ func (s *MarketService) GetQuote(ctx context.Context, contractID string, outcome
 	// Convert price_after from scaled i128 to float64 (0-1)
 	priceAfter := float64(priceAfterScaled) / float64(soroban.ScaleFactor)
 
+	if err := s.limits.checkCost(float64(cost) / float64(soroban.ScaleFactor)); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.quoteSigner.sign(quoteClaims{
+		ContractID: contractID,
+		Outcome:    string(outcome),
+		Amount:     amountScaled,
+		Cost:       cost,
+	}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	return &Quote{
 		Cost:       cost,
 		PriceAfter: priceAfter,
+		Token:      token,
+		ExpiresAt:  expiresAt,
 	}, nil
 }
 
@@ -1,11 +1,23 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
 func TestSafeFloatToInt64(t *testing.T) {
@@ -46,6 +58,31 @@ func TestSafeFloatToInt64(t *testing.T) {
 	}
 }
 
+func TestImprovedMaxCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		quoted    int64
+		fresh     int64
+		wantCost  int64
+		wantTight bool
+	}{
+		{"unchanged", 10_000_000, 10_000_000, 0, false},
+		{"risen", 10_000_000, 11_000_000, 0, false},
+		{"within threshold", 10_000_000, 9_960_000, 0, false},
+		{"fallen", 10_000_000, 9_000_000, 9_090_000, true},
+		{"no fresh cost", 10_000_000, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := improvedMaxCost(tt.quoted, tt.fresh, 0.01)
+			if ok != tt.wantTight || got != tt.wantCost {
+				t.Errorf("improvedMaxCost(%d, %d) = %d, %v, want %d, %v", tt.quoted, tt.fresh, got, ok, tt.wantCost, tt.wantTight)
+			}
+		})
+	}
+}
+
 func TestTradeRequest_Validate(t *testing.T) {
 	validRequest := TradeRequest{
 		UserPublicKey: "GBXGQJWVLWOYHFLVTKWV5FGHA3LNYY2JQKM7OAJAUEQFU6LPCSEFVXON",
@@ -248,3 +285,75 @@ func TestWithdrawRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestMarketService_BuildClaimTxLoadsAccountFromHorizon(t *testing.T) {
+	horizonSrv := stellartest.NewServer(t)
+	var simulations atomic.Int32
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		simulations.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"simulation unavailable"}}`))
+	}))
+	t.Cleanup(rpc.Close)
+
+	sorobanClient := soroban.NewClient(rpc.URL, rpc.Client())
+	stellarClient := stellar.NewCachingClient(horizonSrv.Client(t))
+	txBuilder := stellar.NewBuilder(stellarClient, stellartest.Passphrase, 100, 0, sorobanClient)
+	quoteSigner, err := NewQuoteSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	markets := NewMarketService(stellarClient, sorobanClient, txBuilder, quoteSigner, TradeLimits{}, keypair.MustRandom().Address(), slog.New(slog.DiscardHandler))
+
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := keypair.MustRandom().Address()
+
+	_, err = markets.BuildClaimTx(context.Background(), ClaimRequest{UserPublicKey: user, ContractID: contractID})
+	if !errors.Is(err, stellar.ErrAccountNotFound) {
+		t.Fatalf("BuildClaimTx(unfunded) error = %v, want %v", err, stellar.ErrAccountNotFound)
+	}
+	if got := simulations.Load(); got != 0 {
+		t.Errorf("RPC calls for an unfunded account = %d, want 0", got)
+	}
+
+	horizonSrv.AddFundedAccount(user, 7, "10.0000000")
+	_, err = markets.BuildClaimTx(context.Background(), ClaimRequest{UserPublicKey: user, ContractID: contractID})
+	if err == nil || errors.Is(err, stellar.ErrAccountNotFound) || !strings.Contains(err.Error(), "failed to simulate") {
+		t.Fatalf("BuildClaimTx(funded) error = %v, want a simulation failure", err)
+	}
+	if got := simulations.Load(); got != 1 {
+		t.Errorf("RPC calls for a funded account = %d, want 1", got)
+	}
+	if got := horizonSrv.Requests("/accounts/" + user); got != 2 {
+		t.Errorf("Horizon requests = %d, want 2 (failed lookups are not cached)", got)
+	}
+}
+
+func TestMarketService_BuildRefreshTxOracleNeedsSession(t *testing.T) {
+	horizonSrv := stellartest.NewServer(t)
+	stellarClient := stellar.NewCachingClient(horizonSrv.Client(t))
+	txBuilder := stellar.NewBuilder(stellarClient, stellartest.Passphrase, 100, 0, nil)
+	quoteSigner, err := NewQuoteSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oracle := keypair.MustRandom().Address()
+	markets := NewMarketService(stellarClient, nil, txBuilder, quoteSigner, TradeLimits{}, oracle, slog.New(slog.DiscardHandler))
+	horizonSrv.AddFundedAccount(oracle, 7, "10.0000000")
+
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txXDR := buildContractTx(t, oracle, contractID, "resolve", []xdr.ScVal{soroban.EncodeU32(0)}, nil)
+
+	_, err = markets.BuildRefreshTx(context.Background(), RefreshRequest{XDR: txXDR})
+	if !errors.Is(err, ErrOracleRefresh) {
+		t.Fatalf("BuildRefreshTx() without oracle session error = %v, want %v", err, ErrOracleRefresh)
+	}
+	if got := horizonSrv.Requests("/accounts/" + oracle); got != 0 {
+		t.Errorf("oracle account loaded %d times, want 0", got)
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// keystoreKDF names the key derivation of oracle keystore files.
+	keystoreKDF = "pbkdf2-sha256"
+	// keystoreIterations is the PBKDF2 iteration count of newly sealed keystores.
+	keystoreIterations = 600_000
+)
+
+var (
+	ErrOracleSignerDisabled = errors.New("server-side oracle signing is not configured")
+	ErrOracleSignerMismatch = errors.New("oracle signing key does not match ORACLE_PUBLIC_KEY")
+	ErrKeystorePassphrase   = errors.New("wrong keystore passphrase or corrupted keystore")
+	ErrOracleTxNotAllowed   = errors.New("transaction is not an oracle action this server signs")
+)
+
+// OracleSignedFunctions are the contract functions the server signs with the oracle
+// key: resolving a market, withdrawing its remaining pool and deploying a market.
+var OracleSignedFunctions = map[string]bool{
+	"resolve":            true,
+	"withdraw_remaining": true,
+	"deploy_market":      true,
+}
+
+// OracleContracts are the contracts the oracle key signs calls to: the factory for
+// deploys and its markets for resolving and withdrawing. FactoryService implements it.
+type OracleContracts interface {
+	FactoryContractID() string
+	ListMarkets(ctx context.Context) ([]string, error)
+}
+
+// oracleKeystore is the JSON file holding an oracle secret key encrypted with a
+// passphrase: AES-256-GCM under a PBKDF2-SHA256 key. The public key is kept in the
+// clear so a wrong file is reported before the passphrase is tried.
+type oracleKeystore struct {
+	PublicKey  string `json:"public_key"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// OracleSigner signs oracle transactions with the operator's own key, so the oracle
+// can resolve, withdraw and deploy from the admin UI in one click. It exists only when
+// a secret key or keystore is configured; without it every oracle transaction is
+// signed externally like any other.
+type OracleSigner struct {
+	key               *keypair.Full
+	networkPassphrase string
+}
+
+// NewOracleSigner creates a signer from a secret seed (S...), which must belong to
+// oraclePublicKey.
+func NewOracleSigner(secretSeed, oraclePublicKey, networkPassphrase string) (*OracleSigner, error) {
+	key, err := keypair.ParseFull(secretSeed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oracle secret key: %w", err)
+	}
+	if key.Address() != oraclePublicKey {
+		return nil, fmt.Errorf("%w: key is %s", ErrOracleSignerMismatch, key.Address())
+	}
+	return &OracleSigner{key: key, networkPassphrase: networkPassphrase}, nil
+}
+
+// LoadOracleSigner creates a signer from a keystore file sealed by SealOracleKeystore.
+func LoadOracleSigner(path, passphrase, oraclePublicKey, networkPassphrase string) (*OracleSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oracle keystore: %w", err)
+	}
+	var ks oracleKeystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to parse oracle keystore: %w", err)
+	}
+	if ks.PublicKey != oraclePublicKey {
+		return nil, fmt.Errorf("%w: keystore is for %s", ErrOracleSignerMismatch, ks.PublicKey)
+	}
+	seed, err := openKeystore(ks, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewOracleSigner(seed, oraclePublicKey, networkPassphrase)
+}
+
+// SealOracleKeystore encrypts a secret seed with passphrase into keystore JSON for
+// ORACLE_KEYSTORE_FILE.
+func SealOracleKeystore(secretSeed, passphrase string) ([]byte, error) {
+	key, err := keypair.ParseFull(secretSeed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oracle secret key: %w", err)
+	}
+	if passphrase == "" {
+		return nil, errors.New("keystore passphrase must not be empty")
+	}
+
+	ks := oracleKeystore{
+		PublicKey:  key.Address(),
+		KDF:        keystoreKDF,
+		Iterations: keystoreIterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(ks.Salt); err != nil {
+		return nil, err
+	}
+	gcm, err := keystoreCipher(ks, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ks.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(ks.Nonce); err != nil {
+		return nil, err
+	}
+	ks.Ciphertext = gcm.Seal(nil, ks.Nonce, []byte(key.Seed()), []byte(ks.PublicKey))
+	return json.MarshalIndent(ks, "", "  ")
+}
+
+func openKeystore(ks oracleKeystore, passphrase string) (string, error) {
+	if ks.KDF != keystoreKDF {
+		return "", fmt.Errorf("unsupported keystore kdf %q", ks.KDF)
+	}
+	gcm, err := keystoreCipher(ks, passphrase)
+	if err != nil {
+		return "", err
+	}
+	if len(ks.Nonce) != gcm.NonceSize() {
+		return "", ErrKeystorePassphrase
+	}
+	seed, err := gcm.Open(nil, ks.Nonce, ks.Ciphertext, []byte(ks.PublicKey))
+	if err != nil {
+		return "", ErrKeystorePassphrase
+	}
+	return string(seed), nil
+}
+
+func keystoreCipher(ks oracleKeystore, passphrase string) (cipher.AEAD, error) {
+	if ks.Iterations <= 0 || len(ks.Salt) == 0 {
+		return nil, errors.New("keystore is missing kdf parameters")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, ks.Salt, ks.Iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keystore key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Address returns the oracle account the signer signs for.
+func (s *OracleSigner) Address() string {
+	return s.key.Address()
+}
+
+// Sign signs an oracle transaction built by this app and returns the signed XDR and
+// its hash. Only a single invocation of one of OracleSignedFunctions with the oracle
+// as source is signed, so the key cannot be used for anything else from the UI: the
+// invoked contract must be the factory (deploy_market) or one of its markets, and the
+// auth entries the signature covers may only authorize that call. Callers must also
+// check that this server built the transaction.
+func (s *OracleSigner) Sign(ctx context.Context, txXDR string, contracts OracleContracts) (signedXDR, hash string, err error) {
+	if s == nil {
+		return "", "", ErrOracleSignerDisabled
+	}
+	invoke, err := soroban.DecodeInvokeTx(txXDR)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+	if invoke.SourceAccount != s.key.Address() || !OracleSignedFunctions[invoke.FunctionName] {
+		return "", "", fmt.Errorf("%w: %s from %s", ErrOracleTxNotAllowed, invoke.FunctionName, invoke.SourceAccount)
+	}
+	if err := checkOracleContract(ctx, contracts, invoke); err != nil {
+		return "", "", err
+	}
+
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &envelope); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+	if err := s.checkOracleAuth(envelope.V1.Tx.Operations[0].Body.InvokeHostFunctionOp.Auth, invoke); err != nil {
+		return "", "", err
+	}
+	return s.signEnvelope(envelope)
+}
+
+// checkOracleContract checks that an oracle call goes to the factory or one of its markets.
+func checkOracleContract(ctx context.Context, contracts OracleContracts, invoke *soroban.DecodedInvoke) error {
+	if contracts == nil {
+		return fmt.Errorf("%w: no known contracts", ErrOracleTxNotAllowed)
+	}
+	if invoke.FunctionName == "deploy_market" {
+		if factory := contracts.FactoryContractID(); factory == "" || invoke.ContractID != factory {
+			return fmt.Errorf("%w: deploy_market on %s, not the factory", ErrOracleTxNotAllowed, invoke.ContractID)
+		}
+		return nil
+	}
+	markets, err := contracts.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	if !slices.Contains(markets, invoke.ContractID) {
+		return fmt.Errorf("%w: %s on %s, not a market of the factory", ErrOracleTxNotAllowed, invoke.FunctionName, invoke.ContractID)
+	}
+	return nil
+}
+
+// checkOracleAuth checks the auth entries of an oracle call. Entries with source
+// account credentials are authorized by the transaction signature, so each must be
+// the call itself. Only deploy_market has sub-invocations: creating the market, whose
+// constructor transfers the initial funding from the oracle.
+func (s *OracleSigner) checkOracleAuth(entries []xdr.SorobanAuthorizationEntry, invoke *soroban.DecodedInvoke) error {
+	for _, entry := range entries {
+		if entry.Credentials.Type != xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount {
+			// Address credentials need their own signature; the oracle never signs them here.
+			continue
+		}
+		root := entry.RootInvocation
+		fn := root.Function.ContractFn
+		if root.Function.Type != xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn || fn == nil ||
+			string(fn.FunctionName) != invoke.FunctionName || !sameContract(fn.ContractAddress, invoke.ContractID) {
+			return fmt.Errorf("%w: auth entry for another call", ErrOracleTxNotAllowed)
+		}
+		if invoke.FunctionName != "deploy_market" {
+			if len(root.SubInvocations) > 0 {
+				return fmt.Errorf("%w: unexpected sub-invocations in auth entry", ErrOracleTxNotAllowed)
+			}
+			continue
+		}
+		if len(invoke.Args) < 4 {
+			return fmt.Errorf("%w: deploy_market without initial funding", ErrOracleTxNotAllowed)
+		}
+		funding, err := soroban.DecodeI128(invoke.Args[3])
+		if err != nil {
+			return fmt.Errorf("%w: invalid initial funding: %v", ErrOracleTxNotAllowed, err)
+		}
+		if err := s.checkDeployAuth(root.SubInvocations, funding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDeployAuth allows creating contracts and transferring the initial funding
+// from the oracle, nothing else.
+func (s *OracleSigner) checkDeployAuth(invocations []xdr.SorobanAuthorizedInvocation, funding int64) error {
+	for _, inv := range invocations {
+		switch inv.Function.Type {
+		case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractHostFn,
+			xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn:
+		case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn:
+			fn := inv.Function.ContractFn
+			if fn == nil || fn.FunctionName != "transfer" || len(fn.Args) != 3 {
+				return fmt.Errorf("%w: auth entry authorizes a call other than the funding transfer", ErrOracleTxNotAllowed)
+			}
+			from, err := soroban.DecodeAddress(fn.Args[0])
+			if err != nil || from != s.key.Address() {
+				return fmt.Errorf("%w: transfer from another account", ErrOracleTxNotAllowed)
+			}
+			if amount, err := soroban.DecodeI128(fn.Args[2]); err != nil || amount != funding {
+				return fmt.Errorf("%w: transfer of more than the initial funding", ErrOracleTxNotAllowed)
+			}
+		default:
+			return fmt.Errorf("%w: auth entry authorizes %s", ErrOracleTxNotAllowed, inv.Function.Type)
+		}
+		if err := s.checkDeployAuth(inv.SubInvocations, funding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameContract reports whether a contract address is the contract with the given ID.
+func sameContract(address xdr.ScAddress, contractID string) bool {
+	if address.ContractId == nil {
+		return false
+	}
+	id, err := strkey.Encode(strkey.VersionByteContract, address.ContractId[:])
+	return err == nil && id == contractID
+}
+
+// SignBootstrap signs a transaction of "total bootstrap": a single operation from the
+// oracle account that uploads WASM, creates a contract or calls initialize. It is not
+// reachable from the UI.
+func (s *OracleSigner) SignBootstrap(txXDR string) (signedXDR, hash string, err error) {
+	if s == nil {
+		return "", "", ErrOracleSignerDisabled
+	}
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &envelope); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+	if envelope.Type != xdr.EnvelopeTypeEnvelopeTypeTx || len(envelope.V1.Tx.Operations) != 1 {
+		return "", "", fmt.Errorf("%w: expected a single operation", ErrOracleTxNotAllowed)
+	}
+	tx := envelope.V1.Tx
+	op := tx.Operations[0]
+	source := tx.SourceAccount.ToAccountId()
+	if op.SourceAccount != nil || source.Address() != s.key.Address() || op.Body.InvokeHostFunctionOp == nil {
+		return "", "", fmt.Errorf("%w: not a contract operation of the oracle", ErrOracleTxNotAllowed)
+	}
+	fn := op.Body.InvokeHostFunctionOp.HostFunction
+	switch fn.Type {
+	case xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm, xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+	case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+		if fn.InvokeContract.FunctionName != "initialize" {
+			return "", "", fmt.Errorf("%w: %s", ErrOracleTxNotAllowed, fn.InvokeContract.FunctionName)
+		}
+	default:
+		return "", "", fmt.Errorf("%w: host function %s", ErrOracleTxNotAllowed, fn.Type)
+	}
+	return s.signEnvelope(envelope)
+}
+
+// signEnvelope adds the oracle's signature to a transaction envelope.
+func (s *OracleSigner) signEnvelope(envelope xdr.TransactionEnvelope) (signedXDR, hash string, err error) {
+	txHash, err := network.HashTransactionInEnvelope(envelope, s.networkPassphrase)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+	sig, err := s.key.SignDecorated(txHash[:])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	envelope.V1.Signatures = append(envelope.V1.Signatures, sig)
+
+	signedXDR, err = xdr.MarshalBase64(envelope)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode signed transaction: %w", err)
+	}
+	return signedXDR, hex.EncodeToString(txHash[:]), nil
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func buildInvokeTx(t *testing.T, source, function string) string {
+	t.Helper()
+	return buildContractTx(t, source, seriesContractID(t, 1), function, nil, nil)
+}
+
+// buildContractTx builds a single contract call with the given args and auth entries.
+func buildContractTx(t *testing.T, source, contract, function string, args []xdr.ScVal, auth []xdr.SorobanAuthorizationEntry) string {
+	t.Helper()
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: source, Sequence: 1},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{&txnbuild.InvokeHostFunction{
+			HostFunction: xdr.HostFunction{
+				Type:           xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+				InvokeContract: contractCall(t, contract, function, args),
+			},
+			Auth: auth,
+		}},
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(300)},
+	})
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	txXDR, err := tx.Base64()
+	if err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	return txXDR
+}
+
+func contractCall(t *testing.T, contract, function string, args []xdr.ScVal) *xdr.InvokeContractArgs {
+	t.Helper()
+	raw, err := strkey.Decode(strkey.VersionByteContract, contract)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	var id xdr.ContractId
+	copy(id[:], raw)
+	return &xdr.InvokeContractArgs{
+		ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id},
+		FunctionName:    xdr.ScSymbol(function),
+		Args:            args,
+	}
+}
+
+// sourceAuth is an auth entry covered by the transaction signature.
+func sourceAuth(root *xdr.InvokeContractArgs, subs ...xdr.SorobanAuthorizedInvocation) xdr.SorobanAuthorizationEntry {
+	return xdr.SorobanAuthorizationEntry{
+		Credentials: xdr.SorobanCredentials{Type: xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount},
+		RootInvocation: xdr.SorobanAuthorizedInvocation{
+			Function:       xdr.SorobanAuthorizedFunction{Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn, ContractFn: root},
+			SubInvocations: subs,
+		},
+	}
+}
+
+// fakeOracleContracts is a factory with a fixed market list.
+type fakeOracleContracts struct {
+	factory string
+	markets []string
+}
+
+func (f fakeOracleContracts) FactoryContractID() string { return f.factory }
+
+func (f fakeOracleContracts) ListMarkets(context.Context) ([]string, error) { return f.markets, nil }
+
+func TestOracleSigner_Sign(t *testing.T) {
+	oracle := keypair.MustRandom()
+	other := keypair.MustRandom()
+	signer, err := NewOracleSigner(oracle.Seed(), oracle.Address(), config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewOracleSigner() error = %v", err)
+	}
+	market, factory, token := seriesContractID(t, 1), seriesContractID(t, 2), seriesContractID(t, 3)
+	contracts := fakeOracleContracts{factory: factory, markets: []string{market}}
+	ctx := context.Background()
+
+	oracleAddr, err := soroban.EncodeAddress(oracle.Address())
+	if err != nil {
+		t.Fatalf("EncodeAddress() error = %v", err)
+	}
+	marketAddr, err := soroban.EncodeAddress(market)
+	if err != nil {
+		t.Fatalf("EncodeAddress() error = %v", err)
+	}
+	resolveArgs := []xdr.ScVal{oracleAddr, soroban.EncodeU32(0)}
+	resolveCall := contractCall(t, market, "resolve", resolveArgs)
+	transfer := func(amount int64) xdr.SorobanAuthorizedInvocation {
+		return xdr.SorobanAuthorizedInvocation{Function: xdr.SorobanAuthorizedFunction{
+			Type:       xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn,
+			ContractFn: contractCall(t, token, "transfer", []xdr.ScVal{oracleAddr, marketAddr, soroban.EncodeI128(amount)}),
+		}}
+	}
+	deployArgs := []xdr.ScVal{oracleAddr, soroban.EncodeI128(1000), soroban.EncodeI128(0), soroban.EncodeI128(500)}
+	deployCall := contractCall(t, factory, "deploy_market", deployArgs)
+	create := xdr.SorobanAuthorizedInvocation{
+		Function: xdr.SorobanAuthorizedFunction{
+			Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn,
+			CreateContractV2HostFn: &xdr.CreateContractArgsV2{
+				ContractIdPreimage: xdr.ContractIdPreimage{
+					Type:        xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+					FromAddress: &xdr.ContractIdPreimageFromAddress{Address: deployCall.ContractAddress},
+				},
+				Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &xdr.Hash{}},
+			},
+		},
+		SubInvocations: []xdr.SorobanAuthorizedInvocation{transfer(500)},
+	}
+
+	built := buildContractTx(t, oracle.Address(), market, "resolve", resolveArgs, []xdr.SorobanAuthorizationEntry{sourceAuth(resolveCall)})
+	signed, hash, err := signer.Sign(ctx, built, contracts)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	pending := PendingTx{Account: oracle.Address(), XDR: built, Hash: hash, ExpiresAt: time.Now().Add(5 * time.Minute)}
+	if err := CheckSignedTx(signed, config.TestnetNetworkPassphrase, pending, time.Now()); err != nil {
+		t.Errorf("CheckSignedTx() of signed transaction error = %v", err)
+	}
+	deploy := buildContractTx(t, oracle.Address(), factory, "deploy_market", deployArgs, []xdr.SorobanAuthorizationEntry{sourceAuth(deployCall, create)})
+	if _, _, err := signer.Sign(ctx, deploy, contracts); err != nil {
+		t.Errorf("Sign() of deploy funding the market error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		xdr     string
+		wantErr error
+	}{
+		{"user function", buildContractTx(t, oracle.Address(), market, "buy", nil, nil), ErrOracleTxNotAllowed},
+		{"other source", buildContractTx(t, other.Address(), market, "resolve", nil, nil), ErrOracleTxNotAllowed},
+		{"unknown contract", buildContractTx(t, oracle.Address(), token, "resolve", nil, nil), ErrOracleTxNotAllowed},
+		{"deploy on a market", buildContractTx(t, oracle.Address(), market, "deploy_market", deployArgs, nil), ErrOracleTxNotAllowed},
+		{"auth for another call", buildContractTx(t, oracle.Address(), market, "resolve", resolveArgs,
+			[]xdr.SorobanAuthorizationEntry{sourceAuth(contractCall(t, token, "transfer", nil))}), ErrOracleTxNotAllowed},
+		{"resolve with a transfer", buildContractTx(t, oracle.Address(), market, "resolve", resolveArgs,
+			[]xdr.SorobanAuthorizationEntry{sourceAuth(resolveCall, transfer(1))}), ErrOracleTxNotAllowed},
+		{"deploy overfunding", buildContractTx(t, oracle.Address(), factory, "deploy_market", deployArgs,
+			[]xdr.SorobanAuthorizationEntry{sourceAuth(deployCall, transfer(501))}), ErrOracleTxNotAllowed},
+		{"not an invocation", mustBase64(t, buildTestTx(t, oracle.Address(), 1, time.Now().Add(time.Minute))), ErrInvalidTransaction},
+		{"garbage", "not-xdr", ErrInvalidTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := signer.Sign(ctx, tt.xdr, contracts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Sign() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var disabled *OracleSigner
+	if _, _, err := disabled.Sign(ctx, built, contracts); !errors.Is(err, ErrOracleSignerDisabled) {
+		t.Errorf("nil Sign() error = %v, want %v", err, ErrOracleSignerDisabled)
+	}
+}
+
+func TestOracleSigner_SignBootstrap(t *testing.T) {
+	oracle := keypair.MustRandom()
+	signer, err := NewOracleSigner(oracle.Seed(), oracle.Address(), config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("NewOracleSigner() error = %v", err)
+	}
+
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d}
+	upload, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: oracle.Address(), Sequence: 1},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{&txnbuild.InvokeHostFunction{
+			HostFunction: xdr.HostFunction{Type: xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm, Wasm: &wasm},
+		}},
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(300)},
+	})
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+
+	for name, txXDR := range map[string]string{
+		"upload wasm": mustBase64(t, upload),
+		"initialize":  buildInvokeTx(t, oracle.Address(), "initialize"),
+	} {
+		if _, _, err := signer.SignBootstrap(txXDR); err != nil {
+			t.Errorf("SignBootstrap(%s) error = %v", name, err)
+		}
+	}
+	for name, txXDR := range map[string]string{
+		"market function": buildInvokeTx(t, oracle.Address(), "resolve"),
+		"other source":    buildInvokeTx(t, keypair.MustRandom().Address(), "initialize"),
+	} {
+		if _, _, err := signer.SignBootstrap(txXDR); !errors.Is(err, ErrOracleTxNotAllowed) {
+			t.Errorf("SignBootstrap(%s) error = %v, want %v", name, err, ErrOracleTxNotAllowed)
+		}
+	}
+}
+
+func TestNewOracleSigner_Mismatch(t *testing.T) {
+	oracle := keypair.MustRandom()
+	other := keypair.MustRandom()
+	if _, err := NewOracleSigner(other.Seed(), oracle.Address(), config.TestnetNetworkPassphrase); !errors.Is(err, ErrOracleSignerMismatch) {
+		t.Errorf("NewOracleSigner() error = %v, want %v", err, ErrOracleSignerMismatch)
+	}
+	if _, err := NewOracleSigner("not-a-seed", oracle.Address(), config.TestnetNetworkPassphrase); err == nil {
+		t.Error("NewOracleSigner() with invalid seed succeeded")
+	}
+}
+
+func TestOracleKeystore(t *testing.T) {
+	oracle := keypair.MustRandom()
+	data, err := SealOracleKeystore(oracle.Seed(), "correct horse")
+	if err != nil {
+		t.Fatalf("SealOracleKeystore() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "oracle.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := LoadOracleSigner(path, "correct horse", oracle.Address(), config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("LoadOracleSigner() error = %v", err)
+	}
+	if signer.Address() != oracle.Address() {
+		t.Errorf("Address() = %s, want %s", signer.Address(), oracle.Address())
+	}
+
+	if _, err := LoadOracleSigner(path, "wrong", oracle.Address(), config.TestnetNetworkPassphrase); !errors.Is(err, ErrKeystorePassphrase) {
+		t.Errorf("LoadOracleSigner() with wrong passphrase error = %v, want %v", err, ErrKeystorePassphrase)
+	}
+	if _, err := LoadOracleSigner(path, "correct horse", keypair.MustRandom().Address(), config.TestnetNetworkPassphrase); !errors.Is(err, ErrOracleSignerMismatch) {
+		t.Errorf("LoadOracleSigner() for another oracle error = %v, want %v", err, ErrOracleSignerMismatch)
+	}
+	if _, err := SealOracleKeystore(oracle.Seed(), ""); err == nil {
+		t.Error("SealOracleKeystore() with empty passphrase succeeded")
+	}
+}
+
+func mustBase64(t *testing.T, tx *txnbuild.Transaction) string {
+	t.Helper()
+	txXDR, err := tx.Base64()
+	if err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	return txXDR
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// DefaultPaperBalance is the virtual EURMTL a paper trading account starts with.
+	DefaultPaperBalance = 1000.0
+
+	maxPaperAccounts = 10000 // Accounts that may open a paper balance
+	maxPaperTrades   = 200   // Trades kept per account, oldest dropped first
+
+	// PaperTradeClaim marks the payout of a paper position when its market resolves.
+	PaperTradeClaim TradeKind = "claim"
+)
+
+var (
+	// ErrPaperInsufficientBalance is returned when a paper buy costs more than the virtual balance.
+	ErrPaperInsufficientBalance = errors.New("insufficient paper balance")
+	// ErrPaperInsufficientTokens is returned when a paper sell exceeds the paper position.
+	ErrPaperInsufficientTokens = errors.New("insufficient paper tokens")
+	// ErrPaperAccountsFull is returned when no more paper accounts can be opened.
+	ErrPaperAccountsFull = errors.New("paper trading is not accepting new accounts")
+)
+
+// paperQuoter is the part of the market service paper trades are priced with.
+type paperQuoter interface {
+	GetQuote(ctx context.Context, contractID string, outcome model.Outcome, amount float64) (*Quote, error)
+	GetSellQuote(ctx context.Context, contractID string, outcome model.Outcome, amount float64) (*SellQuote, error)
+}
+
+// PaperTrade is one simulated trade.
+type PaperTrade struct {
+	ContractID string    `json:"contract_id"`
+	Kind       TradeKind `json:"kind"` // TradeKindBuy, TradeKindSell or PaperTradeClaim
+	Outcome    string    `json:"outcome"`
+	Amount     float64   `json:"amount"` // Tokens
+	Cost       float64   `json:"cost"`   // Collateral paid (buy), received (sell) or paid out (claim)
+	PriceAfter float64   `json:"price_after,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// PaperPosition is a paper account's tokens in one market.
+type PaperPosition struct {
+	ContractID string  `json:"contract_id"`
+	Yes        float64 `json:"yes"`
+	No         float64 `json:"no"`
+	Cost       float64 `json:"cost"` // Net collateral paid for the open tokens
+}
+
+// PaperAccount is a virtual EURMTL balance with its open positions and recent trades.
+type PaperAccount struct {
+	Account   string                    `json:"account"`
+	Balance   float64                   `json:"balance"`
+	Positions map[string]*PaperPosition `json:"positions"`
+	Trades    []PaperTrade              `json:"trades"` // Oldest first
+	Created   time.Time                 `json:"created"`
+}
+
+// PaperTrading simulates trades against real market prices without on-chain
+// transactions: buys and sells are priced by the same contract quotes as real trades,
+// but only move a virtual balance. Simulated trades never change market prices.
+// Accounts are kept in memory, optionally persisted to a JSON file.
+type PaperTrading struct {
+	quoter       paperQuoter
+	startBalance float64
+	path         string // Empty keeps paper accounts in memory only
+	logger       *slog.Logger
+	now          func() time.Time
+
+	mu       sync.RWMutex
+	accounts map[string]*PaperAccount
+}
+
+// NewPaperTrading creates paper trading with startBalance virtual EURMTL per account
+// and loads existing accounts from path, if set.
+func NewPaperTrading(quoter *MarketService, startBalance float64, path string, logger *slog.Logger) (*PaperTrading, error) {
+	if quoter == nil {
+		panic("NewPaperTrading: quoter must not be nil")
+	}
+	return newPaperTrading(quoter, startBalance, path, logger)
+}
+
+func newPaperTrading(quoter paperQuoter, startBalance float64, path string, logger *slog.Logger) (*PaperTrading, error) {
+	if logger == nil {
+		panic("NewPaperTrading: logger must not be nil")
+	}
+	if startBalance <= 0 {
+		return nil, fmt.Errorf("paper balance must be positive, got %v", startBalance)
+	}
+
+	p := &PaperTrading{
+		quoter:       quoter,
+		startBalance: startBalance,
+		path:         path,
+		logger:       logger,
+		now:          time.Now,
+		accounts:     make(map[string]*PaperAccount),
+	}
+	if path == "" {
+		return p, nil
+	}
+	if _, err := readJSONFile(path, &p.accounts); err != nil {
+		return nil, fmt.Errorf("failed to load paper trading file: %w", err)
+	}
+	if p.accounts == nil {
+		p.accounts = make(map[string]*PaperAccount)
+	}
+	return p, nil
+}
+
+// StartBalance returns the virtual EURMTL a new paper account starts with.
+func (p *PaperTrading) StartBalance() float64 {
+	return p.startBalance
+}
+
+// Account returns a copy of an account's paper balance, positions sorted by market.
+// An account that never traded is returned with the starting balance.
+func (p *PaperTrading) Account(address string) (PaperAccount, []PaperPosition) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	a := p.accounts[address]
+	if a == nil {
+		return PaperAccount{Account: address, Balance: p.startBalance}, nil
+	}
+	account := *a
+	account.Trades = slices.Clone(a.Trades)
+	account.Positions = nil
+	positions := make([]PaperPosition, 0, len(a.Positions))
+	for _, pos := range a.Positions {
+		positions = append(positions, *pos)
+	}
+	slices.SortFunc(positions, func(a, b PaperPosition) int { return cmp.Compare(a.ContractID, b.ContractID) })
+	return account, positions
+}
+
+// Buy buys amount paper tokens of outcome at the market's current quote.
+func (p *PaperTrading) Buy(ctx context.Context, address, contractID string, outcome model.Outcome, amount float64) (PaperTrade, error) {
+	quote, err := p.quoter.GetQuote(ctx, contractID, outcome, amount)
+	if err != nil {
+		return PaperTrade{}, err
+	}
+	trade := PaperTrade{
+		ContractID: contractID,
+		Kind:       TradeKindBuy,
+		Outcome:    string(outcome),
+		Amount:     amount,
+		Cost:       float64(quote.Cost) / float64(soroban.ScaleFactor),
+		PriceAfter: quote.PriceAfter,
+		Timestamp:  p.now(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a, err := p.accountLocked(address)
+	if err != nil {
+		return PaperTrade{}, err
+	}
+	if trade.Cost > a.Balance {
+		return PaperTrade{}, fmt.Errorf("%w: costs %.2f EURMTL, %.2f left", ErrPaperInsufficientBalance, trade.Cost, a.Balance)
+	}
+	a.Balance -= trade.Cost
+	pos := a.position(contractID)
+	*pos.tokens(outcome) += amount
+	pos.Cost += trade.Cost
+	a.record(trade)
+	return trade, p.saveLocked()
+}
+
+// Sell sells amount paper tokens of outcome back at the market's current quote.
+func (p *PaperTrading) Sell(ctx context.Context, address, contractID string, outcome model.Outcome, amount float64) (PaperTrade, error) {
+	if held := p.held(address, contractID, outcome); amount > held+positionEpsilon {
+		return PaperTrade{}, fmt.Errorf("%w: selling %.2f, holding %.2f", ErrPaperInsufficientTokens, amount, held)
+	}
+	quote, err := p.quoter.GetSellQuote(ctx, contractID, outcome, amount)
+	if err != nil {
+		return PaperTrade{}, err
+	}
+	trade := PaperTrade{
+		ContractID: contractID,
+		Kind:       TradeKindSell,
+		Outcome:    string(outcome),
+		Amount:     amount,
+		Cost:       float64(quote.ReturnAmount) / float64(soroban.ScaleFactor),
+		PriceAfter: quote.PriceAfter,
+		Timestamp:  p.now(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a := p.accounts[address]
+	var pos *PaperPosition
+	if a != nil {
+		pos = a.Positions[contractID]
+	}
+	// The position may have been sold or settled while the quote was fetched.
+	if pos == nil || amount > *pos.tokens(outcome)+positionEpsilon {
+		return PaperTrade{}, ErrPaperInsufficientTokens
+	}
+	held := *pos.tokens(outcome)
+	pos.Cost -= pos.Cost * min(amount/(pos.Yes+pos.No), 1)
+	*pos.tokens(outcome) = max(held-amount, 0)
+	a.Balance += trade.Cost
+	a.dropEmpty(contractID)
+	a.record(trade)
+	return trade, p.saveLocked()
+}
+
+// Settle pays out the paper positions in a resolved market: each winning token pays
+// 1 EURMTL less the claim fee, as if claimed. It returns how many positions settled.
+func (p *PaperTrading) Settle(contractID, winningOutcome string) (int, error) {
+	outcome, err := model.ParseOutcome(winningOutcome)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var settled int
+	for _, a := range p.accounts {
+		pos := a.Positions[contractID]
+		if pos == nil {
+			continue
+		}
+		winning := *pos.tokens(outcome)
+		payout := winning * (1 - ClaimFeeRate)
+		a.Balance += payout
+		delete(a.Positions, contractID)
+		a.record(PaperTrade{
+			ContractID: contractID,
+			Kind:       PaperTradeClaim,
+			Outcome:    string(outcome),
+			Amount:     winning,
+			Cost:       payout,
+			Timestamp:  p.now(),
+		})
+		settled++
+	}
+	if settled == 0 {
+		return 0, nil
+	}
+	return settled, p.saveLocked()
+}
+
+// Reset closes an account's paper positions and restores the starting balance.
+func (p *PaperTrading) Reset(address string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.accounts[address]; !ok {
+		return nil
+	}
+	delete(p.accounts, address)
+	return p.saveLocked()
+}
+
+// held returns the paper tokens of outcome an account holds in a market.
+func (p *PaperTrading) held(address, contractID string, outcome model.Outcome) float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if a := p.accounts[address]; a != nil {
+		if pos := a.Positions[contractID]; pos != nil {
+			return *pos.tokens(outcome)
+		}
+	}
+	return 0
+}
+
+// accountLocked returns an account's paper balance, opening it on first use.
+func (p *PaperTrading) accountLocked(address string) (*PaperAccount, error) {
+	if a := p.accounts[address]; a != nil {
+		return a, nil
+	}
+	if len(p.accounts) >= maxPaperAccounts {
+		return nil, ErrPaperAccountsFull
+	}
+	a := &PaperAccount{
+		Account:   address,
+		Balance:   p.startBalance,
+		Positions: make(map[string]*PaperPosition),
+		Created:   p.now(),
+	}
+	p.accounts[address] = a
+	return a, nil
+}
+
+func (a *PaperAccount) position(contractID string) *PaperPosition {
+	if a.Positions == nil {
+		a.Positions = make(map[string]*PaperPosition)
+	}
+	pos := a.Positions[contractID]
+	if pos == nil {
+		pos = &PaperPosition{ContractID: contractID}
+		a.Positions[contractID] = pos
+	}
+	return pos
+}
+
+// dropEmpty removes a position without tokens left.
+func (a *PaperAccount) dropEmpty(contractID string) {
+	if pos := a.Positions[contractID]; pos != nil && pos.Yes < positionEpsilon && pos.No < positionEpsilon {
+		delete(a.Positions, contractID)
+	}
+}
+
+func (a *PaperAccount) record(trade PaperTrade) {
+	a.Trades = append(a.Trades, trade)
+	if len(a.Trades) > maxPaperTrades {
+		a.Trades = slices.Delete(a.Trades, 0, len(a.Trades)-maxPaperTrades)
+	}
+}
+
+func (pos *PaperPosition) tokens(outcome model.Outcome) *float64 {
+	if outcome == model.OutcomeYes {
+		return &pos.Yes
+	}
+	return &pos.No
+}
+
+func (p *PaperTrading) saveLocked() error {
+	if p.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(p.path, p.accounts); err != nil {
+		return fmt.Errorf("failed to save paper trading file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// fixedQuoter prices every token at the same price, for buys and sells alike.
+type fixedQuoter struct{ price float64 }
+
+func (q fixedQuoter) GetQuote(_ context.Context, _ string, _ model.Outcome, amount float64) (*Quote, error) {
+	return &Quote{Cost: int64(amount * q.price * float64(soroban.ScaleFactor)), PriceAfter: q.price}, nil
+}
+
+func (q fixedQuoter) GetSellQuote(_ context.Context, _ string, _ model.Outcome, amount float64) (*SellQuote, error) {
+	return &SellQuote{ReturnAmount: int64(amount * q.price * float64(soroban.ScaleFactor)), PriceAfter: q.price}, nil
+}
+
+func TestPaperTradingBuySellSettle(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "paper.json")
+	p, err := newPaperTrading(fixedQuoter{price: 0.25}, 100, path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Buy(ctx, "GA", "CA", model.OutcomeYes, 500); !errors.Is(err, ErrPaperInsufficientBalance) {
+		t.Fatalf("Buy() over balance error = %v, want ErrPaperInsufficientBalance", err)
+	}
+	if _, err := p.Buy(ctx, "GA", "CA", model.OutcomeYes, 200); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Sell(ctx, "GA", "CA", model.OutcomeYes, 300); !errors.Is(err, ErrPaperInsufficientTokens) {
+		t.Fatalf("Sell() over position error = %v, want ErrPaperInsufficientTokens", err)
+	}
+	if _, err := p.Sell(ctx, "GA", "CA", model.OutcomeYes, 40); err != nil {
+		t.Fatal(err)
+	}
+	account, positions := p.Account("GA")
+	if account.Balance != 60 || len(positions) != 1 || positions[0].Yes != 160 {
+		t.Fatalf("Account() = balance %v, positions %+v, want 60 and 160 YES", account.Balance, positions)
+	}
+
+	// Reload from disk before settling
+	p, err = newPaperTrading(fixedQuoter{price: 0.25}, 100, path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := p.Settle("CA", "YES"); err != nil || n != 1 {
+		t.Fatalf("Settle() = %d, %v, want 1 position", n, err)
+	}
+	account, positions = p.Account("GA")
+	if want := 60 + 160*(1-ClaimFeeRate); account.Balance != want || len(positions) != 0 {
+		t.Fatalf("Account() after settle = balance %v, positions %+v, want %v and none", account.Balance, positions, want)
+	}
+	if last := account.Trades[len(account.Trades)-1]; last.Kind != PaperTradeClaim {
+		t.Errorf("last trade kind = %q, want claim", last.Kind)
+	}
+
+	if err := p.Reset("GA"); err != nil {
+		t.Fatal(err)
+	}
+	if account, _ := p.Account("GA"); account.Balance != 100 || len(account.Trades) != 0 {
+		t.Errorf("Account() after reset = %+v, want the starting balance", account)
+	}
+}
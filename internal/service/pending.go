@@ -0,0 +1,220 @@
+package service
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/hot"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	pendingTxTTL         = 24 * time.Hour
+	pendingTxCacheSize   = 10000
+	maxPendingPerAccount = 20
+)
+
+// ErrPendingTxNotFound is returned when a pending transaction does not exist or has been dismissed.
+var ErrPendingTxNotFound = errors.New("pending transaction not found")
+
+// PendingTxKind identifies the user action that produced a pending transaction.
+type PendingTxKind string
+
+const (
+	PendingTxBuy      PendingTxKind = "buy"
+	PendingTxSell     PendingTxKind = "sell"
+	PendingTxClaim    PendingTxKind = "claim"
+	PendingTxDeploy   PendingTxKind = "deploy"
+	PendingTxResolve  PendingTxKind = "resolve"
+	PendingTxWithdraw PendingTxKind = "withdraw"
+)
+
+// PendingTx is a transaction built for a user that has not been dismissed yet.
+// The original request is kept so the transaction can be rebuilt after it expires.
+type PendingTx struct {
+	ID            string
+	Kind          PendingTxKind
+	Account       string
+	ContractID    string
+	Description   string
+	XDR           string
+	Hash          string // Hex transaction hash; signing does not change it
+	Sequence      int64  // Source account sequence number; transactions of one account land in this order
+	CreatedAt     time.Time
+	ExpiresAt     time.Time            // Zero when the transaction has no upper time bound
+	AuthExpiresAt time.Time            // Estimated expiry of its auth entries; zero when it has none
+	BuiltBy       string               // Signed-in account that built it; empty for anonymous builds
+	Trade         *TradeRequest        // Set for buy/sell
+	Deploy        *DeployMarketRequest // Set for deploy
+	Resolve       *ResolveRequest      // Set for resolve
+}
+
+// IsExpired reports whether the transaction's time bounds, or its auth entries, have
+// expired. Either way it must be regenerated, which re-simulates it.
+func (p PendingTx) IsExpired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && now.After(p.ExpiresAt)
+}
+
+// PendingTxStore keeps recently built transactions per account in memory.
+// Entries are lost on restart, which is acceptable: users can always rebuild from the market page.
+type PendingTxStore struct {
+	mu    sync.Mutex
+	cache *hot.HotCache[string, []PendingTx]
+}
+
+// NewPendingTxStore creates a new in-memory pending transaction store.
+func NewPendingTxStore() *PendingTxStore {
+	return &PendingTxStore{
+		cache: hot.NewHotCache[string, []PendingTx](hot.LRU, pendingTxCacheSize).
+			WithTTL(pendingTxTTL).
+			Build(),
+	}
+}
+
+// Add records a built transaction for the account and returns the stored entry.
+// Only the newest maxPendingPerAccount entries are kept per account.
+func (s *PendingTxStore) Add(tx PendingTx) (PendingTx, error) {
+	id, err := newPendingTxID()
+	if err != nil {
+		return PendingTx{}, err
+	}
+	tx.ID = id
+	if tx.CreatedAt.IsZero() {
+		tx.CreatedAt = time.Now().UTC()
+	}
+	if tx.ExpiresAt.IsZero() {
+		expiresAt, err := transactionExpiry(tx.XDR)
+		if err != nil {
+			return PendingTx{}, fmt.Errorf("failed to read transaction time bounds: %w", err)
+		}
+		tx.ExpiresAt = expiresAt
+	}
+	if tx.Sequence == 0 {
+		seq, err := transactionSequence(tx.XDR)
+		if err != nil {
+			return PendingTx{}, err
+		}
+		tx.Sequence = seq
+	}
+	if !tx.AuthExpiresAt.IsZero() && (tx.ExpiresAt.IsZero() || tx.AuthExpiresAt.Before(tx.ExpiresAt)) {
+		tx.ExpiresAt = tx.AuthExpiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, _, _ := s.cache.Get(tx.Account)
+	list = append([]PendingTx{tx}, list...)
+	if len(list) > maxPendingPerAccount {
+		list = list[:maxPendingPerAccount]
+	}
+	s.cache.Set(tx.Account, list)
+	return tx, nil
+}
+
+// List returns the account's pending transactions, newest first.
+func (s *PendingTxStore) List(account string) []PendingTx {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, found, err := s.cache.Get(account)
+	if err != nil || !found {
+		return nil
+	}
+	return slices.Clone(list)
+}
+
+// ListForContract returns the account's pending transactions for a single market.
+func (s *PendingTxStore) ListForContract(account, contractID string) []PendingTx {
+	var result []PendingTx
+	for _, tx := range s.List(account) {
+		if tx.ContractID == contractID {
+			result = append(result, tx)
+		}
+	}
+	return result
+}
+
+// Queue returns the account's pending transactions in the order they must be
+// submitted: by sequence number, oldest first for equal numbers.
+func (s *PendingTxStore) Queue(account string) []PendingTx {
+	list := s.List(account)
+	slices.Reverse(list)
+	slices.SortStableFunc(list, func(a, b PendingTx) int { return cmp.Compare(a.Sequence, b.Sequence) })
+	return list
+}
+
+// Get returns a single pending transaction.
+func (s *PendingTxStore) Get(account, id string) (PendingTx, error) {
+	for _, tx := range s.List(account) {
+		if tx.ID == id {
+			return tx, nil
+		}
+	}
+	return PendingTx{}, ErrPendingTxNotFound
+}
+
+// FindByHash returns the account's pending transaction with the given hash.
+func (s *PendingTxStore) FindByHash(account, hash string) (PendingTx, error) {
+	for _, tx := range s.List(account) {
+		if tx.Hash != "" && tx.Hash == hash {
+			return tx, nil
+		}
+	}
+	return PendingTx{}, ErrPendingTxNotFound
+}
+
+// Remove deletes a pending transaction. Removing an unknown ID is not an error.
+func (s *PendingTxStore) Remove(account, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, found, err := s.cache.Get(account)
+	if err != nil || !found {
+		return
+	}
+	list = slices.DeleteFunc(slices.Clone(list), func(tx PendingTx) bool { return tx.ID == id })
+	if len(list) == 0 {
+		s.cache.Delete(account)
+		return
+	}
+	s.cache.Set(account, list)
+}
+
+// newPendingTxID generates a random identifier for a pending transaction.
+func newPendingTxID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate pending tx ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// transactionExpiry returns the upper time bound of a transaction envelope.
+// Returns zero time for transactions built with an infinite timeout.
+func transactionExpiry(txXDR string) (time.Time, error) {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	tb := env.TimeBounds()
+	if tb == nil || tb.MaxTime == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(int64(tb.MaxTime), 0).UTC(), nil
+}
+
+// transactionSequence returns the sequence number of a transaction envelope.
+func transactionSequence(txXDR string) (int64, error) {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+		return 0, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	return env.SeqNum(), nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+func TestPendingTxQueueOrdersBySequence(t *testing.T) {
+	account := keypair.MustRandom().Address()
+	buildTx := func(seq int64) string {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount:        &txnbuild.SimpleAccount{AccountID: account, Sequence: seq - 1},
+			IncrementSequenceNum: true,
+			Operations:           []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 0}},
+			BaseFee:              txnbuild.MinBaseFee,
+			Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(300)},
+		})
+		if err != nil {
+			t.Fatalf("NewTransaction() error = %v", err)
+		}
+		return mustBase64(t, tx)
+	}
+
+	store := NewPendingTxStore()
+	for _, seq := range []int64{12, 10, 11} {
+		if _, err := store.Add(PendingTx{Kind: PendingTxResolve, Account: account, Description: "resolve", XDR: buildTx(seq)}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	queue := store.Queue(account)
+	if len(queue) != 3 {
+		t.Fatalf("Queue() returned %d transactions, want 3", len(queue))
+	}
+	for i, want := range []int64{10, 11, 12} {
+		if queue[i].Sequence != want {
+			t.Errorf("Queue()[%d].Sequence = %d, want %d", i, queue[i].Sequence, want)
+		}
+	}
+}
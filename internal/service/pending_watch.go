@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// PendingTxWatchInterval is how often the oracle's pending transactions are looked up
+// on-chain, so the ones signed and submitted elsewhere are closed without a poll.
+const PendingTxWatchInterval = time.Minute
+
+// PendingTxState is what the network knows about a pending transaction.
+type PendingTxState string
+
+const (
+	PendingTxWaiting  PendingTxState = "waiting"  // Not seen on-chain yet
+	PendingTxIncluded PendingTxState = "included" // It, or a transaction doing the same, succeeded
+	PendingTxFailed   PendingTxState = "failed"   // Included but failed, or can no longer succeed
+)
+
+// PendingTxCheck is the on-chain state of a pending transaction. Included ones are
+// removed from the pending store.
+type PendingTxCheck struct {
+	ID         string         `json:"id"`
+	Hash       string         `json:"hash"`
+	State      PendingTxState `json:"state"`
+	MatchedBy  string         `json:"matched_by,omitempty"`  // "hash", or "contents" for an equivalent transaction
+	ContractID string         `json:"contract_id,omitempty"` // Market resolved or deployed
+	Error      string         `json:"error,omitempty"`
+}
+
+// PendingTxWatcher follows pending transactions that are signed outside this app, e.g.
+// on a hardware wallet or an air-gapped machine from a QR code, and submitted from
+// there. A transaction is matched by its hash, which signing does not change; resolve
+// and deploy transactions are also matched by their effect, so one rebuilt and signed
+// elsewhere still closes the pending action.
+//
+// When a deploy lands, the new market's listing, state and metadata are fetched into
+// their caches, so its first visitors do not wait on a cold IPFS fetch.
+type PendingTxWatcher struct {
+	pendingTxs     *PendingTxStore
+	eventService   *EventService
+	factoryService *FactoryService
+	ipfsClient     *ipfs.Client // nil: metadata is not prefetched
+	logger         *slog.Logger
+}
+
+// NewPendingTxWatcher creates a watcher of the pending store. ipfsClient may be nil.
+func NewPendingTxWatcher(pendingTxs *PendingTxStore, eventService *EventService, factoryService *FactoryService, ipfsClient *ipfs.Client, logger *slog.Logger) *PendingTxWatcher {
+	if pendingTxs == nil || eventService == nil || factoryService == nil {
+		panic("pending tx watcher requires the pending store, event and factory services")
+	}
+	return &PendingTxWatcher{
+		pendingTxs:     pendingTxs,
+		eventService:   eventService,
+		factoryService: factoryService,
+		ipfsClient:     ipfsClient,
+		logger:         logger,
+	}
+}
+
+// Check looks up one pending transaction of the account on-chain.
+func (w *PendingTxWatcher) Check(ctx context.Context, account, id string) (PendingTxCheck, error) {
+	tx, err := w.pendingTxs.Get(account, id)
+	if err != nil {
+		return PendingTxCheck{}, err
+	}
+	return w.check(ctx, tx, nil)
+}
+
+// Refresh checks all pending transactions of the account, typically the oracle's.
+func (w *PendingTxWatcher) Refresh(ctx context.Context, account string) error {
+	pending := w.pendingTxs.List(account)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Deploys are matched against the metadata of all markets; list them once.
+	var errs []error
+	var deployed map[string]string
+	for _, tx := range pending {
+		if tx.Kind == PendingTxDeploy && tx.Deploy != nil {
+			var err error
+			if deployed, err = w.deployedMarkets(ctx); err != nil {
+				errs = append(errs, err)
+			}
+			break
+		}
+	}
+
+	for _, tx := range pending {
+		if _, err := w.check(ctx, tx, deployed); err != nil {
+			errs = append(errs, fmt.Errorf("pending %s: %w", tx.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check looks up tx by hash, then by contents. deployed maps metadata hashes to
+// market IDs; nil skips matching deploys by contents.
+func (w *PendingTxWatcher) check(ctx context.Context, tx PendingTx, deployed map[string]string) (PendingTxCheck, error) {
+	result := PendingTxCheck{ID: tx.ID, Hash: tx.Hash, State: PendingTxWaiting}
+
+	if tx.Hash != "" {
+		status, err := w.eventService.TransactionStatus(ctx, tx.Hash)
+		if err != nil {
+			return result, err
+		}
+		switch status.Outcome.Status {
+		case soroban.TxResultSuccess:
+			result.State, result.MatchedBy, result.ContractID = PendingTxIncluded, "hash", tx.ContractID
+			if tx.Kind == PendingTxDeploy {
+				result.ContractID = deployedContract(status.Outcome)
+			}
+		case soroban.TxResultFailed:
+			// The sequence number is used up; the transaction must be rebuilt.
+			result.State, result.MatchedBy = PendingTxFailed, "hash"
+			result.Error = "transaction failed on-chain; rebuild it and sign again"
+		}
+	}
+
+	if result.State == PendingTxWaiting {
+		switch {
+		case tx.Kind == PendingTxResolve && tx.Resolve != nil:
+			loaded, err := w.factoryService.GetMarketStates(ctx, []string{tx.Resolve.ContractID})
+			if err != nil {
+				return result, err
+			}
+			states := loaded.States
+			if len(states) == 1 && states[0].Resolved {
+				result.ContractID = tx.Resolve.ContractID
+				result.MatchedBy = "contents"
+				if states[0].WinningOutcome == tx.Resolve.WinningOutcome.String() {
+					result.State = PendingTxIncluded
+				} else {
+					result.State = PendingTxFailed
+					result.Error = "market was already resolved to " + states[0].WinningOutcome
+				}
+			}
+		case tx.Kind == PendingTxDeploy && tx.Deploy != nil && deployed != nil:
+			if contractID, ok := deployed[tx.Deploy.MetadataHash]; ok {
+				result.State, result.MatchedBy, result.ContractID = PendingTxIncluded, "contents", contractID
+			}
+		}
+	}
+
+	if result.State == PendingTxIncluded {
+		w.pendingTxs.Remove(tx.Account, tx.ID)
+		w.logger.Info("pending transaction landed on-chain", "kind", tx.Kind, "tx_hash", tx.Hash, "matched_by", result.MatchedBy, "contract_id", result.ContractID)
+		if tx.Kind == PendingTxDeploy && tx.Deploy != nil && result.ContractID != "" {
+			w.prefetchMarket(ctx, result.ContractID, tx.Deploy.MetadataHash)
+		}
+	}
+	return result, nil
+}
+
+// deployedContract returns the market address a successful deploy_market call
+// returned, or "" when the outcome has none.
+func deployedContract(outcome *soroban.TransactionOutcome) string {
+	if outcome == nil || outcome.ReturnValue == nil {
+		return ""
+	}
+	contractID, err := soroban.DecodeAddress(*outcome.ReturnValue)
+	if err != nil {
+		return ""
+	}
+	return contractID
+}
+
+// prefetchMarket loads what the market card and page of a new market read: the
+// market list, the market state and its metadata. Failures are logged; the caches
+// then fill on the first visit as before.
+func (w *PendingTxWatcher) prefetchMarket(ctx context.Context, contractID, metadataHash string) {
+	if _, err := w.factoryService.RefreshMarketList(ctx); err != nil {
+		w.logger.Warn("prefetch: failed to refresh market list", "contract_id", contractID, "error", err)
+	}
+	if _, err := w.factoryService.GetMarketStates(ctx, []string{contractID}); err != nil {
+		w.logger.Warn("prefetch: failed to load market state", "contract_id", contractID, "error", err)
+	}
+	if w.ipfsClient == nil || metadataHash == "" {
+		return
+	}
+	var metadata model.MarketMetadata
+	if err := w.ipfsClient.GetJSON(ctx, metadataHash, &metadata); err != nil {
+		w.logger.Warn("prefetch: failed to load market metadata", "contract_id", contractID, "hash", metadataHash, "error", err)
+		return
+	}
+	w.logger.Info("prefetched new market", "contract_id", contractID, "question", metadata.Question)
+}
+
+// deployedMarkets maps the metadata hash of every listed market to its contract ID.
+func (w *PendingTxWatcher) deployedMarkets(ctx context.Context) (map[string]string, error) {
+	contractIDs, err := w.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := w.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		w.logger.Warn("pending watch: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	byHash := make(map[string]string, len(states))
+	for _, st := range states {
+		if st.MetadataHash != "" {
+			byHash[st.MetadataHash] = st.ContractID
+		}
+	}
+	return byHash, nil
+}
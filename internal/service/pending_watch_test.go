@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+func TestDeployedContract(t *testing.T) {
+	market, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := soroban.EncodeAddress(market)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := deployedContract(&soroban.TransactionOutcome{Successful: true, ReturnValue: &address}); got != market {
+		t.Errorf("deployedContract() = %q, want %q", got, market)
+	}
+	notAddress := soroban.EncodeU32(1)
+	for name, outcome := range map[string]*soroban.TransactionOutcome{
+		"nil outcome":     nil,
+		"no return value": {Successful: true},
+		"not an address":  {Successful: true, ReturnValue: &notAddress},
+	} {
+		if got := deployedContract(outcome); got != "" {
+			t.Errorf("deployedContract(%s) = %q, want empty", name, got)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+const (
+	// PriceHistoryInterval is how often market prices are sampled.
+	PriceHistoryInterval = 15 * time.Minute
+	// PriceRollupInterval is how often samples past their retention are rolled up.
+	PriceRollupInterval = time.Hour
+	// priceHistoryRetention is the longest window queried; raw samples are kept at least
+	// this long.
+	priceHistoryRetention = 25 * time.Hour
+)
+
+// PriceRetention bounds the price history. Raw samples older than Raw are rolled up
+// into hourly aggregates, hourly aggregates older than Hourly into daily ones, and
+// daily aggregates older than Daily are dropped; a zero Daily keeps them.
+type PriceRetention struct {
+	Raw    time.Duration
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// DefaultPriceRetention keeps two days of samples and a month of hourly aggregates.
+var DefaultPriceRetention = PriceRetention{Raw: 48 * time.Hour, Hourly: 30 * 24 * time.Hour}
+
+// PriceAggregate summarizes the YES price samples of one hour or day.
+type PriceAggregate struct {
+	Start   time.Time `json:"start"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Samples int       `json:"samples"`
+}
+
+// merge adds a later aggregate of the same period.
+func (a *PriceAggregate) merge(b PriceAggregate) {
+	a.High, a.Low, a.Close = max(a.High, b.High), min(a.Low, b.Low), b.Close
+	a.Samples += b.Samples
+}
+
+// priceHistoryFile is the persisted price history.
+type priceHistoryFile struct {
+	Raw    map[string][]priceHistorySample `json:"raw"`
+	Hourly map[string][]PriceAggregate     `json:"hourly"`
+	Daily  map[string][]PriceAggregate     `json:"daily"`
+}
+
+type priceHistorySample struct {
+	At    time.Time `json:"at"`
+	Price float64   `json:"price"`
+}
+
+// PriceHistory samples YES prices of all markets from the state cache, so recent probability
+// changes can be shown without replaying trade events. A scheduled rollup keeps the
+// history bounded: raw samples become hourly, then daily aggregates, which still chart
+// a market's whole life. The history is optionally persisted to a JSON file; without
+// one, changes become available after a restart once the window has been observed.
+type PriceHistory struct {
+	factoryService *FactoryService
+	retention      PriceRetention
+	path           string // Empty keeps the history in memory only
+	logger         *slog.Logger
+
+	mu      sync.RWMutex
+	samples map[string][]priceSample // contract ID -> samples, oldest first
+	hourly  map[string][]PriceAggregate
+	daily   map[string][]PriceAggregate
+}
+
+// NewPriceHistory creates a price history and loads it from path, if set. A Raw
+// retention below the longest queried window is raised to it.
+func NewPriceHistory(factoryService *FactoryService, retention PriceRetention, path string, logger *slog.Logger) (*PriceHistory, error) {
+	if factoryService == nil {
+		panic("NewPriceHistory: factoryService must not be nil")
+	}
+	if logger == nil {
+		panic("NewPriceHistory: logger must not be nil")
+	}
+	retention.Raw = max(retention.Raw, priceHistoryRetention)
+	h := &PriceHistory{
+		factoryService: factoryService,
+		retention:      retention,
+		path:           path,
+		logger:         logger,
+		samples:        make(map[string][]priceSample),
+		hourly:         make(map[string][]PriceAggregate),
+		daily:          make(map[string][]PriceAggregate),
+	}
+	if path == "" {
+		return h, nil
+	}
+	var file priceHistoryFile
+	if _, err := readJSONFile(path, &file); err != nil {
+		return nil, fmt.Errorf("failed to load price history: %w", err)
+	}
+	for id, samples := range file.Raw {
+		for _, p := range samples {
+			h.samples[id] = append(h.samples[id], priceSample{at: p.At, price: p.Price})
+		}
+	}
+	maps.Copy(h.hourly, file.Hourly)
+	maps.Copy(h.daily, file.Daily)
+	return h, nil
+}
+
+// Refresh samples the current prices of all listed markets.
+func (h *PriceHistory) Refresh(ctx context.Context) error {
+	if !h.factoryService.HasFactory() {
+		return nil
+	}
+	ids, err := h.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := h.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		h.logger.Warn("price history: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	h.record(states, time.Now())
+	return h.save()
+}
+
+func (h *PriceHistory) record(states []MarketState, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, s := range states {
+		h.samples[s.ContractID] = append(h.samples[s.ContractID], priceSample{at: now, price: s.PriceYes})
+	}
+}
+
+// Rollup rolls samples and hourly aggregates past their retention up into coarser
+// aggregates and drops daily aggregates past theirs.
+func (h *PriceHistory) Rollup(now time.Time) error {
+	h.mu.Lock()
+	rawCutoff := now.Add(-h.retention.Raw)
+	for id, samples := range h.samples {
+		i := slices.IndexFunc(samples, func(p priceSample) bool { return !p.at.Before(rawCutoff) })
+		if i < 0 {
+			i = len(samples)
+		}
+		for _, p := range samples[:i] {
+			h.hourly[id] = appendAggregate(h.hourly[id], p.at.Truncate(time.Hour), PriceAggregate{Open: p.price, High: p.price, Low: p.price, Close: p.price, Samples: 1})
+		}
+		h.samples[id] = slices.Delete(samples, 0, i)
+		if len(h.samples[id]) == 0 {
+			delete(h.samples, id)
+		}
+	}
+
+	hourlyCutoff := now.Add(-h.retention.Hourly).UTC().Truncate(24 * time.Hour)
+	for id, hours := range h.hourly {
+		i := slices.IndexFunc(hours, func(a PriceAggregate) bool { return !a.Start.Before(hourlyCutoff) })
+		if i < 0 {
+			i = len(hours)
+		}
+		for _, a := range hours[:i] {
+			h.daily[id] = appendAggregate(h.daily[id], a.Start.UTC().Truncate(24*time.Hour), a)
+		}
+		h.hourly[id] = slices.Delete(hours, 0, i)
+		if len(h.hourly[id]) == 0 {
+			delete(h.hourly, id)
+		}
+	}
+
+	if h.retention.Daily > 0 {
+		dailyCutoff := now.Add(-h.retention.Daily)
+		for id, days := range h.daily {
+			h.daily[id] = slices.DeleteFunc(days, func(a PriceAggregate) bool { return a.Start.Before(dailyCutoff) })
+			if len(h.daily[id]) == 0 {
+				delete(h.daily, id)
+			}
+		}
+	}
+	h.mu.Unlock()
+	return h.save()
+}
+
+// appendAggregate adds an aggregate of the period starting at start to a series,
+// merging it into the last one of the same period.
+func appendAggregate(series []PriceAggregate, start time.Time, a PriceAggregate) []PriceAggregate {
+	if n := len(series); n > 0 && series[n-1].Start.Equal(start) {
+		series[n-1].merge(a)
+		return series
+	}
+	a.Start = start
+	return append(series, a)
+}
+
+// Series returns a market's price history for charting, oldest first: the closes of
+// daily and hourly aggregates, then the raw samples.
+func (h *PriceHistory) Series(contractID string) []model.PricePoint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var points []model.PricePoint
+	for _, a := range h.daily[contractID] {
+		points = append(points, model.PricePoint{Timestamp: a.Start, PriceYes: a.Close})
+	}
+	for _, a := range h.hourly[contractID] {
+		points = append(points, model.PricePoint{Timestamp: a.Start, PriceYes: a.Close})
+	}
+	for _, p := range h.samples[contractID] {
+		points = append(points, model.PricePoint{Timestamp: p.at, PriceYes: p.price})
+	}
+	return points
+}
+
+// PriceRow is one entry of the price history: a daily or hourly aggregate, or a raw
+// sample with Samples set to 1.
+type PriceRow struct {
+	ContractID  string
+	Granularity string // "day", "hour" or "sample"
+	PriceAggregate
+}
+
+// Rows returns the whole price history by market, coarsest entries first.
+func (h *PriceHistory) Rows() []PriceRow {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ids := slices.Sorted(maps.Keys(h.samples))
+	ids = append(ids, slices.Collect(maps.Keys(h.hourly))...)
+	ids = append(ids, slices.Collect(maps.Keys(h.daily))...)
+	slices.Sort(ids)
+	var rows []PriceRow
+	for _, id := range slices.Compact(ids) {
+		for _, a := range h.daily[id] {
+			rows = append(rows, PriceRow{ContractID: id, Granularity: "day", PriceAggregate: a})
+		}
+		for _, a := range h.hourly[id] {
+			rows = append(rows, PriceRow{ContractID: id, Granularity: "hour", PriceAggregate: a})
+		}
+		for _, p := range h.samples[id] {
+			rows = append(rows, PriceRow{ContractID: id, Granularity: "sample", PriceAggregate: PriceAggregate{
+				Start: p.at, Open: p.price, High: p.price, Low: p.price, Close: p.price, Samples: 1,
+			}})
+		}
+	}
+	return rows
+}
+
+func (h *PriceHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	h.mu.RLock()
+	file := priceHistoryFile{
+		Raw:    make(map[string][]priceHistorySample, len(h.samples)),
+		Hourly: h.hourly,
+		Daily:  h.daily,
+	}
+	for id, samples := range h.samples {
+		for _, p := range samples {
+			file.Raw[id] = append(file.Raw[id], priceHistorySample{At: p.at, Price: p.price})
+		}
+	}
+	err := writeJSONFile(h.path, file)
+	h.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to save price history: %w", err)
+	}
+	return nil
+}
+
+// Change returns how much the YES price moved from the oldest sample within window to
+// the latest sample, in probability (-1..1). ok is false until the market has been
+// observed for most of the window.
+func (h *PriceHistory) Change(contractID string, window time.Duration, now time.Time) (change float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	samples := h.samples[contractID]
+	start := now.Add(-window)
+	i := slices.IndexFunc(samples, func(p priceSample) bool { return !p.at.Before(start) })
+	if i < 0 {
+		return 0, false
+	}
+	first, last := samples[i], samples[len(samples)-1]
+	// Require a baseline from the first quarter of the window, so a market observed
+	// for ten minutes does not claim a daily move.
+	if first.at.After(start.Add(window / 4)) {
+		return 0, false
+	}
+	return last.price - first.price, true
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceHistoryChange(t *testing.T) {
+	h := &PriceHistory{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		samples: make(map[string][]priceSample),
+	}
+	start := time.Unix(1_700_000_000, 0)
+	record := func(at time.Duration, price float64) {
+		h.record([]MarketState{{ContractID: testContractID, PriceYes: price}}, start.Add(at))
+	}
+
+	record(0, 0.40)
+	if _, ok := h.Change(testContractID, 24*time.Hour, start.Add(time.Hour)); ok {
+		t.Fatal("Change() ok after one hour of a 24h window, want false")
+	}
+
+	record(12*time.Hour, 0.55)
+	record(23*time.Hour, 0.70)
+	change, ok := h.Change(testContractID, 24*time.Hour, start.Add(23*time.Hour))
+	if !ok || math.Abs(change-0.30) > 1e-9 {
+		t.Errorf("Change() = %v, %v, want 0.30, true", change, ok)
+	}
+
+	// Samples older than the window are ignored, so the baseline moves forward.
+	record(36*time.Hour, 0.60)
+	change, ok = h.Change(testContractID, 24*time.Hour, start.Add(36*time.Hour))
+	if !ok || math.Abs(change-0.05) > 1e-9 {
+		t.Errorf("Change() = %v, %v, want 0.05, true", change, ok)
+	}
+
+	if _, ok := h.Change("unknown", 24*time.Hour, start); ok {
+		t.Error("Change() ok for unknown market, want false")
+	}
+}
+
+func TestPriceHistoryRollup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	h, err := NewPriceHistory(&FactoryService{}, PriceRetention{Raw: 48 * time.Hour, Hourly: 72 * time.Hour, Daily: 10 * 24 * time.Hour}, path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewPriceHistory() error = %v", err)
+	}
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, price := range []float64{0.40, 0.60, 0.30, 0.50} {
+		h.record([]MarketState{{ContractID: testContractID, PriceYes: price}}, start.Add(time.Duration(i)*15*time.Minute))
+	}
+	h.record([]MarketState{{ContractID: testContractID, PriceYes: 0.70}}, start.Add(time.Hour))
+
+	// Two days later both hours are rolled up.
+	if err := h.Rollup(start.Add(50 * time.Hour)); err != nil {
+		t.Fatalf("Rollup() error = %v", err)
+	}
+	want := PriceAggregate{Start: start, Open: 0.40, High: 0.60, Low: 0.30, Close: 0.50, Samples: 4}
+	if got := h.hourly[testContractID]; len(got) != 2 || got[0] != want || len(h.samples) != 0 {
+		t.Fatalf("hourly = %+v, raw = %v, want %+v and the 11:00 hour", got, h.samples, want)
+	}
+
+	// Four days later the hours become one day, which survives a restart.
+	if err := h.Rollup(start.Add(4 * 24 * time.Hour)); err != nil {
+		t.Fatalf("Rollup() error = %v", err)
+	}
+	loaded, err := NewPriceHistory(&FactoryService{}, DefaultPriceRetention, path, h.logger)
+	if err != nil {
+		t.Fatalf("NewPriceHistory() error = %v", err)
+	}
+	want = PriceAggregate{Start: start.Truncate(24 * time.Hour), Open: 0.40, High: 0.70, Low: 0.30, Close: 0.70, Samples: 5}
+	if got := loaded.daily[testContractID]; len(got) != 1 || !got[0].Start.Equal(want.Start) || got[0].Close != want.Close || got[0].Samples != want.Samples {
+		t.Fatalf("daily = %+v, want %+v", got, want)
+	}
+	if series := loaded.Series(testContractID); len(series) != 1 || series[0].PriceYes != 0.70 {
+		t.Errorf("Series() = %+v, want the daily close", series)
+	}
+
+	// Days past the daily retention are dropped.
+	if err := h.Rollup(start.Add(11 * 24 * time.Hour)); err != nil {
+		t.Fatalf("Rollup() error = %v", err)
+	}
+	if series := h.Series(testContractID); len(series) != 0 {
+		t.Errorf("Series() = %+v, want none", series)
+	}
+}
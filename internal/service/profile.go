@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ProfileSettings records the accounts that hid their public profile. Only the
+// account itself, signed in via SEP-10, may change its setting. Opt-outs are kept in
+// memory, optionally persisted to a JSON file.
+type ProfileSettings struct {
+	path   string // Empty keeps opt-outs in memory only
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	hidden map[string]time.Time // account -> when the profile was hidden
+}
+
+// NewProfileSettings creates profile settings and loads existing opt-outs from path, if set.
+func NewProfileSettings(path string, logger *slog.Logger) (*ProfileSettings, error) {
+	if logger == nil {
+		panic("NewProfileSettings: logger must not be nil")
+	}
+
+	p := &ProfileSettings{
+		path:   path,
+		logger: logger,
+		hidden: make(map[string]time.Time),
+	}
+	if path == "" {
+		return p, nil
+	}
+	if _, err := readJSONFile(path, &p.hidden); err != nil {
+		return nil, fmt.Errorf("failed to load profiles file: %w", err)
+	}
+	if p.hidden == nil {
+		p.hidden = make(map[string]time.Time)
+	}
+	return p, nil
+}
+
+// Hidden reports whether an account hid its public profile.
+func (p *ProfileSettings) Hidden(account string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.hidden[account]
+	return ok
+}
+
+// SetHidden hides or shows an account's public profile.
+func (p *ProfileSettings) SetHidden(account string, hidden bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.hidden[account]; ok == hidden {
+		return nil
+	}
+	if hidden {
+		p.hidden[account] = time.Now()
+	} else {
+		delete(p.hidden, account)
+	}
+	return p.saveLocked()
+}
+
+func (p *ProfileSettings) saveLocked() error {
+	if p.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(p.path, p.hidden); err != nil {
+		return fmt.Errorf("failed to save profiles file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// QuarantineReason is why strict mode hides a market from public lists.
+type QuarantineReason string
+
+const (
+	QuarantineMissingMetadata  QuarantineReason = "missing metadata"
+	QuarantineInvalidMetadata  QuarantineReason = "invalid metadata"
+	QuarantineUnknownWasm      QuarantineReason = "unknown WASM hash"
+	QuarantineUndecodableState QuarantineReason = "undecodable state"
+)
+
+// QuarantineIssue is one problem found with a quarantined market.
+type QuarantineIssue struct {
+	Reason QuarantineReason
+	Detail string
+	Since  time.Time
+}
+
+// QuarantinedMarket is a market hidden from public lists, with what is wrong with it.
+type QuarantinedMarket struct {
+	ContractID string
+	Issues     []QuarantineIssue
+}
+
+// Quarantine tracks markets whose data cannot be trusted: metadata that is missing or
+// does not validate, a WASM build not in the contract version registry, or state that
+// does not decode. Such markets would otherwise be listed with placeholder questions.
+// Issues are cleared as soon as a later check passes.
+type Quarantine struct {
+	mu      sync.Mutex
+	markets map[string]map[QuarantineReason]QuarantineIssue
+}
+
+// NewQuarantine creates an empty quarantine.
+func NewQuarantine() *Quarantine {
+	return &Quarantine{markets: make(map[string]map[QuarantineReason]QuarantineIssue)}
+}
+
+// Flag records an issue with a market. A market flagged again for the same reason
+// keeps the time it was first flagged.
+func (q *Quarantine) Flag(contractID string, reason QuarantineReason, detail string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	issues := q.markets[contractID]
+	if issues == nil {
+		issues = make(map[QuarantineReason]QuarantineIssue)
+		q.markets[contractID] = issues
+	}
+	since := time.Now()
+	if prev, ok := issues[reason]; ok {
+		since = prev.Since
+	}
+	issues[reason] = QuarantineIssue{Reason: reason, Detail: detail, Since: since}
+}
+
+// Clear removes an issue with a market; the market is released when none are left.
+func (q *Quarantine) Clear(contractID string, reason QuarantineReason) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	issues := q.markets[contractID]
+	delete(issues, reason)
+	if len(issues) == 0 {
+		delete(q.markets, contractID)
+	}
+}
+
+// Contains reports whether a market is quarantined.
+func (q *Quarantine) Contains(contractID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.markets[contractID]) > 0
+}
+
+// Len returns the number of quarantined markets.
+func (q *Quarantine) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.markets)
+}
+
+// Markets returns the quarantined markets by contract ID, their issues by reason.
+func (q *Quarantine) Markets() []QuarantinedMarket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	markets := make([]QuarantinedMarket, 0, len(q.markets))
+	for id, issues := range q.markets {
+		m := QuarantinedMarket{ContractID: id}
+		for _, issue := range issues {
+			m.Issues = append(m.Issues, issue)
+		}
+		slices.SortFunc(m.Issues, func(a, b QuarantineIssue) int { return cmp.Compare(a.Reason, b.Reason) })
+		markets = append(markets, m)
+	}
+	slices.SortFunc(markets, func(a, b QuarantinedMarket) int { return cmp.Compare(a.ContractID, b.ContractID) })
+	return markets
+}
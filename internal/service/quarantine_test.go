@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQuarantineFlagAndClear(t *testing.T) {
+	q := NewQuarantine()
+	q.Flag("CB", QuarantineMissingMetadata, "no hash")
+	q.Flag("CA", QuarantineUnknownWasm, "ab12")
+	q.Flag("CA", QuarantineInvalidMetadata, "question is required")
+
+	markets := q.Markets()
+	if len(markets) != 2 || markets[0].ContractID != "CA" || markets[1].ContractID != "CB" {
+		t.Fatalf("Markets() = %+v, want CA and CB in order", markets)
+	}
+	if issues := markets[0].Issues; len(issues) != 2 || issues[0].Reason != QuarantineInvalidMetadata || issues[1].Reason != QuarantineUnknownWasm {
+		t.Errorf("issues of CA = %+v, want invalid metadata then unknown WASM", issues)
+	}
+
+	since := markets[1].Issues[0].Since
+	time.Sleep(time.Millisecond)
+	q.Flag("CB", QuarantineMissingMetadata, "still no hash")
+	if got := q.Markets()[1].Issues[0]; !got.Since.Equal(since) || got.Detail != "still no hash" {
+		t.Errorf("reflagged issue = %+v, want the first flag time and the new detail", got)
+	}
+
+	q.Clear("CA", QuarantineUnknownWasm)
+	if !q.Contains("CA") {
+		t.Error("CA should stay quarantined while its metadata is invalid")
+	}
+	q.Clear("CA", QuarantineInvalidMetadata)
+	q.Clear("CC", QuarantineInvalidMetadata)
+	if q.Contains("CA") || q.Len() != 1 {
+		t.Errorf("after clearing CA: Contains(CA) = %v, Len() = %d, want released and 1 left", q.Contains("CA"), q.Len())
+	}
+}
+
+func TestFactoryServiceInspect(t *testing.T) {
+	versions := newTestVersionRegistry(t, "", &fakeWasmHashes{})
+	s := &FactoryService{versions: versions}
+	known := MarketState{ContractID: "CA", WasmHash: testWasmV1, ContractVersion: "v1.0.0"}
+	unknown := MarketState{ContractID: "CA", WasmHash: testWasmV2}
+
+	// Disabled: nothing is recorded
+	s.inspect("CA", unknown, nil)
+
+	s.EnableStrictMarkets()
+	s.inspect("CA", unknown, nil)
+	if s.Quarantine().Contains("CA") {
+		t.Error("no versions are registered, so no build is unknown")
+	}
+	if _, err := versions.Register(ContractVersion{WasmHash: testWasmV1, Version: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	s.inspect("CA", unknown, nil)
+	if !s.Quarantine().Contains("CA") {
+		t.Error("a market running an unregistered build should be quarantined")
+	}
+	s.inspect("CA", known, nil)
+	if s.Quarantine().Contains("CA") {
+		t.Error("a market running a registered build should be released")
+	}
+
+	s.inspect("CB", MarketState{}, fmt.Errorf("%w: expected 4 elements in state tuple, got 2", ErrUndecodableState))
+	s.inspect("CC", MarketState{}, errors.New("failed to simulate get_state: timeout"))
+	if !s.Quarantine().Contains("CB") || s.Quarantine().Contains("CC") {
+		t.Errorf("Markets() = %+v, want only the undecodable market, not the one the RPC failed for", s.Quarantine().Markets())
+	}
+}
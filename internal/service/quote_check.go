@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/mtlprog/total/internal/lmsr"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// QuoteMismatchTolerance is the relative difference between a contract quote and the
+// Go LMSR estimate above which they are reported as mismatched. The contract works in
+// 7-decimal fixed point, so small differences are rounding.
+const QuoteMismatchTolerance = 0.001
+
+// QuoteEstimate is the Go LMSR price of a buy, shown next to the contract's quote.
+// The contract's cost is authoritative: it is what the buy is charged.
+type QuoteEstimate struct {
+	Cost       float64 // collateral
+	PriceAfter float64 // probability of the bought outcome after the buy
+	Difference float64 // contract cost minus estimated cost, in collateral
+	Mismatch   bool    // Difference exceeds QuoteMismatchTolerance of the contract cost
+}
+
+// EstimateQuote prices a buy the contract quoted with the Go LMSR calculator, at the
+// market's liquidity parameter and sold quantities. A mismatch means the two
+// implementations disagree, or a trade landed between the quote and the estimate.
+func (s *MarketService) EstimateQuote(ctx context.Context, contractID string, outcome model.Outcome, amount float64, quote *Quote) (*QuoteEstimate, error) {
+	liquidity, err := s.liquidityParam(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	yesSold, noSold, err := s.soldQuantities(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	calc, err := lmsr.New(liquidity)
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := estimateQuote(calc, yesSold, noSold, outcome, amount, quote)
+	if err != nil {
+		return nil, err
+	}
+	if estimate.Mismatch {
+		s.logger.Warn("contract quote differs from LMSR estimate",
+			"contract_id", contractID, "outcome", outcome, "amount", amount,
+			"contract_cost", float64(quote.Cost)/float64(soroban.ScaleFactor), "estimated_cost", estimate.Cost)
+	}
+	return estimate, nil
+}
+
+func estimateQuote(calc *lmsr.Calculator, yesSold, noSold float64, outcome model.Outcome, amount float64, quote *Quote) (*QuoteEstimate, error) {
+	cost, _, priceAfter, err := calc.Quote(yesSold, noSold, amount, string(outcome))
+	if err != nil {
+		return nil, err
+	}
+	contractCost := float64(quote.Cost) / float64(soroban.ScaleFactor)
+	diff := contractCost - cost
+	// One stroop of rounding per side is never a mismatch, however small the trade.
+	tolerance := max(QuoteMismatchTolerance*contractCost, 2/float64(soroban.ScaleFactor))
+	return &QuoteEstimate{
+		Cost:       cost,
+		PriceAfter: priceAfter,
+		Difference: diff,
+		Mismatch:   math.Abs(diff) > tolerance,
+	}, nil
+}
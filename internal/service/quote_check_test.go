@@ -0,0 +1,49 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mtlprog/total/internal/lmsr"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+func TestEstimateQuote(t *testing.T) {
+	calc, err := lmsr.New(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, err := calc.CalculateCost(20, 5, 10, "YES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaled := int64(math.Round(cost * float64(soroban.ScaleFactor)))
+
+	tests := []struct {
+		name         string
+		contractCost int64
+		wantMismatch bool
+	}{
+		{"same cost", scaled, false},
+		{"fixed-point rounding", scaled + 1, false},
+		{"within tolerance", scaled + scaled/2000, false},
+		{"one percent off", scaled + scaled/100, true},
+		{"cheaper than estimated", scaled - scaled/100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := estimateQuote(calc, 20, 5, model.OutcomeYes, 10, &Quote{Cost: tt.contractCost})
+			if err != nil {
+				t.Fatalf("estimateQuote() error = %v", err)
+			}
+			if got.Mismatch != tt.wantMismatch || math.Abs(got.Cost-cost) > 1e-12 {
+				t.Errorf("estimateQuote() = %+v, want cost %v and mismatch %v", got, cost, tt.wantMismatch)
+			}
+			wantDiff := float64(tt.contractCost)/float64(soroban.ScaleFactor) - cost
+			if math.Abs(got.Difference-wantDiff) > 1e-9 {
+				t.Errorf("Difference = %v, want %v", got.Difference, wantDiff)
+			}
+		})
+	}
+}
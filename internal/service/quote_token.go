@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuoteTokenTTL is how long a quote the user saw can be used to build a buy transaction.
+const QuoteTokenTTL = 60 * time.Second
+
+var (
+	ErrQuoteTokenRequired = errors.New("quote token required")
+	ErrQuoteTokenInvalid  = errors.New("invalid quote token")
+	ErrQuoteTokenExpired  = errors.New("quote token expired")
+)
+
+// quoteClaims is the signed content of a quote token.
+type quoteClaims struct {
+	ContractID string `json:"c"`
+	Outcome    string `json:"o"`
+	Amount     int64  `json:"a"` // Scaled by 10^7
+	Cost       int64  `json:"q"` // Scaled by 10^7
+	ExpiresAt  int64  `json:"e"` // Unix seconds
+}
+
+// QuoteSigner issues and verifies HMAC-signed quote tokens.
+// Tokens are stateless: any instance with the same secret can verify them.
+type QuoteSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewQuoteSigner creates a quote signer. An empty secret generates a random key,
+// so tokens do not survive a restart and are not shared between replicas.
+func NewQuoteSigner(secret string) (*QuoteSigner, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate quote token key: %w", err)
+		}
+	}
+	return &QuoteSigner{key: key, ttl: QuoteTokenTTL}, nil
+}
+
+// sign returns a token for claims, valid for the signer's TTL from now.
+func (s *QuoteSigner) sign(claims quoteClaims, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(s.ttl).Truncate(time.Second)
+	claims.ExpiresAt = expiresAt.Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode quote token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.mac(encoded), expiresAt.UTC(), nil
+}
+
+// verify checks the token signature and expiry and returns its claims.
+func (s *QuoteSigner) verify(token string, now time.Time) (quoteClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(encoded))) {
+		return quoteClaims{}, ErrQuoteTokenInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return quoteClaims{}, ErrQuoteTokenInvalid
+	}
+	var claims quoteClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return quoteClaims{}, ErrQuoteTokenInvalid
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return quoteClaims{}, ErrQuoteTokenExpired
+	}
+	return claims, nil
+}
+
+func (s *QuoteSigner) mac(encoded string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuoteSigner(t *testing.T) {
+	signer, err := NewQuoteSigner("test-secret")
+	if err != nil {
+		t.Fatalf("NewQuoteSigner() error = %v", err)
+	}
+	other, err := NewQuoteSigner("other-secret")
+	if err != nil {
+		t.Fatalf("NewQuoteSigner() error = %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	claims := quoteClaims{ContractID: "CABC", Outcome: "YES", Amount: 10_000_000, Cost: 5_000_000}
+	token, expiresAt, err := signer.sign(claims, now)
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+	if want := now.Add(QuoteTokenTTL).UTC(); !expiresAt.Equal(want) {
+		t.Errorf("sign() expiresAt = %v, want %v", expiresAt, want)
+	}
+
+	tests := []struct {
+		name    string
+		signer  *QuoteSigner
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", signer, token, now, nil},
+		{"valid at expiry", signer, token, now.Add(QuoteTokenTTL), nil},
+		{"expired", signer, token, now.Add(QuoteTokenTTL + time.Second), ErrQuoteTokenExpired},
+		{"wrong key", other, token, now, ErrQuoteTokenInvalid},
+		{"tampered payload", signer, "x" + token, now, ErrQuoteTokenInvalid},
+		{"no signature", signer, "abc", now, ErrQuoteTokenInvalid},
+		{"empty", signer, "", now, ErrQuoteTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.signer.verify(tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.ContractID != claims.ContractID || got.Cost != claims.Cost || got.Amount != claims.Amount) {
+				t.Errorf("verify() claims = %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateLimitKeys bounds the number of tracked keys; idle keys are pruned first.
+const maxRateLimitKeys = 100000
+
+// RateLimiter is an in-memory token bucket per key (e.g. client IP or account):
+// each key may make limit requests per window, refilled continuously.
+type RateLimiter struct {
+	limit  float64
+	window time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastPrune time.Time
+}
+
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing limit requests per window and key.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if limit <= 0 || window <= 0 {
+		panic("NewRateLimiter: limit and window must be positive")
+	}
+	return &RateLimiter{
+		limit:   float64(limit),
+		window:  window,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// Allow takes a token for key. When none is left it returns false and how long
+// until the next request would be allowed.
+func (l *RateLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) >= l.window || len(l.buckets) >= maxRateLimitKeys {
+		l.pruneLocked(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitKeys {
+			// Every tracked key is active: refuse new keys rather than grow unbounded.
+			return false, l.window
+		}
+		b = &rateBucket{tokens: l.limit, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.limit * float64(l.window))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *RateLimiter) refill(b *rateBucket, now time.Time) float64 {
+	elapsed := now.Sub(b.updated)
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return min(l.limit, b.tokens+elapsed.Seconds()/l.window.Seconds()*l.limit)
+}
+
+// pruneLocked drops keys whose bucket has refilled completely: they behave like new keys.
+func (l *RateLimiter) pruneLocked(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.limit {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := NewRateLimiter(3, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		if ok, _ := l.Allow("ip:1", now); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, retry := l.Allow("ip:1", now)
+	if ok {
+		t.Fatal("4th request within the window should be limited")
+	}
+	if retry != 20*time.Second {
+		t.Errorf("retry after = %v, want 20s", retry)
+	}
+
+	if ok, _ := l.Allow("ip:2", now); !ok {
+		t.Error("other keys should not be limited")
+	}
+
+	if ok, _ := l.Allow("ip:1", now.Add(20*time.Second)); !ok {
+		t.Error("a token should be refilled after limit/window")
+	}
+	if ok, _ := l.Allow("ip:1", now.Add(21*time.Second)); ok {
+		t.Error("only one token should have been refilled")
+	}
+}
+
+func TestRateLimiterPrunesIdleKeys(t *testing.T) {
+	l := NewRateLimiter(2, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l.Allow("a", now)
+	l.Allow("b", now)
+	l.Allow("b", now.Add(2*time.Minute))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("idle key should be pruned")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("active key should be kept")
+	}
+}
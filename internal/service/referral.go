@@ -0,0 +1,268 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// ReferralRefreshInterval is how often built referral transactions are matched against trades.
+	ReferralRefreshInterval = 5 * time.Minute
+	// ReferralMemoPrefix marks the referral code in a trade transaction's text memo.
+	ReferralMemoPrefix = "ref:"
+
+	minReferralCodeLength = 3
+	maxReferralCodeLength = 20 // "ref:" + 20 fits the 28-byte text memo
+
+	// referralTrackTTL bounds how long a built transaction waits for its trade event.
+	// Trade events are read with a ~24h lookback, so older entries can never match.
+	referralTrackTTL    = 48 * time.Hour
+	maxTrackedReferrals = 10000
+)
+
+// ErrInvalidReferralCode is returned for malformed referral codes.
+var ErrInvalidReferralCode = fmt.Errorf("referral code must be %d-%d letters, digits, '-' or '_'", minReferralCodeLength, maxReferralCodeLength)
+
+// ValidateReferralCode checks that a referral code is short and URL/memo safe.
+func ValidateReferralCode(code string) error {
+	if len(code) < minReferralCodeLength || len(code) > maxReferralCodeLength {
+		return ErrInvalidReferralCode
+	}
+	for _, c := range code {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return ErrInvalidReferralCode
+		}
+	}
+	return nil
+}
+
+// referralMemo returns the text memo attributing a trade to code, or "" without a referral.
+func referralMemo(code string) string {
+	if code == "" {
+		return ""
+	}
+	return ReferralMemoPrefix + code
+}
+
+// ReferrerStats is the attributed trading activity of one referral code.
+type ReferrerStats struct {
+	Code      string    `json:"code"`
+	Built     int       `json:"built"`  // Trade transactions built with the code
+	Trades    int       `json:"trades"` // Trades confirmed on-chain
+	Volume    float64   `json:"volume"` // Collateral paid (buy) or received (sell) in confirmed trades
+	Traders   []string  `json:"traders,omitempty"`
+	LastTrade time.Time `json:"last_trade,omitzero"`
+}
+
+// trackedReferral is a built trade transaction waiting for its trade event.
+type trackedReferral struct {
+	Code       string    `json:"code"`
+	ContractID string    `json:"contract_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// referralFile is the on-disk format of the referral store.
+type referralFile struct {
+	Referrers map[string]*ReferrerStats  `json:"referrers"`
+	Tracked   map[string]trackedReferral `json:"tracked"` // tx hash -> pending attribution
+}
+
+// ReferralService attributes trades to referral codes. Trade transactions built with a
+// referral carry a "ref:CODE" memo; their hashes are remembered and joined with the
+// contract's trade events once the user submits them. State is kept in memory,
+// optionally persisted to a JSON file.
+type ReferralService struct {
+	eventService      *EventService
+	networkPassphrase string
+	path              string // Empty keeps referrals in memory only
+	logger            *slog.Logger
+
+	mu        sync.Mutex
+	referrers map[string]*ReferrerStats
+	tracked   map[string]trackedReferral
+}
+
+// NewReferralService creates a referral service and loads existing state from path, if set.
+func NewReferralService(eventService *EventService, networkPassphrase, path string, logger *slog.Logger) (*ReferralService, error) {
+	if eventService == nil {
+		panic("NewReferralService: eventService must not be nil")
+	}
+	if logger == nil {
+		panic("NewReferralService: logger must not be nil")
+	}
+
+	s := &ReferralService{
+		eventService:      eventService,
+		networkPassphrase: networkPassphrase,
+		path:              path,
+		logger:            logger,
+		referrers:         make(map[string]*ReferrerStats),
+		tracked:           make(map[string]trackedReferral),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var file referralFile
+	if _, err := readJSONFile(path, &file); err != nil {
+		return nil, fmt.Errorf("failed to load referrals file: %w", err)
+	}
+	for code, stats := range file.Referrers {
+		s.referrers[code] = stats
+	}
+	for hash, t := range file.Tracked {
+		s.tracked[hash] = t
+	}
+	return s, nil
+}
+
+// Track remembers a built trade transaction if its memo carries a referral code.
+// Transactions without a referral, and calls other than buy/sell, are ignored.
+func (s *ReferralService) Track(txXDR string) error {
+	invoke, err := soroban.DecodeInvokeTx(txXDR)
+	if err != nil {
+		return err
+	}
+	if invoke.FunctionName != string(TradeKindBuy) && invoke.FunctionName != string(TradeKindSell) {
+		return nil
+	}
+	code, ok := strings.CutPrefix(invoke.Memo, ReferralMemoPrefix)
+	if !ok || ValidateReferralCode(code) != nil {
+		return nil
+	}
+	hash, err := soroban.TransactionHash(txXDR, s.networkPassphrase)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tracked[hash]; exists {
+		return nil
+	}
+	if len(s.tracked) >= maxTrackedReferrals {
+		return errors.New("too many referral transactions awaiting confirmation")
+	}
+	s.tracked[hash] = trackedReferral{Code: code, ContractID: invoke.ContractID, CreatedAt: time.Now().UTC()}
+	s.statsLocked(code).Built++
+	return s.saveLocked()
+}
+
+// Refresh matches tracked transactions against trade events and credits confirmed trades.
+// Entries older than the event lookback window are dropped.
+func (s *ReferralService) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	contracts := make(map[string]bool)
+	for _, t := range s.tracked {
+		contracts[t.ContractID] = true
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for contractID := range contracts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		events, err := s.eventService.GetTradeEvents(ctx, contractID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", contractID, err))
+			continue
+		}
+		s.credit(events)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-referralTrackTTL)
+	for hash, t := range s.tracked {
+		if t.CreatedAt.Before(cutoff) {
+			delete(s.tracked, hash)
+		}
+	}
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// credit attributes trade events of tracked transactions to their referral codes.
+func (s *ReferralService) credit(events []TradeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, evt := range events {
+		t, ok := s.tracked[evt.TxHash]
+		if evt.TxHash == "" || !ok {
+			continue
+		}
+		delete(s.tracked, evt.TxHash)
+
+		stats := s.statsLocked(t.Code)
+		stats.Trades++
+		stats.Volume += evt.Cost
+		if !slices.Contains(stats.Traders, evt.User) {
+			stats.Traders = append(stats.Traders, evt.User)
+		}
+		if evt.Timestamp.After(stats.LastTrade) {
+			stats.LastTrade = evt.Timestamp
+		}
+		s.logger.Debug("credited referral trade", "code", t.Code, "tx_hash", evt.TxHash, "cost", evt.Cost)
+	}
+}
+
+// Report returns per-referrer stats, highest volume first.
+func (s *ReferralService) Report() []ReferrerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := make([]ReferrerStats, 0, len(s.referrers))
+	for _, stats := range s.referrers {
+		r := *stats
+		r.Traders = slices.Clone(stats.Traders)
+		report = append(report, r)
+	}
+	slices.SortFunc(report, func(a, b ReferrerStats) int {
+		if c := cmp.Compare(b.Volume, a.Volume); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Code, b.Code)
+	})
+	return report
+}
+
+// Pending returns the number of built referral transactions not yet seen on-chain.
+func (s *ReferralService) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tracked)
+}
+
+func (s *ReferralService) statsLocked(code string) *ReferrerStats {
+	stats, ok := s.referrers[code]
+	if !ok {
+		stats = &ReferrerStats{Code: code}
+		s.referrers[code] = stats
+	}
+	return stats
+}
+
+// saveLocked writes the store to the file atomically. Must be called with mu held.
+func (s *ReferralService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(s.path, referralFile{Referrers: s.referrers, Tracked: s.tracked}); err != nil {
+		return fmt.Errorf("failed to save referrals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestValidateReferralCode(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantErr bool
+	}{
+		{"alice", false},
+		{"MTL_crew-2", false},
+		{"abcdefghijklmnopqrst", false},
+		{"ab", true},
+		{"abcdefghijklmnopqrstu", true},
+		{"with space", true},
+		{"émile", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := ValidateReferralCode(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateReferralCode(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidReferralCode) {
+				t.Errorf("ValidateReferralCode(%q) error = %v, want ErrInvalidReferralCode", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestReferralServiceCredit(t *testing.T) {
+	s := &ReferralService{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		referrers: make(map[string]*ReferrerStats),
+		tracked: map[string]trackedReferral{
+			"hash1": {Code: "alice", ContractID: testContractID},
+			"hash2": {Code: "alice", ContractID: testContractID},
+			"hash3": {Code: "bob", ContractID: testContractID},
+		},
+	}
+	ts := time.Unix(1_700_000_000, 0).UTC()
+	events := []TradeEvent{
+		{Kind: TradeKindBuy, User: "GA", Cost: 10, TxHash: "hash1", Timestamp: ts},
+		{Kind: TradeKindSell, User: "GA", Cost: 4, TxHash: "hash2", Timestamp: ts.Add(time.Hour)},
+		{Kind: TradeKindBuy, User: "GB", Cost: 50, TxHash: "other", Timestamp: ts},
+		{Kind: TradeKindBuy, User: "GC", Cost: 7, TxHash: "", Timestamp: ts},
+	}
+
+	s.credit(events)
+	s.credit(events) // Matched transactions are credited once
+
+	report := s.Report()
+	if len(report) != 1 {
+		t.Fatalf("Report() = %+v, want 1 referrer", report)
+	}
+	got := report[0]
+	if got.Code != "alice" || got.Trades != 2 || got.Volume != 14 || len(got.Traders) != 1 || !got.LastTrade.Equal(ts.Add(time.Hour)) {
+		t.Errorf("Report()[0] = %+v", got)
+	}
+	if s.Pending() != 1 {
+		t.Errorf("Pending() = %d, want 1", s.Pending())
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ResolutionEvent is a parsed "resolve" event emitted when the oracle resolves a market.
+type ResolutionEvent struct {
+	Oracle    string    // G... address
+	Outcome   string    // "YES" or "NO"
+	Timestamp time.Time // ledger close time
+	Ledger    uint32
+	TxHash    string // empty when the RPC does not report transaction hashes
+}
+
+// GetResolution finds the resolve event of a market within the RPC retention window.
+// Returns false when the market was not resolved within the window.
+// Found events are cached for the lifetime of the process, misses for eventCacheTTL.
+func (s *EventService) GetResolution(ctx context.Context, contractID string) (ResolutionEvent, bool, error) {
+	s.resolutionsMu.RLock()
+	cached, ok := s.resolutions[contractID]
+	missedAt, missed := s.notResolved[contractID]
+	s.resolutionsMu.RUnlock()
+	if ok {
+		return cached, true, nil
+	}
+	if missed && time.Since(missedAt) < eventCacheTTL {
+		return ResolutionEvent{}, false, nil
+	}
+
+	resolution, found, err := s.findResolution(ctx, contractID)
+	if err != nil {
+		return ResolutionEvent{}, false, err
+	}
+
+	s.resolutionsMu.Lock()
+	defer s.resolutionsMu.Unlock()
+	if !found {
+		s.notResolved[contractID] = time.Now()
+		return ResolutionEvent{}, false, nil
+	}
+	delete(s.notResolved, contractID)
+	s.resolutions[contractID] = resolution
+	return resolution, true, nil
+}
+
+func (s *EventService) findResolution(ctx context.Context, contractID string) (ResolutionEvent, bool, error) {
+	health, err := s.sorobanClient.GetHealth(ctx)
+	if err != nil {
+		return ResolutionEvent{}, false, fmt.Errorf("failed to get RPC health: %w", err)
+	}
+
+	resolveTopicXDR, err := encodeSymbolBase64("resolve")
+	if err != nil {
+		return ResolutionEvent{}, false, fmt.Errorf("failed to encode resolve topic: %w", err)
+	}
+	filters := []soroban.EventFilter{
+		soroban.NewContractEventFilter([]string{contractID}, []string{resolveTopicXDR, soroban.TopicWildcard}),
+	}
+
+	startLedger := max(health.OldestLedger, 1)
+	cursor := ""
+	for page := 0; page < maxEventPages; page++ {
+		result, err := s.sorobanClient.GetEvents(ctx, startLedger, filters, cursor)
+		if err != nil {
+			return ResolutionEvent{}, false, fmt.Errorf("failed to get events: %w", err)
+		}
+		for _, evt := range result.Events {
+			if !evt.InSuccessfulContractCall {
+				continue
+			}
+			resolution, err := parseResolutionEvent(evt)
+			if err != nil {
+				s.logger.Warn("failed to parse resolve event", "id", evt.ID, "error", err)
+				continue
+			}
+			return resolution, true, nil
+		}
+
+		if len(result.Events) < soroban.EventsPageLimit || result.Cursor == "" || result.Cursor == cursor {
+			break
+		}
+		cursor = result.Cursor
+	}
+	return ResolutionEvent{}, false, nil
+}
+
+func parseResolutionEvent(evt soroban.ContractEvent) (ResolutionEvent, error) {
+	if len(evt.Topic) < 2 {
+		return ResolutionEvent{}, fmt.Errorf("expected at least 2 topics, got %d", len(evt.Topic))
+	}
+
+	kindVal, err := soroban.ParseReturnValue(evt.Topic[0])
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to parse kind topic: %w", err)
+	}
+	if kindVal.Type != xdr.ScValTypeScvSymbol || kindVal.Sym == nil || string(*kindVal.Sym) != "resolve" {
+		return ResolutionEvent{}, fmt.Errorf("expected resolve symbol topic")
+	}
+
+	// Topic[1]: address (oracle)
+	oracleVal, err := soroban.ParseReturnValue(evt.Topic[1])
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to parse oracle topic: %w", err)
+	}
+	oracle, err := soroban.DecodeAddress(oracleVal)
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to decode oracle address: %w", err)
+	}
+
+	// Value: u32 (winning outcome)
+	outcomeVal, err := soroban.ParseReturnValue(evt.Value)
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to parse event data: %w", err)
+	}
+	outcomeU32, err := soroban.DecodeU32(outcomeVal)
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to decode outcome: %w", err)
+	}
+	outcome, err := soroban.U32ToOutcome(outcomeU32)
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to convert outcome: %w", err)
+	}
+
+	ts, err := time.Parse(time.RFC3339, evt.LedgerClosedAt)
+	if err != nil {
+		return ResolutionEvent{}, fmt.Errorf("failed to parse ledger close time %q: %w", evt.LedgerClosedAt, err)
+	}
+
+	return ResolutionEvent{
+		Oracle:    oracle,
+		Outcome:   outcome,
+		Timestamp: ts,
+		Ledger:    evt.Ledger,
+		TxHash:    evt.TxHash,
+	}, nil
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// RolloverInterval is how often recurring series are checked for a resolved last market.
+const RolloverInterval = 5 * time.Minute
+
+// NextEndDate suggests the close time of the next round of a recurring market: the
+// same weekday next week for markets that ran about a week, otherwise the same day
+// next month, moved forward until it is in the future.
+func NextEndDate(metadata model.MarketMetadata, now time.Time) time.Time {
+	end := metadata.EndDate
+	if end.IsZero() {
+		return now.AddDate(0, 1, 0).Truncate(time.Hour)
+	}
+	weekly := !metadata.CreatedAt.IsZero() && end.Sub(metadata.CreatedAt) <= 8*24*time.Hour
+	for !end.After(now) {
+		if weekly {
+			end = end.AddDate(0, 0, 7)
+		} else {
+			end = end.AddDate(0, 1, 0)
+		}
+	}
+	return end
+}
+
+// NextPeriodMetadata returns the metadata of the next round of a recurring market:
+// the same question and rules with the next close time. A condition on another market
+// is dropped, since it belongs to the period it was written for.
+func NextPeriodMetadata(metadata model.MarketMetadata, now time.Time) model.MarketMetadata {
+	next := metadata
+	next.EndDate = NextEndDate(metadata, now).UTC()
+	next.CreatedAt = now.UTC()
+	next.Condition = nil
+	return next
+}
+
+// Rollover is the next-period market of a recurring series, prepared when its last
+// market resolved and waiting for the oracle to sign the deploy transaction.
+type Rollover struct {
+	SeriesID     string    `json:"series_id"`
+	FromMarket   string    `json:"from_market"` // Resolved last market it follows
+	MetadataHash string    `json:"metadata_hash"`
+	Account      string    `json:"account"` // Oracle account that signs the deploy
+	Question     string    `json:"question"`
+	EndDate      time.Time `json:"end_date"`
+	PreparedAt   time.Time `json:"prepared_at"`
+}
+
+// RolloverService keeps recurring series going: when the last market of a series
+// resolves, it pins the next period's metadata, puts the deploy transaction in the
+// oracle's pending queue, and adds the market to the series once it is deployed.
+// Prepared rollovers are optionally persisted to a JSON file so a restart does not
+// pin them twice.
+type RolloverService struct {
+	factoryService    *FactoryService
+	ipfsClient        *ipfs.Client
+	series            *SeriesStore
+	pendingTxs        *PendingTxStore
+	networkPassphrase string
+	path              string // Empty keeps rollovers in memory only
+	logger            *slog.Logger
+
+	mu        sync.Mutex
+	rollovers map[string]Rollover // series ID -> prepared rollover
+}
+
+// NewRolloverService creates a rollover service and loads prepared rollovers from
+// path, if set.
+func NewRolloverService(
+	factoryService *FactoryService,
+	ipfsClient *ipfs.Client,
+	series *SeriesStore,
+	pendingTxs *PendingTxStore,
+	networkPassphrase string,
+	path string,
+	logger *slog.Logger,
+) (*RolloverService, error) {
+	if factoryService == nil {
+		panic("NewRolloverService: factoryService must not be nil")
+	}
+	if ipfsClient == nil {
+		panic("NewRolloverService: ipfsClient must not be nil")
+	}
+	if series == nil {
+		panic("NewRolloverService: series must not be nil")
+	}
+	if pendingTxs == nil {
+		panic("NewRolloverService: pendingTxs must not be nil")
+	}
+	if logger == nil {
+		panic("NewRolloverService: logger must not be nil")
+	}
+
+	s := &RolloverService{
+		factoryService:    factoryService,
+		ipfsClient:        ipfsClient,
+		series:            series,
+		pendingTxs:        pendingTxs,
+		networkPassphrase: networkPassphrase,
+		path:              path,
+		logger:            logger,
+		rollovers:         make(map[string]Rollover),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var rollovers []Rollover
+	if _, err := readJSONFile(path, &rollovers); err != nil {
+		return nil, fmt.Errorf("failed to load rollovers file: %w", err)
+	}
+	for _, r := range rollovers {
+		s.rollovers[r.SeriesID] = r
+	}
+	return s, nil
+}
+
+// Refresh rolls over the recurring series whose last market has resolved, keeps the
+// deploy transactions of prepared rollovers in the pending queue, and adds deployed
+// rollovers to their series.
+func (s *RolloverService) Refresh(ctx context.Context) error {
+	if !s.factoryService.HasFactory() {
+		return nil
+	}
+	var recurring []Series
+	for _, ser := range s.series.List() {
+		if ser.Recurring && len(ser.MarketIDs) > 0 {
+			recurring = append(recurring, ser)
+		}
+	}
+	if len(recurring) == 0 {
+		return nil
+	}
+
+	contractIDs, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, contractIDs)
+	if err != nil {
+		s.logger.Warn("rollover: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+	byID := make(map[string]MarketState, len(states))
+	byHash := make(map[string]string, len(states))
+	for _, st := range states {
+		byID[st.ContractID] = st
+		if st.MetadataHash != "" {
+			byHash[st.MetadataHash] = st.ContractID
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	changed := false
+	for _, ser := range recurring {
+		last := ser.MarketIDs[len(ser.MarketIDs)-1]
+		if r, ok := s.rollovers[ser.ID]; ok {
+			if contractID, deployed := byHash[r.MetadataHash]; deployed {
+				if err := s.adoptLocked(ser, contractID); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				delete(s.rollovers, ser.ID)
+				changed = true
+				continue
+			}
+			if r.FromMarket == last {
+				if err := s.ensurePendingLocked(ctx, ser, r); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			// The series was edited since; prepare for its new last market instead.
+			delete(s.rollovers, ser.ID)
+			changed = true
+		}
+
+		st, ok := byID[last]
+		if !ok || !st.Resolved {
+			continue
+		}
+		r, err := s.prepareLocked(ctx, ser, st)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("series %s: %w", ser.ID, err))
+			continue
+		}
+		s.rollovers[ser.ID] = r
+		changed = true
+	}
+
+	if changed {
+		if err := s.saveLocked(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prepareLocked pins the next-period metadata of the series' resolved last market and
+// queues its deploy transaction. Must be called with mu held.
+func (s *RolloverService) prepareLocked(ctx context.Context, ser Series, last MarketState) (Rollover, error) {
+	if last.MetadataHash == "" {
+		return Rollover{}, fmt.Errorf("market %s has no metadata", last.ContractID)
+	}
+	var metadata model.MarketMetadata
+	if err := s.ipfsClient.GetJSON(ctx, last.MetadataHash, &metadata); err != nil {
+		return Rollover{}, fmt.Errorf("failed to fetch metadata of %s: %w", last.ContractID, err)
+	}
+	if metadata.Scalar != nil {
+		return Rollover{}, ErrScalarMarketUnsupported
+	}
+
+	next := NextPeriodMetadata(metadata, time.Now())
+	cid, err := s.ipfsClient.PinJSON(ctx, next)
+	if err != nil {
+		return Rollover{}, fmt.Errorf("failed to pin next-period metadata: %w", err)
+	}
+	r := Rollover{
+		SeriesID:     ser.ID,
+		FromMarket:   last.ContractID,
+		MetadataHash: cid,
+		Question:     next.Question,
+		EndDate:      next.EndDate,
+		PreparedAt:   time.Now().UTC(),
+	}
+	account, err := s.queueDeploy(ctx, ser, r)
+	if err != nil {
+		// Keep the pinned metadata: the transaction is queued again on the next run.
+		s.logger.Warn("rollover: failed to queue deploy transaction", "series", ser.ID, "cid", cid, "error", err)
+	}
+	r.Account = account
+	s.logger.Info("prepared series rollover", "series", ser.ID, "from", last.ContractID, "cid", cid, "end_date", r.EndDate)
+	return r, nil
+}
+
+// ensurePendingLocked queues the deploy transaction of a prepared rollover again when
+// it is no longer pending or has expired. Must be called with mu held.
+func (s *RolloverService) ensurePendingLocked(ctx context.Context, ser Series, r Rollover) error {
+	if r.Account != "" {
+		now := time.Now()
+		for _, tx := range s.pendingTxs.List(r.Account) {
+			if tx.Kind == PendingTxDeploy && tx.Deploy != nil && tx.Deploy.MetadataHash == r.MetadataHash {
+				if !tx.IsExpired(now) {
+					return nil
+				}
+				s.pendingTxs.Remove(r.Account, tx.ID)
+			}
+		}
+	}
+	account, err := s.queueDeploy(ctx, ser, r)
+	if err != nil {
+		return fmt.Errorf("series %s: failed to queue deploy transaction: %w", ser.ID, err)
+	}
+	if account != r.Account {
+		r.Account = account
+		s.rollovers[ser.ID] = r
+		return s.saveLocked()
+	}
+	return nil
+}
+
+// queueDeploy builds the deploy transaction of a rollover and adds it to the oracle's
+// pending queue. Returns the account that must sign it.
+func (s *RolloverService) queueDeploy(ctx context.Context, ser Series, r Rollover) (string, error) {
+	req := ser.deployRequest(r.MetadataHash)
+	result, err := s.factoryService.BuildDeployMarketTx(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	hash, err := soroban.TransactionHash(result.XDR, s.networkPassphrase)
+	if err != nil {
+		s.logger.Warn("rollover: failed to hash deploy transaction", "series", ser.ID, "error", err)
+	}
+	if _, err := s.pendingTxs.Add(PendingTx{
+		Kind:          PendingTxDeploy,
+		Account:       result.SignWith,
+		Description:   fmt.Sprintf("%s — next period: %s (closes %s)", ser.Name, r.Question, r.EndDate.Format("2006-01-02 15:04 UTC")),
+		XDR:           result.XDR,
+		Hash:          hash,
+		AuthExpiresAt: result.AuthExpiresAt,
+		BuiltBy:       result.SignWith, // the oracle configured the series
+		Deploy:        &req,
+	}); err != nil {
+		return "", err
+	}
+	return result.SignWith, nil
+}
+
+// adoptLocked appends a deployed rollover market to its series. Must be called with
+// mu held.
+func (s *RolloverService) adoptLocked(ser Series, contractID string) error {
+	current, err := s.series.Get(ser.ID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(current.MarketIDs, contractID) {
+		return nil
+	}
+	current.MarketIDs = append(current.MarketIDs, contractID)
+	if _, err := s.series.Save(current); err != nil {
+		return fmt.Errorf("series %s: failed to add rolled-over market: %w", ser.ID, err)
+	}
+	s.logger.Info("added rolled-over market to series", "series", ser.ID, "contract_id", contractID)
+	return nil
+}
+
+// List returns the prepared rollovers waiting to be deployed, soonest close first.
+func (s *RolloverService) List() []Rollover {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollovers := make([]Rollover, 0, len(s.rollovers))
+	for _, r := range s.rollovers {
+		rollovers = append(rollovers, r)
+	}
+	slices.SortFunc(rollovers, func(a, b Rollover) int {
+		return cmp.Or(a.EndDate.Compare(b.EndDate), cmp.Compare(a.SeriesID, b.SeriesID))
+	})
+	return rollovers
+}
+
+// saveLocked writes all rollovers to the file atomically. Must be called with mu held.
+func (s *RolloverService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	rollovers := make([]Rollover, 0, len(s.rollovers))
+	for _, r := range s.rollovers {
+		rollovers = append(rollovers, r)
+	}
+	slices.SortFunc(rollovers, func(a, b Rollover) int { return cmp.Compare(a.SeriesID, b.SeriesID) })
+	if err := writeJSONFile(s.path, rollovers); err != nil {
+		return fmt.Errorf("failed to save rollovers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestNextEndDate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		metadata model.MarketMetadata
+		want     time.Time
+	}{
+		{
+			name:     "no end date is a month out",
+			metadata: model.MarketMetadata{},
+			want:     time.Date(2026, 4, 10, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekly market moves by weeks",
+			metadata: model.MarketMetadata{
+				CreatedAt: time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC),
+			},
+			want: time.Date(2026, 3, 15, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "monthly market moves by months",
+			metadata: model.MarketMetadata{
+				CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2026, 2, 28, 23, 59, 0, 0, time.UTC),
+			},
+			want: time.Date(2026, 3, 28, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "future end date is kept",
+			metadata: model.MarketMetadata{EndDate: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+			want:     time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextEndDate(tt.metadata, now); !got.Equal(tt.want) {
+				t.Errorf("NextEndDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextPeriodMetadata(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	metadata := model.MarketMetadata{
+		Question:         "Will BTC close above $100k this week?",
+		Description:      "Resolves by the Sunday close.",
+		ResolutionSource: "coinbase.com",
+		Category:         "crypto",
+		CreatedAt:        time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC),
+		EndDate:          time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC),
+		Condition:        &model.MarketCondition{MarketID: seriesContractID(t, 1), Outcome: "YES"},
+	}
+
+	next := NextPeriodMetadata(metadata, now)
+	if next.Question != metadata.Question || next.Description != metadata.Description || next.Category != metadata.Category {
+		t.Errorf("NextPeriodMetadata() changed the question or rules: %+v", next)
+	}
+	if want := time.Date(2026, 3, 15, 18, 0, 0, 0, time.UTC); !next.EndDate.Equal(want) {
+		t.Errorf("EndDate = %v, want %v", next.EndDate, want)
+	}
+	if !next.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", next.CreatedAt, now)
+	}
+	if next.Condition != nil {
+		t.Errorf("Condition = %+v, want nil", next.Condition)
+	}
+	if metadata.Condition == nil {
+		t.Error("NextPeriodMetadata() modified the source metadata")
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+const (
+	MaxRuleTemplateNameLength = 100
+	maxRuleTemplates          = 200
+	ruleTemplateIDBytes       = 4 // 8 hex characters
+
+	// RuleVarEndDate is filled in from the market's close time, not by the oracle.
+	RuleVarEndDate = "end_date"
+)
+
+var (
+	ErrRuleTemplateNotFound = errors.New("rule template not found")
+	ErrInvalidRuleTemplate  = errors.New("invalid rule template")
+	ErrMissingRuleVariable  = errors.New("rule template variable is required")
+	ErrTooManyRuleTemplates = errors.New("too many rule templates")
+)
+
+// ruleVariable matches a placeholder such as {threshold} in rule template text.
+var ruleVariable = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+
+// RuleTemplate is reusable resolution-rule text for new markets, so markets of the same
+// kind use the same wording. Rules and ResolutionSource may contain {variable}
+// placeholders, filled in when the template is applied; {end_date} is the close time.
+type RuleTemplate struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Rules            string    `json:"rules"`
+	ResolutionSource string    `json:"resolution_source,omitempty"`
+	Category         string    `json:"category,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Validate checks that the template has a name and rules that fit market metadata.
+func (t *RuleTemplate) Validate() error {
+	switch {
+	case t.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRuleTemplate)
+	case len(t.Name) > MaxRuleTemplateNameLength:
+		return fmt.Errorf("%w: name exceeds %d characters", ErrInvalidRuleTemplate, MaxRuleTemplateNameLength)
+	case t.Rules == "":
+		return fmt.Errorf("%w: rules are required", ErrInvalidRuleTemplate)
+	case len(t.Rules) > model.MaxDescriptionLength:
+		return fmt.Errorf("%w: rules exceed %d characters", ErrInvalidRuleTemplate, model.MaxDescriptionLength)
+	case len(t.ResolutionSource) > model.MaxResolutionSourceLength:
+		return fmt.Errorf("%w: resolution source exceeds %d characters", ErrInvalidRuleTemplate, model.MaxResolutionSourceLength)
+	}
+	return nil
+}
+
+// Variables returns the placeholder names the oracle fills in, in order of first
+// appearance. RuleVarEndDate is left out.
+func (t RuleTemplate) Variables() []string {
+	var names []string
+	for _, text := range []string{t.Rules, t.ResolutionSource} {
+		for _, m := range ruleVariable.FindAllStringSubmatch(text, -1) {
+			if name := m[1]; name != RuleVarEndDate && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Apply fills in the template's placeholders from vars and merges it into metadata:
+// the rules are appended to the description, and the resolution source and category
+// are used when the metadata has none. Metadata limits are checked by the caller.
+func (t RuleTemplate) Apply(metadata *model.MarketMetadata, vars map[string]string) error {
+	values := make(map[string]string, len(vars)+1)
+	for name, value := range vars {
+		values[name] = strings.TrimSpace(value)
+	}
+	if !metadata.EndDate.IsZero() {
+		values[RuleVarEndDate] = metadata.EndDate.UTC().Format("2006-01-02 15:04 UTC")
+	}
+
+	var missing []string
+	fill := func(text string) string {
+		return ruleVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			value := values[name]
+			if value == "" {
+				if !slices.Contains(missing, name) {
+					missing = append(missing, name)
+				}
+				return placeholder
+			}
+			return value
+		})
+	}
+	rules := fill(t.Rules)
+	source := fill(t.ResolutionSource)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingRuleVariable, strings.Join(missing, ", "))
+	}
+
+	if metadata.Description == "" {
+		metadata.Description = rules
+	} else {
+		metadata.Description += "\n\n" + rules
+	}
+	if metadata.ResolutionSource == "" {
+		metadata.ResolutionSource = source
+	}
+	if metadata.Category == "" {
+		metadata.Category = t.Category
+	}
+	return nil
+}
+
+// RuleTemplateStore keeps the oracle's rule templates in memory, optionally persisted
+// to a JSON file.
+type RuleTemplateStore struct {
+	path   string // Empty keeps templates in memory only
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	templates map[string]RuleTemplate // id -> template
+}
+
+// NewRuleTemplateStore creates a rule template store and loads existing templates from
+// path, if set.
+func NewRuleTemplateStore(path string, logger *slog.Logger) (*RuleTemplateStore, error) {
+	if logger == nil {
+		panic("NewRuleTemplateStore: logger must not be nil")
+	}
+
+	s := &RuleTemplateStore{
+		path:      path,
+		logger:    logger,
+		templates: make(map[string]RuleTemplate),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var templates []RuleTemplate
+	if _, err := readJSONFile(path, &templates); err != nil {
+		return nil, fmt.Errorf("failed to load rule templates file: %w", err)
+	}
+	for _, t := range templates {
+		s.templates[t.ID] = t
+	}
+	return s, nil
+}
+
+// List returns all templates sorted by name.
+func (s *RuleTemplateStore) List() []RuleTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates := make([]RuleTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, t)
+	}
+	slices.SortFunc(templates, func(a, b RuleTemplate) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), cmp.Compare(a.ID, b.ID))
+	})
+	return templates
+}
+
+// Get returns the template with the given ID.
+func (s *RuleTemplateStore) Get(id string) (RuleTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.templates[id]
+	if !ok {
+		return RuleTemplate{}, ErrRuleTemplateNotFound
+	}
+	return t, nil
+}
+
+// Save creates a template, or replaces the one with the same ID when t.ID is set.
+func (s *RuleTemplateStore) Save(t RuleTemplate) (RuleTemplate, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Rules = strings.TrimSpace(t.Rules)
+	t.ResolutionSource = strings.TrimSpace(t.ResolutionSource)
+	t.Category = strings.TrimSpace(t.Category)
+	if err := t.Validate(); err != nil {
+		return RuleTemplate{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, exists := s.templates[t.ID]
+	switch {
+	case t.ID != "" && !exists:
+		return RuleTemplate{}, ErrRuleTemplateNotFound
+	case t.ID == "":
+		if len(s.templates) >= maxRuleTemplates {
+			return RuleTemplate{}, ErrTooManyRuleTemplates
+		}
+		for {
+			id, err := randomHex(ruleTemplateIDBytes)
+			if err != nil {
+				return RuleTemplate{}, err
+			}
+			if _, taken := s.templates[id]; !taken {
+				t.ID = id
+				break
+			}
+		}
+	}
+	t.UpdatedAt = time.Now().UTC()
+
+	s.templates[t.ID] = t
+	if err := s.saveLocked(); err != nil {
+		if exists {
+			s.templates[t.ID] = previous
+		} else {
+			delete(s.templates, t.ID)
+		}
+		return RuleTemplate{}, err
+	}
+	s.logger.Info("saved rule template", "id", t.ID, "name", t.Name)
+	return t, nil
+}
+
+// Delete removes a template.
+func (s *RuleTemplateStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.templates[id]
+	if !ok {
+		return ErrRuleTemplateNotFound
+	}
+	delete(s.templates, id)
+	if err := s.saveLocked(); err != nil {
+		s.templates[id] = t
+		return err
+	}
+	s.logger.Info("deleted rule template", "id", id, "name", t.Name)
+	return nil
+}
+
+// saveLocked writes all templates to the file atomically. Must be called with mu held.
+func (s *RuleTemplateStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	templates := make([]RuleTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, t)
+	}
+	slices.SortFunc(templates, func(a, b RuleTemplate) int { return cmp.Compare(a.ID, b.ID) })
+	if err := writeJSONFile(s.path, templates); err != nil {
+		return fmt.Errorf("failed to save rule templates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestRuleTemplateApply(t *testing.T) {
+	tmpl := RuleTemplate{
+		Name:             "Price threshold",
+		Rules:            "Resolves YES if {asset} closes above {threshold} on {end_date}, per {asset} on {exchange}.",
+		ResolutionSource: "https://{exchange}/price/{asset}",
+		Category:         "crypto",
+	}
+	if got, want := tmpl.Variables(), []string{"asset", "threshold", "exchange"}; !slices.Equal(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+
+	metadata := model.MarketMetadata{
+		Question: "Will BTC close above $100k?",
+		EndDate:  time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC),
+	}
+	vars := map[string]string{"asset": "BTC", "threshold": " $100,000 ", "exchange": "coinbase.com"}
+	if err := tmpl.Apply(&metadata, vars); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "Resolves YES if BTC closes above $100,000 on 2026-12-31 23:59 UTC, per BTC on coinbase.com."; metadata.Description != want {
+		t.Errorf("Description = %q, want %q", metadata.Description, want)
+	}
+	if want := "https://coinbase.com/price/BTC"; metadata.ResolutionSource != want {
+		t.Errorf("ResolutionSource = %q, want %q", metadata.ResolutionSource, want)
+	}
+	if metadata.Category != "crypto" {
+		t.Errorf("Category = %q, want crypto", metadata.Category)
+	}
+
+	t.Run("keeps the metadata's own fields", func(t *testing.T) {
+		metadata := model.MarketMetadata{Description: "Context.", ResolutionSource: "kraken.com", Category: "btc", EndDate: time.Now()}
+		if err := tmpl.Apply(&metadata, vars); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if metadata.ResolutionSource != "kraken.com" || metadata.Category != "btc" {
+			t.Errorf("Apply() overwrote fields: %+v", metadata)
+		}
+		if want := "Context.\n\nResolves YES if BTC"; metadata.Description[:len(want)] != want {
+			t.Errorf("Description = %q, want rules appended", metadata.Description)
+		}
+	})
+
+	t.Run("reports missing variables", func(t *testing.T) {
+		metadata := model.MarketMetadata{}
+		err := tmpl.Apply(&metadata, map[string]string{"asset": "BTC", "threshold": " "})
+		if !errors.Is(err, ErrMissingRuleVariable) {
+			t.Fatalf("Apply() error = %v, want ErrMissingRuleVariable", err)
+		}
+		if want := "rule template variable is required: threshold, end_date, exchange"; err.Error() != want {
+			t.Errorf("Apply() error = %q, want %q", err, want)
+		}
+		if metadata.Description != "" {
+			t.Errorf("Apply() changed metadata on error: %+v", metadata)
+		}
+	})
+}
+
+func TestRuleTemplateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewRuleTemplateStore(path, logger)
+	if err != nil {
+		t.Fatalf("NewRuleTemplateStore() error = %v", err)
+	}
+
+	sports, err := s.Save(RuleTemplate{Name: " Sports ", Rules: "Resolves by the official result."})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if sports.ID == "" || sports.Name != "Sports" || sports.UpdatedAt.IsZero() {
+		t.Errorf("Save() = %+v, want ID, trimmed name and UpdatedAt", sports)
+	}
+	if _, err := s.Save(RuleTemplate{Name: "Crypto", Rules: "Closing price."}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	sports.Rules = "Resolves by the league's official result."
+	if _, err := s.Save(sports); err != nil {
+		t.Fatalf("Save() update error = %v", err)
+	}
+
+	for _, bad := range []RuleTemplate{
+		{Rules: "No name."},
+		{Name: "No rules"},
+		{ID: "missing", Name: "Unknown", Rules: "Text."},
+	} {
+		if _, err := s.Save(bad); err == nil {
+			t.Errorf("Save(%+v) error = nil, want error", bad)
+		}
+	}
+
+	reloaded, err := NewRuleTemplateStore(path, logger)
+	if err != nil {
+		t.Fatalf("NewRuleTemplateStore() reload error = %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 || list[0].Name != "Crypto" || list[1].Rules != sports.Rules {
+		t.Errorf("List() after reload = %+v, want Crypto then updated Sports", list)
+	}
+
+	if err := reloaded.Delete(sports.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := reloaded.Get(sports.ID); !errors.Is(err, ErrRuleTemplateNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrRuleTemplateNotFound", err)
+	}
+	if err := reloaded.Delete(sports.ID); !errors.Is(err, ErrRuleTemplateNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrRuleTemplateNotFound", err)
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	MaxSeriesNameLength = 100
+	MaxSeriesMarkets    = 50
+	maxSeries           = 200
+	seriesIDBytes       = 4 // 8 hex characters
+)
+
+var (
+	ErrSeriesNotFound = errors.New("series not found")
+	ErrInvalidSeries  = errors.New("invalid series")
+	ErrTooManySeries  = errors.New("too many series")
+)
+
+// Series groups related markets, such as the monthly rounds of a recurring question or
+// the candidates of one election, under a page of their own.
+type Series struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MarketIDs   []string  `json:"market_ids"` // In display order
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Recurring series roll over: when the last market resolves, the next period's
+	// market is prepared with these deploy parameters (see RolloverService).
+	Recurring      bool    `json:"recurring,omitempty"`
+	LiquidityParam float64 `json:"liquidity_param,omitempty"`
+	InitialFunding float64 `json:"initial_funding,omitempty"`
+}
+
+// Validate checks that the series has a name and between one and MaxSeriesMarkets
+// distinct market contract IDs.
+func (s *Series) Validate() error {
+	switch {
+	case s.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidSeries)
+	case len(s.Name) > MaxSeriesNameLength:
+		return fmt.Errorf("%w: name exceeds %d characters", ErrInvalidSeries, MaxSeriesNameLength)
+	case len(s.Description) > model.MaxDescriptionLength:
+		return fmt.Errorf("%w: description exceeds %d characters", ErrInvalidSeries, model.MaxDescriptionLength)
+	case len(s.MarketIDs) == 0:
+		return fmt.Errorf("%w: at least one market is required", ErrInvalidSeries)
+	case len(s.MarketIDs) > MaxSeriesMarkets:
+		return fmt.Errorf("%w: more than %d markets", ErrInvalidSeries, MaxSeriesMarkets)
+	}
+	if s.Recurring {
+		// The metadata is pinned at rollover; check the deploy parameters now.
+		req := s.deployRequest("-")
+		if err := req.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSeries, err)
+		}
+	}
+	for i, id := range s.MarketIDs {
+		if err := soroban.ValidateContractID(id); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSeries, err)
+		}
+		if slices.Contains(s.MarketIDs[:i], id) {
+			return fmt.Errorf("%w: market %s is listed twice", ErrInvalidSeries, id)
+		}
+	}
+	return nil
+}
+
+// deployRequest returns the request deploying the next market of a recurring series.
+func (s *Series) deployRequest(metadataHash string) DeployMarketRequest {
+	return DeployMarketRequest{
+		LiquidityParam: s.LiquidityParam,
+		MetadataHash:   metadataHash,
+		InitialFunding: s.InitialFunding,
+	}
+}
+
+// SeriesStore keeps the oracle's market series in memory, optionally persisted to a
+// JSON file.
+type SeriesStore struct {
+	path   string // Empty keeps series in memory only
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	series map[string]Series // id -> series
+}
+
+// NewSeriesStore creates a series store and loads existing series from path, if set.
+func NewSeriesStore(path string, logger *slog.Logger) (*SeriesStore, error) {
+	if logger == nil {
+		panic("NewSeriesStore: logger must not be nil")
+	}
+	s := &SeriesStore{
+		path:   path,
+		logger: logger,
+		series: make(map[string]Series),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var series []Series
+	if _, err := readJSONFile(path, &series); err != nil {
+		return nil, fmt.Errorf("failed to load series file: %w", err)
+	}
+	for _, ser := range series {
+		s.series[ser.ID] = ser
+	}
+	return s, nil
+}
+
+// List returns all series sorted by name.
+func (s *SeriesStore) List() []Series {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series := make([]Series, 0, len(s.series))
+	for _, ser := range s.series {
+		series = append(series, ser)
+	}
+	sortSeries(series)
+	return series
+}
+
+// ForMarket returns the series the market belongs to, sorted by name.
+func (s *SeriesStore) ForMarket(contractID string) []Series {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var series []Series
+	for _, ser := range s.series {
+		if slices.Contains(ser.MarketIDs, contractID) {
+			series = append(series, ser)
+		}
+	}
+	sortSeries(series)
+	return series
+}
+
+func sortSeries(series []Series) {
+	slices.SortFunc(series, func(a, b Series) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// Get returns the series with the given ID.
+func (s *SeriesStore) Get(id string) (Series, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ser, ok := s.series[id]
+	if !ok {
+		return Series{}, ErrSeriesNotFound
+	}
+	return ser, nil
+}
+
+// Save creates a series, or replaces the one with the same ID when ser.ID is set.
+// Blank market IDs are dropped.
+func (s *SeriesStore) Save(ser Series) (Series, error) {
+	ser.Name = strings.TrimSpace(ser.Name)
+	ser.Description = strings.TrimSpace(ser.Description)
+	ids := make([]string, 0, len(ser.MarketIDs))
+	for _, id := range ser.MarketIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	ser.MarketIDs = ids
+	if !ser.Recurring {
+		ser.LiquidityParam, ser.InitialFunding = 0, 0
+	}
+	if err := ser.Validate(); err != nil {
+		return Series{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, exists := s.series[ser.ID]
+	switch {
+	case ser.ID != "" && !exists:
+		return Series{}, ErrSeriesNotFound
+	case ser.ID == "":
+		if len(s.series) >= maxSeries {
+			return Series{}, ErrTooManySeries
+		}
+		for {
+			id, err := randomHex(seriesIDBytes)
+			if err != nil {
+				return Series{}, err
+			}
+			if _, taken := s.series[id]; !taken {
+				ser.ID = id
+				break
+			}
+		}
+	}
+	ser.UpdatedAt = time.Now().UTC()
+
+	s.series[ser.ID] = ser
+	if err := s.saveLocked(); err != nil {
+		if exists {
+			s.series[ser.ID] = previous
+		} else {
+			delete(s.series, ser.ID)
+		}
+		return Series{}, err
+	}
+	s.logger.Info("saved market series", "id", ser.ID, "name", ser.Name, "markets", len(ser.MarketIDs))
+	return ser, nil
+}
+
+// Delete removes a series. Its markets are not affected.
+func (s *SeriesStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ser, ok := s.series[id]
+	if !ok {
+		return ErrSeriesNotFound
+	}
+	delete(s.series, id)
+	if err := s.saveLocked(); err != nil {
+		s.series[id] = ser
+		return err
+	}
+	s.logger.Info("deleted market series", "id", id, "name", ser.Name)
+	return nil
+}
+
+// saveLocked writes all series to the file atomically. Must be called with mu held.
+func (s *SeriesStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	series := make([]Series, 0, len(s.series))
+	for _, ser := range s.series {
+		series = append(series, ser)
+	}
+	slices.SortFunc(series, func(a, b Series) int { return cmp.Compare(a.ID, b.ID) })
+	if err := writeJSONFile(s.path, series); err != nil {
+		return fmt.Errorf("failed to save series: %w", err)
+	}
+	return nil
+}
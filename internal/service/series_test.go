@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+func seriesContractID(t *testing.T, b byte) string {
+	t.Helper()
+	id, err := strkey.Encode(strkey.VersionByteContract, bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatalf("strkey.Encode() error = %v", err)
+	}
+	return id
+}
+
+func TestSeriesStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewSeriesStore(path, logger)
+	if err != nil {
+		t.Fatalf("NewSeriesStore() error = %v", err)
+	}
+	a, b, c := seriesContractID(t, 1), seriesContractID(t, 2), seriesContractID(t, 3)
+
+	monthly, err := s.Save(Series{Name: " Monthly MTL price ", MarketIDs: []string{a, " ", b}})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if monthly.ID == "" || monthly.Name != "Monthly MTL price" || len(monthly.MarketIDs) != 2 {
+		t.Errorf("Save() = %+v, want ID, trimmed name and blank market dropped", monthly)
+	}
+	if _, err := s.Save(Series{Name: "Election", MarketIDs: []string{c}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	monthly.MarketIDs = append(monthly.MarketIDs, c)
+	monthly.Recurring, monthly.LiquidityParam, monthly.InitialFunding = true, 100, 72
+	if _, err := s.Save(monthly); err != nil {
+		t.Fatalf("Save() update error = %v", err)
+	}
+
+	for _, bad := range []Series{
+		{MarketIDs: []string{a}},
+		{Name: "Empty"},
+		{Name: "Invalid", MarketIDs: []string{"CNOTACONTRACT"}},
+		{Name: "Twice", MarketIDs: []string{a, a}},
+		{ID: "missing", Name: "Unknown", MarketIDs: []string{a}},
+		{Name: "No liquidity", MarketIDs: []string{a}, Recurring: true},
+		{Name: "Underfunded", MarketIDs: []string{a}, Recurring: true, LiquidityParam: 100, InitialFunding: 10},
+	} {
+		if _, err := s.Save(bad); err == nil {
+			t.Errorf("Save(%+v) error = nil, want error", bad)
+		}
+	}
+
+	reloaded, err := NewSeriesStore(path, logger)
+	if err != nil {
+		t.Fatalf("NewSeriesStore() reload error = %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 || list[0].Name != "Election" || len(list[1].MarketIDs) != 3 || !list[1].Recurring {
+		t.Errorf("List() after reload = %+v, want Election then updated Monthly", list)
+	}
+	if got := reloaded.ForMarket(c); len(got) != 2 {
+		t.Errorf("ForMarket() = %d series, want 2", len(got))
+	}
+	if got := reloaded.ForMarket(a); len(got) != 1 || got[0].ID != monthly.ID {
+		t.Errorf("ForMarket() = %+v, want only Monthly", got)
+	}
+
+	if err := reloaded.Delete(monthly.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := reloaded.Get(monthly.ID); !errors.Is(err, ErrSeriesNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrSeriesNotFound", err)
+	}
+	if err := reloaded.Delete(monthly.ID); !errors.Is(err, ErrSeriesNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrSeriesNotFound", err)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// MaxShareAmount bounds the token amount a share link can prefill.
+	MaxShareAmount = 1_000_000
+
+	shareCodeBytes = 4 // 8 hex characters
+	maxShareLinks  = 50000
+)
+
+var (
+	ErrInvalidShareLinkAmount = fmt.Errorf("amount must be a positive number up to %d", MaxShareAmount)
+	ErrShareLinkNotFound      = errors.New("share link not found")
+	ErrTooManyShareLinks      = errors.New("too many share links")
+)
+
+// ShareLink is a short code for a market page with a prefilled trade form.
+type ShareLink struct {
+	Code       string        `json:"code"`
+	ContractID string        `json:"contract_id"`
+	Side       model.Outcome `json:"side"`
+	Amount     float64       `json:"amount"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Path returns the market page path that prefills the trade form, e.g.
+// /market/C...?side=YES&amount=25.
+func (l ShareLink) Path() string {
+	return MarketTradePath(l.ContractID, l.Side, l.Amount)
+}
+
+// MarketTradePath returns the market page path with side and amount query parameters.
+func MarketTradePath(contractID string, side model.Outcome, amount float64) string {
+	q := url.Values{
+		"side":   {string(side)},
+		"amount": {strconv.FormatFloat(amount, 'f', -1, 64)},
+	}
+	return "/market/" + contractID + "?" + q.Encode()
+}
+
+// CreateShareLinkRequest contains parameters for shortening a trade link.
+type CreateShareLinkRequest struct {
+	ContractID string
+	Side       model.Outcome
+	Amount     float64
+}
+
+// Validate validates the create share link request.
+func (r *CreateShareLinkRequest) Validate() error {
+	if err := soroban.ValidateContractID(r.ContractID); err != nil {
+		return err
+	}
+	if !r.Side.IsValid() {
+		return ErrInvalidOutcome
+	}
+	if r.Amount <= 0 || r.Amount > MaxShareAmount || math.IsNaN(r.Amount) {
+		return ErrInvalidShareLinkAmount
+	}
+	return nil
+}
+
+// ShareLinkService stores short codes for prefilled trade links. Links are kept in
+// memory, optionally persisted to a JSON file. Identical links share one code.
+type ShareLinkService struct {
+	path   string // Empty keeps links in memory only
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	links  map[string]ShareLink // code -> link
+	byPath map[string]string    // link path -> code
+}
+
+// NewShareLinkService creates a share link service and loads existing links from path, if set.
+func NewShareLinkService(path string, logger *slog.Logger) (*ShareLinkService, error) {
+	if logger == nil {
+		panic("NewShareLinkService: logger must not be nil")
+	}
+
+	s := &ShareLinkService{
+		path:   path,
+		logger: logger,
+		links:  make(map[string]ShareLink),
+		byPath: make(map[string]string),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var links []ShareLink
+	if _, err := readJSONFile(path, &links); err != nil {
+		return nil, fmt.Errorf("failed to load share links file: %w", err)
+	}
+	for _, l := range links {
+		s.links[l.Code] = l
+		s.byPath[l.Path()] = l.Code
+	}
+	return s, nil
+}
+
+// Create returns the short link for a trade, reusing the existing code for the same link.
+func (s *ShareLinkService) Create(req CreateShareLinkRequest) (ShareLink, error) {
+	if err := req.Validate(); err != nil {
+		return ShareLink{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := MarketTradePath(req.ContractID, req.Side, req.Amount)
+	if code, ok := s.byPath[path]; ok {
+		return s.links[code], nil
+	}
+	if len(s.links) >= maxShareLinks {
+		return ShareLink{}, ErrTooManyShareLinks
+	}
+
+	var code string
+	for {
+		c, err := randomHex(shareCodeBytes)
+		if err != nil {
+			return ShareLink{}, err
+		}
+		if _, taken := s.links[c]; !taken {
+			code = c
+			break
+		}
+	}
+
+	link := ShareLink{
+		Code:       code,
+		ContractID: req.ContractID,
+		Side:       req.Side,
+		Amount:     req.Amount,
+		CreatedAt:  time.Now().UTC(),
+	}
+	s.links[code] = link
+	s.byPath[path] = code
+	if err := s.saveLocked(); err != nil {
+		delete(s.links, code)
+		delete(s.byPath, path)
+		return ShareLink{}, err
+	}
+	s.logger.Debug("created share link", "code", code, "contract_id", req.ContractID)
+	return link, nil
+}
+
+// Get returns the link for a short code.
+func (s *ShareLinkService) Get(code string) (ShareLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return ShareLink{}, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+// saveLocked writes all links to the file atomically. Must be called with mu held.
+func (s *ShareLinkService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	links := make([]ShareLink, 0, len(s.links))
+	for _, l := range s.links {
+		links = append(links, l)
+	}
+	if err := writeJSONFile(s.path, links); err != nil {
+		return fmt.Errorf("failed to save share links: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestShareLinkService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewShareLinkService(path, logger)
+	if err != nil {
+		t.Fatalf("NewShareLinkService() error = %v", err)
+	}
+
+	req := CreateShareLinkRequest{ContractID: testContractID, Side: model.OutcomeYes, Amount: 25}
+	link, err := s.Create(req)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := "/market/" + testContractID + "?amount=25&side=YES"; link.Path() != want {
+		t.Errorf("Path() = %q, want %q", link.Path(), want)
+	}
+	again, err := s.Create(req)
+	if err != nil || again.Code != link.Code {
+		t.Errorf("Create() same link = %q, %v, want code %q", again.Code, err, link.Code)
+	}
+
+	for _, bad := range []CreateShareLinkRequest{
+		{ContractID: testContractID, Side: "MAYBE", Amount: 1},
+		{ContractID: testContractID, Side: model.OutcomeNo, Amount: 0},
+		{ContractID: testContractID, Side: model.OutcomeNo, Amount: MaxShareAmount + 1},
+		{ContractID: "bad", Side: model.OutcomeNo, Amount: 1},
+	} {
+		if _, err := s.Create(bad); err == nil {
+			t.Errorf("Create(%+v) error = nil, want validation error", bad)
+		}
+	}
+
+	reloaded, err := NewShareLinkService(path, logger)
+	if err != nil {
+		t.Fatalf("NewShareLinkService() reload error = %v", err)
+	}
+	got, err := reloaded.Get(link.Code)
+	if err != nil || got.Path() != link.Path() {
+		t.Errorf("Get() after reload = %+v, %v, want %+v", got, err, link)
+	}
+	if _, err := reloaded.Get("missing"); !errors.Is(err, ErrShareLinkNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrShareLinkNotFound", err)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DuplicateThreshold is the question similarity (0..1) from which a new market is
+// reported as a likely duplicate of an open one.
+const DuplicateThreshold = 0.6
+
+// questionStopWords carry no meaning for duplicate detection: almost every question
+// starts with "Will ... by ...".
+var questionStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "will": true, "be": true, "is": true, "are": true,
+	"by": true, "of": true, "in": true, "on": true, "at": true, "to": true, "for": true,
+	"before": true, "end": true,
+}
+
+// NormalizeQuestion lowercases a question, strips punctuation and stop words and
+// collapses whitespace, so trivially reworded questions compare equal.
+func NormalizeQuestion(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !questionStopWords[w] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// QuestionSimilarity returns the trigram similarity of two questions after
+// normalization, in the same way as PostgreSQL's pg_trgm similarity(): the share
+// of distinct word trigrams the two have in common.
+func QuestionSimilarity(a, b string) float64 {
+	ta, tb := trigrams(NormalizeQuestion(a)), trigrams(NormalizeQuestion(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// trigrams returns the set of trigrams of each word, padded with two leading and
+// one trailing space as pg_trgm does.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
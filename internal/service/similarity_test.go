@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestNormalizeQuestion(t *testing.T) {
+	got := NormalizeQuestion("  Will BTC reach $100k by the END of 2025?! ")
+	if want := "btc reach 100k 2025"; got != want {
+		t.Errorf("NormalizeQuestion() = %q, want %q", got, want)
+	}
+}
+
+func TestQuestionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		duplicate bool
+	}{
+		{"Will BTC reach $100k by end of 2025?", "Will BTC reach 100k before 2025 ends?", true},
+		{"Will BTC reach $100k by end of 2025?", "will btc reach $100K by end of 2025", true},
+		{"Will BTC reach $100k by end of 2025?", "Will ETH reach $10k by end of 2025?", false},
+		{"Will BTC reach $100k by end of 2025?", "Who wins the Montenegro election?", false},
+		{"", "Will BTC reach $100k?", false},
+	}
+	for _, tt := range tests {
+		score := QuestionSimilarity(tt.a, tt.b)
+		if got := score >= DuplicateThreshold; got != tt.duplicate {
+			t.Errorf("QuestionSimilarity(%q, %q) = %.2f, duplicate = %v, want %v", tt.a, tt.b, score, got, tt.duplicate)
+		}
+	}
+	if got := QuestionSimilarity("Will it rain?", "will it RAIN"); got != 1 {
+		t.Errorf("QuestionSimilarity() of reworded question = %v, want 1", got)
+	}
+}
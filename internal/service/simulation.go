@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/mtlprog/total/internal/lmsr"
+)
+
+const (
+	// MaxSimulationTrades bounds the trades in one simulated market.
+	MaxSimulationTrades = 1000
+	// MaxSimulationRuns bounds how many markets one simulation plays through.
+	MaxSimulationRuns = 200
+
+	simulationMaxTradeFactor = 10 // Trade sizes are capped at this many times the mean
+)
+
+// ErrInvalidSimulation is returned for simulation parameters out of range.
+var ErrInvalidSimulation = errors.New("invalid simulation parameters")
+
+// SimulationParams describe the trade flow a market is simulated with. Each run plays
+// Trades buys against a fresh market: informed traders buy the outcome the market
+// underprices relative to TrueProbability, the rest pick an outcome at random. Trade
+// sizes are exponentially distributed around TradeSize tokens. The market then
+// resolves YES with TrueProbability.
+type SimulationParams struct {
+	LiquidityParam  float64 // b
+	Trades          int
+	TradeSize       float64 // Mean tokens per trade
+	TrueProbability float64 // Chance the market resolves YES, 0..1
+	Informed        float64 // Share of informed trades, 0..1
+	Runs            int
+	Seed            uint64 // Same parameters and seed give the same result
+}
+
+// Validate checks that the parameters are in range.
+func (p SimulationParams) Validate() error {
+	switch {
+	case !(p.LiquidityParam > 0) || math.IsInf(p.LiquidityParam, 0):
+		return fmt.Errorf("%w: liquidity parameter must be positive", ErrInvalidSimulation)
+	case p.Trades < 1 || p.Trades > MaxSimulationTrades:
+		return fmt.Errorf("%w: trades must be 1-%d", ErrInvalidSimulation, MaxSimulationTrades)
+	case !(p.TradeSize > 0) || math.IsInf(p.TradeSize, 0):
+		return fmt.Errorf("%w: trade size must be positive", ErrInvalidSimulation)
+	case !(p.TrueProbability >= 0 && p.TrueProbability <= 1):
+		return fmt.Errorf("%w: true probability must be 0-1", ErrInvalidSimulation)
+	case !(p.Informed >= 0 && p.Informed <= 1):
+		return fmt.Errorf("%w: informed share must be 0-1", ErrInvalidSimulation)
+	case p.Runs < 1 || p.Runs > MaxSimulationRuns:
+		return fmt.Errorf("%w: runs must be 1-%d", ErrInvalidSimulation, MaxSimulationRuns)
+	}
+	return nil
+}
+
+// SimulationStep summarizes all runs after the same number of trades.
+type SimulationStep struct {
+	Trade int
+	// YES price: mean and 10th/90th percentile across runs
+	PriceYes, PriceLow, PriceHigh float64
+	// Pool left after paying out the larger outcome supply in full, less the claim fee:
+	// mean and 10th/90th percentile. The oracle's funding is the reserve of an empty
+	// market; it shrinks as the market grows lopsided.
+	Reserve, ReserveLow, ReserveHigh float64
+}
+
+// MarketSimulation is the outcome of simulating a market's life many times.
+type MarketSimulation struct {
+	Params          SimulationParams
+	Funding         float64 // Collateral the oracle puts in: b·ln 2
+	MeanOracleLoss  float64 // Mean loss at resolution; negative is a profit
+	WorstOracleLoss float64 // Largest loss of any run, at most Funding
+	LossShare       float64 // Share of runs the oracle lost money in
+	MeanVolume      float64 // Mean collateral traded per run
+	Steps           []SimulationStep
+}
+
+// SimulateMarket plays a market's life Runs times with the LMSR pricing of the market
+// contract and reports the oracle's loss at resolution, the YES price path and how far
+// the pool is drawn down. Payouts are counted as claimed in full, less ClaimFeeRate.
+func SimulateMarket(p SimulationParams) (*MarketSimulation, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	calc, err := lmsr.New(p.LiquidityParam)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &MarketSimulation{Params: p, Funding: calc.InitialLiquidity(), WorstOracleLoss: math.Inf(-1)}
+	prices := make([][]float64, p.Trades+1) // step -> YES price of each run
+	reserves := make([][]float64, p.Trades+1)
+	for i := range prices {
+		prices[i] = make([]float64, p.Runs)
+		reserves[i] = make([]float64, p.Runs)
+	}
+
+	var losses int
+	for run := range p.Runs {
+		rng := rand.New(rand.NewPCG(p.Seed, uint64(run)))
+		var qYes, qNo, collected float64
+		for step := 0; ; step++ {
+			priceYes, _, err := calc.Price(qYes, qNo)
+			if err != nil {
+				return nil, err
+			}
+			prices[step][run] = priceYes
+			reserves[step][run] = sim.Funding + collected - max(qYes, qNo)*(1-ClaimFeeRate)
+			if step == p.Trades {
+				break
+			}
+
+			outcome := "NO"
+			if rng.Float64() < p.Informed {
+				if priceYes < p.TrueProbability {
+					outcome = "YES"
+				}
+			} else if rng.IntN(2) == 0 {
+				outcome = "YES"
+			}
+			amount := min(rng.ExpFloat64(), simulationMaxTradeFactor) * p.TradeSize
+			cost, err := calc.CalculateCost(qYes, qNo, amount, outcome)
+			if err != nil {
+				return nil, err
+			}
+			collected += cost
+			if outcome == "YES" {
+				qYes += amount
+			} else {
+				qNo += amount
+			}
+		}
+
+		winning := qNo
+		if rng.Float64() < p.TrueProbability {
+			winning = qYes
+		}
+		loss := winning*(1-ClaimFeeRate) - collected
+		sim.MeanOracleLoss += loss / float64(p.Runs)
+		sim.WorstOracleLoss = max(sim.WorstOracleLoss, loss)
+		sim.MeanVolume += collected / float64(p.Runs)
+		if loss > 0 {
+			losses++
+		}
+	}
+	sim.LossShare = float64(losses) / float64(p.Runs)
+
+	sim.Steps = make([]SimulationStep, p.Trades+1)
+	for i := range sim.Steps {
+		step := SimulationStep{Trade: i}
+		step.PriceYes, step.PriceLow, step.PriceHigh = spread(prices[i])
+		step.Reserve, step.ReserveLow, step.ReserveHigh = spread(reserves[i])
+		sim.Steps[i] = step
+	}
+	return sim, nil
+}
+
+// spread returns the mean and the 10th and 90th percentile of values, reordering them.
+func spread(values []float64) (mean, low, high float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	slices.Sort(values)
+	last := len(values) - 1
+	return mean, values[last/10], values[last-last/10]
+}
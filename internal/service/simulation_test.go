@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSimulateMarket(t *testing.T) {
+	params := SimulationParams{
+		LiquidityParam:  100,
+		Trades:          200,
+		TradeSize:       20,
+		TrueProbability: 0.8,
+		Informed:        0.5,
+		Runs:            50,
+		Seed:            1,
+	}
+	sim, err := SimulateMarket(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sim.Steps) != params.Trades+1 {
+		t.Fatalf("len(Steps) = %d, want %d", len(sim.Steps), params.Trades+1)
+	}
+	if first := sim.Steps[0]; first.PriceYes != 0.5 || math.Abs(first.Reserve-sim.Funding) > 1e-9 {
+		t.Errorf("first step = %+v, want an even market holding the funding", first)
+	}
+	if last := sim.Steps[params.Trades]; last.PriceYes < 0.6 {
+		t.Errorf("final mean YES price = %.3f, want informed traders to move it toward 0.8", last.PriceYes)
+	}
+	if sim.WorstOracleLoss > sim.Funding {
+		t.Errorf("WorstOracleLoss = %.2f, exceeds the LMSR bound %.2f", sim.WorstOracleLoss, sim.Funding)
+	}
+	for _, step := range sim.Steps {
+		if step.ReserveLow < 0 {
+			t.Fatalf("step %d reserve = %.2f, want the pool to cover every payout", step.Trade, step.ReserveLow)
+		}
+	}
+
+	again, err := SimulateMarket(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.MeanOracleLoss != sim.MeanOracleLoss {
+		t.Errorf("same seed gave mean loss %v, then %v", sim.MeanOracleLoss, again.MeanOracleLoss)
+	}
+
+	params.Runs = MaxSimulationRuns + 1
+	if _, err := SimulateMarket(params); !errors.Is(err, ErrInvalidSimulation) {
+		t.Errorf("SimulateMarket() with too many runs error = %v, want ErrInvalidSimulation", err)
+	}
+}
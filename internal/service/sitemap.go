@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SitemapRefreshInterval is how often the sitemap market list is rebuilt.
+const SitemapRefreshInterval = 30 * time.Minute
+
+// SitemapEntry is one indexable market page.
+type SitemapEntry struct {
+	ContractID string
+	Resolved   bool
+	LastMod    time.Time // Latest trade or resolution; zero when none has been seen
+}
+
+// SitemapService keeps the list of market pages for /sitemap.xml, refreshed in the
+// background so crawlers never trigger RPC calls.
+type SitemapService struct {
+	factoryService *FactoryService
+	eventService   *EventService
+	logger         *slog.Logger
+
+	mu      sync.RWMutex
+	entries map[string]SitemapEntry
+}
+
+// NewSitemapService creates an empty sitemap service.
+func NewSitemapService(factoryService *FactoryService, eventService *EventService, logger *slog.Logger) *SitemapService {
+	if factoryService == nil {
+		panic("NewSitemapService: factoryService must not be nil")
+	}
+	if eventService == nil {
+		panic("NewSitemapService: eventService must not be nil")
+	}
+	if logger == nil {
+		panic("NewSitemapService: logger must not be nil")
+	}
+	return &SitemapService{
+		factoryService: factoryService,
+		eventService:   eventService,
+		logger:         logger,
+		entries:        make(map[string]SitemapEntry),
+	}
+}
+
+// Refresh lists all markets and updates their last-modified times. Trade events only
+// cover the last ~24h, so a previously seen LastMod is kept when no newer activity exists.
+func (s *SitemapService) Refresh(ctx context.Context) error {
+	if !s.factoryService.HasFactory() {
+		return nil
+	}
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		s.logger.Warn("sitemap: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+
+	var errs []error
+	entries := make(map[string]SitemapEntry, len(states))
+	for _, state := range states {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry := SitemapEntry{ContractID: state.ContractID, Resolved: state.Resolved}
+
+		if state.Resolved {
+			resolution, found, err := s.eventService.GetResolution(ctx, state.ContractID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			} else if found {
+				entry.LastMod = resolution.Timestamp
+			}
+		} else {
+			events, err := s.eventService.GetTradeEvents(ctx, state.ContractID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			}
+			for _, evt := range events {
+				if evt.Timestamp.After(entry.LastMod) {
+					entry.LastMod = evt.Timestamp
+				}
+			}
+		}
+
+		s.mu.RLock()
+		prev := s.entries[state.ContractID]
+		s.mu.RUnlock()
+		if prev.LastMod.After(entry.LastMod) {
+			entry.LastMod = prev.LastMod
+		}
+		entries[state.ContractID] = entry
+	}
+
+	s.mu.Lock()
+	// Keep markets whose state could not be loaded this time.
+	for _, id := range ids {
+		if prev, ok := s.entries[id]; ok {
+			if _, refreshed := entries[id]; !refreshed {
+				entries[id] = prev
+			}
+		}
+	}
+	s.entries = entries
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Entries returns all market pages: active markets first, then by contract ID.
+func (s *SitemapService) Entries() []SitemapEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]SitemapEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b SitemapEntry) int {
+		if a.Resolved != b.Resolved {
+			if a.Resolved {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.ContractID, b.ContractID)
+	})
+	return entries
+}
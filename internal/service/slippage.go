@@ -0,0 +1,73 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+const (
+	// MinSuggestedSlippage is the slippage suggested for a market whose price has not moved.
+	MinSuggestedSlippage = 0.005
+	// volatilityWindow is how far back price samples are used for the volatility.
+	volatilityWindow = 24 * time.Hour
+	// minVolatilitySamples is how many samples within the window a volatility needs.
+	minVolatilitySamples = 8
+	// slippageDeviations is how many standard deviations of one sampling interval's
+	// price change the suggested slippage covers.
+	slippageDeviations = 3
+)
+
+// Volatility returns how much the YES price of a market moves between consecutive
+// samples within window: the root mean square of the changes, in probability. ok is
+// false until the market has minVolatilitySamples samples in the window.
+func (h *PriceHistory) Volatility(contractID string, window time.Duration, now time.Time) (volatility float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return sampleVolatility(h.samples[contractID], window, now)
+}
+
+// SuggestSlippage suggests a slippage for buying or selling outcome from the market's
+// recent volatility: tight for calm markets, wider for volatile ones, within
+// MinSuggestedSlippage and model.MaxSlippage. ok is false while the market has too
+// little price history.
+func (h *PriceHistory) SuggestSlippage(contractID string, outcome model.Outcome, now time.Time) (slippage float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	samples := h.samples[contractID]
+	volatility, ok := sampleVolatility(samples, volatilityWindow, now)
+	if !ok {
+		return 0, false
+	}
+	price := samples[len(samples)-1].price
+	if outcome == model.OutcomeNo {
+		price = 1 - price
+	}
+	return suggestSlippage(volatility, price), true
+}
+
+func sampleVolatility(samples []priceSample, window time.Duration, now time.Time) (float64, bool) {
+	start := now.Add(-window)
+	i := slices.IndexFunc(samples, func(p priceSample) bool { return !p.at.Before(start) })
+	if i < 0 || len(samples)-i < minVolatilitySamples {
+		return 0, false
+	}
+	var sum float64
+	for j := i + 1; j < len(samples); j++ {
+		d := samples[j].price - samples[j-1].price
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(samples)-i-1)), true
+}
+
+// suggestSlippage converts a price volatility in probability into a slippage on the
+// cost of an outcome priced at price: the same move is a larger share of a cheap
+// outcome's cost. The result is rounded up to 0.1%.
+func suggestSlippage(volatility, price float64) float64 {
+	relative := slippageDeviations * volatility / max(price, 0.01)
+	slippage := math.Ceil(relative*1000-1e-9) / 1000
+	return min(max(slippage, MinSuggestedSlippage), model.MaxSlippage)
+}
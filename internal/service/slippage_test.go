@@ -0,0 +1,53 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/model"
+)
+
+func TestSuggestSlippage(t *testing.T) {
+	h := &PriceHistory{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		samples: make(map[string][]priceSample),
+	}
+	start := time.Unix(1_700_000_000, 0)
+	record := func(id string, prices ...float64) {
+		for i, price := range prices {
+			h.record([]MarketState{{ContractID: id, PriceYes: price}}, start.Add(time.Duration(i)*PriceHistoryInterval))
+		}
+	}
+	now := start.Add(2 * time.Hour)
+
+	record("short", 0.5, 0.6, 0.4)
+	if _, ok := h.SuggestSlippage("short", model.OutcomeYes, now); ok {
+		t.Error("SuggestSlippage() ok with three samples, want false")
+	}
+
+	// A calm market gets the minimum.
+	record("calm", 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5)
+	if got, ok := h.SuggestSlippage("calm", model.OutcomeYes, now); !ok || got != MinSuggestedSlippage {
+		t.Errorf("SuggestSlippage(calm) = %v, %v, want %v", got, ok, MinSuggestedSlippage)
+	}
+
+	// Moves of 0.01 are a volatility of 0.01: three of them are 6% of a price of 0.5
+	// and 7.5% of 0.4.
+	record("moving", 0.5, 0.51, 0.5, 0.51, 0.5, 0.51, 0.5, 0.6)
+	if v, ok := h.Volatility("moving", 24*time.Hour, now); !ok || math.Abs(v-math.Sqrt((6*0.0001+0.01)/7)) > 1e-9 {
+		t.Errorf("Volatility() = %v, %v", v, ok)
+	}
+	record("wavy", 0.5, 0.51, 0.5, 0.51, 0.5, 0.51, 0.5, 0.51, 0.5)
+	if got, _ := h.SuggestSlippage("wavy", model.OutcomeNo, now); math.Abs(got-0.06) > 1e-9 {
+		t.Errorf("SuggestSlippage(wavy, NO) = %v, want 0.06", got)
+	}
+	if got := suggestSlippage(0.01, 0.4); math.Abs(got-0.075) > 1e-9 {
+		t.Errorf("suggestSlippage(0.01, 0.4) = %v, want 0.075", got)
+	}
+	if got := suggestSlippage(0.2, 0.5); got != model.MaxSlippage {
+		t.Errorf("suggestSlippage(0.2, 0.5) = %v, want the maximum", got)
+	}
+}
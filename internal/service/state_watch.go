@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// StateWatchInterval is how often the background scheduler checks market ledger
+// entries for changes, about once per ledger.
+const StateWatchInterval = 5 * time.Second
+
+// StateWatchStats counts what the ledger entry watch found.
+type StateWatchStats struct {
+	Markets      int    // Markets checked in the last run
+	LatestLedger uint32 // Ledger the last run saw
+	LastRun      time.Time
+	Changed      uint64 // Market states fetched because their entry changed or was not seen yet
+	Unchanged    uint64 // Cached market states kept because their entry did not change
+}
+
+// stateWatch remembers the last modified ledger of each market's instance entry.
+type stateWatch struct {
+	mu    sync.Mutex
+	seqs  map[string]uint32
+	stats StateWatchStats
+}
+
+// observe records the last modified ledger of market entries and returns the markets
+// whose entry changed since it was last observed, or was never observed.
+func (w *stateWatch) observe(seqs map[string]uint32) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seqs == nil {
+		w.seqs = make(map[string]uint32)
+	}
+	var changed []string
+	for id, seq := range seqs {
+		if prev, ok := w.seqs[id]; !ok || seq > prev {
+			changed = append(changed, id)
+		}
+		w.seqs[id] = seq
+	}
+	return changed
+}
+
+// forget drops a market, so the next observation reports it as changed again.
+func (w *stateWatch) forget(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.seqs, id)
+}
+
+func (w *stateWatch) record(markets int, ledger uint32, changed, unchanged int, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Markets = markets
+	w.stats.LatestLedger = ledger
+	w.stats.LastRun = now
+	w.stats.Changed += uint64(changed)
+	w.stats.Unchanged += uint64(unchanged)
+}
+
+// WatchMarketStates keeps the market state cache current at the cost of one
+// getLedgerEntries call per MaxLedgerEntryKeys markets. Market state lives in the
+// contract's instance storage, so its instance entry's last modified ledger advances
+// whenever the state changes: only those markets are fetched again through get_state;
+// the others keep their cached state, which is stored again so background revalidation
+// does not simulate them. Archived markets are skipped.
+func (s *FactoryService) WatchMarketStates(ctx context.Context) error {
+	ids, err := s.ListMarkets(ctx)
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]string, len(ids))
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := s.frozenState(id); ok {
+			continue
+		}
+		key, err := soroban.ContractInstanceKey(id)
+		if err != nil {
+			s.logger.Warn("skipping market with invalid contract ID", "contract_id", id, "error", err)
+			continue
+		}
+		byKey[key] = id
+		keys = append(keys, key)
+	}
+
+	seqs := make(map[string]uint32, len(keys))
+	var ledger uint32
+	for start := 0; start < len(keys); start += soroban.MaxLedgerEntryKeys {
+		batch := keys[start:min(start+soroban.MaxLedgerEntryKeys, len(keys))]
+		result, err := s.sorobanClient.GetLedgerEntries(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to get market ledger entries: %w", err)
+		}
+		ledger = max(ledger, result.LatestLedger)
+		for _, entry := range result.Entries {
+			if id, ok := byKey[entry.Key]; ok {
+				seqs[id] = entry.LastModifiedLedgerSeq
+			}
+		}
+	}
+
+	changed := make(map[string]bool)
+	for _, id := range s.watch.observe(seqs) {
+		changed[id] = true
+	}
+	var errs []error
+	var fetched, kept int
+	for id := range seqs {
+		if cached, ok := s.stateCache.Peek(id); ok && !changed[id] {
+			s.stateCache.Set(id, cached)
+			kept++
+			continue
+		}
+		state, err := s.fetchMarketState(ctx, id)
+		if err != nil {
+			s.inspect(id, MarketState{}, err)
+			// Fetch it again on the next run even if the entry does not change
+			s.watch.forget(id)
+			errs = append(errs, fmt.Errorf("failed to get state for %s: %w", id, err))
+			continue
+		}
+		s.inspect(id, *state, nil)
+		s.stateCache.Set(id, *state)
+		fetched++
+	}
+	s.watch.record(len(seqs), ledger, fetched, kept, time.Now())
+	if fetched > 0 {
+		s.logger.Debug("market states changed", "changed", fetched, "unchanged", kept, "ledger", ledger)
+	}
+	return errors.Join(errs...)
+}
+
+// StateWatchStats returns what WatchMarketStates found so far.
+func (s *FactoryService) StateWatchStats() StateWatchStats {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	return s.watch.stats
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+func TestStateWatchObserve(t *testing.T) {
+	var w stateWatch
+	if got := w.observe(map[string]uint32{"a": 10, "b": 20}); len(got) != 2 {
+		t.Errorf("first observe() = %v, want every market", got)
+	}
+	if got := w.observe(map[string]uint32{"a": 10, "b": 21}); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("observe() = %v, want only b, whose entry advanced", got)
+	}
+	if got := w.observe(map[string]uint32{"a": 10, "b": 21}); len(got) != 0 {
+		t.Errorf("observe() = %v, want nothing changed", got)
+	}
+	w.forget("a")
+	if got := w.observe(map[string]uint32{"a": 10, "b": 21}); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("observe() after forget = %v, want a", got)
+	}
+}
+
+// ledgerEntryRPC serves getLedgerEntries from a map of key to last modified ledger
+// and fails simulations, counting them.
+type ledgerEntryRPC struct {
+	mu          sync.Mutex
+	seqs        map[string]uint32
+	simulations int
+}
+
+func (f *ledgerEntryRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			Keys []string `json:"keys"`
+		} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.Method != "getLedgerEntries" {
+		f.simulations++
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"simulation unavailable"}}`))
+		return
+	}
+	result := soroban.GetLedgerEntriesResult{LatestLedger: 1000}
+	for _, key := range req.Params.Keys {
+		if seq, ok := f.seqs[key]; ok {
+			result.Entries = append(result.Entries, soroban.LedgerEntry{Key: key, LastModifiedLedgerSeq: seq})
+		}
+	}
+	raw, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": json.RawMessage(raw)})
+}
+
+func (f *ledgerEntryRPC) set(key string, seq uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seqs[key] = seq
+}
+
+func (f *ledgerEntryRPC) simulated() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.simulations
+}
+
+func TestWatchMarketStatesRefetchesOnlyChangedMarkets(t *testing.T) {
+	rpc := &ledgerEntryRPC{seqs: make(map[string]uint32)}
+	srv := httptest.NewServer(rpc)
+	t.Cleanup(srv.Close)
+
+	sorobanClient := soroban.NewClient(srv.URL, srv.Client())
+	stellarClient := stellar.NewCachingClient(stellartest.NewServer(t).Client(t))
+	txBuilder := stellar.NewBuilder(stellarClient, stellartest.Passphrase, 100, 0, sorobanClient)
+	fs := NewFactoryService(sorobanClient, stellarClient, txBuilder, "factory", keypair.MustRandom().Address(), nil, slog.New(slog.DiscardHandler))
+
+	var ids, keys []string
+	for i := range 3 {
+		id, err := strkey.Encode(strkey.VersionByteContract, append(make([]byte, 31), byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := soroban.ContractInstanceKey(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, keys = append(ids, id), append(keys, key)
+		rpc.set(key, 100)
+		fs.stateCache.Set(id, MarketState{ContractID: id, YesSold: int64(i)})
+	}
+	fs.marketListCache.Set("all", ids)
+	// As if an earlier run had fetched every market at ledger 100
+	for _, id := range ids {
+		fs.watch.observe(map[string]uint32{id: 100})
+	}
+	ctx := context.Background()
+
+	if err := fs.WatchMarketStates(ctx); err != nil {
+		t.Fatalf("WatchMarketStates() error = %v", err)
+	}
+	if got := rpc.simulated(); got != 0 {
+		t.Errorf("simulations for unchanged markets = %d, want 0", got)
+	}
+	for i, id := range ids {
+		if state, ok := fs.stateCache.Peek(id); !ok || state.YesSold != int64(i) {
+			t.Errorf("cached state of %s = %+v, %v, want it kept", id, state, ok)
+		}
+	}
+
+	rpc.set(keys[1], 101)
+	if err := fs.WatchMarketStates(ctx); err == nil {
+		t.Fatal("WatchMarketStates() should report the failed fetch")
+	}
+	if got := rpc.simulated(); got != 1 {
+		t.Errorf("simulations after one market changed = %d, want 1", got)
+	}
+	// A failed fetch is retried on the next run although the entry did not change again.
+	if err := fs.WatchMarketStates(ctx); err == nil {
+		t.Fatal("WatchMarketStates() should report the failed retry")
+	}
+	if got := rpc.simulated(); got != 2 {
+		t.Errorf("simulations after the retry = %d, want 2", got)
+	}
+
+	stats := fs.StateWatchStats()
+	if stats.Markets != 3 || stats.LatestLedger != 1000 || stats.Unchanged != 7 || stats.Changed != 0 {
+		t.Errorf("StateWatchStats() = %+v, want 3 markets, 7 kept and no successful fetches", stats)
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+	"github.com/mtlprog/total/internal/model"
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+const (
+	// StatsRefreshInterval is how often platform statistics are aggregated. It must stay
+	// well below the ~24h trade event lookback, or trades are missed from the totals.
+	StatsRefreshInterval = 15 * time.Minute
+	// statsHistoryDays bounds the daily volume series.
+	statsHistoryDays = 90
+)
+
+const statsDayFormat = time.DateOnly
+
+// DailyVolume is the collateral traded on one UTC day.
+type DailyVolume struct {
+	Day    time.Time `json:"day"`
+	Volume float64   `json:"volume"`
+}
+
+// PlatformStats are platform-wide totals. Volume and traders are counted from the
+// trades observed since Since, since the RPC only keeps about a day of events.
+type PlatformStats struct {
+	OpenMarkets     int           `json:"open_markets"`
+	ResolvedMarkets int           `json:"resolved_markets"`
+	Volume          float64       `json:"volume"` // collateral, buys and sells
+	Trades          int           `json:"trades"`
+	TradesLastHour  int           `json:"trades_last_hour"`
+	PoolLocked      float64       `json:"pool_locked"` // collateral held by all market pools
+	Traders         int           `json:"traders"`
+	AvgResolution   time.Duration `json:"-"`                // creation to resolution
+	ResolutionCount int           `json:"resolution_count"` // markets AvgResolution is based on
+	DailyVolume     []DailyVolume `json:"daily_volume"`     // oldest first
+	Since           time.Time     `json:"since,omitzero"`
+	UpdatedAt       time.Time     `json:"updated_at,omitzero"`
+}
+
+// statsFile is the persisted aggregation state.
+type statsFile struct {
+	Since       time.Time                 `json:"since,omitzero"`
+	Volume      float64                   `json:"volume"`
+	Trades      int                       `json:"trades"`
+	Traders     map[string]bool           `json:"traders"`
+	DailyVolume map[string]float64        `json:"daily_volume"` // UTC date -> collateral
+	Counted     map[string]uint32         `json:"counted"`      // contract ID -> last counted ledger
+	Resolutions map[string]time.Duration  `json:"resolutions"`  // contract ID -> time to resolution
+	Outcomes    map[string]string         `json:"outcomes"`     // contract ID -> winning outcome of resolved markets
+	Created     map[string]time.Time      `json:"created"`      // contract ID -> creation time from metadata, zero when unknown
+	Accounts    map[string]*accountTotals `json:"accounts"`
+	// Cursors maps contract ID -> ID of the last counted trade event. Trades are counted
+	// once by event ID, so a refresh that stopped in the middle of a ledger resumes
+	// there; files from before it fall back to Counted.
+	Cursors map[string]string `json:"cursors"`
+	// CountedFrom maps contract ID -> first counted ledger: earlier trades were never
+	// counted and are left for a backfill. 0 when unknown, for markets counted before
+	// it was tracked.
+	CountedFrom map[string]uint32 `json:"counted_from"`
+	// Net maps contract ID -> net tokens bought in the counted trades, and Baselines
+	// contract ID -> what the state held beyond them when first checked.
+	Net       map[string]*marketNet `json:"net"`
+	Baselines map[string]marketNet  `json:"baselines"`
+	// Achievements maps account -> achievement ID -> when it was earned.
+	Achievements map[string]map[string]time.Time `json:"achievements"`
+}
+
+// StatsService aggregates platform statistics from market states and trade events in
+// the background, so /stats never triggers RPC calls. Trades are counted once, by
+// event ID, and the running totals are optionally persisted to a JSON file together
+// with each market's cursor, so a crash before a save only repeats the lost counting.
+type StatsService struct {
+	factoryService *FactoryService
+	eventService   *EventService
+	ipfsClient     *ipfs.Client
+	path           string // Empty keeps totals in memory only
+	logger         *slog.Logger
+
+	mu           sync.RWMutex
+	file         statsFile
+	achievements AchievementRuleSet
+	divergences  []IndexDivergence // oldest first
+	open         int
+	resolved     int
+	pool         int64 // scaled by soroban.ScaleFactor
+	recentHour   int   // trades in the hour before the last refresh
+	updated      time.Time
+}
+
+// NewStatsService creates a stats service and loads the totals from path, if set.
+func NewStatsService(factoryService *FactoryService, eventService *EventService, ipfsClient *ipfs.Client, path string, logger *slog.Logger) (*StatsService, error) {
+	if factoryService == nil {
+		panic("NewStatsService: factoryService must not be nil")
+	}
+	if eventService == nil {
+		panic("NewStatsService: eventService must not be nil")
+	}
+	if ipfsClient == nil {
+		panic("NewStatsService: ipfsClient must not be nil")
+	}
+	if logger == nil {
+		panic("NewStatsService: logger must not be nil")
+	}
+
+	s := &StatsService{
+		factoryService: factoryService,
+		eventService:   eventService,
+		ipfsClient:     ipfsClient,
+		path:           path,
+		logger:         logger,
+		achievements:   DefaultAchievements,
+	}
+	if path != "" {
+		if _, err := readJSONFile(path, &s.file); err != nil {
+			return nil, fmt.Errorf("failed to load stats file: %w", err)
+		}
+	}
+	s.file.init()
+	return s, nil
+}
+
+func (f *statsFile) init() {
+	if f.Traders == nil {
+		f.Traders = make(map[string]bool)
+	}
+	if f.DailyVolume == nil {
+		f.DailyVolume = make(map[string]float64)
+	}
+	if f.Counted == nil {
+		f.Counted = make(map[string]uint32)
+	}
+	if f.Cursors == nil {
+		f.Cursors = make(map[string]string)
+	}
+	if f.CountedFrom == nil {
+		// Files from before backfills record no coverage: a backfill must not count
+		// their markets' trades again.
+		f.CountedFrom = make(map[string]uint32, len(f.Counted))
+		for id := range f.Counted {
+			f.CountedFrom[id] = 0
+		}
+	}
+	if f.Net == nil {
+		f.Net = make(map[string]*marketNet)
+	}
+	if f.Baselines == nil {
+		f.Baselines = make(map[string]marketNet)
+	}
+	if f.Resolutions == nil {
+		f.Resolutions = make(map[string]time.Duration)
+	}
+	if f.Outcomes == nil {
+		f.Outcomes = make(map[string]string)
+	}
+	if f.Accounts == nil {
+		f.Accounts = make(map[string]*accountTotals)
+	}
+	if f.Created == nil {
+		f.Created = make(map[string]time.Time)
+	}
+	if f.Achievements == nil {
+		f.Achievements = make(map[string]map[string]time.Time)
+	}
+}
+
+// Refresh counts new trades of all markets and the resolution times of newly
+// resolved ones.
+func (s *StatsService) Refresh(ctx context.Context) error {
+	if !s.factoryService.HasFactory() {
+		return nil
+	}
+	ids, err := s.factoryService.ListMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+	loaded, err := s.factoryService.GetMarketStates(ctx, ids)
+	if err != nil {
+		s.logger.Warn("stats: failed to get some market states", "error", err)
+	}
+	states := loaded.States
+
+	var errs []error
+	var open, resolved, recentHour int
+	var pool int64
+	hourAgo := time.Now().Add(-time.Hour)
+	for _, state := range states {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		created, err := s.marketCreated(ctx, state)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+		}
+		if state.Resolved {
+			resolved++
+			s.recordOutcome(state)
+			if err := s.recordResolution(ctx, state, created); err != nil {
+				errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			}
+		} else {
+			open++
+		}
+		pool += state.Pool
+		// Resolved markets still count: their last trades may be within the lookback.
+		events, err := s.eventService.GetTradeEvents(ctx, state.ContractID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %s: %w", state.ContractID, err))
+			continue
+		}
+		s.count(state.ContractID, events, time.Now())
+		var lastTrade time.Time
+		for _, evt := range events {
+			if evt.Timestamp.After(hourAgo) {
+				recentHour++
+			}
+			if evt.Timestamp.After(lastTrade) {
+				lastTrade = evt.Timestamp
+			}
+		}
+		s.checkDivergence(state, lastTrade, time.Now())
+	}
+
+	s.mu.Lock()
+	s.open, s.resolved = open, resolved
+	s.pool, s.recentHour = pool, recentHour
+	s.updated = time.Now()
+	s.pruneDivergencesLocked(s.updated)
+	if awarded := s.awardLocked(s.updated); awarded > 0 {
+		s.logger.Info("achievements awarded", "count", awarded)
+	}
+	if err := s.saveLocked(); err != nil {
+		errs = append(errs, err)
+	}
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// count adds the trades of a market after its cursor.
+func (s *StatsService) count(contractID string, events []TradeEvent, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file.Since.IsZero() {
+		s.file.Since = now
+	}
+	for _, evt := range events {
+		if !s.file.isNew(contractID, evt) {
+			continue
+		}
+		if _, ok := s.file.CountedFrom[contractID]; !ok {
+			s.file.CountedFrom[contractID] = evt.Ledger
+		}
+		s.addLocked(contractID, evt)
+	}
+	s.pruneDailyLocked(now)
+}
+
+// addLocked counts one trade of a market.
+func (s *StatsService) addLocked(contractID string, evt TradeEvent) {
+	s.file.Volume += evt.Cost
+	s.file.Trades++
+	s.file.Traders[evt.User] = true
+	s.file.DailyVolume[evt.Timestamp.UTC().Format(statsDayFormat)] += evt.Cost
+	s.file.account(evt.User).add(contractID, evt)
+	if s.file.Net[contractID] == nil {
+		s.file.Net[contractID] = &marketNet{}
+	}
+	s.file.Net[contractID].add(evt)
+	s.file.Counted[contractID] = max(s.file.Counted[contractID], evt.Ledger)
+	s.file.Cursors[contractID] = max(s.file.Cursors[contractID], evt.ID)
+}
+
+// isNew reports whether a trade is after the market's cursor. Without event IDs on
+// both sides, whole ledgers are compared.
+func (f *statsFile) isNew(contractID string, evt TradeEvent) bool {
+	if cursor := f.Cursors[contractID]; cursor != "" && evt.ID != "" {
+		return evt.ID > cursor
+	}
+	return evt.Ledger > f.Counted[contractID]
+}
+
+// pruneDailyLocked drops days beyond the daily volume history.
+func (s *StatsService) pruneDailyLocked(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -statsHistoryDays).Format(statsDayFormat)
+	maps.DeleteFunc(s.file.DailyVolume, func(day string, _ float64) bool { return day < cutoff })
+}
+
+// recordResolution stores how long a resolved market took from creation to resolution.
+// Markets resolved before the event lookback, or without a creation time, are skipped.
+func (s *StatsService) recordResolution(ctx context.Context, state MarketState, created time.Time) error {
+	s.mu.RLock()
+	_, known := s.file.Resolutions[state.ContractID]
+	s.mu.RUnlock()
+	if known || created.IsZero() {
+		return nil
+	}
+
+	resolution, found, err := s.eventService.GetResolution(ctx, state.ContractID)
+	if err != nil || !found {
+		return err
+	}
+	if resolution.Timestamp.Before(created) {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.file.Resolutions[state.ContractID] = resolution.Timestamp.Sub(created)
+	s.mu.Unlock()
+	return nil
+}
+
+// marketCreated returns a market's creation time from its metadata, fetched once per
+// market. It is zero for markets without metadata or without a creation time.
+func (s *StatsService) marketCreated(ctx context.Context, state MarketState) (time.Time, error) {
+	s.mu.RLock()
+	created, known := s.file.Created[state.ContractID]
+	s.mu.RUnlock()
+	if known || state.MetadataHash == "" {
+		return created, nil
+	}
+
+	var metadata model.MarketMetadata
+	if err := s.ipfsClient.GetJSON(ctx, state.MetadataHash, &metadata); err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	s.mu.Lock()
+	s.file.Created[state.ContractID] = metadata.CreatedAt
+	s.mu.Unlock()
+	return metadata.CreatedAt, nil
+}
+
+// Stats returns the latest aggregated statistics; UpdatedAt is zero before the
+// first refresh.
+func (s *StatsService) Stats() PlatformStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := PlatformStats{
+		OpenMarkets:     s.open,
+		ResolvedMarkets: s.resolved,
+		Volume:          s.file.Volume,
+		Trades:          s.file.Trades,
+		TradesLastHour:  s.recentHour,
+		PoolLocked:      float64(s.pool) / float64(soroban.ScaleFactor),
+		Traders:         len(s.file.Traders),
+		ResolutionCount: len(s.file.Resolutions),
+		Since:           s.file.Since,
+		UpdatedAt:       s.updated,
+	}
+	if stats.ResolutionCount > 0 {
+		var total time.Duration
+		for _, d := range s.file.Resolutions {
+			total += d
+		}
+		stats.AvgResolution = total / time.Duration(stats.ResolutionCount)
+	}
+	// Fill days without trades, so the series has one entry per day.
+	days := slices.Sorted(maps.Keys(s.file.DailyVolume))
+	if len(days) > 0 {
+		first, err1 := time.Parse(statsDayFormat, days[0])
+		last, err2 := time.Parse(statsDayFormat, days[len(days)-1])
+		if err1 == nil && err2 == nil {
+			for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+				stats.DailyVolume = append(stats.DailyVolume, DailyVolume{Day: day, Volume: s.file.DailyVolume[day.Format(statsDayFormat)]})
+			}
+		}
+	}
+	return stats
+}
+
+func (s *StatsService) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(s.path, s.file); err != nil {
+		return fmt.Errorf("failed to save stats file: %w", err)
+	}
+	return nil
+}
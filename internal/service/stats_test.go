@@ -0,0 +1,257 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStatsServiceCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	s := &StatsService{path: path, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	events := []TradeEvent{
+		{Kind: TradeKindBuy, User: "GA", Cost: 10, Ledger: 100, Timestamp: day},
+		{Kind: TradeKindBuy, User: "GB", Cost: 5, Ledger: 101, Timestamp: day},
+	}
+	s.count(testContractID, events, day)
+
+	// The next refresh sees the same events again plus a new one the next day.
+	events = append(events, TradeEvent{Kind: TradeKindSell, User: "GA", Cost: 2.5, Ledger: 200, Timestamp: day.Add(24 * time.Hour)})
+	s.count(testContractID, events, day.Add(24*time.Hour))
+
+	s.file.Resolutions["CA"] = 10 * 24 * time.Hour
+	s.file.Resolutions["CB"] = 20 * 24 * time.Hour
+
+	stats := s.Stats()
+	if math.Abs(stats.Volume-17.5) > 1e-9 || stats.Trades != 3 || stats.Traders != 2 {
+		t.Errorf("Stats() volume, trades, traders = %v, %d, %d, want 17.5, 3, 2", stats.Volume, stats.Trades, stats.Traders)
+	}
+	if len(stats.DailyVolume) != 2 || stats.DailyVolume[0].Volume != 15 || stats.DailyVolume[1].Volume != 2.5 {
+		t.Errorf("Stats() daily volume = %+v, want 15 then 2.5", stats.DailyVolume)
+	}
+
+	// Days without trades are filled in.
+	s.count("CB", []TradeEvent{{User: "GC", Cost: 1, Ledger: 300, Timestamp: day.AddDate(0, 0, 3)}}, day.AddDate(0, 0, 3))
+	if daily := s.Stats().DailyVolume; len(daily) != 4 || daily[2].Volume != 0 || daily[3].Volume != 1 {
+		t.Errorf("Stats() daily volume = %+v, want 4 days with an empty third", daily)
+	}
+	if stats.AvgResolution != 15*24*time.Hour || stats.ResolutionCount != 2 {
+		t.Errorf("Stats() average resolution = %v over %d, want 360h over 2", stats.AvgResolution, stats.ResolutionCount)
+	}
+	if !stats.Since.Equal(day) {
+		t.Errorf("Stats() since = %v, want %v", stats.Since, day)
+	}
+
+	// Days beyond the history are dropped from the series, not from the totals.
+	s.count(testContractID, nil, day.AddDate(0, 0, statsHistoryDays+1))
+	if stats := s.Stats(); len(stats.DailyVolume) != 3 || math.Abs(stats.Volume-18.5) > 1e-9 {
+		t.Errorf("Stats() after %d days = %+v, want the first day dropped and unchanged volume", statsHistoryDays, stats)
+	}
+
+	// Totals survive a restart.
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		t.Fatalf("saveLocked() error = %v", err)
+	}
+	s.mu.Unlock()
+	var loaded statsFile
+	if _, err := readJSONFile(path, &loaded); err != nil {
+		t.Fatalf("readJSONFile() error = %v", err)
+	}
+	if loaded.Trades != 4 || loaded.Counted[testContractID] != 200 {
+		t.Errorf("loaded trades = %d, counted ledger = %d, want 4, 200", loaded.Trades, loaded.Counted[testContractID])
+	}
+}
+
+func TestStatsServiceAccountStats(t *testing.T) {
+	s := &StatsService{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	// GA wins CA, loses CB, sells out of CC at a profit and still holds CD.
+	s.count("CA", []TradeEvent{
+		{Kind: TradeKindBuy, User: "GA", Outcome: "YES", Amount: 20, Cost: 10, Ledger: 100, Timestamp: day},
+		{Kind: TradeKindSell, User: "GA", Outcome: "YES", Amount: 5, Cost: 3, Ledger: 101, Timestamp: day},
+		{Kind: TradeKindBuy, User: "GB", Outcome: "NO", Amount: 10, Cost: 5, Ledger: 102, Timestamp: day},
+	}, day)
+	s.count("CB", []TradeEvent{{Kind: TradeKindBuy, User: "GA", Outcome: "YES", Amount: 8, Cost: 4, Ledger: 100, Timestamp: day}}, day)
+	s.count("CC", []TradeEvent{
+		{Kind: TradeKindBuy, User: "GA", Outcome: "NO", Amount: 10, Cost: 4, Ledger: 100, Timestamp: day},
+		{Kind: TradeKindSell, User: "GA", Outcome: "NO", Amount: 10, Cost: 6, Ledger: 110, Timestamp: day},
+	}, day)
+	s.count("CD", []TradeEvent{{Kind: TradeKindBuy, User: "GA", Outcome: "NO", Amount: 10, Cost: 5, Ledger: 100, Timestamp: day}}, day)
+	s.recordOutcome(MarketState{ContractID: "CA", Resolved: true, WinningOutcome: "YES"})
+	s.recordOutcome(MarketState{ContractID: "CB", Resolved: true, WinningOutcome: "NO"})
+
+	stats := s.AccountStats("GA")
+	if stats.Trades != 6 || math.Abs(stats.Volume-32) > 1e-9 || stats.Markets != 4 {
+		t.Errorf("AccountStats() trades, volume, markets = %d, %v, %d, want 6, 32, 4", stats.Trades, stats.Volume, stats.Markets)
+	}
+	// CA: 3 + 15*0.98 - 10 = 7.7; CB: -4; CC: 2
+	if stats.Settled != 3 || stats.Wins != 2 || math.Abs(stats.WinRate-2.0/3) > 1e-9 {
+		t.Errorf("AccountStats() settled, wins, win rate = %d, %d, %v, want 3, 2, 0.67", stats.Settled, stats.Wins, stats.WinRate)
+	}
+	if math.Abs(stats.RealizedPnL-5.7) > 1e-9 {
+		t.Errorf("AccountStats() realized P&L = %v, want 5.7", stats.RealizedPnL)
+	}
+
+	if empty := s.AccountStats("GZ"); empty.Trades != 0 || empty.Account != "GZ" {
+		t.Errorf("AccountStats() of an unknown account = %+v, want zero totals", empty)
+	}
+}
+
+func TestStatsServiceAwardsAchievements(t *testing.T) {
+	s := &StatsService{achievements: DefaultAchievements, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	s.file.Created["CA"] = created
+	s.file.Created["CB"] = created
+	// GA buys YES in CA at 8% a day after creation; CA resolves YES. GB trades CB early.
+	s.count("CA", []TradeEvent{{Kind: TradeKindBuy, User: "GA", Outcome: "YES", Amount: 100, Cost: 8, Ledger: 100, Timestamp: created.Add(30 * time.Hour)}}, created)
+	s.count("CB", []TradeEvent{{Kind: TradeKindBuy, User: "GB", Outcome: "NO", Amount: 10, Cost: 5, Ledger: 100, Timestamp: created.Add(time.Hour)}}, created)
+	s.recordOutcome(MarketState{ContractID: "CA", Resolved: true, WinningOutcome: "YES"})
+
+	now := created.Add(48 * time.Hour)
+	s.mu.Lock()
+	if awarded := s.awardLocked(now); awarded != 4 {
+		t.Errorf("awardLocked() = %d, want 4", awarded)
+	}
+	if awarded := s.awardLocked(now.Add(time.Hour)); awarded != 0 {
+		t.Errorf("second awardLocked() = %d, want nothing new", awarded)
+	}
+	s.mu.Unlock()
+
+	ids := func(list []EarnedAchievement) []string {
+		var out []string
+		for _, a := range list {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+	if got := ids(s.Achievements("GA")); !slices.Equal(got, []string{"first-trade", "upset"}) {
+		t.Errorf("Achievements(GA) = %v, want first-trade and upset", got)
+	}
+	if got := s.Achievements("GB"); len(got) != 2 || got[1].ID != "early-bettor" || !got[1].EarnedAt.Equal(now) {
+		t.Errorf("Achievements(GB) = %+v, want first-trade and early-bettor earned at %v", got, now)
+	}
+
+	// Earned achievements are kept when the rule set no longer awards them.
+	s.SetAchievements(DefaultAchievements[:2])
+	if got := ids(s.Achievements("GA")); !slices.Equal(got, []string{"first-trade"}) {
+		t.Errorf("Achievements(GA) with a smaller rule set = %v, want first-trade", got)
+	}
+
+	board := s.Leaderboard(10, func(account string) bool { return account == "GB" })
+	if len(board) != 1 || board[0].Stats.Account != "GA" || board[0].Rank != 1 {
+		t.Errorf("Leaderboard() = %+v, want only GA", board)
+	}
+}
+
+func TestProfileSettingsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := NewProfileSettings(path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetHidden("GA", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetHidden("GB", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetHidden("GB", false); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewProfileSettings(path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Hidden("GA") || loaded.Hidden("GB") {
+		t.Errorf("after reload Hidden(GA), Hidden(GB) = %v, %v, want true, false", loaded.Hidden("GA"), loaded.Hidden("GB"))
+	}
+}
+
+func TestStatsServiceBackfill(t *testing.T) {
+	s := &StatsService{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	// The aggregation first saw CA at ledger 500; CB had no trades in its window.
+	s.count("CA", []TradeEvent{{User: "GA", Cost: 10, Ledger: 500, Timestamp: day}}, day)
+
+	history := map[string][]TradeEvent{
+		"CA": {
+			{User: "GB", Cost: 4, Ledger: 100, Timestamp: day.Add(-48 * time.Hour)},
+			{User: "GA", Cost: 10, Ledger: 500, Timestamp: day}, // already counted
+		},
+		"CB": {{User: "GC", Cost: 1, Ledger: 200, Timestamp: day.Add(-24 * time.Hour)}},
+	}
+	bounds := map[string]uint32{"CA": s.file.CountedFrom["CA"], "CB": 0}
+	if n := s.backfillLocked(history, bounds, day); n != 2 {
+		t.Errorf("backfillLocked() = %d, want 2", n)
+	}
+
+	stats := s.Stats()
+	if math.Abs(stats.Volume-15) > 1e-9 || stats.Trades != 3 || stats.Traders != 3 {
+		t.Errorf("Stats() volume, trades, traders = %v, %d, %d, want 15, 3, 3", stats.Volume, stats.Trades, stats.Traders)
+	}
+	if !stats.Since.Equal(day.Add(-48 * time.Hour)) {
+		t.Errorf("Stats() since = %v, want the oldest backfilled trade", stats.Since)
+	}
+	if s.file.CountedFrom["CA"] != 100 || s.file.CountedFrom["CB"] != 200 || s.file.Counted["CB"] != 200 {
+		t.Errorf("counted from = %v, counted = %v, want CA from 100, CB 200-200", s.file.CountedFrom, s.file.Counted)
+	}
+
+	// The next refresh does not count CB's trade again.
+	s.count("CB", history["CB"], day)
+	if trades := s.Stats().Trades; trades != 3 {
+		t.Errorf("Stats() trades after refresh = %d, want 3", trades)
+	}
+
+	// Markets counted before coverage was tracked are never backfilled.
+	legacy := statsFile{Counted: map[string]uint32{"CA": 500}}
+	legacy.init()
+	if from, ok := legacy.CountedFrom["CA"]; !ok || from != 0 {
+		t.Errorf("legacy counted from = %d, %v, want 0, true", from, ok)
+	}
+}
+
+func TestStatsServiceCountResumesAtCursor(t *testing.T) {
+	s := &StatsService{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.file.init()
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	ledger := []TradeEvent{
+		{User: "GA", Cost: 1, Ledger: 100, ID: "0000000429496729600-0000000001", Timestamp: day},
+		{User: "GB", Cost: 2, Ledger: 100, ID: "0000000429496733696-0000000001", Timestamp: day},
+	}
+	// A refresh saw only the first trade of ledger 100, e.g. at the end of a page.
+	s.count(testContractID, ledger[:1], day)
+	s.count(testContractID, ledger, day)
+	s.count(testContractID, ledger, day)
+	if stats := s.Stats(); stats.Trades != 2 || math.Abs(stats.Volume-3) > 1e-9 {
+		t.Errorf("Stats() trades, volume = %d, %v, want 2, 3", stats.Trades, stats.Volume)
+	}
+
+	// Files from before cursors compare ledgers.
+	legacy := &StatsService{logger: s.logger}
+	legacy.file.Counted = map[string]uint32{testContractID: 100}
+	legacy.file.init()
+	legacy.count(testContractID, append(ledger, TradeEvent{User: "GC", Cost: 4, Ledger: 101, ID: "0000000433791696896-0000000001", Timestamp: day}), day)
+	if trades := legacy.Stats().Trades; trades != 1 {
+		t.Errorf("legacy Stats() trades = %d, want 1", trades)
+	}
+	if cursor := legacy.file.Cursors[testContractID]; cursor != "0000000433791696896-0000000001" {
+		t.Errorf("legacy cursor = %q, want the last counted event", cursor)
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+)
+
+// StatusRefreshInterval is how often the background scheduler refreshes network status.
+const StatusRefreshInterval = 30 * time.Second
+
+// statusStaleAfter marks the status as stale when several refreshes in a row were missed.
+const statusStaleAfter = 3 * StatusRefreshInterval
+
+// NetworkStatus is a snapshot of Soroban RPC and Horizon health.
+// Error fields are empty when the corresponding backend responded.
+type NetworkStatus struct {
+	LatestLedger   uint32    `json:"latest_ledger"`
+	LedgerClosedAt time.Time `json:"ledger_closed_at,omitzero"`
+	RPCStatus      string    `json:"rpc_status"`
+	RPCError       string    `json:"rpc_error,omitempty"`
+	HorizonLedger  int32     `json:"horizon_ledger"`
+	HorizonError   string    `json:"horizon_error,omitempty"`
+	SuggestedFee   int64     `json:"suggested_fee"` // stroops, mode of max fees in recent ledgers
+	UpdatedAt      time.Time `json:"updated_at,omitzero"`
+	Stale          bool      `json:"stale"`
+}
+
+// Healthy reports whether both backends responded and the RPC reports healthy.
+func (s NetworkStatus) Healthy() bool {
+	return !s.Stale && s.RPCError == "" && s.HorizonError == "" && s.RPCStatus == "healthy"
+}
+
+// StatusService keeps the latest network status, refreshed in the background.
+type StatusService struct {
+	sorobanClient *soroban.Client
+	stellarClient stellar.Client
+	logger        *slog.Logger
+
+	mu     sync.RWMutex
+	status NetworkStatus
+}
+
+// NewStatusService creates a new status service.
+func NewStatusService(sorobanClient *soroban.Client, stellarClient stellar.Client, logger *slog.Logger) *StatusService {
+	if sorobanClient == nil {
+		panic("NewStatusService: sorobanClient must not be nil")
+	}
+	if stellarClient == nil {
+		panic("NewStatusService: stellarClient must not be nil")
+	}
+	if logger == nil {
+		panic("NewStatusService: logger must not be nil")
+	}
+	return &StatusService{
+		sorobanClient: sorobanClient,
+		stellarClient: stellarClient,
+		logger:        logger,
+	}
+}
+
+// Status returns the last refreshed network status.
+func (s *StatusService) Status() NetworkStatus {
+	s.mu.RLock()
+	status := s.status
+	s.mu.RUnlock()
+
+	status.Stale = status.UpdatedAt.IsZero() || time.Since(status.UpdatedAt) > statusStaleAfter
+	return status
+}
+
+// Refresh queries Soroban RPC and Horizon and stores a new snapshot.
+// A failing backend is recorded in the snapshot; the error is returned for logging.
+func (s *StatusService) Refresh(ctx context.Context) error {
+	// Each backend fills its own snapshot so the goroutines never share fields.
+	var (
+		status     NetworkStatus
+		horizon    NetworkStatus
+		wg         sync.WaitGroup
+		rpcErr     error
+		horizonErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		rpcErr = s.refreshRPC(ctx, &status)
+	}()
+	go func() {
+		defer wg.Done()
+		horizonErr = s.refreshHorizon(ctx, &horizon)
+	}()
+	wg.Wait()
+
+	status.HorizonLedger = horizon.HorizonLedger
+	status.SuggestedFee = horizon.SuggestedFee
+	if status.LedgerClosedAt.IsZero() {
+		status.LedgerClosedAt = horizon.LedgerClosedAt
+	}
+
+	if rpcErr != nil {
+		status.RPCError = "Soroban RPC unavailable"
+	}
+	if horizonErr != nil {
+		status.HorizonError = "Horizon unavailable"
+	}
+	status.UpdatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+
+	return errors.Join(rpcErr, horizonErr)
+}
+
+// refreshRPC fills the Soroban RPC fields of status.
+func (s *StatusService) refreshRPC(ctx context.Context, status *NetworkStatus) error {
+	health, err := s.sorobanClient.GetHealth(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get RPC health: %w", err)
+	}
+	status.RPCStatus = health.Status
+
+	latest, err := s.sorobanClient.GetLatestLedger(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest ledger: %w", err)
+	}
+	status.LatestLedger = latest.Sequence
+
+	if latest.CloseTime != "" {
+		closeTime, err := strconv.ParseInt(latest.CloseTime, 10, 64)
+		if err != nil {
+			s.logger.Debug("failed to parse ledger close time", "close_time", latest.CloseTime, "error", err)
+		} else {
+			status.LedgerClosedAt = time.Unix(closeTime, 0).UTC()
+		}
+	}
+	return nil
+}
+
+// refreshHorizon fills the Horizon fields of status.
+func (s *StatusService) refreshHorizon(ctx context.Context, status *NetworkStatus) error {
+	root, err := s.stellarClient.GetRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get horizon status: %w", err)
+	}
+	status.HorizonLedger = root.HorizonSequence
+	status.LedgerClosedAt = root.HorizonLatestClosedAt.UTC()
+
+	feeStats, err := s.stellarClient.GetFeeStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get fee stats: %w", err)
+	}
+	status.SuggestedFee = feeStats.MaxFee.Mode
+	return nil
+}
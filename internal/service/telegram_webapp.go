@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InitDataMaxAge is how long Telegram Mini App launch data is accepted after
+// Telegram signed it.
+const InitDataMaxAge = 24 * time.Hour
+
+var (
+	ErrInitDataInvalid = errors.New("invalid Telegram launch data")
+	ErrInitDataExpired = errors.New("Telegram launch data expired, reopen the app")
+)
+
+// TelegramUser is the Telegram account that opened the Mini App. Its ID is also
+// the chat ID of the user's private chat with the bot.
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// TelegramWebApp verifies the initData Telegram passes to a Mini App, which is
+// signed with a key derived from the bot token.
+type TelegramWebApp struct {
+	secret []byte
+}
+
+// NewTelegramWebApp creates a verifier for Mini Apps of the bot with the given token.
+func NewTelegramWebApp(botToken string) (*TelegramWebApp, error) {
+	if botToken == "" {
+		return nil, errors.New("telegram bot token must not be empty")
+	}
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(botToken))
+	return &TelegramWebApp{secret: mac.Sum(nil)}, nil
+}
+
+// Verify checks the signature and age of initData and returns the user in it.
+func (t *TelegramWebApp) Verify(initData string) (*TelegramUser, error) {
+	return t.verify(initData, time.Now())
+}
+
+func (t *TelegramWebApp) verify(initData string, now time.Time) (*TelegramUser, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, ErrInitDataInvalid
+	}
+	hash, err := hex.DecodeString(values.Get("hash"))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, ErrInitDataInvalid
+	}
+
+	// The data-check-string is every other field as key=value, sorted by key.
+	fields := make([]string, 0, len(values))
+	for key := range values {
+		if key != "hash" {
+			fields = append(fields, key+"="+values.Get(key))
+		}
+	}
+	slices.Sort(fields)
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(strings.Join(fields, "\n")))
+	if !hmac.Equal(mac.Sum(nil), hash) {
+		return nil, ErrInitDataInvalid
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, ErrInitDataInvalid
+	}
+	if now.Sub(time.Unix(authDate, 0)) > InitDataMaxAge {
+		return nil, ErrInitDataExpired
+	}
+
+	var user TelegramUser
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return nil, ErrInitDataInvalid
+	}
+	return &user, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// signInitData signs launch data the way Telegram does for the bot token.
+func signInitData(botToken string, values url.Values) string {
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	var check string
+	for i, key := range sortedKeys(values) {
+		if i > 0 {
+			check += "\n"
+		}
+		check += key + "=" + values.Get(key)
+	}
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(check))
+
+	signed := url.Values{}
+	for key := range values {
+		signed.Set(key, values.Get(key))
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed.Encode()
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestTelegramWebApp_Verify(t *testing.T) {
+	const botToken = "123456:test-token"
+	app, err := NewTelegramWebApp(botToken)
+	if err != nil {
+		t.Fatalf("NewTelegramWebApp() error = %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	launch := func(authDate time.Time, user string) url.Values {
+		return url.Values{
+			"query_id":  {"AAHdF6IQAAAAAN0XohDhrOrc"},
+			"user":      {user},
+			"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		}
+	}
+	validUser := `{"id":279058397,"first_name":"Vlad","username":"vlad"}`
+
+	tampered, _ := url.ParseQuery(signInitData(botToken, launch(now, validUser)))
+	tampered.Set("user", `{"id":1,"first_name":"Mallory"}`)
+
+	tests := []struct {
+		name     string
+		initData string
+		wantID   int64
+		wantErr  error
+	}{
+		{"valid", signInitData(botToken, launch(now.Add(-time.Minute), validUser)), 279058397, nil},
+		{"other bot", signInitData("654321:other", launch(now, validUser)), 0, ErrInitDataInvalid},
+		{"tampered user", tampered.Encode(), 0, ErrInitDataInvalid},
+		{"expired", signInitData(botToken, launch(now.Add(-InitDataMaxAge-time.Minute), validUser)), 0, ErrInitDataExpired},
+		{"no user", signInitData(botToken, launch(now, "")), 0, ErrInitDataInvalid},
+		{"no hash", launch(now, validUser).Encode(), 0, ErrInitDataInvalid},
+		{"empty", "", 0, ErrInitDataInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := app.verify(tt.initData, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.ID != tt.wantID {
+				t.Errorf("verify() user ID = %d, want %d", user.ID, tt.wantID)
+			}
+		})
+	}
+}
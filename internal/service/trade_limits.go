@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTradeTooLarge is returned for a trade over the per-trade limits.
+	ErrTradeTooLarge = errors.New("trade is too large")
+	// ErrPositionTooLarge is returned for a buy that would take an account's position
+	// in a market over the limit.
+	ErrPositionTooLarge = errors.New("position would be too large")
+)
+
+// TradeLimits bounds trade sizes, so operators can stop fat-fingered amounts and
+// outsized positions. Zero fields are unlimited.
+type TradeLimits struct {
+	MaxTradeTokens    float64 // Tokens bought or sold in one trade
+	MaxTradeCost      float64 // Collateral paid for one buy
+	MaxPositionTokens float64 // Tokens of one outcome an account may hold in a market
+}
+
+// checkTokens checks the token amount of a buy or sell.
+func (l TradeLimits) checkTokens(tokens float64) error {
+	if l.MaxTradeTokens > 0 && tokens > l.MaxTradeTokens {
+		return fmt.Errorf("%w: at most %g tokens per trade", ErrTradeTooLarge, l.MaxTradeTokens)
+	}
+	return nil
+}
+
+// checkCost checks the collateral paid for a buy.
+func (l TradeLimits) checkCost(cost float64) error {
+	if l.MaxTradeCost > 0 && cost > l.MaxTradeCost {
+		return fmt.Errorf("%w: at most %g per trade", ErrTradeTooLarge, l.MaxTradeCost)
+	}
+	return nil
+}
+
+// checkPosition checks the tokens of one outcome an account holds after a buy.
+func (l TradeLimits) checkPosition(held, bought float64) error {
+	if l.MaxPositionTokens > 0 && held+bought > l.MaxPositionTokens {
+		return fmt.Errorf("%w: at most %g tokens of an outcome per market, %g held", ErrPositionTooLarge, l.MaxPositionTokens, held)
+	}
+	return nil
+}
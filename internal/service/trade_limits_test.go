@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTradeLimits(t *testing.T) {
+	limits := TradeLimits{MaxTradeTokens: 100, MaxTradeCost: 50, MaxPositionTokens: 300}
+
+	tests := []struct {
+		name    string
+		check   func() error
+		wantErr error
+	}{
+		{"tokens at limit", func() error { return limits.checkTokens(100) }, nil},
+		{"tokens over limit", func() error { return limits.checkTokens(100.5) }, ErrTradeTooLarge},
+		{"cost at limit", func() error { return limits.checkCost(50) }, nil},
+		{"cost over limit", func() error { return limits.checkCost(51) }, ErrTradeTooLarge},
+		{"position at limit", func() error { return limits.checkPosition(250, 50) }, nil},
+		{"position over limit", func() error { return limits.checkPosition(250, 51) }, ErrPositionTooLarge},
+		{"unlimited tokens", func() error { return TradeLimits{}.checkTokens(1e12) }, nil},
+		{"unlimited cost", func() error { return TradeLimits{}.checkCost(1e12) }, nil},
+		{"unlimited position", func() error { return TradeLimits{}.checkPosition(1e12, 1e12) }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+)
+
+var (
+	ErrReplayTxNotFound  = errors.New("transaction not found; it may be pending or older than the RPC retention window")
+	ErrReplayTxSucceeded = errors.New("transaction succeeded; only failed transactions are replayed")
+)
+
+// ReplayCause is the likely reason a transaction failed, inferred by TxReplayer.
+type ReplayCause string
+
+const (
+	ReplayCauseSlippage       ReplayCause = "slippage"
+	ReplayCauseResolved       ReplayCause = "market_resolved"
+	ReplayCauseNotResolved    ReplayCause = "market_not_resolved"
+	ReplayCauseAuthExpired    ReplayCause = "auth_expired"
+	ReplayCauseResources      ReplayCause = "resources"
+	ReplayCauseArchived       ReplayCause = "archived_state"
+	ReplayCauseBalance        ReplayCause = "insufficient_balance"
+	ReplayCauseNothingToClaim ReplayCause = "nothing_to_claim"
+	ReplayCauseUnauthorized   ReplayCause = "unauthorized"
+	ReplayCauseStateChanged   ReplayCause = "state_changed"
+	ReplayCauseUnknown        ReplayCause = "unknown"
+)
+
+// replayExplanations says what each cause means for the user, for support replies.
+var replayExplanations = map[ReplayCause]string{
+	ReplayCauseSlippage:       "The price moved past the slippage limit between building and inclusion. Retry, or allow more slippage.",
+	ReplayCauseResolved:       "The market was resolved before the transaction landed; trading is closed. Claim instead if the position won.",
+	ReplayCauseNotResolved:    "The market is not resolved yet, so there is nothing to claim or withdraw.",
+	ReplayCauseAuthExpired:    "The signed authorization entries expired before the transaction was included. Refresh the transaction and sign it again.",
+	ReplayCauseResources:      "The transaction ran out of the resources or refundable fee reserved at simulation. Refresh it to re-simulate against current state.",
+	ReplayCauseArchived:       "Contract state it needed was archived. Restore the entries, then rebuild the transaction.",
+	ReplayCauseBalance:        "The account held fewer tokens than it tried to sell.",
+	ReplayCauseNothingToClaim: "The account holds no winning tokens, or has already claimed.",
+	ReplayCauseUnauthorized:   "The call needs the oracle's authorization and was signed by another account.",
+	ReplayCauseStateChanged:   "The same call succeeds against current state, so it failed on state at the time. Rebuilding it should work.",
+	ReplayCauseUnknown:        "No known cause matched; see the original result codes and the replay trace.",
+}
+
+// marketErrors names the lmsr_market contract errors, see contracts/lmsr_market/src/error.rs.
+var marketErrors = map[uint32]string{
+	1:  "AlreadyInitialized",
+	2:  "NotInitialized",
+	3:  "AlreadyResolved",
+	4:  "NotResolved",
+	5:  "InvalidOutcome",
+	6:  "InvalidAmount",
+	7:  "InsufficientBalance",
+	8:  "SlippageExceeded",
+	9:  "ReturnTooLow",
+	10: "Unauthorized",
+	11: "InvalidLiquidity",
+	12: "Overflow",
+	13: "NothingToClaim",
+	14: "StorageCorrupted",
+	15: "InsufficientPool",
+}
+
+// marketErrorCauses maps market contract error codes to failure causes.
+var marketErrorCauses = map[uint32]ReplayCause{
+	3:  ReplayCauseResolved,
+	4:  ReplayCauseNotResolved,
+	7:  ReplayCauseBalance,
+	8:  ReplayCauseSlippage,
+	9:  ReplayCauseSlippage,
+	10: ReplayCauseUnauthorized,
+	13: ReplayCauseNothingToClaim,
+}
+
+// marketFunctions are the lmsr_market functions whose errors marketErrors names.
+var marketFunctions = map[string]bool{
+	"buy":                true,
+	"sell":               true,
+	"claim":              true,
+	"resolve":            true,
+	"withdraw_remaining": true,
+}
+
+// ReplayRun is the result of one execution of the call: the original on-chain one or
+// the replay simulated against current state.
+type ReplayRun struct {
+	Ledger        uint32   `json:"ledger"`
+	Succeeded     bool     `json:"succeeded"`
+	Error         string   `json:"error,omitempty"`          // result codes, or the simulation error
+	ContractError string   `json:"contract_error,omitempty"` // e.g. "SlippageExceeded (#8)"
+	ReturnValue   string   `json:"return_value,omitempty"`
+	Trace         []string `json:"trace,omitempty"`
+
+	code uint32
+}
+
+// TxReplay compares a failed transaction with the same call simulated now.
+type TxReplay struct {
+	Hash       string    `json:"hash"`
+	Source     string    `json:"source"`
+	ContractID string    `json:"contract_id"`
+	Function   string    `json:"function"`
+	Original   ReplayRun `json:"original"`
+	Replay     ReplayRun `json:"replay"`
+	// Reproduced is set when the call still fails the same way against current state.
+	Reproduced  bool        `json:"reproduced"`
+	Cause       ReplayCause `json:"cause"`
+	Explanation string      `json:"explanation"`
+}
+
+// TxReplayer re-runs failed transactions for support: it fetches one with
+// getTransaction, simulates the same contract call against current state and
+// compares the two, so "my trade failed" can be answered without digging into XDR.
+type TxReplayer struct {
+	sorobanClient *soroban.Client
+	txBuilder     *stellar.Builder
+}
+
+// NewTxReplayer creates a replayer.
+func NewTxReplayer(sorobanClient *soroban.Client, txBuilder *stellar.Builder) *TxReplayer {
+	if sorobanClient == nil || txBuilder == nil {
+		panic("tx replayer requires the soroban client and transaction builder")
+	}
+	return &TxReplayer{sorobanClient: sorobanClient, txBuilder: txBuilder}
+}
+
+// Replay fetches a failed transaction, simulates its call again and reports the
+// likely cause of the failure.
+func (r *TxReplayer) Replay(ctx context.Context, hash string) (*TxReplay, error) {
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTxHash, hash)
+	}
+
+	result, err := r.sorobanClient.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	outcome, err := result.Outcome(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s: %w", hash, err)
+	}
+	switch outcome.Status {
+	case soroban.TxResultNotFound:
+		return nil, ErrReplayTxNotFound
+	case soroban.TxResultSuccess:
+		return nil, ErrReplayTxSucceeded
+	}
+
+	invoke, err := soroban.DecodeInvokeTx(result.EnvelopeXdr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	replay := &TxReplay{
+		Hash:       hash,
+		Source:     invoke.SourceAccount,
+		ContractID: invoke.ContractID,
+		Function:   invoke.FunctionName,
+		Original: ReplayRun{
+			Ledger: outcome.Ledger,
+			Error:  joinCodes(outcome.ResultCode, outcome.OperationCode),
+			Trace:  soroban.EventTrace(outcome.Diagnostics),
+		},
+	}
+	if code, ok := soroban.ContractErrorCode("", outcome.Diagnostics); ok {
+		replay.Original.code = code
+		replay.Original.ContractError = replay.contractError(code)
+	}
+
+	replay.Replay, err = r.simulate(ctx, invoke)
+	if err != nil {
+		return nil, err
+	}
+
+	authExpiry, err := soroban.AuthExpiration(result.EnvelopeXdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth entries: %w", err)
+	}
+	replay.diagnose(outcome.OperationCode, authExpiry)
+	return replay, nil
+}
+
+// simulate runs the call against current state. A failing call is a result, not an error.
+func (r *TxReplayer) simulate(ctx context.Context, invoke *soroban.DecodedInvoke) (ReplayRun, error) {
+	txXDR, err := r.txBuilder.BuildReplayTx(ctx, invoke)
+	if err != nil {
+		return ReplayRun{}, fmt.Errorf("failed to build replay: %w", err)
+	}
+	sim, err := r.sorobanClient.SimulateTransaction(ctx, txXDR)
+	var simErr *soroban.SimulationError
+	switch {
+	case errors.As(err, &simErr):
+		run := ReplayRun{Error: simErr.Message, Trace: soroban.EventTrace(simErr.Events)}
+		if sim != nil {
+			run.Ledger = sim.LatestLedger
+		}
+		if code, ok := soroban.ContractErrorCode(simErr.Message, simErr.Events); ok {
+			run.code = code
+		}
+		return run, nil
+	case err != nil:
+		return ReplayRun{}, fmt.Errorf("failed to simulate replay: %w", err)
+	}
+
+	run := ReplayRun{Ledger: sim.LatestLedger, Succeeded: true}
+	if len(sim.Results) > 0 && sim.Results[0].XDR != "" {
+		if val, err := soroban.ParseReturnValue(sim.Results[0].XDR); err == nil {
+			run.ReturnValue = soroban.FormatSCVal(val)
+		}
+	}
+	return run, nil
+}
+
+// diagnose compares the runs and picks the likely cause, most specific first: an
+// authorization that had expired when the transaction was applied, resource limits,
+// then the contract error of the original run or, when the RPC recorded none, of
+// the replay.
+func (t *TxReplay) diagnose(operationCode string, authExpiry uint32) {
+	if t.Replay.code != 0 {
+		t.Replay.ContractError = t.contractError(t.Replay.code)
+	}
+	t.Reproduced = !t.Replay.Succeeded &&
+		(t.Original.code == 0 || t.Original.code == t.Replay.code)
+
+	code := t.Original.code
+	if code == 0 && !t.Replay.Succeeded {
+		code = t.Replay.code
+	}
+	switch {
+	case authExpiry != 0 && authExpiry < t.Original.Ledger:
+		t.Cause = ReplayCauseAuthExpired
+	case operationCode == "InvokeHostFunctionResourceLimitExceeded" || operationCode == "InvokeHostFunctionInsufficientRefundableFee":
+		t.Cause = ReplayCauseResources
+	case operationCode == "InvokeHostFunctionEntryArchived":
+		t.Cause = ReplayCauseArchived
+	case code != 0 && marketFunctions[t.Function] && marketErrorCauses[code] != "":
+		t.Cause = marketErrorCauses[code]
+	case t.Replay.Succeeded && (t.Function == "buy" || t.Function == "sell"):
+		// A trade that fails on-chain but simulates fine moments later almost always
+		// hit its max cost or min return as the price moved.
+		t.Cause = ReplayCauseSlippage
+	case t.Replay.Succeeded:
+		t.Cause = ReplayCauseStateChanged
+	default:
+		t.Cause = ReplayCauseUnknown
+	}
+	t.Explanation = replayExplanations[t.Cause]
+}
+
+// contractError names a contract error code of the invoked function.
+func (t *TxReplay) contractError(code uint32) string {
+	if name, ok := marketErrors[code]; ok && marketFunctions[t.Function] {
+		return fmt.Sprintf("%s (#%d)", name, code)
+	}
+	return fmt.Sprintf("#%d", code)
+}
+
+func joinCodes(resultCode, operationCode string) string {
+	if operationCode == "" {
+		return resultCode
+	}
+	return resultCode + " / " + operationCode
+}
@@ -0,0 +1,106 @@
+package service
+
+import "testing"
+
+func TestTxReplayDiagnose(t *testing.T) {
+	tests := []struct {
+		name           string
+		function       string
+		original       ReplayRun
+		replay         ReplayRun
+		operationCode  string
+		authExpiry     uint32
+		wantCause      ReplayCause
+		wantReproduced bool
+	}{
+		{
+			name:          "auth expired before inclusion",
+			function:      "resolve",
+			original:      ReplayRun{Ledger: 500},
+			replay:        ReplayRun{Succeeded: true},
+			operationCode: "InvokeHostFunctionTrapped",
+			authExpiry:    450,
+			wantCause:     ReplayCauseAuthExpired,
+		},
+		{
+			name:          "auth still valid",
+			function:      "resolve",
+			original:      ReplayRun{Ledger: 500},
+			replay:        ReplayRun{Succeeded: true},
+			operationCode: "InvokeHostFunctionTrapped",
+			authExpiry:    600,
+			wantCause:     ReplayCauseStateChanged,
+		},
+		{
+			name:          "resource limit",
+			function:      "buy",
+			replay:        ReplayRun{Succeeded: true},
+			operationCode: "InvokeHostFunctionResourceLimitExceeded",
+			wantCause:     ReplayCauseResources,
+		},
+		{
+			name:           "slippage recorded in diagnostics",
+			function:       "buy",
+			original:       ReplayRun{code: 8},
+			replay:         ReplayRun{code: 8},
+			operationCode:  "InvokeHostFunctionTrapped",
+			wantCause:      ReplayCauseSlippage,
+			wantReproduced: true,
+		},
+		{
+			name:          "trade simulates fine now",
+			function:      "sell",
+			replay:        ReplayRun{Succeeded: true},
+			operationCode: "InvokeHostFunctionTrapped",
+			wantCause:     ReplayCauseSlippage,
+		},
+		{
+			name:           "market resolved since",
+			function:       "buy",
+			replay:         ReplayRun{code: 3},
+			operationCode:  "InvokeHostFunctionTrapped",
+			wantCause:      ReplayCauseResolved,
+			wantReproduced: true,
+		},
+		{
+			name:          "original error wins over replay",
+			function:      "buy",
+			original:      ReplayRun{code: 8},
+			replay:        ReplayRun{code: 3},
+			operationCode: "InvokeHostFunctionTrapped",
+			wantCause:     ReplayCauseSlippage,
+		},
+		{
+			name:           "factory errors are not market errors",
+			function:       "deploy_market",
+			replay:         ReplayRun{code: 3},
+			operationCode:  "InvokeHostFunctionTrapped",
+			wantCause:      ReplayCauseUnknown,
+			wantReproduced: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := &TxReplay{Function: tt.function, Original: tt.original, Replay: tt.replay}
+			replay.diagnose(tt.operationCode, tt.authExpiry)
+			if replay.Cause != tt.wantCause {
+				t.Errorf("Cause = %s, want %s", replay.Cause, tt.wantCause)
+			}
+			if replay.Reproduced != tt.wantReproduced {
+				t.Errorf("Reproduced = %v, want %v", replay.Reproduced, tt.wantReproduced)
+			}
+			if replay.Explanation == "" {
+				t.Error("Explanation is empty")
+			}
+		})
+	}
+}
+
+func TestTxReplayContractError(t *testing.T) {
+	if got := (&TxReplay{Function: "buy"}).contractError(8); got != "SlippageExceeded (#8)" {
+		t.Errorf("contractError(8) for buy = %q", got)
+	}
+	if got := (&TxReplay{Function: "deploy_market"}).contractError(3); got != "#3" {
+		t.Errorf("contractError(3) for deploy_market = %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/mtlprog/total/internal/soroban"
+)
+
+// ErrInvalidTxHash is returned for a transaction hash that is not 64 hex characters.
+var ErrInvalidTxHash = errors.New("invalid transaction hash")
+
+// TxStatus is what a submitted transaction did, decoded from getTransaction.
+type TxStatus struct {
+	Hash           string
+	Outcome        *soroban.TransactionOutcome
+	Trades         []TradeEvent            // buy and sell events of markets
+	BalanceChanges []soroban.BalanceChange // collateral moved by token contracts
+}
+
+// Found reports whether the network knows the transaction yet.
+func (s *TxStatus) Found() bool {
+	return s.Outcome.Status != soroban.TxResultNotFound
+}
+
+// TransactionStatus looks up a transaction by hash and decodes its outcome. A
+// transaction that is not (yet) known returns a status with Found false: it may be
+// pending or older than the RPC retention window.
+func (s *EventService) TransactionStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTxHash, hash)
+	}
+
+	result, err := s.sorobanClient.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	outcome, err := result.Outcome(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s: %w", hash, err)
+	}
+
+	status := &TxStatus{Hash: hash, Outcome: outcome, BalanceChanges: outcome.BalanceChanges()}
+	for _, evt := range outcome.Events {
+		if !isTradeEvent(evt) {
+			continue
+		}
+		trade, err := s.parseTradeEvent(evt)
+		if err != nil {
+			s.logger.Warn("failed to parse trade event", "tx_hash", hash, "error", err)
+			continue
+		}
+		status.Trades = append(status.Trades, trade)
+	}
+	return status, nil
+}
+
+// isTradeEvent reports whether evt is a market buy or sell event by its first topic.
+func isTradeEvent(evt soroban.ContractEvent) bool {
+	if len(evt.Topic) == 0 {
+		return false
+	}
+	kind, err := soroban.ParseReturnValue(evt.Topic[0])
+	if err != nil || kind.Sym == nil {
+		return false
+	}
+	switch TradeKind(*kind.Sym) {
+	case TradeKindBuy, TradeKindSell:
+		return true
+	}
+	return false
+}
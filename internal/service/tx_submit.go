@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/samber/hot"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	broadcastTimeout          = 2 * time.Minute  // Give up on a transaction not in a ledger by then
+	broadcastInitialBackoff   = time.Second      // First wait before resubmitting or polling
+	broadcastMaxBackoff       = 15 * time.Second // Longest wait between attempts
+	broadcastRebroadcastAfter = 20 * time.Second // Resubmit a PENDING transaction not seen for this long
+	broadcastRetention        = 30 * time.Minute
+	broadcastCacheSize        = 5000
+)
+
+var (
+	// ErrTxNotBuilt is returned for a signed transaction that does not match any
+	// transaction built here for its source account.
+	ErrTxNotBuilt = errors.New("transaction was not built by this app")
+	// ErrTxNotSigned is returned for a transaction without any signature.
+	ErrTxNotSigned = errors.New("transaction is not signed")
+	// ErrTxExpired is returned when the transaction's time bounds have passed.
+	ErrTxExpired = errors.New("transaction has expired")
+)
+
+// CheckSignedTx verifies a signed transaction against the pending transaction it was
+// built as before it is submitted: the same hash, so the same operations, fees and
+// sequence number; at least one signature; and time bounds that have not passed.
+// Whether the signatures meet the account's thresholds is left to the network, so
+// multisig accounts and accounts with a zero-weight master key can submit too.
+func CheckSignedTx(signedXDR, networkPassphrase string, built PendingTx, now time.Time) error {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(signedXDR, &env); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+	hash, err := network.HashTransactionInEnvelope(env, networkPassphrase)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTransaction, err)
+	}
+	if built.Hash == "" || hex.EncodeToString(hash[:]) != built.Hash {
+		return ErrTxNotBuilt
+	}
+
+	if len(env.Signatures()) == 0 {
+		return fmt.Errorf("%w: sign it with %s first", ErrTxNotSigned, built.Account)
+	}
+
+	if built.IsExpired(now) {
+		return ErrTxExpired
+	}
+	return nil
+}
+
+// SubmitTx sends a signed transaction to the network and follows it in the background:
+// resubmitting with backoff while the RPC answers TRY_AGAIN_LATER, and again if it is
+// still not in a ledger a while after PENDING, until broadcastTimeout. Only a rejection
+// of the first submission is an error; later states are read with Broadcast.
+func (s *MarketService) SubmitTx(ctx context.Context, signedXDR, hash, sourceAccount string) (Broadcast, error) {
+	b, err := s.broadcaster.submit(ctx, signedXDR, hash)
+	if err != nil {
+		return Broadcast{}, err
+	}
+	// The account's sequence number moves on once the transaction is applied.
+	s.InvalidateAccount(sourceAccount)
+	return b, nil
+}
+
+// StopBroadcasts stops following submitted transactions on shutdown and waits until
+// the followers have returned or ctx is done. The transactions stay on the network.
+func (s *MarketService) StopBroadcasts(ctx context.Context) error {
+	return s.broadcaster.close(ctx)
+}
+
+// Broadcast returns the submit pipeline state of a transaction submitted here recently.
+func (s *MarketService) Broadcast(hash string) (Broadcast, bool) {
+	return s.broadcaster.get(hash)
+}
+
+// BroadcastState is where a submitted transaction is in the submit pipeline.
+type BroadcastState string
+
+const (
+	BroadcastRetrying BroadcastState = "retrying"  // The RPC answered TRY_AGAIN_LATER; resubmitting with backoff
+	BroadcastPending  BroadcastState = "pending"   // Accepted, waiting to be included in a ledger
+	BroadcastDone     BroadcastState = "done"      // Included in a ledger, successfully or not
+	BroadcastRejected BroadcastState = "rejected"  // A resubmission was rejected with ERROR
+	BroadcastTimedOut BroadcastState = "timed_out" // Not in a ledger within broadcastTimeout
+)
+
+// Broadcast is the submit pipeline state of a transaction.
+type Broadcast struct {
+	Hash        string
+	State       BroadcastState
+	RPCStatus   string // Answer to the last sendTransaction, e.g. PENDING or TRY_AGAIN_LATER
+	Attempts    int    // sendTransaction calls so far
+	SubmittedAt time.Time
+	UpdatedAt   time.Time
+	Error       string // Why the transaction was rejected or given up on
+}
+
+// Final reports whether the transaction is no longer followed.
+func (b Broadcast) Final() bool {
+	return b.State != BroadcastRetrying && b.State != BroadcastPending
+}
+
+// apply records the answer to a sendTransaction call.
+func (b *Broadcast) apply(rpcStatus string, now time.Time) {
+	b.Attempts++
+	b.RPCStatus = rpcStatus
+	b.UpdatedAt = now
+	if rpcStatus == soroban.TxStatusTryAgain {
+		b.State = BroadcastRetrying
+	} else {
+		b.State = BroadcastPending // PENDING or DUPLICATE: the network has it
+	}
+}
+
+// txNetwork is the part of the RPC client the broadcaster uses.
+type txNetwork interface {
+	SendTransaction(ctx context.Context, txXDR string) (*soroban.SendTransactionResult, error)
+	GetTransaction(ctx context.Context, hash string) (*soroban.GetTransactionResult, error)
+}
+
+// txBroadcaster submits transactions and follows them until they are in a ledger.
+// States are kept in memory for broadcastRetention. Followers run under the
+// broadcaster's own context, which close cancels on shutdown.
+type txBroadcaster struct {
+	network txNetwork
+	logger  *slog.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	follows sync.WaitGroup
+
+	timeout          time.Duration
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	rebroadcastAfter time.Duration
+
+	cache *hot.HotCache[string, Broadcast]
+}
+
+func newTxBroadcaster(network txNetwork, logger *slog.Logger) *txBroadcaster {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &txBroadcaster{
+		network:          network,
+		logger:           logger,
+		ctx:              ctx,
+		cancel:           cancel,
+		timeout:          broadcastTimeout,
+		initialBackoff:   broadcastInitialBackoff,
+		maxBackoff:       broadcastMaxBackoff,
+		rebroadcastAfter: broadcastRebroadcastAfter,
+		cache: hot.NewHotCache[string, Broadcast](hot.LRU, broadcastCacheSize).
+			WithTTL(broadcastRetention).
+			Build(),
+	}
+}
+
+func (t *txBroadcaster) get(hash string) (Broadcast, bool) {
+	b, found, err := t.cache.Get(hash)
+	if err != nil || !found {
+		return Broadcast{}, false
+	}
+	return b, true
+}
+
+// update applies fn to the stored state of hash, if any, and returns the result.
+func (t *txBroadcaster) update(hash string, fn func(*Broadcast)) Broadcast {
+	b, ok := t.get(hash)
+	if !ok {
+		return Broadcast{}
+	}
+	fn(&b)
+	t.cache.Set(hash, b)
+	return b
+}
+
+// submit sends the transaction once and, unless the RPC rejected it, keeps following
+// it after the request that submitted it has finished.
+func (t *txBroadcaster) submit(ctx context.Context, signedXDR, hash string) (Broadcast, error) {
+	result, err := t.network.SendTransaction(ctx, signedXDR)
+	if err != nil {
+		return Broadcast{}, fmt.Errorf("failed to submit transaction: %w", err)
+	}
+	now := time.Now()
+	b := Broadcast{Hash: hash, SubmittedAt: now}
+	b.apply(result.Status, now)
+	t.cache.Set(hash, b)
+
+	t.follows.Add(1)
+	go func() {
+		defer t.follows.Done()
+		t.follow(t.ctx, signedXDR, hash)
+	}()
+	return b, nil
+}
+
+// close cancels all followers and waits for them to return, or for ctx to be done.
+func (t *txBroadcaster) close(ctx context.Context) error {
+	t.cancel()
+	done := make(chan struct{})
+	go func() {
+		t.follows.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("transaction followers did not stop: %w", ctx.Err())
+	}
+}
+
+// follow polls a pending transaction and resubmits it with exponential backoff when the
+// RPC asked to try again or it is not seen in a ledger, until it is included, rejected
+// or t.timeout has passed. Cancelling ctx stops following and leaves the state as is.
+func (t *txBroadcaster) follow(ctx context.Context, signedXDR, hash string) {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	backoff := t.initialBackoff
+	lastSent := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			if parent.Err() != nil {
+				t.logger.Info("stopped following submitted transaction on shutdown", "tx_hash", hash)
+				return
+			}
+			b := t.update(hash, func(b *Broadcast) {
+				b.State = BroadcastTimedOut
+				b.Error = fmt.Sprintf("not included in a ledger within %s", t.timeout)
+				b.UpdatedAt = time.Now()
+			})
+			t.logger.Warn("gave up on submitted transaction", "tx_hash", hash, "attempts", b.Attempts, "rpc_status", b.RPCStatus)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, t.maxBackoff)
+
+		b, ok := t.get(hash)
+		if !ok || b.Final() {
+			return
+		}
+		if b.State == BroadcastPending {
+			result, err := t.network.GetTransaction(ctx, hash)
+			if err != nil {
+				t.logger.Debug("failed to poll submitted transaction", "tx_hash", hash, "error", err)
+				continue
+			}
+			if result.Status == soroban.TxResultSuccess || result.Status == soroban.TxResultFailed {
+				t.update(hash, func(b *Broadcast) {
+					b.State = BroadcastDone
+					b.UpdatedAt = time.Now()
+				})
+				return
+			}
+			if time.Since(lastSent) < t.rebroadcastAfter {
+				continue
+			}
+		}
+
+		result, err := t.network.SendTransaction(ctx, signedXDR)
+		lastSent = time.Now()
+		if errors.Is(err, soroban.ErrTransactionFailed) {
+			// An earlier submission may have been applied in the meantime, which also
+			// makes this one fail (e.g. txBAD_SEQ).
+			if t.applied(ctx, hash) {
+				t.update(hash, func(b *Broadcast) {
+					b.Attempts++
+					b.State = BroadcastDone
+					b.UpdatedAt = time.Now()
+				})
+				return
+			}
+			t.update(hash, func(b *Broadcast) {
+				b.Attempts++
+				b.State = BroadcastRejected
+				b.RPCStatus = soroban.TxStatusError
+				b.Error = err.Error()
+				b.UpdatedAt = lastSent
+			})
+			return
+		}
+		if err != nil {
+			t.logger.Debug("failed to resubmit transaction", "tx_hash", hash, "error", err)
+			continue
+		}
+		t.update(hash, func(b *Broadcast) { b.apply(result.Status, lastSent) })
+	}
+}
+
+// applied reports whether the transaction is in a ledger, successfully or not.
+func (t *txBroadcaster) applied(ctx context.Context, hash string) bool {
+	result, err := t.network.GetTransaction(ctx, hash)
+	if err != nil {
+		t.logger.Debug("failed to look up rejected transaction", "tx_hash", hash, "error", err)
+		return false
+	}
+	return result.Status == soroban.TxResultSuccess || result.Status == soroban.TxResultFailed
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+)
+
+func buildTestTx(t *testing.T, source string, seq int64, expires time.Time) *txnbuild.Transaction {
+	t.Helper()
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: source, Sequence: seq},
+		IncrementSequenceNum: true,
+		Operations:           []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 0}},
+		BaseFee:              txnbuild.MinBaseFee,
+		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewTimebounds(0, expires.Unix())},
+	})
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	return tx
+}
+
+func TestCheckSignedTx(t *testing.T) {
+	user := keypair.MustRandom()
+	other := keypair.MustRandom()
+	now := time.Now()
+	expires := now.Add(5 * time.Minute)
+
+	built := buildTestTx(t, user.Address(), 1, expires)
+	builtXDR, err := built.Base64()
+	if err != nil {
+		t.Fatalf("Base64() error = %v", err)
+	}
+	hash, err := soroban.TransactionHash(builtXDR, config.TestnetNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("TransactionHash() error = %v", err)
+	}
+	pending := PendingTx{Account: user.Address(), XDR: builtXDR, Hash: hash, ExpiresAt: expires}
+
+	sign := func(tx *txnbuild.Transaction, signers ...*keypair.Full) string {
+		t.Helper()
+		tx, err := tx.Sign(config.TestnetNetworkPassphrase, signers...)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		signed, err := tx.Base64()
+		if err != nil {
+			t.Fatalf("Base64() error = %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		xdr     string
+		now     time.Time
+		wantErr error
+	}{
+		{"signed by account", sign(built, user), now, nil},
+		{"signed by account and another key", sign(built, other, user), now, nil},
+		{"signed by another key (multisig signer)", sign(built, other), now, nil},
+		{"unsigned", builtXDR, now, ErrTxNotSigned},
+		{"different transaction", sign(buildTestTx(t, user.Address(), 2, expires), user), now, ErrTxNotBuilt},
+		{"expired", sign(built, user), expires.Add(time.Second), ErrTxExpired},
+		{"garbage", "not-xdr", now, ErrInvalidTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSignedTx(tt.xdr, config.TestnetNetworkPassphrase, pending, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckSignedTx() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("other network", func(t *testing.T) {
+		err := CheckSignedTx(sign(built, user), config.MainnetNetworkPassphrase, pending, now)
+		if !errors.Is(err, ErrTxNotBuilt) {
+			t.Errorf("CheckSignedTx() error = %v, want %v", err, ErrTxNotBuilt)
+		}
+	})
+}
+
+// fakeTxNetwork answers sendTransaction with sends in order (repeating the last) and
+// getTransaction with NOT_FOUND until included is set.
+type fakeTxNetwork struct {
+	mu       sync.Mutex
+	sends    []string // RPC statuses; "" returns a rejection
+	sent     int
+	included bool
+}
+
+func (f *fakeTxNetwork) SendTransaction(_ context.Context, _ string) (*soroban.SendTransactionResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := f.sends[min(f.sent, len(f.sends)-1)]
+	f.sent++
+	if status == "" {
+		return &soroban.SendTransactionResult{Status: soroban.TxStatusError}, fmt.Errorf("%w: txBAD_SEQ", soroban.ErrTransactionFailed)
+	}
+	return &soroban.SendTransactionResult{Status: status}, nil
+}
+
+func (f *fakeTxNetwork) GetTransaction(_ context.Context, _ string) (*soroban.GetTransactionResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.included {
+		return &soroban.GetTransactionResult{Status: soroban.TxResultSuccess}, nil
+	}
+	return &soroban.GetTransactionResult{Status: soroban.TxResultNotFound}, nil
+}
+
+func (f *fakeTxNetwork) include() {
+	f.mu.Lock()
+	f.included = true
+	f.mu.Unlock()
+}
+
+func TestTxBroadcaster(t *testing.T) {
+	newBroadcaster := func(network txNetwork) *txBroadcaster {
+		b := newTxBroadcaster(network, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		b.timeout = 500 * time.Millisecond
+		b.initialBackoff = 5 * time.Millisecond
+		b.maxBackoff = 10 * time.Millisecond
+		b.rebroadcastAfter = 30 * time.Millisecond
+		return b
+	}
+	waitFor := func(t *testing.T, b *txBroadcaster, hash string, want BroadcastState) Broadcast {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if got, _ := b.get(hash); got.State == want {
+				return got
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		got, _ := b.get(hash)
+		t.Fatalf("state = %q, want %q", got.State, want)
+		return got
+	}
+
+	t.Run("retries TRY_AGAIN_LATER until accepted", func(t *testing.T) {
+		network := &fakeTxNetwork{sends: []string{soroban.TxStatusTryAgain, soroban.TxStatusTryAgain, soroban.TxStatusPending}}
+		b := newBroadcaster(network)
+		first, err := b.submit(t.Context(), "xdr", "h1")
+		if err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		if first.State != BroadcastRetrying || first.RPCStatus != soroban.TxStatusTryAgain {
+			t.Errorf("submit() = %+v, want retrying after TRY_AGAIN_LATER", first)
+		}
+		pending := waitFor(t, b, "h1", BroadcastPending)
+		if pending.Attempts != 3 {
+			t.Errorf("Attempts = %d, want 3", pending.Attempts)
+		}
+		network.include()
+		waitFor(t, b, "h1", BroadcastDone)
+	})
+
+	t.Run("resubmits a pending transaction that does not show up", func(t *testing.T) {
+		network := &fakeTxNetwork{sends: []string{soroban.TxStatusPending, soroban.TxStatusDuplicate}}
+		b := newBroadcaster(network)
+		if _, err := b.submit(t.Context(), "xdr", "h2"); err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if got, _ := b.get("h2"); got.Attempts >= 2 {
+				if got.State != BroadcastPending || got.RPCStatus != soroban.TxStatusDuplicate {
+					t.Errorf("after resubmit = %+v, want pending DUPLICATE", got)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("pending transaction was not resubmitted")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		network.include()
+		waitFor(t, b, "h2", BroadcastDone)
+	})
+
+	t.Run("stops on rejection", func(t *testing.T) {
+		network := &fakeTxNetwork{sends: []string{soroban.TxStatusTryAgain, ""}}
+		b := newBroadcaster(network)
+		if _, err := b.submit(t.Context(), "xdr", "h3"); err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		if got := waitFor(t, b, "h3", BroadcastRejected); got.Error == "" {
+			t.Error("rejected broadcast has no error")
+		}
+	})
+
+	t.Run("rejection of a transaction already applied", func(t *testing.T) {
+		// An earlier PENDING submission landed, so the resubmission fails with txBAD_SEQ.
+		network := &fakeTxNetwork{sends: []string{soroban.TxStatusTryAgain, ""}, included: true}
+		b := newBroadcaster(network)
+		if _, err := b.submit(t.Context(), "xdr", "h6"); err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		waitFor(t, b, "h6", BroadcastDone)
+	})
+
+	t.Run("close stops following", func(t *testing.T) {
+		b := newBroadcaster(&fakeTxNetwork{sends: []string{soroban.TxStatusTryAgain}})
+		b.timeout = time.Minute
+		if _, err := b.submit(t.Context(), "xdr", "h7"); err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+		if err := b.close(ctx); err != nil {
+			t.Fatalf("close() error = %v", err)
+		}
+		if got, _ := b.get("h7"); got.State != BroadcastRetrying {
+			t.Errorf("state after close = %q, want it left as %q", got.State, BroadcastRetrying)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		b := newBroadcaster(&fakeTxNetwork{sends: []string{soroban.TxStatusTryAgain}})
+		if _, err := b.submit(t.Context(), "xdr", "h4"); err != nil {
+			t.Fatalf("submit() error = %v", err)
+		}
+		if got := waitFor(t, b, "h4", BroadcastTimedOut); !got.Final() {
+			t.Errorf("timed out broadcast %+v is not final", got)
+		}
+	})
+
+	t.Run("first rejection is an error", func(t *testing.T) {
+		b := newBroadcaster(&fakeTxNetwork{sends: []string{""}})
+		if _, err := b.submit(t.Context(), "xdr", "h5"); !errors.Is(err, soroban.ErrTransactionFailed) {
+			t.Errorf("submit() error = %v, want ErrTransactionFailed", err)
+		}
+		if _, ok := b.get("h5"); ok {
+			t.Error("rejected submission is tracked")
+		}
+	})
+}
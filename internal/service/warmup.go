@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mtlprog/total/internal/ipfs"
+)
+
+// DefaultWarmupConcurrency is how many IPFS metadata fetches the startup warmup runs
+// at once.
+const DefaultWarmupConcurrency = 4
+
+// warmupProgressInterval is how often a running warmup logs its progress.
+const warmupProgressInterval = 10 * time.Second
+
+// WarmupStats describes the startup cache warmup.
+type WarmupStats struct {
+	Started  time.Time
+	Finished time.Time // Zero while running
+	Markets  int
+	Fetched  int    // Metadata documents fetched or already cached
+	Failed   int    // Metadata fetches that failed
+	Error    string // Why the market list could not be loaded, if it could not
+}
+
+// Done reports whether the warmup has finished.
+func (s WarmupStats) Done() bool {
+	return !s.Finished.IsZero()
+}
+
+// Duration returns how long the warmup ran, or has been running.
+func (s WarmupStats) Duration() time.Duration {
+	if s.Started.IsZero() {
+		return 0
+	}
+	if s.Finished.IsZero() {
+		return time.Since(s.Started)
+	}
+	return s.Finished.Sub(s.Started)
+}
+
+// WarmupService fills the market state and IPFS metadata caches at startup, so the
+// first visitors do not wait on the RPC and gateways. It runs once; Done lets the HTTP
+// server wait for it before accepting requests.
+type WarmupService struct {
+	factoryService *FactoryService
+	fetch          func(ctx context.Context, hash string) error
+	concurrency    int
+	logger         *slog.Logger
+
+	mu    sync.Mutex
+	stats WarmupStats
+	done  chan struct{}
+}
+
+// NewWarmupService creates a warmup that fetches metadata with at most concurrency
+// requests in flight. A concurrency below 1 uses DefaultWarmupConcurrency.
+func NewWarmupService(factoryService *FactoryService, ipfsClient *ipfs.Client, concurrency int, logger *slog.Logger) *WarmupService {
+	if factoryService == nil {
+		panic("NewWarmupService: factoryService must not be nil")
+	}
+	if ipfsClient == nil {
+		panic("NewWarmupService: ipfsClient must not be nil")
+	}
+	if logger == nil {
+		panic("NewWarmupService: logger must not be nil")
+	}
+	if concurrency < 1 {
+		concurrency = DefaultWarmupConcurrency
+	}
+	return &WarmupService{
+		factoryService: factoryService,
+		fetch:          ipfsClient.Prefetch,
+		concurrency:    concurrency,
+		logger:         logger,
+		done:           make(chan struct{}),
+	}
+}
+
+// Run lists the markets, loads their states and prefetches their metadata. It returns
+// when everything is fetched or ctx is cancelled; failures are counted in Stats and
+// logged, not returned. Run must be called once.
+func (w *WarmupService) Run(ctx context.Context) {
+	defer close(w.done)
+	w.mu.Lock()
+	w.stats.Started = time.Now()
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.stats.Finished = time.Now()
+		stats := w.stats
+		w.mu.Unlock()
+		w.logger.Info("cache warmup finished",
+			"markets", stats.Markets,
+			"fetched", stats.Fetched,
+			"failed", stats.Failed,
+			"duration", stats.Duration().Round(time.Millisecond),
+		)
+	}()
+
+	hashes, err := w.metadataHashes(ctx)
+	if err != nil {
+		w.logger.Warn("cache warmup could not load markets", "error", err)
+		w.mu.Lock()
+		w.stats.Error = err.Error()
+		w.mu.Unlock()
+		return
+	}
+	w.logger.Info("warming up caches", "metadata", len(hashes), "concurrency", w.concurrency)
+	w.prefetch(ctx, hashes)
+}
+
+// metadataHashes lists the markets and returns their metadata hashes.
+func (w *WarmupService) metadataHashes(ctx context.Context) ([]string, error) {
+	listCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	markets, err := w.factoryService.ListMarkets(listCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list markets: %w", err)
+	}
+	w.mu.Lock()
+	w.stats.Markets = len(markets)
+	w.mu.Unlock()
+	if len(markets) == 0 {
+		return nil, nil
+	}
+
+	loaded, err := w.factoryService.GetMarketStates(listCtx, markets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market states: %w", err)
+	}
+	states := loaded.States
+	var hashes []string
+	for _, s := range states {
+		if s.MetadataHash != "" {
+			hashes = append(hashes, s.MetadataHash)
+		}
+	}
+	return hashes, nil
+}
+
+// prefetch fetches hashes with at most w.concurrency fetches in flight, logging
+// progress every warmupProgressInterval.
+func (w *WarmupService) prefetch(ctx context.Context, hashes []string) {
+	progress := time.NewTicker(warmupProgressInterval)
+	defer progress.Stop()
+
+	sem := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for _, hash := range hashes {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				err := w.fetch(ctx, hash)
+				if ctx.Err() != nil {
+					return // Cancelled fetches are neither fetched nor failed
+				}
+				w.mu.Lock()
+				if err != nil {
+					w.stats.Failed++
+				} else {
+					w.stats.Fetched++
+				}
+				w.mu.Unlock()
+				if err != nil {
+					w.logger.Warn("cache warmup fetch failed", "hash", hash, "error", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-finished:
+			return
+		case <-progress.C:
+			stats := w.Stats()
+			w.logger.Info("cache warmup in progress",
+				"fetched", stats.Fetched,
+				"failed", stats.Failed,
+				"total", len(hashes),
+			)
+		}
+	}
+}
+
+// Stats returns the warmup's progress.
+func (w *WarmupService) Stats() WarmupStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// Done is closed when Run returns.
+func (w *WarmupService) Done() <-chan struct{} {
+	return w.done
+}
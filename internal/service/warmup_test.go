@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmupPrefetchBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	w := &WarmupService{
+		concurrency: 3,
+		logger:      slog.Default(),
+		done:        make(chan struct{}),
+		fetch: func(ctx context.Context, hash string) error {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if hash == "bad" {
+				return errors.New("gateway timeout")
+			}
+			return nil
+		},
+	}
+
+	hashes := []string{"bad"}
+	for i := range 11 {
+		hashes = append(hashes, fmt.Sprintf("hash-%d", i))
+	}
+	w.prefetch(context.Background(), hashes)
+
+	if got := peak.Load(); got > 3 {
+		t.Errorf("peak concurrent fetches = %d, want at most 3", got)
+	}
+	stats := w.Stats()
+	if stats.Fetched != 11 || stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 11 fetched and 1 failed", stats)
+	}
+}
+
+func TestWarmupPrefetchStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	w := &WarmupService{
+		concurrency: 1,
+		logger:      slog.Default(),
+		done:        make(chan struct{}),
+		fetch: func(ctx context.Context, hash string) error {
+			calls.Add(1)
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	w.prefetch(ctx, []string{"a", "b", "c"})
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1 before the cancellation stopped the warmup", got)
+	}
+	if stats := w.Stats(); stats.Fetched != 0 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v, want cancelled fetches not counted", stats)
+	}
+}
+
+func TestWarmupStatsDuration(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	running := WarmupStats{Started: start}
+	if running.Done() || running.Duration() < time.Minute {
+		t.Errorf("running warmup: Done() = %v, Duration() = %s", running.Done(), running.Duration())
+	}
+	finished := WarmupStats{Started: start, Finished: start.Add(3 * time.Second)}
+	if !finished.Done() || finished.Duration() != 3*time.Second {
+		t.Errorf("finished warmup: Done() = %v, Duration() = %s", finished.Done(), finished.Duration())
+	}
+	if (WarmupStats{}).Duration() != 0 {
+		t.Error("a warmup that has not started should have no duration")
+	}
+}
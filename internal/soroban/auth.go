@@ -0,0 +1,100 @@
+package soroban
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// LedgerInterval is the average time between ledger closes. It turns ledger
+	// sequence numbers into wall-clock estimates.
+	LedgerInterval = 5 * time.Second
+	// DefaultAuthValidityLedgers is how long address auth entries stay valid when
+	// transactions have no upper time bound: about a day, enough for multisig signers.
+	DefaultAuthValidityLedgers = 17280
+	// authValidityMargin keeps auth entries valid a little past the time bounds, so
+	// the transaction expires first and is rebuilt with fresh entries as a whole.
+	authValidityMargin = 12
+)
+
+// ledgerClock remembers the latest ledger seen in a simulation and when it was seen,
+// to estimate when a future ledger closes.
+type ledgerClock struct {
+	mu     sync.Mutex
+	ledger uint32
+	seenAt time.Time
+}
+
+func (c *ledgerClock) observe(ledger uint32, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ledger >= c.ledger {
+		c.ledger, c.seenAt = ledger, now
+	}
+}
+
+// estimate returns when ledger is expected to close; zero before any ledger was seen.
+func (c *ledgerClock) estimate(ledger uint32) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ledger == 0 {
+		return time.Time{}
+	}
+	return c.seenAt.Add(time.Duration(int64(ledger)-int64(c.ledger)) * LedgerInterval)
+}
+
+// authValidityLedgers returns how many ledgers address auth entries stay valid.
+func (ci *ContractInvoker) authValidityLedgers() uint32 {
+	if ci.txTimeout <= 0 {
+		return DefaultAuthValidityLedgers
+	}
+	return uint32((ci.txTimeout+LedgerInterval-1)/LedgerInterval) + authValidityMargin
+}
+
+// LedgerTime estimates when the given ledger closes, from the latest ledger seen
+// while preparing transactions. Returns zero time before the first simulation.
+func (ci *ContractInvoker) LedgerTime(ledger uint32) time.Time {
+	return ci.clock.estimate(ledger)
+}
+
+// setAuthExpiration sets the signature expiration ledger of address auth entries
+// that simulation left at zero. Source-account entries are covered by the transaction
+// signature and have no expiration of their own.
+func setAuthExpiration(entries []xdr.SorobanAuthorizationEntry, ledger uint32) {
+	for i := range entries {
+		creds := entries[i].Credentials.Address
+		if entries[i].Credentials.Type == xdr.SorobanCredentialsTypeSorobanCredentialsAddress && creds != nil && creds.SignatureExpirationLedger == 0 {
+			creds.SignatureExpirationLedger = xdr.Uint32(ledger)
+		}
+	}
+}
+
+// AuthExpiration returns the earliest signature expiration ledger among the address
+// auth entries of a contract invocation, or 0 when it has none. After that ledger the
+// entries must be signed again, so the transaction has to be re-simulated.
+func AuthExpiration(txXDR string) (uint32, error) {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+		return 0, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	var earliest uint32
+	for _, op := range env.Operations() {
+		invoke := op.Body.InvokeHostFunctionOp
+		if invoke == nil {
+			continue
+		}
+		for _, entry := range invoke.Auth {
+			creds := entry.Credentials.Address
+			if creds == nil || creds.SignatureExpirationLedger == 0 {
+				continue
+			}
+			if exp := uint32(creds.SignatureExpirationLedger); earliest == 0 || exp < earliest {
+				earliest = exp
+			}
+		}
+	}
+	return earliest, nil
+}
@@ -0,0 +1,134 @@
+package soroban
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func addressAuth(t *testing.T, expiration uint32) xdr.SorobanAuthorizationEntry {
+	t.Helper()
+	addr, err := EncodeAddress(keypair.MustRandom().Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract, err := EncodeAddress(testContractID(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xdr.SorobanAuthorizationEntry{
+		RootInvocation: xdr.SorobanAuthorizedInvocation{
+			Function: xdr.SorobanAuthorizedFunction{
+				Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn,
+				ContractFn: &xdr.InvokeContractArgs{
+					ContractAddress: *contract.Address,
+					FunctionName:    "resolve",
+				},
+			},
+		},
+		Credentials: xdr.SorobanCredentials{
+			Type: xdr.SorobanCredentialsTypeSorobanCredentialsAddress,
+			Address: &xdr.SorobanAddressCredentials{
+				Address:                   *addr.Address,
+				SignatureExpirationLedger: xdr.Uint32(expiration),
+				Signature:                 xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+			},
+		},
+	}
+}
+
+func testContractID(t *testing.T) string {
+	t.Helper()
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contractID
+}
+
+func TestAuthExpiration(t *testing.T) {
+	contractID := testContractID(t)
+	ci := NewContractInvoker(nil, network.TestNetworkPassphrase, 100, 5*time.Minute)
+	txXDR, err := ci.BuildInvokeTx(context.Background(), InvokeParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: keypair.MustRandom().Address(), Sequence: 1},
+		ContractID:    contractID,
+		FunctionName:  "resolve",
+		Args:          []xdr.ScVal{EncodeU32(0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := AuthExpiration(txXDR)
+	if err != nil || got != 0 {
+		t.Fatalf("AuthExpiration() without auth = %d, %v; want 0", got, err)
+	}
+
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+		t.Fatal(err)
+	}
+	sourceAuth := addressAuth(t, 0)
+	sourceAuth.Credentials = xdr.SorobanCredentials{Type: xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount}
+	auth := []xdr.SorobanAuthorizationEntry{addressAuth(t, 0), addressAuth(t, 900), sourceAuth}
+	setAuthExpiration(auth, 1000)
+	if exp := auth[0].Credentials.Address.SignatureExpirationLedger; exp != 1000 {
+		t.Errorf("unset expiration = %d, want 1000", exp)
+	}
+	if exp := auth[1].Credentials.Address.SignatureExpirationLedger; exp != 900 {
+		t.Errorf("set expiration = %d, want it kept at 900", exp)
+	}
+	env.V1.Tx.Operations[0].Body.InvokeHostFunctionOp.Auth = auth
+	withAuth, err := xdr.MarshalBase64(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = AuthExpiration(withAuth)
+	if err != nil || got != 900 {
+		t.Errorf("AuthExpiration() = %d, %v; want the earliest, 900", got, err)
+	}
+	if _, err := AuthExpiration("not xdr"); err == nil {
+		t.Error("AuthExpiration() accepted invalid XDR")
+	}
+}
+
+func TestAuthValidityLedgers(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    uint32
+	}{
+		{0, DefaultAuthValidityLedgers},
+		{5 * time.Minute, 60 + authValidityMargin},
+		{7 * time.Second, 2 + authValidityMargin},
+	}
+	for _, tt := range tests {
+		ci := NewContractInvoker(nil, network.TestNetworkPassphrase, 100, tt.timeout)
+		if got := ci.authValidityLedgers(); got != tt.want {
+			t.Errorf("authValidityLedgers(%v) = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestLedgerTime(t *testing.T) {
+	ci := NewContractInvoker(nil, network.TestNetworkPassphrase, 100, 0)
+	if got := ci.LedgerTime(100); !got.IsZero() {
+		t.Errorf("LedgerTime() before any ledger = %v, want zero", got)
+	}
+
+	seen := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ci.clock.observe(100, seen)
+	ci.clock.observe(90, seen.Add(time.Minute)) // older ledger from a lagging RPC node
+	if got, want := ci.LedgerTime(112), seen.Add(12*LedgerInterval); !got.Equal(want) {
+		t.Errorf("LedgerTime(112) = %v, want %v", got, want)
+	}
+	if got, want := ci.LedgerTime(98), seen.Add(-2*LedgerInterval); !got.Equal(want) {
+		t.Errorf("LedgerTime(98) = %v, want %v", got, want)
+	}
+}
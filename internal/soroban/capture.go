@@ -0,0 +1,100 @@
+package soroban
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// maxCapturedBodyBytes bounds each captured request and response body.
+const maxCapturedBodyBytes = 64 * 1024
+
+// CapturedCall is a recorded JSON-RPC exchange, kept for debugging failed calls.
+type CapturedCall struct {
+	ID         int             `json:"id"`
+	Method     string          `json:"method"`
+	Time       time.Time       `json:"time"`
+	Duration   time.Duration   `json:"duration_ns"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"` // a body exceeded maxCapturedBodyBytes and was dropped
+	Error      string          `json:"error,omitempty"`
+}
+
+// Capture is a ring buffer of the most recent JSON-RPC calls.
+type Capture struct {
+	mu    sync.Mutex
+	calls []CapturedCall
+	next  int
+	full  bool
+}
+
+// NewCapture creates a capture keeping the last size calls.
+func NewCapture(size int) *Capture {
+	if size <= 0 {
+		panic("NewCapture: size must be positive")
+	}
+	return &Capture{calls: make([]CapturedCall, size)}
+}
+
+// Calls returns the captured calls, newest first.
+func (c *Capture) Calls() []CapturedCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.next
+	if c.full {
+		n = len(c.calls)
+	}
+	out := make([]CapturedCall, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, c.calls[(c.next-i+len(c.calls))%len(c.calls)])
+	}
+	return out
+}
+
+func (c *Capture) record(call CapturedCall) {
+	if len(call.Request) > maxCapturedBodyBytes {
+		call.Request, call.Truncated = nil, true
+	}
+	if len(call.Response) > maxCapturedBodyBytes {
+		call.Response, call.Truncated = nil, true
+	}
+	if len(call.Response) > 0 && !json.Valid(call.Response) {
+		// Keep non-JSON bodies (e.g. proxy error pages) readable as a JSON string.
+		call.Response, _ = json.Marshal(string(call.Response))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[c.next] = call
+	c.next = (c.next + 1) % len(c.calls)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// urlPattern matches URLs in error messages, e.g. `Post "https://rpc.example/?key=x": EOF`.
+var urlPattern = regexp.MustCompile(`https?://[^\s"]+`)
+
+// redactURLs removes credentials from URLs in s: user info, query strings and paths,
+// which hosted RPC providers use to carry API keys.
+func redactURLs(s string) string {
+	return urlPattern.ReplaceAllStringFunc(s, func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "[redacted url]"
+		}
+		redacted := u.Scheme + "://" + u.Host
+		if u.Path != "" && u.Path != "/" {
+			redacted += "/[redacted]"
+		}
+		if u.RawQuery != "" {
+			redacted += "?[redacted]"
+		}
+		return redacted
+	})
+}
@@ -0,0 +1,56 @@
+package soroban
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureRecordsCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"status":"healthy"}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, srv.Client())
+	if c.Captured() != nil {
+		t.Fatal("capture should be disabled by default")
+	}
+	c.EnableCapture(2)
+	for range 3 {
+		if _, err := c.GetHealth(context.Background()); err != nil {
+			t.Fatalf("GetHealth: %v", err)
+		}
+	}
+
+	calls := c.Captured()
+	if len(calls) != 2 {
+		t.Fatalf("captured %d calls, want 2 (ring buffer size)", len(calls))
+	}
+	if calls[0].ID <= calls[1].ID {
+		t.Errorf("calls should be newest first, got IDs %d, %d", calls[0].ID, calls[1].ID)
+	}
+	if calls[0].Method != "getHealth" || calls[0].StatusCode != http.StatusOK {
+		t.Errorf("unexpected call: %+v", calls[0])
+	}
+	if !strings.Contains(string(calls[0].Response), "healthy") || !strings.Contains(string(calls[0].Request), "getHealth") {
+		t.Errorf("bodies not captured: %s / %s", calls[0].Request, calls[0].Response)
+	}
+}
+
+func TestCaptureRedactsErrorURLs(t *testing.T) {
+	c := NewClient("http://127.0.0.1:1/rpc/secret-key?apikey=secret", nil)
+	c.EnableCapture(1)
+	if _, err := c.GetHealth(context.Background()); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	calls := c.Captured()
+	if len(calls) != 1 || calls[0].Error == "" {
+		t.Fatalf("failed call not captured: %+v", calls)
+	}
+	if strings.Contains(calls[0].Error, "secret") {
+		t.Errorf("error not redacted: %s", calls[0].Error)
+	}
+}
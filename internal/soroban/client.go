@@ -3,6 +3,7 @@ package soroban
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,11 @@ import (
 	"net/http"
 	"sync/atomic"
 	"time"
+
+	"github.com/mtlprog/total/internal/cachestats"
+	"github.com/mtlprog/total/internal/httpclient"
+	"github.com/samber/hot"
+	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
 var (
@@ -20,6 +26,8 @@ var (
 	ErrTransactionNotFound = errors.New("transaction not found")
 	ErrTimeout             = errors.New("timeout waiting for transaction")
 	ErrUnknownStatus       = errors.New("unknown transaction status")
+	ErrUnsupportedProtocol = errors.New("unsupported protocol version")
+	ErrContractNotFound    = errors.New("contract instance not found")
 )
 
 // maxUnknownStatusRetries is the maximum number of consecutive unknown statuses
@@ -28,32 +36,91 @@ const maxUnknownStatusRetries = 5
 
 // Client is a Soroban RPC client.
 type Client struct {
-	rpcURL     string
+	endpoints  *endpointSet
 	httpClient *http.Client
 	requestID  atomic.Int64
+	capture    *Capture // nil unless debug capture is enabled
+
+	simCache      *hot.HotCache[string, SimulateTransactionResult]
+	simCacheStats cachestats.Counter
 }
 
 // NewClient creates a new Soroban RPC client.
-func NewClient(rpcURL string) *Client {
+// A nil httpClient uses httpclient.Default().
+func NewClient(rpcURL string, httpClient *http.Client) *Client {
+	return NewClientWithEndpoints([]string{rpcURL}, httpClient)
+}
+
+// NewClientWithEndpoints creates a client that sends calls to the first of rpcURLs
+// and fails over to the others, see CheckEndpoints.
+// A nil httpClient uses httpclient.Default().
+func NewClientWithEndpoints(rpcURLs []string, httpClient *http.Client) *Client {
+	if len(rpcURLs) == 0 {
+		panic("NewClientWithEndpoints: at least one RPC URL is required")
+	}
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
 	c := &Client{
-		rpcURL: rpcURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		endpoints:  newEndpointSet(rpcURLs),
+		httpClient: httpClient,
+		simCache: hot.NewHotCache[string, SimulateTransactionResult](hot.LRU, simulationCacheSize).
+			WithTTL(SimulationCacheTTL).
+			Build(),
 	}
 	c.requestID.Store(1)
 	return c
 }
 
-// RPCURL returns the RPC URL.
+// EnableCapture records the last size JSON-RPC calls for inspection via Captured.
+// Call it before the client is used.
+func (c *Client) EnableCapture(size int) {
+	c.capture = NewCapture(size)
+}
+
+// Captured returns the recorded calls, newest first, or nil when capture is disabled.
+func (c *Client) Captured() []CapturedCall {
+	if c.capture == nil {
+		return nil
+	}
+	return c.capture.Calls()
+}
+
+// SimulationCacheStats returns hit and miss counts of the read-only simulation cache.
+func (c *Client) SimulationCacheStats() cachestats.Stats {
+	return c.simCacheStats.Stats()
+}
+
+// RPCURL returns the URL of the active RPC endpoint.
 func (c *Client) RPCURL() string {
-	return c.rpcURL
+	return c.endpoints.current()
 }
 
-// call makes a JSON-RPC call.
-func (c *Client) call(ctx context.Context, method string, params any) (*RPCResponse, error) {
+// call makes a JSON-RPC call, recording it when capture is enabled.
+func (c *Client) call(ctx context.Context, method string, params any) (_ *RPCResponse, err error) {
 	id := c.requestID.Add(1)
 
+	var body, respBody []byte
+	var statusCode int
+	if c.capture != nil {
+		start := time.Now()
+		defer func() {
+			call := CapturedCall{
+				ID:         int(id),
+				Method:     method,
+				Time:       start,
+				Duration:   time.Since(start),
+				StatusCode: statusCode,
+				Request:    body,
+				Response:   respBody,
+			}
+			if err != nil {
+				call.Error = redactURLs(err.Error())
+			}
+			c.capture.record(call)
+		}()
+	}
+
 	req := RPCRequest{
 		JSONRPC: "2.0",
 		ID:      int(id),
@@ -61,38 +128,60 @@ func (c *Client) call(ctx context.Context, method string, params any) (*RPCRespo
 		Params:  params,
 	}
 
-	body, err := json.Marshal(req)
+	body, err = json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcURL, bytes.NewReader(body))
+	// Calls are not retried on another endpoint: sendTransaction may have reached the
+	// network even when its response was lost.
+	rpcURL := c.endpoints.current()
+	statusCode, respBody, err = c.post(ctx, rpcURL, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		if ctx.Err() == nil {
+			c.endpoints.failed(rpcURL, err)
+		}
+		return nil, err
+	}
+
+	var rpcResp RPCResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		err = fmt.Errorf("failed to unmarshal response: %w", err)
+		c.endpoints.failed(rpcURL, err)
+		return nil, err
+	}
+	c.endpoints.succeeded(rpcURL)
+
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("%w: method %s: %s", ErrRPCError, method, rpcResp.Error.Error())
+	}
+
+	return &rpcResp, nil
+}
+
+// post sends a JSON-RPC request body to url. A 5xx status is an error, since
+// providers answer overload and outages with non-JSON-RPC bodies.
+func (c *Client) post(ctx context.Context, url string, body []byte) (int, []byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return 0, nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer httpResp.Body.Close()
 
 	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return httpResp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
 	}
-
-	var resp RPCResponse
-	if err := json.Unmarshal(respBody, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if resp.Error != nil {
-		return nil, fmt.Errorf("%w: method %s: %s", ErrRPCError, method, resp.Error.Error())
+	if httpResp.StatusCode >= http.StatusInternalServerError {
+		return httpResp.StatusCode, respBody, fmt.Errorf("request failed: %s", httpResp.Status)
 	}
-
-	return &resp, nil
+	return httpResp.StatusCode, respBody, nil
 }
 
 // GetHealth checks the health of the RPC server.
@@ -125,6 +214,31 @@ func (c *Client) GetNetwork(ctx context.Context) (*GetNetworkResult, error) {
 	return &result, nil
 }
 
+// GetVersionInfo gets the RPC server build and protocol version.
+func (c *Client) GetVersionInfo(ctx context.Context) (*GetVersionInfoResult, error) {
+	resp, err := c.call(ctx, "getVersionInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result GetVersionInfoResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CheckProtocolVersion returns ErrUnsupportedProtocol when the network protocol is outside
+// the range the XDR encoding in this package was built and tested against.
+func CheckProtocolVersion(version int) error {
+	if version < MinProtocolVersion || version > MaxProtocolVersion {
+		return fmt.Errorf("%w: RPC reports protocol %d, supported range is %d-%d",
+			ErrUnsupportedProtocol, version, MinProtocolVersion, MaxProtocolVersion)
+	}
+	return nil
+}
+
 // GetLatestLedger gets the latest ledger info.
 func (c *Client) GetLatestLedger(ctx context.Context) (*GetLatestLedgerResult, error) {
 	resp, err := c.call(ctx, "getLatestLedger", nil)
@@ -141,7 +255,18 @@ func (c *Client) GetLatestLedger(ctx context.Context) (*GetLatestLedgerResult, e
 }
 
 // SimulateTransaction simulates a transaction to get resource requirements.
+// A failed simulation returns a *SimulationError with the decoded diagnostic events.
 func (c *Client) SimulateTransaction(ctx context.Context, txXDR string) (*SimulateTransactionResult, error) {
+	// Identical read-only calls within SimulationCacheTTL share one simulation.
+	key, cacheable := simulationCacheKey(txXDR)
+	if cacheable {
+		if cached, found, err := c.simCache.Get(key); err == nil && found {
+			c.simCacheStats.Hit()
+			return &cached, nil
+		}
+		c.simCacheStats.Miss()
+	}
+
 	params := SimulateTransactionParams{
 		Transaction: txXDR,
 	}
@@ -157,9 +282,12 @@ func (c *Client) SimulateTransaction(ctx context.Context, txXDR string) (*Simula
 	}
 
 	if result.Error != "" {
-		return &result, fmt.Errorf("%w: %s", ErrSimulationFailed, result.Error)
+		return &result, &SimulationError{Message: result.Error, Events: ParseDiagnosticEvents(result.Events)}
 	}
 
+	if cacheable {
+		c.simCache.Set(key, result)
+	}
 	return &result, nil
 }
 
@@ -252,8 +380,21 @@ func (c *Client) WaitForTransaction(ctx context.Context, hash string, timeout ti
 	}
 }
 
-// GetEvents retrieves contract events matching the given filters.
-func (c *Client) GetEvents(ctx context.Context, params GetEventsParams) (*GetEventsResult, error) {
+// GetEvents retrieves one page of events matching the given filters.
+// The first call starts at startLedger with an empty cursor; follow-up calls pass the
+// cursor from the previous result, in which case startLedger is ignored.
+func (c *Client) GetEvents(ctx context.Context, startLedger uint32, filters []EventFilter, cursor string) (*GetEventsResult, error) {
+	params := GetEventsParams{
+		Filters:    filters,
+		Pagination: &EventPagination{Limit: EventsPageLimit, Cursor: cursor},
+	}
+	if cursor == "" {
+		if startLedger == 0 {
+			return nil, fmt.Errorf("getEvents requires a start ledger or a cursor")
+		}
+		params.StartLedger = startLedger
+	}
+
 	resp, err := c.call(ctx, "getEvents", params)
 	if err != nil {
 		return nil, err
@@ -267,7 +408,8 @@ func (c *Client) GetEvents(ctx context.Context, params GetEventsParams) (*GetEve
 	return &result, nil
 }
 
-// GetLedgerEntries retrieves ledger entries by their keys.
+// GetLedgerEntries retrieves ledger entries by their keys, at most MaxLedgerEntryKeys
+// per call. Entries that do not exist are left out of the result.
 func (c *Client) GetLedgerEntries(ctx context.Context, keys []string) (*GetLedgerEntriesResult, error) {
 	params := GetLedgerEntriesParams{
 		Keys: keys,
@@ -285,3 +427,87 @@ func (c *Client) GetLedgerEntries(ctx context.Context, keys []string) (*GetLedge
 
 	return &result, nil
 }
+
+// ContractInstanceKey returns the base64 ledger key of a contract's instance entry,
+// which holds the contract's instance storage.
+func ContractInstanceKey(contractID string) (string, error) {
+	address, err := EncodeAddress(contractID)
+	if err != nil {
+		return "", err
+	}
+	key, err := xdr.MarshalBase64(xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   *address.Address,
+			Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+			Durability: xdr.ContractDataDurabilityPersistent,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ledger key: %w", err)
+	}
+	return key, nil
+}
+
+// ContractInstance returns the instance of a deployed contract, read from its instance
+// ledger entry. ErrContractNotFound means nothing is deployed at contractID.
+func (c *Client) ContractInstance(ctx context.Context, contractID string) (*xdr.ScContractInstance, error) {
+	key, err := ContractInstanceKey(contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.GetLedgerEntries(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrContractNotFound, contractID)
+	}
+	var data xdr.LedgerEntryData
+	if err := xdr.SafeUnmarshalBase64(result.Entries[0].XDR, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode contract instance: %w", err)
+	}
+	if data.ContractData == nil || data.ContractData.Val.Instance == nil {
+		return nil, fmt.Errorf("ledger entry of %s is not a contract instance", contractID)
+	}
+	return data.ContractData.Val.Instance, nil
+}
+
+// ContractWasmHash returns the hex hash of the WASM code a contract instance runs.
+// Stellar Asset Contracts have no WASM and return an error.
+func (c *Client) ContractWasmHash(ctx context.Context, contractID string) (string, error) {
+	instance, err := c.ContractInstance(ctx, contractID)
+	if err != nil {
+		return "", err
+	}
+	executable := instance.Executable
+	if executable.Type != xdr.ContractExecutableTypeContractExecutableWasm || executable.WasmHash == nil {
+		return "", fmt.Errorf("contract %s does not run WASM code", contractID)
+	}
+	return hex.EncodeToString(executable.WasmHash[:]), nil
+}
+
+// WasmInstalled reports whether WASM code with the given hex hash has been uploaded
+// to the network, so contracts can be deployed from it.
+func (c *Client) WasmInstalled(ctx context.Context, wasmHash string) (bool, error) {
+	b, err := hex.DecodeString(wasmHash)
+	if err != nil || len(b) != 32 {
+		return false, fmt.Errorf("invalid wasm hash %q", wasmHash)
+	}
+	var hash xdr.Hash
+	copy(hash[:], b)
+	key, err := xdr.MarshalBase64(xdr.LedgerKey{
+		Type:         xdr.LedgerEntryTypeContractCode,
+		ContractCode: &xdr.LedgerKeyContractCode{Hash: hash},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode ledger key: %w", err)
+	}
+
+	result, err := c.GetLedgerEntries(ctx, []string{key})
+	if err != nil {
+		return false, err
+	}
+	return len(result.Entries) > 0, nil
+}
@@ -3,11 +3,14 @@ package soroban
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"math"
 	"strconv"
+	"time"
 
+	"github.com/stellar/go-stellar-sdk/network"
 	"github.com/stellar/go-stellar-sdk/strkey"
 	"github.com/stellar/go-stellar-sdk/txnbuild"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -35,17 +38,29 @@ type ContractInvoker struct {
 	client            *Client
 	networkPassphrase string
 	baseFee           int64
+	txTimeout         time.Duration
+	clock             ledgerClock
 }
 
 // NewContractInvoker creates a new contract invoker.
-func NewContractInvoker(client *Client, networkPassphrase string, baseFee int64) *ContractInvoker {
+// A zero txTimeout builds transactions without an upper time bound.
+func NewContractInvoker(client *Client, networkPassphrase string, baseFee int64, txTimeout time.Duration) *ContractInvoker {
 	return &ContractInvoker{
 		client:            client,
 		networkPassphrase: networkPassphrase,
 		baseFee:           baseFee,
+		txTimeout:         txTimeout,
 	}
 }
 
+// timeBounds returns the time bounds for a transaction built now.
+func (ci *ContractInvoker) timeBounds() txnbuild.TimeBounds {
+	if ci.txTimeout <= 0 {
+		return txnbuild.NewInfiniteTimeout()
+	}
+	return txnbuild.NewTimeout(int64(ci.txTimeout / time.Second))
+}
+
 // InvokeParams contains parameters for invoking a contract function.
 type InvokeParams struct {
 	SourceAccount txnbuild.Account
@@ -53,6 +68,7 @@ type InvokeParams struct {
 	FunctionName  string
 	Args          []xdr.ScVal
 	Auth          []xdr.SorobanAuthorizationEntry
+	Memo          string // Optional text memo, at most 28 bytes
 }
 
 // BuildInvokeTx builds an InvokeHostFunction transaction.
@@ -84,22 +100,30 @@ func (ci *ContractInvoker) BuildInvokeTx(ctx context.Context, params InvokeParam
 		InvokeContract: &invokeArgs,
 	}
 
+	return ci.buildHostFunctionTx(params.SourceAccount, hostFunc, params.Auth, params.Memo)
+}
+
+// buildHostFunctionTx builds a transaction with a single InvokeHostFunction operation.
+func (ci *ContractInvoker) buildHostFunctionTx(source txnbuild.Account, hostFunc xdr.HostFunction, auth []xdr.SorobanAuthorizationEntry, memo string) (string, error) {
 	op := &txnbuild.InvokeHostFunction{
 		HostFunction: hostFunc,
-		Auth:         params.Auth,
+		Auth:         auth,
 	}
 
-	tx, err := txnbuild.NewTransaction(
-		txnbuild.TransactionParams{
-			SourceAccount:        params.SourceAccount,
-			IncrementSequenceNum: true,
-			Operations:           []txnbuild.Operation{op},
-			BaseFee:              ci.baseFee,
-			Preconditions: txnbuild.Preconditions{
-				TimeBounds: txnbuild.NewInfiniteTimeout(),
-			},
+	txParams := txnbuild.TransactionParams{
+		SourceAccount:        source,
+		IncrementSequenceNum: true,
+		Operations:           []txnbuild.Operation{op},
+		BaseFee:              ci.baseFee,
+		Preconditions: txnbuild.Preconditions{
+			TimeBounds: ci.timeBounds(),
 		},
-	)
+	}
+	if memo != "" {
+		txParams.Memo = txnbuild.MemoText(memo)
+	}
+
+	tx, err := txnbuild.NewTransaction(txParams)
 	if err != nil {
 		return "", fmt.Errorf("failed to build transaction: %w", err)
 	}
@@ -112,7 +136,89 @@ func (ci *ContractInvoker) BuildInvokeTx(ctx context.Context, params InvokeParam
 	return xdrBytes, nil
 }
 
+// TransactionFee returns the total fee (inclusion + resource) of a transaction envelope.
+func TransactionFee(txXDR string) (int64, error) {
+	var txEnvelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &txEnvelope); err != nil {
+		return 0, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	return int64(txEnvelope.Fee()), nil
+}
+
+// DecodedInvoke describes the contract call carried by an InvokeHostFunction transaction.
+type DecodedInvoke struct {
+	SourceAccount string
+	ContractID    string
+	FunctionName  string
+	Args          []xdr.ScVal
+	Memo          string // Text memo, empty when the transaction has none
+}
+
+// DecodeInvokeTx extracts the source account and contract call from a single-operation
+// InvokeHostFunction transaction. Auth entries and resources are dropped: they are
+// recomputed by simulation when the call is rebuilt.
+func DecodeInvokeTx(txXDR string) (*DecodedInvoke, error) {
+	var txEnvelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &txEnvelope); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	if txEnvelope.Type != xdr.EnvelopeTypeEnvelopeTypeTx {
+		return nil, fmt.Errorf("unsupported envelope type: %v", txEnvelope.Type)
+	}
+
+	tx := txEnvelope.V1.Tx
+	if len(tx.Operations) != 1 {
+		return nil, fmt.Errorf("expected 1 operation, got %d", len(tx.Operations))
+	}
+	invokeOp := tx.Operations[0].Body.InvokeHostFunctionOp
+	if invokeOp == nil {
+		return nil, fmt.Errorf("operation is not an InvokeHostFunction")
+	}
+	invokeArgs := invokeOp.HostFunction.InvokeContract
+	if invokeArgs == nil {
+		return nil, fmt.Errorf("host function is not a contract invocation")
+	}
+	if invokeArgs.ContractAddress.ContractId == nil {
+		return nil, fmt.Errorf("invoked address is not a contract")
+	}
+
+	contractID, err := strkey.Encode(strkey.VersionByteContract, invokeArgs.ContractAddress.ContractId[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode contract ID: %w", err)
+	}
+
+	var memo string
+	if tx.Memo.Text != nil {
+		memo = *tx.Memo.Text
+	}
+
+	source := tx.SourceAccount.ToAccountId()
+	return &DecodedInvoke{
+		SourceAccount: source.Address(),
+		ContractID:    contractID,
+		FunctionName:  string(invokeArgs.FunctionName),
+		Args:          invokeArgs.Args,
+		Memo:          memo,
+	}, nil
+}
+
+// TransactionHash returns the hex hash of a transaction envelope on the given network.
+// Signatures are not part of the hash, so an unsigned and a signed envelope hash the same.
+func TransactionHash(txXDR, networkPassphrase string) (string, error) {
+	var txEnvelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &txEnvelope); err != nil {
+		return "", fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	hash, err := network.HashTransactionInEnvelope(txEnvelope, networkPassphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash transaction: %w", err)
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
+
 // SimulateAndPrepare simulates a transaction and returns it with resources attached.
+// Address auth entries get a signature expiration ledger past the transaction's time
+// bounds; re-simulating a rebuilt transaction refreshes them.
 func (ci *ContractInvoker) SimulateAndPrepare(ctx context.Context, txXDR string) (string, error) {
 	simResult, err := ci.client.SimulateTransaction(ctx, txXDR)
 	if err != nil {
@@ -121,6 +227,9 @@ func (ci *ContractInvoker) SimulateAndPrepare(ctx context.Context, txXDR string)
 
 	// Note: simResult.Error check is handled by SimulateTransaction which returns
 	// ErrSimulationFailed when the error field is non-empty.
+	if simResult.LatestLedger > 0 {
+		ci.clock.observe(simResult.LatestLedger, time.Now())
+	}
 
 	// Parse the original transaction
 	var txEnvelope xdr.TransactionEnvelope
@@ -185,6 +294,9 @@ func (ci *ContractInvoker) SimulateAndPrepare(ctx context.Context, txXDR string)
 			}
 			invokeOp.Auth[i] = auth
 		}
+		if simResult.LatestLedger > 0 {
+			setAuthExpiration(invokeOp.Auth, simResult.LatestLedger+ci.authValidityLedgers())
+		}
 	}
 
 	// Re-encode the updated envelope
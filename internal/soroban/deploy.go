@@ -0,0 +1,81 @@
+package soroban
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// WasmHash returns the hash WASM code is stored under once uploaded.
+func WasmHash(wasm []byte) [32]byte {
+	return sha256.Sum256(wasm)
+}
+
+// BuildUploadWasmTx builds a transaction uploading contract WASM code to the network.
+// Uploading code that is already installed only extends its TTL.
+func (ci *ContractInvoker) BuildUploadWasmTx(source txnbuild.Account, wasm []byte) (string, error) {
+	if len(wasm) == 0 {
+		return "", fmt.Errorf("wasm code is empty")
+	}
+	return ci.buildHostFunctionTx(source, xdr.HostFunction{
+		Type: xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm,
+		Wasm: &wasm,
+	}, nil, "")
+}
+
+// BuildCreateContractTx builds a transaction deploying a contract instance of uploaded
+// WASM code. The contract ID is derived from the source account and salt; the
+// transaction returns it.
+func (ci *ContractInvoker) BuildCreateContractTx(source txnbuild.Account, wasmHash, salt [32]byte) (string, error) {
+	deployer, err := EncodeAddress(source.GetAccountID())
+	if err != nil {
+		return "", fmt.Errorf("invalid deployer: %w", err)
+	}
+	hash := xdr.Hash(wasmHash)
+	return ci.buildHostFunctionTx(source, xdr.HostFunction{
+		Type: xdr.HostFunctionTypeHostFunctionTypeCreateContract,
+		CreateContract: &xdr.CreateContractArgs{
+			ContractIdPreimage: xdr.ContractIdPreimage{
+				Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+				FromAddress: &xdr.ContractIdPreimageFromAddress{
+					Address: *deployer.Address,
+					Salt:    xdr.Uint256(salt),
+				},
+			},
+			Executable: xdr.ContractExecutable{
+				Type:     xdr.ContractExecutableTypeContractExecutableWasm,
+				WasmHash: &hash,
+			},
+		},
+	}, nil, "")
+}
+
+// BuildDeployAssetContractTx builds a transaction deploying the Stellar Asset Contract
+// of a classic asset. Each asset has one SAC, at AssetContractID.
+func (ci *ContractInvoker) BuildDeployAssetContractTx(source txnbuild.Account, asset xdr.Asset) (string, error) {
+	return ci.buildHostFunctionTx(source, xdr.HostFunction{
+		Type: xdr.HostFunctionTypeHostFunctionTypeCreateContract,
+		CreateContract: &xdr.CreateContractArgs{
+			ContractIdPreimage: xdr.ContractIdPreimage{
+				Type:      xdr.ContractIdPreimageTypeContractIdPreimageFromAsset,
+				FromAsset: &asset,
+			},
+			Executable: xdr.ContractExecutable{
+				Type: xdr.ContractExecutableTypeContractExecutableStellarAsset,
+			},
+		},
+	}, nil, "")
+}
+
+// AssetContractID returns the contract ID (C...) of a classic asset's Stellar Asset
+// Contract on the given network.
+func AssetContractID(asset xdr.Asset, networkPassphrase string) (string, error) {
+	id, err := asset.ContractID(networkPassphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive asset contract ID: %w", err)
+	}
+	return strkey.Encode(strkey.VersionByteContract, id[:])
+}
@@ -0,0 +1,140 @@
+package soroban
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// TxSummary describes a transaction envelope for people checking what they are about
+// to sign. Fees are in stroops.
+type TxSummary struct {
+	Hash           string
+	Source         string
+	FeeSource      string // Set for fee bump transactions, which another account pays for
+	MaxFee         int64  // Most the transaction can be charged, resource fee included
+	ResourceFee    int64  // Soroban resource fee, zero for classic transactions
+	SequenceNumber int64
+	MinTime        *time.Time // nil when the transaction has no lower time bound
+	MaxTime        *time.Time // nil when the transaction has no upper time bound
+	Memo           string
+	Operations     []OperationSummary
+	Signatures     int
+}
+
+// OperationSummary describes one operation. Contract fields are set for contract calls.
+type OperationSummary struct {
+	Type        string // e.g. "InvokeHostFunction", "Payment"
+	Source      string // Set when the operation has its own source account
+	ContractID  string
+	Function    string
+	Args        []string // Formatted with FormatSCVal
+	AuthEntries int      // Authorizations the call carries, signed or to be signed
+}
+
+// DescribeTx decodes a transaction envelope, signed or not, into a TxSummary.
+func DescribeTx(txXDR, networkPassphrase string) (*TxSummary, error) {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(strings.TrimSpace(txXDR), &env); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	hash, err := network.HashTransactionInEnvelope(env, networkPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash transaction: %w", err)
+	}
+
+	source := env.SourceAccount()
+	summary := &TxSummary{
+		Hash:           hex.EncodeToString(hash[:]),
+		Source:         source.Address(),
+		MaxFee:         int64(env.Fee()),
+		SequenceNumber: env.SeqNum(),
+		Memo:           formatMemo(env.Memo()),
+		Signatures:     len(env.Signatures()),
+	}
+	if env.IsFeeBump() {
+		feeSource := env.FeeBumpAccount()
+		summary.FeeSource = feeSource.Address()
+		summary.MaxFee = env.FeeBumpFee()
+	}
+	if data, ok := sorobanData(env); ok {
+		summary.ResourceFee = int64(data.ResourceFee)
+	}
+	if tb := env.TimeBounds(); tb != nil {
+		if tb.MinTime != 0 {
+			t := time.Unix(int64(tb.MinTime), 0).UTC()
+			summary.MinTime = &t
+		}
+		if tb.MaxTime != 0 {
+			t := time.Unix(int64(tb.MaxTime), 0).UTC()
+			summary.MaxTime = &t
+		}
+	}
+
+	for _, op := range env.Operations() {
+		opSummary, err := describeOperation(op)
+		if err != nil {
+			return nil, err
+		}
+		summary.Operations = append(summary.Operations, opSummary)
+	}
+	return summary, nil
+}
+
+func describeOperation(op xdr.Operation) (OperationSummary, error) {
+	s := OperationSummary{Type: strings.TrimPrefix(op.Body.Type.String(), "OperationType")}
+	if op.SourceAccount != nil {
+		s.Source = op.SourceAccount.Address()
+	}
+	invoke, ok := op.Body.GetInvokeHostFunctionOp()
+	if !ok {
+		return s, nil
+	}
+	s.AuthEntries = len(invoke.Auth)
+	call, ok := invoke.HostFunction.GetInvokeContract()
+	if !ok {
+		return s, nil
+	}
+	contractID, err := call.ContractAddress.String()
+	if err != nil {
+		return s, fmt.Errorf("failed to encode contract address: %w", err)
+	}
+	s.ContractID = contractID
+	s.Function = string(call.FunctionName)
+	s.Args = make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		s.Args[i] = FormatSCVal(arg)
+	}
+	return s, nil
+}
+
+func sorobanData(env xdr.TransactionEnvelope) (xdr.SorobanTransactionData, bool) {
+	switch env.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		return env.V1.Tx.Ext.GetSorobanData()
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		return env.FeeBump.Tx.InnerTx.V1.Tx.Ext.GetSorobanData()
+	default:
+		return xdr.SorobanTransactionData{}, false
+	}
+}
+
+func formatMemo(m xdr.Memo) string {
+	switch m.Type {
+	case xdr.MemoTypeMemoText:
+		return strconv.Quote(*m.Text)
+	case xdr.MemoTypeMemoId:
+		return fmt.Sprintf("ID %d", *m.Id)
+	case xdr.MemoTypeMemoHash:
+		return "hash " + hex.EncodeToString(m.Hash[:])
+	case xdr.MemoTypeMemoReturn:
+		return "return " + hex.EncodeToString(m.RetHash[:])
+	default:
+		return ""
+	}
+}
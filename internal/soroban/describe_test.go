@@ -0,0 +1,73 @@
+package soroban
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestDescribeTx(t *testing.T) {
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := keypair.MustRandom()
+	ci := NewContractInvoker(nil, network.TestNetworkPassphrase, 100, 5*time.Minute)
+	txXDR, err := ci.BuildInvokeTx(context.Background(), InvokeParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: user.Address(), Sequence: 41},
+		ContractID:    contractID,
+		FunctionName:  "buy",
+		Args:          []xdr.ScVal{EncodeU32(0), EncodeI128(10), EncodeString("x")},
+		Memo:          "ref:abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := DescribeTx(txXDR, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("DescribeTx() error = %v", err)
+	}
+	wantHash, _ := TransactionHash(txXDR, network.TestNetworkPassphrase)
+	if summary.Hash != wantHash {
+		t.Errorf("Hash = %s, want %s", summary.Hash, wantHash)
+	}
+	if summary.Source != user.Address() || summary.FeeSource != "" {
+		t.Errorf("Source = %s, FeeSource = %s", summary.Source, summary.FeeSource)
+	}
+	if summary.SequenceNumber != 42 {
+		t.Errorf("SequenceNumber = %d, want 42", summary.SequenceNumber)
+	}
+	if summary.MaxFee != 100 {
+		t.Errorf("MaxFee = %d, want 100", summary.MaxFee)
+	}
+	if summary.MinTime != nil || summary.MaxTime == nil {
+		t.Errorf("time bounds = %v..%v, want only an upper bound", summary.MinTime, summary.MaxTime)
+	}
+	if summary.Memo != `"ref:abc"` {
+		t.Errorf("Memo = %s", summary.Memo)
+	}
+	if summary.Signatures != 0 {
+		t.Errorf("Signatures = %d, want 0", summary.Signatures)
+	}
+	if len(summary.Operations) != 1 {
+		t.Fatalf("got %d operations, want 1", len(summary.Operations))
+	}
+	op := summary.Operations[0]
+	if op.Type != "InvokeHostFunction" || op.ContractID != contractID || op.Function != "buy" {
+		t.Errorf("operation = %+v", op)
+	}
+	if len(op.Args) != 3 || op.Args[2] != `"x"` {
+		t.Errorf("Args = %v", op.Args)
+	}
+
+	if _, err := DescribeTx("not xdr", network.TestNetworkPassphrase); err == nil {
+		t.Error("DescribeTx() should fail on invalid XDR")
+	}
+}
@@ -0,0 +1,189 @@
+package soroban
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// maxTraceEvents bounds the diagnostic events turned into a trace; failing calls
+// rarely need more and traces end up on error pages.
+const maxTraceEvents = 50
+
+// SimulationError is a failed simulation with the diagnostic events the RPC
+// returned. It matches ErrSimulationFailed with errors.Is.
+type SimulationError struct {
+	Message string
+	Events  []DiagnosticEvent
+}
+
+func (e *SimulationError) Error() string {
+	return ErrSimulationFailed.Error() + ": " + e.Message
+}
+
+func (e *SimulationError) Unwrap() error {
+	return ErrSimulationFailed
+}
+
+// DiagnosticEvent is a decoded diagnostic event from a simulation: a contract call,
+// return, log or error raised while the transaction ran.
+type DiagnosticEvent struct {
+	InSuccessfulCall bool
+	ContractID       string   // C... address; empty for host events
+	Topics           []string // readable topic values, e.g. ["fn_call", "C...", "buy"]
+	Data             string   // readable event data
+}
+
+// String renders the event as one line of a call trace.
+func (e DiagnosticEvent) String() string {
+	kind := ""
+	if len(e.Topics) > 0 {
+		kind = e.Topics[0]
+	}
+	var line string
+	switch {
+	case kind == "fn_call" && len(e.Topics) >= 3:
+		line = fmt.Sprintf("→ call %s.%s(%s)", e.Topics[1], e.Topics[2], strings.TrimSuffix(strings.TrimPrefix(e.Data, "["), "]"))
+	case kind == "fn_return" && len(e.Topics) >= 2:
+		line = fmt.Sprintf("← %s returned %s", e.Topics[1], e.Data)
+	case kind == "error":
+		line = fmt.Sprintf("✗ %s: %s", strings.Join(e.Topics[1:], " "), e.Data)
+	default:
+		line = fmt.Sprintf("• %s [%s]: %s", e.ContractID, strings.Join(e.Topics, ", "), e.Data)
+	}
+	if !e.InSuccessfulCall {
+		line += " (in failed call)"
+	}
+	return line
+}
+
+// ParseDiagnosticEvents decodes base64 DiagnosticEvent XDR as returned in
+// simulateTransaction's events. Undecodable events are skipped.
+func ParseDiagnosticEvents(encoded []string) []DiagnosticEvent {
+	events := make([]DiagnosticEvent, 0, len(encoded))
+	for _, raw := range encoded {
+		var de xdr.DiagnosticEvent
+		if err := xdr.SafeUnmarshalBase64(raw, &de); err != nil {
+			continue
+		}
+		events = append(events, diagnosticEvent(de))
+	}
+	return events
+}
+
+func diagnosticEvent(de xdr.DiagnosticEvent) DiagnosticEvent {
+	event := DiagnosticEvent{InSuccessfulCall: de.InSuccessfulContractCall}
+	if de.Event.ContractId != nil {
+		id := *de.Event.ContractId
+		event.ContractID, _ = strkey.Encode(strkey.VersionByteContract, id[:])
+	}
+	if body, ok := de.Event.Body.GetV0(); ok {
+		for i, topic := range body.Topics {
+			// The second fn_call topic is the callee's contract ID as raw bytes.
+			if i == 1 && topic.Type == xdr.ScValTypeScvBytes && len(event.Topics) > 0 && event.Topics[0] == "fn_call" && len(*topic.Bytes) == 32 {
+				if id, err := strkey.Encode(strkey.VersionByteContract, *topic.Bytes); err == nil {
+					event.Topics = append(event.Topics, id)
+					continue
+				}
+			}
+			event.Topics = append(event.Topics, FormatSCVal(topic))
+		}
+		event.Data = FormatSCVal(body.Data)
+	}
+	return event
+}
+
+// DiagnosticTrace returns the call trace of a failed simulation in err, one line per
+// event, or nil when err carries no diagnostic events.
+func DiagnosticTrace(err error) []string {
+	var simErr *SimulationError
+	if !errors.As(err, &simErr) {
+		return nil
+	}
+	return EventTrace(simErr.Events)
+}
+
+// EventTrace renders diagnostic events as a call trace, one line per event, keeping
+// the last maxTraceEvents. Returns nil without events.
+func EventTrace(events []DiagnosticEvent) []string {
+	if len(events) == 0 {
+		return nil
+	}
+	var trace []string
+	if len(events) > maxTraceEvents {
+		trace = append(trace, fmt.Sprintf("… %d earlier events omitted", len(events)-maxTraceEvents))
+		events = events[len(events)-maxTraceEvents:]
+	}
+	for _, e := range events {
+		trace = append(trace, e.String())
+	}
+	return trace
+}
+
+// contractErrorPattern matches contract errors as rendered by FormatSCVal and the host.
+var contractErrorPattern = regexp.MustCompile(`Error\(Contract, #(\d+)\)`)
+
+// ContractErrorCode returns the first contract error code, the #N of
+// Error(Contract, #N), found in a simulation error message or diagnostic events.
+func ContractErrorCode(message string, events []DiagnosticEvent) (uint32, bool) {
+	texts := []string{message}
+	for _, e := range events {
+		texts = append(texts, strings.Join(e.Topics, " "), e.Data)
+	}
+	for _, text := range texts {
+		if m := contractErrorPattern.FindStringSubmatch(text); m != nil {
+			code, err := strconv.ParseUint(m[1], 10, 32)
+			if err == nil {
+				return uint32(code), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// FormatSCVal renders a contract value for people: strings quoted, errors in the
+// Error(Contract, #3) form used by the host, vectors and maps expanded.
+func FormatSCVal(v xdr.ScVal) string {
+	switch v.Type {
+	case xdr.ScValTypeScvString:
+		return strconv.Quote(string(*v.Str))
+	case xdr.ScValTypeScvError:
+		return formatSCError(*v.Error)
+	case xdr.ScValTypeScvVec:
+		if *v.Vec == nil {
+			return "[]"
+		}
+		items := make([]string, 0, len(**v.Vec))
+		for _, item := range **v.Vec {
+			items = append(items, FormatSCVal(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case xdr.ScValTypeScvMap:
+		if *v.Map == nil {
+			return "{}"
+		}
+		entries := make([]string, 0, len(**v.Map))
+		for _, entry := range **v.Map {
+			entries = append(entries, FormatSCVal(entry.Key)+": "+FormatSCVal(entry.Val))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	default:
+		return v.String()
+	}
+}
+
+func formatSCError(e xdr.ScError) string {
+	kind := strings.TrimPrefix(e.Type.String(), "ScErrorTypeSce")
+	if e.Type == xdr.ScErrorTypeSceContract && e.ContractCode != nil {
+		return fmt.Sprintf("Error(%s, #%d)", kind, *e.ContractCode)
+	}
+	if e.Code != nil {
+		return fmt.Sprintf("Error(%s, %s)", kind, strings.TrimPrefix(e.Code.String(), "ScErrorCodeScec"))
+	}
+	return fmt.Sprintf("Error(%s)", kind)
+}
@@ -0,0 +1,112 @@
+package soroban
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func diagnosticEventXDR(t *testing.T, inSuccessfulCall bool, contract *xdr.ContractId, topics []xdr.ScVal, data xdr.ScVal) string {
+	t.Helper()
+	event := xdr.DiagnosticEvent{
+		InSuccessfulContractCall: inSuccessfulCall,
+		Event: xdr.ContractEvent{
+			ContractId: contract,
+			Type:       xdr.ContractEventTypeDiagnostic,
+			Body: xdr.ContractEventBody{
+				V:  0,
+				V0: &xdr.ContractEventV0{Topics: topics, Data: data},
+			},
+		},
+	}
+	encoded, err := xdr.MarshalBase64(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return encoded
+}
+
+func sym(s string) xdr.ScVal {
+	v := xdr.ScSymbol(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &v}
+}
+
+func u32(n uint32) xdr.ScVal {
+	v := xdr.Uint32(n)
+	return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+}
+
+func vec(items ...xdr.ScVal) xdr.ScVal {
+	v := xdr.ScVec(items)
+	p := &v
+	return xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &p}
+}
+
+func TestParseDiagnosticEvents(t *testing.T) {
+	var contract xdr.ContractId
+	contract[0] = 1
+	calleeBytes := xdr.ScBytes(contract[:])
+	code := xdr.Uint32(3)
+	msg := xdr.ScString("slippage exceeded")
+
+	encoded := []string{
+		diagnosticEventXDR(t, false, nil,
+			[]xdr.ScVal{sym("fn_call"), {Type: xdr.ScValTypeScvBytes, Bytes: &calleeBytes}, sym("buy")},
+			vec(u32(0), u32(100))),
+		diagnosticEventXDR(t, false, &contract,
+			[]xdr.ScVal{sym("error"), {Type: xdr.ScValTypeScvError, Error: &xdr.ScError{Type: xdr.ScErrorTypeSceContract, ContractCode: &code}}},
+			xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &msg}),
+		"not xdr",
+	}
+
+	events := ParseDiagnosticEvents(encoded)
+	if len(events) != 2 {
+		t.Fatalf("parsed %d events, want 2 (invalid XDR skipped)", len(events))
+	}
+	if !strings.HasPrefix(events[0].Topics[1], "C") || events[0].Topics[2] != "buy" {
+		t.Errorf("fn_call topics = %v, want callee address and function name", events[0].Topics)
+	}
+	if events[1].ContractID == "" {
+		t.Error("contract ID of the error event not decoded")
+	}
+
+	err := fmt.Errorf("simulation failed: %w", &SimulationError{Message: "HostError: Error(Contract, #3)", Events: events})
+	if !errors.Is(err, ErrSimulationFailed) {
+		t.Error("SimulationError should match ErrSimulationFailed")
+	}
+	trace := DiagnosticTrace(err)
+	if len(trace) != 2 {
+		t.Fatalf("trace = %v, want 2 lines", trace)
+	}
+	if !strings.Contains(trace[0], ".buy(0, 100)") {
+		t.Errorf("call line = %q", trace[0])
+	}
+	if !strings.Contains(trace[1], `Error(Contract, #3): "slippage exceeded"`) {
+		t.Errorf("error line = %q", trace[1])
+	}
+
+	if DiagnosticTrace(ErrSimulationFailed) != nil {
+		t.Error("errors without events should have no trace")
+	}
+}
+
+func TestContractErrorCode(t *testing.T) {
+	code, ok := ContractErrorCode("HostError: Error(Contract, #8)", nil)
+	if !ok || code != 8 {
+		t.Errorf("from message = %d, %v; want 8", code, ok)
+	}
+	events := []DiagnosticEvent{
+		{Topics: []string{"fn_call", "C...", "buy"}, Data: "[1, 2]"},
+		{Topics: []string{"error", "Error(Contract, #3)"}, Data: `"escalating error to panic"`},
+	}
+	code, ok = ContractErrorCode("", events)
+	if !ok || code != 3 {
+		t.Errorf("from events = %d, %v; want 3", code, ok)
+	}
+	if _, ok := ContractErrorCode("HostError: Error(Auth, InvalidAction)", nil); ok {
+		t.Error("found a contract error in a host error")
+	}
+}
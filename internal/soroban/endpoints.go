@@ -0,0 +1,274 @@
+package soroban
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointFailureThreshold is how many consecutive failed calls to the active RPC
+	// endpoint make the client fail over to another one.
+	EndpointFailureThreshold = 3
+	// EndpointCheckInterval is how often CheckEndpoints should run when more than one
+	// RPC endpoint is configured.
+	EndpointCheckInterval = 30 * time.Second
+)
+
+// EndpointStatus describes one configured RPC endpoint.
+type EndpointStatus struct {
+	URL                 string        `json:"url"` // Credentials redacted, like captured calls
+	Active              bool          `json:"active"`
+	Healthy             bool          `json:"healthy"`
+	Latency             time.Duration `json:"-"` // Of the last health check
+	LatencyMillis       int64         `json:"latency_ms"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastChecked         time.Time     `json:"last_checked,omitzero"`
+	LastError           string        `json:"last_error,omitempty"`
+}
+
+type endpoint struct {
+	url       string
+	healthy   bool
+	latency   time.Duration
+	failures  int
+	checked   time.Time
+	lastError string
+}
+
+// endpointSet picks the RPC endpoint calls go to. All endpoints start healthy and the
+// first one is active. A call that fails at the transport level counts against the
+// endpoint it went to; after EndpointFailureThreshold consecutive failures the
+// endpoint is marked unhealthy and calls move to the next one. Health checks bring
+// endpoints back and prefer the healthy endpoint with the lowest latency.
+type endpointSet struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	active    int
+	failovers int64
+}
+
+func newEndpointSet(urls []string) *endpointSet {
+	s := &endpointSet{}
+	for _, u := range urls {
+		s.endpoints = append(s.endpoints, &endpoint{url: u, healthy: true})
+	}
+	return s
+}
+
+// current returns the URL calls should go to.
+func (s *endpointSet) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.endpoints[s.active].url
+}
+
+// succeeded records a call that reached the endpoint, including calls the RPC
+// answered with a JSON-RPC error.
+func (s *endpointSet) succeeded(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.find(url); e != nil {
+		e.failures = 0
+		e.healthy = true
+	}
+}
+
+// failed records a call that did not reach the endpoint or got no usable answer, and
+// fails over when the active endpoint reaches EndpointFailureThreshold.
+func (s *endpointSet) failed(url string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.find(url)
+	if e == nil {
+		return
+	}
+	e.failures++
+	e.lastError = redactURLs(err.Error())
+	if e.failures < EndpointFailureThreshold {
+		return
+	}
+	e.healthy = false
+	if s.endpoints[s.active] == e {
+		s.switchTo(s.next(), "consecutive failures")
+	}
+}
+
+// checked records a health check result. A passing check clears the failure count.
+func (s *endpointSet) checked(url string, latency time.Duration, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.find(url)
+	if e == nil {
+		return
+	}
+	e.checked = now
+	e.latency = latency
+	e.healthy = err == nil
+	e.lastError = ""
+	if err != nil {
+		e.lastError = redactURLs(err.Error())
+	} else {
+		e.failures = 0
+	}
+}
+
+// prefer switches to the healthy endpoint with the lowest latency. The active
+// endpoint is kept unless it is unhealthy or another one is at least a quarter
+// faster, so similar endpoints do not flap.
+func (s *endpointSet) prefer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	best := -1
+	for i, e := range s.endpoints {
+		if e.healthy && (best < 0 || e.latency < s.endpoints[best].latency) {
+			best = i
+		}
+	}
+	if best < 0 || best == s.active {
+		return
+	}
+	active := s.endpoints[s.active]
+	if !active.healthy {
+		s.switchTo(best, "unhealthy")
+	} else if s.endpoints[best].latency < active.latency*3/4 {
+		s.switchTo(best, "lower latency")
+	}
+}
+
+// next returns the first healthy endpoint after the active one, or simply the next
+// one when none is healthy.
+func (s *endpointSet) next() int {
+	n := len(s.endpoints)
+	for i := 1; i < n; i++ {
+		if j := (s.active + i) % n; s.endpoints[j].healthy {
+			return j
+		}
+	}
+	return (s.active + 1) % n
+}
+
+func (s *endpointSet) switchTo(i int, reason string) {
+	if i == s.active {
+		return
+	}
+	slog.Warn("switching Soroban RPC endpoint",
+		"from", redactURLs(s.endpoints[s.active].url),
+		"to", redactURLs(s.endpoints[i].url),
+		"reason", reason,
+	)
+	s.active = i
+	s.failovers++
+}
+
+func (s *endpointSet) find(url string) *endpoint {
+	for _, e := range s.endpoints {
+		if e.url == url {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *endpointSet) urls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urls := make([]string, len(s.endpoints))
+	for i, e := range s.endpoints {
+		urls[i] = e.url
+	}
+	return urls
+}
+
+func (s *endpointSet) status() []EndpointStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]EndpointStatus, len(s.endpoints))
+	for i, e := range s.endpoints {
+		statuses[i] = EndpointStatus{
+			URL:                 redactURLs(e.url),
+			Active:              i == s.active,
+			Healthy:             e.healthy,
+			Latency:             e.latency,
+			LatencyMillis:       e.latency.Milliseconds(),
+			ConsecutiveFailures: e.failures,
+			LastChecked:         e.checked,
+			LastError:           e.lastError,
+		}
+	}
+	return statuses
+}
+
+// Endpoints returns the configured RPC endpoints and their health.
+func (c *Client) Endpoints() []EndpointStatus {
+	return c.endpoints.status()
+}
+
+// Failovers returns how many times calls moved to another RPC endpoint.
+func (c *Client) Failovers() int64 {
+	c.endpoints.mu.Lock()
+	defer c.endpoints.mu.Unlock()
+	return c.endpoints.failovers
+}
+
+// CheckEndpoints calls getHealth on every endpoint, records whether it is healthy and
+// how long it took, and switches to the healthy endpoint with the lowest latency. It
+// returns an error only when no endpoint is healthy.
+func (c *Client) CheckEndpoints(ctx context.Context) error {
+	urls := c.endpoints.urls()
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := c.checkEndpoint(ctx, u)
+			c.endpoints.checked(u, time.Since(start), err, time.Now())
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", redactURLs(u), err)
+			}
+		}()
+	}
+	wg.Wait()
+	c.endpoints.prefer()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkEndpoint calls getHealth on one endpoint, bypassing capture and failure
+// counting.
+func (c *Client) checkEndpoint(ctx context.Context, url string) error {
+	body, err := json.Marshal(RPCRequest{JSONRPC: "2.0", ID: int(c.requestID.Add(1)), Method: "getHealth"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	_, respBody, err := c.post(ctx, url, body)
+	if err != nil {
+		return err
+	}
+	var rpcResp RPCResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%w: method getHealth: %s", ErrRPCError, rpcResp.Error.Error())
+	}
+	var result GetHealthResult
+	if err := json.Unmarshal(rpcResp.Result, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	if result.Status != "healthy" {
+		return fmt.Errorf("RPC reports status %q", result.Status)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package soroban
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRPC answers every call with a healthy getHealth result until broken is set,
+// then with 503.
+type fakeRPC struct {
+	*httptest.Server
+	broken atomic.Bool
+	calls  atomic.Int32
+}
+
+func newFakeRPC(t *testing.T) *fakeRPC {
+	t.Helper()
+	f := &fakeRPC{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls.Add(1)
+		if f.broken.Load() {
+			http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"status":"healthy","latestLedger":100}}`))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestClientFailsOverAfterRepeatedErrors(t *testing.T) {
+	primary, backup := newFakeRPC(t), newFakeRPC(t)
+	c := NewClientWithEndpoints([]string{primary.URL, backup.URL}, nil)
+	ctx := context.Background()
+
+	primary.broken.Store(true)
+	for i := range EndpointFailureThreshold {
+		if c.RPCURL() != primary.URL {
+			t.Fatalf("switched after %d failures, want %d", i, EndpointFailureThreshold)
+		}
+		if _, err := c.GetHealth(ctx); err == nil {
+			t.Fatal("GetHealth() against a broken endpoint should fail")
+		}
+	}
+	if c.RPCURL() != backup.URL {
+		t.Fatalf("RPCURL() = %s after %d failures, want the backup", c.RPCURL(), EndpointFailureThreshold)
+	}
+	if _, err := c.GetHealth(ctx); err != nil {
+		t.Fatalf("GetHealth() after failover error = %v", err)
+	}
+	if got := c.Failovers(); got != 1 {
+		t.Errorf("Failovers() = %d, want 1", got)
+	}
+
+	endpoints := c.Endpoints()
+	if len(endpoints) != 2 || endpoints[0].Healthy || endpoints[0].Active || !endpoints[1].Active {
+		t.Errorf("Endpoints() = %+v, want the primary unhealthy and the backup active", endpoints)
+	}
+	if endpoints[0].LastError == "" {
+		t.Error("the primary should record its last error")
+	}
+}
+
+func TestClientRPCErrorsDoNotFailOver(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params"}}`))
+	}))
+	defer srv.Close()
+	backup := newFakeRPC(t)
+	c := NewClientWithEndpoints([]string{srv.URL, backup.URL}, nil)
+
+	for range EndpointFailureThreshold + 1 {
+		if _, err := c.GetHealth(context.Background()); !errors.Is(err, ErrRPCError) {
+			t.Fatalf("GetHealth() error = %v, want %v", err, ErrRPCError)
+		}
+	}
+	if c.RPCURL() != srv.URL {
+		t.Error("JSON-RPC errors come from a working endpoint and must not cause a failover")
+	}
+}
+
+func TestCheckEndpoints(t *testing.T) {
+	primary, backup := newFakeRPC(t), newFakeRPC(t)
+	c := NewClientWithEndpoints([]string{primary.URL, backup.URL}, nil)
+	ctx := context.Background()
+
+	primary.broken.Store(true)
+	if err := c.CheckEndpoints(ctx); err != nil {
+		t.Fatalf("CheckEndpoints() error = %v, want nil while the backup is healthy", err)
+	}
+	if c.RPCURL() != backup.URL {
+		t.Errorf("RPCURL() = %s, want the backup after the primary failed its check", c.RPCURL())
+	}
+
+	primary.broken.Store(false)
+	if err := c.CheckEndpoints(ctx); err != nil {
+		t.Fatalf("CheckEndpoints() error = %v", err)
+	}
+	for _, e := range c.Endpoints() {
+		if !e.Healthy || e.LastChecked.IsZero() {
+			t.Errorf("endpoint %s = %+v, want healthy and checked", e.URL, e)
+		}
+	}
+
+	primary.broken.Store(true)
+	backup.broken.Store(true)
+	if err := c.CheckEndpoints(ctx); err == nil {
+		t.Error("CheckEndpoints() should fail when no endpoint is healthy")
+	}
+}
+
+func TestEndpointSetPrefersLowerLatency(t *testing.T) {
+	s := newEndpointSet([]string{"https://a.example", "https://b.example", "https://c.example"})
+	now := time.Now()
+
+	s.checked("https://a.example", 100*time.Millisecond, nil, now)
+	s.checked("https://b.example", 90*time.Millisecond, nil, now)
+	s.checked("https://c.example", 20*time.Millisecond, errors.New("down"), now)
+	s.prefer()
+	if got := s.current(); got != "https://a.example" {
+		t.Errorf("current() = %s, want a: b is not enough faster and c is unhealthy", got)
+	}
+
+	s.checked("https://c.example", 20*time.Millisecond, nil, now)
+	s.prefer()
+	if got := s.current(); got != "https://c.example" {
+		t.Errorf("current() = %s, want the much faster c", got)
+	}
+}
+
+func TestEndpointStatusRedactsCredentials(t *testing.T) {
+	c := NewClientWithEndpoints([]string{"https://rpc.example/key/secret?apikey=secret"}, nil)
+	if got := c.Endpoints()[0].URL; got != "https://rpc.example/[redacted]?[redacted]" {
+		t.Errorf("Endpoints()[0].URL = %q, want credentials redacted", got)
+	}
+}
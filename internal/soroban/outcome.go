@@ -0,0 +1,285 @@
+package soroban
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// TransactionOutcome is a getTransaction result with its XDR decoded.
+type TransactionOutcome struct {
+	Status     string // TxResultSuccess, TxResultFailed or TxResultNotFound
+	Successful bool
+	Source     string // G... source account whose sequence number the transaction used
+	Ledger     uint32
+	CreatedAt  time.Time // ledger close time; zero while not found
+	FeeCharged int64     // stroops
+	// ResultCode is the transaction result code, e.g. "TxSuccess" or "TxFailed";
+	// OperationCode is the code of the first failed operation, e.g. "InvokeHostFunctionTrapped".
+	ResultCode    string
+	OperationCode string
+	ReturnValue   *xdr.ScVal // contract call return value; nil for failed or non-contract transactions
+	// Events are the contract events the transaction emitted, in the form getEvents
+	// returns them so the same parsers apply.
+	Events []ContractEvent
+	// Diagnostics are the diagnostic events in the meta, e.g. the contract error of a
+	// failed call. Only RPC nodes with diagnostic events enabled record them.
+	Diagnostics []DiagnosticEvent
+}
+
+// BalanceChange is a token balance change of one address, from a Stellar Asset
+// Contract transfer, mint or burn event.
+type BalanceChange struct {
+	Token   string // token contract C... address
+	Asset   string // asset name from the event, e.g. "EURMTL:G..."; empty when absent
+	Address string // G... or C... address whose balance changed
+	Amount  int64  // signed, in stroops: positive is received
+}
+
+// Outcome decodes the result, meta and return value XDR of a getTransaction result.
+// hash is only used to fill in ContractEvent.TxHash.
+func (r *GetTransactionResult) Outcome(hash string) (*TransactionOutcome, error) {
+	outcome := &TransactionOutcome{Status: r.Status, Ledger: r.Ledger}
+	if r.Status == TxResultNotFound {
+		return outcome, nil
+	}
+
+	if r.CreatedAt != "" {
+		secs, err := strconv.ParseInt(r.CreatedAt, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse createdAt %q: %w", r.CreatedAt, err)
+		}
+		outcome.CreatedAt = time.Unix(secs, 0).UTC()
+	}
+
+	if r.EnvelopeXdr != "" {
+		var env xdr.TransactionEnvelope
+		if err := xdr.SafeUnmarshalBase64(r.EnvelopeXdr, &env); err != nil {
+			return nil, fmt.Errorf("failed to decode envelope XDR: %w", err)
+		}
+		// For fee bumps this is the inner transaction's source.
+		source := env.SourceAccount().ToAccountId()
+		outcome.Source = source.Address()
+	}
+
+	if r.ResultXdr != "" {
+		var result xdr.TransactionResult
+		if err := xdr.SafeUnmarshalBase64(r.ResultXdr, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode result XDR: %w", err)
+		}
+		outcome.Successful = result.Successful()
+		outcome.FeeCharged = int64(result.FeeCharged)
+		outcome.ResultCode = strings.TrimPrefix(result.Result.Code.String(), "TransactionResultCode")
+		if inner, ok := result.Result.GetInnerResultPair(); ok && !outcome.Successful {
+			outcome.ResultCode = strings.TrimPrefix(inner.Result.Result.Code.String(), "TransactionResultCode")
+		}
+		if ops, ok := result.OperationResults(); ok {
+			outcome.OperationCode = failedOperationCode(ops)
+		}
+	} else {
+		outcome.Successful = r.Status == TxResultSuccess
+	}
+
+	if r.ReturnValue != "" {
+		val, err := ParseReturnValue(r.ReturnValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode return value: %w", err)
+		}
+		outcome.ReturnValue = &val
+	}
+
+	if r.ResultMetaXdr != "" {
+		var meta xdr.TransactionMeta
+		if err := xdr.SafeUnmarshalBase64(r.ResultMetaXdr, &meta); err != nil {
+			return nil, fmt.Errorf("failed to decode result meta XDR: %w", err)
+		}
+		if outcome.ReturnValue == nil {
+			outcome.ReturnValue = metaReturnValue(meta)
+		}
+		for _, de := range metaDiagnosticEvents(meta) {
+			outcome.Diagnostics = append(outcome.Diagnostics, diagnosticEvent(de))
+		}
+		events, err := meta.GetContractEventsForOperation(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read contract events: %w", err)
+		}
+		for _, e := range events {
+			evt, err := contractEvent(e, outcome, hash)
+			if err != nil {
+				return nil, err
+			}
+			outcome.Events = append(outcome.Events, evt)
+		}
+	}
+
+	return outcome, nil
+}
+
+// BalanceChanges returns the token balance changes reported by the transaction's
+// Stellar Asset Contract events, in event order.
+func (o *TransactionOutcome) BalanceChanges() []BalanceChange {
+	var changes []BalanceChange
+	for _, evt := range o.Events {
+		if len(evt.Topic) < 2 {
+			continue
+		}
+		topics := make([]xdr.ScVal, 0, len(evt.Topic))
+		for _, raw := range evt.Topic {
+			val, err := ParseReturnValue(raw)
+			if err != nil {
+				break
+			}
+			topics = append(topics, val)
+		}
+		if len(topics) != len(evt.Topic) || topics[0].Type != xdr.ScValTypeScvSymbol {
+			continue
+		}
+		amount, ok := eventAmount(evt.Value)
+		if !ok {
+			continue
+		}
+
+		change := func(topic xdr.ScVal, sign int64, asset []xdr.ScVal) {
+			address, err := DecodeAddress(topic)
+			if err != nil {
+				return
+			}
+			c := BalanceChange{Token: evt.ContractID, Address: address, Amount: sign * amount}
+			if len(asset) > 0 {
+				c.Asset, _ = DecodeString(asset[0])
+			}
+			changes = append(changes, c)
+		}
+		switch string(*topics[0].Sym) {
+		case "transfer":
+			if len(topics) >= 3 {
+				change(topics[1], -1, topics[3:])
+				change(topics[2], 1, topics[3:])
+			}
+		case "mint":
+			// Protocol 23 dropped the admin topic: ["mint", to, asset], before ["mint", admin, to, asset].
+			to, asset := topics[1], topics[2:]
+			if len(topics) >= 4 {
+				to, asset = topics[2], topics[3:]
+			}
+			change(to, 1, asset)
+		case "burn", "clawback":
+			from, asset := topics[1], topics[2:]
+			if string(*topics[0].Sym) == "clawback" && len(topics) >= 4 {
+				from, asset = topics[2], topics[3:]
+			}
+			change(from, -1, asset)
+		}
+	}
+	return changes
+}
+
+// failedOperationCode returns the result code of the first failed operation, or ""
+// when all succeeded.
+func failedOperationCode(ops []xdr.OperationResult) string {
+	for _, op := range ops {
+		if op.Code != xdr.OperationResultCodeOpInner {
+			return strings.TrimPrefix(op.Code.String(), "OperationResultCode")
+		}
+		if op.Tr == nil {
+			continue
+		}
+		if res, ok := op.Tr.GetInvokeHostFunctionResult(); ok && res.Code != xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess {
+			return strings.TrimPrefix(res.Code.String(), "InvokeHostFunctionResultCode")
+		}
+	}
+	return ""
+}
+
+// metaReturnValue returns the return value recorded in the Soroban meta, for RPC
+// versions that do not report returnValue separately.
+func metaReturnValue(meta xdr.TransactionMeta) *xdr.ScVal {
+	switch meta.V {
+	case 3:
+		if sm := meta.MustV3().SorobanMeta; sm != nil {
+			val := sm.ReturnValue
+			return &val
+		}
+	case 4:
+		if sm := meta.MustV4().SorobanMeta; sm != nil && sm.ReturnValue != nil {
+			val := *sm.ReturnValue
+			return &val
+		}
+	}
+	return nil
+}
+
+// metaDiagnosticEvents returns the diagnostic events recorded in the meta.
+func metaDiagnosticEvents(meta xdr.TransactionMeta) []xdr.DiagnosticEvent {
+	switch meta.V {
+	case 3:
+		if sm := meta.MustV3().SorobanMeta; sm != nil {
+			return sm.DiagnosticEvents
+		}
+	case 4:
+		return meta.MustV4().DiagnosticEvents
+	}
+	return nil
+}
+
+func contractEvent(e xdr.ContractEvent, outcome *TransactionOutcome, hash string) (ContractEvent, error) {
+	evt := ContractEvent{
+		Type:                     EventTypeContract,
+		Ledger:                   outcome.Ledger,
+		InSuccessfulContractCall: outcome.Successful,
+		TxHash:                   hash,
+	}
+	if e.Type == xdr.ContractEventTypeSystem {
+		evt.Type = EventTypeSystem
+	}
+	if !outcome.CreatedAt.IsZero() {
+		evt.LedgerClosedAt = outcome.CreatedAt.Format(time.RFC3339)
+	}
+	if e.ContractId != nil {
+		id := *e.ContractId
+		evt.ContractID, _ = strkey.Encode(strkey.VersionByteContract, id[:])
+	}
+	body, ok := e.Body.GetV0()
+	if !ok {
+		return evt, nil
+	}
+	for _, topic := range body.Topics {
+		encoded, err := xdr.MarshalBase64(topic)
+		if err != nil {
+			return ContractEvent{}, fmt.Errorf("failed to encode event topic: %w", err)
+		}
+		evt.Topic = append(evt.Topic, encoded)
+	}
+	value, err := xdr.MarshalBase64(body.Data)
+	if err != nil {
+		return ContractEvent{}, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	evt.Value = value
+	return evt, nil
+}
+
+// eventAmount decodes the amount of a token event: an i128, or since protocol 23 a
+// map with an "amount" entry when the event carries a muxed ID.
+func eventAmount(raw string) (int64, bool) {
+	val, err := ParseReturnValue(raw)
+	if err != nil {
+		return 0, false
+	}
+	if val.Type == xdr.ScValTypeScvMap && val.Map != nil && *val.Map != nil {
+		for _, entry := range **val.Map {
+			if entry.Key.Type == xdr.ScValTypeScvSymbol && string(*entry.Key.Sym) == "amount" {
+				val = entry.Val
+				break
+			}
+		}
+	}
+	amount, err := DecodeI128(val)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
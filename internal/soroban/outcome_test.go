@@ -0,0 +1,135 @@
+package soroban
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func contractEventXDR(contract xdr.ContractId, topics []xdr.ScVal, data xdr.ScVal) xdr.ContractEvent {
+	return xdr.ContractEvent{
+		ContractId: &contract,
+		Type:       xdr.ContractEventTypeContract,
+		Body: xdr.ContractEventBody{
+			V:  0,
+			V0: &xdr.ContractEventV0{Topics: topics, Data: data},
+		},
+	}
+}
+
+func accountAddress(b byte) xdr.ScVal {
+	var key xdr.Uint256
+	key[0] = b
+	account := xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &key}
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &account}}
+}
+
+func contractAddress(id xdr.ContractId) xdr.ScVal {
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id}}
+}
+
+func TestGetTransactionResultOutcome(t *testing.T) {
+	var market, token xdr.ContractId
+	market[0], token[0] = 1, 2
+	user := accountAddress(7)
+	asset := EncodeString("EURMTL:GABC")
+
+	result := xdr.TransactionResult{
+		FeeCharged: 12345,
+		Result: xdr.TransactionResultResult{
+			Code: xdr.TransactionResultCodeTxSuccess,
+			Results: &[]xdr.OperationResult{{
+				Code: xdr.OperationResultCodeOpInner,
+				Tr: &xdr.OperationResultTr{
+					Type:                     xdr.OperationTypeInvokeHostFunction,
+					InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess, Success: &xdr.Hash{}},
+				},
+			}},
+		},
+	}
+	resultXDR, err := xdr.MarshalBase64(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{SorobanMeta: &xdr.SorobanTransactionMeta{
+		Events: []xdr.ContractEvent{
+			contractEventXDR(token, []xdr.ScVal{EncodeSymbol("transfer"), user, contractAddress(market), asset}, EncodeI128(52_000_000)),
+			contractEventXDR(market, []xdr.ScVal{EncodeSymbol("buy"), user, EncodeU32(OutcomeYes)}, vec(EncodeI128(100_000_000), EncodeI128(52_000_000))),
+		},
+		ReturnValue: EncodeI128(52_000_000),
+	}}}
+	metaXDR, err := xdr.MarshalBase64(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &GetTransactionResult{Status: TxResultSuccess, Ledger: 42, CreatedAt: "1700000000", ResultXdr: resultXDR, ResultMetaXdr: metaXDR}
+	outcome, err := r.Outcome("abc")
+	if err != nil {
+		t.Fatalf("Outcome() error = %v", err)
+	}
+	if !outcome.Successful || outcome.ResultCode != "TxSuccess" || outcome.OperationCode != "" {
+		t.Errorf("outcome = %+v, want successful TxSuccess", outcome)
+	}
+	if outcome.FeeCharged != 12345 || !outcome.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("fee = %d, created = %v", outcome.FeeCharged, outcome.CreatedAt)
+	}
+	if outcome.ReturnValue == nil {
+		t.Fatal("return value not taken from meta")
+	}
+	if got, _ := DecodeI128(*outcome.ReturnValue); got != 52_000_000 {
+		t.Errorf("return value = %d, want 52000000", got)
+	}
+	if len(outcome.Events) != 2 || outcome.Events[1].TxHash != "abc" || outcome.Events[1].LedgerClosedAt == "" {
+		t.Fatalf("events = %+v", outcome.Events)
+	}
+
+	changes := outcome.BalanceChanges()
+	if len(changes) != 2 {
+		t.Fatalf("balance changes = %+v, want sender and receiver", changes)
+	}
+	if changes[0].Amount != -52_000_000 || changes[1].Amount != 52_000_000 || changes[0].Asset != "EURMTL:GABC" {
+		t.Errorf("balance changes = %+v", changes)
+	}
+	if changes[0].Address[0] != 'G' || changes[1].Address[0] != 'C' {
+		t.Errorf("addresses = %q, %q", changes[0].Address, changes[1].Address)
+	}
+}
+
+func TestGetTransactionResultOutcomeFailed(t *testing.T) {
+	result := xdr.TransactionResult{
+		FeeCharged: 100,
+		Result: xdr.TransactionResultResult{
+			Code: xdr.TransactionResultCodeTxFailed,
+			Results: &[]xdr.OperationResult{{
+				Code: xdr.OperationResultCodeOpInner,
+				Tr: &xdr.OperationResultTr{
+					Type:                     xdr.OperationTypeInvokeHostFunction,
+					InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionTrapped},
+				},
+			}},
+		},
+	}
+	resultXDR, err := xdr.MarshalBase64(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outcome, err := (&GetTransactionResult{Status: TxResultFailed, ResultXdr: resultXDR}).Outcome("abc")
+	if err != nil {
+		t.Fatalf("Outcome() error = %v", err)
+	}
+	if outcome.Successful || outcome.ResultCode != "TxFailed" || outcome.OperationCode != "InvokeHostFunctionTrapped" {
+		t.Errorf("outcome = %+v, want failed with trapped operation", outcome)
+	}
+
+	if _, err := (&GetTransactionResult{Status: TxResultSuccess, ResultXdr: "not xdr"}).Outcome("abc"); err == nil {
+		t.Error("invalid result XDR should fail to decode")
+	}
+	notFound, err := (&GetTransactionResult{Status: TxResultNotFound}).Outcome("abc")
+	if err != nil || notFound.Successful {
+		t.Errorf("not found outcome = %+v, %v", notFound, err)
+	}
+}
@@ -0,0 +1,54 @@
+package soroban
+
+import (
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// SimulationCacheTTL is how long the result of a read-only simulation is reused for
+	// identical calls. It only absorbs bursts; the market state cache handles longer reuse.
+	SimulationCacheTTL = 5 * time.Second
+	// simulationCacheSize bounds the number of cached simulation results.
+	simulationCacheSize = 2000
+)
+
+// cachedFunctions are the read-only contract functions whose simulation results
+// depend only on the contract, the function and its arguments. get_balance is left
+// out, so balances are fresh right after a trade.
+var cachedFunctions = map[xdr.ScSymbol]bool{
+	"get_state":           true,
+	"get_metadata_hash":   true,
+	"get_winning_outcome": true,
+	"get_quote":           true,
+	"get_sell_quote":      true,
+	"get_liquidity_param": true,
+}
+
+// simulationCacheKey returns the cache key of a transaction that makes a single call to
+// a cached function: the contract, function and arguments. The source account, sequence
+// number and time bounds are not part of the key.
+func simulationCacheKey(txXDR string) (string, bool) {
+	var env xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+		return "", false
+	}
+	ops := env.Operations()
+	if len(ops) != 1 {
+		return "", false
+	}
+	invoke, ok := ops[0].Body.GetInvokeHostFunctionOp()
+	if !ok {
+		return "", false
+	}
+	call, ok := invoke.HostFunction.GetInvokeContract()
+	if !ok || !cachedFunctions[call.FunctionName] {
+		return "", false
+	}
+	key, err := call.MarshalBinary()
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
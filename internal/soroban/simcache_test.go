@@ -0,0 +1,93 @@
+package soroban
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func buildTestInvoke(t *testing.T, sequence int64, function string, args ...xdr.ScVal) string {
+	t.Helper()
+	contractID, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := NewContractInvoker(nil, network.TestNetworkPassphrase, 100, 0)
+	source := &txnbuild.SimpleAccount{AccountID: keypair.MustRandom().Address(), Sequence: sequence}
+	txXDR, err := ci.BuildInvokeTx(context.Background(), InvokeParams{
+		SourceAccount: source,
+		ContractID:    contractID,
+		FunctionName:  function,
+		Args:          args,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txXDR
+}
+
+func TestSimulationCacheKey(t *testing.T) {
+	quote := func(seq int64, amount int64) string {
+		return buildTestInvoke(t, seq, "get_quote", EncodeU32(0), EncodeI128(amount))
+	}
+
+	a, ok := simulationCacheKey(quote(1, 10))
+	if !ok {
+		t.Fatal("get_quote should be cacheable")
+	}
+	if b, _ := simulationCacheKey(quote(7, 10)); b != a {
+		t.Error("source account and sequence must not be part of the key")
+	}
+	if c, _ := simulationCacheKey(quote(1, 20)); c == a {
+		t.Error("different arguments must have different keys")
+	}
+	if _, ok := simulationCacheKey(buildTestInvoke(t, 1, "buy", EncodeU32(0))); ok {
+		t.Error("state-changing calls must not be cacheable")
+	}
+	if _, ok := simulationCacheKey(buildTestInvoke(t, 1, "get_balance")); ok {
+		t.Error("get_balance must not be cacheable")
+	}
+	if _, ok := simulationCacheKey("not xdr"); ok {
+		t.Error("invalid XDR must not be cacheable")
+	}
+}
+
+func TestSimulateTransactionCachesReadOnlyCalls(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"latestLedger":100,"results":[{"xdr":"AAAAAQ=="}]}}`))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, srv.Client())
+
+	for seq := range int64(3) {
+		if _, err := c.SimulateTransaction(context.Background(), buildTestInvoke(t, seq, "get_state")); err != nil {
+			t.Fatalf("SimulateTransaction: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("RPC calls for identical get_state = %d, want 1", got)
+	}
+
+	buy := buildTestInvoke(t, 1, "buy", EncodeU32(0))
+	for range 2 {
+		if _, err := c.SimulateTransaction(context.Background(), buy); err != nil {
+			t.Fatalf("SimulateTransaction: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("RPC calls after two buy simulations = %d, want 3", got)
+	}
+	if stats := c.SimulationCacheStats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("cache stats = %+v, want 2 hits, 1 miss", stats)
+	}
+}
@@ -1,6 +1,7 @@
 package soroban
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -38,6 +39,30 @@ func (e *RPCError) Error() string {
 	return e.Message
 }
 
+// UnmarshalJSON accepts error data as a string or, as some RPC releases send it,
+// any other JSON value, which is kept as its JSON text.
+func (e *RPCError) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	e.Code, e.Message, e.Data = raw.Code, raw.Message, ""
+	if len(raw.Data) > 0 && string(raw.Data) != "null" {
+		if err := json.Unmarshal(raw.Data, &e.Data); err != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw.Data); err != nil {
+				return err
+			}
+			e.Data = compact.String()
+		}
+	}
+	return nil
+}
+
 // SimulateTransactionParams for simulateTransaction RPC call.
 type SimulateTransactionParams struct {
 	Transaction    string          `json:"transaction"`
@@ -139,6 +164,9 @@ const (
 	TxResultFailed   = "FAILED"
 )
 
+// MaxLedgerEntryKeys is how many keys one getLedgerEntries call accepts.
+const MaxLedgerEntryKeys = 200
+
 // GetLedgerEntriesParams for getLedgerEntries RPC call.
 type GetLedgerEntriesParams struct {
 	Keys []string `json:"keys"`
@@ -173,27 +201,68 @@ type GetNetworkResult struct {
 	ProtocolVersion int    `json:"protocolVersion"`
 }
 
+// GetVersionInfoResult from getVersionInfo RPC call.
+type GetVersionInfoResult struct {
+	Version            string `json:"version"`
+	CommitHash         string `json:"commitHash"`
+	BuildTimestamp     string `json:"buildTimestamp"`
+	CaptiveCoreVersion string `json:"captiveCoreVersion"`
+	ProtocolVersion    int    `json:"protocolVersion"`
+}
+
+// Protocol versions supported by the XDR encoding in this package
+// (go-stellar-sdk v0.1.0). Bump after verifying against a new protocol.
+const (
+	MinProtocolVersion = 22
+	MaxProtocolVersion = 23
+)
+
 // GetLatestLedgerResult from getLatestLedger RPC call.
 type GetLatestLedgerResult struct {
 	ID              string `json:"id"`
 	ProtocolVersion int    `json:"protocolVersion"`
 	Sequence        uint32 `json:"sequence"`
+	CloseTime       string `json:"closeTime,omitempty"` // Unix seconds; only reported by newer RPC versions
 }
 
 // GetEventsParams for getEvents RPC call.
+// StartLedger and Pagination.Cursor are mutually exclusive: the RPC rejects requests with both.
 type GetEventsParams struct {
-	StartLedger uint32           `json:"startLedger"`
+	StartLedger uint32           `json:"startLedger,omitempty"`
 	Filters     []EventFilter    `json:"filters,omitempty"`
 	Pagination  *EventPagination `json:"pagination,omitempty"`
 }
 
+// Event filter types accepted by getEvents.
+const (
+	EventTypeContract = "contract"
+	EventTypeSystem   = "system"
+)
+
+// TopicWildcard matches any single topic segment in an event filter.
+const TopicWildcard = "*"
+
+// EventsPageLimit is the page size requested from getEvents.
+const EventsPageLimit = 200
+
 // EventFilter filters events by type, contract, and topics.
+// Each entry of Topics is one topic pattern: a list of base64 ScVal XDR segments or TopicWildcard.
 type EventFilter struct {
 	Type        string     `json:"type"`
 	ContractIDs []string   `json:"contractIds,omitempty"`
 	Topics      [][]string `json:"topics,omitempty"`
 }
 
+// NewContractEventFilter creates a filter for contract events emitted by the given contracts.
+// Without topic patterns, all events of those contracts match.
+func NewContractEventFilter(contractIDs []string, topics ...[]string) EventFilter {
+	return EventFilter{
+		Type:        EventTypeContract,
+		ContractIDs: contractIDs,
+		Topics:      topics,
+	}
+}
+
 // EventPagination controls pagination for getEvents.
 type EventPagination struct {
 	Limit  int    `json:"limit,omitempty"`
@@ -201,9 +270,13 @@ type EventPagination struct {
 }
 
 // GetEventsResult from getEvents RPC call.
+// Cursor points after the last scanned event; pass it to the next call to continue paging.
 type GetEventsResult struct {
-	Events       []ContractEvent `json:"events"`
-	LatestLedger uint32          `json:"latestLedger"`
+	Events                []ContractEvent `json:"events"`
+	Cursor                string          `json:"cursor"`
+	LatestLedger          uint32          `json:"latestLedger"`
+	OldestLedger          uint32          `json:"oldestLedger"`
+	LatestLedgerCloseTime string          `json:"latestLedgerCloseTime"`
 }
 
 // ContractEvent represents a single contract event from the ledger.
@@ -215,6 +288,7 @@ type ContractEvent struct {
 	ID                       string   `json:"id"`
 	PagingToken              string   `json:"pagingToken"`
 	InSuccessfulContractCall bool     `json:"inSuccessfulContractCall"`
+	TxHash                   string   `json:"txHash,omitempty"`
 	Topic                    []string `json:"topic"`
 	Value                    string   `json:"value"`
 }
@@ -0,0 +1,139 @@
+// Forms marked data-idempotent get a per-page-load key, so a double submit
+// returns the same transaction instead of building a second one.
+(function() {
+    var forms = document.querySelectorAll('form[data-idempotent]');
+    for (var i = 0; i < forms.length; i++) {
+        var input = document.createElement('input');
+        input.type = 'hidden';
+        input.name = 'idempotency_key';
+        input.value = window.crypto && crypto.randomUUID ? crypto.randomUUID() : Date.now() + '-' + Math.random().toString(36).slice(2);
+        forms[i].appendChild(input);
+    }
+})();
+
+(function() {
+    var el = document.getElementById('network-status');
+    if (!el || !window.fetch) return;
+    var text = el.querySelector('.network-status-text');
+
+    function render(s) {
+        var parts = [];
+        if (s.latest_ledger) parts.push('ledger ' + s.latest_ledger);
+        if (s.ledger_closed_at) {
+            var age = Math.max(0, Math.round((Date.now() - Date.parse(s.ledger_closed_at)) / 1000));
+            parts.push(age + 's ago');
+        }
+        if (s.suggested_fee) parts.push('fee ' + s.suggested_fee + ' stroops');
+        if (s.rpc_error) parts.push(s.rpc_error);
+        if (s.horizon_error) parts.push(s.horizon_error);
+        if (s.stale) parts.push('status stale');
+        text.textContent = parts.length ? parts.join(' · ') : 'network status unavailable';
+        el.className = 'network-status ' + (s.healthy ? 'ok' : 'degraded');
+    }
+
+    function refresh() {
+        fetch('/status', {headers: {'Accept': 'application/json'}})
+            .then(function(r) { return r.ok ? r.json() : Promise.reject(r.status); })
+            .then(render)
+            .catch(function() {
+                text.textContent = 'status unavailable';
+                el.className = 'network-status degraded';
+            });
+    }
+
+    refresh();
+    setInterval(refresh, 30000);
+})();
+
+// Elements with data-poll are replaced by the HTML fragment at that URL every
+// data-poll-interval seconds (default 60) while the page is visible.
+(function() {
+    var els = document.querySelectorAll('[data-poll]');
+    if (!els.length || !window.fetch) return;
+
+    function poll(el) {
+        if (document.hidden) return;
+        fetch(el.getAttribute('data-poll'), {headers: {'Accept': 'text/html'}})
+            .then(function(r) { return r.ok ? r.text() : Promise.reject(r.status); })
+            .then(function(html) { el.innerHTML = html; })
+            .catch(function() {});
+    }
+
+    for (var i = 0; i < els.length; i++) {
+        var seconds = parseInt(els[i].getAttribute('data-poll-interval'), 10) || 60;
+        setInterval(poll.bind(null, els[i]), seconds * 1000);
+    }
+})();
+
+// A pending transaction signed elsewhere (data-watch-pending = its status URL) is
+// looked up every 10 seconds; once it is on-chain the page moves on to /tx/{hash}.
+(function() {
+    var el = document.querySelector('[data-watch-pending]');
+    if (!el || !window.fetch) return;
+    var hash = el.getAttribute('data-tx-hash');
+    var text = el.querySelector('.pending-watch-text');
+    var timer;
+
+    function done() {
+        clearInterval(timer);
+        window.location = '/tx/' + hash;
+    }
+
+    function poll() {
+        if (document.hidden) return;
+        fetch(el.getAttribute('data-watch-pending'), {headers: {'Accept': 'application/json'}})
+            .then(function(r) {
+                if (r.status === 404) return {state: 'included'}; // closed since, e.g. by the background check
+                return r.ok ? r.json() : Promise.reject(r.status);
+            })
+            .then(function(s) {
+                if (s.state === 'included') return done();
+                if (s.state === 'failed') {
+                    clearInterval(timer);
+                    text.textContent = s.error || 'Transaction failed';
+                    el.className = 'pending-watch text-no';
+                }
+            })
+            .catch(function() {});
+    }
+
+    timer = setInterval(poll, 10000);
+})();
+
+// Time zone picker: times are rendered server-side in the zone from the tz cookie.
+// On the first visit the cookie is set from the browser, so later pages are local.
+(function() {
+    var form = document.getElementById('tz-form');
+    if (!form) return;
+    var match = document.cookie.match(/(?:^|;\s*)tz=([^;]*)/);
+    var current = match ? decodeURIComponent(match[1]) : '';
+    var detected = '';
+    try { detected = Intl.DateTimeFormat().resolvedOptions().timeZone || ''; } catch (e) {}
+
+    if (!current && detected) {
+        document.cookie = 'tz=' + encodeURIComponent(detected) + '; path=/; max-age=315360000; SameSite=Lax';
+        current = detected;
+    }
+
+    form.elements.tz.value = current || 'UTC';
+    form.elements['return'].value = location.pathname + location.search;
+
+    if (Intl.supportedValuesOf) {
+        var list = document.getElementById('tz-list');
+        Intl.supportedValuesOf('timeZone').forEach(function(zone) {
+            var option = document.createElement('option');
+            option.value = zone;
+            list.appendChild(option);
+        });
+    }
+})();
+
+// Alert form: show an example target for the selected delivery channel.
+(function() {
+    var channel = document.getElementById('alert-channel');
+    var target = document.getElementById('alert-target');
+    if (!channel || !target) return;
+    channel.addEventListener('change', function() {
+        target.placeholder = channel.options[channel.selectedIndex].dataset.placeholder || '';
+    });
+})();
@@ -0,0 +1,124 @@
+// Package static embeds the CSS, JavaScript and images shipped with the templates and
+// serves them under content-hash fingerprinted URLs, so browsers can cache them forever.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+//go:embed assets
+var files embed.FS
+
+const (
+	// Prefix is the URL path all assets are served under.
+	Prefix = "/static/"
+
+	fingerprintLength = 12 // Hex characters of the SHA-256 content hash
+
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// Unfingerprinted URLs may be linked from outside; keep them fresh-ish.
+	plainCacheControl = "public, max-age=300"
+)
+
+type asset struct {
+	name        string // Path relative to assets/, e.g. "app.css"
+	url         string // Fingerprinted URL, e.g. "/static/app.1a2b3c4d5e6f.css"
+	etag        string
+	contentType string
+	data        []byte
+}
+
+// Assets is the fingerprinted set of embedded static files.
+type Assets struct {
+	byName map[string]*asset
+	byPath map[string]*asset // Fingerprinted path below Prefix -> asset
+}
+
+// New hashes all embedded assets.
+func New() (*Assets, error) {
+	a := &Assets{
+		byName: make(map[string]*asset),
+		byPath: make(map[string]*asset),
+	}
+	err := fs.WalkDir(files, "assets", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := files.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(p, "assets/")
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])[:fingerprintLength]
+		ext := path.Ext(name)
+		fingerprinted := strings.TrimSuffix(name, ext) + "." + hash + ext
+
+		contentType := mime.TypeByExtension(ext)
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		entry := &asset{
+			name:        name,
+			url:         Prefix + fingerprinted,
+			etag:        `"` + hash + `"`,
+			contentType: contentType,
+			data:        data,
+		}
+		a.byName[name] = entry
+		a.byPath[fingerprinted] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load static assets: %w", err)
+	}
+	return a, nil
+}
+
+// URL returns the fingerprinted URL of an asset, e.g. URL("app.css").
+// Unknown names are an error, so a typo in a template fails rendering instead of
+// producing a broken link.
+func (a *Assets) URL(name string) (string, error) {
+	entry, ok := a.byName[name]
+	if !ok {
+		return "", fmt.Errorf("unknown static asset %q", name)
+	}
+	return entry.url, nil
+}
+
+// RegisterRoutes registers the static file route.
+func (a *Assets) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+Prefix+"{file...}", a.handleAsset)
+}
+
+// handleAsset serves fingerprinted URLs with far-future cache headers. The plain
+// name is served too, with a short cache lifetime, for links that cannot be fingerprinted.
+func (a *Assets) handleAsset(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	cacheControl := immutableCacheControl
+	entry, ok := a.byPath[file]
+	if !ok {
+		entry, ok = a.byName[file]
+		cacheControl = plainCacheControl
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", entry.contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", entry.etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, entry.name, time.Time{}, bytes.NewReader(entry.data))
+}
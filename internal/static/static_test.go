@@ -0,0 +1,63 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAssets(t *testing.T) {
+	assets, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	assets.RegisterRoutes(mux)
+
+	url, err := assets.URL("app.css")
+	if err != nil {
+		t.Fatalf("URL(app.css) error = %v", err)
+	}
+	if !regexp.MustCompile(`^/static/app\.[0-9a-f]{12}\.css$`).MatchString(url) {
+		t.Errorf("URL(app.css) = %q, want fingerprinted path", url)
+	}
+	if _, err := assets.URL("missing.css"); err == nil {
+		t.Error("URL(missing.css) error = nil, want error")
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCache  string
+		wantType   string
+	}{
+		{url, http.StatusOK, immutableCacheControl, "text/css"},
+		{"/static/app.css", http.StatusOK, plainCacheControl, "text/css"},
+		{"/static/app.000000000000.css", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %s status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			continue
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+			t.Errorf("GET %s Cache-Control = %q, want %q", tt.path, got, tt.wantCache)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+			t.Errorf("GET %s Content-Type = %q, want %s", tt.path, got, tt.wantType)
+		}
+	}
+
+	// A matching ETag is answered without a body.
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", assets.byName["app.css"].etag)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
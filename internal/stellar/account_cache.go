@@ -0,0 +1,74 @@
+package stellar
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mtlprog/total/internal/cachestats"
+	"github.com/samber/hot"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+)
+
+const (
+	// AccountCacheTTL is how long a loaded account is reused. It bounds how long a
+	// sequence number can be stale when a submission is not noticed via Invalidate.
+	AccountCacheTTL = 15 * time.Second
+	// accountCacheSize bounds the number of cached accounts.
+	accountCacheSize = 1000
+)
+
+// CachingClient is a Client that reuses GetAccount results for AccountCacheTTL, so
+// building several transactions in a session loads the account from Horizon once.
+// Other methods go to the wrapped client. Failed lookups are not cached, so a newly
+// funded account is found on the next request.
+type CachingClient struct {
+	Client
+	accounts *hot.HotCache[string, horizon.Account]
+	stats    cachestats.Counter
+}
+
+// NewCachingClient wraps client with an account cache.
+func NewCachingClient(client Client) *CachingClient {
+	if client == nil {
+		panic("NewCachingClient: client must not be nil")
+	}
+	return &CachingClient{
+		Client: client,
+		accounts: hot.NewHotCache[string, horizon.Account](hot.LRU, accountCacheSize).
+			WithTTL(AccountCacheTTL).
+			Build(),
+	}
+}
+
+// GetAccount implements Client. Each call returns its own copy, since building a
+// transaction increments the sequence number of its source account in place.
+func (c *CachingClient) GetAccount(ctx context.Context, publicKey string) (*horizon.Account, error) {
+	account, found, err := c.accounts.Get(publicKey)
+	if err != nil {
+		slog.Warn("account cache error, treating as miss", "account", publicKey, "error", err)
+	}
+	if err == nil && found {
+		c.stats.Hit()
+		return &account, nil
+	}
+	c.stats.Miss()
+
+	loaded, err := c.Client.GetAccount(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	c.accounts.Set(publicKey, *loaded)
+	return loaded, nil
+}
+
+// Invalidate drops the cached account, e.g. after a transaction from it was submitted
+// and its sequence number changed.
+func (c *CachingClient) Invalidate(publicKey string) {
+	c.accounts.Delete(publicKey)
+}
+
+// CacheStats returns hit and miss counts of the account cache.
+func (c *CachingClient) CacheStats() cachestats.Stats {
+	return c.stats.Stats()
+}
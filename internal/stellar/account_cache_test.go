@@ -0,0 +1,54 @@
+package stellar
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+)
+
+// countingClient serves accounts with a fixed sequence number and counts lookups.
+type countingClient struct {
+	Client
+	lookups int
+}
+
+func (c *countingClient) GetAccount(_ context.Context, publicKey string) (*horizon.Account, error) {
+	c.lookups++
+	return &horizon.Account{AccountID: publicKey, Sequence: 100}, nil
+}
+
+func TestCachingClientGetAccount(t *testing.T) {
+	inner := &countingClient{}
+	c := NewCachingClient(inner)
+	ctx := context.Background()
+
+	first, err := c.GetAccount(ctx, "GA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Building a transaction increments the source sequence in place.
+	first.IncrementSequenceNumber()
+
+	second, err := c.GetAccount(ctx, "GA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.lookups != 1 {
+		t.Errorf("lookups = %d, want 1", inner.lookups)
+	}
+	if second.Sequence != 100 {
+		t.Errorf("cached sequence = %d, want 100 (callers must not share the cached account)", second.Sequence)
+	}
+
+	c.Invalidate("GA")
+	if _, err := c.GetAccount(ctx, "GA"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.lookups != 2 {
+		t.Errorf("lookups after Invalidate = %d, want 2", inner.lookups)
+	}
+	if stats := c.CacheStats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("cache stats = %+v, want 1 hit, 2 misses", stats)
+	}
+}
@@ -2,12 +2,23 @@ package stellar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
 	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
+// BuilderVersion identifies how this builder encodes transactions. The golden XDRs
+// in testdata/golden pin the encoding of every signed transaction; bump the version
+// when they change on purpose (new arguments, a different SDK encoding), so deployments
+// can be told apart in /health.
+const BuilderVersion = 1
+
 // Builder creates Soroban transactions for market operations.
 type Builder struct {
 	client            Client
@@ -15,22 +26,77 @@ type Builder struct {
 	baseFee           int64
 	sorobanClient     *soroban.Client
 	contractInvoker   *soroban.ContractInvoker
+	simulationAccount string // Source of read-only simulations; empty uses the caller's account
+	oracleSequences   *SequenceAllocator
 }
 
 // NewBuilder creates a new transaction builder.
-func NewBuilder(client Client, networkPassphrase string, baseFee int64, sorobanClient *soroban.Client) *Builder {
+// A zero txTimeout builds transactions without an upper time bound.
+func NewBuilder(client Client, networkPassphrase string, baseFee int64, txTimeout time.Duration, sorobanClient *soroban.Client) *Builder {
 	b := &Builder{
 		client:            client,
 		networkPassphrase: networkPassphrase,
 		baseFee:           baseFee,
 		sorobanClient:     sorobanClient,
+		oracleSequences:   NewSequenceAllocator(),
 	}
 	if sorobanClient != nil {
-		b.contractInvoker = soroban.NewContractInvoker(sorobanClient, networkPassphrase, baseFee)
+		b.contractInvoker = soroban.NewContractInvoker(sorobanClient, networkPassphrase, baseFee, txTimeout)
 	}
 	return b
 }
 
+// SetSimulationAccount sets the account used as source of read-only simulations
+// (quotes, balances, market state) instead of the account passed by the caller.
+// The account should exist on the network. Call it before the builder is used.
+func (b *Builder) SetSimulationAccount(publicKey string) error {
+	if !strkey.IsValidEd25519PublicKey(publicKey) {
+		return fmt.Errorf("invalid simulation account %q", publicKey)
+	}
+	b.simulationAccount = publicKey
+	return nil
+}
+
+// InvalidateAccount drops a cached account after a transaction of it was submitted,
+// so the next transaction is built with its new sequence number. It is a no-op
+// unless the builder's client is a CachingClient.
+func (b *Builder) InvalidateAccount(publicKey string) {
+	if c, ok := b.client.(*CachingClient); ok {
+		c.Invalidate(publicKey)
+	}
+}
+
+// ReleaseSequences drops the sequence numbers reserved for an account's built
+// transactions, so the next one is built from the account's sequence number on the
+// network. Used when a queue of transactions is rebuilt in order.
+func (b *Builder) ReleaseSequences(publicKey string) {
+	b.InvalidateAccount(publicKey)
+	b.oracleSequences.Release(publicKey)
+}
+
+// oracleSource loads the oracle account for a deploy, resolve or withdraw transaction
+// and reserves its sequence number, so several oracle transactions built before any
+// is submitted get consecutive numbers.
+func (b *Builder) oracleSource(ctx context.Context, publicKey string) (*horizon.Account, error) {
+	b.InvalidateAccount(publicKey)
+	account, err := b.client.GetAccount(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	b.oracleSequences.Reserve(account)
+	return account, nil
+}
+
+// simulationSource returns the source account of a simulation-only transaction.
+// Simulation does not check sequence numbers, so the account is not loaded from
+// Horizon and reads keep working while Horizon is unavailable.
+func (b *Builder) simulationSource(publicKey string) txnbuild.Account {
+	if b.simulationAccount != "" {
+		publicKey = b.simulationAccount
+	}
+	return &txnbuild.SimpleAccount{AccountID: publicKey}
+}
+
 // BuyTxParams contains parameters for buying tokens via Soroban contract.
 type BuyTxParams struct {
 	UserPublicKey string
@@ -38,6 +104,7 @@ type BuyTxParams struct {
 	Outcome       uint32 // 0 for YES, 1 for NO
 	Amount        int64  // Amount scaled by 10^7
 	MaxCost       int64  // Max cost scaled by 10^7
+	Memo          string // Optional text memo (e.g. referral attribution)
 }
 
 // BuildBuyTx builds an InvokeHostFunction transaction for buying tokens.
@@ -68,6 +135,7 @@ func (b *Builder) BuildBuyTx(ctx context.Context, params BuyTxParams) (string, e
 		ContractID:    params.ContractID,
 		FunctionName:  "buy",
 		Args:          args,
+		Memo:          params.Memo,
 	}
 
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
@@ -80,6 +148,7 @@ type SellTxParams struct {
 	Outcome       uint32 // 0 for YES, 1 for NO
 	Amount        int64  // Amount scaled by 10^7
 	MinReturn     int64  // Min return scaled by 10^7
+	Memo          string // Optional text memo (e.g. referral attribution)
 }
 
 // BuildSellTx builds an InvokeHostFunction transaction for selling tokens.
@@ -110,6 +179,7 @@ func (b *Builder) BuildSellTx(ctx context.Context, params SellTxParams) (string,
 		ContractID:    params.ContractID,
 		FunctionName:  "sell",
 		Args:          args,
+		Memo:          params.Memo,
 	}
 
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
@@ -128,7 +198,7 @@ func (b *Builder) BuildResolveTx(ctx context.Context, params ResolveTxParams) (s
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	oracleAccount, err := b.client.GetAccount(ctx, params.OraclePublicKey)
+	oracleAccount, err := b.oracleSource(ctx, params.OraclePublicKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to get oracle account: %w", err)
 	}
@@ -189,6 +259,76 @@ func (b *Builder) BuildClaimTx(ctx context.Context, params ClaimTxParams) (strin
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
 }
 
+// MaxMultiInvokeCalls bounds the number of calls in one BuildMultiInvokeTx batch.
+const MaxMultiInvokeCalls = 10
+
+// ErrTooManyCalls is returned when a batch exceeds MaxMultiInvokeCalls.
+var ErrTooManyCalls = errors.New("too many calls in batch")
+
+// MultiInvokeCall describes one contract call in a batch.
+type MultiInvokeCall struct {
+	ContractID   string
+	FunctionName string
+	Args         []xdr.ScVal
+}
+
+// MultiInvokeResult contains the prepared transactions of a batch, in submission order.
+type MultiInvokeResult struct {
+	XDRs     []string
+	TotalFee int64 // Sum of inclusion and resource fees of all transactions, in stroops
+}
+
+// BuildMultiInvokeTx builds a batch of contract calls for one source account, e.g. claim on
+// several markets. Soroban allows exactly one InvokeHostFunction operation per transaction,
+// so the batch is returned as one simulated transaction per call with consecutive sequence
+// numbers: the user signs them together and submits them in order.
+// Each call is simulated against current ledger state, so calls must not depend on each other.
+func (b *Builder) BuildMultiInvokeTx(ctx context.Context, userPublicKey string, calls []MultiInvokeCall) (*MultiInvokeResult, error) {
+	if b.contractInvoker == nil {
+		return nil, fmt.Errorf("soroban client not configured")
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("batch has no calls")
+	}
+	if len(calls) > MaxMultiInvokeCalls {
+		return nil, fmt.Errorf("%w: %d (max %d)", ErrTooManyCalls, len(calls), MaxMultiInvokeCalls)
+	}
+
+	// Each BuildInvokeTx increments the account's sequence number, chaining the batch.
+	userAccount, err := b.client.GetAccount(ctx, userPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user account: %w", err)
+	}
+
+	result := &MultiInvokeResult{XDRs: make([]string, 0, len(calls))}
+	for i, call := range calls {
+		txXDR, err := b.contractInvoker.BuildInvokeTx(ctx, soroban.InvokeParams{
+			SourceAccount: userAccount,
+			ContractID:    call.ContractID,
+			FunctionName:  call.FunctionName,
+			Args:          call.Args,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("call %d (%s on %s): %w", i, call.FunctionName, call.ContractID, err)
+		}
+
+		preparedXDR, err := b.contractInvoker.SimulateAndPrepare(ctx, txXDR)
+		if err != nil {
+			return nil, fmt.Errorf("call %d (%s on %s): %w", i, call.FunctionName, call.ContractID, err)
+		}
+
+		fee, err := soroban.TransactionFee(preparedXDR)
+		if err != nil {
+			return nil, fmt.Errorf("call %d (%s on %s): %w", i, call.FunctionName, call.ContractID, err)
+		}
+
+		result.XDRs = append(result.XDRs, preparedXDR)
+		result.TotalFee += fee
+	}
+
+	return result, nil
+}
+
 // WithdrawTxParams contains parameters for oracle withdrawing remaining pool.
 type WithdrawTxParams struct {
 	OraclePublicKey string
@@ -201,7 +341,7 @@ func (b *Builder) BuildWithdrawTx(ctx context.Context, params WithdrawTxParams)
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	oracleAccount, err := b.client.GetAccount(ctx, params.OraclePublicKey)
+	oracleAccount, err := b.oracleSource(ctx, params.OraclePublicKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to get oracle account: %w", err)
 	}
@@ -237,10 +377,7 @@ func (b *Builder) BuildGetQuoteTx(ctx context.Context, params GetQuoteTxParams)
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	args := []xdr.ScVal{
 		soroban.EncodeU32(params.Outcome),
@@ -263,10 +400,7 @@ func (b *Builder) BuildGetSellQuoteTx(ctx context.Context, params GetQuoteTxPara
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	args := []xdr.ScVal{
 		soroban.EncodeU32(params.Outcome),
@@ -297,10 +431,7 @@ func (b *Builder) BuildGetBalanceTx(ctx context.Context, params GetBalanceTxPara
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	accountAddr, err := soroban.EncodeAddress(params.Account)
 	if err != nil {
@@ -322,12 +453,82 @@ func (b *Builder) BuildGetBalanceTx(ctx context.Context, params GetBalanceTxPara
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
 }
 
+// RebuildInvokeTx rebuilds the contract call carried by a previously built transaction
+// with the source account's current sequence number and fresh time bounds. oracle
+// marks a transaction of the oracle account, whose sequence reservations restart
+// from the network's sequence number; callers must have authenticated the oracle.
+func (b *Builder) RebuildInvokeTx(ctx context.Context, invoke *soroban.DecodedInvoke, oracle bool) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+
+	// Rebuilds usually follow a sequence number mismatch, so never reuse a cached account.
+	b.InvalidateAccount(invoke.SourceAccount)
+	if oracle {
+		b.oracleSequences.Release(invoke.SourceAccount)
+	}
+	sourceAccount, err := b.client.GetAccount(ctx, invoke.SourceAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to get source account: %w", err)
+	}
+	if oracle {
+		b.oracleSequences.Reserve(sourceAccount)
+	}
+
+	invokeParams := soroban.InvokeParams{
+		SourceAccount: sourceAccount,
+		ContractID:    invoke.ContractID,
+		FunctionName:  invoke.FunctionName,
+		Args:          invoke.Args,
+		Memo:          invoke.Memo,
+	}
+
+	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
+}
+
+// BuildReplayTx builds the contract call carried by a previously submitted
+// transaction again, for simulation only: the source account is not loaded and no
+// sequence number is reserved.
+func (b *Builder) BuildReplayTx(ctx context.Context, invoke *soroban.DecodedInvoke) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+	return b.contractInvoker.BuildInvokeTx(ctx, soroban.InvokeParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: invoke.SourceAccount},
+		ContractID:    invoke.ContractID,
+		FunctionName:  invoke.FunctionName,
+		Args:          invoke.Args,
+		Memo:          invoke.Memo,
+	})
+}
+
 // SimulateAndPrepareTx simulates a Soroban transaction and returns it with resources attached.
 func (b *Builder) SimulateAndPrepareTx(ctx context.Context, txXDR string) (string, error) {
 	if b.contractInvoker == nil {
 		return "", fmt.Errorf("soroban client not configured")
 	}
-	return b.contractInvoker.SimulateAndPrepare(ctx, txXDR)
+	prepared, err := b.contractInvoker.SimulateAndPrepare(ctx, txXDR)
+	if err != nil {
+		// The transaction is not handed out, so its oracle sequence number is free again.
+		var env xdr.TransactionEnvelope
+		if xdr.SafeUnmarshalBase64(txXDR, &env) == nil {
+			source := env.SourceAccount().ToAccountId()
+			b.oracleSequences.Cancel(source.Address(), env.SeqNum())
+		}
+		return "", err
+	}
+	return prepared, nil
+}
+
+// AuthExpiry returns the earliest signature expiration ledger of a prepared
+// transaction's auth entries and an estimate of when it closes. Both are zero when
+// the transaction has no address auth entries.
+func (b *Builder) AuthExpiry(txXDR string) (uint32, time.Time, error) {
+	ledger, err := soroban.AuthExpiration(txXDR)
+	if err != nil || ledger == 0 || b.contractInvoker == nil {
+		return ledger, time.Time{}, err
+	}
+	return ledger, b.contractInvoker.LedgerTime(ledger), nil
 }
 
 // --- Factory contract methods ---
@@ -344,10 +545,7 @@ func (b *Builder) BuildListMarketsTx(ctx context.Context, params ListMarketsTxPa
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	// list_markets() takes no arguments
 	invokeParams := soroban.InvokeParams{
@@ -372,10 +570,7 @@ func (b *Builder) BuildGetStateTx(ctx context.Context, params GetStateTxParams)
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	// get_state() takes no arguments
 	invokeParams := soroban.InvokeParams{
@@ -400,10 +595,7 @@ func (b *Builder) BuildGetMetadataHashTx(ctx context.Context, params GetMetadata
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	// get_metadata_hash() takes no arguments
 	invokeParams := soroban.InvokeParams{
@@ -416,6 +608,31 @@ func (b *Builder) BuildGetMetadataHashTx(ctx context.Context, params GetMetadata
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
 }
 
+// GetLiquidityParamTxParams contains parameters for getting the LMSR liquidity parameter.
+type GetLiquidityParamTxParams struct {
+	UserPublicKey string
+	ContractID    string
+}
+
+// BuildGetLiquidityParamTx builds a transaction to call market.get_liquidity_param() (simulation only).
+func (b *Builder) BuildGetLiquidityParamTx(ctx context.Context, params GetLiquidityParamTxParams) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+
+	userAccount := b.simulationSource(params.UserPublicKey)
+
+	// get_liquidity_param() takes no arguments
+	invokeParams := soroban.InvokeParams{
+		SourceAccount: userAccount,
+		ContractID:    params.ContractID,
+		FunctionName:  "get_liquidity_param",
+		Args:          []xdr.ScVal{},
+	}
+
+	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
+}
+
 // GetWinningOutcomeTxParams contains parameters for getting winning outcome.
 type GetWinningOutcomeTxParams struct {
 	UserPublicKey string
@@ -428,10 +645,7 @@ func (b *Builder) BuildGetWinningOutcomeTx(ctx context.Context, params GetWinnin
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	userAccount, err := b.client.GetAccount(ctx, params.UserPublicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user account: %w", err)
-	}
+	userAccount := b.simulationSource(params.UserPublicKey)
 
 	invokeParams := soroban.InvokeParams{
 		SourceAccount: userAccount,
@@ -459,7 +673,7 @@ func (b *Builder) BuildDeployMarketTx(ctx context.Context, params DeployMarketTx
 		return "", fmt.Errorf("soroban client not configured")
 	}
 
-	oracleAccount, err := b.client.GetAccount(ctx, params.OraclePublicKey)
+	oracleAccount, err := b.oracleSource(ctx, params.OraclePublicKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to get oracle account: %w", err)
 	}
@@ -487,3 +701,178 @@ func (b *Builder) BuildDeployMarketTx(ctx context.Context, params DeployMarketTx
 
 	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
 }
+
+// --- Factory administration ---
+
+// FactoryQueryTxParams contains parameters for reading factory settings.
+type FactoryQueryTxParams struct {
+	UserPublicKey   string
+	FactoryContract string
+}
+
+// BuildGetFactoryAdminTx builds a transaction to call factory.get_admin() (simulation only).
+func (b *Builder) BuildGetFactoryAdminTx(ctx context.Context, params FactoryQueryTxParams) (string, error) {
+	return b.buildFactoryQueryTx(ctx, params, "get_admin")
+}
+
+// BuildGetMarketWasmHashTx builds a transaction to call factory.get_market_wasm_hash()
+// (simulation only).
+func (b *Builder) BuildGetMarketWasmHashTx(ctx context.Context, params FactoryQueryTxParams) (string, error) {
+	return b.buildFactoryQueryTx(ctx, params, "get_market_wasm_hash")
+}
+
+func (b *Builder) buildFactoryQueryTx(ctx context.Context, params FactoryQueryTxParams, function string) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+
+	invokeParams := soroban.InvokeParams{
+		SourceAccount: b.simulationSource(params.UserPublicKey),
+		ContractID:    params.FactoryContract,
+		FunctionName:  function,
+		Args:          []xdr.ScVal{},
+	}
+
+	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
+}
+
+// SetMarketWasmHashTxParams contains parameters for pointing the factory at a new
+// market contract build.
+type SetMarketWasmHashTxParams struct {
+	AdminPublicKey  string
+	FactoryContract string
+	WasmHash        [32]byte
+}
+
+// BuildSetMarketWasmHashTx builds a transaction to call factory.set_market_wasm_hash().
+// Markets deployed afterwards run the new build; existing markets keep theirs.
+func (b *Builder) BuildSetMarketWasmHashTx(ctx context.Context, params SetMarketWasmHashTxParams) (string, error) {
+	return b.buildFactoryAdminTx(ctx, params.AdminPublicKey, params.FactoryContract, "set_market_wasm_hash", soroban.EncodeBytes32(params.WasmHash))
+}
+
+// SetCollateralTokenTxParams contains parameters for changing the collateral token
+// of markets deployed by the factory.
+type SetCollateralTokenTxParams struct {
+	AdminPublicKey  string
+	FactoryContract string
+	TokenContract   string // SAC or token contract ID (C...)
+}
+
+// BuildSetCollateralTokenTx builds a transaction to call factory.set_default_collateral_token().
+func (b *Builder) BuildSetCollateralTokenTx(ctx context.Context, params SetCollateralTokenTxParams) (string, error) {
+	tokenAddr, err := soroban.EncodeAddress(params.TokenContract)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token address: %w", err)
+	}
+	return b.buildFactoryAdminTx(ctx, params.AdminPublicKey, params.FactoryContract, "set_default_collateral_token", tokenAddr)
+}
+
+// buildFactoryAdminTx builds an admin-only factory call: function(admin, arg).
+func (b *Builder) buildFactoryAdminTx(ctx context.Context, adminPublicKey, factoryContract, function string, arg xdr.ScVal) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+
+	adminAccount, err := b.oracleSource(ctx, adminPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get admin account: %w", err)
+	}
+
+	adminAddr, err := soroban.EncodeAddress(adminPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode admin address: %w", err)
+	}
+
+	invokeParams := soroban.InvokeParams{
+		SourceAccount: adminAccount,
+		ContractID:    factoryContract,
+		FunctionName:  function,
+		Args:          []xdr.ScVal{adminAddr, arg},
+	}
+
+	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
+}
+
+// --- Environment bootstrap ---
+
+// BuildUploadWasmTx builds a transaction uploading contract WASM code from the
+// oracle account.
+func (b *Builder) BuildUploadWasmTx(ctx context.Context, oraclePublicKey string, wasm []byte) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+	oracleAccount, err := b.oracleSource(ctx, oraclePublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get oracle account: %w", err)
+	}
+	return b.contractInvoker.BuildUploadWasmTx(oracleAccount, wasm)
+}
+
+// BuildCreateContractTx builds a transaction deploying an instance of uploaded WASM
+// code from the oracle account.
+func (b *Builder) BuildCreateContractTx(ctx context.Context, oraclePublicKey string, wasmHash, salt [32]byte) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+	oracleAccount, err := b.oracleSource(ctx, oraclePublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get oracle account: %w", err)
+	}
+	return b.contractInvoker.BuildCreateContractTx(oracleAccount, wasmHash, salt)
+}
+
+// BuildDeployAssetContractTx builds a transaction deploying the Stellar Asset Contract
+// of a classic asset from the oracle account.
+func (b *Builder) BuildDeployAssetContractTx(ctx context.Context, oraclePublicKey string, asset xdr.Asset) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+	oracleAccount, err := b.oracleSource(ctx, oraclePublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get oracle account: %w", err)
+	}
+	return b.contractInvoker.BuildDeployAssetContractTx(oracleAccount, asset)
+}
+
+// InitializeFactoryTxParams contains parameters for initializing a deployed factory.
+type InitializeFactoryTxParams struct {
+	AdminPublicKey  string
+	FactoryContract string
+	MarketWasmHash  [32]byte
+	CollateralToken string // Token contract ID (C...)
+}
+
+// BuildInitializeFactoryTx builds a transaction to call
+// factory.initialize(admin, market_wasm_hash, default_collateral_token).
+func (b *Builder) BuildInitializeFactoryTx(ctx context.Context, params InitializeFactoryTxParams) (string, error) {
+	if b.contractInvoker == nil {
+		return "", fmt.Errorf("soroban client not configured")
+	}
+
+	adminAccount, err := b.oracleSource(ctx, params.AdminPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get admin account: %w", err)
+	}
+
+	adminAddr, err := soroban.EncodeAddress(params.AdminPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode admin address: %w", err)
+	}
+	tokenAddr, err := soroban.EncodeAddress(params.CollateralToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode collateral token: %w", err)
+	}
+
+	invokeParams := soroban.InvokeParams{
+		SourceAccount: adminAccount,
+		ContractID:    params.FactoryContract,
+		FunctionName:  "initialize",
+		Args: []xdr.ScVal{
+			adminAddr,
+			soroban.EncodeBytes32(params.MarketWasmHash),
+			tokenAddr,
+		},
+	}
+
+	return b.contractInvoker.BuildInvokeTx(ctx, invokeParams)
+}
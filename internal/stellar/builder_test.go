@@ -0,0 +1,222 @@
+package stellar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden XDRs in testdata/golden")
+
+const (
+	goldenDir          = "testdata/golden"
+	goldenManifestName = "manifest.json"
+	goldenPassphrase   = "Test SDF Network ; September 2015"
+)
+
+// goldenManifest records the BuilderVersion the golden XDRs were written with.
+type goldenManifest struct {
+	BuilderVersion int `json:"builder_version"`
+}
+
+// goldenAccount derives a fixed account from a one-byte seed.
+func goldenAccount(t *testing.T, seed byte) string {
+	t.Helper()
+	kp, err := keypair.FromRawSeed([32]byte{seed})
+	if err != nil {
+		t.Fatalf("FromRawSeed() error = %v", err)
+	}
+	return kp.Address()
+}
+
+// goldenContract derives a fixed contract ID from a one-byte seed.
+func goldenContract(t *testing.T, seed byte) string {
+	t.Helper()
+	id, err := strkey.Encode(strkey.VersionByteContract, bytes.Repeat([]byte{seed}, 32))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return id
+}
+
+// goldenBuilds builds every transaction the app has signed, with fixed inputs. Each
+// build gets a fresh builder, so all sources start at sequence 100.
+func goldenBuilds(t *testing.T) map[string]string {
+	t.Helper()
+	user, oracle := goldenAccount(t, 1), goldenAccount(t, 2)
+	market, factory, token := goldenContract(t, 3), goldenContract(t, 4), goldenContract(t, 5)
+	var salt, wasmHash [32]byte
+	copy(salt[:], bytes.Repeat([]byte{6}, 32))
+	copy(wasmHash[:], bytes.Repeat([]byte{7}, 32))
+
+	builds := map[string]func(context.Context, *Builder) (string, error){
+		"buy": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildBuyTx(ctx, BuyTxParams{UserPublicKey: user, ContractID: market, Outcome: 0, Amount: 10_0000000, MaxCost: 5_5000000, Memo: "ref:golden"})
+		},
+		"sell": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildSellTx(ctx, SellTxParams{UserPublicKey: user, ContractID: market, Outcome: 1, Amount: 4_0000000, MinReturn: 1_2500000})
+		},
+		"resolve": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildResolveTx(ctx, ResolveTxParams{OraclePublicKey: oracle, ContractID: market, WinningOutcome: 1})
+		},
+		"claim": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildClaimTx(ctx, ClaimTxParams{UserPublicKey: user, ContractID: market})
+		},
+		"withdraw": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildWithdrawTx(ctx, WithdrawTxParams{OraclePublicKey: oracle, ContractID: market})
+		},
+		"deploy_market": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildDeployMarketTx(ctx, DeployMarketTxParams{
+				OraclePublicKey: oracle,
+				FactoryContract: factory,
+				LiquidityParam:  100_0000000,
+				MetadataHash:    "QmGoldenMetadataHash",
+				InitialFunding:  70_0000000,
+				Salt:            salt,
+			})
+		},
+		"set_market_wasm_hash": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildSetMarketWasmHashTx(ctx, SetMarketWasmHashTxParams{AdminPublicKey: oracle, FactoryContract: factory, WasmHash: wasmHash})
+		},
+		"set_default_collateral_token": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildSetCollateralTokenTx(ctx, SetCollateralTokenTxParams{AdminPublicKey: oracle, FactoryContract: factory, TokenContract: token})
+		},
+		"upload_wasm": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildUploadWasmTx(ctx, oracle, []byte("\x00asm\x01\x00\x00\x00"))
+		},
+		"create_contract": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildCreateContractTx(ctx, oracle, wasmHash, salt)
+		},
+		"deploy_asset_contract": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildDeployAssetContractTx(ctx, oracle, xdr.MustNewNativeAsset())
+		},
+		"initialize_factory": func(ctx context.Context, b *Builder) (string, error) {
+			return b.BuildInitializeFactoryTx(ctx, InitializeFactoryTxParams{AdminPublicKey: oracle, FactoryContract: factory, MarketWasmHash: wasmHash, CollateralToken: token})
+		},
+	}
+
+	got := make(map[string]string, len(builds))
+	for name, build := range builds {
+		// No time bounds and no RPC: building is offline and deterministic.
+		b := NewBuilder(&countingClient{}, goldenPassphrase, 100, 0, soroban.NewClient("http://127.0.0.1:0", nil))
+		txXDR, err := build(context.Background(), b)
+		if err != nil {
+			t.Fatalf("build %s: %v", name, err)
+		}
+		got[name] = txXDR
+	}
+	return got
+}
+
+// TestBuilderGolden fails when a built transaction's encoding changes. If the change
+// is intended, bump BuilderVersion and run
+//
+//	go test ./internal/stellar -run TestBuilderGolden -update
+func TestBuilderGolden(t *testing.T) {
+	builds := goldenBuilds(t)
+	manifestPath := filepath.Join(goldenDir, goldenManifestName)
+
+	if *updateGolden {
+		var manifest goldenManifest
+		if data, err := os.ReadFile(manifestPath); err == nil {
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				t.Fatalf("failed to parse %s: %v", manifestPath, err)
+			}
+		}
+		var changed []string
+		for name, txXDR := range builds {
+			old, err := os.ReadFile(filepath.Join(goldenDir, name+".xdr"))
+			if err == nil && strings.TrimSpace(string(old)) != txXDR {
+				changed = append(changed, name)
+			}
+		}
+		if len(changed) > 0 && manifest.BuilderVersion == BuilderVersion {
+			t.Fatalf("encoding of %v changed: bump BuilderVersion (now %d) before updating the golden files", changed, BuilderVersion)
+		}
+
+		if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for name, txXDR := range builds {
+			if err := os.WriteFile(filepath.Join(goldenDir, name+".xdr"), []byte(txXDR+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, _ := json.MarshalIndent(goldenManifest{BuilderVersion: BuilderVersion}, "", "  ")
+		if err := os.WriteFile(manifestPath, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read %s (run with -update to create it): %v", manifestPath, err)
+	}
+	var manifest goldenManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("failed to parse %s: %v", manifestPath, err)
+	}
+	if manifest.BuilderVersion != BuilderVersion {
+		t.Errorf("golden files are for builder version %d, BuilderVersion is %d: run with -update", manifest.BuilderVersion, BuilderVersion)
+	}
+
+	for name, txXDR := range builds {
+		t.Run(name, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join(goldenDir, name+".xdr"))
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if strings.TrimSpace(string(want)) != txXDR {
+				t.Errorf("encoding of %s changed unexpectedly.\ngot:  %s\nwant: %s\nIf intended, bump BuilderVersion and run with -update.", name, txXDR, strings.TrimSpace(string(want)))
+			}
+		})
+	}
+}
+
+// TestBuilderGoldenDecodes checks the golden XDRs still decode to the contract calls
+// they were built as, so a golden file cannot be regenerated into something else.
+func TestBuilderGoldenDecodes(t *testing.T) {
+	market, factory := goldenContract(t, 3), goldenContract(t, 4)
+	tests := []struct {
+		name     string
+		contract string
+		function string
+		args     int
+	}{
+		{"buy", market, "buy", 4},
+		{"sell", market, "sell", 4},
+		{"resolve", market, "resolve", 2},
+		{"claim", market, "claim", 1},
+		{"withdraw", market, "withdraw_remaining", 1},
+		{"deploy_market", factory, "deploy_market", 5},
+		{"set_market_wasm_hash", factory, "set_market_wasm_hash", 2},
+		{"set_default_collateral_token", factory, "set_default_collateral_token", 2},
+		{"initialize_factory", factory, "initialize", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(goldenDir, tt.name+".xdr"))
+			if err != nil {
+				t.Fatalf("missing golden file: %v", err)
+			}
+			invoke, err := soroban.DecodeInvokeTx(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatalf("DecodeInvokeTx() error = %v", err)
+			}
+			if invoke.ContractID != tt.contract || invoke.FunctionName != tt.function || len(invoke.Args) != tt.args {
+				t.Errorf("golden %s decodes to %s.%s with %d args, want %s.%s with %d", tt.name, invoke.ContractID, invoke.FunctionName, len(invoke.Args), tt.contract, tt.function, tt.args)
+			}
+		})
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
+	"github.com/mtlprog/total/internal/httpclient"
 	"github.com/stellar/go-stellar-sdk/clients/horizonclient"
 	"github.com/stellar/go-stellar-sdk/protocols/horizon"
 	"github.com/stellar/go-stellar-sdk/protocols/horizon/operations"
@@ -39,6 +39,12 @@ type Client interface {
 	// GetOperations returns recent operations for an account.
 	GetOperations(ctx context.Context, publicKey string, limit int) ([]operations.Operation, error)
 
+	// GetRoot returns Horizon server status (ingested ledger, versions).
+	GetRoot(ctx context.Context) (*horizon.Root, error)
+
+	// GetFeeStats returns fee statistics for recent ledgers.
+	GetFeeStats(ctx context.Context) (*horizon.FeeStats, error)
+
 	// HorizonURL returns the Horizon server URL.
 	HorizonURL() string
 
@@ -53,20 +59,22 @@ type HorizonClient struct {
 }
 
 // NewHorizonClient creates a new Horizon client.
+// A nil httpClient uses httpclient.Default().
 // Returns an error if required parameters are empty.
-func NewHorizonClient(horizonURL, networkPassphrase string) (*HorizonClient, error) {
+func NewHorizonClient(horizonURL, networkPassphrase string, httpClient *http.Client) (*HorizonClient, error) {
 	if horizonURL == "" {
 		return nil, ErrEmptyHorizonURL
 	}
 	if networkPassphrase == "" {
 		return nil, ErrEmptyPassphrase
 	}
+	if httpClient == nil {
+		httpClient = httpclient.Default()
+	}
 	return &HorizonClient{
 		client: &horizonclient.Client{
 			HorizonURL: horizonURL,
-			HTTP: &http.Client{
-				Timeout: 30 * time.Second,
-			},
+			HTTP:       httpClient,
 		},
 		networkPassphrase: networkPassphrase,
 	}, nil
@@ -130,6 +138,38 @@ func (c *HorizonClient) GetTransactions(ctx context.Context, publicKey string, l
 	return page.Embedded.Records, nil
 }
 
+// ledgerTransactionsPageLimit is the largest page Horizon serves.
+const ledgerTransactionsPageLimit = 200
+
+// GetLedgerTransactions returns the successful transactions of one ledger, following
+// pages for busy ledgers. Their ResultMetaXdr is empty when Horizon does not keep
+// transaction meta.
+func (c *HorizonClient) GetLedgerTransactions(ctx context.Context, ledger uint32) ([]horizon.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	page, err := c.client.Transactions(horizonclient.TransactionRequest{
+		ForLedger: uint(ledger),
+		Limit:     ledgerTransactionsPageLimit,
+		Order:     horizonclient.OrderAsc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions of ledger %d: %w", ledger, err)
+	}
+	txs := page.Embedded.Records
+	for len(page.Embedded.Records) == ledgerTransactionsPageLimit {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("context error: %w", err)
+		}
+		if page, err = c.client.NextTransactionsPage(page); err != nil {
+			return nil, fmt.Errorf("failed to get transactions of ledger %d: %w", ledger, err)
+		}
+		txs = append(txs, page.Embedded.Records...)
+	}
+	return txs, nil
+}
+
 // GetOperations implements Client.
 func (c *HorizonClient) GetOperations(ctx context.Context, publicKey string, limit int) ([]operations.Operation, error) {
 	// Check context before making request
@@ -151,6 +191,34 @@ func (c *HorizonClient) GetOperations(ctx context.Context, publicKey string, lim
 	return page.Embedded.Records, nil
 }
 
+// GetRoot implements Client.
+func (c *HorizonClient) GetRoot(ctx context.Context) (*horizon.Root, error) {
+	// Check context before making request
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	root, err := c.client.Root()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get horizon root: %w", err)
+	}
+	return &root, nil
+}
+
+// GetFeeStats implements Client.
+func (c *HorizonClient) GetFeeStats(ctx context.Context) (*horizon.FeeStats, error) {
+	// Check context before making request
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	stats, err := c.client.FeeStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee stats: %w", err)
+	}
+	return &stats, nil
+}
+
 // HorizonURL implements Client.
 func (c *HorizonClient) HorizonURL() string {
 	return c.client.HorizonURL
@@ -0,0 +1,171 @@
+package stellar_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon/base"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon/operations"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestHorizonClientGetAccount(t *testing.T) {
+	srv := stellartest.NewServer(t)
+	client := srv.Client(t)
+	ctx := context.Background()
+
+	user := keypair.MustRandom().Address()
+	srv.AddAccount(horizon.Account{
+		AccountID: user,
+		Sequence:  4242,
+		Balances:  []horizon.Balance{{Balance: "12.5000000", Asset: base.Asset{Type: "native"}}},
+		Data:      map[string]string{"total_referral": "YWxpY2U="},
+	})
+
+	account, err := client.GetAccount(ctx, user)
+	if err != nil {
+		t.Fatalf("GetAccount() error = %v", err)
+	}
+	if account.AccountID != user || account.Sequence != 4242 {
+		t.Errorf("GetAccount() = %s at %d, want %s at 4242", account.AccountID, account.Sequence, user)
+	}
+	if balances, err := client.GetAccountBalances(ctx, user); err != nil || len(balances) != 1 || balances[0].Balance != "12.5000000" {
+		t.Errorf("GetAccountBalances() = %+v, %v", balances, err)
+	}
+	if data, err := client.GetAccountData(ctx, user); err != nil || data["total_referral"] != "YWxpY2U=" {
+		t.Errorf("GetAccountData() = %v, %v", data, err)
+	}
+
+	missing := keypair.MustRandom().Address()
+	if _, err := client.GetAccount(ctx, missing); !errors.Is(err, stellar.ErrAccountNotFound) {
+		t.Errorf("GetAccount(unfunded) error = %v, want %v", err, stellar.ErrAccountNotFound)
+	}
+	if got := srv.Requests("/accounts/" + user); got != 3 {
+		t.Errorf("requests for the account = %d, want 3", got)
+	}
+}
+
+func TestHorizonClientHistory(t *testing.T) {
+	srv := stellartest.NewServer(t)
+	client := srv.Client(t)
+	ctx := context.Background()
+
+	user := keypair.MustRandom().Address()
+	srv.AddFundedAccount(user, 1, "100.0000000")
+	for _, hash := range []string{"aa", "bb", "cc"} {
+		srv.AddTransactions(user, horizon.Transaction{Hash: hash, Account: user, Successful: true})
+	}
+	srv.AddOperations(user,
+		operations.Payment{Base: operations.Base{ID: "1", Type: "payment", TypeI: int32(xdr.OperationTypePayment)}, Amount: "5.0000000"},
+		operations.InvokeHostFunction{Base: operations.Base{ID: "2", Type: "invoke_host_function", TypeI: int32(xdr.OperationTypeInvokeHostFunction)}, Function: "HostFunctionTypeHostFunctionTypeInvokeContract"},
+	)
+
+	txs, err := client.GetTransactions(ctx, user, 2)
+	if err != nil {
+		t.Fatalf("GetTransactions() error = %v", err)
+	}
+	if len(txs) != 2 || txs[0].Hash != "cc" || txs[1].Hash != "bb" {
+		t.Errorf("GetTransactions(limit 2) = %v, want the two newest, newest first", txs)
+	}
+
+	ops, err := client.GetOperations(ctx, user, 10)
+	if err != nil {
+		t.Fatalf("GetOperations() error = %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("GetOperations() returned %d operations, want 2", len(ops))
+	}
+	if _, ok := ops[0].(operations.InvokeHostFunction); !ok {
+		t.Errorf("newest operation = %T, want operations.InvokeHostFunction", ops[0])
+	}
+	if payment, ok := ops[1].(operations.Payment); !ok || payment.Amount != "5.0000000" {
+		t.Errorf("oldest operation = %#v, want the payment", ops[1])
+	}
+
+	if _, err := client.GetTransactions(ctx, keypair.MustRandom().Address(), 10); err == nil {
+		t.Error("GetTransactions(unfunded) should fail")
+	}
+}
+
+// sequenceOf decodes the sequence number of a built transaction.
+func sequenceOf(t *testing.T, txXDR string) int64 {
+	t.Helper()
+	generic, err := txnbuild.TransactionFromXDR(txXDR)
+	if err != nil {
+		t.Fatalf("TransactionFromXDR() error = %v", err)
+	}
+	tx, ok := generic.Transaction()
+	if !ok {
+		t.Fatal("expected a transaction, got a fee bump")
+	}
+	return tx.SequenceNumber()
+}
+
+func TestBuilderLoadsAccountsFromHorizon(t *testing.T) {
+	srv := stellartest.NewServer(t)
+	ctx := context.Background()
+	b := stellar.NewBuilder(stellar.NewCachingClient(srv.Client(t)), stellartest.Passphrase, 100, 0, soroban.NewClient("http://127.0.0.1:0", nil))
+
+	market, err := strkey.Encode(strkey.VersionByteContract, make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, oracle := keypair.MustRandom().Address(), keypair.MustRandom().Address()
+	srv.AddFundedAccount(user, 500, "100.0000000")
+	srv.AddFundedAccount(oracle, 800, "1000.0000000")
+
+	t.Run("user transactions reuse the cached account", func(t *testing.T) {
+		for range 2 {
+			txXDR, err := b.BuildClaimTx(ctx, stellar.ClaimTxParams{UserPublicKey: user, ContractID: market})
+			if err != nil {
+				t.Fatalf("BuildClaimTx() error = %v", err)
+			}
+			if seq := sequenceOf(t, txXDR); seq != 501 {
+				t.Errorf("sequence = %d, want 501", seq)
+			}
+		}
+		if got := srv.Requests("/accounts/" + user); got != 1 {
+			t.Errorf("Horizon requests for the user = %d, want 1", got)
+		}
+
+		b.InvalidateAccount(user)
+		srv.AddFundedAccount(user, 501, "99.0000000")
+		txXDR, err := b.BuildClaimTx(ctx, stellar.ClaimTxParams{UserPublicKey: user, ContractID: market})
+		if err != nil {
+			t.Fatalf("BuildClaimTx() error = %v", err)
+		}
+		if seq := sequenceOf(t, txXDR); seq != 502 {
+			t.Errorf("sequence after invalidation = %d, want 502", seq)
+		}
+	})
+
+	t.Run("oracle transactions reload and reserve sequence numbers", func(t *testing.T) {
+		for want := int64(801); want <= 802; want++ {
+			txXDR, err := b.BuildResolveTx(ctx, stellar.ResolveTxParams{OraclePublicKey: oracle, ContractID: market, WinningOutcome: 0})
+			if err != nil {
+				t.Fatalf("BuildResolveTx() error = %v", err)
+			}
+			if seq := sequenceOf(t, txXDR); seq != want {
+				t.Errorf("sequence = %d, want %d", seq, want)
+			}
+		}
+		if got := srv.Requests("/accounts/" + oracle); got != 2 {
+			t.Errorf("Horizon requests for the oracle = %d, want 2", got)
+		}
+	})
+
+	t.Run("unfunded account", func(t *testing.T) {
+		_, err := b.BuildClaimTx(ctx, stellar.ClaimTxParams{UserPublicKey: keypair.MustRandom().Address(), ContractID: market})
+		if !errors.Is(err, stellar.ErrAccountNotFound) {
+			t.Errorf("BuildClaimTx(unfunded) error = %v, want %v", err, stellar.ErrAccountNotFound)
+		}
+	})
+}
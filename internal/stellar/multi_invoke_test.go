@@ -0,0 +1,101 @@
+package stellar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mtlprog/total/internal/soroban"
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/mtlprog/total/internal/stellar/stellartest"
+	"github.com/stellar/go-stellar-sdk/keypair"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// simulateResourceFee is the minResourceFee of simulations served by fakeSimulateRPC.
+const simulateResourceFee = 98765
+
+// fakeSimulateRPC answers simulateTransaction like a protocol 23 RPC, failing the
+// simulation number failAt (1-based; 0 never fails).
+func fakeSimulateRPC(t *testing.T, failAt *atomic.Int32) *soroban.Client {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "simulateTransaction" {
+			t.Errorf("unexpected RPC request %q (%v)", req.Method, err)
+		}
+		if n := calls.Add(1); n == failAt.Load() {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"error":"HostError: Error(Contract, #3)","latestLedger":51234}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"transactionData":"AAAAAAAAAAAAAAAAABLWhwAACAAAAAIAAAAAAAABgc0=","minResourceFee":"98765","events":[],"results":[{"auth":[],"xdr":"AAAAAQ=="}],"latestLedger":51234}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return soroban.NewClient(srv.URL, srv.Client())
+}
+
+func TestBuildMultiInvokeTx(t *testing.T) {
+	horizonSrv := stellartest.NewServer(t)
+	user := keypair.MustRandom().Address()
+	horizonSrv.AddFundedAccount(user, 7, "100.0000000")
+
+	var failAt atomic.Int32
+	const baseFee = 100
+	b := stellar.NewBuilder(stellar.NewCachingClient(horizonSrv.Client(t)), stellartest.Passphrase, baseFee, 0, fakeSimulateRPC(t, &failAt))
+
+	var calls []stellar.MultiInvokeCall
+	for seed := byte(1); seed <= 3; seed++ {
+		contractID, err := strkey.Encode(strkey.VersionByteContract, []byte{seed, 31: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, stellar.MultiInvokeCall{ContractID: contractID, FunctionName: "claim", Args: []xdr.ScVal{}})
+	}
+	ctx := context.Background()
+
+	// The second simulation fails: the batch is dropped as a whole.
+	failAt.Store(2)
+	if _, err := b.BuildMultiInvokeTx(ctx, user, calls); err == nil || !strings.Contains(err.Error(), "call 1 ") {
+		t.Fatalf("BuildMultiInvokeTx() with a failing second simulation error = %v, want call 1 to fail", err)
+	}
+
+	// Nothing stays reserved: the next batch starts from the network sequence again.
+	failAt.Store(0)
+	result, err := b.BuildMultiInvokeTx(ctx, user, calls)
+	if err != nil {
+		t.Fatalf("BuildMultiInvokeTx() error = %v", err)
+	}
+	if len(result.XDRs) != len(calls) {
+		t.Fatalf("built %d transactions, want %d", len(result.XDRs), len(calls))
+	}
+	var wantFee int64
+	for i, txXDR := range result.XDRs {
+		var env xdr.TransactionEnvelope
+		if err := xdr.SafeUnmarshalBase64(txXDR, &env); err != nil {
+			t.Fatalf("transaction %d: %v", i, err)
+		}
+		if got, want := env.SeqNum(), int64(8+i); got != want {
+			t.Errorf("transaction %d sequence = %d, want %d", i, got, want)
+		}
+		if env.SourceAccount().ToAccountId().Address() != user {
+			t.Errorf("transaction %d source = %s, want %s", i, env.SourceAccount().ToAccountId().Address(), user)
+		}
+		wantFee += baseFee + simulateResourceFee
+	}
+	if result.TotalFee != wantFee {
+		t.Errorf("TotalFee = %d, want %d", result.TotalFee, wantFee)
+	}
+
+	calls = append(calls, make([]stellar.MultiInvokeCall, stellar.MaxMultiInvokeCalls)...)
+	if _, err := b.BuildMultiInvokeTx(ctx, user, calls); err == nil {
+		t.Error("BuildMultiInvokeTx() over MaxMultiInvokeCalls: expected error")
+	}
+}
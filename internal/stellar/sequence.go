@@ -0,0 +1,75 @@
+package stellar
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+)
+
+// SequenceReservationTTL is how long a sequence number handed out in a built but not
+// yet submitted transaction stays reserved. Abandoned transactions free their numbers
+// after this time.
+const SequenceReservationTTL = 10 * time.Minute
+
+type sequenceReservation struct {
+	last    int64 // highest sequence number handed out
+	expires time.Time
+}
+
+// SequenceAllocator hands out consecutive sequence numbers to transactions of one
+// account built back-to-back, before any of them is submitted. Without it, each
+// transaction would get the account's current sequence number plus one, and only the
+// first one submitted could succeed. Transactions must then be submitted in the order
+// they were built.
+type SequenceAllocator struct {
+	mu       sync.Mutex
+	reserved map[string]sequenceReservation
+}
+
+// NewSequenceAllocator creates an empty allocator.
+func NewSequenceAllocator() *SequenceAllocator {
+	return &SequenceAllocator{reserved: make(map[string]sequenceReservation)}
+}
+
+// Reserve advances account past the sequence numbers already reserved for it, so a
+// transaction built from it with IncrementSequenceNum gets the next free number, and
+// reserves that number. account must be freshly loaded from Horizon.
+func (a *SequenceAllocator) Reserve(account *horizon.Account) {
+	a.reserve(account, time.Now())
+}
+
+func (a *SequenceAllocator) reserve(account *horizon.Account, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	maps.DeleteFunc(a.reserved, func(_ string, r sequenceReservation) bool { return !now.Before(r.expires) })
+	// Once submitted, reserved numbers show up in the account's own sequence.
+	if r, ok := a.reserved[account.AccountID]; ok && r.last > account.Sequence {
+		account.Sequence = r.last
+	}
+	a.reserved[account.AccountID] = sequenceReservation{
+		last:    account.Sequence + 1,
+		expires: now.Add(SequenceReservationTTL),
+	}
+}
+
+// Release drops the reservations of an account, e.g. when its transactions are
+// rebuilt because their sequence numbers no longer match the network.
+func (a *SequenceAllocator) Release(accountID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.reserved, accountID)
+}
+
+// Cancel returns sequence number seq of an account if it is the last one reserved,
+// e.g. when the transaction it was built for failed simulation and is never handed out.
+func (a *SequenceAllocator) Cancel(accountID string, seq int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r, ok := a.reserved[accountID]; ok && r.last == seq {
+		r.last--
+		a.reserved[accountID] = r
+	}
+}
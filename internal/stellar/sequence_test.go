@@ -0,0 +1,49 @@
+package stellar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+)
+
+func TestSequenceAllocatorReserve(t *testing.T) {
+	a := NewSequenceAllocator()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// build mimics building a transaction with IncrementSequenceNum from a fresh account.
+	build := func(horizonSeq int64, at time.Time) int64 {
+		account := &horizon.Account{AccountID: "GORACLE", Sequence: horizonSeq}
+		a.reserve(account, at)
+		return account.Sequence + 1
+	}
+
+	if got := build(100, now); got != 101 {
+		t.Fatalf("first transaction sequence = %d, want 101", got)
+	}
+	if got := build(100, now); got != 102 {
+		t.Errorf("second unsubmitted transaction sequence = %d, want 102", got)
+	}
+	// Both were submitted: the network sequence caught up with the reservations.
+	if got := build(102, now); got != 103 {
+		t.Errorf("sequence after submission = %d, want 103", got)
+	}
+
+	a.Cancel("GORACLE", 103)
+	if got := build(102, now); got != 103 {
+		t.Errorf("sequence after cancel = %d, want 103 again", got)
+	}
+	a.Cancel("GORACLE", 101) // not the last reservation: ignored
+	if got := build(102, now); got != 104 {
+		t.Errorf("sequence after cancelling an older number = %d, want 104", got)
+	}
+
+	// Abandoned transactions free their numbers after the TTL.
+	if got := build(102, now.Add(SequenceReservationTTL)); got != 103 {
+		t.Errorf("sequence after reservation expiry = %d, want 103", got)
+	}
+
+	a.Release("GORACLE")
+	if got := build(102, now); got != 103 {
+		t.Errorf("sequence after release = %d, want 103", got)
+	}
+}
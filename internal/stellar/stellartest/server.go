@@ -0,0 +1,235 @@
+// Package stellartest provides a fake Horizon server for tests that load accounts,
+// transactions or operations through stellar.HorizonClient without network access.
+package stellartest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mtlprog/total/internal/stellar"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon/base"
+	"github.com/stellar/go-stellar-sdk/protocols/horizon/operations"
+)
+
+// Passphrase is the network passphrase of clients created by Server.Client.
+const Passphrase = "Test SDF Network ; September 2015"
+
+// defaultLimit is Horizon's page size when a request has no limit.
+const defaultLimit = 10
+
+// Server is a fake Horizon serving the account detail, account transactions and
+// account operations endpoints from fixtures. Unknown accounts get Horizon's 404
+// problem, so stellar.HorizonClient returns stellar.ErrAccountNotFound for them.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	accounts     map[string]horizon.Account
+	transactions map[string][]horizon.Transaction
+	operations   map[string][]operations.Operation
+	requests     map[string]int
+}
+
+// NewServer starts a fake Horizon that is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		accounts:     make(map[string]horizon.Account),
+		transactions: make(map[string][]horizon.Transaction),
+		operations:   make(map[string][]operations.Operation),
+		requests:     make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts/{id}", s.handleAccount)
+	mux.HandleFunc("GET /accounts/{id}/transactions", s.handleTransactions)
+	mux.HandleFunc("GET /accounts/{id}/operations", s.handleOperations)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.count(r)
+		writeNotFound(w)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a HorizonClient for the server on the Passphrase network.
+func (s *Server) Client(t testing.TB) *stellar.HorizonClient {
+	t.Helper()
+	client, err := stellar.NewHorizonClient(s.URL, Passphrase, s.Server.Client())
+	if err != nil {
+		t.Fatalf("NewHorizonClient() error = %v", err)
+	}
+	return client
+}
+
+// AddAccount serves account, replacing an account with the same ID.
+func (s *Server) AddAccount(account horizon.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[account.AccountID] = account
+}
+
+// AddFundedAccount serves an account holding balance XLM at the given sequence.
+func (s *Server) AddFundedAccount(accountID string, sequence int64, balance string) {
+	s.AddAccount(horizon.Account{
+		ID:        accountID,
+		AccountID: accountID,
+		Sequence:  sequence,
+		Balances: []horizon.Balance{{
+			Balance: balance,
+			Asset:   base.Asset{Type: "native"},
+		}},
+	})
+}
+
+// RemoveAccount stops serving an account, as if it was merged.
+func (s *Server) RemoveAccount(accountID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.accounts, accountID)
+}
+
+// AddTransactions appends transactions of an account, oldest first.
+func (s *Server) AddTransactions(accountID string, txs ...horizon.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[accountID] = append(s.transactions[accountID], txs...)
+}
+
+// AddOperations appends operations of an account, oldest first. Each operation's
+// TypeI must be set, since clients decode operations by it.
+func (s *Server) AddOperations(accountID string, ops ...operations.Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations[accountID] = append(s.operations[accountID], ops...)
+}
+
+// Requests returns how many requests were made for path, e.g. "/accounts/G...".
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) count(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.URL.Path]++
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	s.mu.Lock()
+	account, ok := s.accounts[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, account)
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, known := s.accounts[id]
+	records := slices.Clone(s.transactions[id])
+	s.mu.Unlock()
+	if !known {
+		writeNotFound(w)
+		return
+	}
+	records, err := page(r, records)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, embedded(records))
+}
+
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, known := s.accounts[id]
+	records := slices.Clone(s.operations[id])
+	s.mu.Unlock()
+	if !known {
+		writeNotFound(w)
+		return
+	}
+	records, err := page(r, records)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, embedded(records))
+}
+
+// page applies the order and limit query parameters to records stored oldest first.
+// Cursors are not supported: every request gets the first page.
+func page[T any](r *http.Request, records []T) ([]T, error) {
+	query := r.URL.Query()
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		slices.Reverse(records)
+	default:
+		return nil, fmt.Errorf("invalid order %q", query.Get("order"))
+	}
+	limit := defaultLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 200 {
+			return nil, fmt.Errorf("invalid limit %q", raw)
+		}
+		limit = n
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// embedded wraps records in a Horizon collection page.
+func embedded[T any](records []T) any {
+	if records == nil {
+		records = []T{}
+	}
+	return map[string]any{
+		"_links":    map[string]any{},
+		"_embedded": map[string]any{"records": records},
+	}
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]any{
+		"type":   "https://stellar.org/horizon-errors/not_found",
+		"title":  "Resource Missing",
+		"status": http.StatusNotFound,
+		"detail": "The resource at the url requested was not found.",
+	})
+}
+
+func writeBadRequest(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"type":   "https://stellar.org/horizon-errors/bad_request",
+		"title":  "Bad Request",
+		"status": http.StatusBadRequest,
+		"detail": err.Error(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/hal+json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -7,6 +7,9 @@ import (
 	"io"
 	"net/url"
 	"strings"
+
+	"github.com/mtlprog/total/internal/config"
+	"github.com/mtlprog/total/internal/static"
 )
 
 //go:embed templates/*.html
@@ -51,12 +54,19 @@ var funcMap = template.FuncMap{
 		}
 		return s[:8] + "..." + s[len(s)-8:]
 	},
+	"explorerTxURL": func(network, hash string) string {
+		return config.StellarExpertURL + network + "/tx/" + hash
+	},
 	"stellarURI": func(xdr string) string {
 		return "web+stellar:tx?xdr=" + url.QueryEscape(xdr)
 	},
 	"isTestnet": func(passphrase string) bool {
 		return strings.Contains(passphrase, "Test")
 	},
+	"countdown":  countdown,
+	"timeAgo":    timeAgo,
+	"localTime":  localTime,
+	"localClock": localClock,
 	"networkName": func(passphrase string) string {
 		if strings.Contains(passphrase, "Test") {
 			return "testnet"
@@ -65,8 +75,16 @@ var funcMap = template.FuncMap{
 	},
 }
 
-func New() (*Template, error) {
-	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templates, "templates/*.html")
+// New parses all templates. The asset function resolves static file names to
+// their fingerprinted URLs, e.g. {{asset "app.css"}}.
+func New(assets *static.Assets) (*Template, error) {
+	if assets == nil {
+		panic("template.New: assets must not be nil")
+	}
+	tmpl, err := template.New("").
+		Funcs(funcMap).
+		Funcs(template.FuncMap{"asset": assets.URL}).
+		ParseFS(templates, "templates/*.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
@@ -76,3 +94,8 @@ func New() (*Template, error) {
 func (t *Template) Render(w io.Writer, name string, data any) error {
 	return t.tmpl.ExecuteTemplate(w, name+".html", data)
 }
+
+// RenderFragment renders a {{define}}d partial on its own, for pages that poll it.
+func (t *Template) RenderFragment(w io.Writer, name string, data any) error {
+	return t.tmpl.ExecuteTemplate(w, name, data)
+}
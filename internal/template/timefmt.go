@@ -0,0 +1,82 @@
+package template
+
+import (
+	"fmt"
+	"time"
+)
+
+// countdown returns the time left until t, e.g. "3d 4h", or "" once t has passed.
+func countdown(t time.Time) string {
+	return formatCountdown(t, time.Now())
+}
+
+// timeAgo returns how long ago t was, e.g. "2 weeks ago".
+func timeAgo(t time.Time) string {
+	return formatAgo(t, time.Now())
+}
+
+// localTime formats t in the user's time zone, e.g. "2026-03-01 18:00 CET". A nil
+// location means UTC.
+func localTime(t time.Time, loc *time.Location) string {
+	return inLocation(t, loc).Format("2006-01-02 15:04 MST")
+}
+
+// localClock formats the time of day of t in the user's time zone, e.g. "18:00:05 CET".
+func localClock(t time.Time, loc *time.Location) string {
+	return inLocation(t, loc).Format("15:04:05 MST")
+}
+
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc)
+}
+
+func formatCountdown(t, now time.Time) string {
+	d := t.Sub(now)
+	if d <= 0 {
+		return ""
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "less than a minute"
+	}
+}
+
+func formatAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < day:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 14*day:
+		return plural(int(d/day), "day") + " ago"
+	case d < 60*day:
+		return plural(int(d/(7*day)), "week") + " ago"
+	case d < 365*day:
+		return plural(int(d/(30*day)), "month") + " ago"
+	default:
+		return plural(int(d/(365*day)), "year") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
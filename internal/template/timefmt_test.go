@@ -0,0 +1,48 @@
+package template
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatCountdown(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{-time.Hour, ""},
+		{0, ""},
+		{30 * time.Second, "less than a minute"},
+		{12 * time.Minute, "12m"},
+		{4*time.Hour + 12*time.Minute, "4h 12m"},
+		{3*24*time.Hour + 4*time.Hour + 59*time.Minute, "3d 4h"},
+	}
+	for _, tt := range tests {
+		if got := formatCountdown(now.Add(tt.in), now); got != tt.want {
+			t.Errorf("formatCountdown(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{5 * time.Hour, "5 hours ago"},
+		{day, "1 day ago"},
+		{15 * day, "2 weeks ago"},
+		{90 * day, "3 months ago"},
+		{800 * day, "2 years ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAgo(now.Add(-tt.in), now); got != tt.want {
+			t.Errorf("formatAgo(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}